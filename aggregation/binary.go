@@ -52,3 +52,109 @@ func eval(binaryOp stmt.BinaryOP, left, right float64) float64 {
 		return 0
 	}
 }
+
+// binaryEvalWithFill evaluates two float array element-wise over aligned slots,
+// the missing slots of left/right array are handled by the fill policy, interval is the time interval of slot.
+func binaryEvalWithFill(binaryOp stmt.BinaryOP, fill stmt.Fill, interval int64,
+	left, right collections.FloatArray,
+) collections.FloatArray {
+	switch fill.Type {
+	case stmt.FillNull:
+		return binaryEvalAligned(binaryOp, left, right)
+	case stmt.FillPrevious, stmt.FillValue:
+		if left == nil || right == nil || (left.IsEmpty() && right.IsEmpty()) {
+			return nil
+		}
+		return binaryEval(binaryOp, fillArray(fill, interval, left), fillArray(fill, interval, right))
+	default:
+		return binaryEval(binaryOp, left, right)
+	}
+}
+
+// binaryEvalAligned evaluates two float array, only the slots which both left and right have value are evaluated
+func binaryEvalAligned(binaryOp stmt.BinaryOP, left, right collections.FloatArray) collections.FloatArray {
+	if left == nil || right == nil {
+		return nil
+	}
+	if left.IsEmpty() || right.IsEmpty() {
+		return nil
+	}
+
+	capacity := left.Capacity()
+	result := collections.NewFloatArray(capacity)
+	for i := 0; i < capacity; i++ {
+		if left.HasValue(i) && right.HasValue(i) {
+			result.SetValue(i, eval(binaryOp, left.GetValue(i), right.GetValue(i)))
+		}
+	}
+	if result.IsEmpty() {
+		return nil
+	}
+	return result
+}
+
+// fillArray returns a new float array which missing slots are filled based on fill policy,
+// 1. previous: fills with the previous value, keeps the slots before first value missing,
+// keeps the slots beyond the max gap since the previous value missing if max gap is set
+// 2. value: fills with the given value
+func fillArray(fill stmt.Fill, interval int64, values collections.FloatArray) collections.FloatArray {
+	if values.IsSingle() {
+		return values
+	}
+	capacity := values.Capacity()
+	result := collections.NewFloatArray(capacity)
+	// max count of slots carried forward, -1 means unbounded
+	maxGapSlots := -1
+	if fill.MaxGap > 0 && interval > 0 {
+		maxGapSlots = int(fill.MaxGap / interval)
+	}
+	hasPrevious := false
+	previous := 0.0
+	previousIdx := 0
+	for i := 0; i < capacity; i++ {
+		switch {
+		case values.HasValue(i):
+			previous = values.GetValue(i)
+			previousIdx = i
+			hasPrevious = true
+			result.SetValue(i, previous)
+		case fill.Type == stmt.FillPrevious && hasPrevious:
+			if maxGapSlots < 0 || i-previousIdx <= maxGapSlots {
+				result.SetValue(i, previous)
+			}
+		case fill.Type == stmt.FillValue:
+			result.SetValue(i, fill.Value)
+		}
+	}
+	return result
+}
+
+// scalarEvalWithFill evaluates float array with a constant, only the slots which have value are evaluated,
+// the missing slots are filled first if fill policy is previous or value.
+// scalarLeft indicates if the constant is the left operand, such as 100 - f.
+func scalarEvalWithFill(binaryOp stmt.BinaryOP, fill stmt.Fill, interval int64, values collections.FloatArray,
+	scalar float64, scalarLeft bool,
+) collections.FloatArray {
+	if values == nil || values.IsEmpty() {
+		return nil
+	}
+	if fill.Type == stmt.FillPrevious || fill.Type == stmt.FillValue {
+		values = fillArray(fill, interval, values)
+	}
+	capacity := values.Capacity()
+	result := collections.NewFloatArray(capacity)
+	for i := 0; i < capacity; i++ {
+		if !values.HasValue(i) {
+			continue
+		}
+		if scalarLeft {
+			result.SetValue(i, eval(binaryOp, scalar, values.GetValue(i)))
+		} else {
+			result.SetValue(i, eval(binaryOp, values.GetValue(i), scalar))
+		}
+	}
+	if result.IsEmpty() {
+		return nil
+	}
+	return result
+}
@@ -108,3 +108,130 @@ func TestBinaryEval(t *testing.T) {
 	assert.Equal(t, 0.0, result.GetValue(5))
 	assert.Equal(t, 0.0, result.GetValue(8))
 }
+
+func TestBinaryEvalWithFill(t *testing.T) {
+	// a: slot 0,1,3; b: slot 0,2,3
+	left := collections.NewFloatArray(5)
+	left.SetValue(0, 10)
+	left.SetValue(1, 20)
+	left.SetValue(3, 40)
+	right := collections.NewFloatArray(5)
+	right.SetValue(0, 1)
+	right.SetValue(2, 3)
+	right.SetValue(3, 4)
+
+	// none: missing slot evaluates as 0
+	result := binaryEvalWithFill(stmt.SUB, stmt.Fill{}, 10, left, right)
+	assert.Equal(t, 4, result.Size())
+	assert.Equal(t, 9.0, result.GetValue(0))
+	assert.Equal(t, 20.0, result.GetValue(1))
+	assert.Equal(t, -3.0, result.GetValue(2))
+	assert.Equal(t, 36.0, result.GetValue(3))
+	assert.False(t, result.HasValue(4))
+
+	// null: only aligned slots
+	result = binaryEvalWithFill(stmt.SUB, stmt.Fill{Type: stmt.FillNull}, 10, left, right)
+	assert.Equal(t, 2, result.Size())
+	assert.Equal(t, 9.0, result.GetValue(0))
+	assert.Equal(t, 36.0, result.GetValue(3))
+
+	// previous: missing slot uses previous value of same series
+	result = binaryEvalWithFill(stmt.SUB, stmt.Fill{Type: stmt.FillPrevious}, 10, left, right)
+	assert.Equal(t, 5, result.Size())
+	assert.Equal(t, 9.0, result.GetValue(0))
+	assert.Equal(t, 19.0, result.GetValue(1))
+	assert.Equal(t, 17.0, result.GetValue(2))
+	assert.Equal(t, 36.0, result.GetValue(3))
+	assert.Equal(t, 36.0, result.GetValue(4))
+
+	// value: missing slot uses fill value
+	result = binaryEvalWithFill(stmt.SUB, stmt.Fill{Type: stmt.FillValue, Value: 5}, 10, left, right)
+	assert.Equal(t, 5, result.Size())
+	assert.Equal(t, 9.0, result.GetValue(0))
+	assert.Equal(t, 15.0, result.GetValue(1))
+	assert.Equal(t, 2.0, result.GetValue(2))
+	assert.Equal(t, 36.0, result.GetValue(3))
+	assert.Equal(t, 0.0, result.GetValue(4))
+	assert.True(t, result.HasValue(4))
+
+	// single value not filled
+	single := collections.NewFloatArray(5)
+	single.SetSingle(true)
+	for i := 0; i < 5; i++ {
+		single.SetValue(i, 1)
+	}
+	result = binaryEvalWithFill(stmt.SUB, stmt.Fill{Type: stmt.FillValue, Value: 5}, 10, right, single)
+	assert.Equal(t, 5, result.Size())
+	assert.Equal(t, 4.0, result.GetValue(1))
+
+	// nil/empty
+	empty := collections.NewFloatArray(5)
+	assert.Nil(t, binaryEvalWithFill(stmt.SUB, stmt.Fill{Type: stmt.FillNull}, 10, left, nil))
+	assert.Nil(t, binaryEvalWithFill(stmt.SUB, stmt.Fill{Type: stmt.FillNull}, 10, left, empty))
+	assert.Nil(t, binaryEvalWithFill(stmt.SUB, stmt.Fill{Type: stmt.FillPrevious}, 10, nil, right))
+	assert.Nil(t, binaryEvalWithFill(stmt.SUB, stmt.Fill{Type: stmt.FillValue}, 10, empty, empty))
+	assert.Nil(t, binaryEvalWithFill(stmt.SUB, stmt.Fill{Type: stmt.FillNull}, 10, left, collections.NewFloatArray(6)))
+}
+
+func TestScalarEvalWithFill(t *testing.T) {
+	values := collections.NewFloatArray(5)
+	values.SetValue(0, 0.1)
+	values.SetValue(2, 0.25)
+
+	result := scalarEvalWithFill(stmt.MUL, stmt.Fill{}, 10, values, 100, false)
+	assert.Equal(t, 2, result.Size())
+	assert.Equal(t, 0.1*100, result.GetValue(0))
+	assert.Equal(t, 0.25*100, result.GetValue(2))
+	assert.False(t, result.HasValue(1))
+
+	// constant is left operand
+	result = scalarEvalWithFill(stmt.SUB, stmt.Fill{Type: stmt.FillNull}, 10, values, 1, true)
+	assert.Equal(t, 2, result.Size())
+	assert.Equal(t, 1-0.1, result.GetValue(0))
+	assert.Equal(t, 1-0.25, result.GetValue(2))
+	result = scalarEvalWithFill(stmt.DIV, stmt.Fill{}, 10, values, 0, false)
+	assert.Equal(t, 0.0, result.GetValue(0))
+
+	// previous: missing slot filled before evaluating
+	result = scalarEvalWithFill(stmt.MUL, stmt.Fill{Type: stmt.FillPrevious}, 10, values, 100, false)
+	assert.Equal(t, 5, result.Size())
+	assert.Equal(t, 0.1*100, result.GetValue(1))
+	assert.Equal(t, 0.25*100, result.GetValue(4))
+
+	// nil/empty
+	assert.Nil(t, scalarEvalWithFill(stmt.MUL, stmt.Fill{}, 10, nil, 100, false))
+	assert.Nil(t, scalarEvalWithFill(stmt.MUL, stmt.Fill{}, 10, collections.NewFloatArray(5), 100, false))
+}
+
+func TestFillArray_maxGap(t *testing.T) {
+	values := collections.NewFloatArray(10)
+	values.SetValue(1, 1)
+	values.SetValue(6, 6)
+
+	// unbounded
+	result := fillArray(stmt.Fill{Type: stmt.FillPrevious}, 10, values)
+	assert.False(t, result.HasValue(0))
+	for i := 1; i < 10; i++ {
+		assert.True(t, result.HasValue(i))
+	}
+	assert.Equal(t, 1.0, result.GetValue(5))
+	assert.Equal(t, 6.0, result.GetValue(9))
+
+	// carries forward within 2 slots(20ms) since the previous value, missing beyond
+	result = fillArray(stmt.Fill{Type: stmt.FillPrevious, MaxGap: 20}, 10, values)
+	assert.Equal(t, []bool{false, true, true, true, false, false, true, true, true, false},
+		[]bool{result.HasValue(0), result.HasValue(1), result.HasValue(2), result.HasValue(3), result.HasValue(4),
+			result.HasValue(5), result.HasValue(6), result.HasValue(7), result.HasValue(8), result.HasValue(9)})
+	assert.Equal(t, 1.0, result.GetValue(3))
+	assert.Equal(t, 6.0, result.GetValue(8))
+
+	// max gap is less than interval, nothing carried forward
+	result = fillArray(stmt.Fill{Type: stmt.FillPrevious, MaxGap: 5}, 10, values)
+	assert.Equal(t, 2, result.Size())
+
+	// bounded fill is applied before evaluating
+	result = scalarEvalWithFill(stmt.MUL, stmt.Fill{Type: stmt.FillPrevious, MaxGap: 10}, 10, values, 10, false)
+	assert.Equal(t, 4, result.Size())
+	assert.Equal(t, 10.0, result.GetValue(2))
+	assert.False(t, result.HasValue(3))
+}
@@ -1,6 +1,9 @@
 package aggregation
 
 import (
+	"regexp"
+	"sort"
+
 	"github.com/lindb/lindb/aggregation/fields"
 	"github.com/lindb/lindb/aggregation/function"
 	"github.com/lindb/lindb/pkg/collections"
@@ -30,20 +33,24 @@ type expression struct {
 	interval    int64
 	timeRange   timeutil.TimeRange
 	selectItems []stmt.Expr
+	fill        stmt.Fill
 
-	fieldStore map[string]fields.Field
-	resultSet  map[string]collections.FloatArray
+	fieldStore   map[string]fields.Field
+	resultSet    map[string]collections.FloatArray
+	fieldRegexps map[string]*regexp.Regexp // field name regex pattern => compiled regex
 }
 
-// NewExpression creates an expression
-func NewExpression(timeRange timeutil.TimeRange, interval int64, selectItems []stmt.Expr) Expression {
+// NewExpression creates an expression, the fill policy is used for missing slots when evaluating binary operator
+func NewExpression(timeRange timeutil.TimeRange, interval int64, selectItems []stmt.Expr, fill stmt.Fill) Expression {
 	return &expression{
-		pointCount:  timeutil.CalPointCount(timeRange.Start, timeRange.End, interval),
-		interval:    interval,
-		timeRange:   timeRange,
-		selectItems: selectItems,
-		fieldStore:  make(map[string]fields.Field),
-		resultSet:   make(map[string]collections.FloatArray),
+		pointCount:   timeutil.CalPointCount(timeRange.Start, timeRange.End, interval),
+		interval:     interval,
+		timeRange:    timeRange,
+		selectItems:  selectItems,
+		fill:         fill,
+		fieldStore:   make(map[string]fields.Field),
+		resultSet:    make(map[string]collections.FloatArray),
+		fieldRegexps: make(map[string]*regexp.Regexp),
 	}
 }
 
@@ -60,16 +67,104 @@ func (e *expression) Eval(timeSeries series.GroupedIterator) {
 	}
 
 	for _, selectItem := range e.selectItems {
-		values := e.eval(nil, selectItem)
-		if len(values) != 0 {
-			item, ok := selectItem.(*stmt.SelectItem)
-			if ok && len(item.Alias) > 0 {
-				e.resultSet[item.Alias] = values[0]
-			} else {
-				e.resultSet[item.Rewrite()] = values[0]
+		for _, expandedItem := range e.expandFieldRegex(selectItem) {
+			values := e.eval(nil, expandedItem)
+			if len(values) != 0 {
+				item, ok := expandedItem.(*stmt.SelectItem)
+				if ok && len(item.Alias) > 0 {
+					e.resultSet[item.Alias] = values[0]
+				} else {
+					e.resultSet[item.Rewrite()] = values[0]
+				}
+			}
+		}
+	}
+}
+
+// expandFieldRegex expands the select item by the fields which field name matches the field name regex,
+// like /disk_.*/, a select item is expanded for each matched field in order of field name,
+// the alias of select item is dropped, because the expanded items cannot share an alias.
+func (e *expression) expandFieldRegex(selectItem stmt.Expr) []stmt.Expr {
+	fieldExpr := findFieldRegex(selectItem)
+	if fieldExpr == nil {
+		return []stmt.Expr{selectItem}
+	}
+	pattern, _ := fieldExpr.NamePattern()
+	regex, ok := e.fieldRegexps[pattern]
+	if !ok {
+		// the pattern is validated by storage, so the field is skipped if the pattern is invalid
+		regex, _ = regexp.Compile(pattern)
+		e.fieldRegexps[pattern] = regex
+	}
+	if regex == nil {
+		return nil
+	}
+	var fieldNames []string
+	for fieldName := range e.fieldStore {
+		if regex.MatchString(fieldName) {
+			fieldNames = append(fieldNames, fieldName)
+		}
+	}
+	sort.Strings(fieldNames)
+	var result []stmt.Expr
+	for _, fieldName := range fieldNames {
+		// the expanded item may have other field name regex
+		result = append(result, e.expandFieldRegex(replaceField(selectItem, fieldExpr.Name, fieldName))...)
+	}
+	return result
+}
+
+// findFieldRegex returns the first field expr in the expression which field name is a regex
+func findFieldRegex(expr stmt.Expr) *stmt.FieldExpr {
+	switch ex := expr.(type) {
+	case *stmt.SelectItem:
+		return findFieldRegex(ex.Expr)
+	case *stmt.CallExpr:
+		for _, param := range ex.Params {
+			if fieldExpr := findFieldRegex(param); fieldExpr != nil {
+				return fieldExpr
 			}
 		}
+	case *stmt.ParenExpr:
+		return findFieldRegex(ex.Expr)
+	case *stmt.BinaryExpr:
+		if fieldExpr := findFieldRegex(ex.Left); fieldExpr != nil {
+			return fieldExpr
+		}
+		return findFieldRegex(ex.Right)
+	case *stmt.FieldExpr:
+		if _, ok := ex.NamePattern(); ok {
+			return ex
+		}
 	}
+	return nil
+}
+
+// replaceField returns a copy of the expression which field name is replaced by the new field name
+func replaceField(expr stmt.Expr, oldName, newName string) stmt.Expr {
+	switch ex := expr.(type) {
+	case *stmt.SelectItem:
+		return &stmt.SelectItem{Expr: replaceField(ex.Expr, oldName, newName)}
+	case *stmt.CallExpr:
+		params := make([]stmt.Expr, len(ex.Params))
+		for idx, param := range ex.Params {
+			params[idx] = replaceField(param, oldName, newName)
+		}
+		return &stmt.CallExpr{FuncType: ex.FuncType, Params: params}
+	case *stmt.ParenExpr:
+		return &stmt.ParenExpr{Expr: replaceField(ex.Expr, oldName, newName)}
+	case *stmt.BinaryExpr:
+		return &stmt.BinaryExpr{
+			Left:     replaceField(ex.Left, oldName, newName),
+			Operator: ex.Operator,
+			Right:    replaceField(ex.Right, oldName, newName),
+		}
+	case *stmt.FieldExpr:
+		if ex.Name == oldName {
+			return &stmt.FieldExpr{Name: newName}
+		}
+	}
+	return expr
 }
 
 // ResultSet returns the eval result
@@ -117,8 +212,9 @@ func (e *expression) eval(parentFunc *stmt.CallExpr, expr stmt.Expr) []collectio
 			return nil
 		}
 
-		// tests if has func with field
-		if parentFunc == nil {
+		// tests if has func with field,
+		// moving average smooths the downsampled values, so uses the default values of field
+		if parentFunc == nil || parentFunc.FuncType == function.MovingAverage {
 			return fieldValues.GetDefaultValues()
 		}
 		return fieldValues.GetValues(parentFunc.FuncType)
@@ -132,10 +228,14 @@ func (e *expression) funcCall(expr *stmt.CallExpr) []collections.FloatArray {
 	var params []collections.FloatArray
 	for _, param := range expr.Params {
 		paramValues := e.eval(expr, param)
-		if len(paramValues) != 1 {
+		switch {
+		case len(paramValues) == 0:
+			return nil
+		case len(paramValues) > 1 && (expr.FuncType != function.Avg || len(expr.Params) != 1):
+			// params are positional, only the single field of avg returns multi values(e.g. sum and count)
 			return nil
 		}
-		params = append(params, paramValues[0])
+		params = append(params, paramValues...)
 	}
 	result := function.FuncCall(expr.FuncType, params...)
 	if result == nil {
@@ -148,6 +248,13 @@ func (e *expression) funcCall(expr *stmt.CallExpr) []collections.FloatArray {
 func (e *expression) binaryEval(expr *stmt.BinaryExpr) []collections.FloatArray {
 	binaryOP := expr.Operator
 	if binaryOP == stmt.ADD || binaryOP == stmt.SUB || binaryOP == stmt.DIV || binaryOP == stmt.MUL {
+		// field op constant, applies the constant on the field values directly
+		if scalar, ok := expr.Right.(*stmt.NumberLiteral); ok {
+			return e.scalarEval(binaryOP, expr.Left, scalar.Val, false)
+		}
+		if scalar, ok := expr.Left.(*stmt.NumberLiteral); ok {
+			return e.scalarEval(binaryOP, expr.Right, scalar.Val, true)
+		}
 		left := e.eval(nil, expr.Left)
 		if len(left) != 1 {
 			return nil
@@ -156,13 +263,26 @@ func (e *expression) binaryEval(expr *stmt.BinaryExpr) []collections.FloatArray
 		if len(right) != 1 {
 			return nil
 		}
-		result := binaryEval(binaryOP, left[0], right[0])
+		result := binaryEvalWithFill(binaryOP, e.fill, e.interval, left[0], right[0])
 		return []collections.FloatArray{result}
 	}
 
 	return nil
 }
 
+// scalarEval evaluates the expression with a constant, scalarLeft indicates if the constant is the left operand
+func (e *expression) scalarEval(binaryOP stmt.BinaryOP, expr stmt.Expr, scalar float64, scalarLeft bool) []collections.FloatArray {
+	values := e.eval(nil, expr)
+	if len(values) != 1 {
+		return nil
+	}
+	result := scalarEvalWithFill(binaryOP, e.fill, e.interval, values[0], scalar, scalarLeft)
+	if result == nil {
+		return nil
+	}
+	return []collections.FloatArray{result}
+}
+
 // Reset resets the expression context for reusing
 func (e *expression) Reset() {
 	for _, f := range e.fieldStore {
@@ -6,7 +6,9 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/lindb/lindb/aggregation/fields"
 	"github.com/lindb/lindb/aggregation/function"
+	"github.com/lindb/lindb/pkg/collections"
 	"github.com/lindb/lindb/pkg/timeutil"
 	"github.com/lindb/lindb/series"
 	"github.com/lindb/lindb/series/field"
@@ -29,7 +31,7 @@ func TestExpression_prepare(t *testing.T) {
 	expression := NewExpression(timeutil.TimeRange{
 		Start: now,
 		End:   now + timeutil.OneHour*2,
-	}, timeutil.OneMinute, query.SelectItems)
+	}, timeutil.OneMinute, query.SelectItems, query.Fill)
 	gomock.InOrder(
 		timeSeries.EXPECT().HasNext().Return(true),
 		timeSeries.EXPECT().Next().Return(sumSeries),
@@ -59,7 +61,7 @@ func TestExpression_prepare(t *testing.T) {
 	expression = NewExpression(timeutil.TimeRange{
 		Start: now,
 		End:   now + timeutil.OneHour*2,
-	}, timeutil.OneMinute, query.SelectItems)
+	}, timeutil.OneMinute, query.SelectItems, query.Fill)
 	expression.Eval(nil)
 	resultSet = expression.ResultSet()
 	assert.Equal(t, 0, len(resultSet))
@@ -94,7 +96,7 @@ func TestExpression_prepare(t *testing.T) {
 	expression = NewExpression(timeutil.TimeRange{
 		Start: now,
 		End:   now + timeutil.OneHour*2,
-	}, timeutil.OneMinute, query.SelectItems)
+	}, timeutil.OneMinute, query.SelectItems, query.Fill)
 	expression.Eval(timeSeries)
 	resultSet = expression.ResultSet()
 	assert.Equal(t, 0, len(resultSet))
@@ -113,7 +115,7 @@ func TestExpression_Paren(t *testing.T) {
 	expression := NewExpression(timeutil.TimeRange{
 		Start: now,
 		End:   now + timeutil.OneHour*2,
-	}, timeutil.OneMinute, query.SelectItems)
+	}, timeutil.OneMinute, query.SelectItems, query.Fill)
 	gomock.InOrder(
 		timeSeries.EXPECT().HasNext().Return(true),
 		timeSeries.EXPECT().Next().Return(series1),
@@ -144,7 +146,7 @@ func TestExpression_BinaryEval(t *testing.T) {
 	expression := NewExpression(timeutil.TimeRange{
 		Start: now,
 		End:   now + timeutil.OneHour*2,
-	}, timeutil.OneMinute, query.SelectItems)
+	}, timeutil.OneMinute, query.SelectItems, query.Fill)
 	gomock.InOrder(
 		timeSeries.EXPECT().HasNext().Return(true),
 		timeSeries.EXPECT().Next().Return(series1),
@@ -165,7 +167,7 @@ func TestExpression_BinaryEval(t *testing.T) {
 	expression = NewExpression(timeutil.TimeRange{
 		Start: now,
 		End:   now + timeutil.OneHour*2,
-	}, timeutil.OneMinute, query.SelectItems)
+	}, timeutil.OneMinute, query.SelectItems, query.Fill)
 	gomock.InOrder(
 		timeSeries.EXPECT().HasNext().Return(true),
 		timeSeries.EXPECT().Next().Return(series1),
@@ -187,7 +189,7 @@ func TestExpression_BinaryEval(t *testing.T) {
 	expression = NewExpression(timeutil.TimeRange{
 		Start: now,
 		End:   now + timeutil.OneHour*2,
-	}, timeutil.OneMinute, query.SelectItems)
+	}, timeutil.OneMinute, query.SelectItems, query.Fill)
 	gomock.InOrder(
 		timeSeries.EXPECT().HasNext().Return(true),
 		timeSeries.EXPECT().Next().Return(series1),
@@ -202,7 +204,7 @@ func TestExpression_BinaryEval(t *testing.T) {
 	expression = NewExpression(timeutil.TimeRange{
 		Start: now,
 		End:   now + timeutil.OneHour*2,
-	}, timeutil.OneMinute, query.SelectItems)
+	}, timeutil.OneMinute, query.SelectItems, query.Fill)
 	gomock.InOrder(
 		timeSeries.EXPECT().HasNext().Return(true),
 		timeSeries.EXPECT().Next().Return(series2),
@@ -222,7 +224,7 @@ func TestExpression_BinaryEval(t *testing.T) {
 		Left:     &stmt.FieldExpr{Name: "f1"},
 		Operator: stmt.AND,
 		Right:    &stmt.FieldExpr{Name: "f2"},
-	}}})
+	}}}, stmt.Fill{})
 	gomock.InOrder(
 		timeSeries.EXPECT().HasNext().Return(true),
 		timeSeries.EXPECT().Next().Return(series1),
@@ -235,6 +237,50 @@ func TestExpression_BinaryEval(t *testing.T) {
 	assert.Equal(t, 0, len(resultSet))
 }
 
+func TestExpression_ScalarEval(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeRange := timeutil.TimeRange{Start: now, End: now + timeutil.OneHour*2}
+	timeSeries := series.NewMockGroupedIterator(ctrl)
+	evalQuery := func(ql string) map[string]collections.FloatArray {
+		query, _ := sql.Parse(ql)
+		expression := NewExpression(timeRange, timeutil.OneMinute, query.SelectItems, query.Fill)
+		gomock.InOrder(
+			timeSeries.EXPECT().HasNext().Return(true),
+			timeSeries.EXPECT().Next().Return(mockTimeSeries(ctrl, familyTime, "f", field.SumField)),
+			timeSeries.EXPECT().HasNext().Return(false),
+		)
+		expression.Eval(timeSeries)
+		return expression.ResultSet()
+	}
+	raw := evalQuery("select f from cpu")["f"]
+	assert.NotNil(t, raw)
+
+	cases := []struct {
+		sql    string
+		result func(v float64) float64
+	}{
+		{sql: "select f*100 as f from cpu", result: func(v float64) float64 { return v * 100 }},
+		{sql: "select 100*f as f from cpu", result: func(v float64) float64 { return 100 * v }},
+		{sql: "select f/10 as f from cpu", result: func(v float64) float64 { return v / 10 }},
+		{sql: "select 1-f as f from cpu", result: func(v float64) float64 { return 1 - v }},
+		{sql: "select f+0.5 as f from cpu", result: func(v float64) float64 { return v + 0.5 }},
+	}
+	for _, c := range cases {
+		scaled := evalQuery(c.sql)["f"]
+		assert.Equal(t, raw.Size(), scaled.Size(), c.sql)
+		it := raw.Iterator()
+		for it.HasNext() {
+			idx, value := it.Next()
+			assert.Equal(t, c.result(value), scaled.GetValue(idx), c.sql)
+		}
+	}
+
+	// field not found
+	assert.Nil(t, evalQuery("select f2*100 as f from cpu")["f"])
+}
+
 func TestExpression_FuncCall_Sum(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -246,7 +292,7 @@ func TestExpression_FuncCall_Sum(t *testing.T) {
 	expression := NewExpression(timeutil.TimeRange{
 		Start: now,
 		End:   now + timeutil.OneHour*2,
-	}, timeutil.OneMinute, query.SelectItems)
+	}, timeutil.OneMinute, query.SelectItems, query.Fill)
 	gomock.InOrder(
 		timeSeries.EXPECT().HasNext().Return(true),
 		timeSeries.EXPECT().Next().Return(series1),
@@ -265,7 +311,7 @@ func TestExpression_FuncCall_Sum(t *testing.T) {
 	expression = NewExpression(timeutil.TimeRange{
 		Start: now,
 		End:   now + timeutil.OneHour*2,
-	}, timeutil.OneMinute, query.SelectItems)
+	}, timeutil.OneMinute, query.SelectItems, query.Fill)
 	gomock.InOrder(
 		timeSeries.EXPECT().HasNext().Return(true),
 		timeSeries.EXPECT().Next().Return(series1),
@@ -281,7 +327,7 @@ func TestExpression_FuncCall_Sum(t *testing.T) {
 		End:   now + timeutil.OneHour*2,
 	}, timeutil.OneMinute, []stmt.Expr{&stmt.SelectItem{Expr: &stmt.CallExpr{
 		FuncType: function.Sum,
-	}}})
+	}}}, stmt.Fill{})
 	gomock.InOrder(
 		timeSeries.EXPECT().HasNext().Return(true),
 		timeSeries.EXPECT().Next().Return(series1),
@@ -292,13 +338,170 @@ func TestExpression_FuncCall_Sum(t *testing.T) {
 	assert.Equal(t, 0, len(resultSet))
 }
 
+func TestExpression_Sub(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// a: 100@slot11, 200@slot12, 300@slot14; b: 30@slot11, 50@slot13, 70@slot14
+	mockSeries := func(fieldName string, points map[int]float64, slots ...int) series.Iterator {
+		timeSeries := series.NewMockIterator(ctrl)
+		timeSeries.EXPECT().FieldType().Return(field.SumField)
+		timeSeries.EXPECT().FieldName().Return(fieldName)
+		it := series.NewMockFieldIterator(ctrl)
+		primitiveIt := series.NewMockPrimitiveIterator(ctrl)
+		it.EXPECT().HasNext().Return(true)
+		it.EXPECT().Next().Return(primitiveIt)
+		primitiveIt.EXPECT().FieldID().Return(uint16(1))
+		for _, slot := range slots {
+			primitiveIt.EXPECT().HasNext().Return(true)
+			primitiveIt.EXPECT().Next().Return(slot, points[slot])
+		}
+		primitiveIt.EXPECT().HasNext().Return(false)
+		it.EXPECT().HasNext().Return(false)
+		timeSeries.EXPECT().HasNext().Return(true)
+		timeSeries.EXPECT().Next().Return(familyTime, it)
+		timeSeries.EXPECT().HasNext().Return(false)
+		return timeSeries
+	}
+	eval := func(sqlStr string) collections.FloatArray {
+		query, err := sql.Parse(sqlStr)
+		assert.NoError(t, err)
+		expression := NewExpression(timeutil.TimeRange{
+			Start: now,
+			End:   now + timeutil.OneHour,
+		}, timeutil.OneMinute, query.SelectItems, query.Fill)
+		timeSeries := series.NewMockGroupedIterator(ctrl)
+		gomock.InOrder(
+			timeSeries.EXPECT().HasNext().Return(true),
+			timeSeries.EXPECT().Next().Return(mockSeries("a", map[int]float64{11: 100, 12: 200, 14: 300}, 11, 12, 14)),
+			timeSeries.EXPECT().HasNext().Return(true),
+			timeSeries.EXPECT().Next().Return(mockSeries("b", map[int]float64{11: 30, 13: 50, 14: 70}, 11, 13, 14)),
+			timeSeries.EXPECT().HasNext().Return(false),
+		)
+		expression.Eval(timeSeries)
+		return expression.ResultSet()["d"]
+	}
+
+	// element-wise subtraction over aligned slots
+	rs := eval("select a-b as d from cpu group by time(1m) fill(null)")
+	assert.Equal(t, 2, rs.Size())
+	assert.Equal(t, 70.0, rs.GetValue(11-10))
+	assert.Equal(t, 230.0, rs.GetValue(14-10))
+
+	// misaligned slots filled with previous value
+	rs = eval("select a-b as d from cpu group by time(1m) fill(previous)")
+	assert.Equal(t, 60, rs.Size())
+	assert.False(t, rs.HasValue(0))
+	assert.Equal(t, 70.0, rs.GetValue(11-10))
+	assert.Equal(t, 170.0, rs.GetValue(12-10))
+	assert.Equal(t, 150.0, rs.GetValue(13-10))
+	assert.Equal(t, 230.0, rs.GetValue(14-10))
+	assert.Equal(t, 230.0, rs.GetValue(59))
+	// the slot at the end of time range is filled
+	assert.Equal(t, 230.0, rs.GetValue(60))
+}
+
+func TestExpression_MovingAverage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// a: 10@slot11, 20@slot12, 60@slot13, 30@slot15
+	timeSeries := series.NewMockIterator(ctrl)
+	timeSeries.EXPECT().FieldType().Return(field.SumField)
+	timeSeries.EXPECT().FieldName().Return("a")
+	it := series.NewMockFieldIterator(ctrl)
+	primitiveIt := series.NewMockPrimitiveIterator(ctrl)
+	it.EXPECT().HasNext().Return(true)
+	it.EXPECT().Next().Return(primitiveIt)
+	primitiveIt.EXPECT().FieldID().Return(uint16(1))
+	points := map[int]float64{11: 10, 12: 20, 13: 60, 15: 30}
+	for _, slot := range []int{11, 12, 13, 15} {
+		primitiveIt.EXPECT().HasNext().Return(true)
+		primitiveIt.EXPECT().Next().Return(slot, points[slot])
+	}
+	primitiveIt.EXPECT().HasNext().Return(false)
+	it.EXPECT().HasNext().Return(false)
+	timeSeries.EXPECT().HasNext().Return(true)
+	timeSeries.EXPECT().Next().Return(familyTime, it)
+	timeSeries.EXPECT().HasNext().Return(false)
+	groupedIt := series.NewMockGroupedIterator(ctrl)
+	gomock.InOrder(
+		groupedIt.EXPECT().HasNext().Return(true),
+		groupedIt.EXPECT().Next().Return(timeSeries),
+		groupedIt.EXPECT().HasNext().Return(false),
+	)
+
+	expression := NewExpression(timeutil.TimeRange{
+		Start: now,
+		End:   now + timeutil.OneHour,
+	}, timeutil.OneMinute, []stmt.Expr{&stmt.SelectItem{
+		Expr: &stmt.CallExpr{
+			FuncType: function.MovingAverage,
+			Params:   []stmt.Expr{&stmt.FieldExpr{Name: "a"}, &stmt.NumberLiteral{Val: 3}},
+		},
+		Alias: "ma",
+	}}, stmt.Fill{})
+	expression.Eval(groupedIt)
+	rs := expression.ResultSet()["ma"]
+	assert.Equal(t, 4, rs.Size())
+	// partial windows
+	assert.Equal(t, 10.0, rs.GetValue(11-10))
+	assert.Equal(t, (10.0+20.0)/2, rs.GetValue(12-10))
+	// full windows
+	assert.Equal(t, (10.0+20.0+60.0)/3, rs.GetValue(13-10))
+	assert.False(t, rs.HasValue(14-10))
+	assert.Equal(t, (60.0+30.0)/2, rs.GetValue(15-10))
+}
+
+func TestExpression_FuncCall_positionalParams(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	values1 := collections.NewFloatArray(10)
+	values1.SetValue(1, 10)
+	values2 := collections.NewFloatArray(10)
+	values2.SetValue(1, 2)
+	f := fields.NewMockField(ctrl)
+	expression := NewExpression(timeutil.TimeRange{
+		Start: now,
+		End:   now + timeutil.OneHour,
+	}, timeutil.OneMinute, nil, stmt.Fill{}).(*expression)
+	expression.fieldStore["a"] = f
+
+	// field with multi values cannot shift the window of moving average
+	f.EXPECT().GetDefaultValues().Return([]collections.FloatArray{values1, values2})
+	assert.Nil(t, expression.funcCall(&stmt.CallExpr{
+		FuncType: function.MovingAverage,
+		Params:   []stmt.Expr{&stmt.FieldExpr{Name: "a"}, &stmt.NumberLiteral{Val: 3}},
+	}))
+	// the single field of avg takes sum and count
+	f.EXPECT().GetValues(function.Avg).Return([]collections.FloatArray{values1, values2})
+	rs := expression.funcCall(&stmt.CallExpr{
+		FuncType: function.Avg,
+		Params:   []stmt.Expr{&stmt.FieldExpr{Name: "a"}},
+	})
+	assert.Len(t, rs, 1)
+	assert.Equal(t, 5.0, rs[0].GetValue(1))
+	// avg keeps the window position of outer moving average
+	f.EXPECT().GetValues(function.Avg).Return([]collections.FloatArray{values1, values2})
+	rs = expression.funcCall(&stmt.CallExpr{
+		FuncType: function.MovingAverage,
+		Params: []stmt.Expr{&stmt.CallExpr{
+			FuncType: function.Avg,
+			Params:   []stmt.Expr{&stmt.FieldExpr{Name: "a"}},
+		}, &stmt.NumberLiteral{Val: 3}},
+	})
+	assert.Len(t, rs, 1)
+	assert.Equal(t, 5.0, rs[0].GetValue(1))
+}
+
 func TestExpression_NotSupport_Expr(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	expression := NewExpression(timeutil.TimeRange{
 		Start: now,
 		End:   now + timeutil.OneHour*2,
-	}, timeutil.OneMinute, []stmt.Expr{})
+	}, timeutil.OneMinute, []stmt.Expr{}, stmt.Fill{})
 	expression.Eval(nil)
 	resultSet := expression.ResultSet()
 	assert.Equal(t, 0, len(resultSet))
@@ -308,7 +511,7 @@ func TestExpression_NotSupport_Expr(t *testing.T) {
 	expression = NewExpression(timeutil.TimeRange{
 		Start: now,
 		End:   now + timeutil.OneHour*2,
-	}, timeutil.OneMinute, []stmt.Expr{&stmt.EqualsExpr{}})
+	}, timeutil.OneMinute, []stmt.Expr{&stmt.EqualsExpr{}}, stmt.Fill{})
 	gomock.InOrder(
 		timeSeries.EXPECT().HasNext().Return(true),
 		timeSeries.EXPECT().Next().Return(series1),
@@ -318,3 +521,23 @@ func TestExpression_NotSupport_Expr(t *testing.T) {
 	resultSet = expression.ResultSet()
 	assert.Equal(t, 0, len(resultSet))
 }
+
+func TestExpression_expandFieldRegex(t *testing.T) {
+	e := NewExpression(timeutil.TimeRange{Start: now, End: now + timeutil.OneHour}, timeutil.OneMinute,
+		nil, stmt.Fill{}).(*expression)
+	e.fieldStore["disk_tmp"] = nil
+	e.fieldStore["disk_var"] = nil
+	e.fieldStore["mem"] = nil
+
+	query, _ := sql.Parse("select (\"/disk_.*/\"+\"/mem/\")*2 as d from host")
+	items := e.expandFieldRegex(query.SelectItems[0])
+	assert.Len(t, items, 2)
+	assert.Equal(t, "(disk_tmp+mem)*2.00", items[0].Rewrite())
+	assert.Equal(t, "(disk_var+mem)*2.00", items[1].Rewrite())
+	// not regex
+	query, _ = sql.Parse("select mem from host")
+	assert.Equal(t, query.SelectItems, e.expandFieldRegex(query.SelectItems[0]))
+	// invalid regex
+	query, _ = sql.Parse("select \"/disk_[/\" from host")
+	assert.Empty(t, e.expandFieldRegex(query.SelectItems[0]))
+}
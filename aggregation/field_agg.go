@@ -34,18 +34,22 @@ type downSamplingFieldAggregator struct {
 }
 
 // NewDownSamplingFieldAggregator creates a field aggregator for down sampling,
-// time range 's start and end is index based on segment start time and interval.
+// time range 's start and end is index based on segment start time and storage interval.
 // e.g. segment start time = 20190905 10:00:00, start = 10, end = 50, interval = 10 seconds,
 // real query time range {20190905 10:01:40 ~ 20190905 10:08:20}
+// the primitive aggregators aggregate the values with the time slot of storage interval, then roll up
+// the values into the index of query interval if the interval ratio > 1,
+// so the result set is based on query interval, e.g. ratio = 30, start = 10 => result start = 0.
 func NewDownSamplingFieldAggregator(
 	segmentStartTime int64,
 	selector selector.SlotSelector,
 	aggSpec AggregatorSpec,
 ) FieldAggregator {
-	start, _ := selector.Range()
+	start, end := selector.Range()
+	ratio := selector.IntervalRatio()
 	agg := &downSamplingFieldAggregator{
 		segmentStartTime: segmentStartTime,
-		start:            start,
+		start:            start / ratio,
 	}
 	aggregatorMap := make(map[aggKey]PrimitiveAggregator)
 	// if down sampling spec need init all aggregator
@@ -56,7 +60,23 @@ func NewDownSamplingFieldAggregator(
 				primitiveID: id,
 				aggType:     aggType,
 			}
-			aggregatorMap[key] = NewPrimitiveAggregator(id, start, selector.PointCount(), aggType.AggFunc())
+			aggFunc := aggType.AggFunc()
+			if ratio > 1 {
+				aggFunc = aggSpec.FieldType().RollupAggFunc(aggType)
+			}
+			// series count is not stored, counts the time slot of series which has value instead
+			var pAgg PrimitiveAggregator
+			if id == field.SeriesCountFieldID {
+				pAgg = newSeriesCountAggregator(agg.start, selector.PointCount())
+			} else {
+				pAgg = NewPrimitiveAggregator(id, agg.start, selector.PointCount(), aggFunc)
+			}
+			aggregatorMap[key] = &rollupAggregator{
+				PrimitiveAggregator: pAgg,
+				start:               start,
+				end:                 end,
+				ratio:               ratio,
+			}
 		}
 	}
 	length := len(aggregatorMap)
@@ -97,10 +117,34 @@ func (agg *downSamplingFieldAggregator) reset() {
 	}
 }
 
+// rollupAggregator represents a primitive aggregator which aggregates the value with time slot of storage interval,
+// the time slot is rolled up into the index of query interval.
+// NOTICE: gauge keeps the value of the last aggregated time slot in query interval, so the values are
+// aggregated in time order.
+type rollupAggregator struct {
+	PrimitiveAggregator
+	start, end int // time slot range of storage interval
+	ratio      int
+}
+
+// Aggregate aggregates value with time slot of storage interval,
+// true: time slot > end of time range, aggregate completed
+func (agg *rollupAggregator) Aggregate(timeSlot int, value float64) (completed bool) {
+	switch {
+	case timeSlot < agg.start:
+		return false
+	case timeSlot > agg.end:
+		return true
+	default:
+		return agg.PrimitiveAggregator.Aggregate(timeSlot/agg.ratio-agg.start/agg.ratio, value)
+	}
+}
+
 // fieldAggregator implements field aggregator interface, aggregator field series based on aggregator spec
 type fieldAggregator struct {
 	segmentStartTime int64
-	start            int
+	start            int // start index of query interval
+	ratio            int
 
 	aggregateMap map[aggKey]PrimitiveAggregator
 
@@ -112,11 +156,15 @@ type fieldAggregator struct {
 // time range 's start and end is index based on segment start time and interval.
 // e.g. segment start time = 20190905 10:00:00, start = 10, end = 50, interval = 10 seconds,
 // real query time range {20190905 10:01:40 ~ 20190905 10:08:20}
+// the values are rolled up into the index of query interval if the interval ratio > 1,
+// so the result set is based on query interval, e.g. ratio = 30, start = 10 => result start = 0.
 func NewFieldAggregator(segmentStartTime int64, selector selector.SlotSelector, aggSpec AggregatorSpec) FieldAggregator {
 	start, _ := selector.Range()
+	ratio := selector.IntervalRatio()
 	agg := &fieldAggregator{
 		segmentStartTime: segmentStartTime,
-		start:            start,
+		start:            start / ratio,
+		ratio:            ratio,
 		selector:         selector,
 		aggSpec:          aggSpec,
 		aggregateMap:     make(map[aggKey]PrimitiveAggregator),
@@ -159,7 +207,13 @@ func (a *fieldAggregator) Aggregate(it series.FieldIterator) {
 			continue
 		}
 		primitiveFieldID := primitiveIt.FieldID()
-		aggregator := a.getAggregator(primitiveFieldID, primitiveIt.AggType())
+		aggType := primitiveIt.AggType()
+		aggregator := a.getAggregator(primitiveFieldID, aggType)
+		// the values of the field series are rolled up into the index of query interval first,
+		// e.g. counter keeps the last value, then the rolled up value is aggregated with other series
+		rollupFunc := a.aggSpec.FieldType().RollupAggFunc(aggType)
+		rollupIdx := -1
+		rollupValue := 0.0
 		for primitiveIt.HasNext() {
 			timeSlot, value := primitiveIt.Next()
 			idx, completed := a.selector.IndexOf(timeSlot)
@@ -169,7 +223,20 @@ func (a *fieldAggregator) Aggregate(it series.FieldIterator) {
 			if idx < 0 {
 				continue
 			}
-			aggregator.Aggregate(idx, value)
+			// the index of query interval is same as the rollup of down sampling field aggregator
+			idx = timeSlot/a.ratio - a.start
+			if idx == rollupIdx {
+				rollupValue = rollupFunc.AggregateFloat(rollupValue, value)
+				continue
+			}
+			if rollupIdx >= 0 {
+				aggregator.Aggregate(rollupIdx, rollupValue)
+			}
+			rollupIdx = idx
+			rollupValue = value
+		}
+		if rollupIdx >= 0 {
+			aggregator.Aggregate(rollupIdx, rollupValue)
 		}
 	}
 }
@@ -183,8 +250,7 @@ func (a *fieldAggregator) getAggregator(primitiveFieldID uint16, aggType field.A
 	if ok {
 		return agg
 	}
-	start, _ := a.selector.Range()
-	agg = NewPrimitiveAggregator(primitiveFieldID, start, a.selector.PointCount(), aggType.AggFunc())
+	agg = NewPrimitiveAggregator(primitiveFieldID, a.start, a.selector.PointCount(), aggType.AggFunc())
 	a.aggregateMap[key] = agg
 	return agg
 }
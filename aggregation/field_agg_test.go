@@ -81,6 +81,68 @@ func TestFieldAggregator_Aggregate(t *testing.T) {
 	assert.False(t, fieldIt.HasNext())
 }
 
+func TestFieldAggregator_Aggregate_rollup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	baseTime, _ := timeutil.ParseTimestamp("20190729 10:00:00")
+
+	aggSpec := NewAggregatorSpec("f", field.CounterField)
+	aggSpec.AddFunctionType(function.Rate)
+	// storage interval 10s, query interval 1m
+	agg := NewFieldAggregator(baseTime, selector.NewIndexSlotSelector(0, 29, 6), aggSpec)
+	// counter keeps the last cumulative value of each series in query interval, then sums the series
+	agg.Aggregate(MockSumFieldIterator(ctrl, uint16(1), map[int]interface{}{
+		1:  10.0,
+		3:  12.0,
+		7:  15.0,
+		30: 20.0,
+	}))
+	agg.Aggregate(MockSumFieldIterator(ctrl, uint16(1), map[int]interface{}{
+		2: 100.0,
+		5: 110.0,
+	}))
+	_, fieldIt := agg.ResultSet()
+	assert.True(t, fieldIt.HasNext())
+	AssertPrimitiveIt(t, fieldIt.Next(), map[int]float64{
+		0: 122,
+		1: 15,
+	})
+	assert.False(t, fieldIt.HasNext())
+}
+
+func TestFieldAggregator_Aggregate_sameAsDownSampling(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	baseTime, _ := timeutil.ParseTimestamp("20190729 10:00:00")
+
+	aggSpec := NewAggregatorSpec("f", field.GaugeField)
+	aggSpec.AddFunctionType(function.Max)
+	// storage interval 10s, query interval 1m, query time range 10:01:00 ~ 10:04:50
+	values := map[int]float64{5: 1, 6: 2, 8: 3, 13: 4, 20: 5, 29: 6}
+	agg := NewFieldAggregator(baseTime, selector.NewIndexSlotSelector(6, 29, 6), aggSpec)
+	fieldValues := make(map[int]interface{})
+	for slot, value := range values {
+		fieldValues[slot] = value
+	}
+	agg.Aggregate(MockSumFieldIterator(ctrl, uint16(1), fieldValues))
+	_, fieldIt := agg.ResultSet()
+	assert.True(t, fieldIt.HasNext())
+	// result is based on query interval, gauge keeps the last value
+	expect := map[int]float64{1: 3, 2: 4, 3: 5, 4: 6}
+	AssertPrimitiveIt(t, fieldIt.Next(), expect)
+
+	downSampling := NewDownSamplingFieldAggregator(baseTime, selector.NewIndexSlotSelector(6, 29, 6), aggSpec)
+	for _, pAgg := range downSampling.GetAllAggregators() {
+		for slot := 0; slot <= 30; slot++ {
+			if value, ok := values[slot]; ok {
+				pAgg.Aggregate(slot, value)
+			}
+		}
+	}
+	_, fieldIt = downSampling.ResultSet()
+	AssertPrimitiveIt(t, fieldIt.Next(), expect)
+}
+
 func TestDownSamplingFieldAggregator(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -103,3 +165,34 @@ func TestDownSamplingFieldAggregator(t *testing.T) {
 	assert.Equal(t, baseTime, startTime)
 	agg.reset()
 }
+
+func TestDownSamplingFieldAggregator_rollup(t *testing.T) {
+	baseTime, _ := timeutil.ParseTimestamp("20190729 10:00:00")
+	// storage interval 10s, query interval 1m, query time range 10:01:00 ~ 10:05:00
+	rollup := func(fieldType field.Type, values map[int]float64) map[int]float64 {
+		aggSpec := NewAggregatorSpec("f", fieldType)
+		aggSpec.AddFunctionType(fieldType.DownSamplingFunc())
+		agg := NewDownSamplingFieldAggregator(baseTime, selector.NewIndexSlotSelector(6, 30, 6), aggSpec)
+		for _, pAgg := range agg.GetAllAggregators() {
+			for _, slot := range []int{5, 6, 7, 11, 12} {
+				assert.False(t, pAgg.Aggregate(slot, values[slot]))
+			}
+			assert.True(t, pAgg.Aggregate(31, 1))
+		}
+		startTime, fieldIt := agg.ResultSet()
+		assert.Equal(t, baseTime, startTime)
+		result := make(map[int]float64)
+		for fieldIt.HasNext() {
+			primitiveIt := fieldIt.Next()
+			for primitiveIt.HasNext() {
+				slot, value := primitiveIt.Next()
+				result[slot] = value
+			}
+		}
+		return result
+	}
+	values := map[int]float64{5: 1, 6: 5, 7: 4, 11: 3, 12: 2}
+	assert.Equal(t, map[int]float64{1: 12, 2: 2}, rollup(field.SumField, values))
+	assert.Equal(t, map[int]float64{1: 3, 2: 2}, rollup(field.GaugeField, values))
+	assert.Equal(t, map[int]float64{1: 5, 2: 2}, rollup(field.MaxField, values))
+}
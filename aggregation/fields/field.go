@@ -1,6 +1,8 @@
 package fields
 
 import (
+	"sort"
+
 	"github.com/lindb/lindb/aggregation/function"
 	"github.com/lindb/lindb/pkg/collections"
 	"github.com/lindb/lindb/series"
@@ -87,12 +89,19 @@ func (f *dynamicField) Reset() {
 	}
 }
 
-// getFieldValues returns the values by primitive field ids
+// getFieldValues returns the values by primitive field ids, in order of primitive field id
 func (f *dynamicField) getFieldValues(pFields map[uint16]field.AggType) (result []collections.FloatArray) {
 	if len(pFields) == 0 {
 		return
 	}
+	pIDs := make([]uint16, 0, len(pFields))
 	for pID := range pFields {
+		pIDs = append(pIDs, pID)
+	}
+	sort.Slice(pIDs, func(i, j int) bool {
+		return pIDs[i] < pIDs[j]
+	})
+	for _, pID := range pIDs {
 		pField, ok := f.fields[pID]
 		if ok {
 			result = append(result, pField)
@@ -5,12 +5,96 @@ import "github.com/lindb/lindb/pkg/collections"
 // FuncCall calls the function calc by function type and params
 func FuncCall(funcType FuncType, params ...collections.FloatArray) collections.FloatArray {
 	switch funcType {
-	case Sum, Min, Max:
+	case Sum, Min, Max, Count:
 		if len(params) == 0 {
 			return nil
 		}
 		return params[0]
+	case Avg:
+		if len(params) != 2 || params[0] == nil || params[1] == nil {
+			return nil
+		}
+		return avg(params[0], params[1])
+	case MovingAverage:
+		if len(params) != 2 || params[1] == nil || !params[1].HasValue(0) {
+			return nil
+		}
+		return movingAverage(params[0], int(params[1].GetValue(0)))
+	case Rate:
+		if len(params) == 0 || params[0] == nil {
+			return nil
+		}
+		return rate(params[0])
 	default:
 		return nil
 	}
 }
+
+// avg calculates the average by the values of sum and count primitive fields,
+// the slot which has no count is skipped.
+func avg(sum, count collections.FloatArray) collections.FloatArray {
+	capacity := sum.Capacity()
+	result := collections.NewFloatArray(capacity)
+	for i := 0; i < capacity; i++ {
+		if !sum.HasValue(i) || !count.HasValue(i) || count.GetValue(i) == 0 {
+			continue
+		}
+		result.SetValue(i, sum.GetValue(i)/count.GetValue(i))
+	}
+	return result
+}
+
+// movingAverage smooths the values with the trailing window of downsampled slots,
+// the slot which has no value is skipped, so the result has value only if the slot of values has.
+// For the slots at the beginning of values, the window is partial(less than window size),
+// the average is calculated by the slots in the partial window.
+func movingAverage(values collections.FloatArray, window int) collections.FloatArray {
+	if values == nil || values.IsEmpty() || window <= 0 {
+		return nil
+	}
+	capacity := values.Capacity()
+	result := collections.NewFloatArray(capacity)
+	sum := 0.0
+	count := 0
+	for i := 0; i < capacity; i++ {
+		if values.HasValue(i) {
+			sum += values.GetValue(i)
+			count++
+		}
+		// remove the slot which slides out of the window
+		if out := i - window; out >= 0 && values.HasValue(out) {
+			sum -= values.GetValue(out)
+			count--
+		}
+		if values.HasValue(i) {
+			result.SetValue(i, sum/float64(count))
+		}
+	}
+	return result
+}
+
+// rate calculates the increase per slot of the raw cumulative values of counter,
+// the delta between the slot and the previous slot which has value is divided by the slots between them.
+// If the value decreases, the counter is considered reset, so the value itself is the delta from zero.
+// The first slot which has value has no rate, because there is no previous value.
+func rate(values collections.FloatArray) collections.FloatArray {
+	capacity := values.Capacity()
+	result := collections.NewFloatArray(capacity)
+	prev := -1
+	for i := 0; i < capacity; i++ {
+		if !values.HasValue(i) {
+			continue
+		}
+		value := values.GetValue(i)
+		if prev >= 0 {
+			delta := value - values.GetValue(prev)
+			if delta < 0 {
+				// counter reset
+				delta = value
+			}
+			result.SetValue(i, delta/float64(i-prev))
+		}
+		prev = i
+	}
+	return result
+}
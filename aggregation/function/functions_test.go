@@ -24,3 +24,95 @@ func TestFuncCall_Sum(t *testing.T) {
 	result = FuncCall(Sum, array1, array2)
 	assert.Equal(t, array1, result)
 }
+
+func TestFuncCall_Avg(t *testing.T) {
+	assert.Nil(t, FuncCall(Avg, collections.NewFloatArray(10)))
+	assert.Nil(t, FuncCall(Avg, collections.NewFloatArray(10), nil))
+
+	sum := collections.NewFloatArray(4)
+	sum.SetValue(0, 10)
+	sum.SetValue(1, 9)
+	sum.SetValue(3, 5)
+	count := collections.NewFloatArray(4)
+	count.SetValue(0, 4)
+	count.SetValue(1, 0)
+	count.SetValue(2, 1)
+	count.SetValue(3, 2)
+	result := FuncCall(Avg, sum, count)
+	assert.Equal(t, 2.5, result.GetValue(0))
+	// zero count or no sum
+	assert.False(t, result.HasValue(1))
+	assert.False(t, result.HasValue(2))
+	assert.Equal(t, 2.5, result.GetValue(3))
+
+	array := collections.NewFloatArray(10)
+	assert.Equal(t, array, FuncCall(Count, array))
+}
+
+func TestFuncCall_MovingAverage(t *testing.T) {
+	values := collections.NewFloatArray(6)
+	values.SetValue(0, 1)
+	values.SetValue(1, 2)
+	values.SetValue(2, 6)
+	values.SetValue(4, 3)
+	values.SetValue(5, 9)
+	window := collections.NewFloatArray(6)
+	window.SetValue(0, 3)
+	window.SetSingle(true)
+
+	result := FuncCall(MovingAverage, values, window)
+	// partial windows at beginning
+	assert.Equal(t, 1.0, result.GetValue(0))
+	assert.Equal(t, 1.5, result.GetValue(1))
+	// full windows, slot 3 has no value
+	assert.Equal(t, 3.0, result.GetValue(2))
+	assert.False(t, result.HasValue(3))
+	assert.Equal(t, 4.5, result.GetValue(4))
+	assert.Equal(t, 6.0, result.GetValue(5))
+	assert.Equal(t, 5, result.Size())
+
+	// window size 1 returns values as is
+	window.SetValue(0, 1)
+	result = FuncCall(MovingAverage, values, window)
+	assert.Equal(t, values.GetValue(2), result.GetValue(2))
+	assert.Equal(t, values.GetValue(5), result.GetValue(5))
+
+	// invalid params
+	assert.Nil(t, FuncCall(MovingAverage, values))
+	assert.Nil(t, FuncCall(MovingAverage, values, nil))
+	assert.Nil(t, FuncCall(MovingAverage, values, collections.NewFloatArray(6)))
+	assert.Nil(t, FuncCall(MovingAverage, nil, window))
+	assert.Nil(t, FuncCall(MovingAverage, collections.NewFloatArray(6), window))
+	window.SetValue(0, 0)
+	assert.Nil(t, FuncCall(MovingAverage, values, window))
+}
+
+func TestFuncCall_Rate(t *testing.T) {
+	values := collections.NewFloatArray(8)
+	values.SetValue(0, 10)
+	values.SetValue(1, 15)
+	values.SetValue(2, 25)
+	// counter reset
+	values.SetValue(3, 4)
+	values.SetValue(4, 10)
+	// slot 5 has no value
+	values.SetValue(6, 20)
+	values.SetValue(7, 20)
+
+	result := FuncCall(Rate, values)
+	// no previous value
+	assert.False(t, result.HasValue(0))
+	assert.Equal(t, 5.0, result.GetValue(1))
+	assert.Equal(t, 10.0, result.GetValue(2))
+	// delta from zero after reset
+	assert.Equal(t, 4.0, result.GetValue(3))
+	assert.Equal(t, 6.0, result.GetValue(4))
+	assert.False(t, result.HasValue(5))
+	// delta is divided by the slots since previous value
+	assert.Equal(t, 5.0, result.GetValue(6))
+	assert.Equal(t, 0.0, result.GetValue(7))
+	assert.Equal(t, 6, result.Size())
+
+	assert.Nil(t, FuncCall(Rate))
+	assert.Nil(t, FuncCall(Rate, nil))
+}
@@ -11,6 +11,8 @@ const (
 	Avg
 	Histogram
 	Stddev
+	MovingAverage
+	Rate
 
 	Unknown
 )
@@ -32,6 +34,10 @@ func (t FuncType) String() string {
 		return "histogram"
 	case Stddev:
 		return "stddev"
+	case MovingAverage:
+		return "moving_average"
+	case Rate:
+		return "rate"
 	default:
 		return "unknown"
 	}
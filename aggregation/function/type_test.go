@@ -14,5 +14,7 @@ func TestFuncTypeString(t *testing.T) {
 	assert.Equal(t, "avg", Avg.String())
 	assert.Equal(t, "histogram", Histogram.String())
 	assert.Equal(t, "stddev", Stddev.String())
+	assert.Equal(t, "moving_average", MovingAverage.String())
+	assert.Equal(t, "rate", Rate.String())
 	assert.Equal(t, "unknown", Unknown.String())
 }
@@ -1,6 +1,8 @@
 package aggregation
 
 import (
+	"sort"
+
 	"github.com/lindb/lindb/constants"
 	"github.com/lindb/lindb/pkg/timeutil"
 	"github.com/lindb/lindb/series"
@@ -46,22 +48,36 @@ func NewGroupingAggregator(
 
 // Aggregate aggregates the time series data
 func (ga *groupingAggregator) Aggregate(it series.GroupedIterator) {
-	tags := it.Tags()
-	seriesAgg := ga.getAggregator(tags)
+	seriesAgg := ga.getAggregator(it.Tags())
+	aggregateFields(seriesAgg, ga.interval, ga.timeRange, it)
+}
+
+// aggregateFields merges the field series data of time series into the time series aggregator,
+// if the field is not in the aggregator specs, e.g. the field matched by field name regex in storage,
+// the field aggregator is created by the field name and field type of the field series.
+func aggregateFields(
+	seriesAgg *timeSeriesAggregator,
+	interval timeutil.Interval,
+	timeRange timeutil.TimeRange,
+	it series.GroupedIterator,
+) {
 	var sAgg SeriesAggregator
 	for it.HasNext() {
 		seriesIt := it.Next()
 		fieldName := seriesIt.FieldName()
-		// 1. find field aggregator
-		sAgg = nil
-		for _, aggregator := range seriesAgg.aggregator {
-			if aggregator.FieldName() == fieldName {
-				sAgg = aggregator
-				break
-			}
-		}
-		if sAgg == nil {
-			continue
+		// 1. find field aggregator, the field aggregates are sorted by field name
+		aggregates := seriesAgg.aggregator
+		idx := sort.Search(len(aggregates), func(i int) bool {
+			return aggregates[i].FieldName() >= fieldName
+		})
+		if idx < len(aggregates) && aggregates[idx].FieldName() == fieldName {
+			sAgg = aggregates[idx]
+		} else {
+			sAgg = NewSeriesAggregator(interval, 1, timeRange, false, NewAggregatorSpec(fieldName, seriesIt.FieldType()))
+			aggregates = append(aggregates, nil)
+			copy(aggregates[idx+1:], aggregates[idx:])
+			aggregates[idx] = sAgg
+			seriesAgg.aggregator = aggregates
 		}
 		// 2. merge the field series data
 		for seriesIt.HasNext() {
@@ -110,3 +126,69 @@ func (ga *groupingAggregator) getAggregator(tags map[string]string) (agg *timeSe
 	}
 	return
 }
+
+// streamingGroupingAggregator represents a grouping aggregator for high-cardinality group by with bounded memory,
+// the time series must be aggregated in order of group, so that a group is completed when next group arrives,
+// then the completed group is emitted and released, only the aggregator of current group is held in memory.
+type streamingGroupingAggregator struct {
+	aggSpecs  AggregatorSpecs
+	interval  timeutil.Interval
+	timeRange timeutil.TimeRange
+	emit      func(it series.GroupedIterator)
+
+	currentTags string
+	current     *timeSeriesAggregator
+}
+
+// NewStreamingGroupingAggregator creates a streaming grouping aggregator which emits the completed group by emit func,
+// the time series must be ordered by group, otherwise the groups are split into multi results.
+func NewStreamingGroupingAggregator(
+	interval timeutil.Interval,
+	timeRange timeutil.TimeRange,
+	aggSpecs AggregatorSpecs,
+	emit func(it series.GroupedIterator),
+) GroupingAggregator {
+	return &streamingGroupingAggregator{
+		aggSpecs:  aggSpecs,
+		interval:  interval,
+		timeRange: timeRange,
+		emit:      emit,
+	}
+}
+
+// Aggregate aggregates the time series data, emits the current group if the time series belongs to next group
+func (ga *streamingGroupingAggregator) Aggregate(it series.GroupedIterator) {
+	tags := it.Tags()
+	tagsStr := constants.EmptyGroupTagsStr
+	if len(tags) > 0 {
+		tagsStr = tag.Concat(tags)
+	}
+	if ga.current == nil || ga.currentTags != tagsStr {
+		ga.emitCurrent()
+		ga.currentTags = tagsStr
+		ga.current = &timeSeriesAggregator{
+			tags:       tags,
+			aggregator: NewFieldAggregates(ga.interval, 1, ga.timeRange, false, ga.aggSpecs),
+		}
+	}
+	aggregateFields(ga.current, ga.interval, ga.timeRange, it)
+}
+
+// ResultSet returns the result set of the last group which has not been emitted
+func (ga *streamingGroupingAggregator) ResultSet() []series.GroupedIterator {
+	if ga.current == nil {
+		return nil
+	}
+	result := []series.GroupedIterator{ga.current.aggregator.ResultSet(ga.current.tags)}
+	ga.current = nil
+	return result
+}
+
+// emitCurrent emits the completed group, then releases it
+func (ga *streamingGroupingAggregator) emitCurrent() {
+	if ga.current == nil {
+		return
+	}
+	ga.emit(ga.current.aggregator.ResultSet(ga.current.tags))
+	ga.current = nil
+}
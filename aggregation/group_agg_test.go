@@ -1,14 +1,18 @@
 package aggregation
 
 import (
+	"strconv"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/lindb/lindb/aggregation/function"
 	"github.com/lindb/lindb/pkg/timeutil"
 	"github.com/lindb/lindb/series"
 	"github.com/lindb/lindb/series/field"
+	"github.com/lindb/lindb/sql"
+	"github.com/lindb/lindb/sql/stmt"
 )
 
 func TestGroupByAggregator_Aggregate(t *testing.T) {
@@ -47,7 +51,10 @@ func TestGroupByAggregator_Aggregate(t *testing.T) {
 		// series it
 		gIt.EXPECT().HasNext().Return(true),
 		gIt.EXPECT().Next().Return(sIt),
+		// field not in specs, created by field series
 		sIt.EXPECT().FieldName().Return("c"),
+		sIt.EXPECT().FieldType().Return(field.SumField),
+		sIt.EXPECT().HasNext().Return(false),
 
 		gIt.EXPECT().HasNext().Return(false),
 	)
@@ -71,7 +78,10 @@ func TestGroupByAggregator_Aggregate(t *testing.T) {
 		// series it
 		gIt.EXPECT().HasNext().Return(true),
 		gIt.EXPECT().Next().Return(sIt),
+		// field not in specs, created by field series
 		sIt.EXPECT().FieldName().Return("c"),
+		sIt.EXPECT().FieldType().Return(field.SumField),
+		sIt.EXPECT().HasNext().Return(false),
 
 		gIt.EXPECT().HasNext().Return(false),
 	)
@@ -91,3 +101,352 @@ func TestGroupByAggregator_Aggregate(t *testing.T) {
 	assert.Nil(t, rs)
 
 }
+
+// tagsGroupedIterator is a grouped iterator of time series without field data
+type tagsGroupedIterator struct {
+	tags map[string]string
+}
+
+func (it *tagsGroupedIterator) Tags() map[string]string { return it.tags }
+func (it *tagsGroupedIterator) HasNext() bool           { return false }
+func (it *tagsGroupedIterator) Next() series.Iterator   { return nil }
+
+func TestStreamingGroupingAggregator_Aggregate(t *testing.T) {
+	now, _ := timeutil.ParseTimestamp("20190702 19:10:00", "20060102 15:04:05")
+	var emitted []series.GroupedIterator
+	agg := NewStreamingGroupingAggregator(
+		timeutil.Interval(timeutil.OneSecond),
+		timeutil.TimeRange{
+			Start: now,
+			End:   now + 3*timeutil.OneHour,
+		},
+		AggregatorSpecs{NewAggregatorSpec("a", field.SumField)},
+		func(it series.GroupedIterator) {
+			emitted = append(emitted, it)
+		})
+	assert.Nil(t, agg.ResultSet())
+
+	// large group by, series are ordered by group
+	groups, seriesPerGroup := 10000, 3
+	streamingAgg := agg.(*streamingGroupingAggregator)
+	for i := 0; i < groups; i++ {
+		tags := map[string]string{"host": strconv.Itoa(i)}
+		for j := 0; j < seriesPerGroup; j++ {
+			agg.Aggregate(&tagsGroupedIterator{tags: tags})
+			// only the aggregator of current group is held in memory, the completed groups are emitted
+			assert.Len(t, emitted, i)
+			assert.Equal(t, tags, streamingAgg.current.tags)
+		}
+	}
+	rs := agg.ResultSet()
+	assert.Len(t, rs, 1)
+	assert.Nil(t, streamingAgg.current)
+	emitted = append(emitted, rs...)
+	// each group is returned once
+	hosts := make(map[string]struct{})
+	for _, it := range emitted {
+		hosts[it.Tags()["host"]] = struct{}{}
+	}
+	assert.Len(t, emitted, groups)
+	assert.Len(t, hosts, groups)
+}
+
+func TestStreamingGroupingAggregator_merge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now, _ := timeutil.ParseTimestamp("20190702 19:10:00", "20060102 15:04:05")
+	familyTime, _ := timeutil.ParseTimestamp("20190702 19:00:00", "20060102 15:04:05")
+	var emitted []series.GroupedIterator
+	agg := NewStreamingGroupingAggregator(
+		timeutil.Interval(timeutil.OneSecond),
+		timeutil.TimeRange{
+			Start: now,
+			End:   now + 3*timeutil.OneHour,
+		},
+		AggregatorSpecs{NewAggregatorSpec("a", field.SumField)},
+		func(it series.GroupedIterator) {
+			emitted = append(emitted, it)
+		})
+	aggregate := func(tags map[string]string) {
+		gIt := series.NewMockGroupedIterator(ctrl)
+		sIt := series.NewMockIterator(ctrl)
+		fIt := series.NewMockFieldIterator(ctrl)
+		gomock.InOrder(
+			gIt.EXPECT().Tags().Return(tags),
+			gIt.EXPECT().HasNext().Return(true),
+			gIt.EXPECT().Next().Return(sIt),
+			sIt.EXPECT().FieldName().Return("a"),
+			sIt.EXPECT().HasNext().Return(true),
+			sIt.EXPECT().Next().Return(familyTime, fIt),
+			fIt.EXPECT().HasNext().Return(false),
+			sIt.EXPECT().HasNext().Return(false),
+			gIt.EXPECT().HasNext().Return(false),
+		)
+		agg.Aggregate(gIt)
+	}
+	// series of same group are merged
+	aggregate(map[string]string{"host": "1.1.1.1"})
+	aggregate(map[string]string{"host": "1.1.1.1"})
+	assert.Empty(t, emitted)
+	aggregate(map[string]string{"host": "1.1.1.2"})
+	assert.Len(t, emitted, 1)
+	assert.Equal(t, map[string]string{"host": "1.1.1.1"}, emitted[0].Tags())
+	// empty group tags
+	aggregate(nil)
+	assert.Len(t, emitted, 2)
+	assert.Equal(t, map[string]string{"host": "1.1.1.2"}, emitted[1].Tags())
+	rs := agg.ResultSet()
+	assert.Len(t, rs, 1)
+	assert.Nil(t, rs[0].Tags())
+}
+
+func TestGroupingAggregator_functionsPerField(t *testing.T) {
+	familyTime, _ := timeutil.ParseTimestamp("20190702 19:00:00", "20060102 15:04:05")
+	interval := timeutil.Interval(timeutil.OneMinute)
+	timeRange := timeutil.TimeRange{Start: familyTime, End: familyTime + timeutil.OneHour}
+	bytesSpec := NewAggregatorSpec("bytes", field.SumField)
+	bytesSpec.AddFunctionType(function.Sum)
+	latencySpec := NewAggregatorSpec("latency", field.SummaryField)
+	latencySpec.AddFunctionType(function.Avg)
+	aggSpecs := AggregatorSpecs{bytesSpec, latencySpec}
+
+	// down sampling result of a time series, values: field name => primitive field id => value
+	newTimeSeries := func(values map[string]map[uint16]float64) series.GroupedIterator {
+		aggregates := NewFieldAggregates(interval, 1, timeRange, true, aggSpecs)
+		for _, sAgg := range aggregates {
+			fAgg, ok := sAgg.GetAggregator(familyTime)
+			assert.True(t, ok)
+			for _, pAgg := range fAgg.GetAllAggregators() {
+				pAgg.Aggregate(0, values[sAgg.FieldName()][pAgg.FieldID()])
+			}
+		}
+		return aggregates.ResultSet(map[string]string{"host": "1.1.1.1"})
+	}
+
+	// broker creates the field aggregators by the field series returned by storage
+	agg := NewGroupingAggregator(interval, timeRange, nil)
+	agg.Aggregate(newTimeSeries(map[string]map[uint16]float64{
+		"bytes":   {1: 10},
+		"latency": {1: 6, 2: 3},
+	}))
+	agg.Aggregate(newTimeSeries(map[string]map[uint16]float64{
+		"bytes":   {1: 20},
+		"latency": {1: 14, 2: 2},
+	}))
+	rs := agg.ResultSet()
+	assert.Len(t, rs, 1)
+
+	query, err := sql.Parse("select sum(bytes),avg(latency) from cpu group by host")
+	assert.NoError(t, err)
+	selectItems := append(query.SelectItems, &stmt.SelectItem{Expr: &stmt.CallExpr{
+		FuncType: function.Count,
+		Params:   []stmt.Expr{&stmt.FieldExpr{Name: "latency"}},
+	}})
+	expression := NewExpression(timeRange, interval.Int64(), selectItems, query.Fill)
+	expression.Eval(rs[0])
+	resultSet := expression.ResultSet()
+	assert.Len(t, resultSet, 3)
+	// count primitive field of summary
+	assert.Equal(t, 5.0, resultSet["count(latency)"].GetValue(0))
+	// sum of bytes across series
+	assert.Equal(t, 30.0, resultSet["sum(bytes)"].GetValue(0))
+	// total sum / total count of latency across series, not the average of each series' average
+	assert.Equal(t, 4.0, resultSet["avg(latency)"].GetValue(0))
+}
+
+func TestGroupingAggregator_movingAverage(t *testing.T) {
+	familyTime, _ := timeutil.ParseTimestamp("20190702 19:00:00", "20060102 15:04:05")
+	interval := timeutil.Interval(timeutil.OneMinute)
+	timeRange := timeutil.TimeRange{Start: familyTime, End: familyTime + timeutil.OneHour}
+	query, err := sql.Parse("select moving_average(f,2) as ma from cpu group by host")
+	assert.NoError(t, err)
+	aggSpec := NewAggregatorSpec("f", field.SumField)
+	aggSpec.AddFunctionType(function.Sum)
+	aggSpecs := AggregatorSpecs{aggSpec}
+
+	aggregates := NewFieldAggregates(interval, 1, timeRange, true, aggSpecs)
+	fAgg, ok := aggregates[0].GetAggregator(familyTime)
+	assert.True(t, ok)
+	for _, pAgg := range fAgg.GetAllAggregators() {
+		pAgg.Aggregate(0, 10)
+		pAgg.Aggregate(1, 30)
+		pAgg.Aggregate(2, 20)
+	}
+	// broker creates the field aggregators by the field series returned by storage
+	agg := NewGroupingAggregator(interval, timeRange, nil)
+	agg.Aggregate(aggregates.ResultSet(map[string]string{"host": "1.1.1.1"}))
+	rs := agg.ResultSet()
+	assert.Len(t, rs, 1)
+
+	expression := NewExpression(timeRange, interval.Int64(), query.SelectItems, query.Fill)
+	expression.Eval(rs[0])
+	ma := expression.ResultSet()["ma"]
+	assert.Equal(t, 3, ma.Size())
+	// partial window
+	assert.Equal(t, 10.0, ma.GetValue(0))
+	assert.Equal(t, 20.0, ma.GetValue(1))
+	assert.Equal(t, 25.0, ma.GetValue(2))
+}
+
+func TestGroupingAggregator_rate(t *testing.T) {
+	familyTime, _ := timeutil.ParseTimestamp("20190702 19:00:00", "20060102 15:04:05")
+	interval := timeutil.Interval(timeutil.OneMinute)
+	timeRange := timeutil.TimeRange{Start: familyTime, End: familyTime + timeutil.OneHour}
+	query, err := sql.Parse("select rate(f) as r from cpu group by host")
+	assert.NoError(t, err)
+	aggSpec := NewAggregatorSpec("f", field.CounterField)
+	aggSpec.AddFunctionType(function.Rate)
+	aggSpecs := AggregatorSpecs{aggSpec}
+
+	aggregates := NewFieldAggregates(interval, 1, timeRange, true, aggSpecs)
+	fAgg, ok := aggregates[0].GetAggregator(familyTime)
+	assert.True(t, ok)
+	for _, pAgg := range fAgg.GetAllAggregators() {
+		pAgg.Aggregate(0, 10)
+		pAgg.Aggregate(1, 30)
+		// counter reset
+		pAgg.Aggregate(2, 5)
+		pAgg.Aggregate(3, 15)
+	}
+	// broker creates the field aggregators by the field series returned by storage
+	agg := NewGroupingAggregator(interval, timeRange, nil)
+	agg.Aggregate(aggregates.ResultSet(map[string]string{"host": "1.1.1.1"}))
+	rs := agg.ResultSet()
+	assert.Len(t, rs, 1)
+
+	expression := NewExpression(timeRange, interval.Int64(), query.SelectItems, query.Fill)
+	expression.Eval(rs[0])
+	r := expression.ResultSet()["r"]
+	assert.False(t, r.HasValue(0))
+	assert.Equal(t, 20.0, r.GetValue(1))
+	assert.Equal(t, 5.0, r.GetValue(2))
+	assert.Equal(t, 10.0, r.GetValue(3))
+}
+
+func TestGroupingAggregator_fieldRegex(t *testing.T) {
+	familyTime, _ := timeutil.ParseTimestamp("20190702 19:00:00", "20060102 15:04:05")
+	interval := timeutil.Interval(timeutil.OneMinute)
+	timeRange := timeutil.TimeRange{Start: familyTime, End: familyTime + timeutil.OneHour}
+	query, err := sql.Parse("select max(\"/disk_.*/\") as d,cpu from host group by host")
+	assert.NoError(t, err)
+
+	// storage expands the field name regex into the matched fields
+	var aggSpecs AggregatorSpecs
+	for _, fieldName := range []string{"cpu", "disk_tmp", "disk_var"} {
+		aggSpec := NewAggregatorSpec(fieldName, field.MaxField)
+		aggSpec.AddFunctionType(function.Max)
+		aggSpecs = append(aggSpecs, aggSpec)
+	}
+	aggregates := NewFieldAggregates(interval, 1, timeRange, true, aggSpecs)
+	for idx, sAgg := range aggregates {
+		fAgg, ok := sAgg.GetAggregator(familyTime)
+		assert.True(t, ok)
+		for _, pAgg := range fAgg.GetAllAggregators() {
+			pAgg.Aggregate(0, float64(idx+1))
+		}
+	}
+	// broker merges the fields returned by storage
+	agg := NewGroupingAggregator(interval, timeRange, nil)
+	agg.Aggregate(aggregates.ResultSet(map[string]string{"host": "1.1.1.1"}))
+	rs := agg.ResultSet()
+	assert.Len(t, rs, 1)
+
+	expression := NewExpression(timeRange, interval.Int64(), query.SelectItems, query.Fill)
+	expression.Eval(rs[0])
+	resultSet := expression.ResultSet()
+	assert.Len(t, resultSet, 3)
+	assert.Equal(t, 1.0, resultSet["cpu"].GetValue(0))
+	assert.Equal(t, 2.0, resultSet["max(disk_tmp)"].GetValue(0))
+	assert.Equal(t, 3.0, resultSet["max(disk_var)"].GetValue(0))
+}
+
+func TestGroupingAggregator_quantile(t *testing.T) {
+	familyTime, _ := timeutil.ParseTimestamp("20190702 19:00:00", "20060102 15:04:05")
+	interval := timeutil.Interval(timeutil.OneMinute)
+	timeRange := timeutil.TimeRange{Start: familyTime, End: familyTime + timeutil.OneHour}
+
+	// each storage node returns the sketches of quantile field
+	storageResultSet := func(values ...float64) series.GroupedIterator {
+		aggregates := NewFieldAggregates(interval, 1, timeRange, true,
+			AggregatorSpecs{NewAggregatorSpec("latency", field.QuantileField)})
+		fAgg, ok := aggregates[0].GetAggregator(familyTime)
+		assert.True(t, ok)
+		fAgg.(SketchAggregator).AggregateSketch(1, newTestSketch(values...))
+		rs := aggregates.ResultSet(map[string]string{"host": "1.1.1.1"})
+		fields := make(map[string][]byte)
+		for rs.HasNext() {
+			it := rs.Next()
+			data, err := series.MarshalIterator(it)
+			assert.NoError(t, err)
+			fields[it.FieldName()] = data
+		}
+		return series.NewGroupedIterator(rs.Tags(), fields)
+	}
+	var node1, node2 []float64
+	for i := 1; i <= 100; i++ {
+		if i%2 == 0 {
+			node1 = append(node1, float64(i))
+		} else {
+			node2 = append(node2, float64(i))
+		}
+	}
+	// broker merges the sketches, then calculates the quantiles
+	agg := NewGroupingAggregator(interval, timeRange, nil)
+	agg.Aggregate(storageResultSet(node1...))
+	agg.Aggregate(storageResultSet(node2...))
+	rs := agg.ResultSet()
+	assert.Len(t, rs, 1)
+	assert.True(t, rs[0].HasNext())
+	it := rs[0].Next()
+	assert.Equal(t, field.QuantileField, it.FieldType())
+	assert.True(t, it.HasNext())
+	_, fIt := it.Next()
+	for idx, q := range field.Quantiles {
+		assert.True(t, fIt.HasNext())
+		pIt := fIt.Next()
+		assert.Equal(t, uint16(idx+1), pIt.FieldID())
+		assert.True(t, pIt.HasNext())
+		slot, value := pIt.Next()
+		assert.Equal(t, 1, slot)
+		assert.InEpsilon(t, q*100, value, 0.05)
+		assert.False(t, pIt.HasNext())
+	}
+}
+
+func TestGroupingAggregator_avgOfPrimitiveField(t *testing.T) {
+	familyTime, _ := timeutil.ParseTimestamp("20190702 19:00:00", "20060102 15:04:05")
+	interval := timeutil.Interval(timeutil.OneMinute)
+	timeRange := timeutil.TimeRange{Start: familyTime, End: familyTime + timeutil.OneHour}
+	bytesSpec := NewAggregatorSpec("bytes", field.SumField)
+	bytesSpec.AddFunctionType(function.Avg)
+	aggSpecs := AggregatorSpecs{bytesSpec}
+
+	// down sampling result of a time series, values: time slot => value
+	newTimeSeries := func(values map[int]float64) series.GroupedIterator {
+		aggregates := NewFieldAggregates(interval, 1, timeRange, true, aggSpecs)
+		fAgg, ok := aggregates[0].GetAggregator(familyTime)
+		assert.True(t, ok)
+		for slot, value := range values {
+			for _, pAgg := range fAgg.GetAllAggregators() {
+				pAgg.Aggregate(slot, value)
+			}
+		}
+		return aggregates.ResultSet(map[string]string{"host": "1.1.1.1"})
+	}
+
+	agg := NewGroupingAggregator(interval, timeRange, aggSpecs)
+	agg.Aggregate(newTimeSeries(map[int]float64{0: 10, 1: 4}))
+	agg.Aggregate(newTimeSeries(map[int]float64{0: 20}))
+	rs := agg.ResultSet()
+	assert.Len(t, rs, 1)
+
+	query, err := sql.Parse("select avg(bytes) from cpu group by host")
+	assert.NoError(t, err)
+	expression := NewExpression(timeRange, interval.Int64(), query.SelectItems, query.Fill)
+	expression.Eval(rs[0])
+	resultSet := expression.ResultSet()
+	// sum of bytes divided by the count of series which has value in the slot
+	assert.Equal(t, 15.0, resultSet["avg(bytes)"].GetValue(0))
+	assert.Equal(t, 4.0, resultSet["avg(bytes)"].GetValue(1))
+}
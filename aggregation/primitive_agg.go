@@ -76,3 +76,50 @@ func (agg *primitiveAggregator) Aggregate(idx int, value float64) (completed boo
 	}
 	return
 }
+
+// seriesCountAggregator represents the primitive aggregator which counts the series of time slot(index),
+// the slot is marked as 1 if the series has any value in it, the result is merged with other series by sum.
+type seriesCountAggregator struct {
+	start      int
+	values     collections.FloatArray
+	pointCount int
+}
+
+// newSeriesCountAggregator creates the series count aggregator
+func newSeriesCountAggregator(start int, pointCount int) PrimitiveAggregator {
+	return &seriesCountAggregator{
+		start:      start,
+		pointCount: pointCount,
+	}
+}
+
+// FieldID returns the primitive field id of series count
+func (agg *seriesCountAggregator) FieldID() uint16 {
+	return field.SeriesCountFieldID
+}
+
+// Iterator returns an iterator for series count results
+func (agg *seriesCountAggregator) Iterator() series.PrimitiveIterator {
+	return newPrimitiveIterator(field.SeriesCountFieldID, agg.start, field.Sum, agg.values)
+}
+
+func (agg *seriesCountAggregator) reset() {
+	if agg.values != nil {
+		agg.values.Reset()
+	}
+}
+
+// Aggregate marks the time slot(index) which has value, the value is ignored
+func (agg *seriesCountAggregator) Aggregate(idx int, value float64) (completed bool) {
+	if idx < 0 {
+		return
+	}
+	if idx >= agg.pointCount {
+		return true
+	}
+	if agg.values == nil {
+		agg.values = collections.NewFloatArray(agg.pointCount)
+	}
+	agg.values.SetValue(idx, 1)
+	return
+}
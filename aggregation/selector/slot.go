@@ -8,6 +8,8 @@ type SlotSelector interface {
 	IndexOf(timeSlot int) (idx int, completed bool)
 	Range() (start int, end int)
 	PointCount() int
+	// IntervalRatio returns the ratio of query interval to storage interval
+	IntervalRatio() int
 }
 
 // indexSlotSelector represents an index slot selector based on start/ratio
@@ -35,6 +37,10 @@ func (s *indexSlotSelector) PointCount() int {
 	return s.pointCount
 }
 
+func (s *indexSlotSelector) IntervalRatio() int {
+	return s.intervalRatio
+}
+
 // IndexOf returns the index of the specified element in aggregator values
 // index = (timeSlot - start)/ratio, if timeSlot < start return -1
 func (s *indexSlotSelector) IndexOf(timeSlot int) (idx int, completed bool) {
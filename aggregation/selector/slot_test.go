@@ -8,10 +8,11 @@ import (
 
 func TestIndexSlotSelector_IndexOf(t *testing.T) {
 	selector := NewIndexSlotSelector(10, 120, 1)
-	assert.Equal(t, 110, selector.PointCount())
+	assert.Equal(t, 111, selector.PointCount())
 	start, end := selector.Range()
 	assert.Equal(t, 10, start)
 	assert.Equal(t, 120, end)
+	assert.Equal(t, 1, selector.IntervalRatio())
 	idx, completed := selector.IndexOf(5)
 	assert.Equal(t, -1, idx)
 	assert.False(t, completed)
@@ -29,6 +30,8 @@ func TestIndexSlotSelector_IndexOf(t *testing.T) {
 	assert.True(t, completed)
 
 	selector = NewIndexSlotSelector(10, 130, 3)
+	// the index of end slot is within point count
+	assert.Equal(t, 41, selector.PointCount())
 	idx, completed = selector.IndexOf(12)
 	assert.Equal(t, 0, idx)
 	assert.False(t, completed)
@@ -26,6 +26,17 @@ func (agg FieldAggregates) Reset() {
 	}
 }
 
+// SeriesFieldAggregates represents the field aggregates of each time series for group by,
+// key: series id, value: fields aggregates of the time series
+type SeriesFieldAggregates map[uint32]FieldAggregates
+
+// Reset resets the aggregator's context for reusing
+func (agg SeriesFieldAggregates) Reset() {
+	for _, aggregates := range agg {
+		aggregates.Reset()
+	}
+}
+
 // NewFieldAggregates creates the field aggregates based on aggregator specs and query time range.
 // NOTICE: if do down sampling aggregator, aggregator specs must be in order by field id.
 func NewFieldAggregates(
@@ -157,11 +168,14 @@ func (a *seriesAggregator) GetAggregator(segmentStartTime int64) (agg FieldAggre
 		storageInterval := a.queryInterval.Int64() / int64(a.ratio)
 		startIdx := a.calc.CalcSlot(timeRange.Start, segmentStartTime, storageInterval)
 		endIdx := a.calc.CalcSlot(timeRange.End, segmentStartTime, storageInterval)
-		if a.isDownSampling {
+		switch {
+		case a.aggSpec.FieldType() == field.QuantileField:
+			agg = NewSketchFieldAggregator(segmentStartTime, selector.NewIndexSlotSelector(startIdx, endIdx, a.ratio))
+		case a.isDownSampling:
 			agg = NewDownSamplingFieldAggregator(segmentStartTime,
 				selector.NewIndexSlotSelector(startIdx, endIdx, a.ratio),
 				a.aggSpec)
-		} else {
+		default:
 			agg = NewFieldAggregator(segmentStartTime, selector.NewIndexSlotSelector(startIdx, endIdx, a.ratio),
 				a.aggSpec)
 		}
@@ -0,0 +1,120 @@
+package aggregation
+
+import (
+	"github.com/lindb/lindb/aggregation/selector"
+	"github.com/lindb/lindb/pkg/sketch"
+	"github.com/lindb/lindb/series"
+)
+
+// SketchAggregator represents a field aggregator of quantile field, which merges the sketches of field.
+// the sketches in the same query time slot are merged(down sampling),
+// the sketches of multi series are merged by Merge, quantiles are calculated based on the merged sketches.
+type SketchAggregator interface {
+	FieldAggregator
+	// AggregateSketch merges the sketch of storage time slot into current aggregator
+	// true: aggregate completed
+	AggregateSketch(timeSlot int, s *sketch.DDSketch) (completed bool)
+	// Sketches returns the merged sketches by index of query time slot, nil if no data in the slot
+	Sketches() []*sketch.DDSketch
+	// Merge merges the sketches of other aggregator(e.g. aggregator of other series) with same time range
+	Merge(other SketchAggregator)
+}
+
+// sketchFieldAggregator implements sketch aggregator interface
+type sketchFieldAggregator struct {
+	segmentStartTime int64
+	start            int
+	selector         selector.SlotSelector
+
+	sketches []*sketch.DDSketch
+}
+
+// NewSketchFieldAggregator creates a sketch field aggregator for quantile field,
+// time range 's start and end is index based on segment start time and interval,
+// the result set is based on query interval, e.g. ratio = 2, start = 10 => result start = 5.
+func NewSketchFieldAggregator(segmentStartTime int64, selector selector.SlotSelector) SketchAggregator {
+	start, _ := selector.Range()
+	return &sketchFieldAggregator{
+		segmentStartTime: segmentStartTime,
+		start:            start / selector.IntervalRatio(),
+		selector:         selector,
+	}
+}
+
+// AggregateSketch merges the sketch of storage time slot into current aggregator
+func (a *sketchFieldAggregator) AggregateSketch(timeSlot int, s *sketch.DDSketch) (completed bool) {
+	idx, completed := a.selector.IndexOf(timeSlot)
+	if completed || idx < 0 {
+		return
+	}
+	a.mergeSketch(idx, s)
+	return
+}
+
+// Sketches returns the merged sketches by index of query time slot
+func (a *sketchFieldAggregator) Sketches() []*sketch.DDSketch {
+	return a.sketches
+}
+
+// Merge merges the sketches of other aggregator with same time range
+func (a *sketchFieldAggregator) Merge(other SketchAggregator) {
+	for idx, s := range other.Sketches() {
+		if s != nil {
+			a.mergeSketch(idx, s)
+		}
+	}
+}
+
+// mergeSketch merges the sketch into the sketch of query time slot
+func (a *sketchFieldAggregator) mergeSketch(idx int, s *sketch.DDSketch) {
+	if idx >= len(a.sketches) {
+		sketches := make([]*sketch.DDSketch, a.selector.PointCount())
+		if idx >= len(sketches) {
+			sketches = make([]*sketch.DDSketch, idx+1)
+		}
+		copy(sketches, a.sketches)
+		a.sketches = sketches
+	}
+	target := a.sketches[idx]
+	if target == nil {
+		target = sketch.NewDDSketch(s.RelativeAccuracy())
+		a.sketches[idx] = target
+	}
+	// sketches of quantile field are created with same relative accuracy, so merge never fails
+	_ = target.Merge(s)
+}
+
+// Aggregate merges the sketches of field iterator(e.g. the result set of storage nodes on broker),
+// the field iterator of quantile field must be a sketch field iterator, because quantiles cannot be merged again
+func (a *sketchFieldAggregator) Aggregate(it series.FieldIterator) {
+	sketchIt, ok := it.(series.SketchFieldIterator)
+	if !ok {
+		return
+	}
+	startSlot, sketches := sketchIt.Sketches()
+	for idx, s := range sketches {
+		if s == nil {
+			continue
+		}
+		if completed := a.AggregateSketch(startSlot+idx, s); completed {
+			return
+		}
+	}
+}
+
+// GetAllAggregators returns nil, sketches are aggregated by AggregateSketch
+func (a *sketchFieldAggregator) GetAllAggregators() []PrimitiveAggregator {
+	return nil
+}
+
+// ResultSet returns the merged sketches, the quantiles are calculated when iterating the primitive fields,
+// so that the sketches still can be merged after the result set is marshaled, see field.Quantiles
+func (a *sketchFieldAggregator) ResultSet() (startTime int64, it series.FieldIterator) {
+	return a.segmentStartTime, series.NewSketchFieldIterator(a.start, a.sketches)
+}
+
+func (a *sketchFieldAggregator) reset() {
+	for idx := range a.sketches {
+		a.sketches[idx] = nil
+	}
+}
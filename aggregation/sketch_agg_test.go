@@ -0,0 +1,92 @@
+package aggregation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/aggregation/selector"
+	"github.com/lindb/lindb/pkg/sketch"
+	"github.com/lindb/lindb/pkg/timeutil"
+	"github.com/lindb/lindb/series/field"
+)
+
+func newTestSketch(values ...float64) *sketch.DDSketch {
+	s := sketch.NewDDSketch(sketch.DefaultRelativeAccuracy)
+	for _, value := range values {
+		s.Add(value)
+	}
+	return s
+}
+
+func TestSketchFieldAggregator_AggregateSketch(t *testing.T) {
+	baseTime, _ := timeutil.ParseTimestamp("20190729 10:00:00")
+	agg := NewSketchFieldAggregator(baseTime, selector.NewIndexSlotSelector(10, 20, 2))
+	assert.Nil(t, agg.GetAllAggregators())
+
+	assert.False(t, agg.AggregateSketch(5, newTestSketch(1)))
+	assert.False(t, agg.AggregateSketch(10, newTestSketch(1, 2)))
+	assert.False(t, agg.AggregateSketch(11, newTestSketch(3)))
+	assert.False(t, agg.AggregateSketch(14, newTestSketch(4)))
+	assert.True(t, agg.AggregateSketch(21, newTestSketch(5)))
+	// field iterator without sketches is ignored
+	agg.Aggregate(nil)
+
+	sketches := agg.Sketches()
+	assert.Equal(t, uint64(3), sketches[0].Count())
+	assert.Nil(t, sketches[1])
+	assert.Equal(t, uint64(1), sketches[2].Count())
+
+	// merge sketches of other series
+	other := NewSketchFieldAggregator(baseTime, selector.NewIndexSlotSelector(10, 20, 2))
+	other.AggregateSketch(12, newTestSketch(6, 7))
+	other.AggregateSketch(14, newTestSketch(8))
+	agg.Merge(other)
+	sketches = agg.Sketches()
+	assert.Equal(t, uint64(2), sketches[1].Count())
+	assert.Equal(t, uint64(2), sketches[2].Count())
+
+	startTime, it := agg.ResultSet()
+	assert.Equal(t, baseTime, startTime)
+	for idx := range field.Quantiles {
+		assert.True(t, it.HasNext())
+		primitiveIt := it.Next()
+		assert.Equal(t, uint16(idx+1), primitiveIt.FieldID())
+		count := 0
+		for primitiveIt.HasNext() {
+			primitiveIt.Next()
+			count++
+		}
+		assert.Equal(t, 3, count)
+	}
+	assert.False(t, it.HasNext())
+
+	// merge the sketches of result set, e.g. the result sets of storage nodes on broker
+	brokerAgg := NewSketchFieldAggregator(baseTime, selector.NewIndexSlotSelector(5, 10, 1))
+	brokerAgg.Aggregate(it)
+	brokerAgg.Aggregate(it)
+	sketches = brokerAgg.Sketches()
+	assert.Equal(t, uint64(6), sketches[0].Count())
+	assert.Equal(t, uint64(4), sketches[1].Count())
+	assert.Equal(t, uint64(4), sketches[2].Count())
+
+	agg.reset()
+	for _, s := range agg.Sketches() {
+		assert.Nil(t, s)
+	}
+}
+
+func TestSeriesAggregator_quantile(t *testing.T) {
+	familyTime, _ := timeutil.ParseTimestamp("20190702 19:00:00", "20060102 15:04:05")
+	agg := NewSeriesAggregator(
+		timeutil.Interval(timeutil.OneSecond),
+		1,
+		timeutil.TimeRange{Start: familyTime, End: familyTime + timeutil.OneHour},
+		true,
+		NewAggregatorSpec("latency", field.QuantileField),
+	)
+	fAgg, ok := agg.GetAggregator(familyTime)
+	assert.True(t, ok)
+	_, ok = fAgg.(SketchAggregator)
+	assert.True(t, ok)
+}
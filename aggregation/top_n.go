@@ -0,0 +1,230 @@
+package aggregation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/lindb/lindb/aggregation/function"
+	"github.com/lindb/lindb/pkg/timeutil"
+	"github.com/lindb/lindb/series"
+	"github.com/lindb/lindb/series/field"
+	"github.com/lindb/lindb/sql/stmt"
+)
+
+// SeriesOrder represents the order of time series by the aggregated value of field over the time range
+type SeriesOrder struct {
+	FieldName string
+	FuncType  function.FuncType
+	Desc      bool
+}
+
+// NewSeriesOrdersByQuery builds the orders of time series from order by items of query, returns error
+// if the order by expr is not a field or a function call on field, or the field is not in select items.
+func NewSeriesOrdersByQuery(query *stmt.Query) ([]SeriesOrder, error) {
+	if len(query.OrderBy) == 0 {
+		return nil, nil
+	}
+	selectFields := make(map[string]struct{})
+	for _, selectItem := range query.SelectItems {
+		collectFieldNames(selectFields, selectItem)
+	}
+	var orders []SeriesOrder
+	for _, item := range query.OrderBy {
+		order := SeriesOrder{FuncType: function.Sum, Desc: item.Desc}
+		expr := item.Expr
+		if callExpr, ok := expr.(*stmt.CallExpr); ok {
+			if len(callExpr.Params) != 1 {
+				return nil, fmt.Errorf("order by function: %s requires one field", callExpr.FuncType)
+			}
+			switch callExpr.FuncType {
+			case function.Sum, function.Min, function.Max, function.Count, function.Avg:
+			default:
+				return nil, fmt.Errorf("order by function: %s not supported", callExpr.FuncType)
+			}
+			order.FuncType = callExpr.FuncType
+			expr = callExpr.Params[0]
+		}
+		fieldExpr, ok := expr.(*stmt.FieldExpr)
+		if !ok {
+			return nil, fmt.Errorf("order by expr: %s not supported, expect field or function call on field", item.Expr.Rewrite())
+		}
+		if _, ok := selectFields[fieldExpr.Name]; !ok {
+			return nil, fmt.Errorf("order by field: %s not in select items", fieldExpr.Name)
+		}
+		order.FieldName = fieldExpr.Name
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+// collectFieldNames collects the names of fields in the expression
+func collectFieldNames(fieldNames map[string]struct{}, expr stmt.Expr) {
+	switch e := expr.(type) {
+	case *stmt.SelectItem:
+		collectFieldNames(fieldNames, e.Expr)
+	case *stmt.CallExpr:
+		for _, param := range e.Params {
+			collectFieldNames(fieldNames, param)
+		}
+	case *stmt.ParenExpr:
+		collectFieldNames(fieldNames, e.Expr)
+	case *stmt.BinaryExpr:
+		collectFieldNames(fieldNames, e.Left)
+		collectFieldNames(fieldNames, e.Right)
+	case *stmt.FieldExpr:
+		fieldNames[e.Name] = struct{}{}
+	}
+}
+
+// value returns the aggregated value of the order field in time series, false if the field has no data.
+// the values of all the primitive fields are aggregated by order function, except avg is the sum of sum
+// primitive fields divided by the sum of count primitive fields if both exist, or the sum of values divided by
+// the series count for primitive field.
+func (o SeriesOrder) value(it series.GroupedIterator) (float64, bool) {
+	var (
+		result, sumOfSum, sumOfCount, seriesCount float64
+		points                                    int
+	)
+	for it.HasNext() {
+		seriesIt := it.Next()
+		if seriesIt == nil || seriesIt.FieldName() != o.FieldName {
+			continue
+		}
+		for seriesIt.HasNext() {
+			_, fieldIt := seriesIt.Next()
+			if fieldIt == nil {
+				continue
+			}
+			for fieldIt.HasNext() {
+				primitiveIt := fieldIt.Next()
+				if primitiveIt.FieldID() == field.SeriesCountFieldID {
+					for primitiveIt.HasNext() {
+						_, value := primitiveIt.Next()
+						seriesCount += value
+					}
+					continue
+				}
+				aggType := primitiveIt.AggType()
+				for primitiveIt.HasNext() {
+					_, value := primitiveIt.Next()
+					switch {
+					case points == 0:
+						result = value
+					case o.FuncType == function.Min:
+						result = math.Min(result, value)
+					case o.FuncType == function.Max:
+						result = math.Max(result, value)
+					default:
+						result += value
+					}
+					points++
+					switch aggType {
+					case field.Sum:
+						sumOfSum += value
+					case field.Count:
+						sumOfCount += value
+					}
+				}
+			}
+		}
+	}
+	if points == 0 {
+		return 0, false
+	}
+	if o.FuncType == function.Avg {
+		if seriesCount != 0 {
+			return result / seriesCount, true
+		}
+		if sumOfSum != 0 && sumOfCount != 0 {
+			return sumOfSum / sumOfCount, true
+		}
+		return result / float64(points), true
+	}
+	return result, true
+}
+
+// topNGroupingAggregator represents a grouping aggregator which returns the top n time series
+type topNGroupingAggregator struct {
+	*groupingAggregator
+	orders []SeriesOrder
+	limit  int
+}
+
+// NewTopNGroupingAggregator creates a grouping aggregator which returns the top n time series ordered by orders,
+// ties(or no orders) are ordered by tags, the time series without value of order field are ordered last.
+// limit 0 means unlimited, returns the plain grouping aggregator if neither orders nor limit.
+func NewTopNGroupingAggregator(
+	interval timeutil.Interval,
+	timeRange timeutil.TimeRange,
+	aggSpecs AggregatorSpecs,
+	orders []SeriesOrder,
+	limit int,
+) GroupingAggregator {
+	agg := NewGroupingAggregator(interval, timeRange, aggSpecs).(*groupingAggregator)
+	if len(orders) == 0 && limit <= 0 {
+		return agg
+	}
+	return &topNGroupingAggregator{
+		groupingAggregator: agg,
+		orders:             orders,
+		limit:              limit,
+	}
+}
+
+// rankedSeries represents a time series with the values of orders for ranking
+type rankedSeries struct {
+	tagsStr string
+	result  *timeSeriesAggregator
+	values  []float64
+	exists  []bool
+}
+
+// ResultSet returns the top n time series of aggregator in order
+func (ga *topNGroupingAggregator) ResultSet() []series.GroupedIterator {
+	if len(ga.aggregates) == 0 {
+		return nil
+	}
+	ranked := make([]rankedSeries, 0, len(ga.aggregates))
+	for tagsStr, result := range ga.aggregates {
+		rs := rankedSeries{
+			tagsStr: tagsStr,
+			result:  result,
+			values:  make([]float64, len(ga.orders)),
+			exists:  make([]bool, len(ga.orders)),
+		}
+		for idx, order := range ga.orders {
+			// the result set is recreated for each iteration, because the iterator can be iterated once
+			rs.values[idx], rs.exists[idx] = order.value(result.aggregator.ResultSet(result.tags))
+		}
+		ranked = append(ranked, rs)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return ga.less(ranked[i], ranked[j])
+	})
+	if ga.limit > 0 && len(ranked) > ga.limit {
+		ranked = ranked[:ga.limit]
+	}
+	seriesList := make([]series.GroupedIterator, len(ranked))
+	for idx, rs := range ranked {
+		seriesList[idx] = rs.result.aggregator.ResultSet(rs.result.tags)
+	}
+	return seriesList
+}
+
+// less reports whether the time series a is ranked before b
+func (ga *topNGroupingAggregator) less(a, b rankedSeries) bool {
+	for idx, order := range ga.orders {
+		switch {
+		case a.exists[idx] != b.exists[idx]:
+			return a.exists[idx]
+		case !a.exists[idx] || a.values[idx] == b.values[idx]:
+			continue
+		case order.Desc:
+			return a.values[idx] > b.values[idx]
+		default:
+			return a.values[idx] < b.values[idx]
+		}
+	}
+	return a.tagsStr < b.tagsStr
+}
@@ -0,0 +1,198 @@
+package aggregation
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/aggregation/function"
+	"github.com/lindb/lindb/pkg/collections"
+	"github.com/lindb/lindb/pkg/timeutil"
+	"github.com/lindb/lindb/series"
+	"github.com/lindb/lindb/series/field"
+	"github.com/lindb/lindb/sql"
+)
+
+func TestNewSeriesOrdersByQuery(t *testing.T) {
+	q, _ := sql.Parse("select f,max(g) from cpu order by max(g) desc, f")
+	orders, err := NewSeriesOrdersByQuery(q)
+	assert.NoError(t, err)
+	assert.Equal(t, []SeriesOrder{
+		{FieldName: "g", FuncType: function.Max, Desc: true},
+		{FieldName: "f", FuncType: function.Sum},
+	}, orders)
+
+	q, _ = sql.Parse("select f from cpu")
+	orders, err = NewSeriesOrdersByQuery(q)
+	assert.NoError(t, err)
+	assert.Nil(t, orders)
+
+	for _, s := range []string{
+		"select f from cpu order by g",
+		"select f from cpu order by f+1",
+		"select f from cpu order by histogram(f)",
+		"select f from cpu order by sum(f, f)",
+	} {
+		q, err = sql.Parse(s)
+		assert.NoError(t, err, s)
+		_, err = NewSeriesOrdersByQuery(q)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestTopNGroupingAggregator_ResultSet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now, _ := timeutil.ParseTimestamp("20190702 19:10:00", "20060102 15:04:05")
+	familyTime, _ := timeutil.ParseTimestamp("20190702 19:00:00", "20060102 15:04:05")
+	newAgg := func(orders []SeriesOrder, limit int) GroupingAggregator {
+		agg := NewTopNGroupingAggregator(
+			timeutil.Interval(timeutil.OneSecond),
+			timeutil.TimeRange{Start: now, End: now + 3*timeutil.OneHour},
+			AggregatorSpecs{NewAggregatorSpec("f", field.SumField)},
+			orders, limit)
+		aggregate := func(host string, points map[int]interface{}) {
+			gIt := series.NewMockGroupedIterator(ctrl)
+			sIt := series.NewMockIterator(ctrl)
+			gomock.InOrder(
+				gIt.EXPECT().Tags().Return(map[string]string{"host": host}),
+				gIt.EXPECT().HasNext().Return(true),
+				gIt.EXPECT().Next().Return(sIt),
+				sIt.EXPECT().FieldName().Return("f"),
+				sIt.EXPECT().HasNext().Return(true),
+				sIt.EXPECT().Next().Return(familyTime, MockSumFieldIterator(ctrl, uint16(1), points)),
+				sIt.EXPECT().HasNext().Return(false),
+				gIt.EXPECT().HasNext().Return(false),
+			)
+			agg.Aggregate(gIt)
+		}
+		aggregate("a", map[int]interface{}{600: 1.0, 601: 2.0}) // sum 3, max 2
+		aggregate("b", map[int]interface{}{600: 5.0})           // sum 5, max 5
+		aggregate("c", map[int]interface{}{600: 2.5, 601: 0.5}) // sum 3(tie with a), max 2.5
+		aggregate("d", map[int]interface{}{600: 4.0})           // sum 4, max 4
+		// without data of order field
+		agg.Aggregate(&tagsGroupedIterator{tags: map[string]string{"host": "e"}})
+		return agg
+	}
+	hosts := func(rs []series.GroupedIterator) (result []string) {
+		for _, it := range rs {
+			result = append(result, it.Tags()["host"])
+		}
+		return
+	}
+
+	// neither orders nor limit
+	agg := newAgg(nil, 0)
+	assert.IsType(t, &groupingAggregator{}, agg)
+	assert.Len(t, agg.ResultSet(), 5)
+	// limit 0 means unlimited
+	agg = newAgg([]SeriesOrder{{FieldName: "f", FuncType: function.Sum, Desc: true}}, 0)
+	assert.Equal(t, []string{"b", "d", "a", "c", "e"}, hosts(agg.ResultSet()))
+	// top n with ties ordered by tags
+	agg = newAgg([]SeriesOrder{{FieldName: "f", FuncType: function.Sum, Desc: true}}, 3)
+	assert.Equal(t, []string{"b", "d", "a"}, hosts(agg.ResultSet()))
+	agg = newAgg([]SeriesOrder{{FieldName: "f", FuncType: function.Sum}}, 3)
+	assert.Equal(t, []string{"a", "c", "d"}, hosts(agg.ResultSet()))
+	// the result set can be iterated after ranking
+	rs := agg.ResultSet()
+	value, ok := SeriesOrder{FieldName: "f", FuncType: function.Sum}.value(rs[0])
+	assert.True(t, ok)
+	assert.Equal(t, 3.0, value)
+	// multi orders, ties of first order are ordered by next order
+	agg = newAgg([]SeriesOrder{
+		{FieldName: "f", FuncType: function.Sum},
+		{FieldName: "f", FuncType: function.Max, Desc: true},
+	}, 2)
+	assert.Equal(t, []string{"c", "a"}, hosts(agg.ResultSet()))
+	// limit without orders
+	agg = newAgg(nil, 2)
+	assert.Equal(t, []string{"a", "b"}, hosts(agg.ResultSet()))
+	// empty
+	agg = NewTopNGroupingAggregator(timeutil.Interval(timeutil.OneSecond), timeutil.TimeRange{}, nil, nil, 2)
+	assert.Nil(t, agg.ResultSet())
+}
+
+func TestSeriesOrder_value(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	newIt := func(values map[field.AggType][]float64) series.GroupedIterator {
+		fIt := series.NewMockFieldIterator(ctrl)
+		for aggType, points := range values {
+			primitiveIt := series.NewMockPrimitiveIterator(ctrl)
+			fIt.EXPECT().HasNext().Return(true)
+			fIt.EXPECT().Next().Return(primitiveIt)
+			primitiveIt.EXPECT().FieldID().Return(uint16(1))
+			primitiveIt.EXPECT().AggType().Return(aggType)
+			for idx, point := range points {
+				primitiveIt.EXPECT().HasNext().Return(true)
+				primitiveIt.EXPECT().Next().Return(idx, point)
+			}
+			primitiveIt.EXPECT().HasNext().Return(false)
+		}
+		fIt.EXPECT().HasNext().Return(false)
+		sIt := series.NewMockIterator(ctrl)
+		sIt.EXPECT().FieldName().Return("f")
+		sIt.EXPECT().HasNext().Return(true)
+		sIt.EXPECT().Next().Return(int64(0), fIt)
+		sIt.EXPECT().HasNext().Return(true)
+		sIt.EXPECT().Next().Return(int64(0), nil)
+		sIt.EXPECT().HasNext().Return(false)
+		other := series.NewMockIterator(ctrl)
+		other.EXPECT().FieldName().Return("g")
+		gIt := series.NewMockGroupedIterator(ctrl)
+		gomock.InOrder(
+			gIt.EXPECT().HasNext().Return(true),
+			gIt.EXPECT().Next().Return(other),
+			gIt.EXPECT().HasNext().Return(true),
+			gIt.EXPECT().Next().Return(nil),
+			gIt.EXPECT().HasNext().Return(true),
+			gIt.EXPECT().Next().Return(sIt),
+			gIt.EXPECT().HasNext().Return(false),
+		)
+		return gIt
+	}
+	summary := map[field.AggType][]float64{field.Sum: {10, 20}, field.Count: {2, 3}}
+	cases := []struct {
+		funcType function.FuncType
+		values   map[field.AggType][]float64
+		expect   float64
+	}{
+		{function.Sum, map[field.AggType][]float64{field.Sum: {1, 2, 3}}, 6},
+		{function.Count, map[field.AggType][]float64{field.Count: {1, 2, 3}}, 6},
+		{function.Min, map[field.AggType][]float64{field.Min: {3, 1, 2}}, 1},
+		{function.Max, map[field.AggType][]float64{field.Max: {1, 3, 2}}, 3},
+		{function.Avg, map[field.AggType][]float64{field.Max: {1, 3, 2}}, 2},
+		{function.Avg, summary, 6},
+	}
+	for _, c := range cases {
+		value, ok := SeriesOrder{FieldName: "f", FuncType: c.funcType}.value(newIt(c.values))
+		assert.True(t, ok)
+		assert.Equal(t, c.expect, value, c.funcType.String())
+	}
+
+	// avg of primitive field is the sum of values divided by the series count
+	values := collections.NewFloatArray(2)
+	values.SetValue(0, 10)
+	values.SetValue(1, 20)
+	counts := collections.NewFloatArray(2)
+	counts.SetValue(0, 2)
+	counts.SetValue(1, 3)
+	sIt := series.NewMockIterator(ctrl)
+	sIt.EXPECT().FieldName().Return("f")
+	sIt.EXPECT().HasNext().Return(true)
+	sIt.EXPECT().Next().Return(int64(0), newFieldIterator(0, []series.PrimitiveIterator{
+		newPrimitiveIterator(1, 0, field.Sum, values),
+		newPrimitiveIterator(field.SeriesCountFieldID, 0, field.Sum, counts),
+	}))
+	sIt.EXPECT().HasNext().Return(false)
+	gIt := series.NewMockGroupedIterator(ctrl)
+	gIt.EXPECT().HasNext().Return(true)
+	gIt.EXPECT().Next().Return(sIt)
+	gIt.EXPECT().HasNext().Return(false)
+	value, ok := SeriesOrder{FieldName: "f", FuncType: function.Avg}.value(gIt)
+	assert.True(t, ok)
+	assert.Equal(t, 6.0, value)
+}
@@ -0,0 +1,193 @@
+package metric
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lindb/lindb/broker/api"
+	"github.com/lindb/lindb/pkg/timeutil"
+	"github.com/lindb/lindb/rpc/proto/field"
+)
+
+const (
+	// graphiteValueField is the field name of graphite value
+	graphiteValueField = "value"
+	// graphiteWriteBatchSize is the max number of metrics written to channel at once
+	graphiteWriteBatchSize = 1000
+	// graphiteMeasurement is the template part which means the path node is a part of metric name
+	graphiteMeasurement = "measurement"
+	// graphiteMeasurementWildcard is the template part which means the rest path nodes are parts of metric name
+	graphiteMeasurementWildcard = "measurement*"
+)
+
+// GraphitePlaintext writes the metrics of graphite plaintext protocol(newline-delimited request body),
+// each line is written as a metric with a gauge field named value, the metrics are written in batch.
+// the whole path is the metric name by default, or the metric name and tags are extracted by template param,
+// such as template=region.host.measurement*. the malformed lines are skipped and counted in the response.
+// responses bad request if no line is written.
+func (m *WriteAPI) GraphitePlaintext(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	databaseName := params.Get("db")
+	if databaseName == "" {
+		api.BadRequest(w, fmt.Errorf("please input db"))
+		return
+	}
+	parser, err := newGraphiteParser(params.Get("template"), timeutil.Now())
+	if err != nil {
+		api.BadRequest(w, err)
+		return
+	}
+	result := &lineWriteResult{}
+	metrics := make([]*field.Metric, 0, graphiteWriteBatchSize)
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		metric, err := parser.parseLine(line)
+		if err != nil {
+			result.Failed++
+			if len(result.Errors) < maxLineErrors {
+				result.Errors = append(result.Errors, fmt.Sprintf("line %d: %s", lineNum, err))
+			}
+			continue
+		}
+		metrics = append(metrics, metric)
+		if len(metrics) >= graphiteWriteBatchSize {
+			if err := m.cm.Write(&field.MetricList{Database: databaseName, Metrics: metrics}); err != nil {
+				writeError(w, err)
+				return
+			}
+			result.Written += len(metrics)
+			metrics = make([]*field.Metric, 0, graphiteWriteBatchSize)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		api.BadRequest(w, fmt.Errorf("read request body error:%s", err))
+		return
+	}
+	if len(metrics) > 0 {
+		if err := m.cm.Write(&field.MetricList{Database: databaseName, Metrics: metrics}); err != nil {
+			writeError(w, err)
+			return
+		}
+		result.Written += len(metrics)
+	}
+	if result.Written == 0 {
+		api.BadRequest(w, fmt.Errorf("no valid line in request body, failed lines: %d, errors: %v",
+			result.Failed, result.Errors))
+		return
+	}
+	api.OK(w, result)
+}
+
+// graphiteParser parses the metrics from graphite plaintext protocol: metric.path value [timestamp]
+type graphiteParser struct {
+	now      int64    // timestamp(ms) of lines without timestamp or with timestamp -1
+	template []string // parts of template, nil means the whole path is the metric name
+}
+
+// newGraphiteParser creates a plaintext parser with the template separated by dot, each part of template is
+// measurement(the path node is a part of metric name), measurement*(the rest path nodes are parts of metric name),
+// empty(the path node is skipped) or the tag key of path node. returns error if the template is invalid.
+func newGraphiteParser(template string, now int64) (*graphiteParser, error) {
+	p := &graphiteParser{now: now}
+	if template == "" {
+		return p, nil
+	}
+	p.template = strings.Split(template, ".")
+	hasMeasurement := false
+	for idx, part := range p.template {
+		switch part {
+		case graphiteMeasurementWildcard:
+			if idx != len(p.template)-1 {
+				return nil, fmt.Errorf("measurement* must be the last part of template: %s", template)
+			}
+			hasMeasurement = true
+		case graphiteMeasurement:
+			hasMeasurement = true
+		}
+	}
+	if !hasMeasurement {
+		return nil, fmt.Errorf("measurement is required in template: %s", template)
+	}
+	return p, nil
+}
+
+// parseLine parses a line of plaintext protocol into metric, the timestamp in second is converted into millisecond
+func (p *graphiteParser) parseLine(line string) (*field.Metric, error) {
+	sections := strings.Fields(line)
+	if len(sections) < 2 || len(sections) > 3 {
+		return nil, fmt.Errorf("expect metric path, value and optional timestamp separated by space")
+	}
+	metric, err := p.parsePath(sections[0])
+	if err != nil {
+		return nil, err
+	}
+	value, err := strconv.ParseFloat(sections[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value: %s", sections[1])
+	}
+	metric.Fields = []*field.Field{{
+		Name:  graphiteValueField,
+		Field: &field.Field_Gauge{Gauge: &field.Gauge{Value: value}},
+	}}
+	metric.Timestamp = p.now
+	if len(sections) == 3 {
+		timestamp, err := strconv.ParseFloat(sections[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp: %s", sections[2])
+		}
+		// -1 means now
+		if timestamp != -1 {
+			metric.Timestamp = int64(timestamp * 1000)
+		}
+	}
+	return metric, nil
+}
+
+// parsePath parses the metric name and tags from the metric path by template,
+// the path nodes beyond the template are ignored.
+func (p *graphiteParser) parsePath(path string) (*field.Metric, error) {
+	nodes := strings.Split(path, ".")
+	for _, node := range nodes {
+		if node == "" {
+			return nil, fmt.Errorf("invalid metric path: %s", path)
+		}
+	}
+	if p.template == nil {
+		return &field.Metric{Name: path}, nil
+	}
+	metric := &field.Metric{}
+	var names []string
+	for idx, part := range p.template {
+		if idx >= len(nodes) {
+			break
+		}
+		switch part {
+		case "":
+			// skip the path node
+		case graphiteMeasurement:
+			names = append(names, nodes[idx])
+		case graphiteMeasurementWildcard:
+			names = append(names, nodes[idx:]...)
+		default:
+			if metric.Tags == nil {
+				metric.Tags = make(map[string]string)
+			}
+			metric.Tags[part] = nodes[idx]
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no measurement in metric path: %s by template", path)
+	}
+	metric.Name = strings.Join(names, ".")
+	return metric, nil
+}
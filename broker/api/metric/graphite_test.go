@@ -0,0 +1,172 @@
+package metric
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/replication"
+	"github.com/lindb/lindb/rpc/proto/field"
+)
+
+func TestWriteAPI_GraphitePlaintext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cm := replication.NewMockChannelManager(ctrl)
+	api := NewWriteAPI(cm)
+	doWrite := func(url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		resp := httptest.NewRecorder()
+		api.GraphitePlaintext(resp, req)
+		return resp
+	}
+	body := "sh.host1.cpu.usage 1.5 1564300800\n" +
+		"\n" +
+		"sh.host1.mem.used 100 -1\n" +
+		"sh.host1.disk.free abc 1564300800\n"
+
+	// param error
+	resp := doWrite("/metric/graphite/write", body)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	// invalid template
+	resp = doWrite("/metric/graphite/write?db=dal&template=region.host", body)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	// all lines malformed
+	resp = doWrite("/metric/graphite/write?db=dal", "cpu\ncpu abc\n")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "failed lines: 2")
+	// line too long
+	resp = doWrite("/metric/graphite/write?db=dal", "cpu "+strings.Repeat("1", 2*1024*1024))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	// write error
+	cm.EXPECT().Write(gomock.Any()).Return(errors.New("err"))
+	resp = doWrite("/metric/graphite/write?db=dal", body)
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	// write ok with template, malformed line is skipped
+	var metricList *field.MetricList
+	cm.EXPECT().Write(gomock.Any()).DoAndReturn(func(list *field.MetricList) error {
+		metricList = list
+		return nil
+	})
+	resp = doWrite("/metric/graphite/write?db=dal&template=region.host.measurement*", body)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	result := &lineWriteResult{}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), result))
+	assert.Equal(t, 2, result.Written)
+	assert.Equal(t, 1, result.Failed)
+	assert.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0], "line 4")
+
+	assert.Equal(t, "dal", metricList.Database)
+	assert.Len(t, metricList.Metrics, 2)
+	cpu := metricList.Metrics[0]
+	assert.Equal(t, "cpu.usage", cpu.Name)
+	assert.Equal(t, int64(1564300800000), cpu.Timestamp)
+	assert.Equal(t, map[string]string{"region": "sh", "host": "host1"}, cpu.Tags)
+	assert.Equal(t, "value", cpu.Fields[0].Name)
+	assert.Equal(t, 1.5, cpu.Fields[0].GetGauge().Value)
+	assert.True(t, metricList.Metrics[1].Timestamp > 0)
+}
+
+func TestWriteAPI_GraphitePlaintext_batch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cm := replication.NewMockChannelManager(ctrl)
+	api := NewWriteAPI(cm)
+	var lines []string
+	for i := 0; i < 2*graphiteWriteBatchSize+10; i++ {
+		lines = append(lines, fmt.Sprintf("host-%d.cpu.usage %d 1564300800", i, i))
+	}
+	body := strings.Join(lines, "\n")
+	// metrics are written in batch
+	var batches []int
+	cm.EXPECT().Write(gomock.Any()).DoAndReturn(func(list *field.MetricList) error {
+		batches = append(batches, len(list.Metrics))
+		return nil
+	}).Times(3)
+	resp := httptest.NewRecorder()
+	api.GraphitePlaintext(resp, httptest.NewRequest(http.MethodPost, "/metric/graphite/write?db=dal",
+		strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, []int{graphiteWriteBatchSize, graphiteWriteBatchSize, 10}, batches)
+
+	// write batch error
+	cm.EXPECT().Write(gomock.Any()).Return(errors.New("err"))
+	resp = httptest.NewRecorder()
+	api.GraphitePlaintext(resp, httptest.NewRequest(http.MethodPost, "/metric/graphite/write?db=dal",
+		strings.NewReader(body)))
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+}
+
+func TestGraphiteParser_parseLine(t *testing.T) {
+	now := int64(1564300800000)
+	parser, err := newGraphiteParser("", now)
+	assert.NoError(t, err)
+
+	// whole path as metric name
+	metric, err := parser.parseLine("sh.host1.cpu.usage  -1.5e2\t1564300800.5")
+	assert.NoError(t, err)
+	assert.Equal(t, "sh.host1.cpu.usage", metric.Name)
+	assert.Nil(t, metric.Tags)
+	assert.Equal(t, -150.0, metric.Fields[0].GetGauge().Value)
+	assert.Equal(t, now+500, metric.Timestamp)
+	// timestamp -1 uses now
+	metric, err = parser.parseLine("cpu.usage 1 -1")
+	assert.NoError(t, err)
+	assert.Equal(t, now, metric.Timestamp)
+	// missing timestamp uses now
+	metric, err = parser.parseLine("cpu.usage 1")
+	assert.NoError(t, err)
+	assert.Equal(t, now, metric.Timestamp)
+
+	// template extraction
+	cases := []struct {
+		template string
+		path     string
+		name     string
+		tags     map[string]string
+	}{
+		{"region.host.measurement*", "sh.host1.cpu.usage", "cpu.usage", map[string]string{"region": "sh", "host": "host1"}},
+		{"measurement.host.measurement", "cpu.host1.usage", "cpu.usage", map[string]string{"host": "host1"}},
+		{".host.measurement", "sh.host1.cpu.usage", "cpu", map[string]string{"host": "host1"}},
+		{"host.measurement.region", "host1.cpu", "cpu", map[string]string{"host": "host1"}},
+		{"measurement*", "cpu.usage", "cpu.usage", nil},
+	}
+	for _, c := range cases {
+		parser, err = newGraphiteParser(c.template, now)
+		assert.NoError(t, err, c.template)
+		metric, err = parser.parseLine(c.path + " 1 1564300800")
+		assert.NoError(t, err, c.template)
+		assert.Equal(t, c.name, metric.Name, c.template)
+		assert.Equal(t, c.tags, metric.Tags, c.template)
+	}
+
+	// invalid templates
+	for _, template := range []string{"host", "measurement*.host", "."} {
+		_, err = newGraphiteParser(template, now)
+		assert.Error(t, err, template)
+	}
+
+	// malformed lines
+	parser, _ = newGraphiteParser("host.region.measurement", now)
+	for _, line := range []string{
+		"cpu",
+		"cpu 1 1564300800 1",
+		"host1.sh.cpu abc",
+		"host1.sh.cpu 1 abc",
+		"host1..cpu 1",
+		"host1.sh 1",
+	} {
+		_, err = parser.parseLine(line)
+		assert.Error(t, err, line)
+	}
+}
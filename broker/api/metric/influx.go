@@ -0,0 +1,262 @@
+package metric
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lindb/lindb/broker/api"
+	"github.com/lindb/lindb/pkg/timeutil"
+	"github.com/lindb/lindb/rpc/proto/field"
+)
+
+// maxLineErrors is the max number of line errors returned in the response of line protocol writing
+const maxLineErrors = 10
+
+// lineWriteResult represents the result of writing metrics in line based protocol, such as influxdb and graphite
+type lineWriteResult struct {
+	Written int      `json:"written"`
+	Failed  int      `json:"failed"`
+	Errors  []string `json:"errors,omitempty"` // first errors of the failed lines
+}
+
+// InfluxLineProtocol writes the metrics of influxdb line protocol request body into the database,
+// the malformed lines are skipped and counted in the response, others are still written.
+// responses bad request if no line is written.
+// the params are read from url query even for POST method like influxdb, because the body is not form.
+func (m *WriteAPI) InfluxLineProtocol(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	databaseName := params.Get("db")
+	if databaseName == "" {
+		api.BadRequest(w, fmt.Errorf("please input db"))
+		return
+	}
+	precision := params.Get("precision")
+	if precision == "" {
+		precision = "ns"
+	}
+	fieldType := params.Get("fieldType")
+	if fieldType == "" {
+		fieldType = "gauge"
+	}
+	parser, err := newInfluxParser(precision, fieldType, timeutil.Now())
+	if err != nil {
+		api.BadRequest(w, err)
+		return
+	}
+	metricList, result, err := parser.parse(databaseName, r.Body)
+	if err != nil {
+		api.BadRequest(w, err)
+		return
+	}
+	if len(metricList.Metrics) == 0 {
+		api.BadRequest(w, fmt.Errorf("no valid line in request body, failed lines: %d, errors: %v",
+			result.Failed, result.Errors))
+		return
+	}
+	if err := m.cm.Write(metricList); err != nil {
+		writeError(w, err)
+		return
+	}
+	api.OK(w, result)
+}
+
+// influxParser parses the metrics from influxdb line protocol:
+// measurement[,tag_key=tag_value...] field_key=field_value[,field_key=field_value...] [timestamp]
+type influxParser struct {
+	now       int64 // timestamp(ms) of lines without timestamp
+	precision int64 // divisor(negative means multiplier) converting the timestamp into millisecond
+	newField  func(name string, value float64) *field.Field
+}
+
+// newInfluxParser creates a line protocol parser with the timestamp precision(ns/us/ms/s) and
+// the field type(gauge/sum) of numeric fields
+func newInfluxParser(precision, fieldType string, now int64) (*influxParser, error) {
+	p := &influxParser{now: now}
+	switch precision {
+	case "ns", "n":
+		p.precision = 1000 * 1000
+	case "us", "u":
+		p.precision = 1000
+	case "ms":
+		p.precision = 1
+	case "s":
+		p.precision = -1000
+	default:
+		return nil, fmt.Errorf("unknown timestamp precision: %s", precision)
+	}
+	newField, err := newFieldFunc(fieldType)
+	if err != nil {
+		return nil, err
+	}
+	p.newField = newField
+	return p, nil
+}
+
+// newFieldFunc returns the function creating the numeric field of field type(gauge/sum)
+func newFieldFunc(fieldType string) (func(name string, value float64) *field.Field, error) {
+	switch fieldType {
+	case "gauge":
+		return func(name string, value float64) *field.Field {
+			return &field.Field{Name: name, Field: &field.Field_Gauge{Gauge: &field.Gauge{Value: value}}}
+		}, nil
+	case "sum":
+		return func(name string, value float64) *field.Field {
+			return &field.Field{Name: name, Field: &field.Field_Sum{Sum: &field.Sum{Value: value}}}
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported field type: %s", fieldType)
+	}
+}
+
+// parse parses all the lines of reader into metric list, the malformed lines are counted in result
+func (p *influxParser) parse(databaseName string, reader io.Reader) (*field.MetricList, *lineWriteResult, error) {
+	metricList := &field.MetricList{Database: databaseName}
+	result := &lineWriteResult{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		// skip empty line and comment
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		metric, err := p.parseLine(line)
+		if err != nil {
+			result.Failed++
+			if len(result.Errors) < maxLineErrors {
+				result.Errors = append(result.Errors, fmt.Sprintf("line %d: %s", lineNum, err))
+			}
+			continue
+		}
+		metricList.Metrics = append(metricList.Metrics, metric)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("read request body error:%s", err)
+	}
+	result.Written = len(metricList.Metrics)
+	return metricList, result, nil
+}
+
+// parseLine parses a line of line protocol into metric, non-numeric fields are ignored
+func (p *influxParser) parseLine(line string) (*field.Metric, error) {
+	sections := splitEscaped(line, ' ', true)
+	if len(sections) < 2 || len(sections) > 3 {
+		return nil, fmt.Errorf("expect measurement, fields and optional timestamp separated by space")
+	}
+	metric := &field.Metric{}
+	// measurement and tags
+	keys := splitEscaped(sections[0], ',', false)
+	metric.Name = unescape(keys[0])
+	if metric.Name == "" {
+		return nil, fmt.Errorf("measurement is required")
+	}
+	for _, tag := range keys[1:] {
+		kv := splitEscaped(tag, '=', false)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid tag: %s", tag)
+		}
+		if metric.Tags == nil {
+			metric.Tags = make(map[string]string)
+		}
+		metric.Tags[unescape(kv[0])] = unescape(kv[1])
+	}
+	// fields
+	for _, f := range splitEscaped(sections[1], ',', true) {
+		kv := splitEscaped(f, '=', true)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid field: %s", f)
+		}
+		value, numeric, err := parseFieldValue(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of field: %s, error:%s", kv[0], err)
+		}
+		if numeric {
+			metric.Fields = append(metric.Fields, p.newField(unescape(kv[0]), value))
+		}
+	}
+	if len(metric.Fields) == 0 {
+		return nil, fmt.Errorf("at least one numeric field is required")
+	}
+	// timestamp
+	metric.Timestamp = p.now
+	if len(sections) == 3 {
+		timestamp, err := strconv.ParseInt(sections[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp: %s", sections[2])
+		}
+		if p.precision > 0 {
+			metric.Timestamp = timestamp / p.precision
+		} else {
+			metric.Timestamp = timestamp * -p.precision
+		}
+	}
+	return metric, nil
+}
+
+// parseFieldValue parses the field value, returns false if the value is string or boolean
+func parseFieldValue(value string) (float64, bool, error) {
+	switch {
+	case value[0] == '"':
+		if len(value) < 2 || value[len(value)-1] != '"' {
+			return 0, false, fmt.Errorf("unterminated string")
+		}
+		return 0, false, nil
+	case value == "t" || value == "T" || value == "true" || value == "True" || value == "TRUE" ||
+		value == "f" || value == "F" || value == "false" || value == "False" || value == "FALSE":
+		return 0, false, nil
+	case value[len(value)-1] == 'i':
+		v, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+		return float64(v), err == nil, err
+	case value[len(value)-1] == 'u':
+		v, err := strconv.ParseUint(value[:len(value)-1], 10, 64)
+		return float64(v), err == nil, err
+	default:
+		v, err := strconv.ParseFloat(value, 64)
+		return v, err == nil, err
+	}
+}
+
+// splitEscaped splits the string by the separator which is not escaped by backslash,
+// the separator within double quotes is ignored if quoted is true.
+func splitEscaped(s string, sep byte, quoted bool) []string {
+	var parts []string
+	inQuote := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			// skip the escaped char
+			i++
+		case quoted && s[i] == '"':
+			inQuote = !inQuote
+		case s[i] == sep && !inQuote:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unescape removes the backslash of escaped comma, equal sign and space
+func unescape(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case ',', '=', ' ', '\\', '"':
+				i++
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
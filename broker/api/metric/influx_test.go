@@ -0,0 +1,166 @@
+package metric
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/replication"
+	"github.com/lindb/lindb/rpc/proto/field"
+)
+
+func TestWriteAPI_InfluxLineProtocol(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cm := replication.NewMockChannelManager(ctrl)
+	api := NewWriteAPI(cm)
+	doWrite := func(url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		resp := httptest.NewRecorder()
+		api.InfluxLineProtocol(resp, req)
+		return resp
+	}
+	body := "cpu,host=1.1.1.1 usage=1.5,idle=98i 1564300800000000000\n" +
+		"\n" +
+		"# comment\n" +
+		"mem,host=1.1.1.1 used=100u\n" +
+		"disk,host=1.1.1.1 free=abc 1564300800000000000\n"
+
+	// param error
+	resp := doWrite("/metric/influx/write", body)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	// unknown precision
+	resp = doWrite("/metric/influx/write?db=dal&precision=h", body)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	// unknown field type
+	resp = doWrite("/metric/influx/write?db=dal&fieldType=histogram", body)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	// all lines malformed
+	resp = doWrite("/metric/influx/write?db=dal", "cpu\ncpu usage=\n")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "failed lines: 2")
+	// write error
+	cm.EXPECT().Write(gomock.Any()).Return(errors.New("err"))
+	resp = doWrite("/metric/influx/write?db=dal", body)
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	// write ok, malformed line is skipped
+	var metricList *field.MetricList
+	cm.EXPECT().Write(gomock.Any()).DoAndReturn(func(list *field.MetricList) error {
+		metricList = list
+		return nil
+	})
+	resp = doWrite("/metric/influx/write?db=dal", body)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	result := &lineWriteResult{}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), result))
+	assert.Equal(t, 2, result.Written)
+	assert.Equal(t, 1, result.Failed)
+	assert.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0], "line 5")
+
+	assert.Equal(t, "dal", metricList.Database)
+	assert.Len(t, metricList.Metrics, 2)
+	cpu := metricList.Metrics[0]
+	assert.Equal(t, "cpu", cpu.Name)
+	assert.Equal(t, int64(1564300800000), cpu.Timestamp)
+	assert.Equal(t, map[string]string{"host": "1.1.1.1"}, cpu.Tags)
+	assert.Equal(t, "usage", cpu.Fields[0].Name)
+	assert.Equal(t, 1.5, cpu.Fields[0].GetGauge().Value)
+	assert.Equal(t, 98.0, cpu.Fields[1].GetGauge().Value)
+	mem := metricList.Metrics[1]
+	assert.True(t, mem.Timestamp > 0)
+	assert.Equal(t, 100.0, mem.Fields[0].GetGauge().Value)
+
+	// sum field with second precision
+	cm.EXPECT().Write(gomock.Any()).DoAndReturn(func(list *field.MetricList) error {
+		metricList = list
+		return nil
+	})
+	resp = doWrite("/metric/influx/write?db=dal&precision=s&fieldType=sum", "cpu usage=1 1564300800")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, int64(1564300800000), metricList.Metrics[0].Timestamp)
+	assert.Equal(t, 1.0, metricList.Metrics[0].Fields[0].GetSum().Value)
+}
+
+func TestInfluxParser_parseLine(t *testing.T) {
+	now := int64(1564300800000)
+	parser, err := newInfluxParser("ms", "gauge", now)
+	assert.NoError(t, err)
+
+	// escaped comma and space in measurement, tags and field key, string value with space and comma
+	metric, err := parser.parseLine(`disk\ io,path=/tmp\,a,dc\=x=sh\ 1 read\ bytes=1,msg="a b,c=d",ok=true 1564300800001`)
+	assert.NoError(t, err)
+	assert.Equal(t, "disk io", metric.Name)
+	assert.Equal(t, map[string]string{"path": "/tmp,a", "dc=x": "sh 1"}, metric.Tags)
+	assert.Len(t, metric.Fields, 1)
+	assert.Equal(t, "read bytes", metric.Fields[0].Name)
+	assert.Equal(t, int64(1564300800001), metric.Timestamp)
+
+	// missing timestamp uses now
+	metric, err = parser.parseLine(`cpu usage=-1.5e2`)
+	assert.NoError(t, err)
+	assert.Equal(t, now, metric.Timestamp)
+	assert.Nil(t, metric.Tags)
+	assert.Equal(t, -150.0, metric.Fields[0].GetGauge().Value)
+
+	// precision
+	for precision, timestamp := range map[string]string{
+		"ns": "1564300800000000000", "n": "1564300800000000000",
+		"us": "1564300800000000", "u": "1564300800000000",
+		"ms": "1564300800000", "s": "1564300800",
+	} {
+		parser, err = newInfluxParser(precision, "sum", now)
+		assert.NoError(t, err)
+		metric, err = parser.parseLine("cpu usage=1 " + timestamp)
+		assert.NoError(t, err)
+		assert.Equal(t, now, metric.Timestamp, precision)
+		assert.Equal(t, 1.0, metric.Fields[0].GetSum().Value)
+	}
+
+	// malformed lines
+	for _, line := range []string{
+		"cpu",
+		"cpu usage=1 1564300800000 1",
+		",host=a usage=1",
+		"cpu,host usage=1",
+		"cpu,host= usage=1",
+		"cpu usage",
+		"cpu usage=",
+		"cpu =1",
+		"cpu usage=abc",
+		"cpu usage=1.5i",
+		"cpu usage=-1u",
+		`cpu msg="abc`,
+		`cpu msg="abc",ok=false`,
+		"cpu usage=1 abc",
+	} {
+		_, err = parser.parseLine(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestInfluxParser_parse(t *testing.T) {
+	parser, _ := newInfluxParser("ns", "gauge", 1)
+	var lines []string
+	for i := 0; i < maxLineErrors+5; i++ {
+		lines = append(lines, "cpu")
+	}
+	lines = append(lines, "cpu usage=1\r")
+	metricList, result, err := parser.parse("dal", strings.NewReader(strings.Join(lines, "\n")))
+	assert.NoError(t, err)
+	assert.Len(t, metricList.Metrics, 1)
+	assert.Equal(t, 1, result.Written)
+	assert.Equal(t, maxLineErrors+5, result.Failed)
+	assert.Len(t, result.Errors, maxLineErrors)
+
+	// line too long
+	_, _, err = parser.parse("dal", strings.NewReader("cpu usage="+strings.Repeat("1", 2*1024*1024)))
+	assert.Error(t, err)
+}
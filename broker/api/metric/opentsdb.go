@@ -0,0 +1,184 @@
+package metric
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/lindb/lindb/broker/api"
+	"github.com/lindb/lindb/rpc/proto/field"
+)
+
+const (
+	// openTSDBDefaultField is the default field name of data point value
+	openTSDBDefaultField = "value"
+	// openTSDBMaxSecondTimestamp is the max timestamp in second, the timestamp greater than it is in millisecond
+	openTSDBMaxSecondTimestamp = 9999999999
+)
+
+// openTSDBDataPoint is the data point of opentsdb put request
+type openTSDBDataPoint struct {
+	Metric    string            `json:"metric"`
+	Timestamp int64             `json:"timestamp"`
+	Value     json.RawMessage   `json:"value"` // number or numeric string
+	Tags      map[string]string `json:"tags"`
+}
+
+// openTSDBPutError is the error of a failed data point, returned with details param
+type openTSDBPutError struct {
+	DataPoint json.RawMessage `json:"datapoint"`
+	Error     string          `json:"error"`
+}
+
+// openTSDBPutResult is the result of opentsdb put request, returned with details param
+type openTSDBPutResult struct {
+	Success int                `json:"success"`
+	Failed  int                `json:"failed"`
+	Errors  []openTSDBPutError `json:"errors"` // first errors of the failed data points
+}
+
+// OpenTSDBPut writes the data points of opentsdb put request(single object or array in json) into the database,
+// each data point is written as a metric with a numeric field named by fieldName param(default value).
+// the invalid data points such as missing tags or with unknown keys are skipped, others are still written.
+// responses the success/failure counts with details param like opentsdb, otherwise no content if all written,
+// responses bad request if any data point failed without details param or no data point is written.
+func (m *WriteAPI) OpenTSDBPut(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	databaseName := params.Get("db")
+	if databaseName == "" {
+		api.BadRequest(w, fmt.Errorf("please input db"))
+		return
+	}
+	fieldName := params.Get("fieldName")
+	if fieldName == "" {
+		fieldName = openTSDBDefaultField
+	}
+	fieldType := params.Get("fieldType")
+	if fieldType == "" {
+		fieldType = "gauge"
+	}
+	newField, err := newFieldFunc(fieldType)
+	if err != nil {
+		api.BadRequest(w, err)
+		return
+	}
+	parser := &openTSDBParser{fieldName: fieldName, newField: newField}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		api.BadRequest(w, fmt.Errorf("read request body error:%s", err))
+		return
+	}
+	dataPoints, err := splitOpenTSDBDataPoints(body)
+	if err != nil {
+		api.BadRequest(w, err)
+		return
+	}
+	metricList := &field.MetricList{Database: databaseName}
+	result := &openTSDBPutResult{Errors: []openTSDBPutError{}}
+	for _, data := range dataPoints {
+		metric, err := parser.parse(data)
+		if err != nil {
+			result.Failed++
+			if len(result.Errors) < maxLineErrors {
+				result.Errors = append(result.Errors, openTSDBPutError{DataPoint: data, Error: err.Error()})
+			}
+			continue
+		}
+		metricList.Metrics = append(metricList.Metrics, metric)
+	}
+	result.Success = len(metricList.Metrics)
+	if result.Success == 0 {
+		api.BadRequest(w, fmt.Errorf("no valid data point in request body, failed data points: %d", result.Failed))
+		return
+	}
+	if err := m.cm.Write(metricList); err != nil {
+		writeError(w, err)
+		return
+	}
+	_, details := params["details"]
+	switch {
+	case details:
+		api.OK(w, result)
+	case result.Failed > 0:
+		api.BadRequest(w, fmt.Errorf("%d data points had errors, please use details param for more info", result.Failed))
+	default:
+		api.NoContent(w)
+	}
+}
+
+// splitOpenTSDBDataPoints splits the request body into the encoded data points, the body is a single object or array
+func splitOpenTSDBDataPoints(body []byte) ([]json.RawMessage, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, fmt.Errorf("request body cannot be empty")
+	}
+	if body[0] != '[' {
+		return []json.RawMessage{body}, nil
+	}
+	var dataPoints []json.RawMessage
+	if err := json.Unmarshal(body, &dataPoints); err != nil {
+		return nil, fmt.Errorf("malformed json of request body:%s", err)
+	}
+	return dataPoints, nil
+}
+
+// openTSDBParser parses the data points of opentsdb put request into metrics
+type openTSDBParser struct {
+	fieldName string // field name of data point value
+	newField  func(name string, value float64) *field.Field
+}
+
+// parse parses the data point into metric with a numeric field holding the value,
+// the timestamp in second is converted into millisecond. returns error if the data point has unknown keys.
+func (p *openTSDBParser) parse(data []byte) (*field.Metric, error) {
+	dataPoint := &openTSDBDataPoint{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dataPoint); err != nil {
+		return nil, fmt.Errorf("invalid data point:%s", err)
+	}
+	if dataPoint.Metric == "" {
+		return nil, fmt.Errorf("metric name is required")
+	}
+	if dataPoint.Timestamp <= 0 {
+		return nil, fmt.Errorf("timestamp is required")
+	}
+	if len(dataPoint.Tags) == 0 {
+		return nil, fmt.Errorf("at least one tag is required")
+	}
+	value, err := parseOpenTSDBValue(dataPoint.Value)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := dataPoint.Timestamp
+	if timestamp <= openTSDBMaxSecondTimestamp {
+		timestamp *= 1000
+	}
+	return &field.Metric{
+		Name:      dataPoint.Metric,
+		Timestamp: timestamp,
+		Tags:      dataPoint.Tags,
+		Fields:    []*field.Field{p.newField(p.fieldName, value)},
+	}, nil
+}
+
+// parseOpenTSDBValue parses the value of data point, which is a number or numeric string
+func parseOpenTSDBValue(data json.RawMessage) (float64, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return 0, fmt.Errorf("value is required")
+	}
+	var value float64
+	if err := json.Unmarshal(data, &value); err == nil {
+		return value, nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		if value, err := strconv.ParseFloat(str, 64); err == nil {
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid value: %s", data)
+}
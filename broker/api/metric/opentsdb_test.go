@@ -0,0 +1,131 @@
+package metric
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/replication"
+	"github.com/lindb/lindb/rpc/proto/field"
+)
+
+func TestWriteAPI_OpenTSDBPut(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cm := replication.NewMockChannelManager(ctrl)
+	api := NewWriteAPI(cm)
+	doWrite := func(url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		resp := httptest.NewRecorder()
+		api.OpenTSDBPut(resp, req)
+		return resp
+	}
+	var metricList *field.MetricList
+	captureWrite := func() {
+		cm.EXPECT().Write(gomock.Any()).DoAndReturn(func(list *field.MetricList) error {
+			metricList = list
+			return nil
+		})
+	}
+	single := `{"metric":"sys.cpu","timestamp":1564300800,"value":1.5,"tags":{"host":"1.1.1.1"}}`
+	array := `[
+		{"metric":"sys.cpu","timestamp":1564300800000,"value":"2.5","tags":{"host":"1.1.1.1"}},
+		{"metric":"sys.mem","timestamp":1564300800,"value":100,"tags":{}},
+		{"metric":"sys.disk","timestamp":1564300800,"value":1,"tags":{"host":"1.1.1.1"},"unknown":1}
+	]`
+
+	// param error
+	resp := doWrite("/metric/opentsdb/put", single)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	// unknown field type
+	resp = doWrite("/metric/opentsdb/put?db=dal&fieldType=histogram", single)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	// empty body
+	resp = doWrite("/metric/opentsdb/put?db=dal", " ")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	// malformed array
+	resp = doWrite("/metric/opentsdb/put?db=dal", "[{")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	// malformed object
+	resp = doWrite("/metric/opentsdb/put?db=dal", "{")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "failed data points: 1")
+	// write error
+	cm.EXPECT().Write(gomock.Any()).Return(errors.New("err"))
+	resp = doWrite("/metric/opentsdb/put?db=dal", single)
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+
+	// single object
+	captureWrite()
+	resp = doWrite("/metric/opentsdb/put?db=dal", single)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "dal", metricList.Database)
+	assert.Len(t, metricList.Metrics, 1)
+	cpu := metricList.Metrics[0]
+	assert.Equal(t, "sys.cpu", cpu.Name)
+	assert.Equal(t, int64(1564300800000), cpu.Timestamp)
+	assert.Equal(t, map[string]string{"host": "1.1.1.1"}, cpu.Tags)
+	assert.Equal(t, "value", cpu.Fields[0].Name)
+	assert.Equal(t, 1.5, cpu.Fields[0].GetGauge().Value)
+
+	// array without details, the data point missing tags or with unknown key is failed
+	captureWrite()
+	resp = doWrite("/metric/opentsdb/put?db=dal&fieldName=f&fieldType=sum", array)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "2 data points had errors")
+	assert.Len(t, metricList.Metrics, 1)
+	assert.Equal(t, int64(1564300800000), metricList.Metrics[0].Timestamp)
+	assert.Equal(t, "f", metricList.Metrics[0].Fields[0].Name)
+	assert.Equal(t, 2.5, metricList.Metrics[0].Fields[0].GetSum().Value)
+
+	// array with details
+	captureWrite()
+	resp = doWrite("/metric/opentsdb/put?db=dal&details", array)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	result := &openTSDBPutResult{}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), result))
+	assert.Equal(t, 1, result.Success)
+	assert.Equal(t, 2, result.Failed)
+	assert.Len(t, result.Errors, 2)
+	assert.Equal(t, "at least one tag is required", result.Errors[0].Error)
+	assert.Contains(t, string(result.Errors[0].DataPoint), "sys.mem")
+	assert.Contains(t, result.Errors[1].Error, "unknown")
+}
+
+func TestOpenTSDBParser_parse(t *testing.T) {
+	newField, err := newFieldFunc("gauge")
+	assert.NoError(t, err)
+	parser := &openTSDBParser{fieldName: "value", newField: newField}
+
+	metric, err := parser.parse([]byte(`{"metric":"sys.cpu","timestamp":9999999999,"value":-1e2,"tags":{"host":"a"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9999999999000), metric.Timestamp)
+	assert.Equal(t, -100.0, metric.Fields[0].GetGauge().Value)
+	// timestamp in millisecond
+	metric, err = parser.parse([]byte(`{"metric":"sys.cpu","timestamp":10000000000,"value":"1","tags":{"host":"a"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10000000000), metric.Timestamp)
+
+	// invalid data points
+	for _, data := range []string{
+		`[]`,
+		`{"metric":"sys.cpu","timestamp":1564300800,"value":1,"tags":{"host":"a"},"extra":"x"}`,
+		`{"timestamp":1564300800,"value":1,"tags":{"host":"a"}}`,
+		`{"metric":"sys.cpu","value":1,"tags":{"host":"a"}}`,
+		`{"metric":"sys.cpu","timestamp":1564300800,"value":1}`,
+		`{"metric":"sys.cpu","timestamp":1564300800,"tags":{"host":"a"}}`,
+		`{"metric":"sys.cpu","timestamp":1564300800,"value":null,"tags":{"host":"a"}}`,
+		`{"metric":"sys.cpu","timestamp":1564300800,"value":"abc","tags":{"host":"a"}}`,
+		`{"metric":"sys.cpu","timestamp":1564300800,"value":true,"tags":{"host":"a"}}`,
+	} {
+		_, err = parser.parse([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
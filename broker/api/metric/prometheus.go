@@ -0,0 +1,197 @@
+package metric
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+
+	"github.com/lindb/lindb/broker/api"
+	"github.com/lindb/lindb/rpc/proto/field"
+)
+
+const (
+	// promMetricNameLabel is the label of metric name in prometheus
+	promMetricNameLabel = "__name__"
+	// promValueField is the field name of sample value
+	promValueField = "value"
+	// promWriteBatchSize is the max number of metrics written to channel at once
+	promWriteBatchSize = 1000
+)
+
+// promLabel is the label of prometheus remote write request(prompb.Label)
+type promLabel struct {
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3"`
+	Value string `protobuf:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *promLabel) Reset()         { *m = promLabel{} }
+func (m *promLabel) String() string { return proto.CompactTextString(m) }
+func (*promLabel) ProtoMessage()    {}
+
+// promSample is the sample of prometheus remote write request(prompb.Sample)
+type promSample struct {
+	Value     float64 `protobuf:"fixed64,1,opt,name=value,proto3"`
+	Timestamp int64   `protobuf:"varint,2,opt,name=timestamp,proto3"`
+}
+
+func (m *promSample) Reset()         { *m = promSample{} }
+func (m *promSample) String() string { return proto.CompactTextString(m) }
+func (*promSample) ProtoMessage()    {}
+
+// promTimeSeries is the time series of prometheus remote write request(prompb.TimeSeries)
+type promTimeSeries struct {
+	Labels  []*promLabel  `protobuf:"bytes,1,rep,name=labels,proto3"`
+	Samples []*promSample `protobuf:"bytes,2,rep,name=samples,proto3"`
+}
+
+func (m *promTimeSeries) Reset()         { *m = promTimeSeries{} }
+func (m *promTimeSeries) String() string { return proto.CompactTextString(m) }
+func (*promTimeSeries) ProtoMessage()    {}
+
+// PrometheusRemoteWrite writes the metrics of prometheus remote write request(snappy-compressed protobuf),
+// each sample is written as a metric with a gauge field named value, the metrics are written in batch
+// while decoding the time series one by one, instead of building all the metrics of the request at once.
+// responses bad request if the body cannot be decompressed or decoded.
+func (m *WriteAPI) PrometheusRemoteWrite(w http.ResponseWriter, r *http.Request) {
+	databaseName := r.URL.Query().Get("db")
+	if databaseName == "" {
+		api.BadRequest(w, fmt.Errorf("please input db"))
+		return
+	}
+	compressed, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		api.BadRequest(w, fmt.Errorf("read request body error:%s", err))
+		return
+	}
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		api.BadRequest(w, fmt.Errorf("decompress request body error:%s", err))
+		return
+	}
+	reader := &promWriteRequestReader{data: data}
+	metrics := make([]*field.Metric, 0, promWriteBatchSize)
+	timeSeries := &promTimeSeries{}
+	for {
+		seriesData, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			api.BadRequest(w, err)
+			return
+		}
+		if err := proto.Unmarshal(seriesData, timeSeries); err != nil {
+			api.BadRequest(w, fmt.Errorf("decode time series error:%s", err))
+			return
+		}
+		metrics = appendPromMetrics(metrics, timeSeries)
+		if len(metrics) >= promWriteBatchSize {
+			if err := m.cm.Write(&field.MetricList{Database: databaseName, Metrics: metrics}); err != nil {
+				writeError(w, err)
+				return
+			}
+			metrics = make([]*field.Metric, 0, promWriteBatchSize)
+		}
+	}
+	if len(metrics) > 0 {
+		if err := m.cm.Write(&field.MetricList{Database: databaseName, Metrics: metrics}); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	api.NoContent(w)
+}
+
+// appendPromMetrics appends a metric per sample of time series, the series without metric name is ignored
+func appendPromMetrics(metrics []*field.Metric, timeSeries *promTimeSeries) []*field.Metric {
+	var metricName string
+	tags := make(map[string]string, len(timeSeries.Labels))
+	for _, label := range timeSeries.Labels {
+		if label.Name == promMetricNameLabel {
+			metricName = label.Value
+			continue
+		}
+		tags[label.Name] = label.Value
+	}
+	if metricName == "" {
+		return metrics
+	}
+	for _, sample := range timeSeries.Samples {
+		metrics = append(metrics, &field.Metric{
+			Name:      metricName,
+			Timestamp: sample.Timestamp,
+			Tags:      tags,
+			Fields: []*field.Field{{
+				Name:  promValueField,
+				Field: &field.Field_Gauge{Gauge: &field.Gauge{Value: sample.Value}},
+			}},
+		})
+	}
+	return metrics
+}
+
+// promWriteRequestReader reads the time series of prometheus remote write request(prompb.WriteRequest) one by one,
+// the other fields such as metadata are skipped.
+type promWriteRequestReader struct {
+	data []byte
+}
+
+// Next returns the encoded time series, returns io.EOF if no more time series
+func (r *promWriteRequestReader) Next() ([]byte, error) {
+	for len(r.data) > 0 {
+		key, err := r.readUvarint()
+		if err != nil {
+			return nil, err
+		}
+		fieldNum, wireType := key>>3, key&0x7
+		var value []byte
+		switch wireType {
+		case proto.WireVarint:
+			_, err = r.readUvarint()
+		case proto.WireFixed64:
+			_, err = r.readFixed(8)
+		case proto.WireBytes:
+			var length uint64
+			if length, err = r.readUvarint(); err == nil {
+				value, err = r.readFixed(length)
+			}
+		case proto.WireFixed32:
+			_, err = r.readFixed(4)
+		default:
+			err = fmt.Errorf("unsupported wire type: %d of write request", wireType)
+		}
+		if err != nil {
+			return nil, err
+		}
+		// timeseries = 1
+		if fieldNum == 1 && wireType == proto.WireBytes {
+			return value, nil
+		}
+	}
+	return nil, io.EOF
+}
+
+// readUvarint reads an uvarint from data
+func (r *promWriteRequestReader) readUvarint() (uint64, error) {
+	value, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		return 0, fmt.Errorf("corrupted varint of write request")
+	}
+	r.data = r.data[n:]
+	return value, nil
+}
+
+// readFixed reads fixed length bytes from data
+func (r *promWriteRequestReader) readFixed(length uint64) ([]byte, error) {
+	if uint64(len(r.data)) < length {
+		return nil, fmt.Errorf("unexpected end of write request")
+	}
+	value := r.data[:length]
+	r.data = r.data[length:]
+	return value, nil
+}
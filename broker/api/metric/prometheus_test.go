@@ -0,0 +1,171 @@
+package metric
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/replication"
+	"github.com/lindb/lindb/rpc/proto/field"
+)
+
+// promWriteRequest is the prometheus remote write request(prompb.WriteRequest) for building payload
+type promWriteRequest struct {
+	Timeseries []*promTimeSeries `protobuf:"bytes,1,rep,name=timeseries,proto3"`
+	// metadata of prometheus 2.x, which is skipped
+	Metadata []*promLabel `protobuf:"bytes,3,rep,name=metadata,proto3"`
+}
+
+func (m *promWriteRequest) Reset()         { *m = promWriteRequest{} }
+func (m *promWriteRequest) String() string { return proto.CompactTextString(m) }
+func (*promWriteRequest) ProtoMessage()    {}
+
+func newPromPayload(t *testing.T, req *promWriteRequest) []byte {
+	data, err := proto.Marshal(req)
+	assert.NoError(t, err)
+	return snappy.Encode(nil, data)
+}
+
+func TestWriteAPI_PrometheusRemoteWrite(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cm := replication.NewMockChannelManager(ctrl)
+	api := NewWriteAPI(cm)
+	doWrite := func(url string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		resp := httptest.NewRecorder()
+		api.PrometheusRemoteWrite(resp, req)
+		return resp
+	}
+	payload := newPromPayload(t, &promWriteRequest{
+		Timeseries: []*promTimeSeries{
+			{
+				Labels: []*promLabel{{Name: "__name__", Value: "cpu_usage"}, {Name: "host", Value: "1.1.1.1"}},
+				Samples: []*promSample{
+					{Value: 1.5, Timestamp: 1564300800000},
+					{Value: 2.5, Timestamp: 1564300810000},
+				},
+			},
+			{
+				// without metric name
+				Labels:  []*promLabel{{Name: "host", Value: "1.1.1.1"}},
+				Samples: []*promSample{{Value: 1, Timestamp: 1564300800000}},
+			},
+			{
+				Labels:  []*promLabel{{Name: "__name__", Value: "up"}},
+				Samples: []*promSample{{Value: 1, Timestamp: 1564300800000}},
+			},
+		},
+		Metadata: []*promLabel{{Name: "cpu_usage", Value: "gauge"}},
+	})
+
+	// param error
+	resp := doWrite("/metric/prometheus/write", payload)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	// not snappy
+	resp = doWrite("/metric/prometheus/write?db=dal", []byte("abc"))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "decompress")
+	// corrupted time series
+	resp = doWrite("/metric/prometheus/write?db=dal", snappy.Encode(nil, []byte{0x0a, 0x02, 0xff, 0xff}))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	// corrupted write request
+	resp = doWrite("/metric/prometheus/write?db=dal", snappy.Encode(nil, []byte{0x0a, 0x10}))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	// write error
+	cm.EXPECT().Write(gomock.Any()).Return(errors.New("err"))
+	resp = doWrite("/metric/prometheus/write?db=dal", payload)
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	// write ok
+	var metricList *field.MetricList
+	cm.EXPECT().Write(gomock.Any()).DoAndReturn(func(list *field.MetricList) error {
+		metricList = list
+		return nil
+	})
+	resp = doWrite("/metric/prometheus/write?db=dal", payload)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "dal", metricList.Database)
+	assert.Len(t, metricList.Metrics, 3)
+	cpu := metricList.Metrics[1]
+	assert.Equal(t, "cpu_usage", cpu.Name)
+	assert.Equal(t, int64(1564300810000), cpu.Timestamp)
+	assert.Equal(t, map[string]string{"host": "1.1.1.1"}, cpu.Tags)
+	assert.Equal(t, "value", cpu.Fields[0].Name)
+	assert.Equal(t, 2.5, cpu.Fields[0].GetGauge().Value)
+	assert.Equal(t, "up", metricList.Metrics[2].Name)
+	// empty request
+	resp = doWrite("/metric/prometheus/write?db=dal", newPromPayload(t, &promWriteRequest{}))
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+}
+
+func TestWriteAPI_PrometheusRemoteWrite_batch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cm := replication.NewMockChannelManager(ctrl)
+	api := NewWriteAPI(cm)
+	req := &promWriteRequest{}
+	for i := 0; i < 2*promWriteBatchSize+10; i++ {
+		req.Timeseries = append(req.Timeseries, &promTimeSeries{
+			Labels:  []*promLabel{{Name: "__name__", Value: "cpu_usage"}, {Name: "host", Value: fmt.Sprintf("host-%d", i)}},
+			Samples: []*promSample{{Value: float64(i), Timestamp: 1564300800000}},
+		})
+	}
+	// metrics are written in batch
+	var batches []int
+	cm.EXPECT().Write(gomock.Any()).DoAndReturn(func(list *field.MetricList) error {
+		batches = append(batches, len(list.Metrics))
+		return nil
+	}).Times(3)
+	resp := httptest.NewRecorder()
+	api.PrometheusRemoteWrite(resp, httptest.NewRequest(http.MethodPost, "/metric/prometheus/write?db=dal",
+		bytes.NewReader(newPromPayload(t, req))))
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, []int{promWriteBatchSize, promWriteBatchSize, 10}, batches)
+
+	// write batch error
+	cm.EXPECT().Write(gomock.Any()).Return(errors.New("err"))
+	resp = httptest.NewRecorder()
+	api.PrometheusRemoteWrite(resp, httptest.NewRequest(http.MethodPost, "/metric/prometheus/write?db=dal",
+		bytes.NewReader(newPromPayload(t, req))))
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+}
+
+func TestPromWriteRequestReader_Next(t *testing.T) {
+	// skip the fields of all wire types
+	reader := &promWriteRequestReader{data: []byte{
+		0x10, 0x01, // varint
+		0x19, 1, 2, 3, 4, 5, 6, 7, 8, // fixed64
+		0x1d, 1, 2, 3, 4, // fixed32
+		0x0a, 0x01, 0x05, // time series
+	}}
+	data, err := reader.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x05}, data)
+	_, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+
+	// corrupted data
+	for _, corrupted := range [][]byte{
+		{0x80},
+		{0x0b},
+		{0x19, 1, 2},
+		{0x1d, 1, 2},
+		{0x0a, 0x80},
+	} {
+		reader = &promWriteRequestReader{data: corrupted}
+		_, err = reader.Next()
+		assert.Error(t, err)
+		assert.NotEqual(t, io.EOF, err)
+	}
+}
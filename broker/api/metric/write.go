@@ -1,66 +1,133 @@
 package metric
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
-	"strconv"
+
+	"github.com/golang/protobuf/jsonpb"
 
 	"github.com/lindb/lindb/broker/api"
-	"github.com/lindb/lindb/pkg/timeutil"
 	"github.com/lindb/lindb/replication"
 	"github.com/lindb/lindb/rpc/proto/field"
 )
 
+// WriteAPI represents the api of writing metrics in json
 type WriteAPI struct {
 	cm replication.ChannelManager
 }
 
+// NewWriteAPI creates the write api
 func NewWriteAPI(cm replication.ChannelManager) *WriteAPI {
 	return &WriteAPI{
 		cm: cm,
 	}
 }
 
-func (m *WriteAPI) Sum(w http.ResponseWriter, r *http.Request) {
+// writeRequest represents the metrics written in json,
+// the default tags of request are merged into the tags of each metric.
+type writeRequest struct {
+	Tags    map[string]string `json:"tags,omitempty"` // default tags of all the metrics
+	Metrics []json.RawMessage `json:"metrics"`
+}
+
+// Write writes the metrics of json request body into the database,
+// the default tags of request are attached to each metric, the tags of metric win on conflict.
+// responses bad request if the body is malformed or any metric is invalid.
+func (m *WriteAPI) Write(w http.ResponseWriter, r *http.Request) {
 	databaseName, err := api.GetParamsFromRequest("db", r, "", true)
 	if err != nil {
-		api.Error(w, err)
+		api.BadRequest(w, err)
+		return
+	}
+	metricList, err := parseMetricList(databaseName, r.Body)
+	if err != nil {
+		api.BadRequest(w, err)
+		return
+	}
+	if err := m.cm.Write(metricList); err != nil {
+		writeError(w, err)
 		return
 	}
-	c, _ := api.GetParamsFromRequest("c", r, "10", false)
-	//count := 40000
-	count1, _ := strconv.ParseInt(c, 10, 64)
-	count := int(count1)
-	var err2 error
-	//count := 12500
-	for i := 0; i < count; i++ {
-		var metrics []*field.Metric
-		for j := 0; j < 4; j++ {
-			for k := 0; k < 20; k++ {
-				metric := &field.Metric{
-					Name:      "cpu",
-					Timestamp: timeutil.Now(),
-					Fields: []*field.Field{
-						{Name: "f1", Field: &field.Field_Sum{Sum: &field.Sum{
-							Value: 1.0,
-						}}},
-					},
-					Tags: map[string]string{"host": "1.1.1." + strconv.Itoa(i), "disk": "/tmp" + strconv.Itoa(j), "partition": "partition" + strconv.Itoa(k)},
-				}
-				metrics = append(metrics, metric)
-			}
+	api.OK(w, "ok")
+}
+
+// writeError responses the error of writing metrics, the in-flight window full is responded as too many requests,
+// so that the client can retry later.
+func writeError(w http.ResponseWriter, err error) {
+	if err == replication.ErrInFlightWindowFull {
+		api.TooManyRequests(w, err)
+		return
+	}
+	api.Error(w, err)
+}
+
+// parseMetricList parses the metric list from json, then merges the default tags into each metric
+func parseMetricList(databaseName string, reader io.Reader) (*field.MetricList, error) {
+	req := &writeRequest{}
+	if err := json.NewDecoder(reader).Decode(req); err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("request body cannot be empty")
 		}
-		//TODO mock data for test
-		metricList := &field.MetricList{
-			Database: databaseName,
-			Metrics:  metrics,
+		return nil, fmt.Errorf("malformed json of request body:%s", err)
+	}
+	if len(req.Metrics) == 0 {
+		return nil, fmt.Errorf("metrics cannot be empty")
+	}
+	unmarshaler := &jsonpb.Unmarshaler{}
+	metrics := make([]*field.Metric, len(req.Metrics))
+	for idx, data := range req.Metrics {
+		metric := &field.Metric{}
+		if err := unmarshaler.Unmarshal(bytes.NewReader(data), metric); err != nil {
+			return nil, fmt.Errorf("parse metric[%d] error:%s", idx, err)
 		}
-		if e := m.cm.Write(metricList); e != nil {
-			err2 = e
+		if err := validateMetric(metric); err != nil {
+			return nil, fmt.Errorf("invalid metric[%d]:%s", idx, err)
 		}
+		mergeDefaultTags(metric, req.Tags)
+		metrics[idx] = metric
+	}
+	return &field.MetricList{
+		Database: databaseName,
+		Metrics:  metrics,
+	}, nil
+}
+
+// validateMetric checks the required name, timestamp and fields of metric
+func validateMetric(metric *field.Metric) error {
+	if metric.Name == "" {
+		return fmt.Errorf("metric name is required")
 	}
-	if err2 != nil {
-		api.Error(w, err2)
+	if metric.Timestamp <= 0 {
+		return fmt.Errorf("timestamp is required")
+	}
+	if len(metric.Fields) == 0 {
+		return fmt.Errorf("at least one field is required")
+	}
+	for idx, f := range metric.Fields {
+		if f.Name == "" {
+			return fmt.Errorf("name of field[%d] is required", idx)
+		}
+		if f.Field == nil {
+			return fmt.Errorf("value of field[%d] is required", idx)
+		}
+	}
+	return nil
+}
+
+// mergeDefaultTags merges the default tags into the tags of metric, keeps the tag value of metric on conflict
+func mergeDefaultTags(metric *field.Metric, defaultTags map[string]string) {
+	if len(defaultTags) == 0 {
 		return
 	}
-	api.OK(w, "ok")
+	if metric.Tags == nil {
+		metric.Tags = make(map[string]string, len(defaultTags))
+	}
+	for tagKey, tagValue := range defaultTags {
+		if _, ok := metric.Tags[tagKey]; !ok {
+			metric.Tags[tagKey] = tagValue
+		}
+	}
 }
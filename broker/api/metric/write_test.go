@@ -1,44 +1,136 @@
 package metric
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
 
 	"github.com/lindb/lindb/mock"
 	"github.com/lindb/lindb/replication"
+	"github.com/lindb/lindb/rpc/proto/field"
 )
 
-func TestWriteAPI_Sum(t *testing.T) {
+func TestWriteAPI_Write(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	cm := replication.NewMockChannelManager(ctrl)
 	api := NewWriteAPI(cm)
+	body := json.RawMessage(`{"tags":{"dc":"sh","host":"default"},"metrics":[` +
+		`{"name":"cpu","timestamp":1564300800000,"tags":{"host":"1.1.1.1"},"fields":[{"name":"f1","sum":{"value":1}}]},` +
+		`{"name":"mem","timestamp":1564300800000,"fields":[{"name":"f1","gauge":{"value":2}}]}]}`)
 	// param error
 	mock.DoRequest(t, &mock.HTTPHandler{
 		Method:         http.MethodPut,
-		URL:            "/metric/sum",
-		HandlerFunc:    api.Sum,
-		ExpectHTTPCode: 500,
+		URL:            "/metric/write",
+		RequestBody:    body,
+		HandlerFunc:    api.Write,
+		ExpectHTTPCode: 400,
 	})
-
+	// body error
+	mock.DoRequest(t, &mock.HTTPHandler{
+		Method:         http.MethodPut,
+		URL:            "/metric/write?db=dal",
+		RequestBody:    json.RawMessage(`{"metrics":[{"name":1}]}`),
+		HandlerFunc:    api.Write,
+		ExpectHTTPCode: 400,
+	})
+	// write error
 	cm.EXPECT().Write(gomock.Any()).Return(errors.New("err"))
 	mock.DoRequest(t, &mock.HTTPHandler{
 		Method:         http.MethodPut,
-		URL:            "/metric/sum?db=dal&cluster=dal&c=1",
-		HandlerFunc:    api.Sum,
+		URL:            "/metric/write?db=dal",
+		RequestBody:    body,
+		HandlerFunc:    api.Write,
 		ExpectHTTPCode: 500,
 	})
-
-	cm.EXPECT().Write(gomock.Any()).Return(nil)
+	// in-flight window full, retryable
+	cm.EXPECT().Write(gomock.Any()).Return(replication.ErrInFlightWindowFull)
+	mock.DoRequest(t, &mock.HTTPHandler{
+		Method:         http.MethodPut,
+		URL:            "/metric/write?db=dal",
+		RequestBody:    body,
+		HandlerFunc:    api.Write,
+		ExpectHTTPCode: 429,
+	})
+	// write ok
+	var metricList *field.MetricList
+	cm.EXPECT().Write(gomock.Any()).DoAndReturn(func(list *field.MetricList) error {
+		metricList = list
+		return nil
+	})
 	mock.DoRequest(t, &mock.HTTPHandler{
 		Method:         http.MethodPut,
-		URL:            "/metric/sum?db=dal&cluster=dal&c=1",
-		HandlerFunc:    api.Sum,
+		URL:            "/metric/write?db=dal",
+		RequestBody:    body,
+		HandlerFunc:    api.Write,
 		ExpectHTTPCode: 200,
 	})
+	assert.Equal(t, "dal", metricList.Database)
+	assert.Len(t, metricList.Metrics, 2)
+	// tags of metric win on conflict
+	assert.Equal(t, map[string]string{"dc": "sh", "host": "1.1.1.1"}, metricList.Metrics[0].Tags)
+	assert.Equal(t, map[string]string{"dc": "sh", "host": "default"}, metricList.Metrics[1].Tags)
+	assert.Equal(t, 1.0, metricList.Metrics[0].Fields[0].GetSum().Value)
+}
+
+func TestWriteAPI_Write_badRequest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	api := NewWriteAPI(replication.NewMockChannelManager(ctrl))
+	doWrite := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/metric/write?db=dal", strings.NewReader(body))
+		resp := httptest.NewRecorder()
+		api.Write(resp, req)
+		return resp
+	}
+	// empty body
+	resp := doWrite("")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "empty")
+	// malformed json
+	resp = doWrite(`{"metrics":[`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "malformed json")
+	// bad field type
+	resp = doWrite(`{"metrics":[` +
+		`{"name":"cpu","timestamp":1564300800000,"fields":[{"name":"f1","sum":{"value":1}}]},` +
+		`{"name":"mem","timestamp":1564300800000,"fields":[{"name":"f1","sum":{"value":"abc"}}]}]}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "metric[1]")
+	// invalid metric reports the index
+	resp = doWrite(`{"metrics":[` +
+		`{"name":"cpu","timestamp":1564300800000,"fields":[{"name":"f1","sum":{"value":1}}]},` +
+		`{"name":"mem","fields":[{"name":"f1","sum":{"value":1}}]}]}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid metric[1]:timestamp is required")
+}
+
+func TestValidateMetric(t *testing.T) {
+	sum := &field.Field{Name: "f1", Field: &field.Field_Sum{Sum: &field.Sum{Value: 1}}}
+	assert.NoError(t, validateMetric(&field.Metric{Name: "cpu", Timestamp: 1, Fields: []*field.Field{sum}}))
+	assert.Error(t, validateMetric(&field.Metric{Timestamp: 1, Fields: []*field.Field{sum}}))
+	assert.Error(t, validateMetric(&field.Metric{Name: "cpu", Fields: []*field.Field{sum}}))
+	assert.Error(t, validateMetric(&field.Metric{Name: "cpu", Timestamp: 1}))
+	assert.Error(t, validateMetric(&field.Metric{Name: "cpu", Timestamp: 1, Fields: []*field.Field{{Field: sum.Field}}}))
+	assert.Error(t, validateMetric(&field.Metric{Name: "cpu", Timestamp: 1, Fields: []*field.Field{{Name: "f1"}}}))
+}
 
+func TestParseMetricList(t *testing.T) {
+	_, err := parseMetricList("db", strings.NewReader(`abc`))
+	assert.Error(t, err)
+	_, err = parseMetricList("db", strings.NewReader(`{"tags":{"dc":"sh"}}`))
+	assert.Error(t, err)
+	// without default tags
+	metricList, err := parseMetricList("db", strings.NewReader(`{"metrics":[{"name":"cpu","timestamp":1,`+
+		`"tags":{"host":"1.1.1.1"},"fields":[{"name":"f1","sum":{"value":1}}]}]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"host": "1.1.1.1"}, metricList.Metrics[0].Tags)
 }
@@ -2,13 +2,25 @@ package query
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/lindb/lindb/broker/api"
 	"github.com/lindb/lindb/coordinator/broker"
 	"github.com/lindb/lindb/coordinator/replica"
+	"github.com/lindb/lindb/models"
 	"github.com/lindb/lindb/parallel"
+	"github.com/lindb/lindb/pkg/timeutil"
+	"github.com/lindb/lindb/sql/stmt"
+)
+
+// Defines the server-sent event names of streaming query
+const (
+	seriesEvent   = "series"
+	completeEvent = "complete"
+	errorEvent    = "error"
 )
 
 // MetricAPI represents the metric query api
@@ -42,11 +54,21 @@ func (m *MetricAPI) Search(w http.ResponseWriter, r *http.Request) {
 		api.Error(w, err)
 		return
 	}
+	options, err := getQueryOptions(r)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	nullAware, err := getBoolParam(r, "nullAware")
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
 	//TODO add timeout cfg
 	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
 	defer cancel()
 
-	exec := m.executorFactory.NewBrokerExecutor(ctx, db, sql, m.replicaStateMachine, m.nodeStateMachine, m.jobManager)
+	exec := m.executorFactory.NewBrokerExecutor(ctx, db, sql, options, m.replicaStateMachine, m.nodeStateMachine, m.jobManager)
 	exec.Execute()
 
 	brokerExecutor := exec.(parallel.BrokerExecutor)
@@ -62,5 +84,192 @@ func (m *MetricAPI) Search(w http.ResponseWriter, r *http.Request) {
 		api.Error(w, err)
 		return
 	}
+	resultSet.NullAware = nullAware
 	api.OK(w, resultSet)
 }
+
+// Stream searches the metric data based on database and sql, streams the series as server-sent events
+// once each task response of storage is merged rather than buffering the whole result set,
+// order by and limit are not supported because the top n series can't be selected before all merged.
+// events: series(series updated by the response, replacing the series with same tags sent before)...,
+// then complete or error.
+func (m *MetricAPI) Stream(w http.ResponseWriter, r *http.Request) {
+	db, err := api.GetParamsFromRequest("db", r, "", true)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	sql, err := api.GetParamsFromRequest("sql", r, "", true)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	options, err := getQueryOptions(r)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	options.Streaming = true
+	nullAware, err := getBoolParam(r, "nullAware")
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	sse, err := api.NewSSEWriter(w)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	//TODO add timeout cfg
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	exec := m.executorFactory.NewBrokerExecutor(ctx, db, sql, options, m.replicaStateMachine, m.nodeStateMachine, m.jobManager)
+	exec.Execute()
+
+	brokerExecutor := exec.(parallel.BrokerExecutor)
+	exeCtx := brokerExecutor.ExecuteContext()
+
+	sent := 0
+	clientGone := false
+	// drains the result chan even if client gone, for releasing the executor
+	for result := range exeCtx.ResultCh() {
+		exeCtx.Emit(result)
+		resultSet, err := exeCtx.ResultSet()
+		if clientGone || err != nil || len(resultSet.Series) <= sent {
+			continue
+		}
+		// only sends the new series of the result set
+		event := *resultSet
+		event.Series = resultSet.Series[sent:]
+		event.NullAware = nullAware
+		sent = len(resultSet.Series)
+		if err := sse.Send(seriesEvent, &event); err != nil {
+			clientGone = true
+			cancel()
+		}
+	}
+	if clientGone {
+		return
+	}
+
+	resultSet, err := exeCtx.ResultSet()
+	if err != nil {
+		_ = sse.Send(errorEvent, err.Error())
+		return
+	}
+	_ = sse.Send(completeEvent, &models.ResultSet{
+		MetricName: resultSet.MetricName,
+		StartTime:  resultSet.StartTime,
+		EndTime:    resultSet.EndTime,
+		Interval:   resultSet.Interval,
+		Partial:    resultSet.Partial,
+	})
+}
+
+// getQueryOptions returns the query options from the params of request:
+// 1) interval: forced aggregation interval(like 10s)
+// 2) budget: soft time budget of storage scan(like 500ms), returns partial results when elapsed
+// 3) maxGap: max gap of carrying forward the previous value for fill(previous)(like 5m)
+// 4) withSeriesID: returns the internal series id of each time series as a tag for debugging
+// 5) timezone: IANA timezone name(like Asia/Shanghai) which the down sampling buckets are aligned to
+// 6) validateTagKeys: returns error if the query references an unknown tag key
+// 7) staleFor: returns only the series whose latest data is older than the duration(like 5m)
+// 8) seriesOnly: returns only the tags of the series matching the condition without any field values
+func getQueryOptions(r *http.Request) (options stmt.QueryOptions, err error) {
+	if options.ForceInterval, err = getForceInterval(r); err != nil {
+		return
+	}
+	if options.Budget, err = getBudget(r); err != nil {
+		return
+	}
+	if options.FillMaxGap, err = getDurationParam(r, "maxGap"); err != nil {
+		return
+	}
+	if options.WithSeriesID, err = getBoolParam(r, "withSeriesID"); err != nil {
+		return
+	}
+	if options.Timezone, err = getTimezone(r); err != nil {
+		return
+	}
+	if options.ValidateTagKeys, err = getBoolParam(r, "validateTagKeys"); err != nil {
+		return
+	}
+	if options.StaleFor, err = getDurationParam(r, "staleFor"); err != nil {
+		return
+	}
+	if options.SeriesOnly, err = getBoolParam(r, "seriesOnly"); err != nil {
+		return
+	}
+	return
+}
+
+// getForceInterval returns the forced aggregation interval from the interval param(like 10s), 0 if not set.
+func getForceInterval(r *http.Request) (int64, error) {
+	intervalStr, err := api.GetParamsFromRequest("interval", r, "", false)
+	if err != nil || intervalStr == "" {
+		return 0, err
+	}
+	var interval timeutil.Interval
+	if err := interval.ValueOf(intervalStr); err != nil {
+		return 0, err
+	}
+	if interval <= 0 {
+		return 0, fmt.Errorf("interval must be positive")
+	}
+	return interval.Int64(), nil
+}
+
+// getBudget returns the soft time budget(ms) of storage scan from the budget param(like 500ms), 0 if not set.
+func getBudget(r *http.Request) (int64, error) {
+	budgetStr, err := api.GetParamsFromRequest("budget", r, "", false)
+	if err != nil || budgetStr == "" {
+		return 0, err
+	}
+	budget, err := time.ParseDuration(budgetStr)
+	if err != nil {
+		return 0, err
+	}
+	if budget <= 0 {
+		return 0, fmt.Errorf("budget must be positive")
+	}
+	return budget.Nanoseconds() / int64(time.Millisecond), nil
+}
+
+// getDurationParam returns the duration(ms) of the param(like maxGap=5m), 0 if not set.
+func getDurationParam(r *http.Request, paramName string) (int64, error) {
+	durationStr, err := api.GetParamsFromRequest(paramName, r, "", false)
+	if err != nil || durationStr == "" {
+		return 0, err
+	}
+	var duration timeutil.Interval
+	if err := duration.ValueOf(durationStr); err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("%s must be positive", paramName)
+	}
+	return duration.Int64(), nil
+}
+
+// getTimezone returns the IANA timezone name from the timezone param(like Asia/Shanghai), empty if not set.
+func getTimezone(r *http.Request) (string, error) {
+	timezone, err := api.GetParamsFromRequest("timezone", r, "", false)
+	if err != nil || timezone == "" {
+		return "", err
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return "", fmt.Errorf("unknown timezone[%s]: %s", timezone, err)
+	}
+	return timezone, nil
+}
+
+// getBoolParam returns the bool value of the param(like nullAware, renders the missing points as null),
+// false if not set.
+func getBoolParam(r *http.Request, paramName string) (bool, error) {
+	value, err := api.GetParamsFromRequest(paramName, r, "false", false)
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(value)
+}
@@ -1,17 +1,35 @@
 package query
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
 
+	"github.com/lindb/lindb/aggregation"
+	"github.com/lindb/lindb/aggregation/function"
+	"github.com/lindb/lindb/config"
+	"github.com/lindb/lindb/coordinator/broker"
+	"github.com/lindb/lindb/coordinator/replica"
 	"github.com/lindb/lindb/mock"
 	"github.com/lindb/lindb/models"
 	"github.com/lindb/lindb/parallel"
+	"github.com/lindb/lindb/pkg/encoding"
+	"github.com/lindb/lindb/pkg/timeutil"
+	lindbquery "github.com/lindb/lindb/query"
+	pb "github.com/lindb/lindb/rpc/proto/common"
 	"github.com/lindb/lindb/series"
+	"github.com/lindb/lindb/series/field"
+	"github.com/lindb/lindb/sql/stmt"
 )
 
 func TestMetricAPI_Search(t *testing.T) {
@@ -24,8 +42,10 @@ func TestMetricAPI_Search(t *testing.T) {
 	brokerExecutor.EXPECT().ExecuteContext().Return(executeCtx)
 	brokerExecutor.EXPECT().Execute()
 
-	executorFactory.EXPECT().NewBrokerExecutor(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
-		gomock.Any(), gomock.Any()).Return(brokerExecutor)
+	executorFactory.EXPECT().NewBrokerExecutor(gomock.Any(), gomock.Any(), gomock.Any(),
+		stmt.QueryOptions{ForceInterval: 60 * 1000, Budget: 500, WithSeriesID: true, Timezone: "Asia/Shanghai",
+			ValidateTagKeys: true, StaleFor: 5 * 60 * 1000, SeriesOnly: true},
+		gomock.Any(), gomock.Any(), gomock.Any()).Return(brokerExecutor)
 
 	api := NewMetricAPI(nil, nil, executorFactory, nil)
 
@@ -33,7 +53,8 @@ func TestMetricAPI_Search(t *testing.T) {
 
 	executeCtx.EXPECT().ResultCh().Return(ch)
 	executeCtx.EXPECT().Emit(gomock.Any())
-	executeCtx.EXPECT().ResultSet().Return(&models.ResultSet{}, nil)
+	// storage scan budget elapsed
+	executeCtx.EXPECT().ResultSet().Return(&models.ResultSet{Partial: true}, nil)
 
 	time.AfterFunc(100*time.Millisecond, func() {
 		ch <- nil
@@ -42,9 +63,10 @@ func TestMetricAPI_Search(t *testing.T) {
 
 	mock.DoRequest(t, &mock.HTTPHandler{
 		Method:         http.MethodGet,
-		URL:            "/broker/state?db=test&sql=select f from cpu",
+		URL:            "/broker/state?db=test&sql=select f from cpu&interval=1m&nullAware=true&budget=500ms&withSeriesID=true&timezone=Asia/Shanghai&validateTagKeys=true&staleFor=5m&seriesOnly=true",
 		HandlerFunc:    api.Search,
 		ExpectHTTPCode: 200,
+		ExpectResponse: &models.ResultSet{Partial: true, NullAware: true},
 	})
 }
 
@@ -71,13 +93,95 @@ func TestNewMetricAPI_Search_Err(t *testing.T) {
 		ExpectHTTPCode: 500,
 	})
 
+	// interval param error
+	mock.DoRequest(t, &mock.HTTPHandler{
+		Method:         http.MethodGet,
+		URL:            "/broker/state?db=test&sql=select f from cpu&interval=10x",
+		HandlerFunc:    api.Search,
+		ExpectHTTPCode: 500,
+	})
+	mock.DoRequest(t, &mock.HTTPHandler{
+		Method:         http.MethodGet,
+		URL:            "/broker/state?db=test&sql=select f from cpu&interval=-10s",
+		HandlerFunc:    api.Search,
+		ExpectHTTPCode: 500,
+	})
+	// null aware param error
+	mock.DoRequest(t, &mock.HTTPHandler{
+		Method:         http.MethodGet,
+		URL:            "/broker/state?db=test&sql=select f from cpu&nullAware=x",
+		HandlerFunc:    api.Search,
+		ExpectHTTPCode: 500,
+	})
+	// with series id param error
+	mock.DoRequest(t, &mock.HTTPHandler{
+		Method:         http.MethodGet,
+		URL:            "/broker/state?db=test&sql=select f from cpu&withSeriesID=x",
+		HandlerFunc:    api.Search,
+		ExpectHTTPCode: 500,
+	})
+	// series only param error
+	mock.DoRequest(t, &mock.HTTPHandler{
+		Method:         http.MethodGet,
+		URL:            "/broker/state?db=test&sql=select f from cpu&seriesOnly=x",
+		HandlerFunc:    api.Search,
+		ExpectHTTPCode: 500,
+	})
+	// stale for param error
+	mock.DoRequest(t, &mock.HTTPHandler{
+		Method:         http.MethodGet,
+		URL:            "/broker/state?db=test&sql=select f from cpu&staleFor=5x",
+		HandlerFunc:    api.Search,
+		ExpectHTTPCode: 500,
+	})
+	// validate tag keys param error
+	mock.DoRequest(t, &mock.HTTPHandler{
+		Method:         http.MethodGet,
+		URL:            "/broker/state?db=test&sql=select f from cpu&validateTagKeys=x",
+		HandlerFunc:    api.Search,
+		ExpectHTTPCode: 500,
+	})
+	// timezone param error
+	mock.DoRequest(t, &mock.HTTPHandler{
+		Method:         http.MethodGet,
+		URL:            "/broker/state?db=test&sql=select f from cpu&timezone=Unknown/Zone",
+		HandlerFunc:    api.Search,
+		ExpectHTTPCode: 500,
+	})
+	// max gap param error
+	mock.DoRequest(t, &mock.HTTPHandler{
+		Method:         http.MethodGet,
+		URL:            "/broker/state?db=test&sql=select f from cpu&maxGap=10x",
+		HandlerFunc:    api.Search,
+		ExpectHTTPCode: 500,
+	})
+	mock.DoRequest(t, &mock.HTTPHandler{
+		Method:         http.MethodGet,
+		URL:            "/broker/state?db=test&sql=select f from cpu&maxGap=-1m",
+		HandlerFunc:    api.Search,
+		ExpectHTTPCode: 500,
+	})
+	// budget param error
+	mock.DoRequest(t, &mock.HTTPHandler{
+		Method:         http.MethodGet,
+		URL:            "/broker/state?db=test&sql=select f from cpu&budget=10x",
+		HandlerFunc:    api.Search,
+		ExpectHTTPCode: 500,
+	})
+	mock.DoRequest(t, &mock.HTTPHandler{
+		Method:         http.MethodGet,
+		URL:            "/broker/state?db=test&sql=select f from cpu&budget=-1s",
+		HandlerFunc:    api.Search,
+		ExpectHTTPCode: 500,
+	})
+
 	brokerExecutor := parallel.NewMockBrokerExecutor(ctrl)
 	executeCtx := parallel.NewMockBrokerExecuteContext(ctrl)
 	brokerExecutor.EXPECT().ExecuteContext().Return(executeCtx)
 	brokerExecutor.EXPECT().Execute()
 
 	executorFactory.EXPECT().NewBrokerExecutor(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
-		gomock.Any(), gomock.Any()).Return(brokerExecutor)
+		gomock.Any(), gomock.Any(), gomock.Any()).Return(brokerExecutor)
 
 	ch := make(chan *series.TimeSeriesEvent)
 
@@ -94,3 +198,218 @@ func TestNewMetricAPI_Search_Err(t *testing.T) {
 		ExpectHTTPCode: 500,
 	})
 }
+
+func TestMetricAPI_Stream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	executorFactory := parallel.NewMockExecutorFactory(ctrl)
+	brokerExecutor := parallel.NewMockBrokerExecutor(ctrl)
+	executeCtx := parallel.NewMockBrokerExecuteContext(ctrl)
+	brokerExecutor.EXPECT().ExecuteContext().Return(executeCtx)
+	brokerExecutor.EXPECT().Execute()
+	executorFactory.EXPECT().NewBrokerExecutor(gomock.Any(), gomock.Any(), gomock.Any(),
+		stmt.QueryOptions{FillMaxGap: 5 * 60 * 1000, Streaming: true},
+		gomock.Any(), gomock.Any(), gomock.Any()).Return(brokerExecutor)
+
+	api := NewMetricAPI(nil, nil, executorFactory, nil)
+
+	ch := make(chan *series.TimeSeriesEvent)
+	rs := &models.ResultSet{MetricName: "cpu"}
+	executeCtx.EXPECT().ResultCh().Return(ch)
+	executeCtx.EXPECT().Emit(gomock.Any()).DoAndReturn(func(event *series.TimeSeriesEvent) {
+		rs.AddSeries(models.NewSeries(map[string]string{"host": fmt.Sprintf("%d", len(rs.Series))}))
+	}).Times(3)
+	executeCtx.EXPECT().ResultSet().Return(rs, nil).AnyTimes()
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			ch <- &series.TimeSeriesEvent{}
+		}
+		close(ch)
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "/query/metric/stream?db=test&sql=select+f+from+cpu&maxGap=5m", nil)
+	resp := httptest.NewRecorder()
+	api.Stream(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "text/event-stream; charset=utf-8", resp.Header().Get("Content-Type"))
+	body := resp.Body.String()
+	assert.Equal(t, 3, strings.Count(body, "event: series\n"))
+	assert.Contains(t, body, `data: {"metricName":"cpu","series":[{"tags":{"host":"0"}}]}`)
+	assert.Contains(t, body, `data: {"metricName":"cpu","series":[{"tags":{"host":"2"}}]}`)
+	// stream closes with complete event
+	assert.True(t, strings.HasSuffix(body, "event: complete\ndata: {\"metricName\":\"cpu\"}\n\n"))
+}
+
+func TestMetricAPI_Stream_incremental(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	nodeStateMachine := broker.NewMockNodeStateMachine(ctrl)
+	nodeStateMachine.EXPECT().GetCurrentNode().Return(models.Node{IP: "1.1.1.3", Port: 8000}).AnyTimes()
+	nodeStateMachine.EXPECT().GetActiveNodes().Return(nil)
+	replicaStateMachine := replica.NewMockStatusStateMachine(ctrl)
+	replicaStateMachine.EXPECT().GetQueryableReplicas("test").
+		Return(map[string][]int32{"1.1.1.1:9000": {1}, "1.1.1.2:9000": {2}})
+
+	// the results of storage nodes are received by the root task through the real job manager and merger
+	var rootTask parallel.TaskContext
+	requests := make(chan *pb.TaskRequest, 2)
+	taskManager := parallel.NewMockTaskManager(ctrl)
+	taskManager.EXPECT().AllocTaskID().Return("root")
+	taskManager.EXPECT().Submit(gomock.Any(), gomock.Any()).Do(func(taskCtx parallel.TaskContext, _ time.Time) {
+		rootTask = taskCtx
+	})
+	taskManager.EXPECT().SendRequest(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ string, req *pb.TaskRequest) error {
+			requests <- req
+			return nil
+		}).Times(2)
+	api := NewMetricAPI(replicaStateMachine, nodeStateMachine,
+		lindbquery.NewExecutorFactory(config.Query{}), parallel.NewJobManager(taskManager))
+
+	firstEventRead := make(chan struct{})
+	go func() {
+		query := &stmt.Query{}
+		_ = encoding.JSONUnmarshal((<-requests).Payload, query)
+		<-requests
+		rootTask.ReceiveResult(newStorageResponse(t, query, map[string]float64{"a": 1}))
+		// the second storage node responds after the client received the result of first one
+		<-firstEventRead
+		rootTask.ReceiveResult(newStorageResponse(t, query, map[string]float64{"a": 2, "b": 3}))
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(api.Stream))
+	defer server.Close()
+	params := url.Values{}
+	params.Set("db", "test")
+	params.Set("sql", "select f from cpu where time>'20190729 11:00:00' and time<'20190729 12:00:00' group by host")
+	resp, err := http.Get(server.URL + "/query/metric/stream?" + params.Encode())
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+
+	// the series merged so far are sent before all storage nodes completed
+	event, data := readSSEEvent(t, reader)
+	assert.Equal(t, seriesEvent, event)
+	assert.Equal(t, map[string]float64{"a": 1}, sumOfSeries(t, data))
+	close(firstEventRead)
+
+	event, data = readSSEEvent(t, reader)
+	assert.Equal(t, seriesEvent, event)
+	assert.Equal(t, map[string]float64{"a": 3, "b": 3}, sumOfSeries(t, data))
+	event, _ = readSSEEvent(t, reader)
+	assert.Equal(t, completeEvent, event)
+	rest, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Empty(t, rest)
+}
+
+// newStorageResponse returns the completed task response of storage node with the sum field f of each host
+func newStorageResponse(t *testing.T, query *stmt.Query, values map[string]float64) *pb.TaskResponse {
+	seriesList := pb.TimeSeriesList{}
+	for _, host := range []string{"a", "b"} {
+		value, ok := values[host]
+		if !ok {
+			continue
+		}
+		aggSpec := aggregation.NewAggregatorSpec("f", field.SumField)
+		aggSpec.AddFunctionType(function.Sum)
+		aggregates := aggregation.NewFieldAggregates(timeutil.Interval(query.Interval), 1, query.TimeRange,
+			true, aggregation.AggregatorSpecs{aggSpec})
+		fAgg, ok := aggregates[0].GetAggregator(query.TimeRange.Start)
+		assert.True(t, ok)
+		for _, pAgg := range fAgg.GetAllAggregators() {
+			pAgg.Aggregate(0, value)
+		}
+		rs := aggregates.ResultSet(map[string]string{"host": host})
+		fields := make(map[string][]byte)
+		for rs.HasNext() {
+			it := rs.Next()
+			data, err := series.MarshalIterator(it)
+			assert.NoError(t, err)
+			data, err = series.EncodeDelta(data)
+			assert.NoError(t, err)
+			fields[it.FieldName()] = data
+		}
+		seriesList.TimeSeriesList = append(seriesList.TimeSeriesList, &pb.TimeSeries{Tags: rs.Tags(), Fields: fields})
+	}
+	payload, err := seriesList.Marshal()
+	assert.NoError(t, err)
+	return &pb.TaskResponse{TaskID: "root", Completed: true, Payload: payload}
+}
+
+// readSSEEvent reads the next server-sent event
+func readSSEEvent(t *testing.T, reader *bufio.Reader) (event, data string) {
+	for {
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// sumOfSeries returns the sum of field f of each host in the result set
+func sumOfSeries(t *testing.T, data string) map[string]float64 {
+	rs := &models.ResultSet{}
+	assert.NoError(t, json.Unmarshal([]byte(data), rs))
+	sums := make(map[string]float64)
+	for _, s := range rs.Series {
+		for _, value := range s.Fields["f"] {
+			sums[s.Tags["host"]] += value
+		}
+	}
+	return sums
+}
+
+func TestMetricAPI_Stream_Err(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	executorFactory := parallel.NewMockExecutorFactory(ctrl)
+	api := NewMetricAPI(nil, nil, executorFactory, nil)
+
+	// param error
+	mock.DoRequest(t, &mock.HTTPHandler{
+		Method:         http.MethodGet,
+		URL:            "/query/metric/stream",
+		HandlerFunc:    api.Stream,
+		ExpectHTTPCode: 500,
+	})
+	mock.DoRequest(t, &mock.HTTPHandler{
+		Method:         http.MethodGet,
+		URL:            "/query/metric/stream?db=test",
+		HandlerFunc:    api.Stream,
+		ExpectHTTPCode: 500,
+	})
+
+	brokerExecutor := parallel.NewMockBrokerExecutor(ctrl)
+	executeCtx := parallel.NewMockBrokerExecuteContext(ctrl)
+	brokerExecutor.EXPECT().ExecuteContext().Return(executeCtx)
+	brokerExecutor.EXPECT().Execute()
+	executorFactory.EXPECT().NewBrokerExecutor(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+		gomock.Any(), gomock.Any(), gomock.Any()).Return(brokerExecutor)
+
+	ch := make(chan *series.TimeSeriesEvent)
+	executeCtx.EXPECT().ResultCh().Return(ch)
+	executeCtx.EXPECT().Emit(gomock.Any())
+	executeCtx.EXPECT().ResultSet().Return(&models.ResultSet{}, fmt.Errorf("err")).Times(2)
+	go func() {
+		ch <- &series.TimeSeriesEvent{Err: fmt.Errorf("err")}
+		close(ch)
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "/query/metric/stream?db=test&sql=select+f+from+cpu", nil)
+	resp := httptest.NewRecorder()
+	api.Stream(resp, req)
+	assert.Equal(t, "event: error\ndata: \"err\"\n\n", resp.Body.String())
+}
@@ -21,12 +21,24 @@ func NotFound(w http.ResponseWriter) {
 	response(w, http.StatusNotFound, nil)
 }
 
+// BadRequest responses error message of invalid request and set the http status code 400
+func BadRequest(w http.ResponseWriter, err error) {
+	b, _ := json.Marshal(err.Error())
+	response(w, http.StatusBadRequest, b)
+}
+
 // Error responses error message and set the http status code 500
 func Error(w http.ResponseWriter, err error) {
 	b, _ := json.Marshal(err.Error())
 	response(w, http.StatusInternalServerError, b)
 }
 
+// TooManyRequests responses error message of the retryable request and set the http status code 429
+func TooManyRequests(w http.ResponseWriter, err error) {
+	b, _ := json.Marshal(err.Error())
+	response(w, http.StatusTooManyRequests, b)
+}
+
 // response responses json body for http restful api
 func response(w http.ResponseWriter, httpCode int, content []byte) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -36,3 +36,17 @@ func TestError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, resp.Code)
 	assert.Equal(t, `"err"`, resp.Body.String())
 }
+
+func TestBadRequest(t *testing.T) {
+	resp := httptest.NewRecorder()
+	BadRequest(resp, fmt.Errorf("err"))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, `"err"`, resp.Body.String())
+}
+
+func TestTooManyRequests(t *testing.T) {
+	resp := httptest.NewRecorder()
+	TooManyRequests(resp, fmt.Errorf("err"))
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, `"err"`, resp.Body.String())
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type nonFlusher struct {
+	http.ResponseWriter
+}
+
+func TestSSEWriter(t *testing.T) {
+	_, err := NewSSEWriter(&nonFlusher{ResponseWriter: httptest.NewRecorder()})
+	assert.NotNil(t, err)
+
+	resp := httptest.NewRecorder()
+	sse, err := NewSSEWriter(resp)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "text/event-stream; charset=utf-8", resp.Header().Get("Content-Type"))
+	assert.Nil(t, sse.Send("test", "ok"))
+	assert.Nil(t, sse.Send("test", 1))
+	assert.NotNil(t, sse.Send("test", func() {}))
+	assert.Equal(t, "event: test\ndata: \"ok\"\n\nevent: test\ndata: 1\n\n", resp.Body.String())
+}
@@ -316,7 +316,7 @@ func (r *runtime) buildServiceDependency() {
 
 	// hard code create channel first.
 	cm := replication.NewChannelManager(r.config.BrokerBase.ReplicationChannel, rpc.NewClientStreamFactory(r.node), replicatorService)
-	taskManager := parallel.NewTaskManager(r.node, r.factory.taskClient, r.factory.taskServer)
+	taskManager := parallel.NewTaskManager(r.ctx, r.node, r.factory.taskClient, r.factory.taskServer)
 	jobManager := parallel.NewJobManager(taskManager)
 
 	//FIXME (stone100)close it????
@@ -346,7 +346,7 @@ func (r *runtime) buildAPIDependency() {
 		brokerStateAPI:    stateAPI.NewBrokerAPI(r.ctx, r.repo, r.stateMachines.NodeSM),
 		masterAPI:         masterAPI.NewMasterAPI(r.master),
 		metricAPI: queryAPI.NewMetricAPI(r.stateMachines.ReplicaStatusSM,
-			r.stateMachines.NodeSM, query.NewExecutorFactory(), r.srv.jobManager),
+			r.stateMachines.NodeSM, query.NewExecutorFactory(r.config.BrokerBase.Query), r.srv.jobManager),
 		writeAPI: writeAPI.NewWriteAPI(r.srv.channelManager),
 
 		metaDatabaseAPI: metadata.NewDatabaseAPI(r.srv.databaseService),
@@ -371,8 +371,13 @@ func (r *runtime) buildAPIDependency() {
 	api.AddRoute("GetMasterState", http.MethodGet, "/cluster/master", handlers.masterAPI.GetMaster)
 
 	api.AddRoute("QueryMetric", http.MethodGet, "/query/metric", handlers.metricAPI.Search)
+	api.AddRoute("StreamQueryMetric", http.MethodGet, "/query/metric/stream", handlers.metricAPI.Stream)
 
-	api.AddRoute("WriteSumMetric", http.MethodPut, "/metric/sum", handlers.writeAPI.Sum)
+	api.AddRoute("WriteMetric", http.MethodPut, "/metric/write", handlers.writeAPI.Write)
+	api.AddRoute("InfluxWriteMetric", http.MethodPost, "/metric/influx/write", handlers.writeAPI.InfluxLineProtocol)
+	api.AddRoute("PrometheusWriteMetric", http.MethodPost, "/metric/prometheus/write", handlers.writeAPI.PrometheusRemoteWrite)
+	api.AddRoute("GraphiteWriteMetric", http.MethodPost, "/metric/graphite/write", handlers.writeAPI.GraphitePlaintext)
+	api.AddRoute("OpenTSDBWriteMetric", http.MethodPost, "/metric/opentsdb/put", handlers.writeAPI.OpenTSDBPut)
 
 	api.AddRoute("ListDatabaseNodes", http.MethodGet, "/metadata/database/names", handlers.metaDatabaseAPI.ListDatabaseNames)
 }
@@ -63,6 +63,9 @@ func serveBroker(cmd *cobra.Command, args []string) error {
 	if err := ltoml.LoadConfig(cfg, defaultBrokerCfgFile, &brokerCfg); err != nil {
 		return fmt.Errorf("decode config file error: %s", err)
 	}
+	if err := brokerCfg.BrokerBase.ReplicationChannel.Validate(); err != nil {
+		return fmt.Errorf("validate replication channel config error: %s", err)
+	}
 	if err := logger.InitLogger(brokerCfg.Logging); err != nil {
 		return fmt.Errorf("init logger error: %s", err)
 	}
@@ -67,6 +67,12 @@ func serveStandalone(cmd *cobra.Command, args []string) error {
 	if err := ltoml.LoadConfig(cfg, defaultStandaloneCfgFile, &standaloneCfg); err != nil {
 		return fmt.Errorf("decode config file error: %s", err)
 	}
+	if err := standaloneCfg.BrokerBase.ReplicationChannel.Validate(); err != nil {
+		return fmt.Errorf("validate replication channel config error: %s", err)
+	}
+	if err := standaloneCfg.StorageBase.Query.Validate(); err != nil {
+		return fmt.Errorf("validate query config error: %s", err)
+	}
 	if err := logger.InitLogger(standaloneCfg.Logging); err != nil {
 		return fmt.Errorf("init logger error: %s", err)
 	}
@@ -63,6 +63,9 @@ func serveStorage(cmd *cobra.Command, args []string) error {
 	if err := ltoml.LoadConfig(cfg, defaultStorageCfgFile, &storageCfg); err != nil {
 		return fmt.Errorf("decode config file error: %s", err)
 	}
+	if err := storageCfg.StorageBase.Query.Validate(); err != nil {
+		return fmt.Errorf("validate query config error: %s", err)
+	}
 	if err := logger.InitLogger(storageCfg.Logging); err != nil {
 		return fmt.Errorf("init logger error: %s", err)
 	}
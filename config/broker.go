@@ -55,6 +55,9 @@ type ReplicationChannel struct {
 	CheckFlushInterval ltoml.Duration `toml:"check-flush-interval"`
 	FlushInterval      ltoml.Duration `toml:"flush-interval"`
 	BufferSize         uint16         `toml:"buffer-size"`
+	Compression        string         `toml:"compression"`   // compression of messages in segment, none/snappy
+	MaxInFlight        int64          `toml:"max-in-flight"` // max pending messages of replicator, 0 means unlimited
+	WriteTimeout       ltoml.Duration `toml:"write-timeout"` // max time to wait when in-flight window is full
 }
 
 func (rc *ReplicationChannel) SegmentFileSizeInBytes() int {
@@ -67,6 +70,19 @@ func (rc *ReplicationChannel) SegmentFileSizeInBytes() int {
 	return int(rc.SegmentFileSize) * 1024 * 1024
 }
 
+// Validate validates the config of replication channel, returns error if the compression is not supported,
+// the available compression is same as the codec of segment(see segment.ParseCodec).
+func (rc *ReplicationChannel) Validate() error {
+	switch rc.Compression {
+	case "", "none", "snappy":
+		return nil
+	case "zstd":
+		return fmt.Errorf("compression: %s is not supported, available compression is none/snappy", rc.Compression)
+	default:
+		return fmt.Errorf("unknown compression: %s", rc.Compression)
+	}
+}
+
 func (rc *ReplicationChannel) BufferSizeInBytes() int {
 	return int(rc.BufferSize) * 1024
 }
@@ -93,7 +109,19 @@ func (rc *ReplicationChannel) TOML() string {
     flush-interval = "%s"
 
     ## will flush if this size of data in kegabytes get buffered
-    buffer-size = %d`,
+    buffer-size = %d
+
+    ## compression codec of the data written into segment file, available codec is none/snappy(zstd is not supported),
+    ## the segments written with other codec are still readable after changing it
+    compression = "%s"
+
+    ## max num of messages pending to replicate of any replicator in channel, writes are blocked
+    ## when the in-flight window is full, 0 means unlimited
+    max-in-flight = %d
+
+    ## max time for how long a write waits for the in-flight window to be available,
+    ## then a retryable error is returned, no wait if it's 0
+    write-timeout = "%s"`,
 		rc.Dir,
 		rc.SegmentFileSize,
 		rc.RemoveTaskInterval.String(),
@@ -101,6 +129,9 @@ func (rc *ReplicationChannel) TOML() string {
 		rc.CheckFlushInterval.String(),
 		rc.FlushInterval.String(),
 		rc.BufferSize,
+		rc.Compression,
+		rc.MaxInFlight,
+		rc.WriteTimeout.String(),
 	)
 }
 
@@ -168,6 +199,9 @@ func NewDefaultBrokerBase() *BrokerBase {
 			CheckFlushInterval: ltoml.Duration(time.Second),
 			FlushInterval:      ltoml.Duration(5 * time.Second),
 			BufferSize:         128,
+			Compression:        "none",
+			MaxInFlight:        0,
+			WriteTimeout:       ltoml.Duration(5 * time.Second),
 		},
 		Query: *NewDefaultQuery(),
 	}
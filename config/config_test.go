@@ -56,3 +56,27 @@ func Test_ReplicationChannel_SegmentFileSizeInBytes(t *testing.T) {
 	rc.SegmentFileSize = 10000
 	assert.Equal(t, 1024*1024*1024, rc.SegmentFileSizeInBytes())
 }
+
+func Test_ReplicationChannel_Validate(t *testing.T) {
+	rc := NewDefaultBrokerBase().ReplicationChannel
+	assert.NoError(t, rc.Validate())
+	rc.Compression = ""
+	assert.NoError(t, rc.Validate())
+	rc.Compression = "snappy"
+	assert.NoError(t, rc.Validate())
+	rc.Compression = "zstd"
+	assert.Error(t, rc.Validate())
+	rc.Compression = "lz4"
+	assert.Error(t, rc.Validate())
+}
+
+func Test_Query_Validate(t *testing.T) {
+	q := NewDefaultQuery()
+	assert.NoError(t, q.Validate())
+	q.MissingShardPolicy = ""
+	assert.NoError(t, q.Validate())
+	q.MissingShardPolicy = MissingShardSkip
+	assert.NoError(t, q.Validate())
+	q.MissingShardPolicy = "skip"
+	assert.Error(t, q.Validate())
+}
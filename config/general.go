@@ -58,6 +58,43 @@ type Query struct {
 	MaxWorkers  int            `toml:"max-workers"`
 	IdleTimeout ltoml.Duration `toml:"idle-timeout"`
 	Timeout     ltoml.Duration `toml:"timeout"`
+	// max number of fields selected by one query, 0 means unlimited
+	MaxFieldsPerQuery int `toml:"max-fields-per-query"`
+	// behavior when some shards of query are missing in storage, like during rebalancing
+	MissingShardPolicy string `toml:"missing-shard-policy"`
+	// max memory(MB) of the result series held by one query in broker, 0 means unlimited
+	MaxMemoryPerQuery uint16 `toml:"max-memory-per-query"`
+	// dir for spilling the result series when the max memory of query is exceeded, empty means spill disabled
+	SpillDir string `toml:"spill-dir"`
+	// max retries of dispatching the task request to leaf node, 0 means no retry
+	LeafTaskRetries int `toml:"leaf-task-retries"`
+	// backoff before the first retry of dispatching leaf task, doubled for each next retry
+	LeafTaskRetryBackoff ltoml.Duration `toml:"leaf-task-retry-backoff"`
+}
+
+// MaxMemoryPerQueryInBytes returns the max memory of one query in bytes, 0 means unlimited
+func (q *Query) MaxMemoryPerQueryInBytes() int64 {
+	return int64(q.MaxMemoryPerQuery) * 1024 * 1024
+}
+
+// Defines all the policies when shards are missing during query
+const (
+	// MissingShardFailFast fails the query if any shard is missing
+	MissingShardFailFast = "fail-fast"
+	// MissingShardSkip skips the missing shards and continues, returns the partial results flagged as partial
+	MissingShardSkip = "skip-missing"
+)
+
+// Validate validates the config of query, returns error if the missing shard policy is unknown,
+// empty policy means the default policy(fail-fast).
+func (q *Query) Validate() error {
+	switch q.MissingShardPolicy {
+	case "", MissingShardFailFast, MissingShardSkip:
+		return nil
+	default:
+		return fmt.Errorf("unknown missing shard policy: %s, available policy is %s/%s",
+			q.MissingShardPolicy, MissingShardFailFast, MissingShardSkip)
+	}
 }
 
 func (q *Query) TOML() string {
@@ -71,10 +108,37 @@ func (q *Query) TOML() string {
 	idle-timeout = "%s"
 
     ## maximum timeout threshold for the task performed
-    timeout = "%s"`,
+    timeout = "%s"
+
+    ## max number of fields selected by one query, 0 means unlimited
+    max-fields-per-query = %d
+
+    ## behavior when some shards of query are missing, such as during rebalancing,
+    ## fail-fast: fails the query, skip-missing: queries the available shards and returns partial results
+    missing-shard-policy = "%s"
+
+    ## max memory(MB) of the result series held by one query in broker, 0 means unlimited,
+    ## the series of result are sorted by tags when limited
+    max-memory-per-query = %d
+
+    ## dir for spilling the sorted result series when the max memory of query is exceeded,
+    ## empty means spill disabled, then the query fails when exceeding the max memory
+    spill-dir = "%s"
+
+    ## max retries of dispatching the task request to leaf node when sending fails, 0 means no retry
+    leaf-task-retries = %d
+
+    ## backoff before the first retry of dispatching leaf task, doubled for each next retry
+    leaf-task-retry-backoff = "%s"`,
 		q.MaxWorkers,
 		q.IdleTimeout,
 		q.Timeout,
+		q.MaxFieldsPerQuery,
+		q.MissingShardPolicy,
+		q.MaxMemoryPerQuery,
+		q.SpillDir,
+		q.LeafTaskRetries,
+		q.LeafTaskRetryBackoff,
 	)
 }
 
@@ -83,5 +147,11 @@ func NewDefaultQuery() *Query {
 		MaxWorkers:  30,
 		IdleTimeout: ltoml.Duration(5 * time.Second),
 		Timeout:     ltoml.Duration(30 * time.Second),
+
+		MaxFieldsPerQuery:  256,
+		MissingShardPolicy: MissingShardFailFast,
+
+		LeafTaskRetries:      2,
+		LeafTaskRetryBackoff: ltoml.Duration(100 * time.Millisecond),
 	}
 }
@@ -23,13 +23,28 @@ func (r *Replication) TOML() string {
 // TSDB represents the tsdb configuration
 type TSDB struct {
 	Dir string `toml:"dir"`
+	// max number of workers merging the scanned data of query, 0 means the number of CPUs
+	MergeWorkers int `toml:"merge-workers"`
+	// max time of flushing all the memory databases when shutting down, 0 means waiting until done
+	ShutdownTimeout ltoml.Duration `toml:"shutdown-timeout"`
 }
 
 func (t *TSDB) TOML() string {
 	return fmt.Sprintf(`
     ## where the tsdb data is stored
-    dir = "%s"`,
+    dir = "%s"
+
+    ## max number of workers aggregating the scanned data of query per database,
+    ## the CPU-bound merges are parallelized independently of the IO-bound scans,
+    ## 0 means the number of CPUs.
+    merge-workers = %d
+
+    ## max time of flushing all the memory databases when shutting down,
+    ## the unflushed data is lost if exceeded, 0 means waiting until done.
+    shutdown-timeout = "%s"`,
 		t.Dir,
+		t.MergeWorkers,
+		t.ShutdownTimeout.String(),
 	)
 }
 
@@ -82,7 +97,8 @@ func NewDefaultStorageBase() *StorageBase {
 			Port: 2891,
 			TTL:  ltoml.Duration(time.Second)},
 		TSDB: TSDB{
-			Dir: filepath.Join(defaultParentDir, "storage/data")},
+			Dir:             filepath.Join(defaultParentDir, "storage/data"),
+			ShutdownTimeout: ltoml.Duration(time.Minute)},
 		Replication: Replication{
 			Dir: filepath.Join(defaultParentDir, "storage/replication")},
 		Query: *NewDefaultQuery(),
@@ -128,7 +128,7 @@ func (f *family) GetSnapshot() version.Snapshot {
 func (f *family) newTableBuilder() (table.Builder, error) {
 	fileNumber := f.store.versions.NextFileNumber()
 	fileName := filepath.Join(f.familyPath, version.Table(fileNumber))
-	return table.NewStoreBuilder(fileNumber, fileName)
+	return table.NewStoreBuilderWithVersion(fileNumber, fileName, f.option.FlushBufferSize, f.option.FormatVersion)
 }
 
 // commitEditLog persists edit logs into manifest file.
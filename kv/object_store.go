@@ -0,0 +1,128 @@
+package kv
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lindb/lindb/pkg/stream"
+)
+
+//go:generate mockgen -source ./object_store.go -destination=./object_store_mock.go -package kv
+
+// ObjectStore represents a S3-compatible object store for tiered storage
+type ObjectStore interface {
+	// PutObject uploads the object data with the given key
+	PutObject(key string, data []byte) error
+}
+
+// httpObjectStore implements ObjectStore, uploads object by http PUT method
+type httpObjectStore struct {
+	endpoint string
+	bucket   string
+	client   *http.Client
+}
+
+// NewHTTPObjectStore returns a S3-compatible object store which uploads object to endpoint/bucket/key,
+// the upload fails if the object store does not respond within timeout.
+func NewHTTPObjectStore(endpoint, bucket string, timeout time.Duration) ObjectStore {
+	return &httpObjectStore{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		bucket:   bucket,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// PutObject uploads the object data with the given key
+func (s *httpObjectStore) PutObject(key string, data []byte) error {
+	url := fmt.Sprintf("%s/%s/%s", s.endpoint, s.bucket, strings.TrimPrefix(key, "/"))
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("put object[%s] error, status code:%d", key, resp.StatusCode)
+	}
+	return nil
+}
+
+// objectStoreFlusher implements Flusher, buffers all k/v pairs, then uploads them as one object when commit.
+// object layout: [key(uint32), length of value(uvarint), value]...
+type objectStoreFlusher struct {
+	store     ObjectStore
+	objectKey string
+	writer    *stream.BufferWriter
+}
+
+// NewObjectStoreFlusher returns a flusher which writes the k/v pairs to the object store with the object key
+func NewObjectStoreFlusher(store ObjectStore, objectKey string) Flusher {
+	return &objectStoreFlusher{
+		store:     store,
+		objectKey: objectKey,
+		writer:    stream.NewBufferWriter(nil),
+	}
+}
+
+// Add puts k/v pair into the buffer
+func (f *objectStoreFlusher) Add(key uint32, value []byte) error {
+	f.writer.PutUint32(key)
+	f.writer.PutUvarint64(uint64(len(value)))
+	f.writer.PutBytes(value)
+	return nil
+}
+
+// Commit uploads the buffered k/v pairs to the object store
+func (f *objectStoreFlusher) Commit() error {
+	data, err := f.writer.Bytes()
+	if err != nil {
+		return err
+	}
+	// nothing to upload
+	if len(data) == 0 {
+		return nil
+	}
+	if err := f.store.PutObject(f.objectKey, data); err != nil {
+		return fmt.Errorf("upload object[%s] error:%s", f.objectKey, err)
+	}
+	f.writer.Reset()
+	return nil
+}
+
+// multiFlusher implements Flusher, writes the k/v pairs to all the underlying flushers
+type multiFlusher struct {
+	flushers []Flusher
+}
+
+// NewMultiFlusher returns a flusher which duplicates the writes to all the flushers,
+// such as writes data to local disk and object store at the same time.
+func NewMultiFlusher(flushers ...Flusher) Flusher {
+	return &multiFlusher{flushers: flushers}
+}
+
+// Add puts k/v pair to all the flushers
+func (f *multiFlusher) Add(key uint32, value []byte) error {
+	for _, flusher := range f.flushers {
+		if err := flusher.Add(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Commit commits all the flushers
+func (f *multiFlusher) Commit() error {
+	for _, flusher := range f.flushers {
+		if err := flusher.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
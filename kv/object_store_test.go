@@ -0,0 +1,96 @@
+package kv
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/pkg/stream"
+)
+
+func TestHTTPObjectStore_PutObject(t *testing.T) {
+	var path string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		path = r.URL.Path
+		body, _ = ioutil.ReadAll(r.Body)
+		switch path {
+		case "/lindb/fail":
+			w.WriteHeader(http.StatusForbidden)
+		case "/lindb/hung":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	store := NewHTTPObjectStore(server.URL+"/", "lindb", time.Second)
+	assert.Nil(t, store.PutObject("/shard/1/data", []byte("value")))
+	assert.Equal(t, "/lindb/shard/1/data", path)
+	assert.Equal(t, []byte("value"), body)
+	assert.NotNil(t, store.PutObject("fail", []byte("value")))
+	// object store does not respond within timeout
+	store = NewHTTPObjectStore(server.URL, "lindb", 50*time.Millisecond)
+	assert.NotNil(t, store.PutObject("hung", []byte("value")))
+
+	store = NewHTTPObjectStore("http://127.0.0.1:0", "lindb", time.Second)
+	assert.NotNil(t, store.PutObject("key", []byte("value")))
+	store = NewHTTPObjectStore("http://%zz", "lindb", time.Second)
+	assert.NotNil(t, store.PutObject("key", []byte("value")))
+}
+
+func TestObjectStoreFlusher(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := NewMockObjectStore(ctrl)
+	flusher := NewObjectStoreFlusher(store, "shard/1/index")
+	// no data, nothing uploaded
+	assert.Nil(t, flusher.Commit())
+
+	assert.Nil(t, flusher.Add(1, []byte("v1")))
+	assert.Nil(t, flusher.Add(10, []byte("value10")))
+	store.EXPECT().PutObject("shard/1/index", gomock.Any()).DoAndReturn(func(key string, data []byte) error {
+		reader := stream.NewReader(data)
+		assert.Equal(t, uint32(1), reader.ReadUint32())
+		assert.Equal(t, []byte("v1"), reader.ReadBytes(int(reader.ReadUvarint64())))
+		assert.Equal(t, uint32(10), reader.ReadUint32())
+		assert.Equal(t, []byte("value10"), reader.ReadBytes(int(reader.ReadUvarint64())))
+		assert.True(t, reader.Empty())
+		return nil
+	})
+	assert.Nil(t, flusher.Commit())
+	// buffer reset after commit
+	assert.Nil(t, flusher.Commit())
+
+	assert.Nil(t, flusher.Add(1, []byte("v1")))
+	store.EXPECT().PutObject(gomock.Any(), gomock.Any()).Return(fmt.Errorf("err"))
+	assert.NotNil(t, flusher.Commit())
+}
+
+func TestMultiFlusher(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	f1 := NewMockFlusher(ctrl)
+	f2 := NewMockFlusher(ctrl)
+	flusher := NewMultiFlusher(f1, f2)
+
+	f1.EXPECT().Add(uint32(1), []byte("v1")).Return(nil)
+	f2.EXPECT().Add(uint32(1), []byte("v1")).Return(nil)
+	assert.Nil(t, flusher.Add(1, []byte("v1")))
+	f1.EXPECT().Add(uint32(2), []byte("v2")).Return(fmt.Errorf("err"))
+	assert.NotNil(t, flusher.Add(2, []byte("v2")))
+
+	f1.EXPECT().Commit().Return(nil)
+	f2.EXPECT().Commit().Return(nil)
+	assert.Nil(t, flusher.Commit())
+	f1.EXPECT().Commit().Return(fmt.Errorf("err"))
+	assert.NotNil(t, flusher.Commit())
+}
@@ -7,6 +7,8 @@ type FamilyOption struct {
 	CompactThreshold int    `toml:"compactThreshold"` // level 0 compact threshold
 	Merger           string `toml:"merger"`           // merger which need implement Merger interface
 	MaxFileSize      int32  `toml:"maxFileSize"`      // max file size
+	FlushBufferSize  int    `toml:"flushBufferSize"`  // write buffer size of table file, default 256KB if 0
+	FormatVersion    int    `toml:"formatVersion"`    // layout version of table file written, version 0 if not set
 }
 
 // StoreOption defines config item for store level
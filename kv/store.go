@@ -153,6 +153,9 @@ func NewStore(name string, option StoreOption) (Store, error) {
 
 // CreateFamily create/load column family.
 func (s *store) CreateFamily(familyName string, option FamilyOption) (Family, error) {
+	if err := table.ValidateFormatVersion(option.FormatVersion); err != nil {
+		return nil, err
+	}
 	s.rwMutex.RLock()
 	family, ok := s.families[familyName]
 	s.rwMutex.RUnlock()
@@ -94,6 +94,11 @@ func TestCreateFamily(t *testing.T) {
 	f11 := kv.GetFamily("f11")
 	assert.Nil(t, f11)
 
+	// unknown format version of table file
+	f3, err3 := kv.CreateFamily("f3", FamilyOption{Merger: mergerStr, FormatVersion: 100})
+	assert.NotNil(t, err3)
+	assert.Nil(t, f3)
+
 	// cannot re-open
 	_, e := NewStore("test_kv", option)
 	assert.NotNil(t, e)
@@ -16,10 +16,21 @@ import (
 const (
 	// magic-number in the footer of sst file
 	magicNumberOffsetFile uint64 = 0x69632d656d656c65
-	// current file layout version
-	version0 = 0
+	// first file layout version
+	version0 byte = 0
+	// CurrentFormatVersion is the latest file layout version supported
+	CurrentFormatVersion = version0
 )
 
+// ValidateFormatVersion checks if the file layout version is supported by the reader of current release.
+func ValidateFormatVersion(formatVersion int) error {
+	if formatVersion < 0 || formatVersion > int(CurrentFormatVersion) {
+		return fmt.Errorf("unknown format version:%d of sstfile, max supported version:%d",
+			formatVersion, CurrentFormatVersion)
+	}
+	return nil
+}
+
 // Builder builds sst file
 type Builder interface {
 	// FileNumber returns file name for store builder
@@ -47,6 +58,7 @@ type storeBuilder struct {
 	fileName   string
 	writer     bufioutil.BufioWriter
 	offset     *encoding.DeltaBitPackingEncoder
+	version    byte // file layout version written in the footer
 
 	// see paper of roaring bitmap: https://arxiv.org/pdf/1603.06549.pdf
 	keys   *roaring.Bitmap
@@ -60,8 +72,23 @@ type storeBuilder struct {
 
 // NewStoreBuilder creates store builder instance for building store file
 func NewStoreBuilder(fileNumber int64, fileName string) (Builder, error) {
+	return NewStoreBuilderWithBufferSize(fileNumber, fileName, 0)
+}
+
+// NewStoreBuilderWithBufferSize creates store builder instance with the write buffer size of store file,
+// uses the default buffer size if bufferSize <= 0.
+func NewStoreBuilderWithBufferSize(fileNumber int64, fileName string, bufferSize int) (Builder, error) {
+	return NewStoreBuilderWithVersion(fileNumber, fileName, bufferSize, int(CurrentFormatVersion))
+}
+
+// NewStoreBuilderWithVersion creates store builder instance writing the file layout of the format version,
+// so that the files keep readable by the old release during rolling upgrade.
+func NewStoreBuilderWithVersion(fileNumber int64, fileName string, bufferSize, formatVersion int) (Builder, error) {
+	if err := ValidateFormatVersion(formatVersion); err != nil {
+		return nil, err
+	}
 	log := logger.GetLogger("kv", fmt.Sprintf("Builder[%s]", fileName))
-	writer, err := bufioutil.NewBufioWriter(fileName)
+	writer, err := bufioutil.NewBufioWriterSize(fileName, bufferSize)
 	if err != nil {
 		return nil, fmt.Errorf("create file write for store builder error:%s", err)
 	}
@@ -73,6 +100,7 @@ func NewStoreBuilder(fileNumber int64, fileName string) (Builder, error) {
 		writer:     writer,
 		first:      true,
 		offset:     encoding.NewDeltaBitPackingEncoder(),
+		version:    byte(formatVersion),
 	}, nil
 }
 
@@ -156,7 +184,7 @@ func (b *storeBuilder) Close() error {
 	var buf [17]byte
 	binary.LittleEndian.PutUint32(buf[:4], uint32(posOfOffset))
 	binary.LittleEndian.PutUint32(buf[4:8], uint32(posOfKeys))
-	buf[8] = version0
+	buf[8] = b.version
 	binary.LittleEndian.PutUint64(buf[9:], magicNumberOffsetFile)
 	if _, err = b.writer.Write(buf[:]); err != nil {
 		return err
@@ -47,6 +47,16 @@ func Test_BuildStore(t *testing.T) {
 	assert.True(t, builder.Size() > 0)
 }
 
+func TestStoreBuilder_formatVersion(t *testing.T) {
+	assert.NoError(t, ValidateFormatVersion(int(CurrentFormatVersion)))
+	assert.Error(t, ValidateFormatVersion(-1))
+	assert.Error(t, ValidateFormatVersion(int(CurrentFormatVersion)+1))
+
+	builder, err := NewStoreBuilderWithVersion(10, testKVPath+"/000010.sst", 0, int(CurrentFormatVersion)+1)
+	assert.Error(t, err)
+	assert.Nil(t, builder)
+}
+
 func TestStoreBuilder_Abandon(t *testing.T) {
 	_ = fileutil.MkDirIfNotExist(testKVPath)
 	defer func() {
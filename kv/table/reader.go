@@ -87,6 +87,18 @@ func (r *storeMMapReader) initialize() error {
 	if binary.LittleEndian.Uint64(buf[9:]) != magicNumberOffsetFile {
 		return fmt.Errorf("verify magic-number of sstfile:%s failure", r.path)
 	}
+	// dispatch on format version, refuses the file written by newer release instead of misparsing it
+	switch formatVersion := buf[8]; formatVersion {
+	case version0:
+		return r.initializeV0(buf)
+	default:
+		return fmt.Errorf("unknown format version:%d of sstfile:%s, max supported version:%d",
+			formatVersion, r.path, CurrentFormatVersion)
+	}
+}
+
+// initializeV0 reads the index block of format version 0
+func (r *storeMMapReader) initializeV0(buf []byte) error {
 	posOfOffset := int(binary.LittleEndian.Uint32(buf[:4]))
 	posOfKeys := int(binary.LittleEndian.Uint32(buf[4:8]))
 	if err := r.keys.UnmarshalBinary(r.readBytes(posOfKeys)); err != nil {
@@ -1,6 +1,7 @@
 package table
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
 
@@ -121,3 +122,32 @@ func TestStoreIterator(t *testing.T) {
 
 	assert.False(t, it.HasNext())
 }
+
+func TestReader_formatVersion(t *testing.T) {
+	_ = fileutil.MkDirIfNotExist(testKVPath)
+	defer func() {
+		_ = os.RemoveAll(testKVPath)
+	}()
+
+	fileName := testKVPath + "/000010.sst"
+	builder, err := NewStoreBuilderWithVersion(10, fileName, 0, int(CurrentFormatVersion))
+	assert.NoError(t, err)
+	_ = builder.Add(1, []byte("test"))
+	assert.NoError(t, builder.Close())
+
+	// current version
+	reader, err := newMMapStoreReader(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("test"), reader.Get(1))
+	_ = reader.Close()
+
+	// bumps the version byte in footer: [..., version(1), magicNumber(8)]
+	data, err := ioutil.ReadFile(fileName)
+	assert.NoError(t, err)
+	data[len(data)-9] = CurrentFormatVersion + 1
+	assert.NoError(t, ioutil.WriteFile(fileName, data, 0644))
+	reader, err = newMMapStoreReader(fileName)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown format version")
+	assert.Nil(t, reader)
+}
@@ -6,16 +6,27 @@ import "fmt"
 type BrokerReplicaState struct {
 	ReportTime int64          `json:"reportTime"` // broker report state's time(millisecond)
 	Replicas   []ReplicaState `json:"replicas"`   // replica state list under this broker
+	Channels   ChannelStats   `json:"channels"`   // stats of replication channels under this broker
+}
+
+// ChannelStats represents the count of replication channels by state and the total pending messages
+type ChannelStats struct {
+	Healthy       int   `json:"healthy"`       // num. of healthy channels
+	Backpressured int   `json:"backpressured"` // num. of channels which write buffer is full
+	CircuitOpen   int   `json:"circuitOpen"`   // num. of channels which any replicator is disconnected
+	Pending       int64 `json:"pending"`       // total num. of pending messages across all channels
 }
 
 // ReplicaState represents the status of replicator's channel
 type ReplicaState struct {
-	Database     string `json:"database"`     // database name
-	ShardID      int32  `json:"shardID"`      // shard id
-	Target       Node   `json:"target"`       // target storage node for database's shard
-	Pending      int64  `json:"pending"`      // the num. of pending which it need replica msg
-	ReplicaIndex int64  `json:"replicaIndex"` // replica index for current replicator's channel
-	AckIndex     int64  `json:"ackIndex"`     // commit index
+	Database     string  `json:"database"`     // database name
+	ShardID      int32   `json:"shardID"`      // shard id
+	Target       Node    `json:"target"`       // target storage node for database's shard
+	Pending      int64   `json:"pending"`      // the num. of pending which it need replica msg
+	ReplicaIndex int64   `json:"replicaIndex"` // replica index for current replicator's channel
+	AckIndex     int64   `json:"ackIndex"`     // commit index
+	LastAckedSeq int64   `json:"lastAckedSeq"` // the last seq acked by target, -1 if no ack received
+	AppendRate   float64 `json:"appendRate"`   // num. of messages appended into channel per second
 }
 
 // ShardIndicator returns shard indicator based on database/shard id
@@ -1,5 +1,11 @@
 package models
 
+import (
+	"encoding/json"
+
+	"github.com/lindb/lindb/pkg/timeutil"
+)
+
 // ResultSet represents the query result set
 type ResultSet struct {
 	MetricName string    `json:"metricName,omitempty"`
@@ -7,6 +13,47 @@ type ResultSet struct {
 	EndTime    int64     `json:"endTime,omitempty"`
 	Interval   int64     `json:"interval,omitempty"`
 	Series     []*Series `json:"series,omitempty"`
+	// Partial represents some storage nodes returned partial results because the scan budget elapsed
+	Partial bool `json:"partial,omitempty"`
+
+	// NullAware renders the missing points in time range as null when json encoding,
+	// so that charts draw the gaps correctly.
+	NullAware bool `json:"-"`
+}
+
+// nullAwareSeries represents the series which field values are nullable
+type nullAwareSeries struct {
+	Tags   map[string]string             `json:"tags,omitempty"`
+	Fields map[string]map[int64]*float64 `json:"fields,omitempty"`
+}
+
+// MarshalJSON returns json data of result set, renders the missing points as null if null aware
+func (rs *ResultSet) MarshalJSON() ([]byte, error) {
+	type resultSet ResultSet // avoids recursive calling
+	if !rs.NullAware || rs.Interval <= 0 {
+		return json.Marshal((*resultSet)(rs))
+	}
+	inner := struct {
+		*resultSet
+		Series []*nullAwareSeries `json:"series,omitempty"`
+	}{resultSet: (*resultSet)(rs)}
+	pointCount := timeutil.CalPointCount(rs.StartTime, rs.EndTime, rs.Interval)
+	for _, series := range rs.Series {
+		s := &nullAwareSeries{Tags: series.Tags, Fields: make(map[string]map[int64]*float64)}
+		for fieldName, points := range series.Fields {
+			values := make(map[int64]*float64, pointCount)
+			for i := 0; i < pointCount; i++ {
+				values[rs.StartTime+int64(i)*rs.Interval] = nil
+			}
+			for timestamp := range points {
+				value := points[timestamp]
+				values[timestamp] = &value
+			}
+			s.Fields[fieldName] = values
+		}
+		inner.Series = append(inner.Series, s)
+	}
+	return json.Marshal(&inner)
 }
 
 // NewResultSet creates a new result set
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -25,3 +26,36 @@ func TestResultSet(t *testing.T) {
 		int64(20): 10.0},
 		s.Fields["f1"])
 }
+
+func TestResultSet_MarshalJSON_NullAware(t *testing.T) {
+	rs := NewResultSet()
+	rs.StartTime = 10
+	rs.EndTime = 50
+	rs.Interval = 10
+	series := NewSeries(map[string]string{"key": "value"})
+	rs.AddSeries(series)
+	points := NewPoints()
+	points.AddPoint(int64(10), 0)
+	points.AddPoint(int64(30), 1.5)
+	series.AddField("f1", points)
+
+	// gaps omitted by default
+	data, err := json.Marshal(rs)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"startTime":10,"endTime":50,"interval":10,`+
+		`"series":[{"tags":{"key":"value"},"fields":{"f1":{"10":0,"30":1.5}}}]}`, string(data))
+
+	// gaps as null, real zero as 0
+	rs.NullAware = true
+	data, err = json.Marshal(rs)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"startTime":10,"endTime":50,"interval":10,`+
+		`"series":[{"tags":{"key":"value"},"fields":{"f1":{"10":0,"20":null,"30":1.5,"40":null,"50":null}}}]}`, string(data))
+
+	// without interval
+	rs.Interval = 0
+	data, err = json.Marshal(rs)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"startTime":10,"endTime":50,`+
+		`"series":[{"tags":{"key":"value"},"fields":{"f1":{"10":0,"30":1.5}}}]}`, string(data))
+}
@@ -3,6 +3,8 @@ package parallel
 import (
 	"context"
 	"errors"
+	"sync"
+	"time"
 
 	"go.uber.org/atomic"
 
@@ -26,6 +28,10 @@ const (
 	IntermediateTask
 )
 
+// CancelTaskRequest is the type of task request which cancels the dispatched leaf task,
+// the leaf task is identified by the job id and parent task id of request.
+const CancelTaskRequest int32 = 1
+
 // ExecuteContext represents the execute context
 type ExecuteContext interface {
 	// RetainTask adds the task count
@@ -34,6 +40,8 @@ type ExecuteContext interface {
 	Emit(event *series.TimeSeriesEvent)
 	// Complete completes the task with err if task execute fail
 	Complete(err error)
+	// Context returns the context for scanning data, which is canceled if the scan results are not needed any more
+	Context() context.Context
 }
 
 // BrokerExecuteContext represents the broker execute context
@@ -47,23 +55,34 @@ type BrokerExecuteContext interface {
 }
 
 type brokerExecuteContext struct {
+	ctx        context.Context
 	resultCh   chan *series.TimeSeriesEvent
 	err        error
 	query      *stmt.Query
 	expression aggregation.Expression
 	resultSet  *models.ResultSet
+	sorter     *seriesSorter // sorts the series by tags under the max memory of query, nil if unlimited
 }
 
-func NewBrokerExecuteContext(query *stmt.Query) BrokerExecuteContext {
-	ctx := &brokerExecuteContext{
+// NewBrokerExecuteContext creates the broker execute context,
+// if maxMemory > 0, the series of result set are sorted by tags under the max memory(bytes),
+// and spilled to spill dir when the memory limit is exceeded, the query fails if spill dir is empty.
+// the spilled files are removed once the ctx of query is done.
+func NewBrokerExecuteContext(ctx context.Context, query *stmt.Query, maxMemory int64, spillDir string,
+) BrokerExecuteContext {
+	c := &brokerExecuteContext{
+		ctx:       ctx,
 		resultCh:  make(chan *series.TimeSeriesEvent),
 		resultSet: models.NewResultSet(),
 		query:     query,
 	}
+	if maxMemory > 0 {
+		c.sorter = newSeriesSorter(ctx, maxMemory, spillDir)
+	}
 	if query != nil {
-		ctx.expression = aggregation.NewExpression(query.TimeRange, query.Interval, query.SelectItems)
+		c.expression = aggregation.NewExpression(query.TimeRange, query.Interval, query.SelectItems, query.Fill)
 	}
-	return ctx
+	return c
 }
 
 func (c *brokerExecuteContext) RetainTask(tasks int32) {
@@ -74,10 +93,12 @@ func (c *brokerExecuteContext) Emit(event *series.TimeSeriesEvent) {
 		c.err = event.Err
 		return
 	}
+	if event.Partial {
+		c.resultSet.Partial = true
+	}
 
 	for _, ts := range event.SeriesList {
 		timeSeries := models.NewSeries(ts.Tags())
-		c.resultSet.AddSeries(timeSeries)
 		c.expression.Eval(ts)
 		rs := c.expression.ResultSet()
 		for fieldName, values := range rs {
@@ -93,21 +114,48 @@ func (c *brokerExecuteContext) Emit(event *series.TimeSeriesEvent) {
 			timeSeries.AddField(fieldName, points)
 		}
 		c.expression.Reset()
+		if c.sorter == nil {
+			c.resultSet.AddSeries(timeSeries)
+			continue
+		}
+		if err := c.sorter.Add(timeSeries); err != nil {
+			c.err = err
+			return
+		}
 	}
 }
 
 func (c *brokerExecuteContext) Complete(err error) {
 	if err != nil {
 		c.err = err
+		// the result set is not needed any more, removes the spilled files
+		if c.sorter != nil {
+			c.sorter.Close()
+		}
 		close(c.resultCh)
 	}
 }
 
+// Context returns the context of query
+func (c *brokerExecuteContext) Context() context.Context {
+	return c.ctx
+}
+
 func (c *brokerExecuteContext) ResultCh() chan *series.TimeSeriesEvent {
 	return c.resultCh
 }
 
+// ResultSet returns the final result set, the series emitted since last calling are sorted if memory limited
 func (c *brokerExecuteContext) ResultSet() (*models.ResultSet, error) {
+	if c.sorter != nil {
+		seriesList, err := c.sorter.Sorted()
+		if err != nil && c.err == nil {
+			c.err = err
+		}
+		if c.err == nil {
+			c.resultSet.Series = append(c.resultSet.Series, seriesList...)
+		}
+	}
 	c.resultSet.MetricName = c.query.MetricName
 	c.resultSet.StartTime = c.query.TimeRange.Start
 	c.resultSet.EndTime = c.query.TimeRange.End
@@ -115,29 +163,51 @@ func (c *brokerExecuteContext) ResultSet() (*models.ResultSet, error) {
 	return c.resultSet, c.err
 }
 
-// storageExecuteContext represents the storage query executor context
+// storageExecuteContext represents the storage query executor context,
+// sends the partial results aggregated so far when the scan budget elapses before all tasks completed.
 type storageExecuteContext struct {
 	ctx         context.Context
 	taskCounter atomic.Int32 // pending task ref counter
 	stream      pb.TaskService_HandleServer
 	req         *pb.TaskRequest
+	budgetTimer *time.Timer
+
+	// scanCtx is canceled after sending result, the scans running in background are aborted,
+	// not derived from ctx which is canceled once the task is dispatched.
+	scanCtx    context.Context
+	cancelScan context.CancelFunc
 
 	timeSeriesList []*pb.TimeSeries
+	partial        bool // the results are partial, like some shards are missing
 
-	completed atomic.Bool
+	completed   atomic.Bool
+	onCompleted func() // called once the result is sent or the task is canceled, nil if not needed
 
-	err error
+	err   error
+	mutex sync.Mutex
 }
 
+// newStorageExecutorContext creates the storage executor context, the scan budget is disabled if budget <= 0,
+// onCompleted is called once the result is sent or the task is canceled.
 func newStorageExecutorContext(ctx context.Context,
 	req *pb.TaskRequest,
 	stream pb.TaskService_HandleServer,
+	budget time.Duration,
+	onCompleted func(),
 ) ExecuteContext {
-	return &storageExecuteContext{
-		ctx:    ctx,
-		req:    req,
-		stream: stream,
+	c := &storageExecuteContext{
+		ctx:         ctx,
+		req:         req,
+		stream:      stream,
+		onCompleted: onCompleted,
 	}
+	c.scanCtx, c.cancelScan = context.WithCancel(context.Background())
+	if budget > 0 {
+		c.budgetTimer = time.AfterFunc(budget, func() {
+			c.sendResult(true)
+		})
+	}
+	return c
 }
 
 func (c *storageExecuteContext) RetainTask(tasks int32) {
@@ -145,6 +215,9 @@ func (c *storageExecuteContext) RetainTask(tasks int32) {
 }
 
 func (c *storageExecuteContext) Emit(event *series.TimeSeriesEvent) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	if c.completed.Load() {
 		return
 	}
@@ -152,8 +225,15 @@ func (c *storageExecuteContext) Emit(event *series.TimeSeriesEvent) {
 		c.err = event.Err
 		return
 	}
+	if event.Partial {
+		c.partial = true
+	}
 
 	for _, ts := range event.SeriesList {
+		if event.SeriesOnly {
+			c.timeSeriesList = append(c.timeSeriesList, &pb.TimeSeries{Tags: ts.Tags()})
+			continue
+		}
 		fields := make(map[string][]byte)
 		for ts.HasNext() {
 			fieldIt := ts.Next()
@@ -161,6 +241,11 @@ func (c *storageExecuteContext) Emit(event *series.TimeSeriesEvent) {
 			if err != nil || len(data) == 0 {
 				continue
 			}
+			// delta encoding before rpc transmission, decoded by result merger
+			data, err = series.EncodeDelta(data)
+			if err != nil {
+				continue
+			}
 
 			fields[fieldIt.FieldName()] = data
 		}
@@ -176,33 +261,78 @@ func (c *storageExecuteContext) Emit(event *series.TimeSeriesEvent) {
 func (c *storageExecuteContext) Complete(err error) {
 	newVal := c.taskCounter.Dec()
 	if err != nil {
+		c.mutex.Lock()
 		c.err = err
+		c.mutex.Unlock()
 	}
 	// if all tasks completed, close result channel
 	if newVal == 0 {
-		c.completed.Store(true)
-		errMsg := ""
-		var data []byte
-		if c.err != nil {
-			errMsg = c.err.Error()
-		} else {
-			seriesList := pb.TimeSeriesList{
-				TimeSeriesList: c.timeSeriesList,
-			}
-			// no error
-			data, _ = seriesList.Marshal()
+		if c.budgetTimer != nil {
+			c.budgetTimer.Stop()
 		}
+		c.sendResult(false)
+	}
+}
+
+// Context returns the context for scanning data, which is canceled after sending result
+func (c *storageExecuteContext) Context() context.Context {
+	return c.scanCtx
+}
 
-		// send result to upstream
-		if err := c.stream.Send(&pb.TaskResponse{
-			JobID:     c.req.JobID,
-			TaskID:    c.req.ParentTaskID,
-			Completed: true,
-			Payload:   data,
-			ErrMsg:    errMsg,
-		}); err != nil {
-			execLogger.Error("send storage execute result", logger.Error(err))
+// cancel cancels the task without sending result, the scans running in background are aborted
+func (c *storageExecuteContext) cancel() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.completed.CAS(false, true) {
+		return
+	}
+	if c.budgetTimer != nil {
+		c.budgetTimer.Stop()
+	}
+	c.cancelScan()
+	if c.onCompleted != nil {
+		c.onCompleted()
+	}
+}
+
+// sendResult sends the result aggregated so far to upstream only once,
+// partial represents the scan budget elapsed before all tasks completed.
+func (c *storageExecuteContext) sendResult(partial bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.completed.CAS(false, true) {
+		return
+	}
+	// the results emitted after sending are dropped, stops scanning
+	c.cancelScan()
+	if c.onCompleted != nil {
+		c.onCompleted()
+	}
+
+	errMsg := ""
+	var data []byte
+	if c.err != nil {
+		errMsg = c.err.Error()
+	} else {
+		seriesList := pb.TimeSeriesList{
+			TimeSeriesList: c.timeSeriesList,
 		}
+		// no error
+		data, _ = seriesList.Marshal()
+	}
+
+	// send result to upstream
+	if err := c.stream.Send(&pb.TaskResponse{
+		JobID:     c.req.JobID,
+		TaskID:    c.req.ParentTaskID,
+		Completed: true,
+		Partial:   partial || c.partial,
+		Payload:   data,
+		ErrMsg:    errMsg,
+	}); err != nil {
+		execLogger.Error("send storage execute result", logger.Error(err))
 	}
 }
 
@@ -276,8 +406,9 @@ type TaskContext interface {
 	ParentNode() string
 	// ParentTaskID returns the parent node's task id for tracking task
 	ParentTaskID() string
-	// ReceiveResult marks receive result, decreases the num. of task tracking
-	ReceiveResult(resp *pb.TaskResponse)
+	// ReceiveResult marks receive result, decreases the num. of task tracking,
+	// returns true if the task is completed by this result, the results received after completed are ignored
+	ReceiveResult(resp *pb.TaskResponse) (completed bool)
 	// Completed returns if the task is completes
 	Completed() bool
 	// Error returns task's error
@@ -291,20 +422,23 @@ type taskContext struct {
 	parentTaskID string
 	parentNode   string
 	merger       ResultMerger
+	jobCtx       JobContext // job context of root task, completed after root task completed
 
 	err           error
 	expectResults *atomic.Int32
+	mutex         sync.Mutex // guards the completion of task, e.g. result received concurrently with reaping
 }
 
-// newTaskContext creates the task context based on params
+// newTaskContext creates the task context based on params, job context is nil if not root task
 func newTaskContext(taskID string, taskType TaskType, parentTaskID string, parentNode string,
-	expectResults int32, merger ResultMerger) TaskContext {
+	expectResults int32, merger ResultMerger, jobCtx JobContext) TaskContext {
 	return &taskContext{
 		taskID:        taskID,
 		taskType:      taskType,
 		parentTaskID:  parentTaskID,
 		parentNode:    parentNode,
 		merger:        merger,
+		jobCtx:        jobCtx,
 		expectResults: atomic.NewInt32(expectResults),
 	}
 }
@@ -329,16 +463,21 @@ func (c *taskContext) TaskID() string {
 }
 
 // ReceiveResult marks receive result, decreases the num. of task tracking,
-// if no pending task marks this task completed
-func (c *taskContext) ReceiveResult(resp *pb.TaskResponse) {
+// if no pending task or receives error, marks this task completed, then closes the merger and completes the job,
+// returns true if the task is completed by this result, the results received after completed are ignored
+func (c *taskContext) ReceiveResult(resp *pb.TaskResponse) (completed bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// task is completed need return it
+	if c.Completed() {
+		return false
+	}
 	if len(resp.ErrMsg) > 0 {
 		c.expectResults.Store(0)
 		c.err = errors.New(resp.ErrMsg)
-		return
-	}
-	// task is completed need return it
-	if c.Completed() {
-		return
+		c.complete()
+		return true
 	}
 	// merge the response
 	c.merger.merge(resp)
@@ -350,7 +489,18 @@ func (c *taskContext) ReceiveResult(resp *pb.TaskResponse) {
 	// check if task completed,
 	// if yes, closes the merger
 	if c.Completed() {
-		c.merger.close()
+		c.complete()
+		return true
+	}
+	return false
+}
+
+// complete closes the merger which sends the result set or error of task,
+// then completes the job if root task
+func (c *taskContext) complete() {
+	c.merger.close(c.err)
+	if c.jobCtx != nil {
+		c.jobCtx.Complete()
 	}
 }
 
@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/lindb/lindb/aggregation"
 	"github.com/lindb/lindb/pkg/collections"
+	"github.com/lindb/lindb/pkg/fileutil"
 	"github.com/lindb/lindb/pkg/timeutil"
 	pb "github.com/lindb/lindb/rpc/proto/common"
 	"github.com/lindb/lindb/series"
@@ -27,12 +29,13 @@ func TestBrokerExecuteContext(t *testing.T) {
 	assert.NoError(t, err)
 	query.Interval = 10 * timeutil.OneSecond
 
-	ctx := NewBrokerExecuteContext(query)
+	ctx := NewBrokerExecuteContext(context.Background(), query, 0, "")
 	brokerCtx := ctx.(*brokerExecuteContext)
 	brokerCtx.expression = expression
 	ctx.RetainTask(10)
 	assert.NotNil(t, brokerCtx.expression)
 	assert.NotNil(t, ctx.ResultCh())
+	assert.NotNil(t, ctx.Context())
 	it := series.NewMockGroupedIterator(ctrl)
 	it.EXPECT().Tags().Return(nil)
 	expression.EXPECT().Eval(gomock.Any())
@@ -54,6 +57,53 @@ func TestBrokerExecuteContext(t *testing.T) {
 	assert.NotNil(t, rs.Series[0].Fields["f"])
 }
 
+func TestBrokerExecuteContext_sort(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer func() {
+		_ = fileutil.RemoveDir("test_data")
+		ctrl.Finish()
+	}()
+	_ = fileutil.MkDirIfNotExist(testSpillPath)
+
+	query, err := sql.Parse("select f from cpu")
+	assert.NoError(t, err)
+	query.Interval = 10 * timeutil.OneSecond
+	emit := func(ctx BrokerExecuteContext, hosts ...string) {
+		expression := aggregation.NewMockExpression(ctrl)
+		ctx.(*brokerExecuteContext).expression = expression
+		var seriesList []series.GroupedIterator
+		for _, host := range hosts {
+			it := series.NewMockGroupedIterator(ctrl)
+			it.EXPECT().Tags().Return(map[string]string{"host": host})
+			seriesList = append(seriesList, it)
+		}
+		values := collections.NewFloatArray(10)
+		values.SetValue(1, 10.0)
+		expression.EXPECT().Eval(gomock.Any()).AnyTimes()
+		expression.EXPECT().ResultSet().Return(map[string]collections.FloatArray{"f": values}).AnyTimes()
+		expression.EXPECT().Reset().AnyTimes()
+		ctx.Emit(&series.TimeSeriesEvent{SeriesList: seriesList})
+	}
+
+	// spill to disk
+	ctx := NewBrokerExecuteContext(context.Background(), query, 1, testSpillPath)
+	emit(ctx, "c", "a")
+	emit(ctx, "b")
+	rs, err := ctx.ResultSet()
+	assert.NoError(t, err)
+	assert.Len(t, rs.Series, 3)
+	for idx, host := range []string{"a", "b", "c"} {
+		assert.Equal(t, host, rs.Series[idx].Tags["host"])
+		assert.Equal(t, 10.0, rs.Series[idx].Fields["f"][query.TimeRange.Start+10*timeutil.OneSecond])
+	}
+	// spill disabled
+	ctx = NewBrokerExecuteContext(context.Background(), query, 1, "")
+	emit(ctx, "c", "a")
+	rs, err = ctx.ResultSet()
+	assert.Equal(t, errQueryMemoryExceeded, err)
+	assert.Empty(t, rs.Series)
+}
+
 func TestStorageExecuteContext(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -63,7 +113,7 @@ func TestStorageExecuteContext(t *testing.T) {
 	ctx := newStorageExecutorContext(context.TODO(), &pb.TaskRequest{
 		JobID:        10,
 		ParentTaskID: "task_1",
-	}, stream)
+	}, stream, 0, nil)
 	assert.NotNil(t, ctx)
 
 	stream.EXPECT().Send(gomock.Any()).Return(fmt.Errorf("err"))
@@ -73,14 +123,17 @@ func TestStorageExecuteContext(t *testing.T) {
 		Err: fmt.Errorf("err"),
 	})
 	ctx.Complete(nil)
+	assert.NoError(t, ctx.Context().Err())
 	ctx.Complete(fmt.Errorf("err"))
+	// scans are canceled after sending result
+	assert.Equal(t, context.Canceled, ctx.Context().Err())
 	ctx.Emit(nil)
 
 	// test normal case
 	ctx = newStorageExecutorContext(context.TODO(), &pb.TaskRequest{
 		JobID:        10,
 		ParentTaskID: "task_1",
-	}, stream)
+	}, stream, 0, nil)
 	ctx.RetainTask(1)
 	gIt := series.NewMockGroupedIterator(ctrl)
 	it := series.NewMockIterator(ctrl)
@@ -111,7 +164,20 @@ func TestStorageExecuteContext(t *testing.T) {
 		it.EXPECT().FieldType().Return(field.SumField),
 		it.EXPECT().HasNext().Return(true),
 		it.EXPECT().Next().Return(int64(10), fIt),
-		fIt.EXPECT().MarshalBinary().Return([]byte{1, 1, 1}, nil), //normal
+		fIt.EXPECT().MarshalBinary().Return([]byte{1, 1, 1}, nil), //delta encode err
+		it.EXPECT().HasNext().Return(false),
+		gIt.EXPECT().HasNext().Return(false),
+	)
+	ctx.Emit(&series.TimeSeriesEvent{
+		SeriesList: []series.GroupedIterator{gIt},
+	})
+	gomock.InOrder(
+		gIt.EXPECT().HasNext().Return(true),
+		gIt.EXPECT().Next().Return(it),
+		it.EXPECT().FieldType().Return(field.SumField),
+		it.EXPECT().HasNext().Return(true),
+		it.EXPECT().Next().Return(int64(10), fIt),
+		fIt.EXPECT().MarshalBinary().Return([]byte{1, 0, 1, 8, 0, 0, 0, 0}, nil), //normal
 		it.EXPECT().HasNext().Return(false),
 		it.EXPECT().FieldName().Return("f"),
 		gIt.EXPECT().HasNext().Return(false),
@@ -124,3 +190,136 @@ func TestStorageExecuteContext(t *testing.T) {
 	stream.EXPECT().Send(gomock.Any()).Return(nil)
 	ctx.Complete(nil)
 }
+
+func TestStorageExecuteContext_budget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	stream := pb.NewMockTaskService_HandleServer(ctrl)
+	sent := make(chan *pb.TaskResponse, 1)
+	stream.EXPECT().Send(gomock.Any()).DoAndReturn(func(resp *pb.TaskResponse) error {
+		sent <- resp
+		return nil
+	})
+
+	ctx := newStorageExecutorContext(context.TODO(), &pb.TaskRequest{
+		JobID:        10,
+		ParentTaskID: "task_1",
+	}, stream, 50*time.Millisecond, nil)
+	ctx.RetainTask(1)
+
+	gIt := series.NewMockGroupedIterator(ctrl)
+	it := series.NewMockIterator(ctrl)
+	fIt := series.NewMockFieldIterator(ctrl)
+	gomock.InOrder(
+		gIt.EXPECT().HasNext().Return(true),
+		gIt.EXPECT().Next().Return(it),
+		it.EXPECT().FieldType().Return(field.SumField),
+		it.EXPECT().HasNext().Return(true),
+		it.EXPECT().Next().Return(int64(10), fIt),
+		fIt.EXPECT().MarshalBinary().Return([]byte{1, 0, 1, 8, 0, 0, 0, 0}, nil),
+		it.EXPECT().HasNext().Return(false),
+		it.EXPECT().FieldName().Return("f"),
+		gIt.EXPECT().HasNext().Return(false),
+		gIt.EXPECT().Tags().Return(map[string]string{"host": "1.1.1.1"}),
+	)
+	// slow scanner: emits the first series, then scans beyond the budget
+	ctx.Emit(&series.TimeSeriesEvent{
+		SeriesList: []series.GroupedIterator{gIt},
+	})
+
+	select {
+	case resp := <-sent:
+		assert.True(t, resp.Completed)
+		assert.True(t, resp.Partial)
+		assert.Empty(t, resp.ErrMsg)
+		tsList := &pb.TimeSeriesList{}
+		assert.NoError(t, tsList.Unmarshal(resp.Payload))
+		assert.Len(t, tsList.TimeSeriesList, 1)
+		assert.Equal(t, map[string]string{"host": "1.1.1.1"}, tsList.TimeSeriesList[0].Tags)
+	case <-time.After(time.Second):
+		t.Fatal("partial result not sent when budget elapsed")
+	}
+	// the slow scans are canceled after budget elapsed
+	assert.Equal(t, context.Canceled, ctx.Context().Err())
+
+	// events after budget elapsed are dropped, result not sent again
+	ctx.Emit(&series.TimeSeriesEvent{
+		SeriesList: []series.GroupedIterator{gIt},
+	})
+	ctx.Complete(nil)
+}
+
+func TestStorageExecuteContext_completeWithinBudget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	stream := pb.NewMockTaskService_HandleServer(ctrl)
+	stream.EXPECT().Send(gomock.Any()).DoAndReturn(func(resp *pb.TaskResponse) error {
+		assert.True(t, resp.Completed)
+		assert.False(t, resp.Partial)
+		return nil
+	})
+
+	ctx := newStorageExecutorContext(context.TODO(), &pb.TaskRequest{
+		JobID:        10,
+		ParentTaskID: "task_1",
+	}, stream, 50*time.Millisecond, nil)
+	ctx.RetainTask(1)
+	ctx.Complete(nil)
+	// budget timer stopped after completed
+	time.Sleep(100 * time.Millisecond)
+}
+
+func TestStorageExecuteContext_partialEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	stream := pb.NewMockTaskService_HandleServer(ctrl)
+	stream.EXPECT().Send(gomock.Any()).DoAndReturn(func(resp *pb.TaskResponse) error {
+		assert.True(t, resp.Completed)
+		assert.True(t, resp.Partial)
+		assert.Empty(t, resp.ErrMsg)
+		return nil
+	})
+
+	ctx := newStorageExecutorContext(context.TODO(), &pb.TaskRequest{
+		JobID:        10,
+		ParentTaskID: "task_1",
+	}, stream, 0, nil)
+	ctx.RetainTask(1)
+	// some shards are missing
+	ctx.Emit(&series.TimeSeriesEvent{Partial: true})
+	ctx.Complete(nil)
+}
+
+func TestStorageExecuteContext_seriesOnly(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	stream := pb.NewMockTaskService_HandleServer(ctrl)
+	stream.EXPECT().Send(gomock.Any()).DoAndReturn(func(resp *pb.TaskResponse) error {
+		tsList := &pb.TimeSeriesList{}
+		assert.NoError(t, tsList.Unmarshal(resp.Payload))
+		// the series with tags only are sent
+		assert.Equal(t, []*pb.TimeSeries{
+			{Tags: map[string]string{"host": "1.1.1.1"}},
+			{Tags: map[string]string{"host": "1.1.1.2"}},
+		}, tsList.TimeSeriesList)
+		return nil
+	})
+
+	ctx := newStorageExecutorContext(context.TODO(), &pb.TaskRequest{
+		JobID:        10,
+		ParentTaskID: "task_1",
+	}, stream, 0, nil)
+	ctx.RetainTask(1)
+	ctx.Emit(&series.TimeSeriesEvent{
+		SeriesList: []series.GroupedIterator{
+			series.NewGroupedIterator(map[string]string{"host": "1.1.1.1"}, nil),
+			series.NewGroupedIterator(map[string]string{"host": "1.1.1.2"}, nil),
+		},
+		SeriesOnly: true,
+	})
+	ctx.Complete(nil)
+}
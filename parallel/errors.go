@@ -7,4 +7,7 @@ var errUnmarshalQuery = errors.New("unmarshal query statement error")
 var errWrongRequest = errors.New("not found task of current node from physical plan")
 var errNoSendStream = errors.New("not found send stream")
 var errTaskSend = errors.New("send task request error")
+var errTaskTimeout = errors.New("task timeout, sub tasks not completed before deadline")
 var errNoDatabase = errors.New("not found database")
+var errQueryMemoryExceeded = errors.New("query exceeds the max memory limit")
+var errStreamingTopN = errors.New("order by and limit are not supported by streaming query")
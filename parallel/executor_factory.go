@@ -21,11 +21,13 @@ type ExecutorFactory interface {
 		query *stmt.Query,
 	) Executor
 
-	// NewBrokerExecutor creates the broker executor based on params
+	// NewBrokerExecutor creates the broker executor based on params,
+	// the options are applied to the query parsed from sql
 	NewBrokerExecutor(
 		ctx context.Context,
 		databaseName string,
 		sql string,
+		options stmt.QueryOptions,
 		replicaStateMachine replica.StatusStateMachine,
 		nodeStateMachine broker.NodeStateMachine,
 		jobManager JobManager,
@@ -2,13 +2,17 @@ package parallel
 
 import (
 	"context"
+	"time"
+
+	"github.com/damnever/goctl/retry"
 
 	"github.com/lindb/lindb/aggregation"
+	"github.com/lindb/lindb/config"
 	"github.com/lindb/lindb/models"
 	"github.com/lindb/lindb/pkg/encoding"
+	"github.com/lindb/lindb/pkg/logger"
 	"github.com/lindb/lindb/pkg/timeutil"
 	pb "github.com/lindb/lindb/rpc/proto/common"
-	"github.com/lindb/lindb/series/field"
 	"github.com/lindb/lindb/sql/stmt"
 )
 
@@ -20,14 +24,20 @@ type intermediateTask struct {
 	curNode     models.Node
 	curNodeID   string
 	taskManager TaskManager
+	// backoffs of retrying the failed leaf task dispatch
+	retryBackoffs []time.Duration
+
+	logger *logger.Logger
 }
 
 // newIntermediateTask creates the intermediate task
-func newIntermediateTask(curNode models.Node, taskManger TaskManager) *intermediateTask {
+func newIntermediateTask(curNode models.Node, taskManger TaskManager, cfg config.Query) *intermediateTask {
 	return &intermediateTask{
-		curNode:     curNode,
-		curNodeID:   (&curNode).Indicator(),
-		taskManager: taskManger,
+		curNode:       curNode,
+		curNodeID:     (&curNode).Indicator(),
+		taskManager:   taskManger,
+		retryBackoffs: retry.ExponentialBackoffs(cfg.LeafTaskRetries, cfg.LeafTaskRetryBackoff.Duration()),
+		logger:        logger.GetLogger("parallel", "IntermediateTask"),
 	}
 }
 
@@ -43,20 +53,22 @@ func (p *intermediateTask) Process(ctx context.Context, req *pb.TaskRequest) err
 	if err := encoding.JSONUnmarshal(payload, query); err != nil {
 		return errUnmarshalQuery
 	}
-	//fixme
+	// all the time series are merged, the top n time series are selected at the root only,
+	// the field aggregators are created by the field name and type of field series returned by storage
 	groupAgg := aggregation.NewGroupingAggregator(
 		timeutil.Interval(query.Interval),
 		query.TimeRange,
-		aggregation.AggregatorSpecs{
-			aggregation.NewAggregatorSpec("f1", field.SumField)})
+		nil)
+	var taskID string
 	taskSubmitted := false
 	for _, intermediate := range physicalPlan.Intermediates {
 		if intermediate.Indicator == p.curNodeID {
-			taskID := p.taskManager.AllocTaskID()
+			taskID = p.taskManager.AllocTaskID()
 			//TODO set task id
 			taskCtx := newTaskContext(taskID, IntermediateTask, req.ParentTaskID, intermediate.Parent,
-				intermediate.NumOfTask, newResultMerger(ctx, groupAgg, nil))
-			p.taskManager.Submit(taskCtx)
+				intermediate.NumOfTask, newResultMerger(ctx, groupAgg, nil), nil)
+			deadline, _ := ctx.Deadline()
+			p.taskManager.Submit(taskCtx, deadline)
 			taskSubmitted = true
 			break
 		}
@@ -65,25 +77,43 @@ func (p *intermediateTask) Process(ctx context.Context, req *pb.TaskRequest) err
 		return errWrongRequest
 	}
 
-	if err := p.sendLeafTasks(physicalPlan, req); err != nil {
+	if err := p.sendLeafTasks(ctx, physicalPlan, req); err != nil {
+		p.taskManager.Complete(taskID)
 		return err
 	}
 	return nil
 }
 
-// sendLeafTasks sends the task request to the related leaf nodes, if failure return error
-func (p *intermediateTask) sendLeafTasks(physicalPlan models.PhysicalPlan, req *pb.TaskRequest) error {
+// sendLeafTasks sends the task request to the related leaf nodes, retries with backoff if sending fails,
+// if still failure cancels the sent leaf tasks, then returns error
+func (p *intermediateTask) sendLeafTasks(ctx context.Context, physicalPlan models.PhysicalPlan, req *pb.TaskRequest) error {
+	var sentLeafs []string
+	retrier := retry.New(p.retryBackoffs)
 	for _, leaf := range physicalPlan.Leafs {
-		if leaf.Parent == p.curNodeID {
-			if err := p.taskManager.SendRequest(leaf.Indicator, req); err != nil {
-				//TODO kill sent leaf task???
-				return err
-			}
+		if leaf.Parent != p.curNodeID {
+			continue
+		}
+		if err := retrier.Run(ctx, func() (retry.State, error) {
+			return retry.Continue, p.taskManager.SendRequest(leaf.Indicator, req)
+		}); err != nil {
+			p.cancelLeafTasks(sentLeafs, req)
+			return err
 		}
+		sentLeafs = append(sentLeafs, leaf.Indicator)
 	}
 	return nil
 }
 
+// cancelLeafTasks sends the cancel request to the leaf nodes, so that the resources of sent leaf tasks are freed
+func (p *intermediateTask) cancelLeafTasks(leafs []string, req *pb.TaskRequest) {
+	cancelReq := &pb.TaskRequest{JobID: req.JobID, ParentTaskID: req.ParentTaskID, Type: CancelTaskRequest}
+	for _, leaf := range leafs {
+		if err := p.taskManager.SendRequest(leaf, cancelReq); err != nil {
+			p.logger.Warn("cancel leaf task", logger.String("leaf", leaf), logger.Error(err))
+		}
+	}
+}
+
 // Receive receives the sub task's result, and merges the results
 func (p *intermediateTask) Receive(resp *pb.TaskResponse) error {
 	taskID := resp.TaskID
@@ -92,9 +122,7 @@ func (p *intermediateTask) Receive(resp *pb.TaskResponse) error {
 		return nil
 	}
 	//TODO impl result handler
-	taskCtx.ReceiveResult(resp)
-
-	if taskCtx.Completed() {
+	if taskCtx.ReceiveResult(resp) {
 		p.taskManager.Complete(taskID)
 		// if task complete, need send task's result to parent node, if exist parent node
 		if err := p.taskManager.SendResponse(taskCtx.ParentNode(), &pb.TaskResponse{TaskID: taskCtx.ParentTaskID()}); err != nil {
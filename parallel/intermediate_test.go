@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/lindb/lindb/config"
 	"github.com/lindb/lindb/models"
 	"github.com/lindb/lindb/pkg/encoding"
+	"github.com/lindb/lindb/pkg/ltoml"
+	"github.com/lindb/lindb/rpc"
 	pb "github.com/lindb/lindb/rpc/proto/common"
 	"github.com/lindb/lindb/sql"
 )
@@ -20,10 +24,10 @@ func TestIntermediate_Process(t *testing.T) {
 	defer ctrl.Finish()
 
 	taskManager := NewMockTaskManager(ctrl)
-	taskManager.EXPECT().Submit(gomock.Any()).AnyTimes()
+	taskManager.EXPECT().Submit(gomock.Any(), gomock.Any()).AnyTimes()
 
 	currentNode := models.Node{IP: "1.1.1.3", Port: 8000}
-	processor := newIntermediateTask(currentNode, taskManager)
+	processor := newIntermediateTask(currentNode, taskManager, config.Query{})
 
 	// unmarshal error
 	err := processor.Process(context.TODO(), &pb.TaskRequest{PhysicalPlan: nil})
@@ -50,6 +54,7 @@ func TestIntermediate_Process(t *testing.T) {
 	taskManager.EXPECT().AllocTaskID().Return("taskID").AnyTimes()
 	// send request error
 	taskManager.EXPECT().SendRequest(gomock.Any(), gomock.Any()).Return(fmt.Errorf("err"))
+	taskManager.EXPECT().Complete("taskID")
 	err = processor.Process(context.TODO(), &pb.TaskRequest{PhysicalPlan: plan2, Payload: data})
 	assert.NotNil(t, err)
 
@@ -73,7 +78,7 @@ func TestIntermediate_Receive(t *testing.T) {
 	taskManager := NewMockTaskManager(ctrl)
 
 	currentNode := models.Node{IP: "1.1.1.3", Port: 8000}
-	receiver := newIntermediateTask(currentNode, taskManager)
+	receiver := newIntermediateTask(currentNode, taskManager, config.Query{})
 	taskManager.EXPECT().Get("taskID").Return(nil)
 	err := receiver.Receive(&pb.TaskResponse{TaskID: "taskID"})
 	if err != nil {
@@ -83,23 +88,127 @@ func TestIntermediate_Receive(t *testing.T) {
 	// send task result error
 	merger := NewMockResultMerger(ctrl)
 	merger.EXPECT().merge(gomock.Any())
-	merger.EXPECT().close()
+	merger.EXPECT().close(nil)
 	taskManager.EXPECT().Complete("taskID")
 	taskManager.EXPECT().Get("taskID").
-		Return(newTaskContext("taskID", IntermediateTask, "parentTaskID", "parentNode", 1, merger))
+		Return(newTaskContext("taskID", IntermediateTask, "parentTaskID", "parentNode", 1, merger, nil))
 	taskManager.EXPECT().SendResponse(gomock.Any(), gomock.Any()).Return(fmt.Errorf("err"))
 	err = receiver.Receive(&pb.TaskResponse{TaskID: "taskID", Completed: true})
 	assert.NotNil(t, err)
 
 	// normal case
 	merger.EXPECT().merge(gomock.Any())
-	merger.EXPECT().close()
+	merger.EXPECT().close(nil)
 	taskManager.EXPECT().Complete("taskID")
 	taskManager.EXPECT().Get("taskID").
-		Return(newTaskContext("taskID", IntermediateTask, "parentTaskID", "parentNode", 1, merger))
+		Return(newTaskContext("taskID", IntermediateTask, "parentTaskID", "parentNode", 1, merger, nil))
 	taskManager.EXPECT().SendResponse(gomock.Any(), gomock.Any()).Return(nil)
 	err = receiver.Receive(&pb.TaskResponse{TaskID: "taskID", Completed: true})
 	if err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestIntermediate_leafNeverResponds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	currentNode := models.Node{IP: "1.1.1.3", Port: 8000}
+	taskClientFactory := rpc.NewMockTaskClientFactory(ctrl)
+	taskServerFactory := rpc.NewMockTaskServerFactory(ctrl)
+	manager := NewTaskManager(ctx, currentNode, taskClientFactory, taskServerFactory)
+	go manager.(*taskManager).reapLoop(ctx, 10*time.Millisecond)
+	processor := newIntermediateTask(currentNode, manager, config.Query{})
+
+	// leaf receives the request, but never responds
+	client := pb.NewMockTaskService_HandleClient(ctrl)
+	taskClientFactory.EXPECT().GetTaskClient("1.1.1.5:8000").Return(client)
+	client.EXPECT().Send(gomock.Any()).Return(nil)
+	// parent is notified with error after deadline
+	notified := make(chan *pb.TaskResponse, 1)
+	server := pb.NewMockTaskService_HandleServer(ctrl)
+	taskServerFactory.EXPECT().GetStream("1.1.1.1:8000").Return(server)
+	server.EXPECT().Send(gomock.Any()).DoAndReturn(func(resp *pb.TaskResponse) error {
+		notified <- resp
+		return nil
+	})
+
+	plan, _ := json.Marshal(&models.PhysicalPlan{
+		Intermediates: []models.Intermediate{{BaseNode: models.BaseNode{Parent: "1.1.1.1:8000", Indicator: "1.1.1.3:8000"},
+			NumOfTask: 1}},
+		Leafs: []models.Leaf{
+			{BaseNode: models.BaseNode{Parent: "1.1.1.3:8000", Indicator: "1.1.1.5:8000"}},
+		},
+	})
+	query, _ := sql.Parse("select f from cpu group by host")
+	reqCtx, reqCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer reqCancel()
+	start := time.Now()
+	err := processor.Process(reqCtx, &pb.TaskRequest{ParentTaskID: "parentTaskID", PhysicalPlan: plan,
+		Payload: encoding.JSONMarshal(query)})
+	assert.NoError(t, err)
+	taskID := "1.1.1.3:8000-1"
+	assert.NotNil(t, manager.Get(taskID))
+
+	select {
+	case resp := <-notified:
+		assert.Equal(t, "parentTaskID", resp.TaskID)
+		assert.True(t, resp.Completed)
+		assert.Equal(t, errTaskTimeout.Error(), resp.ErrMsg)
+		assert.True(t, time.Since(start) >= 100*time.Millisecond)
+	case <-time.After(time.Second):
+		t.Fatal("parent is not notified after task deadline")
+	}
+	// task is reaped, the late response of leaf is ignored
+	assert.Nil(t, manager.Get(taskID))
+	err = processor.Receive(&pb.TaskResponse{TaskID: taskID, Completed: true})
+	assert.NoError(t, err)
+}
+
+func TestIntermediate_sendLeafTasks_retry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	taskManager := NewMockTaskManager(ctrl)
+	currentNode := models.Node{IP: "1.1.1.3", Port: 8000}
+	processor := newIntermediateTask(currentNode, taskManager, config.Query{
+		LeafTaskRetries:      2,
+		LeafTaskRetryBackoff: ltoml.Duration(time.Millisecond),
+	})
+	plan := models.PhysicalPlan{
+		Leafs: []models.Leaf{
+			{BaseNode: models.BaseNode{Parent: "1.1.1.3:8000", Indicator: "1.1.1.5:8000"}},
+			{BaseNode: models.BaseNode{Parent: "1.1.1.4:8000", Indicator: "1.1.1.6:8000"}},
+			{BaseNode: models.BaseNode{Parent: "1.1.1.3:8000", Indicator: "1.1.1.7:8000"}},
+		},
+	}
+	req := &pb.TaskRequest{JobID: 1, ParentTaskID: "parentTaskID"}
+
+	// flaky leaf succeeds on the second attempt
+	gomock.InOrder(
+		taskManager.EXPECT().SendRequest("1.1.1.5:8000", req).Return(fmt.Errorf("err")),
+		taskManager.EXPECT().SendRequest("1.1.1.5:8000", req).Return(nil),
+		taskManager.EXPECT().SendRequest("1.1.1.7:8000", req).Return(nil),
+	)
+	err := processor.sendLeafTasks(context.TODO(), plan, req)
+	assert.NoError(t, err)
+
+	// retries exhausted, cancels the sent leaf tasks
+	cancelReq := &pb.TaskRequest{JobID: 1, ParentTaskID: "parentTaskID", Type: CancelTaskRequest}
+	gomock.InOrder(
+		taskManager.EXPECT().SendRequest("1.1.1.5:8000", req).Return(nil),
+		taskManager.EXPECT().SendRequest("1.1.1.7:8000", req).Return(fmt.Errorf("err")).Times(3),
+		taskManager.EXPECT().SendRequest("1.1.1.5:8000", cancelReq).Return(fmt.Errorf("err")),
+	)
+	err = processor.sendLeafTasks(context.TODO(), plan, req)
+	assert.Error(t, err)
+
+	// stops retrying if context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	taskManager.EXPECT().SendRequest("1.1.1.5:8000", req).Return(fmt.Errorf("err"))
+	err = processor.sendLeafTasks(ctx, plan, req)
+	assert.Equal(t, context.Canceled, err)
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/lindb/lindb/pkg/encoding"
 	"github.com/lindb/lindb/pkg/timeutil"
 	pb "github.com/lindb/lindb/rpc/proto/common"
-	"github.com/lindb/lindb/series/field"
 )
 
 //go:generate mockgen -source=./job_manager.go -destination=./job_manager_mock.go -package=parallel
@@ -77,17 +76,34 @@ func (j *jobManager) SubmitJob(ctx JobContext) (err error) {
 		Payload:      encoding.JSONMarshal(ctx.Query()),
 	}
 	query := ctx.Query()
-	//TODO fix me
-	groupAgg := aggregation.NewGroupingAggregator(
-		timeutil.Interval(query.Interval),
-		query.TimeRange,
-		aggregation.AggregatorSpecs{
-			aggregation.NewAggregatorSpec("f1", field.SumField),
-		})
-
-	taskCtx := newTaskContext(taskID, RootTask, "", "", plan.Root.NumOfTask,
-		newResultMerger(ctx.Context(), groupAgg, ctx.ResultSet()))
-	j.taskManager.Submit(taskCtx)
+	seriesOrders, err := aggregation.NewSeriesOrdersByQuery(query)
+	if err != nil {
+		return err
+	}
+	var merger ResultMerger
+	if query.Streaming {
+		// the top n time series can't be selected until all the time series of storage nodes are merged
+		if len(seriesOrders) > 0 || query.SeriesLimit() > 0 {
+			return errStreamingTopN
+		}
+		merger = newStreamingResultMerger(ctx.Context(), func() aggregation.GroupingAggregator {
+			return aggregation.NewGroupingAggregator(timeutil.Interval(query.Interval), query.TimeRange, nil)
+		}, ctx.ResultSet())
+	} else {
+		// the top n time series are selected after merging all the time series of storage nodes,
+		// the field aggregators are created by the field name and type of field series returned by storage
+		groupAgg := aggregation.NewTopNGroupingAggregator(
+			timeutil.Interval(query.Interval),
+			query.TimeRange,
+			nil,
+			seriesOrders,
+			query.SeriesLimit())
+		merger = newResultMerger(ctx.Context(), groupAgg, ctx.ResultSet())
+	}
+
+	taskCtx := newTaskContext(taskID, RootTask, "", "", plan.Root.NumOfTask, merger, ctx)
+	deadline, _ := ctx.Context().Deadline()
+	j.taskManager.Submit(taskCtx, deadline)
 
 	if len(plan.Intermediates) > 0 {
 		for _, intermediate := range plan.Intermediates {
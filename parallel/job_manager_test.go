@@ -17,7 +17,7 @@ func TestJobManager_SubmitJob(t *testing.T) {
 	defer ctrl.Finish()
 
 	taskManager := NewMockTaskManager(ctrl)
-	taskManager.EXPECT().Submit(gomock.Any()).AnyTimes()
+	taskManager.EXPECT().Submit(gomock.Any(), gomock.Any()).AnyTimes()
 	taskManager.EXPECT().AllocTaskID().Return("TaskID").AnyTimes()
 
 	jobManager := NewJobManager(taskManager)
@@ -46,7 +46,7 @@ func TestJobManager_SubmitJob_2(t *testing.T) {
 	defer ctrl.Finish()
 
 	taskManager := NewMockTaskManager(ctrl)
-	taskManager.EXPECT().Submit(gomock.Any()).AnyTimes()
+	taskManager.EXPECT().Submit(gomock.Any(), gomock.Any()).AnyTimes()
 	taskManager.EXPECT().AllocTaskID().Return("TaskID").AnyTimes()
 
 	jobManager := NewJobManager(taskManager)
@@ -71,6 +71,36 @@ func TestJobManager_SubmitJob_2(t *testing.T) {
 	assert.NotNil(t, jobManager.GetTaskManager())
 }
 
+func TestJobManager_SubmitJob_streaming(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	taskManager := NewMockTaskManager(ctrl)
+	taskManager.EXPECT().Submit(gomock.Any(), gomock.Any()).AnyTimes()
+	taskManager.EXPECT().AllocTaskID().Return("TaskID").AnyTimes()
+	taskManager.EXPECT().SendRequest(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	jobManager := NewJobManager(taskManager)
+	physicalPlan := models.NewPhysicalPlan(models.Root{Indicator: "1.1.1.3:8000", NumOfTask: 1})
+	physicalPlan.AddLeaf(models.Leaf{
+		BaseNode: models.BaseNode{
+			Parent:    "1.1.1.3:8000",
+			Indicator: "1.1.1.1:9000",
+		},
+		ShardIDs: []int32{1, 2, 4},
+	})
+	query, _ := sql.Parse("select f from cpu group by host")
+	query.Streaming = true
+	assert.NoError(t, jobManager.SubmitJob(NewJobContext(context.TODO(), nil, physicalPlan, query)))
+	// top n series are not supported
+	query, _ = sql.Parse("select f from cpu group by host limit 10")
+	query.Streaming = true
+	assert.Equal(t, errStreamingTopN, jobManager.SubmitJob(NewJobContext(context.TODO(), nil, physicalPlan, query)))
+	query, _ = sql.Parse("select f from cpu group by host order by f")
+	query.Streaming = true
+	assert.Equal(t, errStreamingTopN, jobManager.SubmitJob(NewJobContext(context.TODO(), nil, physicalPlan, query)))
+}
+
 func TestJobManager_GetTaskManager(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
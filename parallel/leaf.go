@@ -3,6 +3,9 @@ package parallel
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/lindb/lindb/models"
 	"github.com/lindb/lindb/pkg/encoding"
@@ -20,6 +23,9 @@ type leafTask struct {
 	storageService    service.StorageService
 	executorFactory   ExecutorFactory
 	taskServerFactory rpc.TaskServerFactory
+
+	// running tasks for canceling, key: task key of request, value: storage execute context
+	runningTasks sync.Map
 }
 
 // newLeafTask creates the leaf task
@@ -37,8 +43,13 @@ func newLeafTask(
 	}
 }
 
-// Process processes the task request, searches the metric's data from time series engine
+// Process processes the task request, searches the metric's data from time series engine,
+// cancels the running task if the request is cancel task request.
 func (p *leafTask) Process(ctx context.Context, req *pb.TaskRequest) error {
+	if req.Type == CancelTaskRequest {
+		p.cancelTask(req)
+		return nil
+	}
 	physicalPlan := models.PhysicalPlan{}
 	if err := json.Unmarshal(req.PhysicalPlan, &physicalPlan); err != nil {
 		return errUnmarshalPlan
@@ -73,8 +84,28 @@ func (p *leafTask) Process(ctx context.Context, req *pb.TaskRequest) error {
 	}
 
 	// execute leaf task
-	exeCtx := newStorageExecutorContext(ctx, req, stream)
+	taskKey := leafTaskKey(req)
+	exeCtx := newStorageExecutorContext(ctx, req, stream, time.Duration(query.Budget)*time.Millisecond, func() {
+		p.runningTasks.Delete(taskKey)
+	})
+	p.runningTasks.Store(taskKey, exeCtx)
 	exec := p.executorFactory.NewStorageExecutor(exeCtx, db, curLeaf.ShardIDs, &query)
 	exec.Execute()
 	return nil
 }
+
+// cancelTask cancels the running task without sending result, the scans running in background are aborted
+func (p *leafTask) cancelTask(req *pb.TaskRequest) {
+	taskKey := leafTaskKey(req)
+	exeCtx, ok := p.runningTasks.Load(taskKey)
+	if !ok {
+		return
+	}
+	p.runningTasks.Delete(taskKey)
+	exeCtx.(*storageExecuteContext).cancel()
+}
+
+// leafTaskKey returns the key of leaf task by the job id and parent task id of request
+func leafTaskKey(req *pb.TaskRequest) string {
+	return fmt.Sprintf("%d-%s", req.JobID, req.ParentTaskID)
+}
@@ -106,3 +106,64 @@ func TestLeafProcessor_Process(t *testing.T) {
 	err := processor.Process(context.TODO(), &pb.TaskRequest{PhysicalPlan: plan, Payload: data})
 	assert.NoError(t, err)
 }
+
+func TestLeafProcessor_Process_cancel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	taskServerFactory := rpc.NewMockTaskServerFactory(ctrl)
+	storageService := service.NewMockStorageService(ctrl)
+	executorFactory := NewMockExecutorFactory(ctrl)
+
+	currentNode := models.Node{IP: "1.1.1.3", Port: 8000}
+	processor := newLeafTask(currentNode, storageService, executorFactory, taskServerFactory)
+	plan, _ := json.Marshal(&models.PhysicalPlan{
+		Database: "test_db",
+		Leafs:    []models.Leaf{{BaseNode: models.BaseNode{Indicator: "1.1.1.3:8000"}}},
+	})
+	data := encoding.JSONMarshal(&stmt.Query{MetricName: "cpu"})
+	storageService.EXPECT().GetDatabase(gomock.Any()).Return(tsdb.NewMockDatabase(ctrl), true).AnyTimes()
+	serverStream := pb.NewMockTaskService_HandleServer(ctrl)
+	taskServerFactory.EXPECT().GetStream(gomock.Any()).Return(serverStream).AnyTimes()
+	exec := NewMockExecutor(ctrl)
+	exec.EXPECT().Execute().AnyTimes()
+	var exeCtx ExecuteContext
+	executorFactory.EXPECT().NewStorageExecutor(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx ExecuteContext, _, _, _ interface{}) Executor {
+			exeCtx = ctx
+			return exec
+		}).AnyTimes()
+	leafTask := processor.(*leafTask)
+	runningTasks := func() (count int) {
+		leafTask.runningTasks.Range(func(key, value interface{}) bool {
+			count++
+			return true
+		})
+		return
+	}
+
+	// cancels the running task without sending result
+	req := &pb.TaskRequest{JobID: 1, ParentTaskID: "parentTaskID", PhysicalPlan: plan, Payload: data}
+	err := processor.Process(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, runningTasks())
+	err = processor.Process(context.TODO(), &pb.TaskRequest{JobID: 1, ParentTaskID: "parentTaskID", Type: CancelTaskRequest})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, runningTasks())
+	assert.Error(t, exeCtx.Context().Err())
+	exeCtx.RetainTask(1)
+	exeCtx.Complete(nil)
+	// cancel the unknown task
+	err = processor.Process(context.TODO(), &pb.TaskRequest{JobID: 2, ParentTaskID: "parentTaskID", Type: CancelTaskRequest})
+	assert.NoError(t, err)
+
+	// running task is removed after sending result
+	err = processor.Process(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, runningTasks())
+	serverStream.EXPECT().Send(gomock.Any()).Return(nil)
+	exeCtx.RetainTask(1)
+	exeCtx.Complete(nil)
+	assert.Equal(t, 0, runningTasks())
+	exeCtx.(*storageExecuteContext).cancel()
+}
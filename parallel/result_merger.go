@@ -4,8 +4,10 @@ import (
 	"context"
 
 	"github.com/lindb/lindb/aggregation"
+	"github.com/lindb/lindb/constants"
 	pb "github.com/lindb/lindb/rpc/proto/common"
 	"github.com/lindb/lindb/series"
+	"github.com/lindb/lindb/series/tag"
 )
 
 //go:generate mockgen -source=./result_merger.go -destination=./result_merger_mock.go -package=parallel
@@ -14,8 +16,9 @@ import (
 type ResultMerger interface {
 	// merge merges the task response and aggregates the result
 	merge(resp *pb.TaskResponse)
-
-	close()
+	// close closes the merger and waits the merging completed, then sends the error if err isn't nil or
+	// merging failed, else sends the merged result set if not streaming, nothing is sent if no result set channel
+	close(err error)
 }
 
 type resultMerger struct {
@@ -23,23 +26,50 @@ type resultMerger struct {
 
 	groupAgg aggregation.GroupingAggregator
 
+	// creates the grouping aggregator of each group when streaming, nil if not streaming
+	newGroupAgg func() aggregation.GroupingAggregator
+	groups      map[string]aggregation.GroupingAggregator // aggregators of the groups merged so far
+
 	events chan *pb.TaskResponse
 
 	closed chan struct{}
 	ctx    context.Context
 
-	err error
+	partial bool // some task response is partial result
+	err     error
 }
 
 // newResultMerger create a result merger
 func newResultMerger(ctx context.Context, groupAgg aggregation.GroupingAggregator, resultSet chan *series.TimeSeriesEvent) ResultMerger {
-	merger := &resultMerger{
+	return startResultMerger(&resultMerger{
 		resultSet: resultSet,
 		groupAgg:  groupAgg,
 		events:    make(chan *pb.TaskResponse),
 		closed:    make(chan struct{}),
 		ctx:       ctx,
-	}
+	})
+}
+
+// newStreamingResultMerger creates a result merger which sends the series updated by each task response
+// once the response is merged, each series carries the values aggregated so far,
+// which replace the values of the series with same tags sent before.
+func newStreamingResultMerger(
+	ctx context.Context,
+	newGroupAgg func() aggregation.GroupingAggregator,
+	resultSet chan *series.TimeSeriesEvent,
+) ResultMerger {
+	return startResultMerger(&resultMerger{
+		resultSet:   resultSet,
+		newGroupAgg: newGroupAgg,
+		groups:      make(map[string]aggregation.GroupingAggregator),
+		events:      make(chan *pb.TaskResponse),
+		closed:      make(chan struct{}),
+		ctx:         ctx,
+	})
+}
+
+// startResultMerger starts processing the task responses of merger in background
+func startResultMerger(merger *resultMerger) ResultMerger {
 	go func() {
 		defer close(merger.closed)
 		merger.process()
@@ -52,19 +82,27 @@ func (m *resultMerger) merge(resp *pb.TaskResponse) {
 	m.events <- resp
 }
 
-func (m *resultMerger) close() {
+func (m *resultMerger) close(err error) {
 	close(m.events)
 	// waiting process completed
 	<-m.closed
+	if m.err == nil {
+		m.err = err
+	}
+	if m.resultSet == nil {
+		return
+	}
 	// send result set
 	if m.err != nil {
 		m.resultSet <- &series.TimeSeriesEvent{Err: m.err}
-	} else {
+	} else if m.newGroupAgg == nil {
+		// the series have been sent once merged if streaming
 		// send all series data
 		resultSet := m.groupAgg.ResultSet()
-		if len(resultSet) > 0 {
+		if len(resultSet) > 0 || m.partial {
 			m.resultSet <- &series.TimeSeriesEvent{
 				SeriesList: resultSet,
+				Partial:    m.partial,
 			}
 		}
 	}
@@ -88,6 +126,9 @@ func (m *resultMerger) process() {
 }
 
 func (m *resultMerger) handleEvent(resp *pb.TaskResponse) bool {
+	if resp.Partial {
+		m.partial = true
+	}
 	data := resp.Payload
 	tsList := &pb.TimeSeriesList{}
 	err := tsList.Unmarshal(data)
@@ -95,12 +136,69 @@ func (m *resultMerger) handleEvent(resp *pb.TaskResponse) bool {
 		m.err = err
 		return false
 	}
+	var updated []aggregation.GroupingAggregator
 	for _, ts := range tsList.TimeSeriesList {
-		// if no field data, ignore this response
-		if len(ts.Fields) == 0 {
-			return true
+		// the time series without field data has only the tags of series, for series only query
+		for fieldName, fieldData := range ts.Fields {
+			data, err := series.DecodeDelta(fieldData)
+			if err != nil {
+				m.err = err
+				return false
+			}
+			ts.Fields[fieldName] = data
 		}
-		m.groupAgg.Aggregate(series.NewGroupedIterator(ts.Tags, ts.Fields))
+		if m.newGroupAgg == nil {
+			m.groupAgg.Aggregate(series.NewGroupedIterator(ts.Tags, ts.Fields))
+			continue
+		}
+		tagsStr := constants.EmptyGroupTagsStr
+		if len(ts.Tags) > 0 {
+			tagsStr = tag.Concat(ts.Tags)
+		}
+		groupAgg, ok := m.groups[tagsStr]
+		if !ok {
+			groupAgg = m.newGroupAgg()
+			m.groups[tagsStr] = groupAgg
+		}
+		groupAgg.Aggregate(series.NewGroupedIterator(ts.Tags, ts.Fields))
+		updated = append(updated, groupAgg)
+	}
+	if m.newGroupAgg == nil || (len(updated) == 0 && !resp.Partial) {
+		return true
+	}
+	return m.sendUpdated(updated, resp.Partial)
+}
+
+// sendUpdated sends the series of updated groups with the values aggregated so far,
+// the values are copied, because the aggregators of groups are changed by the following responses.
+func (m *resultMerger) sendUpdated(updated []aggregation.GroupingAggregator, partial bool) bool {
+	sent := make(map[aggregation.GroupingAggregator]struct{})
+	var seriesList []series.GroupedIterator
+	for _, groupAgg := range updated {
+		if _, ok := sent[groupAgg]; ok {
+			continue
+		}
+		sent[groupAgg] = struct{}{}
+		for _, it := range groupAgg.ResultSet() {
+			fields := make(map[string][]byte)
+			for it.HasNext() {
+				fieldIt := it.Next()
+				data, err := series.MarshalIterator(fieldIt)
+				if err != nil {
+					m.err = err
+					return false
+				}
+				if len(data) > 0 {
+					fields[fieldIt.FieldName()] = data
+				}
+			}
+			seriesList = append(seriesList, series.NewGroupedIterator(it.Tags(), fields))
+		}
+	}
+	select {
+	case m.resultSet <- &series.TimeSeriesEvent{SeriesList: seriesList, Partial: partial}:
+		return true
+	case <-m.ctx.Done():
+		return false
 	}
-	return true
 }
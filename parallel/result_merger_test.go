@@ -2,6 +2,7 @@ package parallel
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"go.uber.org/atomic"
 
 	"github.com/lindb/lindb/aggregation"
+	"github.com/lindb/lindb/pkg/timeutil"
 	pb "github.com/lindb/lindb/rpc/proto/common"
 	"github.com/lindb/lindb/series"
 )
@@ -32,7 +34,7 @@ func TestResultMerger_Merge(t *testing.T) {
 		wait.Done()
 	}()
 	merger.merge(&pb.TaskResponse{TaskID: "taskID"})
-	merger.close()
+	merger.close(nil)
 	wait.Wait()
 	assert.Equal(t, int32(1), c.Load())
 }
@@ -53,7 +55,7 @@ func TestResultMerger_Cancel(t *testing.T) {
 	}()
 	wait.Done()
 	time.Sleep(100 * time.Millisecond)
-	merger.close()
+	merger.close(nil)
 }
 
 func TestResultMerger_Err(t *testing.T) {
@@ -74,11 +76,27 @@ func TestResultMerger_Err(t *testing.T) {
 		}
 	}()
 	merger.merge(&pb.TaskResponse{TaskID: "taskID", Payload: []byte{1, 2, 3}})
-	merger.close()
+	merger.close(nil)
 	wait.Wait()
 	assert.Equal(t, int32(1), c.Load())
 }
 
+func TestResultMerger_closeWithErr(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	groupAgg := aggregation.NewMockGroupingAggregator(ctrl)
+	ch := make(chan *series.TimeSeriesEvent, 1)
+	merger := newResultMerger(context.TODO(), groupAgg, ch)
+	// sends the error instead of result set
+	merger.close(errTaskTimeout)
+	rs := <-ch
+	assert.Equal(t, errTaskTimeout, rs.Err)
+
+	// nothing is sent without result set channel, e.g. intermediate task
+	merger = newResultMerger(context.TODO(), groupAgg, nil)
+	merger.close(nil)
+}
+
 func TestResultMerger_GroupBy(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -117,7 +135,130 @@ func TestResultMerger_GroupBy(t *testing.T) {
 	}
 	data, _ = seriesList.Marshal()
 	merger.merge(&pb.TaskResponse{TaskID: "taskID", Payload: data})
-	merger.close()
+	merger.close(nil)
 	wait.Wait()
 	assert.Equal(t, int32(1), c.Load())
 }
+
+func TestResultMerger_DecodeDelta_Err(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	groupAgg := aggregation.NewMockGroupingAggregator(ctrl)
+	ch := make(chan *series.TimeSeriesEvent)
+	merger := newResultMerger(context.TODO(), groupAgg, ch)
+	c := atomic.NewInt32(0)
+	var wait sync.WaitGroup
+	wait.Add(1)
+	go func() {
+		for rs := range ch {
+			if rs.Err != nil {
+				c.Inc()
+				wait.Done()
+			}
+		}
+	}()
+	seriesList := pb.TimeSeriesList{
+		TimeSeriesList: []*pb.TimeSeries{{
+			Tags:   map[string]string{"host": "1.1.1.1"},
+			Fields: map[string][]byte{"f1": {1, 2, 10, 1}},
+		}},
+	}
+	data, _ := seriesList.Marshal()
+	merger.merge(&pb.TaskResponse{TaskID: "taskID", Payload: data})
+	merger.close(nil)
+	wait.Wait()
+	assert.Equal(t, int32(1), c.Load())
+}
+
+func TestResultMerger_Partial(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	groupAgg := aggregation.NewMockGroupingAggregator(ctrl)
+	groupAgg.EXPECT().ResultSet().Return(nil)
+	ch := make(chan *series.TimeSeriesEvent, 1)
+	merger := newResultMerger(context.TODO(), groupAgg, ch)
+	merger.merge(&pb.TaskResponse{TaskID: "taskID"})
+	merger.merge(&pb.TaskResponse{TaskID: "taskID", Partial: true})
+	merger.close(nil)
+	event := <-ch
+	assert.True(t, event.Partial)
+	assert.Nil(t, event.Err)
+}
+
+func TestResultMerger_seriesOnly(t *testing.T) {
+	// the series are merged by tags, then the first series ordered by tags are returned within the limit
+	groupAgg := aggregation.NewTopNGroupingAggregator(10, timeutil.TimeRange{}, nil, nil, 2)
+	ch := make(chan *series.TimeSeriesEvent, 1)
+	merger := newResultMerger(context.TODO(), groupAgg, ch)
+	for _, hosts := range [][]string{{"1.1.1.3", "1.1.1.1"}, {"1.1.1.1", "1.1.1.2"}} {
+		seriesList := pb.TimeSeriesList{}
+		for _, host := range hosts {
+			seriesList.TimeSeriesList = append(seriesList.TimeSeriesList,
+				&pb.TimeSeries{Tags: map[string]string{"host": host}})
+		}
+		data, _ := seriesList.Marshal()
+		merger.merge(&pb.TaskResponse{TaskID: "taskID", Payload: data})
+	}
+	merger.close(nil)
+	event := <-ch
+	assert.Nil(t, event.Err)
+	var hosts []string
+	for _, it := range event.SeriesList {
+		hosts = append(hosts, it.Tags()["host"])
+		assert.False(t, it.HasNext())
+	}
+	assert.Equal(t, []string{"1.1.1.1", "1.1.1.2"}, hosts)
+}
+
+func TestResultMerger_streaming(t *testing.T) {
+	ch := make(chan *series.TimeSeriesEvent, 2)
+	merger := newStreamingResultMerger(context.TODO(), func() aggregation.GroupingAggregator {
+		return aggregation.NewGroupingAggregator(10, timeutil.TimeRange{}, nil)
+	}, ch)
+	// the series updated by each response are sent once merged
+	for _, hosts := range [][]string{{"1.1.1.1"}, {"1.1.1.2", "1.1.1.1", "1.1.1.2"}} {
+		seriesList := pb.TimeSeriesList{}
+		for _, host := range hosts {
+			seriesList.TimeSeriesList = append(seriesList.TimeSeriesList,
+				&pb.TimeSeries{Tags: map[string]string{"host": host}})
+		}
+		data, _ := seriesList.Marshal()
+		merger.merge(&pb.TaskResponse{TaskID: "taskID", Payload: data})
+	}
+	var sent [][]string
+	for i := 0; i < 2; i++ {
+		event := <-ch
+		var hosts []string
+		for _, it := range event.SeriesList {
+			hosts = append(hosts, it.Tags()["host"])
+		}
+		sent = append(sent, hosts)
+	}
+	assert.Equal(t, [][]string{{"1.1.1.1"}, {"1.1.1.2", "1.1.1.1"}}, sent)
+	// partial response without series
+	merger.merge(&pb.TaskResponse{TaskID: "taskID", Partial: true})
+	event := <-ch
+	assert.True(t, event.Partial)
+	assert.Empty(t, event.SeriesList)
+	// nothing is sent when closed
+	merger.close(nil)
+	assert.Len(t, ch, 0)
+	// error is sent when closed
+	merger = newStreamingResultMerger(context.TODO(), nil, ch)
+	merger.close(fmt.Errorf("err"))
+	assert.Error(t, (<-ch).Err)
+}
+
+func TestResultMerger_streaming_cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	merger := newStreamingResultMerger(ctx, func() aggregation.GroupingAggregator {
+		return aggregation.NewGroupingAggregator(10, timeutil.TimeRange{}, nil)
+	}, make(chan *series.TimeSeriesEvent))
+	seriesList := pb.TimeSeriesList{TimeSeriesList: []*pb.TimeSeries{{Tags: map[string]string{"host": "1.1.1.1"}}}}
+	data, _ := seriesList.Marshal()
+	// no receiver of result, sending is aborted once canceled
+	merger.merge(&pb.TaskResponse{TaskID: "taskID", Payload: data})
+	cancel()
+	merger.close(nil)
+}
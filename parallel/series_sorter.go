@@ -0,0 +1,319 @@
+package parallel
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/gob"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/lindb/lindb/models"
+	"github.com/lindb/lindb/pkg/logger"
+	"github.com/lindb/lindb/series/tag"
+)
+
+const (
+	// seriesOverhead is the estimated memory of a series without tags/fields
+	seriesOverhead = 64
+	// pointOverhead is the estimated memory of a data point stored in the map of field
+	pointOverhead = 32
+	// spillFilePattern is the file name pattern of spilled series
+	spillFilePattern = "query-spill-*"
+)
+
+// sortedSeries represents the series with the sort key(concat tags)
+type sortedSeries struct {
+	key    string
+	series *models.Series
+}
+
+// seriesSorter sorts the series of result set by tags under the max memory of query,
+// spills the sorted series to disk when the memory limit is exceeded, then merges all spilled runs when iterating.
+// if spill dir is empty, the query fails when the memory limit is exceeded.
+// the spilled files are removed after iterating, or once the context of query is done.
+type seriesSorter struct {
+	ctx       context.Context
+	maxMemory int64
+	spillDir  string
+
+	memory int64 // estimated memory of the series held in memory
+	series []*sortedSeries
+	runs   []string      // spilled files, each file is sorted by tags
+	closed chan struct{} // closed when the sorter is closed, nil if no file spilled
+	mutex  sync.Mutex    // guards the sorter against the cleanup on context done
+}
+
+// newSeriesSorter creates the series sorter with the max memory(bytes) and the spill dir
+func newSeriesSorter(ctx context.Context, maxMemory int64, spillDir string) *seriesSorter {
+	return &seriesSorter{
+		ctx:       ctx,
+		maxMemory: maxMemory,
+		spillDir:  spillDir,
+	}
+}
+
+// Add adds the series into sorter, spills the series in memory to disk first if the memory limit is exceeded
+func (s *seriesSorter) Add(series *models.Series) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	size := estimateSeriesSize(series)
+	if s.memory+size > s.maxMemory && len(s.series) > 0 {
+		if s.spillDir == "" {
+			return errQueryMemoryExceeded
+		}
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+	s.series = append(s.series, &sortedSeries{key: tag.Concat(series.Tags), series: series})
+	s.memory += size
+	return nil
+}
+
+// Sorted returns all the series sorted by tags, merges the series with same tags,
+// then resets the sorter and removes the spilled files.
+func (s *seriesSorter) Sorted() ([]*models.Series, error) {
+	var result []*models.Series
+	if err := s.Iterate(func(series *models.Series) error {
+		result = append(result, series)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Iterate merges the spilled runs and the series in memory by k-way merging, calls fn with the series
+// in order of tags one by one, the series with same tags are merged. only the current series of each run
+// are held in memory while merging. the sorter is reset and the spilled files are removed after iterating,
+// the iteration stops if fn fails or the context of query is done.
+func (s *seriesSorter) Iterate(fn func(series *models.Series) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer s.closeLocked()
+
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	s.sortInMemory()
+	h := &seriesHeap{}
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, run := range s.runs {
+		f, err := os.Open(run)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+		source := &fileSeriesSource{decoder: gob.NewDecoder(bufio.NewReader(f))}
+		if err := h.pushNext(source); err != nil {
+			return err
+		}
+	}
+	if err := h.pushNext(&memorySeriesSource{series: s.series}); err != nil {
+		return err
+	}
+
+	var last *sortedSeries
+	for h.Len() > 0 {
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+		item := heap.Pop(h).(*seriesHeapItem)
+		if last != nil && last.key == item.current.key {
+			mergeSeries(last.series, item.current.series)
+		} else {
+			if last != nil {
+				if err := fn(last.series); err != nil {
+					return err
+				}
+			}
+			last = item.current
+		}
+		if err := h.pushNext(item.source); err != nil {
+			return err
+		}
+	}
+	if last != nil {
+		return fn(last.series)
+	}
+	return nil
+}
+
+// Close resets the sorter and removes the spilled files
+func (s *seriesSorter) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closeLocked()
+}
+
+// closeLocked resets the sorter and removes the spilled files, must be called with the lock held
+func (s *seriesSorter) closeLocked() {
+	for _, run := range s.runs {
+		if err := os.Remove(run); err != nil {
+			execLogger.Warn("remove spilled file of query error",
+				logger.String("file", run), logger.Error(err))
+		}
+	}
+	s.runs = nil
+	s.series = nil
+	s.memory = 0
+	if s.closed != nil {
+		close(s.closed)
+		s.closed = nil
+	}
+}
+
+// closeOnDone closes the sorter once the context of query is done, so that the spilled files don't leak
+// if the query is canceled before iterating.
+func (s *seriesSorter) closeOnDone(closed chan struct{}) {
+	select {
+	case <-s.ctx.Done():
+		s.Close()
+	case <-closed:
+	}
+}
+
+// sortInMemory sorts the series held in memory by tags
+func (s *seriesSorter) sortInMemory() {
+	sort.SliceStable(s.series, func(i, j int) bool {
+		return s.series[i].key < s.series[j].key
+	})
+}
+
+// spill writes the sorted series held in memory into a new spilled file, then releases them
+func (s *seriesSorter) spill() (err error) {
+	s.sortInMemory()
+	f, err := ioutil.TempFile(s.spillDir, spillFilePattern)
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, f.Name())
+	if s.closed == nil {
+		s.closed = make(chan struct{})
+		go s.closeOnDone(s.closed)
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	w := bufio.NewWriter(f)
+	encoder := gob.NewEncoder(w)
+	for _, item := range s.series {
+		if err := encoder.Encode(item.series); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	s.series = nil
+	s.memory = 0
+	return nil
+}
+
+// mergeSeries merges the fields of source series into target series with same tags
+func mergeSeries(target, source *models.Series) {
+	for fieldName, points := range source.Fields {
+		target.AddField(fieldName, &models.Points{Points: points})
+	}
+}
+
+// estimateSeriesSize returns the estimated memory of the series
+func estimateSeriesSize(series *models.Series) int64 {
+	size := seriesOverhead
+	for tagKey, tagValue := range series.Tags {
+		size += len(tagKey) + len(tagValue)
+	}
+	for fieldName, points := range series.Fields {
+		size += len(fieldName) + len(points)*pointOverhead
+	}
+	return int64(size)
+}
+
+// seriesSource represents the source of sorted series for merging
+type seriesSource interface {
+	// next returns the next series, returns io.EOF if no more series
+	next() (*models.Series, error)
+}
+
+// memorySeriesSource represents the sorted series held in memory
+type memorySeriesSource struct {
+	series []*sortedSeries
+	idx    int
+}
+
+func (s *memorySeriesSource) next() (*models.Series, error) {
+	if s.idx >= len(s.series) {
+		return nil, io.EOF
+	}
+	series := s.series[s.idx].series
+	s.idx++
+	return series, nil
+}
+
+// fileSeriesSource represents the sorted series of a spilled file
+type fileSeriesSource struct {
+	decoder *gob.Decoder
+}
+
+func (s *fileSeriesSource) next() (*models.Series, error) {
+	series := &models.Series{}
+	if err := s.decoder.Decode(series); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// seriesHeapItem represents the current series of the source
+type seriesHeapItem struct {
+	current *sortedSeries
+	source  seriesSource
+}
+
+// seriesHeap implements heap.Interface, which is a min heap by current series's tags
+type seriesHeap []*seriesHeapItem
+
+func (h seriesHeap) Len() int            { return len(h) }
+func (h seriesHeap) Less(i, j int) bool  { return h[i].current.key < h[j].current.key }
+func (h seriesHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *seriesHeap) Push(x interface{}) { *h = append(*h, x.(*seriesHeapItem)) }
+func (h *seriesHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// pushNext pushes the next series of the source into heap, ignores the source if no more series
+func (h *seriesHeap) pushNext(source seriesSource) error {
+	series, err := source.next()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if series.Fields == nil {
+		series.Fields = make(map[string]map[int64]float64)
+	}
+	heap.Push(h, &seriesHeapItem{
+		current: &sortedSeries{key: tag.Concat(series.Tags), series: series},
+		source:  source,
+	})
+	return nil
+}
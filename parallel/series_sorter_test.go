@@ -0,0 +1,180 @@
+package parallel
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/models"
+	"github.com/lindb/lindb/pkg/fileutil"
+	"github.com/lindb/lindb/series/tag"
+)
+
+var testSpillPath = filepath.Join("test_data", "spill")
+
+func newTestSeries(host string, value float64) *models.Series {
+	s := models.NewSeries(map[string]string{"host": host})
+	points := models.NewPoints()
+	for i := 0; i < 10; i++ {
+		points.AddPoint(int64(i), value)
+	}
+	s.AddField("f", points)
+	return s
+}
+
+func TestSeriesSorter_spill(t *testing.T) {
+	_ = fileutil.MkDirIfNotExist(testSpillPath)
+	defer func() {
+		_ = fileutil.RemoveDir("test_data")
+	}()
+
+	const count = 10000
+	maxMemory := estimateSeriesSize(newTestSeries("host-00000", 0)) * 100
+	sorter := newSeriesSorter(context.Background(), maxMemory, testSpillPath)
+	for _, idx := range rand.Perm(count) {
+		assert.NoError(t, sorter.Add(newTestSeries(fmt.Sprintf("host-%05d", idx), float64(idx))))
+		// memory of series held by sorter respects the limit
+		assert.True(t, sorter.memory <= maxMemory)
+	}
+	assert.True(t, len(sorter.runs) >= count/100-1)
+
+	result, err := sorter.Sorted()
+	assert.NoError(t, err)
+	assert.Len(t, result, count)
+	for idx, s := range result {
+		assert.Equal(t, fmt.Sprintf("host-%05d", idx), s.Tags["host"])
+		assert.Equal(t, float64(idx), s.Fields["f"][9])
+	}
+	// spilled files removed
+	files, _ := ioutil.ReadDir(testSpillPath)
+	assert.Empty(t, files)
+	assert.Empty(t, sorter.runs)
+	assert.Zero(t, sorter.memory)
+}
+
+func TestSeriesSorter_merge_same_tags(t *testing.T) {
+	_ = fileutil.MkDirIfNotExist(testSpillPath)
+	defer func() {
+		_ = fileutil.RemoveDir("test_data")
+	}()
+	// merges in memory
+	sorter := newSeriesSorter(context.Background(), 1024*1024, testSpillPath)
+	s1 := models.NewSeries(map[string]string{"host": "a"})
+	s1.AddField("f1", &models.Points{Points: map[int64]float64{1: 1}})
+	s2 := models.NewSeries(map[string]string{"host": "a"})
+	s2.AddField("f2", &models.Points{Points: map[int64]float64{1: 2}})
+	assert.NoError(t, sorter.Add(s1))
+	assert.NoError(t, sorter.Add(newTestSeries("b", 1)))
+	assert.NoError(t, sorter.Add(s2))
+	result, err := sorter.Sorted()
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, 1.0, result[0].Fields["f1"][1])
+	assert.Equal(t, 2.0, result[0].Fields["f2"][1])
+
+	// merges spilled runs
+	sorter = newSeriesSorter(context.Background(), 1, testSpillPath)
+	assert.NoError(t, sorter.Add(newTestSeries("b", 1)))
+	assert.NoError(t, sorter.Add(models.NewSeries(nil)))
+	assert.NoError(t, sorter.Add(s2))
+	assert.NoError(t, sorter.Add(newTestSeries("a", 3)))
+	assert.Len(t, sorter.runs, 3)
+	result, err = sorter.Sorted()
+	assert.NoError(t, err)
+	assert.Len(t, result, 3)
+	assert.Equal(t, "", tag.Concat(result[0].Tags))
+	assert.Equal(t, "a", result[1].Tags["host"])
+	assert.Equal(t, "b", result[2].Tags["host"])
+	assert.Equal(t, 3.0, result[1].Fields["f"][1])
+	assert.Equal(t, 2.0, result[1].Fields["f2"][1])
+	assert.Equal(t, 1.0, result[2].Fields["f"][1])
+}
+
+func TestSeriesSorter_fail(t *testing.T) {
+	defer func() {
+		_ = fileutil.RemoveDir("test_data")
+	}()
+	// spill disabled
+	sorter := newSeriesSorter(context.Background(), 1, "")
+	assert.NoError(t, sorter.Add(newTestSeries("a", 1)))
+	assert.Equal(t, errQueryMemoryExceeded, sorter.Add(newTestSeries("b", 1)))
+	// spill dir not exist
+	sorter = newSeriesSorter(context.Background(), 1, filepath.Join(testSpillPath, "not_exist"))
+	assert.NoError(t, sorter.Add(newTestSeries("a", 1)))
+	assert.Error(t, sorter.Add(newTestSeries("b", 1)))
+	// spilled file removed before merging
+	_ = fileutil.MkDirIfNotExist(testSpillPath)
+	sorter = newSeriesSorter(context.Background(), 1, testSpillPath)
+	assert.NoError(t, sorter.Add(newTestSeries("a", 1)))
+	assert.NoError(t, sorter.Add(newTestSeries("b", 1)))
+	_ = fileutil.RemoveDir(testSpillPath)
+	_, err := sorter.Sorted()
+	assert.Error(t, err)
+	// spilled file corrupted
+	_ = fileutil.MkDirIfNotExist(testSpillPath)
+	sorter = newSeriesSorter(context.Background(), 1, testSpillPath)
+	assert.NoError(t, sorter.Add(newTestSeries("a", 1)))
+	assert.NoError(t, sorter.Add(newTestSeries("b", 1)))
+	assert.NoError(t, ioutil.WriteFile(sorter.runs[0], []byte("corrupted"), 0644))
+	_, err = sorter.Sorted()
+	assert.Error(t, err)
+}
+
+func TestSeriesSorter_cancel(t *testing.T) {
+	_ = fileutil.MkDirIfNotExist(testSpillPath)
+	defer func() {
+		_ = fileutil.RemoveDir("test_data")
+	}()
+	spilledFiles := func() int {
+		files, _ := ioutil.ReadDir(testSpillPath)
+		return len(files)
+	}
+
+	// spilled files removed once canceled before iterating
+	ctx, cancel := context.WithCancel(context.Background())
+	sorter := newSeriesSorter(ctx, 1, testSpillPath)
+	for _, host := range []string{"c", "a", "b"} {
+		assert.NoError(t, sorter.Add(newTestSeries(host, 1)))
+	}
+	assert.Equal(t, 2, spilledFiles())
+	cancel()
+	for i := 0; i < 100 && spilledFiles() > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Zero(t, spilledFiles())
+	assert.Equal(t, context.Canceled, sorter.Add(newTestSeries("d", 1)))
+	assert.Equal(t, context.Canceled, sorter.Iterate(func(series *models.Series) error { return nil }))
+
+	// stops iterating once canceled, spilled files removed
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	sorter = newSeriesSorter(ctx, 1, testSpillPath)
+	for _, host := range []string{"c", "a", "b"} {
+		assert.NoError(t, sorter.Add(newTestSeries(host, 1)))
+	}
+	var hosts []string
+	err := sorter.Iterate(func(series *models.Series) error {
+		hosts = append(hosts, series.Tags["host"])
+		cancel()
+		return nil
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []string{"a"}, hosts)
+	assert.Zero(t, spilledFiles())
+
+	// stops iterating if fn fails
+	sorter = newSeriesSorter(context.Background(), 1, testSpillPath)
+	for _, host := range []string{"c", "a", "b"} {
+		assert.NoError(t, sorter.Add(newTestSeries(host, 1)))
+	}
+	assert.Equal(t, errQueryMemoryExceeded, sorter.Iterate(func(series *models.Series) error {
+		return errQueryMemoryExceeded
+	}))
+	assert.Zero(t, spilledFiles())
+}
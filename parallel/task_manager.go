@@ -1,24 +1,35 @@
 package parallel
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"go.uber.org/atomic"
 
 	"github.com/lindb/lindb/models"
+	"github.com/lindb/lindb/pkg/logger"
 	"github.com/lindb/lindb/rpc"
 	pb "github.com/lindb/lindb/rpc/proto/common"
 )
 
+const (
+	// defaultTaskTimeout is the timeout of task if the task is submitted without deadline
+	defaultTaskTimeout = 30 * time.Second
+	// taskReapInterval is the interval of checking the expired tasks
+	taskReapInterval = time.Second
+)
+
 //go:generate mockgen -source=./task_manager.go -destination=./task_manager_mock.go -package=parallel
 
 // TaskManager represents the task manager for current node
 type TaskManager interface {
 	// AllocTaskID allocates the task id for new task, before task submits
 	AllocTaskID() string
-	// Submit submits the task, saving task context for task tracking
-	Submit(taskCtx TaskContext)
+	// Submit submits the task, saving task context for task tracking,
+	// the task is reaped if not completed before deadline, zero deadline means the default timeout.
+	Submit(taskCtx TaskContext, deadline time.Time)
 	// Complete completes the task by task id
 	Complete(taskID string)
 	// Get returns the task context by task id
@@ -30,25 +41,38 @@ type TaskManager interface {
 	SendResponse(targetNodeID string, resp *pb.TaskResponse) error
 }
 
+// trackedTask represents the submitted task with the deadline
+type trackedTask struct {
+	taskCtx  TaskContext
+	deadline time.Time
+}
+
 // taskManager implements the task manager interface, tracks all task of the current node
 type taskManager struct {
 	currentNodeID     string
 	seq               *atomic.Int64
 	taskClientFactory rpc.TaskClientFactory
 	taskServerFactory rpc.TaskServerFactory
+	taskTimeout       time.Duration
 
 	tasks sync.Map
+
+	logger *logger.Logger
 }
 
-// NewTaskManager creates the task manager
-func NewTaskManager(currentNode models.Node,
+// NewTaskManager creates the task manager, the expired tasks are reaped in background until ctx is done
+func NewTaskManager(ctx context.Context, currentNode models.Node,
 	taskClientFactory rpc.TaskClientFactory, taskServerFactory rpc.TaskServerFactory) TaskManager {
-	return &taskManager{
+	t := &taskManager{
 		currentNodeID:     (&currentNode).Indicator(),
 		taskClientFactory: taskClientFactory,
 		taskServerFactory: taskServerFactory,
+		taskTimeout:       defaultTaskTimeout,
 		seq:               atomic.NewInt64(0),
+		logger:            logger.GetLogger("parallel", "TaskManager"),
 	}
+	go t.reapLoop(ctx, taskReapInterval)
+	return t
 }
 
 // AllocTaskID allocates the task id for new task, before task submits
@@ -57,10 +81,14 @@ func (t *taskManager) AllocTaskID() string {
 	return fmt.Sprintf("%s-%d", t.currentNodeID, seq)
 }
 
-// Submit submits the task, saving task context for task tracking
-func (t *taskManager) Submit(taskCtx TaskContext) {
+// Submit submits the task, saving task context for task tracking,
+// the task is reaped if not completed before deadline, zero deadline means the default timeout.
+func (t *taskManager) Submit(taskCtx TaskContext, deadline time.Time) {
+	if deadline.IsZero() {
+		deadline = time.Now().Add(t.taskTimeout)
+	}
 	//TODO check duplicate
-	t.tasks.Store(taskCtx.TaskID(), taskCtx)
+	t.tasks.Store(taskCtx.TaskID(), &trackedTask{taskCtx: taskCtx, deadline: deadline})
 }
 
 // Complete completes the task by task id
@@ -74,11 +102,60 @@ func (t *taskManager) Get(taskID string) TaskContext {
 	if !ok {
 		return nil
 	}
-	taskCtx, ok := task.(TaskContext)
+	tracked, ok := task.(*trackedTask)
 	if !ok {
 		return nil
 	}
-	return taskCtx
+	return tracked.taskCtx
+}
+
+// reapLoop reaps the expired tasks periodically until ctx is done
+func (t *taskManager) reapLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			t.reapExpiredTasks(now)
+		}
+	}
+}
+
+// reapExpiredTasks removes the tasks which are not completed before deadline and marks them failed,
+// which closes the merger of task and completes the job if root task, then sends the error response
+// to the parent node if exist, so that the parent task is not blocked by the sub tasks which never respond,
+// the responses received after reaped are ignored.
+func (t *taskManager) reapExpiredTasks(now time.Time) {
+	t.tasks.Range(func(key, value interface{}) bool {
+		tracked, ok := value.(*trackedTask)
+		if !ok || now.Before(tracked.deadline) {
+			return true
+		}
+		t.tasks.Delete(key)
+		taskCtx := tracked.taskCtx
+		// task completed by the result received concurrently
+		if !taskCtx.ReceiveResult(&pb.TaskResponse{
+			TaskID:    taskCtx.TaskID(),
+			Completed: true,
+			ErrMsg:    errTaskTimeout.Error(),
+		}) {
+			return true
+		}
+		t.logger.Warn("reap expired task", logger.String("taskID", taskCtx.TaskID()))
+		if taskCtx.ParentNode() == "" {
+			return true
+		}
+		if err := t.SendResponse(taskCtx.ParentNode(), &pb.TaskResponse{
+			TaskID:    taskCtx.ParentTaskID(),
+			Completed: true,
+			ErrMsg:    errTaskTimeout.Error(),
+		}); err != nil {
+			t.logger.Error("send response of expired task", logger.String("taskID", taskCtx.TaskID()), logger.Error(err))
+		}
+		return true
+	})
 }
 
 // SendRequest sends the task request to target node based on node's indicator,
@@ -1,8 +1,10 @@
 package parallel
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -10,6 +12,7 @@ import (
 	"github.com/lindb/lindb/models"
 	"github.com/lindb/lindb/rpc"
 	pb "github.com/lindb/lindb/rpc/proto/common"
+	"github.com/lindb/lindb/series"
 )
 
 func TestTaskManager_ClientStream(t *testing.T) {
@@ -20,10 +23,10 @@ func TestTaskManager_ClientStream(t *testing.T) {
 	taskClientFactory := rpc.NewMockTaskClientFactory(ctrl)
 	taskServerFactory := rpc.NewMockTaskServerFactory(ctrl)
 
-	taskManager1 := NewTaskManager(currentNode, taskClientFactory, taskServerFactory)
+	taskManager1 := NewTaskManager(context.TODO(), currentNode, taskClientFactory, taskServerFactory)
 
-	taskCtx := newTaskContext("xxx", IntermediateTask, "parentTaskID", "parentNode", 2, nil)
-	taskManager1.Submit(taskCtx)
+	taskCtx := newTaskContext("xxx", IntermediateTask, "parentTaskID", "parentNode", 2, nil, nil)
+	taskManager1.Submit(taskCtx, time.Time{})
 
 	assert.Equal(t, taskCtx, taskManager1.Get("xxx"))
 	assert.Nil(t, taskManager1.Get("xxx11"))
@@ -32,8 +35,8 @@ func TestTaskManager_ClientStream(t *testing.T) {
 	taskManager2.tasks.Store("xxx11", nil)
 	assert.Nil(t, taskManager1.Get("xxx11"))
 
-	taskCtx = newTaskContext("taskID", IntermediateTask, "parentTaskID", "parentNode", 2, nil)
-	taskManager1.Submit(taskCtx)
+	taskCtx = newTaskContext("taskID", IntermediateTask, "parentTaskID", "parentNode", 2, nil, nil)
+	taskManager1.Submit(taskCtx, time.Time{})
 	assert.Equal(t, taskCtx, taskManager1.Get("taskID"))
 	taskManager1.Complete("taskID")
 	assert.Nil(t, taskManager1.Get("taskID"))
@@ -50,7 +53,7 @@ func TestTaskManager_SendRequest(t *testing.T) {
 	currentNode := models.Node{IP: "1.1.1.1", Port: 8000}
 	taskClientFactory := rpc.NewMockTaskClientFactory(ctrl)
 
-	taskManager := NewTaskManager(currentNode, taskClientFactory, nil)
+	taskManager := NewTaskManager(context.TODO(), currentNode, taskClientFactory, nil)
 	taskClientFactory.EXPECT().GetTaskClient("targetNode").Return(nil)
 	err := taskManager.SendRequest("targetNode", nil)
 	assert.NotNil(t, err)
@@ -75,7 +78,7 @@ func TestTaskManager_SendResponse(t *testing.T) {
 	currentNode := models.Node{IP: "1.1.1.1", Port: 8000}
 	taskServerFactory := rpc.NewMockTaskServerFactory(ctrl)
 
-	taskManager := NewTaskManager(currentNode, nil, taskServerFactory)
+	taskManager := NewTaskManager(context.TODO(), currentNode, nil, taskServerFactory)
 	taskServerFactory.EXPECT().GetStream("targetNode").Return(nil)
 	err := taskManager.SendResponse("targetNode", nil)
 	assert.NotNil(t, err)
@@ -92,3 +95,59 @@ func TestTaskManager_SendResponse(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestTaskManager_reapExpiredTasks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	currentNode := models.Node{IP: "1.1.1.1", Port: 8000}
+	taskServerFactory := rpc.NewMockTaskServerFactory(ctrl)
+	taskManager1 := NewTaskManager(ctx, currentNode, nil, taskServerFactory)
+	taskManager2 := taskManager1.(*taskManager)
+
+	now := time.Now()
+	ch := make(chan *series.TimeSeriesEvent, 1)
+	jobCtx := NewJobContext(context.TODO(), ch, nil, nil)
+	rootMerger := NewMockResultMerger(ctrl)
+	rootTask := newTaskContext("root", RootTask, "", "", 1, rootMerger, jobCtx)
+	taskManager1.Submit(rootTask, now.Add(-time.Second))
+	merger := NewMockResultMerger(ctrl)
+	expiredTask := newTaskContext("expired", IntermediateTask, "parentTaskID", "parentNode", 2, merger, nil)
+	taskManager1.Submit(expiredTask, now.Add(-time.Second))
+	pendingTask := newTaskContext("pending", IntermediateTask, "parentTaskID", "parentNode", 2, merger, nil)
+	taskManager1.Submit(pendingTask, time.Time{})
+	// task completed by the result received concurrently isn't reaped again
+	completedTask := newTaskContext("completed", IntermediateTask, "parentTaskID", "parentNode", 1, merger, nil)
+	taskManager1.Submit(completedTask, now.Add(-time.Second))
+	merger.EXPECT().merge(gomock.Any())
+	merger.EXPECT().close(nil)
+	assert.True(t, completedTask.ReceiveResult(&pb.TaskResponse{TaskID: "completed", Completed: true}))
+	taskManager2.tasks.Store("invalid", nil)
+
+	// merger of expired task is closed with error, the job of root task is completed,
+	// send error response of expired task to parent, root task has no parent
+	rootMerger.EXPECT().close(gomock.Not(nil))
+	merger.EXPECT().close(gomock.Not(nil))
+	taskServerFactory.EXPECT().GetStream("parentNode").Return(nil)
+	taskManager2.reapExpiredTasks(now)
+	assert.Nil(t, taskManager1.Get("root"))
+	assert.True(t, rootTask.Completed())
+	assert.Equal(t, errTaskTimeout, rootTask.Error())
+	assert.True(t, jobCtx.Completed())
+	assert.Nil(t, taskManager1.Get("expired"))
+	assert.True(t, expiredTask.Completed())
+	assert.Equal(t, errTaskTimeout, expiredTask.Error())
+	assert.Nil(t, taskManager1.Get("completed"))
+	assert.Nil(t, completedTask.Error())
+	// task is reaped after default timeout
+	assert.Equal(t, pendingTask, taskManager1.Get("pending"))
+	assert.False(t, pendingTask.Completed())
+	server := pb.NewMockTaskService_HandleServer(ctrl)
+	taskServerFactory.EXPECT().GetStream("parentNode").Return(server)
+	server.EXPECT().Send(&pb.TaskResponse{TaskID: "parentTaskID", Completed: true, ErrMsg: errTaskTimeout.Error()}).Return(nil)
+	merger.EXPECT().close(gomock.Not(nil))
+	taskManager2.reapExpiredTasks(now.Add(defaultTaskTimeout + time.Second))
+	assert.Nil(t, taskManager1.Get("pending"))
+}
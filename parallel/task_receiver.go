@@ -3,7 +3,6 @@ package parallel
 import (
 	"github.com/lindb/lindb/rpc"
 	pb "github.com/lindb/lindb/rpc/proto/common"
-	"github.com/lindb/lindb/series"
 )
 
 // taskReceiver represents receive the task result from the sub tasks
@@ -25,21 +24,9 @@ func (r *taskReceiver) Receive(resp *pb.TaskResponse) error {
 		return nil
 	}
 
-	taskCtx.ReceiveResult(resp)
-
-	if taskCtx.Completed() {
+	// the result set or error of root task is sent to the job context by the task context when completed
+	if taskCtx.ReceiveResult(resp) {
 		taskManager.Complete(taskID)
-
-		if taskCtx.TaskType() == RootTask {
-			jobCtx := r.jobManager.GetJob(resp.JobID)
-			if jobCtx != nil && !jobCtx.Completed() {
-				err := taskCtx.Error()
-				if err != nil {
-					jobCtx.Emit(&series.TimeSeriesEvent{Err: err})
-				}
-				jobCtx.Complete()
-			}
-		}
 	}
 	return nil
 }
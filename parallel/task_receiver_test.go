@@ -3,13 +3,12 @@ package parallel
 import (
 	"context"
 	"fmt"
-	"sync"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
-	"go.uber.org/atomic"
 
+	"github.com/lindb/lindb/aggregation"
 	pb "github.com/lindb/lindb/rpc/proto/common"
 	"github.com/lindb/lindb/series"
 )
@@ -28,33 +27,20 @@ func TestTaskReceiver_Receive(t *testing.T) {
 	assert.Nil(t, err)
 
 	merger := NewMockResultMerger(ctrl)
-	taskCtx := newTaskContext("taskID", RootTask, "parentTaskID", "parentNode", 1, merger)
-	c := taskCtx.(*taskContext)
-	c.err = fmt.Errorf("err")
-	merger.EXPECT().merge(gomock.Any())
-	merger.EXPECT().close()
-	taskManager.EXPECT().Complete("taskID")
-	taskManager.EXPECT().Get("taskID").Return(taskCtx)
-	ch := make(chan *series.TimeSeriesEvent)
-	jobCtx := NewJobContext(context.TODO(), ch, nil, nil)
-	jobManager.EXPECT().GetJob(gomock.Any()).Return(jobCtx)
-	a := atomic.NewInt32(0)
-
-	var wait sync.WaitGroup
-	wait.Add(1)
-	go func() {
-		for r := range ch {
-			if r.Err != nil {
-				a.Inc()
-			}
-			wait.Done()
-		}
-	}()
+	jobCtx := NewJobContext(context.TODO(), make(chan *series.TimeSeriesEvent), nil, nil)
+	taskCtx := newTaskContext("taskID", RootTask, "", "", 2, merger, jobCtx)
+	taskManager.EXPECT().Get("taskID").Return(taskCtx).Times(2)
+	merger.EXPECT().merge(gomock.Any()).Times(2)
+	err = receiver.Receive(&pb.TaskResponse{TaskID: "taskID", Completed: true})
+	assert.Nil(t, err)
+	assert.False(t, jobCtx.Completed())
 
+	// root task completed, the job is completed after the result set sent by merger
+	merger.EXPECT().close(nil)
+	taskManager.EXPECT().Complete("taskID")
 	err = receiver.Receive(&pb.TaskResponse{TaskID: "taskID", Completed: true})
 	assert.Nil(t, err)
-	wait.Wait()
-	assert.Equal(t, int32(1), a.Load())
+	assert.True(t, jobCtx.Completed())
 }
 
 func TestTaskReceiver_Receive_Err(t *testing.T) {
@@ -66,23 +52,19 @@ func TestTaskReceiver_Receive_Err(t *testing.T) {
 	jobManager.EXPECT().GetTaskManager().Return(taskManager).AnyTimes()
 	receiver := NewTaskReceiver(jobManager)
 
-	merger := NewMockResultMerger(ctrl)
-	taskCtx := newTaskContext("taskID", RootTask, "parentTaskID", "parentNode", 1, merger)
-	taskManager.EXPECT().Complete("taskID").MaxTimes(2)
-	taskManager.EXPECT().Get("taskID").Return(taskCtx).MaxTimes(2)
 	ch := make(chan *series.TimeSeriesEvent)
 	jobCtx := NewJobContext(context.TODO(), ch, nil, nil)
-	jobManager.EXPECT().GetJob(gomock.Any()).Return(jobCtx).MaxTimes(2)
-	a := atomic.NewInt32(0)
-	var wait sync.WaitGroup
-	wait.Add(1)
+	groupAgg := aggregation.NewMockGroupingAggregator(ctrl)
+	taskCtx := newTaskContext("taskID", RootTask, "", "", 1, newResultMerger(context.TODO(), groupAgg, ch), jobCtx)
+	taskManager.EXPECT().Complete("taskID")
+	taskManager.EXPECT().Get("taskID").Return(taskCtx).Times(2)
+	var errs []error
+	done := make(chan struct{})
 	go func() {
 		for r := range ch {
-			if r.Err != nil {
-				a.Inc()
-			}
-			wait.Done()
+			errs = append(errs, r.Err)
 		}
+		close(done)
 	}()
 
 	err := receiver.Receive(&pb.TaskResponse{TaskID: "taskID", Completed: true, ErrMsg: "error"})
@@ -90,6 +72,8 @@ func TestTaskReceiver_Receive_Err(t *testing.T) {
 	// ignore response
 	err = receiver.Receive(&pb.TaskResponse{TaskID: "taskID", Completed: true})
 	assert.Nil(t, err)
-	wait.Wait()
-	assert.Equal(t, int32(1), a.Load())
+	// job completed with the error of root task
+	<-done
+	assert.True(t, jobCtx.Completed())
+	assert.Equal(t, []error{fmt.Errorf("error")}, errs)
 }
@@ -34,18 +34,29 @@ type bufioWriter struct {
 	w        *bufio.Writer
 	f        *os.File
 	size     int64
+	bufSize  int
 }
 
 // NewBufioWriter returns a new BufioWriter from fileName.
 func NewBufioWriter(fileName string) (BufioWriter, error) {
+	return NewBufioWriterSize(fileName, defaultWriteBufferSize)
+}
+
+// NewBufioWriterSize returns a new BufioWriter from fileName with the buffer size,
+// larger buffer batches more writes into one syscall, uses the default size if size <= 0.
+func NewBufioWriterSize(fileName string, size int) (BufioWriter, error) {
+	if size <= 0 {
+		size = defaultWriteBufferSize
+	}
 	f, err := os.Create(fileName)
 	if err != nil {
 		return nil, err
 	}
 	return &bufioWriter{
 		fileName: fileName,
-		w:        bufio.NewWriterSize(f, defaultWriteBufferSize),
+		w:        bufio.NewWriterSize(f, size),
 		f:        f,
+		bufSize:  size,
 	}, nil
 }
 
@@ -59,7 +70,7 @@ func (bw *bufioWriter) Reset(fileName string) error {
 		return err
 	}
 	bw.f = newF
-	bw.w = bufio.NewWriterSize(newF, defaultWriteBufferSize)
+	bw.w = bufio.NewWriterSize(newF, bw.bufSize)
 	bw.size = 0
 	bw.fileName = fileName
 	return nil
@@ -1,8 +1,10 @@
 package bufioutil
 
 import (
+	"bufio"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -73,6 +75,68 @@ func BenchmarkBufioWriter_Write(b *testing.B) {
 	}
 }
 
+// syscallCounter counts the writes of underlying file, each write is a syscall
+type syscallCounter struct {
+	f     *os.File
+	count int
+}
+
+func (c *syscallCounter) Write(p []byte) (int, error) {
+	c.count++
+	return c.f.Write(p)
+}
+
+func BenchmarkBufioWriter_BufferSize(b *testing.B) {
+	// a large metric with many field blocks
+	block := make([]byte, 1024)
+	const blocks = 4096
+	for _, size := range []int{4 * 1024, defaultWriteBufferSize, 4 * 1024 * 1024} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			defer os.Remove(_testFile)
+			bw, _ := NewBufioWriterSize(_testFile, size)
+			w := bw.(*bufioWriter)
+			counter := &syscallCounter{f: w.f}
+			w.w = bufio.NewWriterSize(counter, size)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := 0; j < blocks; j++ {
+					if _, err := bw.Write(block); err != nil {
+						assert.Nil(b, err)
+					}
+				}
+				if err := bw.Flush(); err != nil {
+					assert.Nil(b, err)
+				}
+			}
+			b.StopTimer()
+			b.Logf("buffer size: %d, syscalls per flush: %d", size, counter.count/b.N)
+			_ = bw.Close()
+		})
+	}
+}
+
+func TestNewBufioWriterSize(t *testing.T) {
+	defer os.Remove(_testFile)
+	defer os.Remove("new" + _testFile)
+
+	bw, err := NewBufioWriterSize(_testFile, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, defaultWriteBufferSize, bw.(*bufioWriter).w.Size())
+	assert.Nil(t, bw.Close())
+
+	bw, err = NewBufioWriterSize(_testFile, 1024*1024)
+	assert.Nil(t, err)
+	assert.Equal(t, 1024*1024, bw.(*bufioWriter).w.Size())
+	// keeps buffer size after reset
+	assert.Nil(t, bw.Reset("new"+_testFile))
+	assert.Equal(t, 1024*1024, bw.(*bufioWriter).w.Size())
+	assert.Nil(t, bw.Close())
+
+	_, err = NewBufioWriterSize("/not-exist/"+_testFile, 1024)
+	assert.NotNil(t, err)
+}
+
 func TestBufioWriter_Close(t *testing.T) {
 	defer os.Remove(_testFile)
 	bw, _ := NewBufioWriter(_testFile)
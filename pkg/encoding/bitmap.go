@@ -0,0 +1,76 @@
+package encoding
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/RoaringBitmap/roaring"
+)
+
+// BitmapFormat represents the serialization format version of the series id bitmap,
+// which is written as the first byte of the serialized bitmap.
+type BitmapFormat byte
+
+// Defines all the bitmap serialization formats
+const (
+	// BitmapFormatRoaringV1 is the portable serialization format of roaring bitmap
+	BitmapFormatRoaringV1 BitmapFormat = iota + 1
+)
+
+// CurrentBitmapFormat is the format used when marshaling bitmap
+const CurrentBitmapFormat = BitmapFormatRoaringV1
+
+const (
+	// roaringSerialCookieNoRunContainer is the cookie of portable roaring bitmap without run containers
+	roaringSerialCookieNoRunContainer = 12346
+	// roaringSerialCookie is the cookie of portable roaring bitmap with run containers
+	roaringSerialCookie = 12347
+)
+
+// BitmapMarshal marshals the bitmap with the current format
+func BitmapMarshal(bitmap *roaring.Bitmap) ([]byte, error) {
+	return BitmapMarshalWithFormat(bitmap, CurrentBitmapFormat)
+}
+
+// BitmapMarshalWithFormat marshals the bitmap with the format.
+// layout: [format version(1 byte), bitmap data]
+func BitmapMarshalWithFormat(bitmap *roaring.Bitmap, format BitmapFormat) ([]byte, error) {
+	switch format {
+	case BitmapFormatRoaringV1:
+		data, err := bitmap.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{byte(format)}, data...), nil
+	default:
+		return nil, fmt.Errorf("unknown bitmap format version: %d", format)
+	}
+}
+
+// BitmapUnmarshal unmarshals the data into the bitmap, dispatches on the format version byte,
+// the legacy data without format version byte is detected by the cookie of roaring bitmap.
+func BitmapUnmarshal(bitmap *roaring.Bitmap, data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("bitmap data is empty")
+	}
+	if isLegacyRoaring(data) {
+		return bitmap.UnmarshalBinary(data)
+	}
+	format := BitmapFormat(data[0])
+	switch format {
+	case BitmapFormatRoaringV1:
+		return bitmap.UnmarshalBinary(data[1:])
+	default:
+		return fmt.Errorf("unknown bitmap format version: %d", format)
+	}
+}
+
+// isLegacyRoaring returns if the data is the portable roaring bitmap written without format version byte,
+// whose first 2 bytes are the cookie in little endian, never conflicts with the format version byte.
+func isLegacyRoaring(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	cookie := binary.LittleEndian.Uint16(data)
+	return cookie == roaringSerialCookieNoRunContainer || cookie == roaringSerialCookie
+}
@@ -0,0 +1,61 @@
+package encoding
+
+import (
+	"testing"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitmapMarshal(t *testing.T) {
+	bitmap := roaring.BitmapOf(1, 2, 3, 1000, 100000)
+	data, err := BitmapMarshal(bitmap)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(CurrentBitmapFormat), data[0])
+
+	bitmap2 := roaring.New()
+	assert.NoError(t, BitmapUnmarshal(bitmap2, data))
+	assert.True(t, bitmap.Equals(bitmap2))
+
+	// read bitmap tagged with v1 format
+	raw, _ := bitmap.MarshalBinary()
+	bitmap3 := roaring.New()
+	assert.NoError(t, BitmapUnmarshal(bitmap3, append([]byte{byte(BitmapFormatRoaringV1)}, raw...)))
+	assert.True(t, bitmap.Equals(bitmap3))
+}
+
+func TestBitmapMarshal_unknownFormat(t *testing.T) {
+	bitmap := roaring.BitmapOf(1, 2, 3)
+	_, err := BitmapMarshalWithFormat(bitmap, BitmapFormat(100))
+	assert.EqualError(t, err, "unknown bitmap format version: 100")
+
+	data, _ := BitmapMarshal(bitmap)
+	data[0] = 100
+	err = BitmapUnmarshal(roaring.New(), data)
+	assert.EqualError(t, err, "unknown bitmap format version: 100")
+
+	assert.Error(t, BitmapUnmarshal(roaring.New(), nil))
+	// corrupted data
+	assert.Error(t, BitmapUnmarshal(roaring.New(), data[:1]))
+}
+
+func TestBitmapUnmarshal_legacy(t *testing.T) {
+	// roaring bitmap of (1, 2, 3, 100000) written without format version byte
+	legacy := []byte{0x3a, 0x30, 0x0, 0x0, 0x2, 0x0, 0x0, 0x0, 0x0, 0x0, 0x2, 0x0, 0x1, 0x0, 0x0, 0x0, 0x18, 0x0,
+		0x0, 0x0, 0x1e, 0x0, 0x0, 0x0, 0x1, 0x0, 0x2, 0x0, 0x3, 0x0, 0xa0, 0x86}
+	bitmap := roaring.New()
+	assert.NoError(t, BitmapUnmarshal(bitmap, legacy))
+	assert.Equal(t, []uint32{1, 2, 3, 100000}, bitmap.ToArray())
+
+	// roaring bitmap with run container of [10, 1000) written without format version byte
+	legacyRun := []byte{0x3b, 0x30, 0x0, 0x0, 0x1, 0x0, 0x0, 0xdd, 0x3, 0x1, 0x0, 0xa, 0x0, 0xdd, 0x3}
+	bitmap = roaring.New()
+	assert.NoError(t, BitmapUnmarshal(bitmap, legacyRun))
+	assert.Equal(t, uint64(990), bitmap.GetCardinality())
+	assert.True(t, bitmap.Contains(10))
+	assert.True(t, bitmap.Contains(999))
+
+	// the legacy data equals the bitmap data written by roaring directly
+	raw, _ := roaring.BitmapOf(1, 2, 3, 100000).MarshalBinary()
+	assert.Equal(t, legacy, raw)
+}
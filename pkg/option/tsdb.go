@@ -2,6 +2,8 @@ package option
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/lindb/lindb/pkg/timeutil"
 )
@@ -21,12 +23,254 @@ type DatabaseOption struct {
 
 	Index FlusherOption `toml:"index" json:"index,omitempty"` // index flusher option
 	Data  FlusherOption `toml:"data" json:"data,omitempty"`   // data flusher data
+
+	// max number of distinct families kept in memory, force flushes the oldest when exceeded, 0 means unlimited
+	MaxFamilies int `toml:"maxFamilies" json:"maxFamilies,omitempty"`
+	// max length of metric name, rejects the metric when exceeded, 0 means unlimited
+	MaxMetricNameLength int `toml:"maxMetricNameLength" json:"maxMetricNameLength,omitempty"`
+	// max count of tags in one metric write, rejects the metric when exceeded, 0 means unlimited
+	MaxTagsPerMetric int `toml:"maxTagsPerMetric" json:"maxTagsPerMetric,omitempty"`
+
+	ObjectStore ObjectStoreOption `toml:"objectStore" json:"objectStore,omitempty"` // object store for flush output
+
+	TagNormalization TagNormalizationOption `toml:"tagNormalization" json:"tagNormalization,omitempty"` // tag value normalization at ingest
+
+	// precision of the written timestamp, converts to millisecond at ingest
+	TimestampPrecision TimestampPrecision `toml:"timestampPrecision" json:"timestampPrecision,omitempty"`
+
+	Quota QuotaOption `toml:"quota" json:"quota,omitempty"` // resource quota of database for multi-tenancy
+
+	FieldFlushOrder FieldFlushOrderOption `toml:"fieldFlushOrder" json:"fieldFlushOrder,omitempty"` // field order of flushed data
+
+	// retention of fields in memory which are evicted sooner than others
+	FieldRetention []FieldRetentionOption `toml:"fieldRetention" json:"fieldRetention,omitempty"`
+
+	FlushWrite FlushWriteOption `toml:"flushWrite" json:"flushWrite,omitempty"` // write behavior during flush
+
+	// per-metric interval overriding the write interval for coarser metrics
+	MetricIntervals []MetricIntervalOption `toml:"metricIntervals" json:"metricIntervals,omitempty"`
+
+	// per-metric ttl of the series versions in forward index overriding the default ttl
+	MetricTTL []MetricTTLOption `toml:"metricTTL" json:"metricTTL,omitempty"`
+}
+
+// MetricTTLOption represents the ttl of the series versions of metric in forward index,
+// the versions older than ttl are dropped when compacting the forward index, except the latest one.
+type MetricTTLOption struct {
+	Metric string `toml:"metric" json:"metric"` // metric name
+	TTL    string `toml:"ttl" json:"ttl"`       // ttl of metric, like 7d
+}
+
+// MetricIntervalOption represents the interval of metric which overrides the write interval of database,
+// the interval must be multiple of the write interval with same interval type(day/month/year),
+// so that fine and coarse metrics share the same families.
+type MetricIntervalOption struct {
+	Metric   string `toml:"metric" json:"metric"`     // metric name
+	Interval string `toml:"interval" json:"interval"` // interval of metric, like 1m
+}
+
+// FieldRetentionOption represents the retention of field in memory, the old data of field is evicted after retention
+type FieldRetentionOption struct {
+	Field     string `toml:"field" json:"field"`         // field name
+	Retention string `toml:"retention" json:"retention"` // retention of field, like 1h
+}
+
+// Defines all the field orders of the flushed metric block
+const (
+	// FieldOrderDefault flushes the fields in the order of field metas
+	FieldOrderDefault = ""
+	// FieldOrderByID flushes the fields ordered by field id
+	FieldOrderByID = "id"
+	// FieldOrderByAffinity flushes the fields of affinity first in configured order, then the others by field id
+	FieldOrderByAffinity = "affinity"
+)
+
+// FieldFlushOrderOption represents the field order of the flushed metric block,
+// grouping the frequently co-queried fields adjacently improves read locality.
+type FieldFlushOrderOption struct {
+	Order    string   `toml:"order" json:"order,omitempty"`       // field order: ""(field metas order)/id/affinity
+	Affinity []string `toml:"affinity" json:"affinity,omitempty"` // field names flushed adjacently in order
+}
+
+// Validate validates field flush order option if valid
+func (o FieldFlushOrderOption) Validate() error {
+	switch o.Order {
+	case FieldOrderDefault, FieldOrderByID:
+		return nil
+	case FieldOrderByAffinity:
+		if len(o.Affinity) == 0 {
+			return fmt.Errorf("field affinity cannot be empty")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown field flush order: %s", o.Order)
+	}
+}
+
+// Defines all the policies of writes during a flush in-progress
+const (
+	// FlushWriteAccept accepts the writes during flush, the written data go into the mutable index
+	// and the families are marked again, then they are flushed by next flush
+	FlushWriteAccept = "accept"
+	// FlushWriteBlockBrief blocks the writes and metric resets until the flush in-progress completes
+	// or the max wait elapses, then accepts them like FlushWriteAccept
+	FlushWriteBlockBrief = "block-brief"
+)
+
+// FlushWriteOption represents the write behavior during a flush in-progress, no write is dropped by any policy
+type FlushWriteOption struct {
+	Policy  string `toml:"policy" json:"policy,omitempty"`   // accept(default)/block-brief
+	MaxWait string `toml:"maxWait" json:"maxWait,omitempty"` // max wait of block-brief policy, default 1s
+}
+
+// Validate validates flush write option if valid
+func (o FlushWriteOption) Validate() error {
+	switch o.Policy {
+	case "", FlushWriteAccept, FlushWriteBlockBrief:
+	default:
+		return fmt.Errorf("unknown flush write policy: %s", o.Policy)
+	}
+	return validateInterval(o.MaxWait, false)
+}
+
+// QuotaOption represents the resource quota of database, rejects the writes when exceeded, 0 means unlimited
+type QuotaOption struct {
+	MaxSeries     int   `toml:"maxSeries" json:"maxSeries,omitempty"`         // max number of series in memory
+	MaxMemory     int64 `toml:"maxMemory" json:"maxMemory,omitempty"`         // max memory size, unit(MB)
+	MaxIngestRate int   `toml:"maxIngestRate" json:"maxIngestRate,omitempty"` // max written points per second
+}
+
+// Validate validates quota option if valid
+func (o QuotaOption) Validate() error {
+	if o.MaxSeries < 0 || o.MaxMemory < 0 || o.MaxIngestRate < 0 {
+		return fmt.Errorf("quota cannot be negative")
+	}
+	return nil
+}
+
+// TimestampPrecision represents the precision of the timestamp sent by client
+type TimestampPrecision string
+
+// Defines all the timestamp precisions
+const (
+	PrecisionSecond      TimestampPrecision = "s"
+	PrecisionMillisecond TimestampPrecision = "ms"
+	PrecisionMicrosecond TimestampPrecision = "us"
+	PrecisionNanosecond  TimestampPrecision = "ns"
+)
+
+// Validate validates timestamp precision if valid, empty means millisecond
+func (p TimestampPrecision) Validate() error {
+	switch p {
+	case "", PrecisionSecond, PrecisionMillisecond, PrecisionMicrosecond, PrecisionNanosecond:
+		return nil
+	default:
+		return fmt.Errorf("unknown timestamp precision: %s", p)
+	}
+}
+
+// ToMillisecond converts the timestamp with the precision to millisecond(internal unit)
+func (p TimestampPrecision) ToMillisecond(timestamp int64) int64 {
+	switch p {
+	case PrecisionSecond:
+		return timestamp * 1000
+	case PrecisionMicrosecond:
+		return timestamp / 1000
+	case PrecisionNanosecond:
+		return timestamp / 1000000
+	default:
+		return timestamp
+	}
+}
+
+// TagNormalizationOption represents the normalization of tag value before indexing,
+// avoids cardinality from trailing spaces or case variance.
+type TagNormalizationOption struct {
+	Trim      bool `toml:"trim" json:"trim,omitempty"`           // trims the leading and trailing spaces
+	Lowercase bool `toml:"lowercase" json:"lowercase,omitempty"` // converts to lower case
+}
+
+// Enabled returns if normalizes the tag value
+func (o TagNormalizationOption) Enabled() bool {
+	return o.Trim || o.Lowercase
+}
+
+// Normalize returns the normalized tag value
+func (o TagNormalizationOption) Normalize(value string) string {
+	if o.Trim {
+		value = strings.TrimSpace(value)
+	}
+	if o.Lowercase {
+		value = strings.ToLower(value)
+	}
+	return value
+}
+
+const (
+	// FlushToLocal flushes memory data to local disk only
+	FlushToLocal = "local"
+	// FlushToRemote flushes memory data to object store only
+	FlushToRemote = "remote"
+	// FlushToBoth flushes memory data to local disk and object store
+	FlushToBoth = "both"
+)
+
+// ObjectStoreOption represents a S3-compatible object store configuration for flushing memory data
+type ObjectStoreOption struct {
+	Endpoint string `toml:"endpoint" json:"endpoint,omitempty"` // object store endpoint, like http://127.0.0.1:9000
+	Bucket   string `toml:"bucket" json:"bucket,omitempty"`     // bucket name
+	Mode     string `toml:"mode" json:"mode,omitempty"`         // flush mode: local(default)/remote/both
+	Timeout  string `toml:"timeout" json:"timeout,omitempty"`   // timeout of uploading object, like 30s, default 30s
+}
+
+// defaultObjectStoreTimeout represents the default timeout of uploading object
+const defaultObjectStoreTimeout = 30 * time.Second
+
+// GetTimeout returns the timeout of uploading object, the default timeout if not set
+func (o ObjectStoreOption) GetTimeout() time.Duration {
+	var timeout timeutil.Interval
+	if err := timeout.ValueOf(o.Timeout); err != nil || timeout <= 0 {
+		return defaultObjectStoreTimeout
+	}
+	return time.Duration(timeout.Int64()) * time.Millisecond
+}
+
+// Enabled returns if flushes memory data to object store
+func (o ObjectStoreOption) Enabled() bool {
+	return o.Mode == FlushToRemote || o.Mode == FlushToBoth
+}
+
+// Validate validates object store option if valid
+func (o ObjectStoreOption) Validate() error {
+	switch o.Mode {
+	case "", FlushToLocal:
+		return nil
+	case FlushToRemote, FlushToBoth:
+		if o.Endpoint == "" || o.Bucket == "" {
+			return fmt.Errorf("object store endpoint/bucket cannot be empty")
+		}
+		if err := validateInterval(o.Timeout, false); err != nil {
+			return fmt.Errorf("object store timeout is invalid, err: %s", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown object store flush mode: %s", o.Mode)
+	}
 }
 
 // FlusherOption represents a flusher configuration for index and memory db
 type FlusherOption struct {
-	TimeThreshold int64 `toml:"timeThreshold" json:"timeThreshold"` // time level flush threshold
-	SizeThreshold int64 `toml:"sizeThreshold" json:"sizeThreshold"` // size level flush threshold, unit(MB)
+	TimeThreshold int64 `toml:"timeThreshold" json:"timeThreshold"`     // time level flush threshold
+	SizeThreshold int64 `toml:"sizeThreshold" json:"sizeThreshold"`     // size level flush threshold, unit(MB)
+	BufferSize    int   `toml:"bufferSize" json:"bufferSize,omitempty"` // write buffer size of flushed file, unit(KB), 0 means default
+}
+
+// Validate validates flusher option if valid
+func (e FlusherOption) Validate() error {
+	if e.BufferSize < 0 {
+		return fmt.Errorf("flush buffer size cannot be negative")
+	}
+	return nil
 }
 
 // Validate validates engine option if valid
@@ -45,6 +289,49 @@ func (e DatabaseOption) Validate() error {
 	if err := validateInterval(e.Behind, false); err != nil {
 		return err
 	}
+	if e.MaxFamilies < 0 {
+		return fmt.Errorf("max families cannot be negative")
+	}
+	if err := e.Index.Validate(); err != nil {
+		return err
+	}
+	if err := e.Data.Validate(); err != nil {
+		return err
+	}
+	if e.MaxMetricNameLength < 0 {
+		return fmt.Errorf("max metric name length cannot be negative")
+	}
+	if e.MaxTagsPerMetric < 0 {
+		return fmt.Errorf("max tags per metric cannot be negative")
+	}
+	if err := e.TimestampPrecision.Validate(); err != nil {
+		return err
+	}
+	if err := e.Quota.Validate(); err != nil {
+		return err
+	}
+	if err := e.FieldFlushOrder.Validate(); err != nil {
+		return err
+	}
+	if err := e.FlushWrite.Validate(); err != nil {
+		return err
+	}
+	if err := e.validateMetricIntervals(); err != nil {
+		return err
+	}
+	for _, metricTTL := range e.MetricTTL {
+		if metricTTL.Metric == "" {
+			return fmt.Errorf("metric name of metric ttl cannot be empty")
+		}
+		if err := validateInterval(metricTTL.TTL, true); err != nil {
+			return fmt.Errorf("ttl of metric[%s] is invalid, err: %s", metricTTL.Metric, err)
+		}
+	}
+	for _, fieldRetention := range e.FieldRetention {
+		if err := validateInterval(fieldRetention.Retention, true); err != nil {
+			return fmt.Errorf("retention of field[%s] is invalid, err: %s", fieldRetention.Field, err)
+		}
+	}
 	var interval timeutil.Interval
 	_ = interval.ValueOf(e.Interval)
 	for _, intervalStr := range e.Rollup {
@@ -54,6 +341,30 @@ func (e DatabaseOption) Validate() error {
 			return fmt.Errorf("rollup interval must be large than write interval")
 		}
 	}
+	return e.ObjectStore.Validate()
+}
+
+// validateMetricIntervals checks the per-metric intervals if valid
+func (e DatabaseOption) validateMetricIntervals() error {
+	if len(e.MetricIntervals) == 0 {
+		return nil
+	}
+	var interval timeutil.Interval
+	_ = interval.ValueOf(e.Interval)
+	for _, metricInterval := range e.MetricIntervals {
+		if metricInterval.Metric == "" {
+			return fmt.Errorf("metric name of metric interval cannot be empty")
+		}
+		if err := validateInterval(metricInterval.Interval, true); err != nil {
+			return fmt.Errorf("interval of metric[%s] is invalid, err: %s", metricInterval.Metric, err)
+		}
+		var value timeutil.Interval
+		_ = value.ValueOf(metricInterval.Interval)
+		if value.Int64()%interval.Int64() != 0 || value.Type() != interval.Type() {
+			return fmt.Errorf("interval of metric[%s] must be multiple of write interval with same interval type",
+				metricInterval.Metric)
+		}
+	}
 	return nil
 }
 
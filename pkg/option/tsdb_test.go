@@ -2,6 +2,7 @@ package option
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -27,4 +28,133 @@ func Test_DatabaseOption_Validate(t *testing.T) {
 	assert.NotNil(t, databaseOption.Validate())
 	databaseOption = DatabaseOption{Interval: "10s", Rollup: []string{"20s", "1m", "1h"}, Behind: "10h", Ahead: "1h"}
 	assert.Nil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", MaxFamilies: -1}
+	assert.NotNil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", MaxFamilies: 24}
+	assert.Nil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", MaxMetricNameLength: -1}
+	assert.NotNil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", MaxMetricNameLength: 128}
+	assert.Nil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", Index: FlusherOption{BufferSize: -1}}
+	assert.NotNil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", Data: FlusherOption{BufferSize: -1}}
+	assert.NotNil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", Index: FlusherOption{BufferSize: 64}, Data: FlusherOption{BufferSize: 1024}}
+	assert.Nil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", MaxTagsPerMetric: -1}
+	assert.NotNil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", MaxTagsPerMetric: 32}
+	assert.Nil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", Quota: QuotaOption{MaxIngestRate: -1}}
+	assert.NotNil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", Quota: QuotaOption{MaxSeries: 100, MaxMemory: 1024, MaxIngestRate: 1000}}
+	assert.Nil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", FieldRetention: []FieldRetentionOption{{Field: "debug", Retention: "1x"}}}
+	assert.NotNil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", FieldRetention: []FieldRetentionOption{{Field: "debug", Retention: "1h"}}}
+	assert.Nil(t, databaseOption.Validate())
+}
+
+func Test_FieldFlushOrderOption_Validate(t *testing.T) {
+	databaseOption := DatabaseOption{Interval: "10s", FieldFlushOrder: FieldFlushOrderOption{Order: FieldOrderByID}}
+	assert.Nil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", FieldFlushOrder: FieldFlushOrderOption{Order: "random"}}
+	assert.NotNil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", FieldFlushOrder: FieldFlushOrderOption{Order: FieldOrderByAffinity}}
+	assert.NotNil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s",
+		FieldFlushOrder: FieldFlushOrderOption{Order: FieldOrderByAffinity, Affinity: []string{"count", "sum"}}}
+	assert.Nil(t, databaseOption.Validate())
+}
+
+func Test_FlushWriteOption_Validate(t *testing.T) {
+	databaseOption := DatabaseOption{Interval: "10s", FlushWrite: FlushWriteOption{Policy: FlushWriteBlockBrief, MaxWait: "2s"}}
+	assert.Nil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", FlushWrite: FlushWriteOption{Policy: FlushWriteAccept}}
+	assert.Nil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", FlushWrite: FlushWriteOption{Policy: "drop"}}
+	assert.NotNil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", FlushWrite: FlushWriteOption{Policy: FlushWriteBlockBrief, MaxWait: "1x"}}
+	assert.NotNil(t, databaseOption.Validate())
+}
+
+func Test_MetricIntervalOption_Validate(t *testing.T) {
+	databaseOption := DatabaseOption{Interval: "10s",
+		MetricIntervals: []MetricIntervalOption{{Metric: "disk", Interval: "1m"}}}
+	assert.Nil(t, databaseOption.Validate())
+	// empty metric name
+	databaseOption.MetricIntervals = []MetricIntervalOption{{Interval: "1m"}}
+	assert.NotNil(t, databaseOption.Validate())
+	// invalid interval
+	databaseOption.MetricIntervals = []MetricIntervalOption{{Metric: "disk", Interval: "1x"}}
+	assert.NotNil(t, databaseOption.Validate())
+	// not multiple of write interval
+	databaseOption.MetricIntervals = []MetricIntervalOption{{Metric: "disk", Interval: "15s"}}
+	assert.NotNil(t, databaseOption.Validate())
+	// different interval type
+	databaseOption.MetricIntervals = []MetricIntervalOption{{Metric: "disk", Interval: "5m"}}
+	assert.NotNil(t, databaseOption.Validate())
+}
+
+func Test_MetricTTLOption_Validate(t *testing.T) {
+	databaseOption := DatabaseOption{Interval: "10s",
+		MetricTTL: []MetricTTLOption{{Metric: "disk", TTL: "7d"}}}
+	assert.Nil(t, databaseOption.Validate())
+	// empty metric name
+	databaseOption.MetricTTL = []MetricTTLOption{{TTL: "7d"}}
+	assert.NotNil(t, databaseOption.Validate())
+	// invalid ttl
+	databaseOption.MetricTTL = []MetricTTLOption{{Metric: "disk", TTL: "7x"}}
+	assert.NotNil(t, databaseOption.Validate())
+}
+
+func Test_ObjectStoreOption_Validate(t *testing.T) {
+	databaseOption := DatabaseOption{Interval: "10s", ObjectStore: ObjectStoreOption{Mode: FlushToLocal}}
+	assert.Nil(t, databaseOption.Validate())
+	assert.False(t, databaseOption.ObjectStore.Enabled())
+	databaseOption = DatabaseOption{Interval: "10s", ObjectStore: ObjectStoreOption{Mode: "s3"}}
+	assert.NotNil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s", ObjectStore: ObjectStoreOption{Mode: FlushToRemote}}
+	assert.NotNil(t, databaseOption.Validate())
+	databaseOption = DatabaseOption{Interval: "10s",
+		ObjectStore: ObjectStoreOption{Mode: FlushToBoth, Endpoint: "http://127.0.0.1:9000", Bucket: "lindb"}}
+	assert.Nil(t, databaseOption.Validate())
+	assert.True(t, databaseOption.ObjectStore.Enabled())
+	assert.Equal(t, 30*time.Second, databaseOption.ObjectStore.GetTimeout())
+	databaseOption.ObjectStore.Timeout = "5s"
+	assert.Nil(t, databaseOption.Validate())
+	assert.Equal(t, 5*time.Second, databaseOption.ObjectStore.GetTimeout())
+	databaseOption.ObjectStore.Timeout = "5x"
+	assert.NotNil(t, databaseOption.Validate())
+}
+
+func Test_TagNormalizationOption(t *testing.T) {
+	opt := TagNormalizationOption{}
+	assert.False(t, opt.Enabled())
+	assert.Equal(t, " Host ", opt.Normalize(" Host "))
+	opt = TagNormalizationOption{Trim: true}
+	assert.True(t, opt.Enabled())
+	assert.Equal(t, "Host", opt.Normalize(" Host "))
+	opt = TagNormalizationOption{Lowercase: true}
+	assert.True(t, opt.Enabled())
+	assert.Equal(t, " host ", opt.Normalize(" Host "))
+	opt = TagNormalizationOption{Trim: true, Lowercase: true}
+	assert.Equal(t, "host", opt.Normalize(" Host "))
+}
+
+func Test_TimestampPrecision(t *testing.T) {
+	databaseOption := DatabaseOption{Interval: "10s", TimestampPrecision: "ps"}
+	assert.NotNil(t, databaseOption.Validate())
+	for _, precision := range []TimestampPrecision{"", PrecisionSecond, PrecisionMillisecond,
+		PrecisionMicrosecond, PrecisionNanosecond} {
+		databaseOption = DatabaseOption{Interval: "10s", TimestampPrecision: precision}
+		assert.Nil(t, databaseOption.Validate())
+	}
+	ms := int64(1573025411123)
+	assert.Equal(t, ms, TimestampPrecision("").ToMillisecond(ms))
+	assert.Equal(t, ms, PrecisionMillisecond.ToMillisecond(ms))
+	assert.Equal(t, int64(1573025411000), PrecisionSecond.ToMillisecond(1573025411))
+	assert.Equal(t, ms, PrecisionMicrosecond.ToMillisecond(ms*1000+999))
+	assert.Equal(t, ms, PrecisionNanosecond.ToMillisecond(ms*1000000+999999))
 }
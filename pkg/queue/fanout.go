@@ -74,10 +74,11 @@ type fanOutQueue struct {
 	closed int32
 }
 
-// NewFanOutQueue returns a FanOutQueue persisted in dirPath.
-func NewFanOutQueue(dirPath string, dataFileSize int, removeTaskInterval time.Duration) (FanOutQueue, error) {
+// NewFanOutQueue returns a FanOutQueue persisted in dirPath, messages of new segments are compressed by codec.
+func NewFanOutQueue(dirPath string, dataFileSize int, codec segment.Codec,
+	removeTaskInterval time.Duration) (FanOutQueue, error) {
 	// loads queue
-	q, err := NewQueue(dirPath, dataFileSize, removeTaskInterval)
+	q, err := NewQueue(dirPath, dataFileSize, codec, removeTaskInterval)
 	if err != nil {
 		return nil, err
 	}
@@ -274,8 +275,9 @@ func NewFanOut(metaPath string, q FanOutQueue) (FanOut, error) {
 		tailSeq = q.TailSeq()
 		headSeq = tailSeq
 	}
-	seg, err := q.GetSegment(headSeq)
+	seg, err := retainSegment(q, headSeq)
 	if err != nil {
+		_ = meta.Close()
 		return nil, err
 	}
 
@@ -334,10 +336,11 @@ func (f *fanOut) Get(seq int64) ([]byte, error) {
 	if err == segment.ErrOutOfRange {
 		var newSeg segment.Segment
 		// try to locate segment
-		newSeg, err = f.q.GetSegment(seq)
+		newSeg, err = retainSegment(f.q, seq)
 		if err != nil {
 			return nil, err
 		}
+		f.seg.Release()
 		f.seg = newSeg
 		bys, err = f.seg.Read(seq)
 	}
@@ -388,8 +391,23 @@ func (f *fanOut) Pending() int64 {
 // Close persists  headSeq, tailSeq.
 func (f *fanOut) Close() {
 	if atomic.CompareAndSwapInt32(&f.closed, 0, 1) {
+		f.seg.Release()
 		if err := f.meta.Close(); err != nil {
 			f.logger.Error("close fanOut meta error", logger.String("fanOut", f.name), logger.Error(err))
 		}
 	}
 }
+
+// retainSegment returns the segment contains seq with a reference retained for reading,
+// retries if the segment is released by compaction concurrently.
+func retainSegment(q FanOutQueue, seq int64) (segment.Segment, error) {
+	for {
+		seg, err := q.GetSegment(seq)
+		if err != nil {
+			return nil, err
+		}
+		if seg.Retain() {
+			return seg, nil
+		}
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/pkg/queue/segment"
 )
 
 const (
@@ -35,7 +37,7 @@ func TestOneFanOut(t *testing.T) {
 
 	}()
 
-	fq, err := NewFanOutQueue(dir, 1024, time.Minute)
+	fq, err := NewFanOutQueue(dir, 1024, segment.CodecNone, time.Minute)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -148,7 +150,7 @@ func TestFanOut_SetHeadSeq(t *testing.T) {
 
 	}()
 
-	fq, err := NewFanOutQueue(dir, 1024, time.Minute)
+	fq, err := NewFanOutQueue(dir, 1024, segment.CodecNone, time.Minute)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -203,7 +205,7 @@ func TestMultipleFanOut(t *testing.T) {
 
 	}()
 
-	fq, err := NewFanOutQueue(dir, 1024, time.Minute)
+	fq, err := NewFanOutQueue(dir, 1024, segment.CodecNone, time.Minute)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -278,7 +280,7 @@ func TestConcurrentRead(t *testing.T) {
 		bytesSli[i] = []byte(randomString(rand.Intn(10) + 1))
 	}
 
-	fq, err := NewFanOutQueue(dir, dataFileSize, time.Second)
+	fq, err := NewFanOutQueue(dir, dataFileSize, segment.CodecNone, time.Second)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -314,7 +316,7 @@ func TestConcurrentRead(t *testing.T) {
 	fq.Close()
 
 	// reload
-	fq2, err := NewFanOutQueue(dir, dataFileSize, time.Second)
+	fq2, err := NewFanOutQueue(dir, dataFileSize, segment.CodecNone, time.Second)
 	if err != nil {
 		t.Fatal(err)
 	}
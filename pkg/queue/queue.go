@@ -67,8 +67,10 @@ type queue struct {
 }
 
 // NewQueue returns Queue based on dirPath, dataFileSizeLimit is used to limit the segment file size,
+// codec specifics the compression codec of messages in new segments,
 // removeTaskInterval specifics the interval to remove expired segments.
-func NewQueue(dirPath string, dataFileSizeLimit int, removeTaskInterval time.Duration) (Queue, error) {
+func NewQueue(dirPath string, dataFileSizeLimit int, codec segment.Codec,
+	removeTaskInterval time.Duration) (Queue, error) {
 	if err := fileutil.MkDir(dirPath); err != nil {
 		return nil, err
 	}
@@ -80,7 +82,7 @@ func NewQueue(dirPath string, dataFileSizeLimit int, removeTaskInterval time.Dur
 	}
 
 	headSeq, tailSeq := meta.ReadInt64(queueHeadSeqOffset), meta.ReadInt64(queueTailSeqOffset)
-	fct, err := segment.NewFactory(path.Join(dirPath, segmentDirName), dataFileSizeLimit, headSeq, tailSeq)
+	fct, err := segment.NewFactory(path.Join(dirPath, segmentDirName), dataFileSizeLimit, codec, headSeq, tailSeq)
 	if err != nil {
 		return nil, err
 	}
@@ -200,7 +202,7 @@ func (q *queue) Close() {
 	}
 }
 
-// RemoveSegments removes segments before TailSeq.
+// RemoveSegments removes segments before TailSeq, then compacts the small sealed segments.
 func (q *queue) initRemoveSegmentsTask() {
 	go func() {
 		q.logger.Info("initRemoveSegmentsTask")
@@ -208,6 +210,9 @@ func (q *queue) initRemoveSegmentsTask() {
 			if err := q.fct.RemoveSegments(q.TailSeq()); err != nil {
 				q.logger.Error("remove segments error", logger.String("dirPath", q.dirPath), logger.Error(err))
 			}
+			if err := q.fct.CompactSegments(); err != nil {
+				q.logger.Error("compact segments error", logger.String("dirPath", q.dirPath), logger.Error(err))
+			}
 		}
 	}()
 }
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/pkg/queue/segment"
 )
 
 func TestOneSegment(t *testing.T) {
@@ -19,7 +21,7 @@ func TestOneSegment(t *testing.T) {
 
 	}()
 
-	q, err := NewQueue(dir, 1024, time.Minute)
+	q, err := NewQueue(dir, 1024, segment.CodecNone, time.Minute)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -65,7 +67,7 @@ func TestMultipleSegments(t *testing.T) {
 	}()
 
 	// interval 1 second for test
-	q, err := NewQueue(dir, 10, time.Second)
+	q, err := NewQueue(dir, 10, segment.CodecNone, time.Second)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,85 @@
+package segment
+
+import (
+	"fmt"
+
+	"github.com/golang/snappy"
+)
+
+// Codec represents the compression codec of the messages in segment,
+// which is written in the header byte of data file for the compressed segment.
+type Codec byte
+
+// Defines all codecs of segment
+const (
+	// CodecNone represents the messages are not compressed, the data file has no header,
+	// which is same as the segment written by old version.
+	CodecNone Codec = iota
+	// CodecSnappy represents the messages are compressed by snappy
+	CodecSnappy
+)
+
+// Defines the names of codec in config
+const (
+	CompressionNone   = "none"
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+)
+
+// segmentHeaderSize is the size of header(codec) in data file of compressed segment
+const segmentHeaderSize = 1
+
+// ParseCodec returns the codec by compression name in config, empty name means no compression.
+func ParseCodec(compression string) (Codec, error) {
+	switch compression {
+	case "", CompressionNone:
+		return CodecNone, nil
+	case CompressionSnappy:
+		return CodecSnappy, nil
+	case CompressionZstd:
+		return CodecNone, fmt.Errorf("compression: %s is not supported, available compression is %s/%s",
+			compression, CompressionNone, CompressionSnappy)
+	default:
+		return CodecNone, fmt.Errorf("unknown compression: %s", compression)
+	}
+}
+
+// String returns the compression name of codec
+func (c Codec) String() string {
+	switch c {
+	case CodecNone:
+		return CompressionNone
+	case CodecSnappy:
+		return CompressionSnappy
+	default:
+		return "unknown"
+	}
+}
+
+// headerSize returns the size of header in data file of the segment with codec
+func (c Codec) headerSize() int {
+	if c == CodecNone {
+		return 0
+	}
+	return segmentHeaderSize
+}
+
+// encode compresses the message by codec
+func (c Codec) encode(message []byte) []byte {
+	if c == CodecSnappy {
+		return snappy.Encode(nil, message)
+	}
+	return message
+}
+
+// decode decompresses the message by codec
+func (c Codec) decode(data []byte) ([]byte, error) {
+	switch c {
+	case CodecNone:
+		return data, nil
+	case CodecSnappy:
+		return snappy.Decode(nil, data)
+	default:
+		return nil, fmt.Errorf("unknown codec: %d of segment", c)
+	}
+}
@@ -0,0 +1,30 @@
+package segment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCodec(t *testing.T) {
+	codec, err := ParseCodec("")
+	assert.NoError(t, err)
+	assert.Equal(t, CodecNone, codec)
+	codec, err = ParseCodec("none")
+	assert.NoError(t, err)
+	assert.Equal(t, CodecNone, codec)
+	codec, err = ParseCodec("snappy")
+	assert.NoError(t, err)
+	assert.Equal(t, CodecSnappy, codec)
+	_, err = ParseCodec("zstd")
+	assert.Error(t, err)
+	_, err = ParseCodec("gzip")
+	assert.Error(t, err)
+
+	assert.Equal(t, "none", CodecNone.String())
+	assert.Equal(t, "snappy", CodecSnappy.String())
+	assert.Equal(t, "unknown", Codec(10).String())
+
+	_, err = Codec(10).decode([]byte("123"))
+	assert.Error(t, err)
+}
@@ -2,6 +2,7 @@ package segment
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"sort"
@@ -17,8 +18,10 @@ import (
 )
 
 const (
-	indexFileSuffix = ".idx"
-	dataFileSuffix  = ".dat"
+	indexFileSuffix    = ".idx"
+	dataFileSuffix     = ".dat"
+	compactFileSuffix  = ".compact"
+	manifestFileSuffix = ".manifest"
 )
 
 // ErrSegmentNotFound represents error when no suitable segment is found.
@@ -74,6 +77,9 @@ type Factory interface {
 	NewSegment(begSeq int64) (Segment, error)
 	// RemoveSegments removes segments with tailSeq <= ackSeq
 	RemoveSegments(ackSeq int64) error
+	// CompactSegments merges adjacent sealed segments into larger one if the data size fits in the size limit,
+	// all segments except the head one are sealed.
+	CompactSegments() error
 	// SegmentsSize returns segments size hold in factory, mainly for test
 	SegmentsSize() int
 	// Close closes the segments.
@@ -86,18 +92,23 @@ type factory struct {
 	dirPath string
 	// the max size limit in bytes for data file
 	dataFileSizeLimit int
+	// compression codec for new segments
+	codec Codec
 	// segments in ascending order
 	segments []Segment
 	// segments beg sequence slice
 	seqRange SeqRange
 	// lock for segments
 	lock4segments sync.RWMutex
-	logger        *logger.Logger
+	// lock for removing and compacting segments
+	lock4compact sync.Mutex
+	logger       *logger.Logger
 }
 
 // NewFactory builds a segment factory by loading file from dirPath.
 // HeadSeq and  TailSeq are used to filter segments in use.
-func NewFactory(dirPath string, dataFileSizeLimit int, headSeq, tailSeq int64) (Factory, error) {
+// The codec is used to compress the messages of new segments, loaded segments keep their own codec.
+func NewFactory(dirPath string, dataFileSizeLimit int, codec Codec, headSeq, tailSeq int64) (Factory, error) {
 	if err := fileutil.MkDir(dirPath); err != nil {
 		return nil, err
 	}
@@ -105,6 +116,7 @@ func NewFactory(dirPath string, dataFileSizeLimit int, headSeq, tailSeq int64) (
 	fct := &factory{
 		dirPath:           dirPath,
 		dataFileSizeLimit: dataFileSizeLimit,
+		codec:             codec,
 		segments:          make([]Segment, 0),
 		seqRange:          make(SeqRange, 0),
 		logger:            logger.GetLogger("pkg/queue", "SegmentFactory"),
@@ -120,6 +132,10 @@ func NewFactory(dirPath string, dataFileSizeLimit int, headSeq, tailSeq int64) (
 
 // load loads segments from dirPath, filtering segment file with headSeq and tailSeq.
 func (fct *factory) load(headSeq, tailSeq int64) error {
+	// finish or discard the compaction broken by crash
+	if err := fct.recoverCompactions(); err != nil {
+		return err
+	}
 	fileNames, err := fileutil.ListDir(fct.dirPath)
 	if err != nil {
 		return err
@@ -189,30 +205,42 @@ func (fct *factory) loadOrCreateSegment(begin, end int64, filePathSet map[string
 		return fmt.Errorf("segemnt file %s not found", dataFilePath)
 	}
 
-	dataMappedBytes, err := fileutil.RWMap(dataFilePath, fct.dataFileSizeLimit)
+	seg, err := fct.openSegment(indexFilePath, dataFilePath, begin, end)
 	if err != nil {
 		return err
 	}
+	fct.segments = append(fct.segments, seg)
+	fct.seqRange = append(fct.seqRange, begin)
+
+	return nil
+}
+
+// openSegment maps the index and data file, then returns the segment with sequence range [begin, end).
+func (fct *factory) openSegment(indexFilePath, dataFilePath string, begin, end int64) (Segment, error) {
+	dataMappedBytes, err := fileutil.RWMap(dataFilePath, fct.dataFileSizeLimit)
+	if err != nil {
+		return nil, err
+	}
 
 	// the worse case, all messages in a dataFile is one byte, one message takes 8 bytes for index
 	// don't worry about the disk usage since init size doesn't occupy real disk storage,
 	// and the index file will be truncated to proper size when close.
 	indexMappedBytes, err := fileutil.RWMap(indexFilePath, indexItemSize*fct.dataFileSizeLimit)
 	if err != nil {
-		return err
+		_ = fileutil.Unmap(dataMappedBytes)
+		return nil, err
 	}
 
 	dataMappedPage := page.NewMappedPage(dataFilePath, dataMappedBytes, page.MMapCloseFunc, page.MMapSyncFunc)
 	indexMappedPage := page.NewMappedPage(indexFilePath, indexMappedBytes, page.MMapCloseFunc, page.MMapSyncFunc)
 
-	seg, err := NewSegment(indexMappedPage, dataMappedPage, begin, end)
+	seg, err := NewSegment(indexMappedPage, dataMappedPage, begin, end, fct.codec)
 	if err != nil {
-		return err
+		_ = dataMappedPage.Close()
+		_ = indexMappedPage.Close()
+		return nil, err
 	}
-	fct.segments = append(fct.segments, seg)
-	fct.seqRange = append(fct.seqRange, begin)
-
-	return nil
+	return seg, nil
 }
 
 // buildFilePath concatenates the dirPath and fileName as a filePath
@@ -267,6 +295,9 @@ func (fct *factory) NewSegment(beginSeq int64) (Segment, error) {
 
 // RemoveSegments removes segments with tailSeq <= ackSeq, removing files error will only be logged, not returned.
 func (fct *factory) RemoveSegments(ackSeq int64) error {
+	fct.lock4compact.Lock()
+	defer fct.lock4compact.Unlock()
+
 	fct.lock4segments.Lock()
 	if fct.seqRange.Len() == 0 {
 		fct.lock4segments.Unlock()
@@ -318,6 +349,252 @@ func (fct *factory) RemoveSegments(ackSeq int64) error {
 	return nil
 }
 
+// CompactSegments merges adjacent sealed segments into larger one if the data size fits in the size limit.
+// The compacted segment keeps the file name of the first merged segment, so that the sequence index is
+// still valid after restart, the files of the other merged segments are removed.
+func (fct *factory) CompactSegments() error {
+	fct.lock4compact.Lock()
+	defer fct.lock4compact.Unlock()
+
+	fct.lock4segments.RLock()
+	// the last segment is head segment for appending
+	sealed := make([]Segment, 0, len(fct.segments))
+	if len(fct.segments) > 1 {
+		sealed = append(sealed, fct.segments[:len(fct.segments)-1]...)
+	}
+	fct.lock4segments.RUnlock()
+
+	groups, err := fct.groupSegments(sealed)
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if err := fct.compact(group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupSegments groups the adjacent segments whose total data size fits in the size limit,
+// returns the groups which have more than one segment. The data size is measured by the messages
+// encoded by the codec of compacted segment, because the messages are re-encoded when compacting.
+func (fct *factory) groupSegments(segments []Segment) (groups [][]Segment, err error) {
+	var (
+		group []Segment
+		size  = fct.codec.headerSize()
+	)
+	for _, seg := range segments {
+		segSize, err := fct.encodedSize(seg)
+		if err != nil {
+			return nil, err
+		}
+		if size+segSize > fct.dataFileSizeLimit {
+			if len(group) > 1 {
+				groups = append(groups, group)
+			}
+			group = nil
+			size = fct.codec.headerSize()
+		}
+		group = append(group, seg)
+		size += segSize
+	}
+	if len(group) > 1 {
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// encodedSize returns the size of the messages of segment encoded by the codec of compacted segment,
+// the messages are encoded again only if the codec of segment is different.
+func (fct *factory) encodedSize(seg Segment) (int, error) {
+	if seg.Codec() == fct.codec {
+		return seg.Size() - seg.Codec().headerSize(), nil
+	}
+	size := 0
+	for seq := seg.Begin(); seq < seg.End(); seq++ {
+		message, err := seg.Read(seq)
+		if err != nil {
+			return 0, err
+		}
+		size += len(fct.codec.encode(message))
+	}
+	return size, nil
+}
+
+// compact merges the adjacent segments into a new segment, then replaces them.
+// The compacted files are written as temporary files, then a manifest recording the sequence range is
+// written as the commit point of the compaction, so that the compaction is either finished or discarded
+// when loading after crash.
+func (fct *factory) compact(group []Segment) error {
+	begin, end := group[0].Begin(), group[len(group)-1].End()
+	// finish or discard the files left by the broken compaction
+	if err := fct.recoverCompaction(begin); err != nil {
+		return err
+	}
+	indexFilePath, dataFilePath := fct.buildIndexAndDataFilePath(begin)
+	tmpIndexFilePath, tmpDataFilePath := indexFilePath+compactFileSuffix, dataFilePath+compactFileSuffix
+
+	// copy messages into the compacting segment
+	tmpSeg, err := fct.openSegment(tmpIndexFilePath, tmpDataFilePath, begin, begin)
+	if err != nil {
+		return err
+	}
+	for _, seg := range group {
+		for seq := seg.Begin(); seq < seg.End(); seq++ {
+			message, err := seg.Read(seq)
+			if err == nil {
+				_, err = tmpSeg.Append(message)
+			}
+			if err != nil {
+				tmpSeg.Close()
+				return err
+			}
+		}
+	}
+	tmpSeg.Close()
+
+	if err := fct.writeManifest(begin, end); err != nil {
+		return err
+	}
+	if err := fct.commitCompaction(begin, end); err != nil {
+		return err
+	}
+	compacted, err := fct.openSegment(indexFilePath, dataFilePath, begin, end)
+	if err != nil {
+		return err
+	}
+
+	fct.lock4segments.Lock()
+	idx, _, _ := fct.seqRange.Find(begin)
+	fct.segments = append(fct.segments[:idx], append([]Segment{compacted}, fct.segments[idx+len(group):]...)...)
+	fct.seqRange = append(fct.seqRange[:idx], append(SeqRange{begin}, fct.seqRange[idx+len(group):]...)...)
+	fct.lock4segments.Unlock()
+
+	// the mapped pages of replaced segments are released after the fan outs reading them move on
+	for _, seg := range group {
+		seg.Close()
+	}
+
+	fct.logger.Info("compact segments",
+		logger.String("dirPath", fct.dirPath),
+		logger.Int64("begin", begin),
+		logger.Int64("end", end),
+		logger.Int32("segments", int32(len(group))))
+	return nil
+}
+
+// writeManifest writes the manifest of the compaction for segments in sequence range [begin, end).
+func (fct *factory) writeManifest(begin, end int64) error {
+	manifestFilePath := fct.buildFilePath(strconv.FormatInt(begin, 10) + manifestFileSuffix)
+	tmpManifestFilePath := manifestFilePath + compactFileSuffix
+	f, err := os.Create(tmpManifestFilePath)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(strconv.FormatInt(end, 10))
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpManifestFilePath, manifestFilePath)
+}
+
+// commitCompaction replaces the files of the first segment with the compacted files,
+// removes the files of the other segments in sequence range (begin, end), then removes the manifest.
+func (fct *factory) commitCompaction(begin, end int64) error {
+	indexFilePath, dataFilePath := fct.buildIndexAndDataFilePath(begin)
+	for _, filePath := range []string{indexFilePath, dataFilePath} {
+		tmpFilePath := filePath + compactFileSuffix
+		if fileutil.Exist(tmpFilePath) {
+			if err := os.Rename(tmpFilePath, filePath); err != nil {
+				return err
+			}
+		}
+	}
+	fileNames, err := fileutil.ListDir(fct.dirPath)
+	if err != nil {
+		return err
+	}
+	for _, fn := range fileNames {
+		if !strings.HasSuffix(fn, indexFileSuffix) && !strings.HasSuffix(fn, dataFileSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseInt(fn[:strings.Index(fn, ".")], 10, 64)
+		if err != nil {
+			return err
+		}
+		if seq > begin && seq < end {
+			if err := os.Remove(fct.buildFilePath(fn)); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Remove(fct.buildFilePath(strconv.FormatInt(begin, 10) + manifestFileSuffix))
+}
+
+// recoverCompaction finishes the compaction of segment with beginSeq if the manifest exists,
+// otherwise removes the temporary files of the broken compaction.
+func (fct *factory) recoverCompaction(beginSeq int64) error {
+	indexFilePath, dataFilePath := fct.buildIndexAndDataFilePath(beginSeq)
+	manifestFilePath := fct.buildFilePath(strconv.FormatInt(beginSeq, 10) + manifestFileSuffix)
+	if !fileutil.Exist(manifestFilePath) {
+		for _, filePath := range []string{indexFilePath, dataFilePath, manifestFilePath} {
+			tmpFilePath := filePath + compactFileSuffix
+			if fileutil.Exist(tmpFilePath) {
+				if err := os.Remove(tmpFilePath); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	content, err := ioutil.ReadFile(manifestFilePath)
+	if err != nil {
+		return err
+	}
+	end, err := strconv.ParseInt(string(content), 10, 64)
+	if err != nil {
+		return err
+	}
+	fct.logger.Info("recover compaction",
+		logger.String("dirPath", fct.dirPath),
+		logger.Int64("begin", beginSeq),
+		logger.Int64("end", end))
+	return fct.commitCompaction(beginSeq, end)
+}
+
+// recoverCompactions recovers the compactions left in dirPath.
+func (fct *factory) recoverCompactions() error {
+	fileNames, err := fileutil.ListDir(fct.dirPath)
+	if err != nil {
+		return err
+	}
+	recovered := make(map[int64]struct{})
+	for _, fn := range fileNames {
+		if !strings.HasSuffix(fn, compactFileSuffix) && !strings.HasSuffix(fn, manifestFileSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseInt(fn[:strings.Index(fn, ".")], 10, 64)
+		if err != nil {
+			return err
+		}
+		if _, ok := recovered[seq]; ok {
+			continue
+		}
+		recovered[seq] = struct{}{}
+		if err := fct.recoverCompaction(seq); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SegmentsSize returns segments size hold in factory, mainly for test
 func (fct *factory) SegmentsSize() int {
 	fct.lock4segments.RLock()
@@ -327,9 +604,13 @@ func (fct *factory) SegmentsSize() int {
 
 // Close closes the segments.
 func (fct *factory) Close() {
-	fct.lock4segments.RLock()
-	defer fct.lock4segments.RUnlock()
+	fct.lock4compact.Lock()
+	defer fct.lock4compact.Unlock()
+	fct.lock4segments.Lock()
+	defer fct.lock4segments.Unlock()
 	for _, seg := range fct.segments {
 		seg.Close()
 	}
+	fct.segments = nil
+	fct.seqRange = nil
 }
@@ -1,6 +1,7 @@
 package segment
 
 import (
+	"math/rand"
 	"os"
 	"path"
 	"sort"
@@ -76,7 +77,7 @@ func TestEmptyFactory(t *testing.T) {
 
 	}()
 
-	fct, err := NewFactory(tmpDir, 1024, 0, 0)
+	fct, err := NewFactory(tmpDir, 1024, CodecNone, 0, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -123,7 +124,7 @@ func TestFactory(t *testing.T) {
 	writeFile(t, tmpDir, 0, []byte("123"))
 	writeFile(t, tmpDir, 1, []byte("456"), []byte("789"))
 
-	fat, err := NewFactory(tmpDir, 10, 3, 0)
+	fat, err := NewFactory(tmpDir, 10, CodecNone, 3, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -236,7 +237,7 @@ func TestFactory_RemoveSegments(t *testing.T) {
 	//[3, 5)
 	writeFile(t, tmpDir, 3, []byte("456"), []byte("789"))
 
-	fct, err := NewFactory(tmpDir, 10, 5, 0)
+	fct, err := NewFactory(tmpDir, 10, CodecNone, 5, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -273,3 +274,254 @@ func TestFactory_RemoveSegments(t *testing.T) {
 	}
 
 }
+
+func TestFactory_CompactSegments(t *testing.T) {
+	tmpDir := path.Join(os.TempDir(), "segment_factory_compact")
+
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	//[0, 1)
+	writeFile(t, tmpDir, 0, []byte("123"))
+	//[1, 3)
+	writeFile(t, tmpDir, 1, []byte("456"), []byte("789"))
+	//[3, 4)
+	writeFile(t, tmpDir, 3, []byte("abc"))
+	//[4, 5), head segment
+	writeFile(t, tmpDir, 4, []byte("def"))
+	messages := []string{"123", "456", "789", "abc", "def"}
+
+	fct, err := NewFactory(tmpDir, 10, CodecNone, 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMessages := func(fct Factory) {
+		for seq, msg := range messages {
+			seg, err := fct.GetSegment(int64(seq))
+			assert.Nil(t, err)
+			bys, err := seg.Read(int64(seq))
+			assert.Nil(t, err)
+			assert.Equal(t, []byte(msg), bys)
+		}
+	}
+	// the reader holds the segment being compacted
+	retired, err := fct.GetSegment(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, retired.Retain())
+
+	// [0, 1) and [1, 3) are merged, [3, 4) exceeds the size limit, head segment is not compacted
+	assert.Nil(t, fct.CompactSegments())
+	assert.Equal(t, 3, fct.SegmentsSize())
+	files, err := fileutil.ListDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"0.dat", "0.idx", "3.dat", "3.idx", "4.dat", "4.idx"}, files)
+	seg, _ := fct.GetSegment(0)
+	assert.Equal(t, int64(0), seg.Begin())
+	assert.Equal(t, int64(3), seg.End())
+	assertMessages(fct)
+	// retired segment is readable until the reader releases it
+	assert.Nil(t, fct.CompactSegments())
+	assert.Equal(t, 3, fct.SegmentsSize())
+	bys, err := retired.Read(1)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("456"), bys)
+	retired.Release()
+	assert.False(t, retired.Retain())
+	_, err = retired.Read(1)
+	assert.Equal(t, ErrOutOfRange, err)
+
+	// replay after restart
+	fct.Close()
+	fct, err = NewFactory(tmpDir, 10, CodecNone, 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, fct.SegmentsSize())
+	assertMessages(fct)
+	fct.Close()
+}
+
+func TestFactory_RecoverCompaction(t *testing.T) {
+	tmpDir := path.Join(os.TempDir(), "segment_factory_recover_compaction")
+	compactDir := path.Join(tmpDir, "compact")
+
+	if err := os.MkdirAll(compactDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	//[0, 1)
+	writeFile(t, tmpDir, 0, []byte("123"))
+	//[1, 3)
+	writeFile(t, tmpDir, 1, []byte("456"), []byte("789"))
+	//[3, 4), head segment
+	writeFile(t, tmpDir, 3, []byte("abc"))
+	// compacted segment [0, 3)
+	writeFile(t, compactDir, 0, []byte("123"), []byte("456"), []byte("789"))
+
+	// crash after renaming the compacted index file
+	assert.Nil(t, os.Rename(path.Join(compactDir, "0.idx"), path.Join(tmpDir, "0.idx")))
+	assert.Nil(t, os.Rename(path.Join(compactDir, "0.dat"), path.Join(tmpDir, "0.dat.compact")))
+	manifest, err := os.Create(path.Join(tmpDir, "0.manifest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = manifest.WriteString("3")
+	_ = manifest.Close()
+	// broken compaction without manifest
+	writeFile(t, compactDir, 1, []byte("456"))
+	assert.Nil(t, os.Rename(path.Join(compactDir, "1.idx"), path.Join(tmpDir, "1.idx.compact")))
+	assert.Nil(t, os.RemoveAll(compactDir))
+
+	fct, err := NewFactory(tmpDir, 10, CodecNone, 4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fct.Close()
+	files, err := fileutil.ListDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"0.dat", "0.idx", "3.dat", "3.idx"}, files)
+	assert.Equal(t, 2, fct.SegmentsSize())
+	for seq, msg := range []string{"123", "456", "789", "abc"} {
+		seg, err := fct.GetSegment(int64(seq))
+		assert.Nil(t, err)
+		bys, err := seg.Read(int64(seq))
+		assert.Nil(t, err)
+		assert.Equal(t, []byte(msg), bys)
+	}
+}
+
+func TestFactory_Codec(t *testing.T) {
+	tmpDir := path.Join(os.TempDir(), "segment_factory_codec")
+
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// uncompressed segments written by old version, [0, 1) and [1, 3)
+	writeFile(t, tmpDir, 0, []byte("123"))
+	writeFile(t, tmpDir, 1, []byte("456"), []byte("789"))
+
+	fct, err := NewFactory(tmpDir, 1024, CodecSnappy, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seg, err := fct.NewSegment(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, CodecSnappy, seg.Codec())
+	_, err = seg.Append([]byte("abc"))
+	assert.Nil(t, err)
+	messages := []string{"123", "456", "789", "abc"}
+	assertMessages := func(fct Factory) {
+		for seq, msg := range messages {
+			seg, err := fct.GetSegment(int64(seq))
+			assert.Nil(t, err)
+			bys, err := seg.Read(int64(seq))
+			assert.Nil(t, err)
+			assert.Equal(t, []byte(msg), bys)
+		}
+	}
+	assertMessages(fct)
+
+	// uncompressed segments are compacted into compressed segment
+	assert.Nil(t, fct.CompactSegments())
+	assert.Equal(t, 2, fct.SegmentsSize())
+	seg, _ = fct.GetSegment(0)
+	assert.Equal(t, CodecSnappy, seg.Codec())
+	assertMessages(fct)
+
+	// replay after restart without compression, codec of segments are restored from data file
+	fct.Close()
+	fct, err = NewFactory(tmpDir, 1024, CodecNone, 4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(fct)
+	seg, _ = fct.GetSegment(3)
+	assert.Equal(t, CodecSnappy, seg.Codec())
+	fct.Close()
+}
+
+func TestFactory_CompactSegments_incompressible(t *testing.T) {
+	tmpDir := path.Join(os.TempDir(), "segment_factory_compact_incompressible")
+
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// uncompressed segments of random messages, which are expanded by snappy
+	rnd := rand.New(rand.NewSource(1))
+	var messages [][]byte
+	for seq := 0; seq < 3; seq++ {
+		msg := make([]byte, 100)
+		_, _ = rnd.Read(msg)
+		messages = append(messages, msg)
+		writeFile(t, tmpDir, seq, msg)
+	}
+	encodedLen := len(CodecSnappy.encode(messages[0]))
+	assert.True(t, encodedLen > len(messages[0]))
+	assertMessages := func(fct Factory) {
+		for seq, msg := range messages {
+			seg, err := fct.GetSegment(int64(seq))
+			assert.Nil(t, err)
+			bys, err := seg.Read(int64(seq))
+			assert.Nil(t, err)
+			assert.Equal(t, msg, bys)
+		}
+	}
+
+	// [0, 1) and [1, 2) fit in the size limit before compression, but exceed it after compression
+	fct, err := NewFactory(tmpDir, 200, CodecSnappy, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, fct.CompactSegments())
+	assert.Equal(t, 3, fct.SegmentsSize())
+	assertMessages(fct)
+	fct.Close()
+
+	// the size limit is enough for the compressed messages with header
+	fct, err = NewFactory(tmpDir, segmentHeaderSize+2*encodedLen, CodecSnappy, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, fct.CompactSegments())
+	assert.Equal(t, 2, fct.SegmentsSize())
+	seg, _ := fct.GetSegment(0)
+	assert.Equal(t, CodecSnappy, seg.Codec())
+	assert.Equal(t, segmentHeaderSize+2*encodedLen, seg.Size())
+	assertMessages(fct)
+	fct.Close()
+}
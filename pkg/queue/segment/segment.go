@@ -36,7 +36,16 @@ type Segment interface {
 	// Append appends the message at the end of sequence,
 	// if success returns the sequence to retrieve the message, otherwise returns the error.
 	Append(message []byte) (int64, error)
-	// Close releases the underlying resources.
+	// Size returns the size in bytes of the messages in data file.
+	Size() int
+	// Codec returns the compression codec of the messages.
+	Codec() Codec
+	// Retain increases the reference count for reading, returns false if the segment has been released.
+	Retain() bool
+	// Release decreases the reference count, unmaps the files after the last reference is released.
+	Release()
+	// Close releases the reference held by the owner, the underlying resources are released
+	// after all the readers release the segment.
 	Close()
 }
 
@@ -52,21 +61,30 @@ type segment struct {
 	end int64
 	// current dataOffset for data page
 	dataOffset int
+	// compression codec of the messages
+	codec Codec
 	// writer for index bytes
 	indexWriter *stream.SliceWriter
 	// writer for data bytes
 	dataWriter *stream.SliceWriter
-	logger     *logger.Logger
+	// reference count, the owner holds one reference, the files are unmapped when it drops to zero
+	refs int32
+	// 0 -> running, 1 -> closed by the owner
+	closed int32
+	logger *logger.Logger
 }
 
 // NewSegment returns a Segment with provided index, data mmap page.
 // Sequence range[begin, end) is used to reconstruct the state when loading from file.
-func NewSegment(indexPage, dataPage page.MappedPage, begin, end int64) (Segment, error) {
+// The codec is used for the new segment, the segment loaded from file uses the codec in the header of data file.
+func NewSegment(indexPage, dataPage page.MappedPage, begin, end int64, codec Codec) (Segment, error) {
 	seg := &segment{
 		indexPage: indexPage,
 		dataPage:  dataPage,
 		begin:     begin,
 		end:       end,
+		codec:     codec,
+		refs:      1,
 		logger:    logger.GetLogger("pkg/queue", "Segment"),
 	}
 
@@ -80,13 +98,14 @@ func NewSegment(indexPage, dataPage page.MappedPage, begin, end int64) (Segment,
 // Append appends the message at the end of sequence,
 // if success returns the sequence to retrieve the message, otherwise returns the error.
 func (seg *segment) Append(message []byte) (int64, error) {
-	dataLen := len(message)
+	data := seg.codec.encode(message)
+	dataLen := len(data)
 	if seg.dataOffset+dataLen > seg.dataPage.Size() {
 		return 0, ErrExceedPageSize
 	}
 
 	// append message, preCheck ensures dataPage has enough space
-	seg.dataWriter.PutBytes(message)
+	seg.dataWriter.PutBytes(data)
 
 	// append index
 	seg.indexWriter.PutInt32(int32(seg.dataOffset))
@@ -100,6 +119,16 @@ func (seg *segment) Append(message []byte) (int64, error) {
 	return seq, nil
 }
 
+// Size returns the size in bytes of the messages in data file.
+func (seg *segment) Size() int {
+	return seg.dataOffset
+}
+
+// Codec returns the compression codec of the messages.
+func (seg *segment) Codec() Codec {
+	return seg.codec
+}
+
 // adjustOffset adjusts dataOffset by sequence range.
 func (seg *segment) adjustOffset() error {
 	// new segment
@@ -107,8 +136,23 @@ func (seg *segment) adjustOffset() error {
 		seg.dataOffset = 0
 		seg.indexWriter = stream.NewSliceWriter(seg.indexPage.Buffer(0))
 		seg.dataWriter = stream.NewSliceWriter(seg.dataPage.Buffer(0))
+		// compressed segment writes the codec in header, uncompressed segment keeps the old format without header
+		if seg.codec != CodecNone {
+			seg.dataWriter.PutByte(byte(seg.codec))
+			seg.dataOffset = segmentHeaderSize
+		}
 		return nil
 	}
+	// restore codec from file, the first message of compressed segment follows the header,
+	// otherwise the first message starts at 0 and the segment is uncompressed.
+	firstOffset, _, err := seg.calDataOffsetAndLen(seg.begin)
+	if err != nil {
+		return err
+	}
+	seg.codec = CodecNone
+	if firstOffset == segmentHeaderSize {
+		seg.codec = Codec(seg.dataPage.Data(0, segmentHeaderSize)[0])
+	}
 	// restore segment from file
 	dataOffset, dataLen, err := seg.calDataOffsetAndLen(seg.end - 1)
 	if err != nil {
@@ -133,11 +177,17 @@ func (seg *segment) End() int64 {
 
 // Reads returns the message with sequence seq, if seq is not in sequence range, errorOutOfRange returns.
 func (seg *segment) Read(seq int64) ([]byte, error) {
+	// keeps the pages mapped while reading
+	if !seg.Retain() {
+		return nil, ErrOutOfRange
+	}
+	defer seg.Release()
+
 	dataOffset, dataLen, err := seg.calDataOffsetAndLen(seq)
 	if err != nil {
 		return nil, err
 	}
-	return seg.dataPage.Data(dataOffset, dataLen), nil
+	return seg.codec.decode(seg.dataPage.Data(dataOffset, dataLen))
 }
 
 // calDataOffsetAndLen returns the offset and length for message with sequence seq in data file.
@@ -156,8 +206,36 @@ func (seg *segment) Contains(seq int64) bool {
 	return seg.Begin() <= seq && seq < seg.End()
 }
 
-// Close releases the underlying resources.
+// Retain increases the reference count for reading, returns false if the segment has been released.
+func (seg *segment) Retain() bool {
+	for {
+		refs := atomic.LoadInt32(&seg.refs)
+		if refs <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&seg.refs, refs, refs+1) {
+			return true
+		}
+	}
+}
+
+// Release decreases the reference count, unmaps the files after the last reference is released.
+func (seg *segment) Release() {
+	if atomic.AddInt32(&seg.refs, -1) == 0 {
+		seg.unmap()
+	}
+}
+
+// Close releases the reference held by the owner, the underlying resources are released
+// after all the readers release the segment.
 func (seg *segment) Close() {
+	if atomic.CompareAndSwapInt32(&seg.closed, 0, 1) {
+		seg.Release()
+	}
+}
+
+// unmap releases the underlying resources.
+func (seg *segment) unmap() {
 	err := seg.indexPage.Close()
 	if err != nil {
 		seg.logger.Error("error close mmap file", zap.Error(err))
@@ -61,7 +61,7 @@ func TestRead(t *testing.T) {
 		[]byte("LinDB"),
 	}
 
-	seg, err := NewSegment(buildIndexPage(t, data...), buildDataPage(t, data...), int64(0), int64(len(data)))
+	seg, err := NewSegment(buildIndexPage(t, data...), buildDataPage(t, data...), int64(0), int64(len(data)), CodecNone)
 
 	if err != nil {
 		t.Error(err)
@@ -97,7 +97,7 @@ func TestWrite(t *testing.T) {
 	indexPage := page.NewMappedPage("0.idx", indexBytes[:], closeFunc, syncFunc)
 	dataPage := page.NewMappedPage("0.dat", dataBytes[:], closeFunc, syncFunc)
 
-	seg, err := NewSegment(indexPage, dataPage, int64(0), int64(0))
+	seg, err := NewSegment(indexPage, dataPage, int64(0), int64(0), CodecNone)
 
 	if err != nil {
 		t.Fatal(err)
@@ -172,7 +172,7 @@ func TestSegment_Append(t *testing.T) {
 	}
 
 	seg, err := NewSegment(page.NewMappedPage("0.idx", indexBytes, closeFunc, syncFunc),
-		page.NewMappedPage("0.dat", dataBytes, closeFunc, syncFunc), 0, 1)
+		page.NewMappedPage("0.dat", dataBytes, closeFunc, syncFunc), 0, 1, CodecNone)
 
 	if err != nil {
 		t.Fatal(err)
@@ -199,3 +199,59 @@ func TestSegment_Append(t *testing.T) {
 	assert.Equal(t, msgr1, msg1)
 
 }
+
+func TestSegment_Codec(t *testing.T) {
+	messages := [][]byte{
+		[]byte("cpu,host=host-1 idle=10,user=20,system=30"),
+		[]byte("cpu,host=host-1 idle=10,user=20,system=30 cpu,host=host-1 idle=10,user=20,system=30"),
+		{},
+		[]byte("1"),
+	}
+	for _, codec := range []Codec{CodecNone, CodecSnappy} {
+		indexBytes := make([]byte, 1024)
+		dataBytes := make([]byte, 1024)
+		indexPage := page.NewMappedPage("0.idx", indexBytes, closeFunc, syncFunc)
+		dataPage := page.NewMappedPage("0.dat", dataBytes, closeFunc, syncFunc)
+
+		seg, err := NewSegment(indexPage, dataPage, 0, 0, codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, codec, seg.Codec())
+		for i, msg := range messages {
+			seq, err := seg.Append(msg)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(i), seq)
+		}
+		assertMessages := func(seg Segment) {
+			for i, msg := range messages {
+				bys, err := seg.Read(int64(i))
+				assert.NoError(t, err)
+				assert.Equal(t, string(msg), string(bys))
+			}
+		}
+		assertMessages(seg)
+
+		// replay the segment with other codec, the codec of segment is restored from data file
+		seg, err = NewSegment(indexPage, dataPage, 0, int64(len(messages)), CodecSnappy-codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, codec, seg.Codec())
+		assertMessages(seg)
+		_, err = seg.Append([]byte("append after replay"))
+		assert.NoError(t, err)
+		bys, err := seg.Read(int64(len(messages)))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("append after replay"), bys)
+	}
+
+	// corrupted message
+	indexPage := page.NewMappedPage("0.idx", make([]byte, 1024), closeFunc, syncFunc)
+	dataPage := page.NewMappedPage("0.dat", make([]byte, 1024), closeFunc, syncFunc)
+	seg, _ := NewSegment(indexPage, dataPage, 0, 0, CodecSnappy)
+	_, _ = seg.Append([]byte("123"))
+	copy(dataPage.Data(segmentHeaderSize, 1), []byte{0xff})
+	_, err := seg.Read(0)
+	assert.Error(t, err)
+}
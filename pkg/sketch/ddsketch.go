@@ -0,0 +1,230 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/lindb/lindb/pkg/stream"
+)
+
+// DefaultRelativeAccuracy is the default relative accuracy of the quantiles returned by sketch
+const DefaultRelativeAccuracy = 0.01
+
+// DDSketch represents a mergeable quantile sketch with relative accuracy guarantee,
+// values are mapped into logarithmic buckets, so the sketches can be merged by adding the counts of buckets.
+// Quantile returns the value x whose relative error is less than alpha, |x'-x| <= alpha*|x|.
+// see paper: DDSketch: A Fast and Fully-Mergeable Quantile Sketch with Relative-Error Guarantees.
+type DDSketch struct {
+	alpha    float64
+	gamma    float64
+	logGamma float64
+
+	positive  map[int32]uint64 // buckets of positive values, key: bucket index
+	negative  map[int32]uint64 // buckets of the absolute value of negative values
+	zeroCount uint64
+	count     uint64
+	min, max  float64
+}
+
+// NewDDSketch creates a sketch with the relative accuracy(0 < alpha < 1)
+func NewDDSketch(alpha float64) *DDSketch {
+	gamma := (1 + alpha) / (1 - alpha)
+	return &DDSketch{
+		alpha:    alpha,
+		gamma:    gamma,
+		logGamma: math.Log(gamma),
+		positive: make(map[int32]uint64),
+		negative: make(map[int32]uint64),
+		min:      math.Inf(1),
+		max:      math.Inf(-1),
+	}
+}
+
+// RelativeAccuracy returns the relative accuracy of sketch
+func (s *DDSketch) RelativeAccuracy() float64 {
+	return s.alpha
+}
+
+// Count returns the count of values added into sketch
+func (s *DDSketch) Count() uint64 {
+	return s.count
+}
+
+// Min returns the min value of sketch, returns +Inf if sketch is empty
+func (s *DDSketch) Min() float64 {
+	return s.min
+}
+
+// Max returns the max value of sketch, returns -Inf if sketch is empty
+func (s *DDSketch) Max() float64 {
+	return s.max
+}
+
+// BucketCount returns the count of buckets which hold values
+func (s *DDSketch) BucketCount() int {
+	return len(s.positive) + len(s.negative)
+}
+
+// Add adds the value into sketch
+func (s *DDSketch) Add(value float64) {
+	switch {
+	case value > 0:
+		s.positive[s.index(value)]++
+	case value < 0:
+		s.negative[s.index(-value)]++
+	default:
+		s.zeroCount++
+	}
+	s.count++
+	if value < s.min {
+		s.min = value
+	}
+	if value > s.max {
+		s.max = value
+	}
+}
+
+// Merge merges the other sketch into current sketch, the sketches must be with same relative accuracy
+func (s *DDSketch) Merge(other *DDSketch) error {
+	if other == nil || other.count == 0 {
+		return nil
+	}
+	if s.alpha != other.alpha {
+		return fmt.Errorf("cannot merge sketches with different relative accuracy, %f != %f", s.alpha, other.alpha)
+	}
+	for idx, count := range other.positive {
+		s.positive[idx] += count
+	}
+	for idx, count := range other.negative {
+		s.negative[idx] += count
+	}
+	s.zeroCount += other.zeroCount
+	s.count += other.count
+	if other.min < s.min {
+		s.min = other.min
+	}
+	if other.max > s.max {
+		s.max = other.max
+	}
+	return nil
+}
+
+// Quantile returns the estimated value of quantile q(0 <= q <= 1), returns false if sketch is empty or q is invalid
+func (s *DDSketch) Quantile(q float64) (float64, bool) {
+	if s.count == 0 || q < 0 || q > 1 {
+		return 0, false
+	}
+	switch q {
+	case 0:
+		return s.min, true
+	case 1:
+		return s.max, true
+	}
+	rank := uint64(q * float64(s.count-1))
+	var value float64
+	var cumulative uint64
+	found := false
+	// negative values, from the largest absolute value
+	negativeIndexes := sortedIndexes(s.negative)
+	for i := len(negativeIndexes) - 1; i >= 0; i-- {
+		idx := negativeIndexes[i]
+		cumulative += s.negative[idx]
+		if cumulative > rank {
+			value = -s.value(idx)
+			found = true
+			break
+		}
+	}
+	if !found {
+		cumulative += s.zeroCount
+		if cumulative > rank {
+			return 0, true
+		}
+		for _, idx := range sortedIndexes(s.positive) {
+			cumulative += s.positive[idx]
+			if cumulative > rank {
+				value = s.value(idx)
+				break
+			}
+		}
+	}
+	// the estimated value never exceeds the range of added values
+	return math.Max(s.min, math.Min(s.max, value)), true
+}
+
+// MarshalBinary encodes the sketch into binary
+func (s *DDSketch) MarshalBinary() ([]byte, error) {
+	writer := stream.NewBufferWriter(nil)
+	writer.PutUint64(math.Float64bits(s.alpha))
+	writer.PutUvarint64(s.count)
+	writer.PutUvarint64(s.zeroCount)
+	writer.PutUint64(math.Float64bits(s.min))
+	writer.PutUint64(math.Float64bits(s.max))
+	writeBuckets(writer, s.positive)
+	writeBuckets(writer, s.negative)
+	return writer.Bytes()
+}
+
+// UnmarshalBinary decodes the sketch from binary
+func (s *DDSketch) UnmarshalBinary(data []byte) error {
+	reader := stream.NewReader(data)
+	alpha := math.Float64frombits(reader.ReadUint64())
+	if reader.Error() != nil || alpha <= 0 || alpha >= 1 {
+		return fmt.Errorf("invalid relative accuracy of sketch")
+	}
+	*s = *NewDDSketch(alpha)
+	s.count = reader.ReadUvarint64()
+	s.zeroCount = reader.ReadUvarint64()
+	s.min = math.Float64frombits(reader.ReadUint64())
+	s.max = math.Float64frombits(reader.ReadUint64())
+	readBuckets(reader, s.positive)
+	readBuckets(reader, s.negative)
+	if reader.Error() != nil {
+		return reader.Error()
+	}
+	if !reader.Empty() {
+		return fmt.Errorf("unexpected trailing data of sketch")
+	}
+	return nil
+}
+
+// index returns the bucket index of the positive value
+func (s *DDSketch) index(value float64) int32 {
+	return int32(math.Ceil(math.Log(value) / s.logGamma))
+}
+
+// value returns the representative value of the bucket, which is the middle(relative) of the bucket's range
+func (s *DDSketch) value(idx int32) float64 {
+	return 2 * math.Pow(s.gamma, float64(idx)) / (s.gamma + 1)
+}
+
+// writeBuckets writes the count of buckets, then the index/count of each bucket
+func writeBuckets(writer *stream.BufferWriter, buckets map[int32]uint64) {
+	writer.PutUvarint32(uint32(len(buckets)))
+	for _, idx := range sortedIndexes(buckets) {
+		writer.PutVarint32(idx)
+		writer.PutUvarint64(buckets[idx])
+	}
+}
+
+// readBuckets reads the buckets written by writeBuckets
+func readBuckets(reader *stream.Reader, buckets map[int32]uint64) {
+	count := int(reader.ReadUvarint32())
+	for i := 0; i < count && reader.Error() == nil; i++ {
+		idx := reader.ReadVarint32()
+		buckets[idx] = reader.ReadUvarint64()
+	}
+}
+
+// sortedIndexes returns the indexes of buckets in ascending order
+func sortedIndexes(buckets map[int32]uint64) []int32 {
+	indexes := make([]int32, 0, len(buckets))
+	for idx := range buckets {
+		indexes = append(indexes, idx)
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i] < indexes[j]
+	})
+	return indexes
+}
@@ -0,0 +1,99 @@
+package sketch
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func assertQuantile(t *testing.T, s *DDSketch, values []float64, q float64) {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	expect := sorted[int(q*float64(len(sorted)-1))]
+	value, ok := s.Quantile(q)
+	assert.True(t, ok)
+	assert.True(t, math.Abs(value-expect) <= s.RelativeAccuracy()*math.Abs(expect),
+		"q:%f, expect:%f, actual:%f", q, expect, value)
+}
+
+func TestDDSketch_Quantile(t *testing.T) {
+	s := NewDDSketch(DefaultRelativeAccuracy)
+	_, ok := s.Quantile(0.5)
+	assert.False(t, ok)
+
+	var values []float64
+	for i := 1; i <= 1000; i++ {
+		values = append(values, float64(i))
+		s.Add(float64(i))
+	}
+	assert.Equal(t, uint64(1000), s.Count())
+	for _, q := range []float64{0, 0.5, 0.95, 0.99, 1} {
+		assertQuantile(t, s, values, q)
+	}
+	_, ok = s.Quantile(1.1)
+	assert.False(t, ok)
+	assert.Equal(t, 1.0, s.Min())
+	assert.Equal(t, 1000.0, s.Max())
+
+	// negative/zero values
+	s = NewDDSketch(DefaultRelativeAccuracy)
+	values = nil
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		v := r.NormFloat64() * 100
+		if i%100 == 0 {
+			v = 0
+		}
+		values = append(values, v)
+		s.Add(v)
+	}
+	for _, q := range []float64{0.01, 0.25, 0.5, 0.95} {
+		assertQuantile(t, s, values, q)
+	}
+	value, ok := s.Quantile(0.5)
+	assert.True(t, ok)
+	assert.True(t, math.Abs(value) < 5)
+}
+
+func TestDDSketch_Merge(t *testing.T) {
+	s1 := NewDDSketch(DefaultRelativeAccuracy)
+	s2 := NewDDSketch(DefaultRelativeAccuracy)
+	var values []float64
+	for i := 1; i <= 2000; i++ {
+		values = append(values, float64(i))
+		if i%2 == 0 {
+			s1.Add(float64(i))
+		} else {
+			s2.Add(float64(i))
+		}
+	}
+	assert.NoError(t, s1.Merge(s2))
+	assert.NoError(t, s1.Merge(nil))
+	assert.Equal(t, uint64(2000), s1.Count())
+	assertQuantile(t, s1, values, 0.5)
+	assertQuantile(t, s1, values, 0.95)
+
+	s3 := NewDDSketch(0.05)
+	s3.Add(1)
+	assert.Error(t, s1.Merge(s3))
+}
+
+func TestDDSketch_MarshalBinary(t *testing.T) {
+	s := NewDDSketch(DefaultRelativeAccuracy)
+	for i := -100; i <= 100; i++ {
+		s.Add(float64(i) * 1.5)
+	}
+	data, err := s.MarshalBinary()
+	assert.NoError(t, err)
+
+	s2 := &DDSketch{}
+	assert.NoError(t, s2.UnmarshalBinary(data))
+	assert.Equal(t, s, s2)
+
+	assert.Error(t, s2.UnmarshalBinary(nil))
+	assert.Error(t, s2.UnmarshalBinary(data[:len(data)-1]))
+	assert.Error(t, s2.UnmarshalBinary(append(data, 1)))
+}
@@ -0,0 +1,137 @@
+package timeutil
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// Clock represents the source of current time,
+// which can be replaced by FakeClock for testing the timing behavior deterministically.
+type Clock interface {
+	// Now returns the current timestamp in millisecond
+	Now() int64
+	// NewTicker returns a ticker delivering ticks of this clock with the period of duration
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker represents the ticks of clock, the ticks are dropped if the receiver is slow like time.Ticker.
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered
+	C() <-chan time.Time
+	// Stop turns off the ticker, no more ticks will be sent
+	Stop()
+}
+
+// SystemClock is the clock of system wall time
+var SystemClock Clock = systemClock{}
+
+// systemClock implements Clock with system wall time
+type systemClock struct{}
+
+// Now returns the current timestamp in millisecond
+func (systemClock) Now() int64 {
+	return Now()
+}
+
+// NewTicker returns a ticker backed by time.Ticker
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return &systemTicker{ticker: time.NewTicker(d)}
+}
+
+// systemTicker implements Ticker with time.Ticker
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+// C returns the channel of time.Ticker
+func (t *systemTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+// Stop stops the time.Ticker
+func (t *systemTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// FakeClock implements Clock, the time is changed only by Set/Advance,
+// the tickers created by the clock tick when the time is moved past their next tick.
+type FakeClock struct {
+	now atomic.Int64
+
+	lock    sync.Mutex
+	tickers map[*fakeTicker]struct{}
+}
+
+// NewFakeClock returns a fake clock starting from the timestamp in millisecond
+func NewFakeClock(now int64) *FakeClock {
+	return &FakeClock{now: *atomic.NewInt64(now), tickers: make(map[*fakeTicker]struct{})}
+}
+
+// Now returns the current timestamp in millisecond
+func (c *FakeClock) Now() int64 {
+	return c.now.Load()
+}
+
+// NewTicker returns a ticker which ticks by Set/Advance of the clock,
+// the period is at least one millisecond.
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	period := d.Nanoseconds() / int64(time.Millisecond)
+	if period <= 0 {
+		period = 1
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	t := &fakeTicker{clock: c, c: make(chan time.Time, 1), period: period, next: c.Now() + period}
+	c.tickers[t] = struct{}{}
+	return t
+}
+
+// Set sets the current timestamp in millisecond
+func (c *FakeClock) Set(now int64) {
+	c.now.Store(now)
+	c.tick(now)
+}
+
+// Advance moves the current time forward by the duration
+func (c *FakeClock) Advance(d time.Duration) {
+	c.tick(c.now.Add(d.Nanoseconds() / int64(time.Millisecond)))
+}
+
+// tick sends a tick to the tickers whose next tick is reached
+func (c *FakeClock) tick(now int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for t := range c.tickers {
+		if now < t.next {
+			continue
+		}
+		select {
+		case t.c <- time.Unix(0, now*int64(time.Millisecond)):
+		default:
+		}
+		// skips the missed ticks like time.Ticker
+		t.next += ((now-t.next)/t.period + 1) * t.period
+	}
+}
+
+// fakeTicker implements Ticker, ticked by FakeClock
+type fakeTicker struct {
+	clock  *FakeClock
+	c      chan time.Time
+	period int64 // in millisecond
+	next   int64 // timestamp of next tick in millisecond
+}
+
+// C returns the channel on which the ticks are delivered
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop removes the ticker from clock
+func (t *fakeTicker) Stop() {
+	t.clock.lock.Lock()
+	delete(t.clock.tickers, t)
+	t.clock.lock.Unlock()
+}
@@ -0,0 +1,65 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemClock(t *testing.T) {
+	now := Now()
+	assert.True(t, SystemClock.Now() >= now)
+}
+
+func TestFakeClock(t *testing.T) {
+	clock := NewFakeClock(1000)
+	assert.Equal(t, int64(1000), clock.Now())
+	clock.Advance(time.Minute)
+	assert.Equal(t, 1000+OneMinute, clock.Now())
+	clock.Set(10)
+	assert.Equal(t, int64(10), clock.Now())
+}
+
+func TestSystemClock_NewTicker(t *testing.T) {
+	ticker := SystemClock.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	select {
+	case <-ticker.C():
+	case <-time.After(time.Second):
+		t.Fatal("ticker should tick")
+	}
+}
+
+func TestFakeClock_NewTicker(t *testing.T) {
+	clock := NewFakeClock(1000)
+	ticker := clock.NewTicker(time.Second)
+	assertNoTick := func() {
+		select {
+		case <-ticker.C():
+			t.Fatal("ticker should not tick")
+		default:
+		}
+	}
+	clock.Advance(999 * time.Millisecond)
+	assertNoTick()
+	clock.Advance(time.Millisecond)
+	assert.Equal(t, time.Unix(2, 0), <-ticker.C())
+	// missed ticks are dropped
+	clock.Advance(3500 * time.Millisecond)
+	assert.Equal(t, time.Unix(5, 500*int64(time.Millisecond)), <-ticker.C())
+	assertNoTick()
+	clock.Set(5999)
+	assertNoTick()
+	clock.Set(6000)
+	assert.Equal(t, time.Unix(6, 0), <-ticker.C())
+
+	ticker.Stop()
+	clock.Advance(time.Minute)
+	assertNoTick()
+
+	// period is at least one millisecond
+	ticker = clock.NewTicker(time.Microsecond)
+	clock.Advance(time.Millisecond)
+	<-ticker.C()
+}
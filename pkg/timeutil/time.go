@@ -66,17 +66,31 @@ func Truncate(timestamp, interval int64) int64 {
 	return timestamp / interval * interval
 }
 
-// CalPointCount calculates point counts between start time and end time by interval
-func CalPointCount(startTime, endTime, interval int64) int {
-	diff := endTime - startTime
-	pointCount := diff / interval
-	if diff%interval > 0 {
-		pointCount++
+// epochWeekStartOffset is the offset of the first Monday after epoch(Thursday, January 1, 1970)
+const epochWeekStartOffset = 4 * OneDay
+
+// TruncateInLocation truncates timestamp based on interval in the location,
+// so that the daily buckets align to local midnight and the weekly buckets align to local Monday midnight.
+// The zone offset at the timestamp is used.
+func TruncateInLocation(timestamp, interval int64, location *time.Location) int64 {
+	if location == nil {
+		location = time.UTC
 	}
-	if pointCount == 0 {
-		pointCount = 1
+	_, offset := time.Unix(timestamp/1000, 0).In(location).Zone()
+	shift := int64(offset) * OneSecond
+	if interval%OneWeek == 0 {
+		shift -= epochWeekStartOffset
+	}
+	return Truncate(timestamp+shift, interval) - shift
+}
+
+// CalPointCount calculates point counts between start time and end time by interval,
+// both start time and end time are inclusive, so the point at end time is counted if aligned with interval.
+func CalPointCount(startTime, endTime, interval int64) int {
+	if endTime <= startTime {
+		return 1
 	}
-	return int(pointCount)
+	return int((endTime-startTime)/interval) + 1
 }
 
 // CalIntervalRatio calculates the interval ratio for query,
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -25,7 +26,12 @@ func TestCalPointCount(t *testing.T) {
 	time, _ := ParseTimestamp(date)
 	assert.Equal(t, 1, CalPointCount(time, time, 10*OneSecond))
 	assert.Equal(t, 10, CalPointCount(time, time+47*OneSecond, 5*OneSecond))
-	assert.Equal(t, 100, CalPointCount(time, time+1000*OneSecond, 10*OneSecond))
+	// end time is inclusive
+	assert.Equal(t, 101, CalPointCount(time, time+1000*OneSecond, 10*OneSecond))
+	assert.Equal(t, 2, CalPointCount(time, time+10*OneSecond, 10*OneSecond))
+	assert.Equal(t, 1, CalPointCount(time, time+9*OneSecond, 10*OneSecond))
+	assert.Equal(t, 2, CalPointCount(time, time+11*OneSecond, 10*OneSecond))
+	assert.Equal(t, 1, CalPointCount(time, time-OneSecond, 10*OneSecond))
 }
 
 func TestCalIntervalRatio(t *testing.T) {
@@ -49,3 +55,25 @@ func TestTruncate(t *testing.T) {
 	t1, _ = ParseTimestamp("20190702 19:10:00", "20060102 15:04:05")
 	assert.Equal(t, t1, Truncate(now, 10*OneMinute))
 }
+
+func TestTruncateInLocation(t *testing.T) {
+	shanghai := time.FixedZone("UTC+8", 8*3600)
+	// 2019-07-02(Tuesday) 03:10:48 in UTC+8, 2019-07-01 19:10:48 in UTC
+	now := time.Date(2019, 7, 2, 3, 10, 48, 0, shanghai).UnixNano() / 1e6
+
+	// daily buckets align to local midnight
+	assert.Equal(t, time.Date(2019, 7, 2, 0, 0, 0, 0, shanghai).UnixNano()/1e6,
+		TruncateInLocation(now, OneDay, shanghai))
+	assert.Equal(t, time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC).UnixNano()/1e6,
+		TruncateInLocation(now, OneDay, time.UTC))
+	assert.Equal(t, TruncateInLocation(now, OneDay, time.UTC), TruncateInLocation(now, OneDay, nil))
+	assert.Equal(t, -8*OneHour, TruncateInLocation(now, OneDay, shanghai)-
+		TruncateInLocation(now+OneDay, OneDay, time.UTC))
+	// weekly buckets align to local monday midnight
+	assert.Equal(t, time.Date(2019, 7, 1, 0, 0, 0, 0, shanghai).UnixNano()/1e6,
+		TruncateInLocation(now, OneWeek, shanghai))
+	assert.Equal(t, time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC).UnixNano()/1e6,
+		TruncateInLocation(now, OneWeek, time.UTC))
+	// sub-hour intervals are not affected by whole-hour offsets
+	assert.Equal(t, Truncate(now, 10*OneMinute), TruncateInLocation(now, 10*OneMinute, shanghai))
+}
@@ -11,9 +11,12 @@ import (
 
 // brokerExecutor implements parallel.BrokerExecutor
 type brokerExecutor struct {
-	database string
-	sql      string
-	query    *stmt.Query
+	database  string
+	sql       string
+	options   stmt.QueryOptions
+	query     *stmt.Query
+	maxMemory int64  // max memory(bytes) of result series, 0 means unlimited
+	spillDir  string // dir for spilling result series, empty means spill disabled
 
 	replicaStateMachine replica.StatusStateMachine
 	nodeStateMachine    broker.NodeStateMachine
@@ -26,11 +29,15 @@ type brokerExecutor struct {
 }
 
 // newBrokerExecutor creates the execution which executes the job of parallel query
-func newBrokerExecutor(ctx context.Context, database string, sql string,
+func newBrokerExecutor(ctx context.Context, database string, sql string, options stmt.QueryOptions,
+	maxMemory int64, spillDir string,
 	replicaStateMachine replica.StatusStateMachine, nodeStateMachine broker.NodeStateMachine,
 	jobManager parallel.JobManager) parallel.BrokerExecutor {
 	exec := &brokerExecutor{
 		sql:                 sql,
+		options:             options,
+		maxMemory:           maxMemory,
+		spillDir:            spillDir,
 		database:            database,
 		replicaStateMachine: replicaStateMachine,
 		nodeStateMachine:    nodeStateMachine,
@@ -48,7 +55,7 @@ func (e *brokerExecutor) Execute() {
 	//FIXME need using storage's replica state ???
 	storageNodes := e.replicaStateMachine.GetQueryableReplicas(e.database)
 	brokerNodes := e.nodeStateMachine.GetActiveNodes()
-	plan := newBrokerPlan(e.sql, storageNodes, e.nodeStateMachine.GetCurrentNode(), brokerNodes)
+	plan := newBrokerPlan(e.sql, e.options, storageNodes, e.nodeStateMachine.GetCurrentNode(), brokerNodes)
 
 	var err error
 	if len(storageNodes) == 0 {
@@ -58,7 +65,7 @@ func (e *brokerExecutor) Execute() {
 	}
 
 	brokerPlan := plan.(*brokerPlan)
-	e.executeCtx = parallel.NewBrokerExecuteContext(brokerPlan.query)
+	e.executeCtx = parallel.NewBrokerExecuteContext(e.ctx, brokerPlan.query, e.maxMemory, e.spillDir)
 
 	if err != nil {
 		e.executeCtx.Complete(err)
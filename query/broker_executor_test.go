@@ -12,6 +12,7 @@ import (
 	"github.com/lindb/lindb/coordinator/replica"
 	"github.com/lindb/lindb/models"
 	"github.com/lindb/lindb/parallel"
+	"github.com/lindb/lindb/sql/stmt"
 )
 
 func TestBrokerExecutor_Execute(t *testing.T) {
@@ -25,7 +26,7 @@ func TestBrokerExecutor_Execute(t *testing.T) {
 	replicaStateMachine := replica.NewMockStatusStateMachine(ctrl)
 	jobManager := parallel.NewMockJobManager(ctrl)
 
-	exec := newBrokerExecutor(context.TODO(), "test_db", "select f from cpu",
+	exec := newBrokerExecutor(context.TODO(), "test_db", "select f from cpu", stmt.QueryOptions{}, 0, "",
 		replicaStateMachine, nodeStateMachine, jobManager)
 	replicaStateMachine.EXPECT().GetQueryableReplicas("test_db").Return(nil)
 	exec.Execute()
@@ -44,13 +45,13 @@ func TestBrokerExecutor_Execute(t *testing.T) {
 		currentNode,
 		generateBrokerActiveNode("1.1.1.4", 8000),
 	}
-	exec = newBrokerExecutor(context.TODO(), "test_db", "select f fro",
+	exec = newBrokerExecutor(context.TODO(), "test_db", "select f fro", stmt.QueryOptions{}, 0, "",
 		replicaStateMachine, nodeStateMachine, jobManager)
 	replicaStateMachine.EXPECT().GetQueryableReplicas("test_db").Return(storageNodes)
 	nodeStateMachine.EXPECT().GetActiveNodes().Return(brokerNodes)
 	exec.Execute()
 
-	exec = newBrokerExecutor(context.TODO(), "test_db", "select f from cpu",
+	exec = newBrokerExecutor(context.TODO(), "test_db", "select f from cpu", stmt.QueryOptions{}, 0, "",
 		replicaStateMachine, nodeStateMachine, jobManager)
 	replicaStateMachine.EXPECT().GetQueryableReplicas("test_db").Return(storageNodes)
 	nodeStateMachine.EXPECT().GetActiveNodes().Return(brokerNodes)
@@ -58,7 +59,7 @@ func TestBrokerExecutor_Execute(t *testing.T) {
 	exec.Execute()
 
 	// submit job error
-	exec = newBrokerExecutor(context.TODO(), "test_db", "select f from cpu",
+	exec = newBrokerExecutor(context.TODO(), "test_db", "select f from cpu", stmt.QueryOptions{}, 0, "",
 		replicaStateMachine, nodeStateMachine, jobManager)
 	replicaStateMachine.EXPECT().GetQueryableReplicas("test_db").Return(storageNodes)
 	nodeStateMachine.EXPECT().GetActiveNodes().Return(brokerNodes)
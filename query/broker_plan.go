@@ -10,6 +10,7 @@ import (
 // brokerPlan represents the broker execute plan
 type brokerPlan struct {
 	sql               string
+	options           stmt.QueryOptions
 	query             *stmt.Query
 	storageNodes      map[string][]int32
 	currentBrokerNode models.Node
@@ -19,11 +20,12 @@ type brokerPlan struct {
 	physicalPlan *models.PhysicalPlan
 }
 
-// newBrokerPlan creates broker execute plan
-func newBrokerPlan(sql string, storageNodes map[string][]int32,
+// newBrokerPlan creates broker execute plan, the options are applied to the parsed query
+func newBrokerPlan(sql string, options stmt.QueryOptions, storageNodes map[string][]int32,
 	currentBrokerNode models.Node, brokerNodes []models.ActiveNode) Plan {
 	return &brokerPlan{
 		sql:               sql,
+		options:           options,
 		storageNodes:      storageNodes,
 		currentBrokerNode: currentBrokerNode,
 		brokerNodes:       brokerNodes,
@@ -49,12 +51,24 @@ func (p *brokerPlan) Plan() error {
 	}
 	// set query statement
 	p.query = query
+	p.options.Apply(p.query)
+	if p.query.Fill.MaxGap > 0 && p.query.Fill.Type != stmt.FillPrevious {
+		return errFillMaxGapWithoutPrevious
+	}
 
 	//FIXME need set interval based on db config if not set
 	interval := 10 * timeutil.OneSecond
+	if p.query.ForceInterval > 0 {
+		// validates if multiple of storage interval in storage node
+		interval = p.query.ForceInterval
+	}
 	p.query.Interval = interval
-	p.query.TimeRange.Start = timeutil.Truncate(p.query.TimeRange.Start, interval)
-	p.query.TimeRange.End = timeutil.Truncate(p.query.TimeRange.End, interval)
+	location, err := p.query.Location()
+	if err != nil {
+		return err
+	}
+	p.query.TimeRange.Start = timeutil.TruncateInLocation(p.query.TimeRange.Start, interval, location)
+	p.query.TimeRange.End = timeutil.TruncateInLocation(p.query.TimeRange.End, interval, location)
 
 	root := p.currentBrokerNode
 
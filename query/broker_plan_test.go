@@ -6,17 +6,20 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/lindb/lindb/models"
+	"github.com/lindb/lindb/pkg/encoding"
+	"github.com/lindb/lindb/pkg/timeutil"
+	"github.com/lindb/lindb/sql/stmt"
 )
 
 func TestBrokerPlan_Wrong_Case(t *testing.T) {
-	plan := newBrokerPlan("sql", nil, models.Node{}, nil)
+	plan := newBrokerPlan("sql", stmt.QueryOptions{}, nil, models.Node{}, nil)
 	// storage nodes cannot be empty
 	err := plan.Plan()
 	assert.Equal(t, errNoAvailableStorageNode, err)
 
 	storageNodes := map[string][]int32{"1.1.1.1:8000": {1, 2, 4}}
 	// wrong sql
-	plan = newBrokerPlan("sql", storageNodes, models.Node{}, nil)
+	plan = newBrokerPlan("sql", stmt.QueryOptions{}, storageNodes, models.Node{}, nil)
 	err = plan.Plan()
 	assert.NotNil(t, err)
 }
@@ -25,7 +28,7 @@ func TestBrokerPlan_No_GroupBy(t *testing.T) {
 	storageNodes := map[string][]int32{"1.1.1.1:9000": {1, 2, 4}, "1.1.1.2:9000": {3, 5, 6}}
 	currentNode := generateBrokerActiveNode("1.1.1.3", 8000)
 	// no group sql
-	plan := newBrokerPlan("select f from cpu", storageNodes, currentNode.Node, nil)
+	plan := newBrokerPlan("select f from cpu", stmt.QueryOptions{}, storageNodes, currentNode.Node, nil)
 	err := plan.Plan()
 	if err != nil {
 		t.Fatal(err)
@@ -66,6 +69,7 @@ func TestBrokerPlan_GroupBy(t *testing.T) {
 	currentNode := generateBrokerActiveNode("1.1.1.3", 8000)
 	plan := newBrokerPlan(
 		"select f from cpu group by host",
+		stmt.QueryOptions{},
 		storageNodes,
 		currentNode.Node,
 		[]models.ActiveNode{
@@ -104,6 +108,7 @@ func TestBrokerPlan_GroupBy_Less_StorageNodes(t *testing.T) {
 	currentNode := generateBrokerActiveNode("1.1.1.3", 8000)
 	plan := newBrokerPlan(
 		"select f from cpu group by host",
+		stmt.QueryOptions{},
 		storageNodes,
 		currentNode.Node,
 		[]models.ActiveNode{
@@ -141,6 +146,7 @@ func TestBrokerPlan_GroupBy_Same_Broker(t *testing.T) {
 	// current node = active node
 	plan := newBrokerPlan(
 		"select f from cpu group by host",
+		stmt.QueryOptions{},
 		storageNodes,
 		currentNode.Node,
 		[]models.ActiveNode{currentNode})
@@ -169,6 +175,7 @@ func TestBrokerPlan_GroupBy_No_Broker(t *testing.T) {
 	// only one storage node
 	plan := newBrokerPlan(
 		"select f from cpu group by host",
+		stmt.QueryOptions{},
 		storageNodes,
 		currentNode.Node,
 		nil)
@@ -197,6 +204,7 @@ func TestBrokerPlan_GroupBy_One_StorageNode(t *testing.T) {
 	// only one storage node
 	plan := newBrokerPlan(
 		"select f from cpu group by host",
+		stmt.QueryOptions{},
 		storageNodes,
 		currentNode.Node,
 		[]models.ActiveNode{
@@ -226,3 +234,84 @@ func TestBrokerPlan_GroupBy_One_StorageNode(t *testing.T) {
 func generateBrokerActiveNode(ip string, port int) models.ActiveNode {
 	return models.ActiveNode{Node: models.Node{IP: ip, Port: uint16(port)}}
 }
+
+func TestBrokerPlan_ForceInterval(t *testing.T) {
+	storageNodes := map[string][]int32{"1.1.1.1:9000": {1, 2, 4}}
+	currentNode := generateBrokerActiveNode("1.1.1.3", 8000)
+
+	// default interval
+	plan := newBrokerPlan("select f from cpu", stmt.QueryOptions{}, storageNodes, currentNode.Node, nil)
+	assert.Nil(t, plan.Plan())
+	p := plan.(*brokerPlan)
+	assert.Equal(t, 10*timeutil.OneSecond, p.query.Interval)
+	assert.Zero(t, p.query.ForceInterval)
+
+	// forced interval
+	plan = newBrokerPlan("select f from cpu where time>'20190729 11:00:30' and time<'20190729 12:00:30'",
+		stmt.QueryOptions{ForceInterval: timeutil.OneMinute}, storageNodes, currentNode.Node, nil)
+	assert.Nil(t, plan.Plan())
+	p = plan.(*brokerPlan)
+	assert.Equal(t, timeutil.OneMinute, p.query.Interval)
+	assert.Equal(t, timeutil.OneMinute, p.query.ForceInterval)
+	assert.Zero(t, p.query.TimeRange.Start%timeutil.OneMinute)
+	assert.Zero(t, p.query.TimeRange.End%timeutil.OneMinute)
+}
+
+func TestBrokerPlan_Timezone(t *testing.T) {
+	storageNodes := map[string][]int32{"1.1.1.1:9000": {1, 2, 4}}
+	currentNode := generateBrokerActiveNode("1.1.1.3", 8000)
+
+	// time range is aligned to local midnight of timezone
+	plan := newBrokerPlan("select f from cpu where time>'20190729 11:00:30' and time<'20190730 12:00:30'",
+		stmt.QueryOptions{ForceInterval: timeutil.OneDay, Timezone: "Asia/Shanghai"},
+		storageNodes, currentNode.Node, nil)
+	assert.Nil(t, plan.Plan())
+	p := plan.(*brokerPlan)
+	assert.Equal(t, "Asia/Shanghai", p.query.Timezone)
+	assert.Zero(t, (p.query.TimeRange.Start+8*timeutil.OneHour)%timeutil.OneDay)
+	assert.Zero(t, (p.query.TimeRange.End+8*timeutil.OneHour)%timeutil.OneDay)
+
+	// unknown timezone
+	plan = newBrokerPlan("select f from cpu", stmt.QueryOptions{Timezone: "Unknown/Zone"},
+		storageNodes, currentNode.Node, nil)
+	assert.Error(t, plan.Plan())
+}
+
+func TestBrokerPlan_FillMaxGap(t *testing.T) {
+	storageNodes := map[string][]int32{"1.1.1.1:9000": {1, 2, 4}}
+	currentNode := generateBrokerActiveNode("1.1.1.3", 8000)
+
+	plan := newBrokerPlan("select f+g from cpu group by host fill(previous)",
+		stmt.QueryOptions{FillMaxGap: 5 * timeutil.OneMinute}, storageNodes, currentNode.Node, nil)
+	assert.Nil(t, plan.Plan())
+	p := plan.(*brokerPlan)
+	assert.Equal(t, stmt.Fill{Type: stmt.FillPrevious, MaxGap: 5 * timeutil.OneMinute}, p.query.Fill)
+
+	// max gap only works with previous fill
+	plan = newBrokerPlan("select f+g from cpu group by host fill(0)",
+		stmt.QueryOptions{FillMaxGap: 5 * timeutil.OneMinute}, storageNodes, currentNode.Node, nil)
+	assert.Equal(t, errFillMaxGapWithoutPrevious, plan.Plan())
+	plan = newBrokerPlan("select f+g from cpu",
+		stmt.QueryOptions{FillMaxGap: 5 * timeutil.OneMinute}, storageNodes, currentNode.Node, nil)
+	assert.Equal(t, errFillMaxGapWithoutPrevious, plan.Plan())
+}
+
+func TestBrokerPlan_StorageOptions(t *testing.T) {
+	storageNodes := map[string][]int32{"1.1.1.1:9000": {1, 2, 4}}
+	currentNode := generateBrokerActiveNode("1.1.1.3", 8000)
+
+	plan := newBrokerPlan("select f from cpu",
+		stmt.QueryOptions{Budget: 500, WithSeriesID: true, Timezone: "Asia/Shanghai", ValidateTagKeys: true},
+		storageNodes, currentNode.Node, nil)
+	assert.Nil(t, plan.Plan())
+	p := plan.(*brokerPlan)
+	assert.Equal(t, int64(500), p.query.Budget)
+	assert.True(t, p.query.WithSeriesID)
+	// the options used by storage are sent to storage nodes with the query
+	query := &stmt.Query{}
+	assert.Nil(t, encoding.JSONUnmarshal(encoding.JSONMarshal(p.query), query))
+	assert.Equal(t, int64(500), query.Budget)
+	assert.True(t, query.WithSeriesID)
+	assert.Equal(t, "Asia/Shanghai", query.Timezone)
+	assert.True(t, query.ValidateTagKeys)
+}
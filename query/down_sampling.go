@@ -1,13 +1,17 @@
 package query
 
 import (
+	"time"
+
 	"github.com/lindb/lindb/pkg/timeutil"
 )
 
-// downSamplingTimeRange returns down sampling time range and interval ratio
+// downSamplingTimeRange returns down sampling time range and interval ratio,
+// the time range is truncated in the location, so that the buckets align to local midnight/week-start.
 func downSamplingTimeRange(queryInterval,
 	storageInterval int64,
 	queryTimeRange timeutil.TimeRange,
+	location *time.Location,
 ) (
 	timeRange timeutil.TimeRange,
 	intervalRatio int,
@@ -22,8 +26,8 @@ func downSamplingTimeRange(queryInterval,
 	}
 	// 2. truncate time range
 	timeRange = timeutil.TimeRange{
-		Start: timeutil.Truncate(queryTimeRange.Start, interval.Int64()),
-		End:   timeutil.Truncate(queryTimeRange.End, interval.Int64()),
+		Start: timeutil.TruncateInLocation(queryTimeRange.Start, interval.Int64(), location),
+		End:   timeutil.TruncateInLocation(queryTimeRange.End, interval.Int64(), location),
 	}
 	return
 }
@@ -2,6 +2,7 @@ package query
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -15,7 +16,7 @@ func Test_downSamplingTimeRange(t *testing.T) {
 		timeutil.TimeRange{
 			Start: 35 * timeutil.OneSecond,
 			End:   65 * timeutil.OneSecond,
-		})
+		}, time.UTC)
 	assert.Equal(t, 3, intervalRatio)
 	assert.Equal(t, 30*timeutil.OneSecond, interval.Int64())
 	assert.Equal(t, timeutil.TimeRange{
@@ -23,3 +24,29 @@ func Test_downSamplingTimeRange(t *testing.T) {
 		End:   60 * timeutil.OneSecond,
 	}, timeRange)
 }
+
+func Test_downSamplingTimeRange_timezone(t *testing.T) {
+	shanghai := time.FixedZone("UTC+8", 8*3600)
+	queryTimeRange := timeutil.TimeRange{
+		Start: time.Date(2019, 7, 2, 3, 0, 0, 0, shanghai).UnixNano() / 1e6,
+		End:   time.Date(2019, 7, 4, 3, 0, 0, 0, shanghai).UnixNano() / 1e6,
+	}
+	// utc
+	timeRange, _, _ := downSamplingTimeRange(timeutil.OneDay, 10*timeutil.OneSecond, queryTimeRange, time.UTC)
+	assert.Equal(t, timeutil.TimeRange{
+		Start: time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC).UnixNano() / 1e6,
+		End:   time.Date(2019, 7, 3, 0, 0, 0, 0, time.UTC).UnixNano() / 1e6,
+	}, timeRange)
+	// bucket boundaries shift to local midnight
+	timeRange, intervalRatio, interval := downSamplingTimeRange(timeutil.OneDay, 10*timeutil.OneSecond,
+		queryTimeRange, shanghai)
+	assert.Equal(t, 8640, intervalRatio)
+	assert.Equal(t, timeutil.OneDay, interval.Int64())
+	assert.Equal(t, timeutil.TimeRange{
+		Start: time.Date(2019, 7, 2, 0, 0, 0, 0, shanghai).UnixNano() / 1e6,
+		End:   time.Date(2019, 7, 4, 0, 0, 0, 0, shanghai).UnixNano() / 1e6,
+	}, timeRange)
+	// weekly bucket starts at local monday
+	timeRange, _, _ = downSamplingTimeRange(timeutil.OneWeek, 10*timeutil.OneSecond, queryTimeRange, shanghai)
+	assert.Equal(t, time.Date(2019, 7, 1, 0, 0, 0, 0, shanghai).UnixNano()/1e6, timeRange.Start)
+}
@@ -4,4 +4,7 @@ import (
 	"errors"
 )
 
-var errNoAvailableStorageNode = errors.New("no available storage node for server")
+var (
+	errNoAvailableStorageNode    = errors.New("no available storage node for server")
+	errFillMaxGapWithoutPrevious = errors.New("max gap of fill only works with fill(previous)")
+)
@@ -3,6 +3,7 @@ package query
 import (
 	"context"
 
+	"github.com/lindb/lindb/config"
 	"github.com/lindb/lindb/coordinator/broker"
 	"github.com/lindb/lindb/coordinator/replica"
 	"github.com/lindb/lindb/parallel"
@@ -11,31 +12,37 @@ import (
 )
 
 // executorFactory implements parallel.ExecutorFactory
-type executorFactory struct{}
+type executorFactory struct {
+	queryCfg config.Query
+}
 
 // NewExecutorFactory creates executor factory
-func NewExecutorFactory() parallel.ExecutorFactory {
-	return &executorFactory{}
+func NewExecutorFactory(queryCfg config.Query) parallel.ExecutorFactory {
+	return &executorFactory{queryCfg: queryCfg}
 }
 
 // NewStorageExecutor creates storage executor
-func (*executorFactory) NewStorageExecutor(
+func (f *executorFactory) NewStorageExecutor(
 	ctx parallel.ExecuteContext,
 	database tsdb.Database,
 	shardIDs []int32,
 	query *stmt.Query,
 ) parallel.Executor {
-	return newStorageExecutor(ctx, database, shardIDs, query)
+	return newStorageExecutor(ctx, database, shardIDs, query, f.queryCfg.MaxFieldsPerQuery,
+		f.queryCfg.MissingShardPolicy == config.MissingShardSkip)
 }
 
 // NewStorageExecutor creates broker executor
-func (*executorFactory) NewBrokerExecutor(
+func (f *executorFactory) NewBrokerExecutor(
 	ctx context.Context,
 	databaseName string,
 	sql string,
+	options stmt.QueryOptions,
 	replicaStateMachine replica.StatusStateMachine,
 	nodeStateMachine broker.NodeStateMachine,
 	jobManager parallel.JobManager,
 ) parallel.BrokerExecutor {
-	return newBrokerExecutor(ctx, databaseName, sql, replicaStateMachine, nodeStateMachine, jobManager)
+	return newBrokerExecutor(ctx, databaseName, sql, options,
+		f.queryCfg.MaxMemoryPerQueryInBytes(), f.queryCfg.SpillDir,
+		replicaStateMachine, nodeStateMachine, jobManager)
 }
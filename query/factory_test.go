@@ -7,7 +7,9 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/lindb/lindb/config"
 	"github.com/lindb/lindb/parallel"
+	"github.com/lindb/lindb/sql/stmt"
 	"github.com/lindb/lindb/tsdb"
 )
 
@@ -15,11 +17,11 @@ func TestExecutorFactory_NewExecutor(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	factory := NewExecutorFactory()
+	factory := NewExecutorFactory(*config.NewDefaultQuery())
 	mockDatabase := tsdb.NewMockDatabase(ctrl)
 	mockDatabase.EXPECT().ExecutorPool().Return(nil)
 	assert.NotNil(t, factory.NewStorageExecutor(
 		parallel.NewMockExecuteContext(ctrl), mockDatabase, nil, nil))
 	assert.NotNil(t, factory.NewBrokerExecutor(
-		context.TODO(), "db", "sql", nil, nil, nil))
+		context.TODO(), "db", "sql", stmt.QueryOptions{}, nil, nil, nil))
 }
@@ -1,8 +1,10 @@
 package query
 
 import (
+	"fmt"
 	"sync"
 
+	"github.com/RoaringBitmap/roaring"
 	"go.uber.org/atomic"
 
 	"github.com/lindb/lindb/aggregation"
@@ -11,11 +13,16 @@ import (
 	"github.com/lindb/lindb/tsdb"
 )
 
+// SeriesIDTagKey is the tag key of the internal series id returned for debugging if query with series id,
+// the tag value is formatted as version:seriesID, which is stable across scans.
+const SeriesIDTagKey = "__seriesID__"
+
 // scanWorker represents dispatch the event of scanner
 type scanWorker struct {
-	hasGroupBy bool
-	metricID   uint32
-	tagKeys    []string
+	hasGroupBy   bool
+	metricID     uint32
+	tagKeys      []string
+	withSeriesID bool // returns the series id as tag of each time series
 
 	metaGetter series.MetaGetter
 	groupAgg   aggregation.GroupingAggregator
@@ -26,6 +33,7 @@ type scanWorker struct {
 	pending atomic.Int32
 
 	done atomic.Bool
+	err  error // error of getting group by tag values
 
 	mutex sync.Mutex
 }
@@ -35,6 +43,7 @@ func createScanWorker(
 	ctx parallel.ExecuteContext,
 	metricID uint32,
 	groupByTagKeys []string,
+	withSeriesID bool,
 	metaGetter series.MetaGetter,
 	groupedAgg aggregation.GroupingAggregator,
 	executorPool *tsdb.ExecutorPool,
@@ -43,6 +52,7 @@ func createScanWorker(
 		metricID:     metricID,
 		executorPool: executorPool,
 		tagKeys:      groupByTagKeys,
+		withSeriesID: withSeriesID,
 		hasGroupBy:   len(groupByTagKeys) > 0,
 		metaGetter:   metaGetter,
 		groupAgg:     groupedAgg,
@@ -63,13 +73,13 @@ func (s *scanWorker) Emit(event series.ScanEvent) {
 				defer s.complete()
 
 				resultSet := event.ResultSet()
-				if resultSet != nil {
-					agg, ok := resultSet.(aggregation.FieldAggregates)
-					if ok {
-						s.mutex.Lock()
-						s.groupAgg.Aggregate(agg.ResultSet(nil))
-						s.mutex.Unlock()
-					}
+				switch agg := resultSet.(type) {
+				case aggregation.FieldAggregates:
+					s.mutex.Lock()
+					s.groupAgg.Aggregate(agg.ResultSet(nil))
+					s.mutex.Unlock()
+				case aggregation.SeriesFieldAggregates:
+					s.aggregateGroupBy(event, agg)
 				}
 				event.Release()
 			})
@@ -79,6 +89,58 @@ func (s *scanWorker) Emit(event series.ScanEvent) {
 	})
 }
 
+// aggregateGroupBy aggregates the data of each time series by the tag values of group by tag keys,
+// if the time series has no value for the tag key, uses empty string as the tag value.
+// The series id is added as tag if query with series id, so that each time series is returned separately.
+func (s *scanWorker) aggregateGroupBy(event series.ScanEvent, agg aggregation.SeriesFieldAggregates) {
+	var (
+		seriesID2TagValues map[uint32][]string
+		err                error
+	)
+	version := event.Version()
+	if s.hasGroupBy {
+		seriesID2TagValues, err = s.getTagValues(version, event.SeriesIDs())
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err != nil {
+		s.err = err
+		return
+	}
+	for seriesID, fieldAggregates := range agg {
+		tagValues := seriesID2TagValues[seriesID]
+		tags := make(map[string]string, len(s.tagKeys)+1)
+		for idx, tagKey := range s.tagKeys {
+			tagValue := ""
+			if idx < len(tagValues) {
+				tagValue = tagValues[idx]
+			}
+			tags[tagKey] = tagValue
+		}
+		if s.withSeriesID {
+			tags[SeriesIDTagKey] = fmt.Sprintf("%d:%d", version, seriesID)
+		}
+		s.groupAgg.Aggregate(fieldAggregates.ResultSet(tags))
+	}
+}
+
+// getTagValues returns the tag values of group by tag keys for the series ids of the version,
+// resolves the tag values by version if the meta getter supports multi versions(e.g. memory database),
+// because the same series id of different versions may be different series.
+func (s *scanWorker) getTagValues(version series.Version, seriesIDs *roaring.Bitmap) (map[uint32][]string, error) {
+	getter, ok := s.metaGetter.(series.MultiVerMetaGetter)
+	if !ok {
+		return s.metaGetter.GetTagValues(s.metricID, s.tagKeys, version, seriesIDs)
+	}
+	multiVerSeriesIDs := series.NewMultiVerSeriesIDSet()
+	multiVerSeriesIDs.Add(version, seriesIDs)
+	version2TagValues, err := getter.GetTagValuesByVersions(s.metricID, s.tagKeys, multiVerSeriesIDs)
+	if err != nil {
+		return nil, err
+	}
+	return version2TagValues[version], nil
+}
+
 // Close marks scan worker can be done
 func (s *scanWorker) Close() {
 	s.done.Store(true)
@@ -94,7 +156,10 @@ func (s *scanWorker) complete() {
 				SeriesList: resultSet,
 			})
 		}
+		s.mutex.Lock()
+		err := s.err
+		s.mutex.Unlock()
 		// complete the scan task
-		s.ctx.Complete(nil)
+		s.ctx.Complete(err)
 	}
 }
@@ -1,14 +1,18 @@
 package query
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/RoaringBitmap/roaring"
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
 
 	"github.com/lindb/lindb/aggregation"
 	"github.com/lindb/lindb/parallel"
 	"github.com/lindb/lindb/pkg/concurrent"
+	"github.com/lindb/lindb/pkg/timeutil"
 	"github.com/lindb/lindb/series"
 	"github.com/lindb/lindb/tsdb"
 )
@@ -25,7 +29,7 @@ func TestScanWorker_Emit(t *testing.T) {
 	groupAgg := aggregation.NewMockGroupingAggregator(ctrl)
 	exeCtx := parallel.NewMockExecuteContext(ctrl)
 
-	worker := createScanWorker(exeCtx, uint32(10), nil, nil, groupAgg, execPool)
+	worker := createScanWorker(exeCtx, uint32(10), nil, false, nil, groupAgg, execPool)
 	event := series.NewMockScanEvent(ctrl)
 	gomock.InOrder(
 		event.EXPECT().Scan().Return(false),
@@ -45,7 +49,7 @@ func TestScanWorker_handle_event(t *testing.T) {
 	agg := aggregation.NewMockSeriesAggregator(ctrl)
 	fieldAggregates := aggregation.FieldAggregates{agg}
 
-	worker := createScanWorker(exeCtx, uint32(10), nil, nil, groupAgg, execPool)
+	worker := createScanWorker(exeCtx, uint32(10), nil, false, nil, groupAgg, execPool)
 	event := series.NewMockScanEvent(ctrl)
 	gomock.InOrder(
 		event.EXPECT().Scan().Return(true),
@@ -60,3 +64,173 @@ func TestScanWorker_handle_event(t *testing.T) {
 	worker.Close()
 	time.Sleep(500 * time.Millisecond)
 }
+
+func TestScanWorker_GroupBy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exeCtx := parallel.NewMockExecuteContext(ctrl)
+	metaGetter := series.NewMockMetaGetter(ctrl)
+	groupAgg := aggregation.NewGroupingAggregator(timeutil.Interval(timeutil.OneSecond), timeutil.TimeRange{}, nil)
+	tagKeys := []string{"host", "disk"}
+	worker := createScanWorker(exeCtx, uint32(10), tagKeys, false, metaGetter, groupAgg, execPool)
+
+	event := series.NewMockScanEvent(ctrl)
+	seriesAggregates := aggregation.SeriesFieldAggregates{
+		1: aggregation.FieldAggregates{},
+		2: aggregation.FieldAggregates{},
+		3: aggregation.FieldAggregates{},
+		4: aggregation.FieldAggregates{},
+		5: aggregation.FieldAggregates{},
+	}
+	seriesIDs := roaring.BitmapOf(1, 2, 3, 4, 5)
+	var groups []map[string]string
+	done := make(chan struct{})
+	gomock.InOrder(
+		event.EXPECT().Scan().Return(true),
+		event.EXPECT().ResultSet().Return(seriesAggregates),
+		event.EXPECT().Version().Return(series.Version(1)),
+		event.EXPECT().SeriesIDs().Return(seriesIDs),
+		metaGetter.EXPECT().GetTagValues(uint32(10), tagKeys, series.Version(1), seriesIDs).
+			Return(map[uint32][]string{
+				1: {"1.1.1.1", "sda"},
+				2: {"1.1.1.1", "sda"},
+				3: {"1.1.1.1", "sdb"},
+				4: {"1.1.1.2", ""}, // missing disk
+				// missing series 5
+			}, nil),
+		event.EXPECT().Release(),
+		exeCtx.EXPECT().Emit(gomock.Any()).Do(func(event *series.TimeSeriesEvent) {
+			for _, it := range event.SeriesList {
+				groups = append(groups, it.Tags())
+			}
+		}),
+		exeCtx.EXPECT().Complete(nil).Do(func(err error) { close(done) }),
+	)
+	worker.Emit(event)
+	worker.Close()
+	<-done
+	assert.Len(t, groups, 4)
+	assert.Contains(t, groups, map[string]string{"host": "1.1.1.1", "disk": "sda"})
+	assert.Contains(t, groups, map[string]string{"host": "1.1.1.1", "disk": "sdb"})
+	assert.Contains(t, groups, map[string]string{"host": "1.1.1.2", "disk": ""})
+	assert.Contains(t, groups, map[string]string{"host": "", "disk": ""})
+}
+
+// multiVerMetaGetter is the meta getter supports multi versions, such as memory database
+type multiVerMetaGetter struct {
+	*series.MockMetaGetter
+	*series.MockMultiVerMetaGetter
+}
+
+func TestScanWorker_GroupBy_multiVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exeCtx := parallel.NewMockExecuteContext(ctrl)
+	metaGetter := series.NewMockMultiVerMetaGetter(ctrl)
+	groupAgg := aggregation.NewGroupingAggregator(timeutil.Interval(timeutil.OneSecond), timeutil.TimeRange{}, nil)
+	worker := createScanWorker(exeCtx, uint32(10), []string{"host"}, false,
+		&multiVerMetaGetter{MockMetaGetter: series.NewMockMetaGetter(ctrl), MockMultiVerMetaGetter: metaGetter},
+		groupAgg, execPool)
+
+	event := series.NewMockScanEvent(ctrl)
+	seriesIDs := roaring.BitmapOf(1, 2)
+	multiVerSeriesIDs := series.NewMultiVerSeriesIDSet()
+	multiVerSeriesIDs.Add(2, seriesIDs)
+	var groups []map[string]string
+	done := make(chan struct{})
+	gomock.InOrder(
+		event.EXPECT().Scan().Return(true),
+		event.EXPECT().ResultSet().Return(aggregation.SeriesFieldAggregates{
+			1: aggregation.FieldAggregates{},
+			2: aggregation.FieldAggregates{},
+		}),
+		event.EXPECT().Version().Return(series.Version(2)),
+		event.EXPECT().SeriesIDs().Return(seriesIDs),
+		metaGetter.EXPECT().GetTagValuesByVersions(uint32(10), []string{"host"}, multiVerSeriesIDs).
+			Return(map[series.Version]map[uint32][]string{
+				2: {1: {"1.1.1.1"}, 2: {"1.1.1.2"}},
+			}, nil),
+		event.EXPECT().Release(),
+		exeCtx.EXPECT().Emit(gomock.Any()).Do(func(event *series.TimeSeriesEvent) {
+			for _, it := range event.SeriesList {
+				groups = append(groups, it.Tags())
+			}
+		}),
+		exeCtx.EXPECT().Complete(nil).Do(func(err error) { close(done) }),
+	)
+	worker.Emit(event)
+	worker.Close()
+	<-done
+	assert.Len(t, groups, 2)
+	assert.Contains(t, groups, map[string]string{"host": "1.1.1.1"})
+	assert.Contains(t, groups, map[string]string{"host": "1.1.1.2"})
+}
+
+func TestScanWorker_GroupBy_GetTagValues_Err(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exeCtx := parallel.NewMockExecuteContext(ctrl)
+	metaGetter := series.NewMockMetaGetter(ctrl)
+	groupAgg := aggregation.NewMockGroupingAggregator(ctrl)
+	worker := createScanWorker(exeCtx, uint32(10), []string{"host"}, false, metaGetter, groupAgg, execPool)
+
+	event := series.NewMockScanEvent(ctrl)
+	done := make(chan struct{})
+	gomock.InOrder(
+		event.EXPECT().Scan().Return(true),
+		event.EXPECT().ResultSet().Return(aggregation.SeriesFieldAggregates{1: aggregation.FieldAggregates{}}),
+		event.EXPECT().Version().Return(series.Version(1)),
+		event.EXPECT().SeriesIDs().Return(roaring.BitmapOf(1)),
+		metaGetter.EXPECT().GetTagValues(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, fmt.Errorf("err")),
+		event.EXPECT().Release(),
+		groupAgg.EXPECT().ResultSet().Return(nil),
+		exeCtx.EXPECT().Complete(gomock.Not(nil)).Do(func(err error) { close(done) }),
+	)
+	worker.Emit(event)
+	worker.Close()
+	<-done
+}
+
+func TestScanWorker_WithSeriesID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	scan := func() []map[string]string {
+		exeCtx := parallel.NewMockExecuteContext(ctrl)
+		groupAgg := aggregation.NewGroupingAggregator(timeutil.Interval(timeutil.OneSecond), timeutil.TimeRange{}, nil)
+		worker := createScanWorker(exeCtx, uint32(10), nil, true, nil, groupAgg, execPool)
+		event := series.NewMockScanEvent(ctrl)
+		var groups []map[string]string
+		done := make(chan struct{})
+		gomock.InOrder(
+			event.EXPECT().Scan().Return(true),
+			event.EXPECT().ResultSet().Return(aggregation.SeriesFieldAggregates{
+				1: aggregation.FieldAggregates{},
+				2: aggregation.FieldAggregates{},
+			}),
+			event.EXPECT().Version().Return(series.Version(100)),
+			event.EXPECT().Release(),
+			exeCtx.EXPECT().Emit(gomock.Any()).Do(func(event *series.TimeSeriesEvent) {
+				for _, it := range event.SeriesList {
+					groups = append(groups, it.Tags())
+				}
+			}),
+			exeCtx.EXPECT().Complete(nil).Do(func(err error) { close(done) }),
+		)
+		worker.Emit(event)
+		worker.Close()
+		<-done
+		return groups
+	}
+	// each time series is returned with series id without group by
+	groups := scan()
+	assert.Len(t, groups, 2)
+	assert.Contains(t, groups, map[string]string{SeriesIDTagKey: "100:1"})
+	assert.Contains(t, groups, map[string]string{SeriesIDTagKey: "100:2"})
+	// series id is stable across scans
+	assert.ElementsMatch(t, groups, scan())
+}
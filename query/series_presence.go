@@ -0,0 +1,193 @@
+package query
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/RoaringBitmap/roaring"
+
+	"github.com/lindb/lindb/series"
+	"github.com/lindb/lindb/sql/stmt"
+)
+
+// tagValuesSeparator separates the tag values of a series as the identity of tag combination
+const tagValuesSeparator = "\x00"
+
+// SeriesIdentity represents the identity of a time series without any field values
+type SeriesIdentity struct {
+	MetricName string
+	Tags       map[string]string
+}
+
+// seriesPresence represents a metadata-only query which finds the identities of the series matching the condition,
+// only the index is searched, the field stores are not touched, for service discovery like "what series exist".
+type seriesPresence struct {
+	metricID uint32
+	query    *stmt.Query
+	limit    int
+
+	filter     series.Filter
+	metaGetter series.MetaGetter
+	tagKeys    []string // all the tag keys of metric, the tags of series are resolved by them
+}
+
+// FindSeriesIdentities finds the identities of the series matching the query condition from the index,
+// returns at most limit identities ordered by version and series id, limit <= 0 means unlimited.
+// the tags of series are resolved by the given tag keys of metric.
+func FindSeriesIdentities(
+	metricID uint32,
+	query *stmt.Query,
+	limit int,
+	filter series.Filter,
+	metaGetter series.MetaGetter,
+	tagKeys []string,
+) ([]SeriesIdentity, error) {
+	p := &seriesPresence{
+		metricID:   metricID,
+		query:      query,
+		limit:      limit,
+		filter:     filter,
+		metaGetter: metaGetter,
+		tagKeys:    tagKeys,
+	}
+	return p.find()
+}
+
+// CountSeries counts the distinct series(tag combinations) matching the query condition over the time range,
+// for cardinality dashboards. The series ids are allocated by version, so the same series existing in
+// multiple versions has different series ids, the series of all versions are deduplicated by tag values.
+func CountSeries(
+	metricID uint32,
+	query *stmt.Query,
+	filter series.Filter,
+	metaGetter series.MetaGetter,
+	tagKeys []string,
+) (uint64, error) {
+	p := &seriesPresence{
+		metricID:   metricID,
+		query:      query,
+		filter:     filter,
+		metaGetter: metaGetter,
+		tagKeys:    tagKeys,
+	}
+	seriesIDs, err := p.search()
+	if err != nil {
+		return 0, err
+	}
+	if seriesIDs == nil {
+		return 0, nil
+	}
+	distinct := make(map[string]struct{})
+	for version, ids := range seriesIDs.Versions() {
+		if ids.IsEmpty() {
+			continue
+		}
+		seriesID2TagValues, err := p.metaGetter.GetTagValues(p.metricID, p.tagKeys, version, ids)
+		if err != nil {
+			return 0, err
+		}
+		for _, tagValues := range seriesID2TagValues {
+			distinct[strings.Join(tagValues, tagValuesSeparator)] = struct{}{}
+		}
+	}
+	return uint64(len(distinct)), nil
+}
+
+// find finds the matching series ids, then gets the tags of them up to the limit
+func (p *seriesPresence) find() ([]SeriesIdentity, error) {
+	seriesIDs, err := p.search()
+	if err != nil {
+		return nil, err
+	}
+	if seriesIDs == nil || seriesIDs.IsEmpty() {
+		return nil, nil
+	}
+	versions := seriesIDs.Versions()
+	sortedVersions := make([]series.Version, 0, len(versions))
+	for version := range versions {
+		sortedVersions = append(sortedVersions, version)
+	}
+	sort.Slice(sortedVersions, func(i, j int) bool {
+		return sortedVersions[i] < sortedVersions[j]
+	})
+
+	var result []SeriesIdentity
+	for _, version := range sortedVersions {
+		ids := p.truncate(versions[version], len(result))
+		if ids.IsEmpty() {
+			continue
+		}
+		seriesID2TagValues, err := p.metaGetter.GetTagValues(p.metricID, p.tagKeys, version, ids)
+		if err == series.ErrNotFound {
+			// the version is not found in the meta getter, such as the version evicted from memory
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		it := ids.Iterator()
+		for it.HasNext() {
+			tagValues, ok := seriesID2TagValues[it.Next()]
+			if !ok {
+				continue
+			}
+			tags := make(map[string]string, len(p.tagKeys))
+			for idx, tagKey := range p.tagKeys {
+				// tag value of the tag key which not exist in the series is empty string
+				if idx < len(tagValues) && tagValues[idx] != "" {
+					tags[tagKey] = tagValues[idx]
+				}
+			}
+			result = append(result, SeriesIdentity{MetricName: p.query.MetricName, Tags: tags})
+		}
+		if p.limit > 0 && len(result) >= p.limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// seriesIdentityKey returns the tag values joined in the order of tag keys as the identity of tag combination
+func seriesIdentityKey(tagKeys []string, tags map[string]string) string {
+	tagValues := make([]string, len(tagKeys))
+	for idx, tagKey := range tagKeys {
+		tagValues[idx] = tags[tagKey]
+	}
+	return strings.Join(tagValues, tagValuesSeparator)
+}
+
+// search searches the series ids by condition, matches all the series having any tag if no condition
+func (p *seriesPresence) search() (*series.MultiVerSeriesIDSet, error) {
+	if p.query.Condition != nil {
+		return newSeriesSearch(p.metricID, p.filter, p.query).Search()
+	}
+	var result *series.MultiVerSeriesIDSet
+	for _, tagKey := range p.tagKeys {
+		seriesIDs, err := p.filter.GetSeriesIDsForTag(p.metricID, tagKey, p.query.TimeRange)
+		if err != nil {
+			return nil, err
+		}
+		if seriesIDs == nil {
+			continue
+		}
+		if result == nil {
+			result = seriesIDs
+		} else {
+			result.Or(seriesIDs)
+		}
+	}
+	return result, nil
+}
+
+// truncate returns the first series ids of the bitmap within the remaining limit
+func (p *seriesPresence) truncate(seriesIDs *roaring.Bitmap, found int) *roaring.Bitmap {
+	if p.limit <= 0 || seriesIDs.GetCardinality() <= uint64(p.limit-found) {
+		return seriesIDs
+	}
+	result := roaring.New()
+	it := seriesIDs.Iterator()
+	for it.HasNext() && int(result.GetCardinality()) < p.limit-found {
+		result.Add(it.Next())
+	}
+	return result
+}
@@ -0,0 +1,187 @@
+package query
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/series"
+	"github.com/lindb/lindb/sql"
+)
+
+func TestFindSeriesIdentities(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	filter := series.NewMockFilter(ctrl)
+	metaGetter := series.NewMockMetaGetter(ctrl)
+	tagKeys := []string{"host", "zone"}
+
+	q, _ := sql.Parse("select f from cpu where host='1.1.1.1'")
+	seriesIDs := series.NewMultiVerSeriesIDSet()
+	seriesIDs.Add(series.Version(2), roaring.BitmapOf(3, 4))
+	seriesIDs.Add(series.Version(1), roaring.BitmapOf(1))
+	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).Return(seriesIDs, nil)
+	gomock.InOrder(
+		metaGetter.EXPECT().GetTagValues(uint32(10), []string{"host", "zone"}, series.Version(1), roaring.BitmapOf(1)).
+			Return(map[uint32][]string{1: {"1.1.1.1", "sh"}}, nil),
+		metaGetter.EXPECT().GetTagValues(uint32(10), []string{"host", "zone"}, series.Version(2), roaring.BitmapOf(3, 4)).
+			Return(map[uint32][]string{3: {"1.1.1.1", ""}, 4: {"1.1.1.1", "bj"}}, nil),
+	)
+	result, err := FindSeriesIdentities(10, q, 0, filter, metaGetter, tagKeys)
+	assert.NoError(t, err)
+	assert.Equal(t, []SeriesIdentity{
+		{MetricName: "cpu", Tags: map[string]string{"host": "1.1.1.1", "zone": "sh"}},
+		{MetricName: "cpu", Tags: map[string]string{"host": "1.1.1.1"}},
+		{MetricName: "cpu", Tags: map[string]string{"host": "1.1.1.1", "zone": "bj"}},
+	}, result)
+
+	// not found
+	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).Return(nil, nil)
+	result, err = FindSeriesIdentities(10, q, 0, filter, metaGetter, tagKeys)
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+
+	// search failure
+	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("err"))
+	result, err = FindSeriesIdentities(10, q, 0, filter, metaGetter, tagKeys)
+	assert.Error(t, err)
+	assert.Nil(t, result)
+
+	// the version not found in meta getter is skipped
+	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).Return(seriesIDs, nil)
+	metaGetter.EXPECT().GetTagValues(uint32(10), []string{"host", "zone"}, series.Version(1), roaring.BitmapOf(1)).
+		Return(nil, series.ErrNotFound)
+	metaGetter.EXPECT().GetTagValues(uint32(10), []string{"host", "zone"}, series.Version(2), roaring.BitmapOf(3, 4)).
+		Return(map[uint32][]string{3: {"1.1.1.3", "sh"}}, nil)
+	result, err = FindSeriesIdentities(10, q, 0, filter, metaGetter, tagKeys)
+	assert.NoError(t, err)
+	assert.Equal(t, []SeriesIdentity{
+		{MetricName: "cpu", Tags: map[string]string{"host": "1.1.1.3", "zone": "sh"}},
+	}, result)
+
+	// get tag values failure
+	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).Return(seriesIDs, nil)
+	metaGetter.EXPECT().GetTagValues(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("err"))
+	result, err = FindSeriesIdentities(10, q, 0, filter, metaGetter, tagKeys)
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestFindSeriesIdentities_limit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	filter := series.NewMockFilter(ctrl)
+	metaGetter := series.NewMockMetaGetter(ctrl)
+
+	// no condition matches all series having any tag
+	q, _ := sql.Parse("select f from cpu")
+	hostSeriesIDs := series.NewMultiVerSeriesIDSet()
+	hostSeriesIDs.Add(series.Version(1), roaring.BitmapOf(1, 2))
+	hostSeriesIDs.Add(series.Version(2), roaring.BitmapOf(1, 2, 3))
+	zoneSeriesIDs := series.NewMultiVerSeriesIDSet()
+	zoneSeriesIDs.Add(series.Version(1), roaring.BitmapOf(5))
+	filter.EXPECT().GetSeriesIDsForTag(uint32(10), "host", gomock.Any()).Return(hostSeriesIDs, nil)
+	filter.EXPECT().GetSeriesIDsForTag(uint32(10), "zone", gomock.Any()).Return(zoneSeriesIDs, nil)
+	// only the tags of series within the limit are read, the later version is not touched
+	metaGetter.EXPECT().GetTagValues(uint32(10), []string{"host", "zone"}, series.Version(1), roaring.BitmapOf(1, 2)).
+		Return(map[uint32][]string{1: {"a", "sh"}, 2: {"b", "sh"}}, nil)
+
+	result, err := FindSeriesIdentities(10, q, 2, filter, metaGetter, []string{"host", "zone"})
+	assert.NoError(t, err)
+	assert.Equal(t, []SeriesIdentity{
+		{MetricName: "cpu", Tags: map[string]string{"host": "a", "zone": "sh"}},
+		{MetricName: "cpu", Tags: map[string]string{"host": "b", "zone": "sh"}},
+	}, result)
+
+	// get series ids for tag failure
+	filter.EXPECT().GetSeriesIDsForTag(uint32(10), "host", gomock.Any()).Return(nil, fmt.Errorf("err"))
+	result, err = FindSeriesIdentities(10, q, 2, filter, metaGetter, []string{"host"})
+	assert.Error(t, err)
+	assert.Nil(t, result)
+
+	// limit across versions
+	seriesIDs := series.NewMultiVerSeriesIDSet()
+	seriesIDs.Add(series.Version(1), roaring.BitmapOf(1))
+	seriesIDs.Add(series.Version(2), roaring.BitmapOf(7, 8, 9))
+	filter.EXPECT().GetSeriesIDsForTag(uint32(10), "host", gomock.Any()).Return(seriesIDs, nil)
+	gomock.InOrder(
+		metaGetter.EXPECT().GetTagValues(uint32(10), []string{"host"}, series.Version(1), roaring.BitmapOf(1)).
+			Return(map[uint32][]string{1: {"a"}}, nil),
+		metaGetter.EXPECT().GetTagValues(uint32(10), []string{"host"}, series.Version(2), roaring.BitmapOf(7)).
+			Return(map[uint32][]string{7: {"c"}}, nil),
+	)
+	result, err = FindSeriesIdentities(10, q, 2, filter, metaGetter, []string{"host"})
+	assert.NoError(t, err)
+	assert.Equal(t, []SeriesIdentity{
+		{MetricName: "cpu", Tags: map[string]string{"host": "a"}},
+		{MetricName: "cpu", Tags: map[string]string{"host": "c"}},
+	}, result)
+}
+
+func TestCountSeries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	filter := series.NewMockFilter(ctrl)
+	metaGetter := series.NewMockMetaGetter(ctrl)
+	tagKeys := []string{"host", "zone"}
+
+	// series having both tags or existing in multiple versions are counted once
+	q, _ := sql.Parse("select f from cpu")
+	hostSeriesIDs := series.NewMultiVerSeriesIDSet()
+	hostSeriesIDs.Add(series.Version(1), roaring.BitmapOf(1, 2))
+	hostSeriesIDs.Add(series.Version(2), roaring.BitmapOf(1, 2, 3))
+	zoneSeriesIDs := series.NewMultiVerSeriesIDSet()
+	zoneSeriesIDs.Add(series.Version(1), roaring.BitmapOf(2, 5))
+	filter.EXPECT().GetSeriesIDsForTag(uint32(10), "host", gomock.Any()).Return(hostSeriesIDs, nil)
+	filter.EXPECT().GetSeriesIDsForTag(uint32(10), "zone", gomock.Any()).Return(zoneSeriesIDs, nil)
+	metaGetter.EXPECT().GetTagValues(uint32(10), []string{"host", "zone"}, series.Version(1), roaring.BitmapOf(1, 2, 5)).
+		Return(map[uint32][]string{1: {"a", ""}, 2: {"b", "sh"}, 5: {"", "bj"}}, nil)
+	// series ids of versions are allocated separately, the same series has different id in other version
+	metaGetter.EXPECT().GetTagValues(uint32(10), []string{"host", "zone"}, series.Version(2), roaring.BitmapOf(1, 2, 3)).
+		Return(map[uint32][]string{1: {"c", ""}, 2: {"a", ""}, 3: {"b", "sh"}}, nil)
+	count, err := CountSeries(10, q, filter, metaGetter, tagKeys)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), count)
+
+	// with condition
+	q, _ = sql.Parse("select f from cpu where host='1.1.1.1'")
+	seriesIDs := series.NewMultiVerSeriesIDSet()
+	seriesIDs.Add(series.Version(1), roaring.BitmapOf(1))
+	seriesIDs.Add(series.Version(2), roaring.BitmapOf(3, 4))
+	seriesIDs.Add(series.Version(3), roaring.New())
+	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).Return(seriesIDs, nil)
+	metaGetter.EXPECT().GetTagValues(uint32(10), []string{"host", "zone"}, series.Version(1), roaring.BitmapOf(1)).
+		Return(map[uint32][]string{1: {"1.1.1.1", "sh"}}, nil)
+	metaGetter.EXPECT().GetTagValues(uint32(10), []string{"host", "zone"}, series.Version(2), roaring.BitmapOf(3, 4)).
+		Return(map[uint32][]string{3: {"1.1.1.1", "sh"}, 4: {"1.1.1.1", "bj"}}, nil)
+	count, err = CountSeries(10, q, filter, metaGetter, tagKeys)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), count)
+
+	// not found
+	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).Return(nil, nil)
+	count, err = CountSeries(10, q, filter, metaGetter, tagKeys)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), count)
+
+	// search failure
+	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("err"))
+	count, err = CountSeries(10, q, filter, metaGetter, tagKeys)
+	assert.Error(t, err)
+	assert.Equal(t, uint64(0), count)
+
+	// get tag values failure
+	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).Return(seriesIDs, nil)
+	metaGetter.EXPECT().GetTagValues(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("err"))
+	count, err = CountSeries(10, q, filter, metaGetter, tagKeys)
+	assert.Error(t, err)
+	assert.Equal(t, uint64(0), count)
+}
@@ -55,49 +55,74 @@ func (s *seriesSearch) findSeriesIDsByExpr(condition stmt.Expr) (series *series.
 	}
 	switch expr := condition.(type) {
 	case stmt.TagFilter:
+		tagKey = expr.TagKey()
 		result, err := s.filter.FindSeriesIDsByExpr(s.metricID, expr, s.query.TimeRange)
 		if err != nil {
 			s.err = err
 			return
 		}
 		series = result
-		tagKey = expr.TagKey()
 	case *stmt.ParenExpr:
 		series, tagKey = s.findSeriesIDsByExpr(expr.Expr)
 	case *stmt.NotExpr:
-		// find series ids by expr => a
-		matchResult, tagKey := s.findSeriesIDsByExpr(expr.Expr)
-		if len(tagKey) > 0 {
-			// get all series ids for tag key
-			all, err := s.filter.GetSeriesIDsForTag(s.metricID, tagKey, s.query.TimeRange)
-			if err != nil {
-				s.err = err
-				return nil, tagKey
-			}
-			// do and not got series ids not in 'a' list
-			all.AndNot(matchResult)
-			series = all
-			return series, tagKey
-		}
+		series, tagKey = s.findSeriesIDsByNotExpr(expr)
 	case *stmt.BinaryExpr:
 		if expr.Operator != stmt.AND && expr.Operator != stmt.OR {
 			return series, tagKey
 		}
 		left, _ := s.findSeriesIDsByExpr(expr.Left)
-		if left == nil {
+		if left == nil && expr.Operator == stmt.AND {
 			return series, tagKey
 		}
 		right, _ := s.findSeriesIDsByExpr(expr.Right)
-		if right == nil {
-			return series, tagKey
+		if s.err != nil {
+			return nil, tagKey
 		}
-
-		if expr.Operator == stmt.AND {
+		// heterogeneous predicates(equality/regex/negation...) are combined per version by set operations
+		switch {
+		case right == nil && expr.Operator == stmt.AND:
+			return nil, tagKey
+		case right == nil:
+			series = left
+		case left == nil:
+			series = right
+		case expr.Operator == stmt.AND:
 			left.And(right)
-		} else {
+			series = left
+		default:
 			left.Or(right)
+			series = left
 		}
-		series = left
 	}
 	return series, tagKey
 }
+
+// findSeriesIDsByNotExpr finds series ids not matching the expr(!=, not in...),
+// the result is all the series of metric except the matched series,
+// so that the series without the tag key are included.
+func (s *seriesSearch) findSeriesIDsByNotExpr(expr *stmt.NotExpr) (*series.MultiVerSeriesIDSet, string) {
+	// find series ids by expr => a
+	matchResult, tagKey := s.findSeriesIDsByExpr(expr.Expr)
+	if len(tagKey) == 0 {
+		return nil, tagKey
+	}
+	if s.err == series.ErrNotFound {
+		// tag key or tag value not found, no series excluded
+		s.err = nil
+		matchResult = nil
+	}
+	if s.err != nil {
+		return nil, tagKey
+	}
+	// get all series ids for metric
+	all, err := s.filter.GetSeriesIDsForMetric(s.metricID, s.query.TimeRange)
+	if err != nil {
+		s.err = err
+		return nil, tagKey
+	}
+	// do and not got series ids not in 'a' list
+	if matchResult != nil {
+		all.AndNot(matchResult)
+	}
+	return all, tagKey
+}
@@ -78,13 +78,80 @@ func TestNotCondition(t *testing.T) {
 		FindSeriesIDsByExpr(uint32(1), &stmt.EqualsExpr{Key: "ip", Value: "1.1.1.1"}, query.TimeRange).
 		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(3, 4)), nil)
 
+	// series 5 has no tag key ip
 	mockFilter.EXPECT().
-		GetSeriesIDsForTag(uint32(1), "ip", query.TimeRange).
-		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 3, 4)), nil)
+		GetSeriesIDsForMetric(uint32(1), query.TimeRange).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 3, 4, 5)), nil)
 	search := newSeriesSearch(1, mockFilter, query)
 	resultSet, _ := search.Search()
+	assert.Equal(t, *mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 5)), *resultSet)
+
+	// not in
+	query, _ = sql.Parse("select f from cpu where region not in ('us','eu')")
+	mockFilter.EXPECT().
+		FindSeriesIDsByExpr(uint32(1), &stmt.InExpr{Key: "region", Values: []string{"us", "eu"}}, query.TimeRange).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 4)), nil)
+	mockFilter.EXPECT().
+		GetSeriesIDsForMetric(uint32(1), query.TimeRange).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 3, 4, 5)), nil)
+	resultSet, _ = newSeriesSearch(1, mockFilter, query).Search()
+	assert.Equal(t, *mockSeriesIDSet(series.Version(11), roaring.BitmapOf(2, 3, 5)), *resultSet)
+
+	// not in with empty value list, no series excluded
+	expr := &stmt.NotExpr{Expr: &stmt.InExpr{Key: "region"}}
+	mockFilter.EXPECT().
+		FindSeriesIDsByExpr(uint32(1), &stmt.InExpr{Key: "region"}, query.TimeRange).
+		Return(series.NewMultiVerSeriesIDSet(), nil)
+	mockFilter.EXPECT().
+		GetSeriesIDsForMetric(uint32(1), query.TimeRange).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2)), nil)
+	resultSet, _ = newSeriesSearch(1, mockFilter, query).findSeriesIDsByExpr(expr)
 	assert.Equal(t, *mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2)), *resultSet)
 
+	// tag key not exist, all series matched
+	query, _ = sql.Parse("select f from cpu where env!='prod'")
+	mockFilter.EXPECT().
+		FindSeriesIDsByExpr(uint32(1), &stmt.EqualsExpr{Key: "env", Value: "prod"}, query.TimeRange).
+		Return(nil, series.ErrNotFound)
+	mockFilter.EXPECT().
+		GetSeriesIDsForMetric(uint32(1), query.TimeRange).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 3)), nil)
+	resultSet, err := newSeriesSearch(1, mockFilter, query).Search()
+	assert.NoError(t, err)
+	assert.Equal(t, *mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 3)), *resultSet)
+
+	// and with positive filter
+	query, _ = sql.Parse("select f from cpu where host='a' and env!='prod'")
+	mockFilter.EXPECT().
+		FindSeriesIDsByExpr(uint32(1), &stmt.EqualsExpr{Key: "host", Value: "a"}, query.TimeRange).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 3)), nil)
+	mockFilter.EXPECT().
+		FindSeriesIDsByExpr(uint32(1), &stmt.EqualsExpr{Key: "env", Value: "prod"}, query.TimeRange).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(2, 4)), nil)
+	mockFilter.EXPECT().
+		GetSeriesIDsForMetric(uint32(1), query.TimeRange).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 3, 4, 5)), nil)
+	resultSet, err = newSeriesSearch(1, mockFilter, query).Search()
+	assert.NoError(t, err)
+	assert.Equal(t, *mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 3)), *resultSet)
+
+	// find matched series error
+	mockFilter.EXPECT().
+		FindSeriesIDsByExpr(uint32(1), &stmt.EqualsExpr{Key: "host", Value: "a"}, query.TimeRange).
+		Return(nil, errors.New("find series ids error"))
+	resultSet, err = newSeriesSearch(1, mockFilter, query).Search()
+	assert.Nil(t, resultSet)
+	assert.Error(t, err)
+	mockFilter.EXPECT().
+		FindSeriesIDsByExpr(uint32(1), &stmt.EqualsExpr{Key: "host", Value: "a"}, query.TimeRange).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 3)), nil)
+	mockFilter.EXPECT().
+		FindSeriesIDsByExpr(uint32(1), &stmt.EqualsExpr{Key: "env", Value: "prod"}, query.TimeRange).
+		Return(nil, errors.New("find series ids error"))
+	resultSet, err = newSeriesSearch(1, mockFilter, query).Search()
+	assert.Nil(t, resultSet)
+	assert.Error(t, err)
+
 	// error
 	query, _ = sql.Parse("select f from cpu where ip!='1.1.1.1'")
 	mockFilter.EXPECT().
@@ -92,10 +159,10 @@ func TestNotCondition(t *testing.T) {
 		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(3, 4)), nil)
 
 	mockFilter.EXPECT().
-		GetSeriesIDsForTag(uint32(1), "ip", query.TimeRange).
+		GetSeriesIDsForMetric(uint32(1), query.TimeRange).
 		Return(nil, errors.New("get series ids error"))
 	search = newSeriesSearch(1, mockFilter, query)
-	resultSet, err := search.Search()
+	resultSet, err = search.Search()
 	assert.Nil(t, resultSet)
 	assert.NotNil(t, err)
 }
@@ -168,8 +235,8 @@ func TestComplexCondition(t *testing.T) {
 		FindSeriesIDsByExpr(uint32(10), &stmt.InExpr{Key: "ip", Values: []string{"1.1.1.1", "2.2.2.2"}}, query.TimeRange).
 		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 4)), nil)
 	mockFilter.EXPECT().
-		GetSeriesIDsForTag(uint32(10), "ip", query.TimeRange).
-		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 3, 4, 6, 7, 8)), nil)
+		GetSeriesIDsForMetric(uint32(10), query.TimeRange).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 3, 4, 5, 6, 7, 8)), nil)
 	mockFilter.EXPECT().
 		FindSeriesIDsByExpr(uint32(10), &stmt.EqualsExpr{Key: "region", Value: "sh"}, query.TimeRange).
 		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(2, 3, 4, 7)), nil)
@@ -181,7 +248,7 @@ func TestComplexCondition(t *testing.T) {
 		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1)), nil)
 	search := newSeriesSearch(10, mockFilter, query)
 	resultSet, _ := search.Search()
-	// ip not in ('1.1.1.1','2.2.2.2') => 3,6,7,8
+	// ip not in ('1.1.1.1','2.2.2.2') => 3,5,6,7,8
 	// ip not in ('1.1.1.1','2.2.2.2') and region='sh' => 3,7
 	// path='/data' or path='/home' => 1,3,5
 	// final => 3
@@ -193,7 +260,7 @@ func TestComplexCondition(t *testing.T) {
 		FindSeriesIDsByExpr(uint32(10), &stmt.InExpr{Key: "ip", Values: []string{"1.1.1.1", "2.2.2.2"}}, query.TimeRange).
 		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 4)), nil)
 	mockFilter1.EXPECT().
-		GetSeriesIDsForTag(uint32(10), "ip", query.TimeRange).
+		GetSeriesIDsForMetric(uint32(10), query.TimeRange).
 		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 3, 4, 6, 7, 8)), nil)
 	mockFilter1.EXPECT().
 		FindSeriesIDsByExpr(uint32(10), &stmt.EqualsExpr{Key: "region", Value: "sh"}, query.TimeRange).
@@ -204,6 +271,83 @@ func TestComplexCondition(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestMultiVersionCondition(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	multiVerSet := func(ids1, ids2 *roaring.Bitmap) *series.MultiVerSeriesIDSet {
+		set := series.NewMultiVerSeriesIDSet()
+		set.Add(series.Version(1), ids1)
+		set.Add(series.Version(2), ids2)
+		return set
+	}
+	mockFilter := func() *series.MockFilter {
+		filter := series.NewMockFilter(ctrl)
+		filter.EXPECT().
+			FindSeriesIDsByExpr(uint32(1), &stmt.EqualsExpr{Key: "host", Value: "a"}, gomock.Any()).
+			Return(multiVerSet(roaring.BitmapOf(1, 2), roaring.BitmapOf(2)), nil).AnyTimes()
+		filter.EXPECT().
+			FindSeriesIDsByExpr(uint32(1), &stmt.RegexExpr{Key: "region", Regexp: "us-.*"}, gomock.Any()).
+			Return(multiVerSet(roaring.BitmapOf(1, 3), roaring.BitmapOf(1, 2)), nil).AnyTimes()
+		filter.EXPECT().
+			GetSeriesIDsForMetric(uint32(1), gomock.Any()).
+			Return(multiVerSet(roaring.BitmapOf(1, 2, 3), roaring.BitmapOf(1, 2)), nil).AnyTimes()
+		return filter
+	}
+
+	// equality and regex
+	query, _ := sql.Parse("select f from cpu where host='a' and region=~'us-.*'")
+	resultSet, err := newSeriesSearch(1, mockFilter(), query).Search()
+	assert.NoError(t, err)
+	assert.Equal(t, *multiVerSet(roaring.BitmapOf(1), roaring.BitmapOf(2)), *resultSet)
+	// equality and negation of regex
+	query, _ = sql.Parse("select f from cpu where host='a' and region!~'us-.*'")
+	resultSet, err = newSeriesSearch(1, mockFilter(), query).Search()
+	assert.NoError(t, err)
+	assert.Len(t, resultSet.Versions(), 2)
+	assert.Equal(t, []uint32{2}, resultSet.Versions()[series.Version(1)].ToArray())
+	assert.True(t, resultSet.Versions()[series.Version(2)].IsEmpty())
+	// negation of regex and equality
+	query, _ = sql.Parse("select f from cpu where region!~'us-.*' and host='a'")
+	resultSet, err = newSeriesSearch(1, mockFilter(), query).Search()
+	assert.NoError(t, err)
+	assert.Len(t, resultSet.Versions(), 2)
+	assert.Equal(t, []uint32{2}, resultSet.Versions()[series.Version(1)].ToArray())
+	assert.True(t, resultSet.Versions()[series.Version(2)].IsEmpty())
+
+	// version only exists in one side is excluded from intersection
+	filter := series.NewMockFilter(ctrl)
+	filter.EXPECT().
+		FindSeriesIDsByExpr(uint32(1), &stmt.EqualsExpr{Key: "host", Value: "a"}, gomock.Any()).
+		Return(mockSeriesIDSet(series.Version(1), roaring.BitmapOf(1, 2)), nil)
+	filter.EXPECT().
+		FindSeriesIDsByExpr(uint32(1), &stmt.RegexExpr{Key: "region", Regexp: "us-.*"}, gomock.Any()).
+		Return(multiVerSet(roaring.BitmapOf(2, 3), roaring.BitmapOf(1, 2)), nil)
+	query, _ = sql.Parse("select f from cpu where host='a' and region=~'us-.*'")
+	resultSet, err = newSeriesSearch(1, filter, query).Search()
+	assert.NoError(t, err)
+	assert.Equal(t, *mockSeriesIDSet(series.Version(1), roaring.BitmapOf(2)), *resultSet)
+
+	// or ignores the side without result
+	filter = series.NewMockFilter(ctrl)
+	filter.EXPECT().
+		FindSeriesIDsByExpr(uint32(1), &stmt.EqualsExpr{Key: "host", Value: "a"}, gomock.Any()).
+		Return(mockSeriesIDSet(series.Version(1), roaring.BitmapOf(1, 2)), nil).Times(2)
+	search := newSeriesSearch(1, filter, query)
+	result, _ := search.findSeriesIDsByExpr(&stmt.BinaryExpr{
+		Left:     &stmt.BinaryExpr{Operator: stmt.ADD},
+		Operator: stmt.OR,
+		Right:    &stmt.EqualsExpr{Key: "host", Value: "a"},
+	})
+	assert.Equal(t, *mockSeriesIDSet(series.Version(1), roaring.BitmapOf(1, 2)), *result)
+	result, _ = search.findSeriesIDsByExpr(&stmt.BinaryExpr{
+		Left:     &stmt.EqualsExpr{Key: "host", Value: "a"},
+		Operator: stmt.OR,
+		Right:    &stmt.BinaryExpr{Operator: stmt.ADD},
+	})
+	assert.Equal(t, *mockSeriesIDSet(series.Version(1), roaring.BitmapOf(1, 2)), *result)
+}
+
 func TestSeriesSearch_condition_fail(t *testing.T) {
 	search := newSeriesSearch(10, nil, nil)
 	result, _ := search.findSeriesIDsByExpr(nil)
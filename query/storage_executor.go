@@ -3,6 +3,7 @@ package query
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/lindb/lindb/aggregation"
 	"github.com/lindb/lindb/parallel"
@@ -24,11 +25,15 @@ type storageExecutor struct {
 	query    *stmt.Query
 	shardIDs []int32
 
-	shards []tsdb.Shard
+	shards            []tsdb.Shard
+	skipMissingShards bool // skips the missing shards, returns partial results instead of failing the query
+	partial           bool // some shards are missing, the results are partial
 
 	metricID uint32
 
 	fieldIDs           []uint16
+	maxFields          int            // max number of fields selected by the query, 0 means unlimited
+	location           *time.Location // location of query timezone for aligning the down sampling buckets
 	storageExecutePlan *storageExecutePlan
 	intervalType       timeutil.IntervalType
 
@@ -43,13 +48,17 @@ func newStorageExecutor(
 	database tsdb.Database,
 	shardIDs []int32,
 	query *stmt.Query,
+	maxFields int,
+	skipMissingShards bool,
 ) parallel.Executor {
 	return &storageExecutor{
-		database:     database,
-		shardIDs:     shardIDs,
-		query:        query,
-		executorPool: database.ExecutorPool(),
-		executeCtx:   ctx,
+		database:          database,
+		shardIDs:          shardIDs,
+		query:             query,
+		maxFields:         maxFields,
+		skipMissingShards: skipMissingShards,
+		executorPool:      database.ExecutorPool(),
+		executeCtx:        ctx,
 	}
 }
 
@@ -79,7 +88,21 @@ func (e *storageExecutor) Execute() {
 		e.executeCtx.Complete(err)
 		return
 	}
-
+	// check forced interval if valid
+	if err := e.checkForceInterval(); err != nil {
+		e.executeCtx.Complete(err)
+		return
+	}
+	location, err := e.query.Location()
+	if err != nil {
+		e.executeCtx.Complete(err)
+		return
+	}
+	e.location = location
+	if e.query.SeriesOnly {
+		e.seriesOnlySearch()
+		return
+	}
 	plan := newStorageExecutePlan(e.database.IDGetter(), e.query)
 	if err := plan.Plan(); err != nil {
 		e.executeCtx.Complete(err)
@@ -92,9 +115,18 @@ func (e *storageExecutor) Execute() {
 
 	e.fieldIDs = storageExecutePlan.getFieldIDs()
 	e.storageExecutePlan = storageExecutePlan
+	// check the number of selected fields if valid
+	if err := e.checkFieldCount(); err != nil {
+		e.executeCtx.Complete(err)
+		return
+	}
 
 	// need retain total memory and shard search
 	e.executeCtx.RetainTask(1)
+	if e.partial {
+		// flags the results as partial for the missing shards
+		e.executeCtx.Emit(&series.TimeSeriesEvent{Partial: true})
+	}
 	for idx := range e.shards {
 		shard := e.shards[idx]
 		// execute memory db search in background goroutine
@@ -109,33 +141,116 @@ func (e *storageExecutor) Execute() {
 	e.executeCtx.Complete(nil)
 }
 
+// seriesOnlySearch finds the tags of the series matching the condition from the memory database and the index of
+// each shard without touching the field stores. The memory database also finds the series of flushed index,
+// so the series are deduplicated by tags across all the sources, and at most limit series are found in total.
+func (e *storageExecutor) seriesOnlySearch() {
+	e.executeCtx.RetainTask(1)
+	idGetter := e.database.IDGetter()
+	metricID, err := idGetter.GetMetricID(e.query.MetricName)
+	if err != nil {
+		e.executeCtx.Complete(err)
+		return
+	}
+	tagKeys, err := idGetter.GetTagKeys(metricID)
+	if err != nil {
+		e.executeCtx.Complete(err)
+		return
+	}
+	limit := e.query.SeriesLimit()
+	var seriesList []series.GroupedIterator
+	found := make(map[string]struct{})
+	// finds limit series from each source at most, which is enough for limit distinct series after deduplication
+	find := func(filter series.Filter, metaGetter series.MetaGetter) error {
+		identities, err := FindSeriesIdentities(metricID, e.query, limit, filter, metaGetter, tagKeys)
+		if err != nil && err != series.ErrNotFound {
+			return err
+		}
+		for _, identity := range identities {
+			if limit > 0 && len(seriesList) >= limit {
+				return nil
+			}
+			key := seriesIdentityKey(tagKeys, identity.Tags)
+			if _, ok := found[key]; ok {
+				continue
+			}
+			found[key] = struct{}{}
+			seriesList = append(seriesList, series.NewGroupedIterator(identity.Tags, nil))
+		}
+		return nil
+	}
+	for _, shard := range e.shards {
+		memoryDB := shard.MemoryDatabase()
+		if err := find(memoryDB, memoryDB); err != nil {
+			e.executeCtx.Complete(err)
+			return
+		}
+		if err := find(shard.IndexFilter(), shard.IndexMetaGetter()); err != nil {
+			e.executeCtx.Complete(err)
+			return
+		}
+	}
+	e.executeCtx.Emit(&series.TimeSeriesEvent{SeriesList: seriesList, Partial: e.partial, SeriesOnly: true})
+	e.executeCtx.Complete(nil)
+}
+
 // memoryDBSearch searches data from memory database
 func (e *storageExecutor) memoryDBSearch(shard tsdb.Shard) {
 	memoryDB := shard.MemoryDatabase()
-	seriesIDSet := e.searchSeriesIDs(memoryDB)
+	// the series not in memory have no data in memory database, so the unknown staleness of them is ignored
+	seriesIDSet, _, err := e.filterStaleSeries(memoryDB, e.searchSeriesIDs(memoryDB))
+	if err != nil {
+		e.executeCtx.Complete(err)
+		return
+	}
 	if seriesIDSet == nil || seriesIDSet.IsEmpty() {
 		// if series ids not found, complete the search task
 		e.executeCtx.Complete(nil)
 		return
 	}
 
-	timeRange, intervalRatio, queryInterval := downSamplingTimeRange(e.query.Interval, memoryDB.Interval(), e.query.TimeRange)
-	aggSpecs := e.storageExecutePlan.getDownSamplingAggSpecs()
-	groupAgg := aggregation.NewGroupingAggregator(queryInterval, timeRange, aggSpecs)
+	timeRange, intervalRatio, queryInterval := downSamplingTimeRange(e.query.Interval,
+		memoryDB.MetricInterval(e.query.MetricName), e.query.TimeRange, e.location)
+	// each series is a group if query with series id, which is scanned only once from memory database
+	groupAgg := e.newGroupingAggregator(queryInterval, timeRange, e.query.WithSeriesID)
 
 	// scan data and complete task in scan worker after scan worker completed
-	worker := createScanWorker(e.executeCtx, e.metricID, e.query.GroupBy, memoryDB, groupAgg, e.executorPool)
+	worker := createScanWorker(e.executeCtx, e.metricID, e.query.GroupBy, e.query.WithSeriesID,
+		memoryDB, groupAgg, e.executorPool)
 	defer worker.Close()
 	memoryDB.Scan(&series.ScanContext{
 		MetricID:    e.metricID,
 		FieldIDs:    e.fieldIDs,
 		SeriesIDSet: seriesIDSet,
-		HasGroupBy:  e.storageExecutePlan.hasGroupBy(),
+		HasGroupBy:  e.storageExecutePlan.hasGroupBy() || e.query.WithSeriesID, // scans each time series for series id
 		Worker:      worker,
+		Context:     e.executeCtx.Context(),
 		Aggregators: e.getAggregatorPool(queryInterval, intervalRatio, timeRange),
+
+		TimeRange:     timeRange,
+		QueryInterval: queryInterval.Int64(),
 	})
 }
 
+// newGroupingAggregator creates the grouping aggregator of scan worker, all the time series are returned,
+// because the top n time series are exact only after merging the results of all the scans at the root.
+// If the time series arrive ordered by group, each group is emitted once completed instead of being held
+// until all the scans completed, so that only the aggregator of current group is held in memory.
+func (e *storageExecutor) newGroupingAggregator(
+	queryInterval timeutil.Interval,
+	timeRange timeutil.TimeRange,
+	orderedByGroup bool,
+) aggregation.GroupingAggregator {
+	aggSpecs := e.storageExecutePlan.getDownSamplingAggSpecs()
+	if orderedByGroup {
+		return aggregation.NewStreamingGroupingAggregator(queryInterval, timeRange, aggSpecs,
+			func(it series.GroupedIterator) {
+				e.executeCtx.Emit(&series.TimeSeriesEvent{SeriesList: []series.GroupedIterator{it}})
+			})
+	}
+	return aggregation.NewGroupingAggregator(queryInterval, timeRange, aggSpecs)
+}
+
 // getAggregatorPool returns aggregator pool
 func (e *storageExecutor) getAggregatorPool(
 	queryInterval timeutil.Interval,
@@ -169,6 +284,34 @@ func (e *storageExecutor) searchSeriesIDs(filter series.Filter) (seriesIDSet *se
 	return
 }
 
+// filterStaleSeries keeps the series whose latest data is older than the stale duration if query for stale series.
+// The staleness is detected from the memory database, so the series not in memory, such as the series only in
+// the flushed index, are filtered out because of unknown staleness, returns if any of them are filtered out.
+func (e *storageExecutor) filterStaleSeries(
+	memoryDB series.StaleFilter,
+	seriesIDSet *series.MultiVerSeriesIDSet,
+) (
+	staleSeriesIDSet *series.MultiVerSeriesIDSet,
+	unknown bool,
+	err error,
+) {
+	if e.query.StaleFor <= 0 || seriesIDSet == nil {
+		return seriesIDSet, false, nil
+	}
+	staleSeriesIDs, knownSeriesIDs, err := memoryDB.FindStaleSeriesIDs(e.metricID, timeutil.Now()-e.query.StaleFor)
+	if err == series.ErrNotFound {
+		// metric not in memory, the staleness of all the series is unknown
+		return nil, !seriesIDSet.IsEmpty(), nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	staleSeriesIDs.And(seriesIDSet)
+	// the remaining series are not in memory
+	seriesIDSet.AndNot(knownSeriesIDs)
+	return staleSeriesIDs, !seriesIDSet.IsEmpty(), nil
+}
+
 // shardLevelSearch searches data from shard
 func (e *storageExecutor) shardLevelSearch(shard tsdb.Shard) {
 	// find data family
@@ -178,42 +321,94 @@ func (e *storageExecutor) shardLevelSearch(shard tsdb.Shard) {
 		return
 	}
 
-	seriesIDSet := e.searchSeriesIDs(shard.IndexFilter())
+	memoryDB := shard.MemoryDatabase()
+	seriesIDSet, unknown, err := e.filterStaleSeries(memoryDB, e.searchSeriesIDs(shard.IndexFilter()))
+	if err != nil {
+		e.executeCtx.Complete(err)
+		return
+	}
+	if unknown {
+		// flags the results as partial for the series filtered out because of unknown staleness
+		e.executeCtx.Emit(&series.TimeSeriesEvent{Partial: true})
+	}
 	if seriesIDSet == nil || seriesIDSet.IsEmpty() {
 		e.executeCtx.Complete(nil)
 		return
 	}
+	// skip the fields out of retention in each family
+	now := timeutil.Now()
+	var (
+		aliveFamilies []tsdb.DataFamily
+		familyFields  [][]uint16
+	)
+	for _, family := range families {
+		fieldIDs := e.aliveFieldIDs(shard, family.TimeRange(), now)
+		if len(fieldIDs) == 0 {
+			continue
+		}
+		aliveFamilies = append(aliveFamilies, family)
+		familyFields = append(familyFields, fieldIDs)
+	}
+	if len(aliveFamilies) == 0 {
+		e.executeCtx.Complete(nil)
+		return
+	}
 	// retain family task first
-	e.executeCtx.RetainTask(int32(2 * len(families)))
-	//FIXME get interval
-	timeRange, _, queryInterval := downSamplingTimeRange(e.query.Interval, 10, e.query.TimeRange)
-	aggSpecs := e.storageExecutePlan.getDownSamplingAggSpecs()
-	groupAgg := aggregation.NewGroupingAggregator(queryInterval, timeRange, aggSpecs)
+	e.executeCtx.RetainTask(int32(2 * len(aliveFamilies)))
+	// down sampling based on the storage interval of metric, same as memory search
+	timeRange, intervalRatio, queryInterval := downSamplingTimeRange(e.query.Interval,
+		memoryDB.MetricInterval(e.query.MetricName), e.query.TimeRange, e.location)
+	// the series of each family are scanned concurrently, so the series of a group don't arrive together
+	groupAgg := e.newGroupingAggregator(queryInterval, timeRange, false)
 
 	worker := createScanWorker(
 		e.executeCtx,
 		e.metricID,
 		e.query.GroupBy,
+		e.query.WithSeriesID,
 		shard.IndexMetaGetter(),
 		groupAgg,
 		e.executorPool,
 	)
-	for _, family := range families {
-		go e.familyLevelSearch(worker, family, seriesIDSet)
+	for idx, family := range aliveFamilies {
+		go e.familyLevelSearch(worker, family, familyFields[idx], seriesIDSet, queryInterval, intervalRatio, timeRange)
+	}
+}
+
+// aliveFieldIDs returns the ids of fields whose retention has not elapsed at the end of family time range,
+// the data of expired field is kept in data family until the family is removed, but it is not read anymore.
+func (e *storageExecutor) aliveFieldIDs(shard tsdb.Shard, familyTimeRange timeutil.TimeRange, now int64) []uint16 {
+	var fieldIDs []uint16
+	for _, fieldID := range e.fieldIDs {
+		retention, ok := shard.FieldRetention(e.storageExecutePlan.fields[fieldID].FieldName())
+		if ok && familyTimeRange.End < now-retention {
+			continue
+		}
+		fieldIDs = append(fieldIDs, fieldID)
 	}
+	return fieldIDs
 }
 
-// familyLevelSearch searches data from data family, do down sampling and aggregation
-func (e *storageExecutor) familyLevelSearch(worker series.ScanWorker, family tsdb.DataFamily,
-	seriesIDSet *series.MultiVerSeriesIDSet) {
+// familyLevelSearch searches data from data family, do down sampling and aggregation,
+// the field aggregators roll up the values of storage interval same as memory search
+func (e *storageExecutor) familyLevelSearch(worker series.ScanWorker, family tsdb.DataFamily, fieldIDs []uint16,
+	seriesIDSet *series.MultiVerSeriesIDSet,
+	queryInterval timeutil.Interval, intervalRatio int, timeRange timeutil.TimeRange,
+) {
 	// must complete task
 	defer e.executeCtx.Complete(nil)
 
 	family.Scan(&series.ScanContext{
 		MetricID:    e.metricID,
-		FieldIDs:    e.fieldIDs,
+		FieldIDs:    fieldIDs,
 		SeriesIDSet: seriesIDSet,
+		HasGroupBy:  e.storageExecutePlan.hasGroupBy() || e.query.WithSeriesID,
 		Worker:      worker,
+		Context:     e.executeCtx.Context(),
+		Aggregators: e.getAggregatorPool(queryInterval, intervalRatio, timeRange),
+
+		TimeRange:     timeRange,
+		QueryInterval: queryInterval.Int64(),
 	})
 }
 
@@ -228,7 +423,7 @@ func (e *storageExecutor) validation() error {
 	if numOfShards == 0 {
 		return fmt.Errorf("tsdb database[%s] hasn't shard", e.database.Name())
 	}
-	if numOfShards != len(e.shardIDs) {
+	if numOfShards != len(e.shardIDs) && !e.skipMissingShards {
 		return fmt.Errorf("storage's num. of shard not match search condition")
 	}
 	return nil
@@ -242,7 +437,33 @@ func (e *storageExecutor) checkShards() error {
 	}
 	numOfShardIDs := len(e.shardIDs)
 	if numOfShards != numOfShardIDs {
+		if e.skipMissingShards {
+			e.partial = true
+			return nil
+		}
 		return fmt.Errorf("got shard size[%d] not eqauls input shard size[%d]", numOfShards, numOfShardIDs)
 	}
 	return nil
 }
+
+// checkFieldCount checks the number of selected fields if exceeds the limit per query
+func (e *storageExecutor) checkFieldCount() error {
+	if e.maxFields <= 0 || len(e.fieldIDs) <= e.maxFields {
+		return nil
+	}
+	return fmt.Errorf("query selects too many fields[%d], exceeds the limit[%d] per query",
+		len(e.fieldIDs), e.maxFields)
+}
+
+// checkForceInterval checks the forced aggregation interval if multiple of storage interval
+func (e *storageExecutor) checkForceInterval() error {
+	if e.query.ForceInterval <= 0 {
+		return nil
+	}
+	storageInterval := e.shards[0].MemoryDatabase().MetricInterval(e.query.MetricName)
+	if storageInterval <= 0 || e.query.ForceInterval%storageInterval != 0 {
+		return fmt.Errorf("force interval[%d] must be multiple of storage interval[%d]",
+			e.query.ForceInterval, storageInterval)
+	}
+	return nil
+}
@@ -1,16 +1,23 @@
 package query
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/RoaringBitmap/roaring"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
 
+	"github.com/lindb/lindb/config"
 	"github.com/lindb/lindb/parallel"
+	"github.com/lindb/lindb/pkg/fileutil"
+	"github.com/lindb/lindb/pkg/option"
 	"github.com/lindb/lindb/pkg/timeutil"
+	pb "github.com/lindb/lindb/rpc/proto/field"
 	"github.com/lindb/lindb/series"
 	"github.com/lindb/lindb/series/field"
 	"github.com/lindb/lindb/sql"
@@ -27,6 +34,7 @@ func TestStorageExecute_validation(t *testing.T) {
 	exeCtx := parallel.NewMockExecuteContext(ctrl)
 	exeCtx.EXPECT().Complete(gomock.Any()).AnyTimes()
 	exeCtx.EXPECT().RetainTask(gomock.Any()).AnyTimes()
+	exeCtx.EXPECT().Context().Return(context.TODO()).AnyTimes()
 
 	mockDatabase := tsdb.NewMockDatabase(ctrl)
 	mockDatabase.EXPECT().ExecutorPool().Return(execPool).AnyTimes()
@@ -34,22 +42,22 @@ func TestStorageExecute_validation(t *testing.T) {
 	query := &stmt.Query{Interval: timeutil.OneSecond}
 
 	// query shards is empty
-	exec := newStorageExecutor(exeCtx, mockDatabase, nil, query)
+	exec := newStorageExecutor(exeCtx, mockDatabase, nil, query, 0, false)
 	exec.Execute()
 
 	// shards of engine is empty
 	mockDatabase.EXPECT().NumOfShards().Return(0)
-	exec = newStorageExecutor(exeCtx, mockDatabase, []int32{1, 2, 3}, query)
+	exec = newStorageExecutor(exeCtx, mockDatabase, []int32{1, 2, 3}, query, 0, false)
 	exec.Execute()
 
 	// num. of shard not match
 	mockDatabase.EXPECT().NumOfShards().Return(2)
-	exec = newStorageExecutor(exeCtx, mockDatabase, []int32{1, 2, 3}, query)
+	exec = newStorageExecutor(exeCtx, mockDatabase, []int32{1, 2, 3}, query, 0, false)
 	exec.Execute()
 
 	mockDatabase.EXPECT().NumOfShards().Return(3).AnyTimes()
 	mockDatabase.EXPECT().GetShard(gomock.Any()).Return(nil, false).MaxTimes(3)
-	exec = newStorageExecutor(exeCtx, mockDatabase, []int32{1, 2, 3}, query)
+	exec = newStorageExecutor(exeCtx, mockDatabase, []int32{1, 2, 3}, query, 0, false)
 	exec.Execute()
 
 	// normal case
@@ -57,7 +65,7 @@ func TestStorageExecute_validation(t *testing.T) {
 	mockDB1 := newMockDatabase(ctrl)
 	mockDB1.EXPECT().ExecutorPool().Return(execPool)
 
-	exec = newStorageExecutor(exeCtx, mockDB1, []int32{1, 2, 3}, query)
+	exec = newStorageExecutor(exeCtx, mockDB1, []int32{1, 2, 3}, query, 0, false)
 	exec.Execute()
 }
 
@@ -79,7 +87,7 @@ func TestStorageExecute_Plan_Fail(t *testing.T) {
 
 	// find metric name err
 	query, _ := sql.Parse("select f from cpu where time>'20190729 11:00:00' and time<'20190729 12:00:00'")
-	exec := newStorageExecutor(exeCtx, mockDatabase, []int32{1, 2, 3}, query)
+	exec := newStorageExecutor(exeCtx, mockDatabase, []int32{1, 2, 3}, query, 0, false)
 	exec.Execute()
 }
 
@@ -90,6 +98,7 @@ func TestStorageExecute_Execute(t *testing.T) {
 	exeCtx := parallel.NewMockExecuteContext(ctrl)
 	exeCtx.EXPECT().Complete(gomock.Any()).AnyTimes()
 	exeCtx.EXPECT().RetainTask(gomock.Any()).AnyTimes()
+	exeCtx.EXPECT().Context().Return(context.TODO()).AnyTimes()
 
 	mockDatabase := tsdb.NewMockDatabase(ctrl)
 	mockDatabase.EXPECT().ExecutorPool().Return(execPool).AnyTimes()
@@ -98,7 +107,9 @@ func TestStorageExecute_Execute(t *testing.T) {
 	family := tsdb.NewMockDataFamily(ctrl)
 	filter := series.NewMockFilter(ctrl)
 	memDB := memdb.NewMockMemoryDatabase(ctrl)
-	memDB.EXPECT().Interval().Return(int64(10)).AnyTimes()
+	memDB.EXPECT().MetricInterval(gomock.Any()).Return(int64(10)).AnyTimes()
+
+	query, _ := sql.Parse("select f from cpu where host='1.1.1.1' and time>'20190729 11:00:00' and time<'20190729 12:00:00'")
 
 	// mock data
 	mockDatabase.EXPECT().NumOfShards().Return(3)
@@ -109,7 +120,10 @@ func TestStorageExecute_Execute(t *testing.T) {
 	idGetter.EXPECT().GetMetricID("cpu").Return(uint32(10), nil)
 	idGetter.EXPECT().GetFieldID(uint32(10), "f").Return(uint16(10), field.SumField, nil)
 	shard.EXPECT().GetDataFamilies(gomock.Any(), gomock.Any()).Return([]tsdb.DataFamily{family, family}).MaxTimes(3)
-	shard.EXPECT().MemoryDatabase().Return(memDB).MaxTimes(3)
+	shard.EXPECT().FieldRetention("f").Return(int64(0), false).AnyTimes()
+	family.EXPECT().TimeRange().Return(query.TimeRange).AnyTimes()
+	// memory database of shard is used for memory search and getting storage interval of family search
+	shard.EXPECT().MemoryDatabase().Return(memDB).MaxTimes(2 * 3)
 	shard.EXPECT().IndexFilter().Return(filter).MaxTimes(3)
 	shard.EXPECT().IndexMetaGetter().Return(nil).MaxTimes(3)
 	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).
@@ -120,11 +134,14 @@ func TestStorageExecute_Execute(t *testing.T) {
 	memDB.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).
 		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 4)), nil).MaxTimes(3)
 	memDB.EXPECT().Scan(gomock.Any()).MaxTimes(3)
-	family.EXPECT().Scan(gomock.Any()).MaxTimes(2 * 3)
+	family.EXPECT().Scan(gomock.Any()).Do(func(sCtx *series.ScanContext) {
+		// down sampling based on storage interval of metric
+		assert.Equal(t, int64(10), sCtx.QueryInterval)
+		assert.NotNil(t, sCtx.Aggregators.Get())
+	}).MaxTimes(2 * 3)
 
 	// normal case
-	query, _ := sql.Parse("select f from cpu where host='1.1.1.1' and time>'20190729 11:00:00' and time<'20190729 12:00:00'")
-	exec := newStorageExecutor(exeCtx, mockDatabase, []int32{1, 2, 3}, query)
+	exec := newStorageExecutor(exeCtx, mockDatabase, []int32{1, 2, 3}, query, 0, false)
 	exec.Execute()
 	time.Sleep(100 * time.Millisecond)
 	e := exec.(*storageExecutor)
@@ -139,17 +156,104 @@ func TestStorageExecute_Execute(t *testing.T) {
 	idGetter.EXPECT().GetMetricID("cpu").Return(uint32(10), nil)
 	idGetter.EXPECT().GetFieldID(uint32(10), "f").Return(uint16(10), field.SumField, nil)
 	shard.EXPECT().GetDataFamilies(gomock.Any(), gomock.Any()).Return([]tsdb.DataFamily{family, family})
-	shard.EXPECT().MemoryDatabase().Return(memDB)
+	shard.EXPECT().MemoryDatabase().Return(memDB).AnyTimes()
 	shard.EXPECT().IndexFilter().Return(filter)
 	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).
 		Return(nil, fmt.Errorf("err"))
 	memDB.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).
 		Return(nil, series.ErrNotFound)
-	exec = newStorageExecutor(exeCtx, mockDatabase, []int32{1}, query)
+	exec = newStorageExecutor(exeCtx, mockDatabase, []int32{1}, query, 0, false)
 	exec.Execute()
 	time.Sleep(100 * time.Millisecond)
 }
 
+func TestStorageExecute_withSeriesID(t *testing.T) {
+	testPath := "test_data_with_series_id"
+	defer func() {
+		_ = fileutil.RemoveDir(testPath)
+	}()
+	engine, err := tsdb.NewEngine(config.TSDB{Dir: testPath})
+	assert.NoError(t, err)
+	defer engine.Close()
+	db, err := engine.CreateDatabase("test_db")
+	assert.NoError(t, err)
+	assert.NoError(t, db.CreateShards(option.DatabaseOption{Interval: "10s"}, 1))
+	shard, _ := db.GetShard(1)
+
+	numOfSeries := 100
+	now := timeutil.Now()
+	for i := 0; i < numOfSeries; i++ {
+		assert.NoError(t, shard.Write(&pb.Metric{
+			Name:      "cpu",
+			Timestamp: now,
+			Tags:      map[string]string{"host": fmt.Sprintf("host-%d", i)},
+			Fields:    []*pb.Field{{Name: "f", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}}},
+		}))
+	}
+	query, err := sql.Parse("select f from cpu where host=~'host-.*' and time>now()-1h")
+	assert.NoError(t, err)
+	query.WithSeriesID = true
+
+	exeCtx := newCollectExecuteContext()
+	exec := newStorageExecutor(exeCtx, db, []int32{1}, query, 0, false)
+	exec.Execute()
+	select {
+	case <-exeCtx.done:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "storage execution not completed")
+		return
+	}
+	assert.NoError(t, exeCtx.err)
+	// each series is emitted once aggregated, the grouping aggregator holds one series at most
+	assert.Len(t, exeCtx.emits, numOfSeries)
+	seriesIDs := make(map[string]struct{})
+	for _, seriesList := range exeCtx.emits {
+		assert.Len(t, seriesList, 1)
+		seriesIDs[seriesList[0].Tags()[SeriesIDTagKey]] = struct{}{}
+	}
+	assert.Len(t, seriesIDs, numOfSeries)
+}
+
+// collectExecuteContext collects the emitted time series until all the tasks completed
+type collectExecuteContext struct {
+	tasks atomic.Int32
+	done  chan struct{}
+	emits [][]series.GroupedIterator
+	err   error
+	mutex sync.Mutex
+}
+
+func newCollectExecuteContext() *collectExecuteContext {
+	return &collectExecuteContext{done: make(chan struct{})}
+}
+
+func (c *collectExecuteContext) RetainTask(tasks int32) {
+	c.tasks.Add(tasks)
+}
+
+func (c *collectExecuteContext) Emit(event *series.TimeSeriesEvent) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(event.SeriesList) > 0 {
+		c.emits = append(c.emits, event.SeriesList)
+	}
+}
+
+func (c *collectExecuteContext) Complete(err error) {
+	c.mutex.Lock()
+	if err != nil {
+		c.err = err
+	}
+	c.mutex.Unlock()
+	if c.tasks.Dec() == 0 {
+		close(c.done)
+	}
+}
+
+func (c *collectExecuteContext) Context() context.Context {
+	return context.TODO()
+}
+
 func TestStorageExecutor_checkShards(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -157,11 +261,12 @@ func TestStorageExecutor_checkShards(t *testing.T) {
 	exeCtx := parallel.NewMockExecuteContext(ctrl)
 	exeCtx.EXPECT().Complete(gomock.Any()).AnyTimes()
 	exeCtx.EXPECT().RetainTask(gomock.Any()).AnyTimes()
+	exeCtx.EXPECT().Context().Return(context.TODO()).AnyTimes()
 
 	mockDatabase := newMockDatabase(ctrl)
 	mockDatabase.EXPECT().ExecutorPool().Return(execPool).AnyTimes()
 	query, _ := sql.Parse("select f from cpu where time>'20190729 11:00:00' and time<'20190729 12:00:00'")
-	exec := newStorageExecutor(exeCtx, mockDatabase, []int32{1, 2, 3}, query)
+	exec := newStorageExecutor(exeCtx, mockDatabase, []int32{1, 2, 3}, query, 0, false)
 	exec.Execute()
 
 	execImpl := exec.(*storageExecutor)
@@ -169,3 +274,364 @@ func TestStorageExecutor_checkShards(t *testing.T) {
 	execImpl.shardIDs = nil
 	assert.NotNil(t, execImpl.checkShards())
 }
+
+func TestStorageExecute_missingShard(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	shard := tsdb.NewMockShard(ctrl)
+	memDB := memdb.NewMockMemoryDatabase(ctrl)
+	memDB.EXPECT().MetricInterval(gomock.Any()).Return(int64(10)).AnyTimes()
+	memDB.EXPECT().Scan(gomock.Any()).AnyTimes()
+	shard.EXPECT().MemoryDatabase().Return(memDB).AnyTimes()
+	shard.EXPECT().GetDataFamilies(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	idGetter := metadb.NewMockIDGetter(ctrl)
+	idGetter.EXPECT().GetMetricID("cpu").Return(uint32(10), nil).AnyTimes()
+	idGetter.EXPECT().GetFieldID(uint32(10), "f").Return(uint16(10), field.SumField, nil).AnyTimes()
+	mockDatabase := tsdb.NewMockDatabase(ctrl)
+	mockDatabase.EXPECT().ExecutorPool().Return(execPool).AnyTimes()
+	mockDatabase.EXPECT().IDGetter().Return(idGetter).AnyTimes()
+	// shard 2 is missing during rebalancing
+	mockDatabase.EXPECT().NumOfShards().Return(1).AnyTimes()
+	mockDatabase.EXPECT().GetShard(int32(1)).Return(shard, true).AnyTimes()
+	mockDatabase.EXPECT().GetShard(int32(2)).Return(nil, false).AnyTimes()
+	query, _ := sql.Parse("select f from cpu")
+
+	// fail fast
+	exeCtx := parallel.NewMockExecuteContext(ctrl)
+	exeCtx.EXPECT().Complete(gomock.Not(gomock.Nil()))
+	exec := newStorageExecutor(exeCtx, mockDatabase, []int32{1, 2}, query, 0, false)
+	exec.Execute()
+	assert.False(t, exec.(*storageExecutor).partial)
+
+	// skip missing shard, results are flagged as partial
+	exeCtx = parallel.NewMockExecuteContext(ctrl)
+	exeCtx.EXPECT().RetainTask(gomock.Any()).AnyTimes()
+	exeCtx.EXPECT().Emit(&series.TimeSeriesEvent{Partial: true})
+	exeCtx.EXPECT().Complete(nil).AnyTimes()
+	exec = newStorageExecutor(exeCtx, mockDatabase, []int32{1, 2}, query, 0, true)
+	exec.Execute()
+	time.Sleep(100 * time.Millisecond)
+	execImpl := exec.(*storageExecutor)
+	assert.True(t, execImpl.partial)
+	assert.Equal(t, []tsdb.Shard{shard}, execImpl.shards)
+
+	// all shards missing
+	exeCtx = parallel.NewMockExecuteContext(ctrl)
+	exeCtx.EXPECT().Complete(gomock.Not(gomock.Nil()))
+	exec = newStorageExecutor(exeCtx, mockDatabase, []int32{2}, query, 0, true)
+	exec.Execute()
+}
+
+func TestStorageExecute_staleSeries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exeCtx := parallel.NewMockExecuteContext(ctrl)
+	exeCtx.EXPECT().Complete(gomock.Any()).AnyTimes()
+	exeCtx.EXPECT().RetainTask(gomock.Any()).AnyTimes()
+	exeCtx.EXPECT().Context().Return(context.TODO()).AnyTimes()
+
+	mockDatabase := tsdb.NewMockDatabase(ctrl)
+	mockDatabase.EXPECT().ExecutorPool().Return(execPool).AnyTimes()
+	mockDatabase.EXPECT().NumOfShards().Return(1).AnyTimes()
+	shard := tsdb.NewMockShard(ctrl)
+	mockDatabase.EXPECT().GetShard(int32(1)).Return(shard, true).AnyTimes()
+	idGetter := metadb.NewMockIDGetter(ctrl)
+	mockDatabase.EXPECT().IDGetter().Return(idGetter).AnyTimes()
+	idGetter.EXPECT().GetMetricID("cpu").Return(uint32(10), nil).AnyTimes()
+	idGetter.EXPECT().GetFieldID(uint32(10), "f").Return(uint16(10), field.SumField, nil).AnyTimes()
+	family := tsdb.NewMockDataFamily(ctrl)
+	filter := series.NewMockFilter(ctrl)
+	memDB := memdb.NewMockMemoryDatabase(ctrl)
+	memDB.EXPECT().MetricInterval(gomock.Any()).Return(int64(10)).AnyTimes()
+	shard.EXPECT().MemoryDatabase().Return(memDB).AnyTimes()
+	shard.EXPECT().IndexFilter().Return(filter).AnyTimes()
+	shard.EXPECT().IndexMetaGetter().Return(nil).AnyTimes()
+	shard.EXPECT().GetDataFamilies(gomock.Any(), gomock.Any()).Return([]tsdb.DataFamily{family}).AnyTimes()
+	shard.EXPECT().FieldRetention("f").Return(int64(0), false).AnyTimes()
+
+	query, _ := sql.Parse("select f from cpu where host='1.1.1.1'")
+	stmt.QueryOptions{StaleFor: 5 * timeutil.OneMinute}.Apply(query)
+	family.EXPECT().TimeRange().Return(query.TimeRange).AnyTimes()
+
+	// only the stale series are scanned from memory database and data family,
+	// series 4 only in the flushed index is filtered out because of unknown staleness, flags the results as partial
+	now := timeutil.Now()
+	memDB.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 4)), nil)
+	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 4)), nil)
+	memDB.EXPECT().FindStaleSeriesIDs(uint32(10), gomock.Any()).DoAndReturn(
+		func(metricID uint32, threshold int64) (stale, known *series.MultiVerSeriesIDSet, err error) {
+			assert.True(t, threshold >= now-5*timeutil.OneMinute)
+			return mockSeriesIDSet(series.Version(11), roaring.BitmapOf(2, 3)),
+				mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 3)), nil
+		}).Times(2)
+	exeCtx.EXPECT().Emit(&series.TimeSeriesEvent{Partial: true})
+	var wait sync.WaitGroup
+	wait.Add(2)
+	memDB.EXPECT().Scan(gomock.Any()).Do(func(sCtx *series.ScanContext) {
+		defer wait.Done()
+		assert.Equal(t, mockSeriesIDSet(series.Version(11), roaring.BitmapOf(2)), sCtx.SeriesIDSet)
+	})
+	family.EXPECT().Scan(gomock.Any()).Do(func(sCtx *series.ScanContext) {
+		defer wait.Done()
+		assert.Equal(t, mockSeriesIDSet(series.Version(11), roaring.BitmapOf(2)), sCtx.SeriesIDSet)
+	})
+	exec := newStorageExecutor(exeCtx, mockDatabase, []int32{1}, query, 0, false)
+	exec.Execute()
+	wait.Wait()
+
+	// metric not in memory database, the staleness of all the series is unknown
+	memDB.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 4)), nil)
+	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 4)), nil)
+	memDB.EXPECT().FindStaleSeriesIDs(uint32(10), gomock.Any()).Return(nil, nil, series.ErrNotFound).Times(2)
+	exeCtx.EXPECT().Emit(&series.TimeSeriesEvent{Partial: true})
+	exec = newStorageExecutor(exeCtx, mockDatabase, []int32{1}, query, 0, false)
+	exec.Execute()
+	time.Sleep(100 * time.Millisecond)
+
+	// find stale series failure
+	memDB.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 4)), nil)
+	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 4)), nil)
+	memDB.EXPECT().FindStaleSeriesIDs(uint32(10), gomock.Any()).Return(nil, nil, fmt.Errorf("err")).Times(2)
+	exec = newStorageExecutor(exeCtx, mockDatabase, []int32{1}, query, 0, false)
+	exec.Execute()
+	time.Sleep(100 * time.Millisecond)
+}
+
+func TestStorageExecute_fieldRetention(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exeCtx := parallel.NewMockExecuteContext(ctrl)
+	exeCtx.EXPECT().Complete(gomock.Any()).AnyTimes()
+	exeCtx.EXPECT().Context().Return(context.TODO()).AnyTimes()
+	// memory search task
+	exeCtx.EXPECT().RetainTask(int32(1)).AnyTimes()
+
+	mockDatabase := tsdb.NewMockDatabase(ctrl)
+	mockDatabase.EXPECT().ExecutorPool().Return(execPool).AnyTimes()
+	mockDatabase.EXPECT().NumOfShards().Return(1).AnyTimes()
+	shard := tsdb.NewMockShard(ctrl)
+	mockDatabase.EXPECT().GetShard(int32(1)).Return(shard, true).AnyTimes()
+	idGetter := metadb.NewMockIDGetter(ctrl)
+	mockDatabase.EXPECT().IDGetter().Return(idGetter).AnyTimes()
+	idGetter.EXPECT().GetMetricID("cpu").Return(uint32(10), nil).AnyTimes()
+	idGetter.EXPECT().GetFieldID(uint32(10), "f").Return(uint16(10), field.SumField, nil).AnyTimes()
+	idGetter.EXPECT().GetFieldID(uint32(10), "g").Return(uint16(11), field.SumField, nil).AnyTimes()
+	filter := series.NewMockFilter(ctrl)
+	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1)), nil).AnyTimes()
+	memDB := memdb.NewMockMemoryDatabase(ctrl)
+	memDB.EXPECT().MetricInterval(gomock.Any()).Return(int64(10)).AnyTimes()
+	memDB.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).Return(nil, series.ErrNotFound).AnyTimes()
+	shard.EXPECT().MemoryDatabase().Return(memDB).AnyTimes()
+	shard.EXPECT().IndexFilter().Return(filter).AnyTimes()
+	shard.EXPECT().IndexMetaGetter().Return(nil).AnyTimes()
+	// field f is kept for 1 hour
+	shard.EXPECT().FieldRetention("f").Return(timeutil.OneHour, true).AnyTimes()
+	shard.EXPECT().FieldRetention("g").Return(int64(0), false).AnyTimes()
+
+	now := timeutil.Now()
+	oldFamily := tsdb.NewMockDataFamily(ctrl)
+	oldFamily.EXPECT().TimeRange().Return(timeutil.TimeRange{Start: now - 3*timeutil.OneHour, End: now - 2*timeutil.OneHour}).
+		AnyTimes()
+	newFamily := tsdb.NewMockDataFamily(ctrl)
+	newFamily.EXPECT().TimeRange().Return(timeutil.TimeRange{Start: now - timeutil.OneHour, End: now}).AnyTimes()
+	shard.EXPECT().GetDataFamilies(gomock.Any(), gomock.Any()).Return([]tsdb.DataFamily{oldFamily, newFamily}).AnyTimes()
+
+	// the expired field is not read from the old family
+	query, _ := sql.Parse("select f,g from cpu where host='1.1.1.1'")
+	var wait sync.WaitGroup
+	wait.Add(2)
+	exeCtx.EXPECT().RetainTask(int32(4))
+	oldFamily.EXPECT().Scan(gomock.Any()).Do(func(sCtx *series.ScanContext) {
+		defer wait.Done()
+		assert.Equal(t, []uint16{11}, sCtx.FieldIDs)
+	})
+	newFamily.EXPECT().Scan(gomock.Any()).Do(func(sCtx *series.ScanContext) {
+		defer wait.Done()
+		assert.Equal(t, []uint16{10, 11}, sCtx.FieldIDs)
+	})
+	exec := newStorageExecutor(exeCtx, mockDatabase, []int32{1}, query, 0, false)
+	exec.Execute()
+	wait.Wait()
+
+	// the old family is skipped if all the fields are expired
+	query, _ = sql.Parse("select f from cpu where host='1.1.1.1'")
+	wait.Add(1)
+	exeCtx.EXPECT().RetainTask(int32(2))
+	newFamily.EXPECT().Scan(gomock.Any()).Do(func(sCtx *series.ScanContext) {
+		defer wait.Done()
+		assert.Equal(t, []uint16{10}, sCtx.FieldIDs)
+	})
+	exec = newStorageExecutor(exeCtx, mockDatabase, []int32{1}, query, 0, false)
+	exec.Execute()
+	wait.Wait()
+}
+
+func TestStorageExecute_seriesOnly(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exeCtx := parallel.NewMockExecuteContext(ctrl)
+	exeCtx.EXPECT().RetainTask(gomock.Any()).AnyTimes()
+
+	mockDatabase := tsdb.NewMockDatabase(ctrl)
+	mockDatabase.EXPECT().ExecutorPool().Return(execPool).AnyTimes()
+	mockDatabase.EXPECT().NumOfShards().Return(1).AnyTimes()
+	shard := tsdb.NewMockShard(ctrl)
+	mockDatabase.EXPECT().GetShard(int32(1)).Return(shard, true).AnyTimes()
+	idGetter := metadb.NewMockIDGetter(ctrl)
+	mockDatabase.EXPECT().IDGetter().Return(idGetter).AnyTimes()
+	filter := series.NewMockFilter(ctrl)
+	metaGetter := series.NewMockMetaGetter(ctrl)
+	memDB := memdb.NewMockMemoryDatabase(ctrl)
+	shard.EXPECT().MemoryDatabase().Return(memDB).AnyTimes()
+	shard.EXPECT().IndexFilter().Return(filter).AnyTimes()
+	shard.EXPECT().IndexMetaGetter().Return(metaGetter).AnyTimes()
+
+	query, _ := sql.Parse("select f from cpu where host='1.1.1.1'")
+	stmt.QueryOptions{SeriesOnly: true}.Apply(query)
+
+	// the tags of series are found from memory database and index without scanning the field data
+	idGetter.EXPECT().GetMetricID("cpu").Return(uint32(10), nil)
+	idGetter.EXPECT().GetTagKeys(uint32(10)).Return([]string{"host"}, nil)
+	memDB.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).
+		Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1)), nil)
+	memDB.EXPECT().GetTagValues(uint32(10), []string{"host"}, series.Version(11), roaring.BitmapOf(1)).
+		Return(map[uint32][]string{1: {"1.1.1.1"}}, nil)
+	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).Return(nil, series.ErrNotFound)
+	exeCtx.EXPECT().Emit(gomock.Any()).Do(func(event *series.TimeSeriesEvent) {
+		assert.True(t, event.SeriesOnly)
+		assert.Len(t, event.SeriesList, 1)
+		assert.Equal(t, map[string]string{"host": "1.1.1.1"}, event.SeriesList[0].Tags())
+	})
+	exeCtx.EXPECT().Complete(nil)
+	exec := newStorageExecutor(exeCtx, mockDatabase, []int32{1}, query, 0, false)
+	exec.Execute()
+
+	// the flushed series are found from both memory database and index, deduplicated by tags
+	flushedAndMemory := func(limit int, expected ...string) {
+		query, _ := sql.Parse(fmt.Sprintf("select f from cpu where host=~'1.1.1.*' limit %d", limit))
+		stmt.QueryOptions{SeriesOnly: true}.Apply(query)
+		idGetter.EXPECT().GetMetricID("cpu").Return(uint32(10), nil)
+		idGetter.EXPECT().GetTagKeys(uint32(10)).Return([]string{"host"}, nil)
+		// series 1 is evicted from memory, series 2 is flushed and still in memory, series 3 is only in memory
+		memDB.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).
+			Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2, 3)), nil)
+		memDB.EXPECT().GetTagValues(uint32(10), []string{"host"}, series.Version(11), gomock.Any()).
+			Return(map[uint32][]string{2: {"1.1.1.2"}, 3: {"1.1.1.3"}}, nil)
+		filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).
+			Return(mockSeriesIDSet(series.Version(11), roaring.BitmapOf(1, 2)), nil)
+		metaGetter.EXPECT().GetTagValues(uint32(10), []string{"host"}, series.Version(11), gomock.Any()).
+			Return(map[uint32][]string{1: {"1.1.1.1"}, 2: {"1.1.1.2"}}, nil)
+		exeCtx.EXPECT().Emit(gomock.Any()).Do(func(event *series.TimeSeriesEvent) {
+			var hosts []string
+			for _, it := range event.SeriesList {
+				hosts = append(hosts, it.Tags()["host"])
+			}
+			assert.Equal(t, expected, hosts)
+		})
+		exeCtx.EXPECT().Complete(nil)
+		exec := newStorageExecutor(exeCtx, mockDatabase, []int32{1}, query, 0, false)
+		exec.Execute()
+	}
+	flushedAndMemory(10, "1.1.1.2", "1.1.1.3", "1.1.1.1")
+	// the limit is applied to all the sources in total
+	flushedAndMemory(3, "1.1.1.2", "1.1.1.3", "1.1.1.1")
+	flushedAndMemory(2, "1.1.1.2", "1.1.1.1")
+
+	// find series failure
+	idGetter.EXPECT().GetMetricID("cpu").Return(uint32(10), nil)
+	idGetter.EXPECT().GetTagKeys(uint32(10)).Return([]string{"host"}, nil)
+	memDB.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).Return(nil, nil)
+	filter.EXPECT().FindSeriesIDsByExpr(uint32(10), gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("err"))
+	exeCtx.EXPECT().Complete(gomock.Not(gomock.Nil()))
+	exec = newStorageExecutor(exeCtx, mockDatabase, []int32{1}, query, 0, false)
+	exec.Execute()
+
+	// get tag keys failure
+	idGetter.EXPECT().GetMetricID("cpu").Return(uint32(10), nil)
+	idGetter.EXPECT().GetTagKeys(uint32(10)).Return(nil, fmt.Errorf("err"))
+	exeCtx.EXPECT().Complete(gomock.Not(gomock.Nil()))
+	exec = newStorageExecutor(exeCtx, mockDatabase, []int32{1}, query, 0, false)
+	exec.Execute()
+}
+
+func TestStorageExecutor_checkForceInterval(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	memDB := memdb.NewMockMemoryDatabase(ctrl)
+	memDB.EXPECT().MetricInterval(gomock.Any()).Return(10 * timeutil.OneSecond).AnyTimes()
+	shard := tsdb.NewMockShard(ctrl)
+	shard.EXPECT().MemoryDatabase().Return(memDB).AnyTimes()
+	exec := &storageExecutor{shards: []tsdb.Shard{shard}, query: &stmt.Query{}}
+
+	// not forced
+	assert.Nil(t, exec.checkForceInterval())
+	// multiple of storage interval
+	exec.query.ForceInterval = 10 * timeutil.OneSecond
+	assert.Nil(t, exec.checkForceInterval())
+	exec.query.ForceInterval = timeutil.OneMinute
+	assert.Nil(t, exec.checkForceInterval())
+	// invalid multiple
+	exec.query.ForceInterval = 15 * timeutil.OneSecond
+	assert.NotNil(t, exec.checkForceInterval())
+	exec.query.ForceInterval = 5 * timeutil.OneSecond
+	assert.NotNil(t, exec.checkForceInterval())
+
+	// coarse metric with per-metric interval
+	coarseMemDB := memdb.NewMockMemoryDatabase(ctrl)
+	coarseMemDB.EXPECT().MetricInterval("disk").Return(timeutil.OneMinute).AnyTimes()
+	coarseShard := tsdb.NewMockShard(ctrl)
+	coarseShard.EXPECT().MemoryDatabase().Return(coarseMemDB).AnyTimes()
+	exec = &storageExecutor{shards: []tsdb.Shard{coarseShard}, query: &stmt.Query{MetricName: "disk"}}
+	exec.query.ForceInterval = 10 * timeutil.OneSecond
+	assert.NotNil(t, exec.checkForceInterval())
+	exec.query.ForceInterval = 2 * timeutil.OneMinute
+	assert.Nil(t, exec.checkForceInterval())
+}
+
+func TestStorageExecute_Execute_tooManyFields(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDatabase := tsdb.NewMockDatabase(ctrl)
+	mockDatabase.EXPECT().ExecutorPool().Return(execPool).AnyTimes()
+	shard := tsdb.NewMockShard(ctrl)
+	mockDatabase.EXPECT().NumOfShards().Return(1)
+	mockDatabase.EXPECT().GetShard(int32(1)).Return(shard, true)
+	idGetter := metadb.NewMockIDGetter(ctrl)
+	mockDatabase.EXPECT().IDGetter().Return(idGetter)
+	idGetter.EXPECT().GetMetricID("cpu").Return(uint32(10), nil)
+	idGetter.EXPECT().GetFieldID(uint32(10), "f1").Return(uint16(1), field.SumField, nil)
+	idGetter.EXPECT().GetFieldID(uint32(10), "f2").Return(uint16(2), field.SumField, nil)
+	idGetter.EXPECT().GetFieldID(uint32(10), "f3").Return(uint16(3), field.SumField, nil)
+
+	// rejected before searching shards
+	exeCtx := parallel.NewMockExecuteContext(ctrl)
+	var err error
+	exeCtx.EXPECT().Complete(gomock.Any()).Do(func(e error) { err = e })
+	query, _ := sql.Parse("select f1,f2,f3 from cpu where time>'20190729 11:00:00' and time<'20190729 12:00:00'")
+	exec := newStorageExecutor(exeCtx, mockDatabase, []int32{1}, query, 2, false)
+	exec.Execute()
+	assert.EqualError(t, err, "query selects too many fields[3], exceeds the limit[2] per query")
+}
+
+func TestStorageExecutor_checkFieldCount(t *testing.T) {
+	exec := &storageExecutor{fieldIDs: []uint16{1, 2, 3}}
+	// unlimited
+	assert.Nil(t, exec.checkFieldCount())
+	exec.maxFields = 3
+	assert.Nil(t, exec.checkFieldCount())
+	exec.maxFields = 2
+	assert.NotNil(t, exec.checkFieldCount())
+}
@@ -3,10 +3,14 @@ package query
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"sort"
+	"strings"
 
 	"github.com/lindb/lindb/aggregation"
 	"github.com/lindb/lindb/aggregation/function"
+	"github.com/lindb/lindb/series"
+	"github.com/lindb/lindb/series/field"
 	"github.com/lindb/lindb/sql/stmt"
 	"github.com/lindb/lindb/tsdb/metadb"
 )
@@ -48,6 +52,11 @@ func (p *storageExecutePlan) Plan() error {
 		return err
 	}
 	p.metricID = metricID
+	if p.query.ValidateTagKeys {
+		if err := p.validateTagKeys(); err != nil {
+			return err
+		}
+	}
 	if err := p.groupBy(); err != nil {
 		return err
 	}
@@ -92,6 +101,44 @@ func (p *storageExecutePlan) groupBy() error {
 	return nil
 }
 
+// validateTagKeys checks if all the tag keys referenced by condition and group by exist,
+// returns a descriptive error listing valid tag keys when an unknown tag key is referenced
+func (p *storageExecutePlan) validateTagKeys() error {
+	tagKeys := append(collectTagKeys(p.query.Condition, nil), p.query.GroupBy...)
+	for _, tagKey := range tagKeys {
+		_, err := p.idGetter.GetTagKeyID(p.metricID, tagKey)
+		if err == nil {
+			continue
+		}
+		if err != series.ErrNotFound {
+			return err
+		}
+		validTagKeys, err := p.idGetter.GetTagKeys(p.metricID)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("tag key[%s] not found in metric[%s], valid tag keys: [%s]",
+			tagKey, p.query.MetricName, strings.Join(validTagKeys, ","))
+	}
+	return nil
+}
+
+// collectTagKeys collects the tag keys referenced by tag filter expr in order
+func collectTagKeys(expr stmt.Expr, tagKeys []string) []string {
+	switch e := expr.(type) {
+	case stmt.TagFilter:
+		tagKeys = append(tagKeys, e.TagKey())
+	case *stmt.ParenExpr:
+		tagKeys = collectTagKeys(e.Expr, tagKeys)
+	case *stmt.NotExpr:
+		tagKeys = collectTagKeys(e.Expr, tagKeys)
+	case *stmt.BinaryExpr:
+		tagKeys = collectTagKeys(e.Left, tagKeys)
+		tagKeys = collectTagKeys(e.Right, tagKeys)
+	}
+	return tagKeys
+}
+
 // getDownSamplingAggSpecs returns the down sampling aggregate specs
 func (p *storageExecutePlan) getDownSamplingAggSpecs() aggregation.AggregatorSpecs {
 	result := make(aggregation.AggregatorSpecs, len(p.fieldIDs))
@@ -132,8 +179,13 @@ func (p *storageExecutePlan) field(parentFunc *stmt.CallExpr, expr stmt.Expr) {
 	case *stmt.SelectItem:
 		p.field(nil, e.Expr)
 	case *stmt.CallExpr:
+		// moving average smooths the down sampling values, so the field uses its default down sampling func
+		callExpr := e
+		if e.FuncType == function.MovingAverage {
+			callExpr = nil
+		}
 		for _, param := range e.Params {
-			p.field(e, param)
+			p.field(callExpr, param)
 		}
 	case *stmt.ParenExpr:
 		p.field(nil, e.Expr)
@@ -141,33 +193,70 @@ func (p *storageExecutePlan) field(parentFunc *stmt.CallExpr, expr stmt.Expr) {
 		p.field(nil, e.Left)
 		p.field(nil, e.Right)
 	case *stmt.FieldExpr:
+		if pattern, ok := e.NamePattern(); ok {
+			p.fieldsByRegex(parentFunc, pattern)
+			return
+		}
 		fieldID, fieldType, err := p.idGetter.GetFieldID(p.metricID, e.Name)
 		if err != nil {
 			p.err = err
 			return
 		}
-		var funcType function.FuncType
-		// tests if has func with field
-		if parentFunc == nil {
-			// if not using field default down sampling func
-			funcType = fieldType.DownSamplingFunc()
-			if funcType == function.Unknown {
-				p.err = fmt.Errorf("cannot get default down sampling func for filed type[%s]", fieldType)
-				return
-			}
-		} else {
-			// using use input, and check func is supported
-			if !fieldType.IsFuncSupported(parentFunc.FuncType) {
-				p.err = fmt.Errorf("field type[%s] not supprot function[%s]", fieldType, parentFunc.FuncType)
-				return
-			}
-			funcType = parentFunc.FuncType
+		p.planField(parentFunc, e.Name, fieldID, fieldType)
+	}
+}
+
+// fieldsByRegex plans all the fields of metric which field name matches the regex pattern
+func (p *storageExecutePlan) fieldsByRegex(parentFunc *stmt.CallExpr, pattern string) {
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		p.err = fmt.Errorf("compile field name regex: %s error:%s", pattern, err)
+		return
+	}
+	fields, err := p.idGetter.GetFields(p.metricID)
+	if err != nil {
+		p.err = err
+		return
+	}
+	matched := false
+	for _, fieldMeta := range fields {
+		if !regex.MatchString(fieldMeta.Name) {
+			continue
 		}
-		downSampling, exist := p.fields[fieldID]
-		if !exist {
-			downSampling = aggregation.NewAggregatorSpec(e.Name, fieldType)
-			p.fields[fieldID] = downSampling
+		matched = true
+		p.planField(parentFunc, fieldMeta.Name, fieldMeta.ID, fieldMeta.Type)
+		if p.err != nil {
+			return
+		}
+	}
+	if !matched {
+		p.err = fmt.Errorf("field regex[%s] not match any field in metric[%s]", pattern, p.query.MetricName)
+	}
+}
+
+// planField plans the down sampling aggregation specification of the field with the function
+func (p *storageExecutePlan) planField(parentFunc *stmt.CallExpr, fieldName string, fieldID uint16, fieldType field.Type) {
+	var funcType function.FuncType
+	// tests if has func with field
+	if parentFunc == nil {
+		// if not using field default down sampling func
+		funcType = fieldType.DownSamplingFunc()
+		if funcType == function.Unknown {
+			p.err = fmt.Errorf("cannot get default down sampling func for filed type[%s]", fieldType)
+			return
 		}
-		downSampling.AddFunctionType(funcType)
+	} else {
+		// using use input, and check func is supported
+		if !fieldType.IsFuncSupported(parentFunc.FuncType) {
+			p.err = fmt.Errorf("field type[%s] not supprot function[%s]", fieldType, parentFunc.FuncType)
+			return
+		}
+		funcType = parentFunc.FuncType
+	}
+	downSampling, exist := p.fields[fieldID]
+	if !exist {
+		downSampling = aggregation.NewAggregatorSpec(fieldName, fieldType)
+		p.fields[fieldID] = downSampling
 	}
+	downSampling.AddFunctionType(funcType)
 }
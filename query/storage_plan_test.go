@@ -120,6 +120,22 @@ func TestStoragePlan_SelectList(t *testing.T) {
 	}
 	assert.Equal(t, expect, storagePlan.fields)
 	assert.Equal(t, []uint16{uint16(11), uint16(13), uint16(14)}, storagePlan.getFieldIDs())
+
+	// moving average uses the default down sampling func of field
+	query, _ = sql.Parse("select moving_average(f,3),moving_average(min(a),2) from cpu")
+	plan = newStorageExecutePlan(metadataIndex, query)
+	err = plan.Plan()
+	assert.NoError(t, err)
+	storagePlan = plan.(*storageExecutePlan)
+	downSampling = aggregation.NewAggregatorSpec("f", field.SumField)
+	downSampling.AddFunctionType(function.Sum)
+	downSampling1 = aggregation.NewAggregatorSpec("a", field.MinField)
+	downSampling1.AddFunctionType(function.Min)
+	expect = map[uint16]aggregation.AggregatorSpec{
+		uint16(10): downSampling,
+		uint16(11): downSampling1,
+	}
+	assert.Equal(t, expect, storagePlan.fields)
 }
 
 func TestStorageExecutePlan_groupBy(t *testing.T) {
@@ -162,6 +178,61 @@ func TestStorageExecutePlan_groupBy(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestStorageExecutePlan_fieldRegex(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	idGetter := metadb.NewMockIDGetter(ctrl)
+	idGetter.EXPECT().GetMetricID("host").Return(uint32(10), nil).AnyTimes()
+	fields := field.Metas{
+		{Name: "cpu", ID: 1, Type: field.SumField},
+		{Name: "disk_/tmp", ID: 3, Type: field.SumField},
+		{Name: "disk_/var", ID: 2, Type: field.MaxField},
+	}
+
+	// select all matched fields
+	idGetter.EXPECT().GetFields(uint32(10)).Return(fields, nil)
+	query, err := sql.Parse("select \"/disk_.*/\" from host")
+	assert.NoError(t, err)
+	plan := newStorageExecutePlan(idGetter, query)
+	assert.NoError(t, plan.Plan())
+	storagePlan := plan.(*storageExecutePlan)
+	assert.Equal(t, []uint16{2, 3}, storagePlan.getFieldIDs())
+	aggSpecs := storagePlan.getDownSamplingAggSpecs()
+	assert.Equal(t, "disk_/var", aggSpecs[0].FieldName())
+	assert.Equal(t, field.MaxField, aggSpecs[0].FieldType())
+	assert.Equal(t, "disk_/tmp", aggSpecs[1].FieldName())
+
+	// regex with function and plain field
+	idGetter.EXPECT().GetFields(uint32(10)).Return(fields, nil)
+	idGetter.EXPECT().GetFieldID(uint32(10), "cpu").Return(uint16(1), field.SumField, nil)
+	query, _ = sql.Parse("select max(\"/disk_/(tmp|var)/\"),cpu from host")
+	plan = newStorageExecutePlan(idGetter, query)
+	assert.NoError(t, plan.Plan())
+	storagePlan = plan.(*storageExecutePlan)
+	assert.Equal(t, []uint16{1, 2, 3}, storagePlan.getFieldIDs())
+	downSampling := aggregation.NewAggregatorSpec("disk_/tmp", field.SumField)
+	downSampling.AddFunctionType(function.Max)
+	assert.Equal(t, downSampling, storagePlan.fields[3])
+
+	// function not supported by matched field
+	idGetter.EXPECT().GetFields(uint32(10)).Return(fields, nil)
+	query, _ = sql.Parse("select histogram(\"/disk_.*/\") from host")
+	assert.Error(t, newStorageExecutePlan(idGetter, query).Plan())
+
+	// no field matched
+	idGetter.EXPECT().GetFields(uint32(10)).Return(fields, nil)
+	query, _ = sql.Parse("select \"/mem_.*/\" from host")
+	assert.Error(t, newStorageExecutePlan(idGetter, query).Plan())
+
+	// get fields error
+	idGetter.EXPECT().GetFields(uint32(10)).Return(nil, fmt.Errorf("err"))
+	assert.Error(t, newStorageExecutePlan(idGetter, query).Plan())
+
+	// invalid regex
+	query, _ = sql.Parse("select \"/disk_[/\" from host")
+	assert.Error(t, newStorageExecutePlan(idGetter, query).Plan())
+}
+
 func TestStorageExecutePlan_empty_select_item(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -216,3 +287,67 @@ func TestStorageExecutePlan_field_expr_fail(t *testing.T) {
 	err = plan.Plan()
 	assert.Error(t, err)
 }
+
+func TestStorageExecutePlan_validateTagKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	idGetter := metadb.NewMockIDGetter(ctrl)
+
+	// typo'd tag key
+	gomock.InOrder(
+		idGetter.EXPECT().GetMetricID("disk").Return(uint32(10), nil),
+		idGetter.EXPECT().GetTagKeyID(uint32(10), "host").Return(uint32(1), nil),
+		idGetter.EXPECT().GetTagKeyID(uint32(10), "zoen").Return(uint32(0), series.ErrNotFound),
+		idGetter.EXPECT().GetTagKeys(uint32(10)).Return([]string{"host", "zone"}, nil),
+	)
+	query, _ := sql.Parse("select f from disk where host='1.1.1.1' and zoen='sh'")
+	query.ValidateTagKeys = true
+	plan := newStorageExecutePlan(idGetter, query)
+	err := plan.Plan()
+	assert.EqualError(t, err, "tag key[zoen] not found in metric[disk], valid tag keys: [host,zone]")
+
+	// typo'd group by tag key
+	gomock.InOrder(
+		idGetter.EXPECT().GetMetricID("disk").Return(uint32(10), nil),
+		idGetter.EXPECT().GetTagKeyID(uint32(10), "hots").Return(uint32(0), series.ErrNotFound),
+		idGetter.EXPECT().GetTagKeys(uint32(10)).Return([]string{"host"}, nil),
+	)
+	query, _ = sql.Parse("select f from disk group by hots")
+	query.ValidateTagKeys = true
+	plan = newStorageExecutePlan(idGetter, query)
+	err = plan.Plan()
+	assert.EqualError(t, err, "tag key[hots] not found in metric[disk], valid tag keys: [host]")
+
+	// get tag key id err
+	gomock.InOrder(
+		idGetter.EXPECT().GetMetricID("disk").Return(uint32(10), nil),
+		idGetter.EXPECT().GetTagKeyID(uint32(10), "host").Return(uint32(0), fmt.Errorf("err")),
+	)
+	query, _ = sql.Parse("select f from disk where host!='1.1.1.1'")
+	query.ValidateTagKeys = true
+	plan = newStorageExecutePlan(idGetter, query)
+	err = plan.Plan()
+	assert.EqualError(t, err, "err")
+
+	// get tag keys err
+	gomock.InOrder(
+		idGetter.EXPECT().GetMetricID("disk").Return(uint32(10), nil),
+		idGetter.EXPECT().GetTagKeyID(uint32(10), "host").Return(uint32(0), series.ErrNotFound),
+		idGetter.EXPECT().GetTagKeys(uint32(10)).Return(nil, fmt.Errorf("err")),
+	)
+	plan = newStorageExecutePlan(idGetter, query)
+	err = plan.Plan()
+	assert.EqualError(t, err, "err")
+
+	// validation passes
+	gomock.InOrder(
+		idGetter.EXPECT().GetMetricID("disk").Return(uint32(10), nil),
+		idGetter.EXPECT().GetTagKeyID(uint32(10), "host").Return(uint32(1), nil),
+		idGetter.EXPECT().GetFieldID(uint32(10), "f").Return(uint16(10), field.SumField, nil),
+	)
+	query, _ = sql.Parse("select f from disk where (host='1.1.1.1')")
+	query.ValidateTagKeys = true
+	plan = newStorageExecutePlan(idGetter, query)
+	err = plan.Plan()
+	assert.NoError(t, err)
+}
@@ -12,11 +12,13 @@ import (
 	"time"
 
 	"github.com/segmentio/fasthash/fnv1a"
+	"go.uber.org/atomic"
 
 	"github.com/lindb/lindb/config"
 	"github.com/lindb/lindb/models"
 	"github.com/lindb/lindb/pkg/logger"
 	"github.com/lindb/lindb/pkg/queue"
+	"github.com/lindb/lindb/pkg/queue/segment"
 	"github.com/lindb/lindb/pkg/stream"
 	"github.com/lindb/lindb/pkg/timeutil"
 	"github.com/lindb/lindb/rpc"
@@ -29,9 +31,28 @@ import (
 // ErrCanceled is the error returned when writing data ctx canceled.
 var ErrCanceled = errors.New("write data ctx done")
 
+// ErrInFlightWindowFull is the error returned when the in-flight window is still full after write timeout,
+// the write can be retried later.
+var ErrInFlightWindowFull = errors.New("replication in-flight window is full")
+
+// PartialWriteError is the error returned when the metrics are written into some shards but failed for others,
+// the write cannot be retried as a whole, otherwise the metrics of written shards are duplicated.
+type PartialWriteError struct {
+	Database string
+	// shards failed to write
+	Shards []int32
+}
+
+// Error returns the failed shards of the write.
+func (e *PartialWriteError) Error() string {
+	return fmt.Sprintf("partial write of database %s, failed shards: %v", e.Database, e.Shards)
+}
+
 const (
 	defaultReportInterval = 30 * time.Second
 	defaultBufferSize     = 32
+	// interval for checking if the in-flight window is available
+	checkInFlightInterval = 10 * time.Millisecond
 )
 
 var log = logger.GetLogger("replication", "ChannelManager")
@@ -39,12 +60,20 @@ var log = logger.GetLogger("replication", "ChannelManager")
 // ChannelManager manages the construction, retrieving, closing for all channels.
 type ChannelManager interface {
 	// Write writes a MetricList, the manager handler the database, sharding things.
+	// ErrInFlightWindowFull is returned if the in-flight window of any shard is full until timeout,
+	// nothing is written so that the write can be retried later.
+	// *PartialWriteError is returned if the write of some shards failed after the window check, not retryable.
 	Write(list *field.MetricList) error
 	// CreateChannel creates a new channel or returns a existed channel for storage with specific database and shardID,
 	// numOfShard should be greater or equal than the origin setting, otherwise error is returned.
 	// numOfShard is used eot calculate the shardID for a given hash.
 	CreateChannel(database string, numOfShard, shardID int32) (Channel, error)
 
+	// Stats returns the count of channels by state and the total pending messages across all channels.
+	Stats() models.ChannelStats
+	// ReplicaState returns the replication state of all channels by target.
+	ReplicaState() []models.ReplicaState
+
 	// Close closes all the channel.
 	Close()
 }
@@ -112,31 +141,63 @@ func (cm *channelManager) Write(metricList *field.MetricList) error {
 		metricsMap[shardID] = l
 	}
 
-	for shardID, l := range metricsMap {
+	channels := make(map[int32]Channel, len(metricsMap))
+	for shardID := range metricsMap {
 		channelID := cm.buildChannelID(metricList.Database, shardID)
 		channelVal, ok := cm.channelMap.Load(channelID)
-
 		if !ok {
 			// broker error, do not return to client
 			cm.logger.Error("channel not found", logger.String("database", metricList.Database), logger.Int32("shardID", shardID))
 			continue
 		}
+		channels[shardID] = channelVal.(Channel)
+	}
 
-		ch := channelVal.(Channel)
-
-		ml := &field.MetricList{
-			Metrics: l,
-		}
-
-		data, err := ml.Marshal()
-		if err != nil {
-			// won't happen
-			return err
-		}
+	// checks the in-flight window of all shards concurrently before writing any of them,
+	// so that the write is all-or-nothing and blocked by the in-flight window at most once
+	var (
+		wg         sync.WaitGroup
+		windowFull atomic.Bool
+	)
+	for shardID, ch := range channels {
+		wg.Add(1)
+		go func(ch Channel, shardID int32) {
+			defer wg.Done()
+			if err := ch.WaitInFlightWindow(); err != nil {
+				windowFull.Store(true)
+				cm.logger.Error("channel wait in-flight window error", logger.String("database", metricList.Database),
+					logger.Int32("shardID", shardID), logger.Error(err))
+			}
+		}(ch, shardID)
+	}
+	wg.Wait()
+	// the in-flight window full is returned to client for retrying later
+	if windowFull.Load() {
+		return ErrInFlightWindowFull
+	}
 
-		if err := ch.Write(data); err != nil {
-			cm.logger.Error("channel write data error", logger.String("database", metricList.Database), logger.Int32("shardID", shardID))
-		}
+	var (
+		lock   sync.Mutex
+		failed []int32
+	)
+	for shardID, ch := range channels {
+		wg.Add(1)
+		go func(ch Channel, shardID int32, metrics []*field.Metric) {
+			defer wg.Done()
+			if err := ch.WriteBatch(metrics); err != nil {
+				cm.logger.Error("channel write data error", logger.String("database", metricList.Database),
+					logger.Int32("shardID", shardID), logger.Error(err))
+				lock.Lock()
+				failed = append(failed, shardID)
+				lock.Unlock()
+			}
+		}(ch, shardID, metricsMap[shardID])
+	}
+	wg.Wait()
+	// some shards may be written, the write cannot be retried as a whole
+	if len(failed) > 0 {
+		sort.Slice(failed, func(i, j int) bool { return failed[i] < failed[j] })
+		return &PartialWriteError{Database: metricList.Database, Shards: failed}
 	}
 	return nil
 }
@@ -178,6 +239,41 @@ func (cm *channelManager) CreateChannel(database string, numOfShard, shardID int
 	return ch, nil
 }
 
+// Stats returns the count of channels by state and the total pending messages across all channels.
+func (cm *channelManager) Stats() models.ChannelStats {
+	var stats models.ChannelStats
+	cm.channelMap.Range(func(key, value interface{}) bool {
+		ch, ok := value.(Channel)
+		if !ok {
+			return true
+		}
+		switch ch.State() {
+		case ChannelCircuitOpen:
+			stats.CircuitOpen++
+		case ChannelBackpressured:
+			stats.Backpressured++
+		default:
+			stats.Healthy++
+		}
+		stats.Pending += ch.Pending()
+		return true
+	})
+	return stats
+}
+
+// ReplicaState returns the replication state of all channels by target.
+func (cm *channelManager) ReplicaState() []models.ReplicaState {
+	var states []models.ReplicaState
+	cm.channelMap.Range(func(key, value interface{}) bool {
+		ch, ok := value.(Channel)
+		if ok {
+			states = append(states, ch.ReplicaState()...)
+		}
+		return true
+	})
+	return states
+}
+
 // Close closes all the channel.
 func (cm *channelManager) Close() {
 	cm.cancel()
@@ -207,32 +303,9 @@ func (cm *channelManager) scheduleStateReport() {
 func (cm *channelManager) reportState() {
 	brokerState := models.BrokerReplicaState{
 		ReportTime: timeutil.Now(),
+		Replicas:   cm.ReplicaState(),
+		Channels:   cm.Stats(),
 	}
-	cm.channelMap.Range(func(key, value interface{}) bool {
-		channel, ok := value.(Channel)
-		if ok {
-			channel.Database()
-			targets := channel.Targets()
-			for i := range targets {
-				target := targets[i]
-				replicator, err := channel.GetOrCreateReplicator(target)
-				if err != nil {
-					log.Error("get replicator fail", logger.String("target", (&target).Indicator()), logger.Error(err))
-					continue
-				}
-				replicatorState := models.ReplicaState{
-					Database:     replicator.Database(),
-					Target:       target,
-					ShardID:      replicator.ShardID(),
-					Pending:      replicator.Pending(),
-					ReplicaIndex: replicator.ReplicaIndex(),
-					AckIndex:     replicator.AckIndex(),
-				}
-				brokerState.Replicas = append(brokerState.Replicas, replicatorState)
-			}
-		}
-		return true
-	})
 	if err := cm.replicatorService.Report(&brokerState); err != nil {
 		log.Error("report broker replicator state fail", logger.Error(err))
 	}
@@ -270,16 +343,47 @@ type Channel interface {
 	// ShardID returns the shardID attribution.
 	ShardID() int32
 	// Write writes the data into the channel, ErrCanceled is returned when the channel is canceled before
-	// data is wrote successfully.
+	// data is wrote successfully, ErrInFlightWindowFull is returned when the in-flight window is full until timeout.
 	// Concurrent safe.
 	Write(data []byte) error
+	// WriteMetric marshals the metric and writes it into the channel, returns error same as Write.
+	// Concurrent safe.
+	WriteMetric(metric *field.Metric) error
+	// WriteBatch marshals the metrics into a metric list and writes it into the channel as one entry,
+	// returns error same as Write.
+	// Concurrent safe.
+	WriteBatch(metrics []*field.Metric) error
+	// WaitInFlightWindow waits until the in-flight window is available without writing any data,
+	// ErrInFlightWindowFull is returned if write timeout, ErrCanceled if the channel is canceled.
+	// Concurrent safe.
+	WaitInFlightWindow() error
 	// GetOrCreateReplicator get a existed or creates a new replicator for target.
 	// Concurrent safe.
 	GetOrCreateReplicator(target models.Node) (Replicator, error)
 	// Nodes returns all the target nodes for replication.
 	Targets() []models.Node
+	// State returns the current state of channel.
+	State() ChannelState
+	// Pending returns the num of messages remaining to replicate of all replicators.
+	Pending() int64
+	// ReplicaState returns the replication state of all replicators by target,
+	// which is cheap to read for polling frequently.
+	ReplicaState() []models.ReplicaState
 }
 
+// ChannelState represents the state of channel
+type ChannelState int
+
+// Defines all the states of channel
+const (
+	// ChannelHealthy means the data is replicated normally
+	ChannelHealthy ChannelState = iota
+	// ChannelBackpressured means the write buffer or in-flight window of channel is full, the writes are blocked
+	ChannelBackpressured
+	// ChannelCircuitOpen means any replicator of channel is disconnected from target, the replication is paused
+	ChannelCircuitOpen
+)
+
 // channel implements Channel.
 type channel struct {
 	// context to close channel
@@ -292,7 +396,9 @@ type channel struct {
 	// underlying storage for written data
 	q queue.FanOutQueue
 	// chanel to convert multiple goroutine write to single goroutine write to FanOutQueue
-	ch chan []byte
+	ch chan WriteEntry
+	// monotonically increasing write sequence of the shard, stamped at ingest for ordering replay
+	writeSeq atomic.Int64
 
 	// last flush time
 	lastFlushTime time.Time
@@ -302,6 +408,15 @@ type channel struct {
 	flushInterval time.Duration
 	//buffer size limit for batch bytes before append to queue
 	bufferSizeLimit int
+	// max pending messages of any replicator, 0 means unlimited
+	maxInFlight int64
+	// max time to wait for the in-flight window to be available
+	writeTimeout time.Duration
+	// num. of messages appended into queue per second
+	appendRate atomic.Float64
+	// queue head seq and time when last calculating append rate, only accessed by append task
+	lastAppendSeq int64
+	lastRateTime  time.Time
 
 	// target -> replicator map
 	replicatorMap sync.Map
@@ -321,7 +436,11 @@ func newChannel(
 	dirPath := path.Join(cfg.Dir, database, strconv.Itoa(int(shardID)))
 	interval := cfg.RemoveTaskInterval.Duration()
 
-	q, err := queue.NewFanOutQueue(dirPath, cfg.SegmentFileSizeInBytes(), interval)
+	codec, err := segment.ParseCodec(cfg.Compression)
+	if err != nil {
+		return nil, err
+	}
+	q, err := queue.NewFanOutQueue(dirPath, cfg.SegmentFileSizeInBytes(), codec, interval)
 	if err != nil {
 		return nil, err
 	}
@@ -333,13 +452,20 @@ func newChannel(
 		database:           database,
 		shardID:            shardID,
 		q:                  q,
-		ch:                 make(chan []byte, defaultBufferSize),
+		ch:                 make(chan WriteEntry, defaultBufferSize),
 		lastFlushTime:      time.Now(),
+		lastRateTime:       time.Now(),
 		checkFlushInterval: cfg.CheckFlushInterval.Duration(),
 		flushInterval:      cfg.FlushInterval.Duration(),
 		bufferSizeLimit:    cfg.BufferSizeInBytes(),
+		maxInFlight:        cfg.MaxInFlight,
+		writeTimeout:       cfg.WriteTimeout.Duration(),
 		logger:             logger.GetLogger("replication", "Channel"),
 	}
+	// starts from current time, keeps the write sequence increasing after restart
+	c.writeSeq.Store(time.Now().UnixNano())
+
+	c.lastAppendSeq = q.HeadSeq()
 
 	c.initAppendTask()
 	c.watchClose()
@@ -392,18 +518,136 @@ func (c *channel) Targets() []models.Node {
 	return nodes
 }
 
+// State returns the current state of channel, circuit-open takes precedence over backpressured.
+func (c *channel) State() ChannelState {
+	state := ChannelHealthy
+	if len(c.ch) >= cap(c.ch) || c.inFlightFull() {
+		state = ChannelBackpressured
+	}
+	c.replicatorMap.Range(func(key, value interface{}) bool {
+		rep, _ := value.(Replicator)
+		if !rep.IsReady() {
+			state = ChannelCircuitOpen
+			return false
+		}
+		return true
+	})
+	return state
+}
+
+// Pending returns the num of messages remaining to replicate of all replicators.
+func (c *channel) Pending() int64 {
+	var pending int64
+	c.replicatorMap.Range(func(key, value interface{}) bool {
+		rep, _ := value.(Replicator)
+		pending += rep.Pending()
+		return true
+	})
+	return pending
+}
+
+// ReplicaState returns the replication state of all replicators by target,
+// the state is read from atomics of replicator without holding lock.
+func (c *channel) ReplicaState() []models.ReplicaState {
+	appendRate := c.appendRate.Load()
+	var states []models.ReplicaState
+	c.replicatorMap.Range(func(key, value interface{}) bool {
+		rep, _ := value.(Replicator)
+		states = append(states, models.ReplicaState{
+			Database:     c.database,
+			ShardID:      c.shardID,
+			Target:       rep.Target(),
+			Pending:      rep.Pending(),
+			ReplicaIndex: rep.ReplicaIndex(),
+			AckIndex:     rep.AckIndex(),
+			LastAckedSeq: rep.LastAckedSeq(),
+			AppendRate:   appendRate,
+		})
+		return true
+	})
+	return states
+}
+
 // Write writes the data into the channel, ErrCanceled is returned when the ctx is canceled before
-// data is wrote successfully.
+// data is wrote successfully. The data is stamped with the write sequence for ordering replay.
+// If the in-flight window is full, blocks until acks reduce the pending messages,
+// ErrInFlightWindowFull is returned after write timeout.
 // Concurrent safe.
 func (c *channel) Write(data []byte) error {
+	if err := c.WaitInFlightWindow(); err != nil {
+		return err
+	}
+	entry := WriteEntry{WriteSeq: c.writeSeq.Inc(), Data: data}
 	select {
-	case c.ch <- data:
+	case c.ch <- entry:
 		return nil
 	case <-c.ctx.Done():
 		return ErrCanceled
 	}
 }
 
+// WriteMetric marshals the metric into a metric list of single metric, then writes it into the channel,
+// the write sequence is stamped by Write, so that the entries of both entry points are ordered consistently.
+// Concurrent safe.
+func (c *channel) WriteMetric(metric *field.Metric) error {
+	return c.WriteBatch([]*field.Metric{metric})
+}
+
+// WriteBatch marshals the metrics into a metric list, then writes it into the channel as one entry.
+// Concurrent safe.
+func (c *channel) WriteBatch(metrics []*field.Metric) error {
+	ml := &field.MetricList{Metrics: metrics}
+	data, err := ml.Marshal()
+	if err != nil {
+		return fmt.Errorf("marshal metric list error:%s", err)
+	}
+	return c.Write(data)
+}
+
+// inFlightFull returns if the pending messages of any replicator reach the in-flight window.
+func (c *channel) inFlightFull() bool {
+	if c.maxInFlight <= 0 {
+		return false
+	}
+	full := false
+	c.replicatorMap.Range(func(key, value interface{}) bool {
+		rep, _ := value.(Replicator)
+		if rep.Pending() >= c.maxInFlight {
+			full = true
+			return false
+		}
+		return true
+	})
+	return full
+}
+
+// WaitInFlightWindow waits until the in-flight window is available,
+// returns ErrInFlightWindowFull if write timeout, ErrCanceled if ctx is canceled.
+func (c *channel) WaitInFlightWindow() error {
+	if !c.inFlightFull() {
+		return nil
+	}
+	if c.writeTimeout <= 0 {
+		return ErrInFlightWindowFull
+	}
+	timer := time.NewTimer(c.writeTimeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(checkInFlightInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !c.inFlightFull() {
+				return nil
+			}
+		case <-timer.C:
+			return ErrInFlightWindowFull
+		case <-c.ctx.Done():
+			return ErrCanceled
+		}
+	}
+}
+
 // initAppendTask starts a goroutine to consume data from ch and batch append to q.
 func (c *channel) initAppendTask() {
 	go func() {
@@ -417,9 +661,10 @@ func (c *channel) initAppendTask() {
 			select {
 			case <-c.ctx.Done():
 				break loop
-			case data := <-c.ch:
-				appendWithVarLen(buffer, data)
-			case <-ticker.C:
+			case entry := <-c.ch:
+				appendWriteEntry(buffer, entry)
+			case now := <-ticker.C:
+				c.updateAppendRate(now)
 			}
 			// check
 			c.checkFlush(buffer)
@@ -429,8 +674,8 @@ func (c *channel) initAppendTask() {
 	closeLoop:
 		for {
 			select {
-			case data := <-c.ch:
-				appendWithVarLen(buffer, data)
+			case entry := <-c.ch:
+				appendWriteEntry(buffer, entry)
 			default:
 				break closeLoop
 			}
@@ -442,6 +687,18 @@ func (c *channel) initAppendTask() {
 	}()
 }
 
+// updateAppendRate calculates the num. of messages appended into queue per second since last calculating.
+func (c *channel) updateAppendRate(now time.Time) {
+	elapsed := now.Sub(c.lastRateTime).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	headSeq := c.q.HeadSeq()
+	c.appendRate.Store(float64(headSeq-c.lastAppendSeq) / elapsed)
+	c.lastAppendSeq = headSeq
+	c.lastRateTime = now
+}
+
 func (c *channel) checkFlush(buffer *stream.BufferWriter) {
 	if buffer.Len() == 0 {
 		return
@@ -462,11 +719,6 @@ func (c *channel) checkFlush(buffer *stream.BufferWriter) {
 	}
 }
 
-func appendWithVarLen(binary *stream.BufferWriter, data []byte) {
-	binary.PutUvarint32(uint32(len(data)))
-	binary.PutBytes(data)
-}
-
 // watchClose waits on the context done then close the ch.
 func (c *channel) watchClose() {
 	go func() {
@@ -1,6 +1,7 @@
 package replication
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -10,9 +11,13 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
 
 	"github.com/lindb/lindb/config"
+	"github.com/lindb/lindb/models"
+	"github.com/lindb/lindb/pkg/logger"
 	"github.com/lindb/lindb/pkg/ltoml"
+	"github.com/lindb/lindb/pkg/queue"
 	"github.com/lindb/lindb/rpc"
 	"github.com/lindb/lindb/rpc/proto/field"
 	"github.com/lindb/lindb/rpc/proto/storage"
@@ -115,6 +120,61 @@ func TestChannelManager_Write(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	// in-flight window full is returned for retrying, nothing is written
+	mockChannel := NewMockChannel(ctrl)
+	cm.(*channelManager).channelMap.Store(cm.(*channelManager).buildChannelID("database", 0), mockChannel)
+	mockChannel.EXPECT().WaitInFlightWindow().Return(ErrInFlightWindowFull)
+	assert.Equal(t, ErrInFlightWindowFull, cm.Write(metricList))
+	// write fails after window check
+	mockChannel.EXPECT().WaitInFlightWindow().Return(nil)
+	mockChannel.EXPECT().WriteBatch(gomock.Any()).Return(ErrCanceled)
+	err = cm.Write(metricList)
+	assert.Equal(t, &PartialWriteError{Database: "database", Shards: []int32{0}}, err)
+	mockChannel.EXPECT().ReplicaState().Return(nil).AnyTimes()
+	cm.Close()
+}
+
+func TestChannelManager_Write_multiShards(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cm := &channelManager{logger: logger.GetLogger("replication", "channelManager")}
+	cm.databaseShardsMap.Store("database", int32(2))
+	channels := make([]*MockChannel, 2)
+	for i := range channels {
+		channels[i] = NewMockChannel(ctrl)
+		cm.channelMap.Store(cm.buildChannelID("database", int32(i)), channels[i])
+	}
+	// metrics of both shards
+	metricList := &field.MetricList{Database: "database"}
+	shards := make(map[uint32]bool)
+	for i := 0; len(shards) < 2; i++ {
+		metric := &field.Metric{Name: "name", Tags: map[string]string{"tagKey": fmt.Sprintf("tagVal%d", i)}}
+		shards[metricHash(metric)%2] = true
+		metricList.Metrics = append(metricList.Metrics, metric)
+	}
+
+	// window of shard 1 is full, shard 0 is not written either
+	channels[0].EXPECT().WaitInFlightWindow().Return(nil)
+	channels[1].EXPECT().WaitInFlightWindow().Return(ErrInFlightWindowFull)
+	assert.Equal(t, ErrInFlightWindowFull, cm.Write(metricList))
+
+	// shard 0 is written, shard 1 fails, not retryable
+	channels[0].EXPECT().WaitInFlightWindow().Return(nil)
+	channels[1].EXPECT().WaitInFlightWindow().Return(nil)
+	channels[0].EXPECT().WriteBatch(gomock.Any()).Return(nil)
+	channels[1].EXPECT().WriteBatch(gomock.Any()).Return(ErrInFlightWindowFull)
+	err := cm.Write(metricList)
+	assert.Equal(t, &PartialWriteError{Database: "database", Shards: []int32{1}}, err)
+	assert.Equal(t, "partial write of database database, failed shards: [1]", err.Error())
+
+	// all shards are written
+	for _, ch := range channels {
+		ch.EXPECT().WaitInFlightWindow().Return(nil)
+		ch.EXPECT().WriteBatch(gomock.Any()).Return(nil)
+	}
+	assert.NoError(t, cm.Write(metricList))
 }
 
 func TestChannel_GetOrCreateReplicator(t *testing.T) {
@@ -207,11 +267,103 @@ func TestChannel_WriteFail(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 
 	assert.Equal(t, rep1.Pending(), int64(1))
+	// not replicated to target
+	states := cm.ReplicaState()
+	assert.Len(t, states, 1)
+	assert.Equal(t, node, states[0].Target)
+	assert.Equal(t, int64(1), states[0].Pending)
+	assert.Equal(t, int64(0), states[0].ReplicaIndex)
+	assert.Equal(t, int64(-1), states[0].LastAckedSeq)
 
 	cm.Close()
 
 }
 
+func TestChannel_WriteMetric(t *testing.T) {
+	ch := &channel{ctx: context.Background(), ch: make(chan WriteEntry, 4)}
+	newMetric := func(name string) *field.Metric {
+		return &field.Metric{
+			Name:      name,
+			Timestamp: 1564300800000,
+			Tags:      map[string]string{"host": "1.1.1.1"},
+			Fields:    []*field.Field{{Name: "sum", Field: &field.Field_Sum{Sum: &field.Sum{Value: 1.0}}}},
+		}
+	}
+	encode := func(metrics ...*field.Metric) []byte {
+		data, err := (&field.MetricList{Metrics: metrics}).Marshal()
+		assert.NoError(t, err)
+		return data
+	}
+	assert.NoError(t, ch.WriteMetric(newMetric("cpu")))
+	assert.NoError(t, ch.Write(encode(newMetric("cpu"))))
+	assert.NoError(t, ch.WriteBatch([]*field.Metric{newMetric("cpu"), newMetric("mem")}))
+	assert.NoError(t, ch.Write(encode(newMetric("cpu"), newMetric("mem"))))
+
+	entries := make([]WriteEntry, 4)
+	for i := range entries {
+		entries[i] = <-ch.ch
+	}
+	// same bytes on the wire
+	assert.Equal(t, entries[1].Data, entries[0].Data)
+	assert.Equal(t, entries[3].Data, entries[2].Data)
+	// sequence is increasing across both entry points
+	for i := 1; i < len(entries); i++ {
+		assert.Equal(t, entries[i-1].WriteSeq+1, entries[i].WriteSeq)
+	}
+	var ml field.MetricList
+	assert.NoError(t, ml.Unmarshal(entries[2].Data))
+	assert.Equal(t, []*field.Metric{newMetric("cpu"), newMetric("mem")}, ml.Metrics)
+}
+
+func TestChannel_Compression(t *testing.T) {
+	dirPath := path.Join(os.TempDir(), "test_channel_compression")
+	ctl := gomock.NewController(t)
+	defer func() {
+		if err := os.RemoveAll(dirPath); err != nil {
+			t.Error(err)
+		}
+		ctl.Finish()
+	}()
+
+	replicatorService := service.NewMockReplicatorService(ctl)
+	replicatorService.EXPECT().Report(gomock.Any()).Return(fmt.Errorf("err")).AnyTimes()
+	mockFct := rpc.NewMockClientStreamFactory(ctl)
+	mockFct.EXPECT().CreateWriteServiceClient(node).Return(nil, errors.New("get service client error any")).AnyTimes()
+
+	for _, compression := range []string{"none", "snappy"} {
+		cfg := replicationConfig
+		cfg.Dir = path.Join(dirPath, compression)
+		cfg.Compression = compression
+		cm := NewChannelManager(cfg, mockFct, replicatorService)
+		ch, err := cm.CreateChannel(database, 2, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rep, err := ch.GetOrCreateReplicator(node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, ch.Write([]byte("cpu,host=host-1 idle=10,user=20,system=30")))
+		}
+		// wait for appending
+		time.Sleep(100 * time.Millisecond)
+		// pending counts messages, which is not affected by compression
+		assert.Equal(t, int64(3), rep.Pending())
+		assert.Equal(t, int64(3), ch.Pending())
+		cm.Close()
+	}
+
+	// compression not supported
+	cfg := replicationConfig
+	cfg.Dir = path.Join(dirPath, "zstd")
+	cfg.Compression = "zstd"
+	cm := NewChannelManager(cfg, mockFct, replicatorService)
+	_, err := cm.CreateChannel(database, 2, 0)
+	assert.Error(t, err)
+	cm.Close()
+}
+
 func TestChannel_WriteSuccess(t *testing.T) {
 	dirPath := path.Join(os.TempDir(), "test_channel_manager")
 	if err := os.RemoveAll(dirPath); err != nil {
@@ -232,24 +384,38 @@ func TestChannel_WriteSuccess(t *testing.T) {
 	replicatorService.EXPECT().Report(gomock.Any()).Return(fmt.Errorf("err")).AnyTimes()
 
 	mockServiceClient := storage.NewMockWriteServiceClient(ctl)
-	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any()).Return(&storage.NextSeqResponse{
+	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any(), gomock.Any()).Return(&storage.NextSeqResponse{
 		Seq: 0,
 	}, nil)
 
 	done := make(chan struct{})
+	sent := make(chan struct{})
 	mockClientStream := storage.NewMockWriteService_WriteClient(ctl)
+	// target acks the replica after received
+	mockClientStream.EXPECT().Recv().DoAndReturn(func() (*storage.WriteResponse, error) {
+		<-sent
+		return &storage.WriteResponse{CurSeq: 0, Ack: &storage.WriteResponse_AckSeq{AckSeq: 0}}, nil
+	})
 	mockClientStream.EXPECT().Recv().DoAndReturn(func() (*storage.WriteResponse, error) {
 		<-done
 		return nil, errors.New("recv errors")
 	})
 
-	wr, _ := buildWriteRequest(0, 1)
-	mockClientStream.EXPECT().Send(wr).Return(nil)
+	mockClientStream.EXPECT().Send(gomock.Any()).DoAndReturn(func(wr *storage.WriteRequest) error {
+		// data is stamped with write sequence
+		assert.Len(t, wr.Replicas, 1)
+		entries, err := DecodeWriteEntries(wr.Replicas[0].Data)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, []byte("0"), entries[0].Data)
+		close(sent)
+		return nil
+	})
 
 	mockFct := rpc.NewMockClientStreamFactory(ctl)
 	mockFct.EXPECT().CreateWriteServiceClient(node).Return(mockServiceClient, nil)
 	mockFct.EXPECT().LogicNode().Return(node)
-	mockFct.EXPECT().CreateWriteClient(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockClientStream, nil)
+	mockFct.EXPECT().CreateWriteClient(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(mockClientStream, nil)
 
 	cm := NewChannelManager(replicationConfig, mockFct, replicatorService)
 
@@ -272,9 +438,175 @@ func TestChannel_WriteSuccess(t *testing.T) {
 	// wait for replication
 	time.Sleep(2 * time.Second)
 	assert.Equal(t, rep1.Pending(), int64(0))
+	// replicated and acked by target
+	states := ch.ReplicaState()
+	assert.Len(t, states, 1)
+	assert.Equal(t, models.ReplicaState{
+		Database:     database,
+		ShardID:      0,
+		Target:       node,
+		Pending:      0,
+		ReplicaIndex: 1,
+		AckIndex:     0,
+		LastAckedSeq: 0,
+		AppendRate:   states[0].AppendRate,
+	}, states[0])
+	assert.Equal(t, states, cm.ReplicaState())
 
 	cm.Close()
 	// cm close pass to replicator is async, wait
 	time.Sleep(100 * time.Millisecond)
 	close(done)
 }
+
+func TestChannel_State(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ch := &channel{ch: make(chan WriteEntry, 1)}
+	assert.Equal(t, ChannelHealthy, ch.State())
+	assert.Equal(t, int64(0), ch.Pending())
+
+	rep1 := NewMockReplicator(ctrl)
+	rep1.EXPECT().IsReady().Return(true).AnyTimes()
+	rep1.EXPECT().Pending().Return(int64(10)).AnyTimes()
+	ch.replicatorMap.Store("rep1", rep1)
+	assert.Equal(t, ChannelHealthy, ch.State())
+	// write buffer is full
+	ch.ch <- WriteEntry{}
+	assert.Equal(t, ChannelBackpressured, ch.State())
+
+	// replicator is disconnected
+	rep2 := NewMockReplicator(ctrl)
+	rep2.EXPECT().IsReady().Return(false).AnyTimes()
+	rep2.EXPECT().Pending().Return(int64(5)).AnyTimes()
+	ch.replicatorMap.Store("rep2", rep2)
+	assert.Equal(t, ChannelCircuitOpen, ch.State())
+	assert.Equal(t, int64(15), ch.Pending())
+}
+
+func TestChannelManager_Stats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cm := &channelManager{}
+	assert.Equal(t, models.ChannelStats{}, cm.Stats())
+
+	newChannel := func(state ChannelState, pending int64) Channel {
+		ch := NewMockChannel(ctrl)
+		ch.EXPECT().State().Return(state).AnyTimes()
+		ch.EXPECT().Pending().Return(pending).AnyTimes()
+		return ch
+	}
+	cm.channelMap.Store("db/0", newChannel(ChannelHealthy, 1))
+	cm.channelMap.Store("db/1", newChannel(ChannelHealthy, 0))
+	cm.channelMap.Store("db/2", newChannel(ChannelBackpressured, 100))
+	cm.channelMap.Store("db/3", newChannel(ChannelCircuitOpen, 20))
+	assert.Equal(t, models.ChannelStats{
+		Healthy:       2,
+		Backpressured: 1,
+		CircuitOpen:   1,
+		Pending:       121,
+	}, cm.Stats())
+}
+
+func TestChannel_InFlightWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := &channel{
+		ctx:          ctx,
+		ch:           make(chan WriteEntry, 10),
+		maxInFlight:  10,
+		writeTimeout: time.Second,
+	}
+	var pending atomic.Int64
+	rep1 := NewMockReplicator(ctrl)
+	rep1.EXPECT().Pending().Return(int64(0)).AnyTimes()
+	rep1.EXPECT().IsReady().Return(true).AnyTimes()
+	rep2 := NewMockReplicator(ctrl)
+	rep2.EXPECT().Pending().DoAndReturn(func() int64 {
+		return pending.Load()
+	}).AnyTimes()
+	rep2.EXPECT().IsReady().Return(true).AnyTimes()
+	ch.replicatorMap.Store("rep1", rep1)
+	ch.replicatorMap.Store("rep2", rep2)
+
+	assert.NoError(t, ch.Write([]byte("1")))
+	assert.Equal(t, ChannelHealthy, ch.State())
+
+	// window of slowest replicator is full, write blocks until acks reduce pending
+	pending.Store(10)
+	assert.Equal(t, ChannelBackpressured, ch.State())
+	time.AfterFunc(200*time.Millisecond, func() {
+		pending.Store(9)
+	})
+	start := time.Now()
+	assert.NoError(t, ch.Write([]byte("2")))
+	assert.True(t, time.Since(start) >= 200*time.Millisecond)
+	assert.Len(t, ch.ch, 2)
+
+	// window still full after timeout
+	pending.Store(11)
+	ch.writeTimeout = 100 * time.Millisecond
+	assert.Equal(t, ErrInFlightWindowFull, ch.Write([]byte("3")))
+	// no wait
+	ch.writeTimeout = 0
+	assert.Equal(t, ErrInFlightWindowFull, ch.Write([]byte("3")))
+	// channel canceled when blocking
+	ch.writeTimeout = time.Minute
+	time.AfterFunc(100*time.Millisecond, cancel)
+	assert.Equal(t, ErrCanceled, ch.Write([]byte("3")))
+	assert.Len(t, ch.ch, 2)
+
+	// unlimited window
+	ch.maxInFlight = 0
+	assert.Equal(t, ChannelHealthy, ch.State())
+}
+
+func TestChannel_ReplicaState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	q := queue.NewMockFanOutQueue(ctrl)
+	now := time.Now()
+	ch := &channel{database: database, shardID: 1, q: q, lastAppendSeq: 10, lastRateTime: now}
+	assert.Empty(t, ch.ReplicaState())
+
+	// 20 messages appended in 2 seconds
+	q.EXPECT().HeadSeq().Return(int64(30))
+	ch.updateAppendRate(now.Add(2 * time.Second))
+	// no time elapsed
+	ch.updateAppendRate(now.Add(2 * time.Second))
+
+	rep := NewMockReplicator(ctrl)
+	rep.EXPECT().Target().Return(node)
+	rep.EXPECT().Pending().Return(int64(8))
+	rep.EXPECT().ReplicaIndex().Return(int64(25))
+	rep.EXPECT().AckIndex().Return(int64(21))
+	rep.EXPECT().LastAckedSeq().Return(int64(21))
+	ch.replicatorMap.Store(node, rep)
+	assert.Equal(t, []models.ReplicaState{{
+		Database:     database,
+		ShardID:      1,
+		Target:       node,
+		Pending:      8,
+		ReplicaIndex: 25,
+		AckIndex:     21,
+		LastAckedSeq: 21,
+		AppendRate:   10,
+	}}, ch.ReplicaState())
+
+	// aggregate state of all channels
+	cm := &channelManager{}
+	assert.Empty(t, cm.ReplicaState())
+	ch1 := NewMockChannel(ctrl)
+	ch1.EXPECT().ReplicaState().Return([]models.ReplicaState{{ShardID: 1}, {ShardID: 1, Pending: 1}})
+	ch2 := NewMockChannel(ctrl)
+	ch2.EXPECT().ReplicaState().Return([]models.ReplicaState{{ShardID: 2}})
+	cm.channelMap.Store("db/1", ch1)
+	cm.channelMap.Store("db/2", ch2)
+	assert.Len(t, cm.ReplicaState(), 3)
+}
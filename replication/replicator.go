@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"go.uber.org/atomic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
 	"github.com/lindb/lindb/models"
 	"github.com/lindb/lindb/pkg/logger"
@@ -14,6 +16,8 @@ import (
 	"github.com/lindb/lindb/rpc/proto/storage"
 )
 
+//go:generate mockgen -source=./replicator.go -destination=./replicator_mock.go -package=replication
+
 const (
 	batchReplicaSize = 10
 	//maxPendingSeqSize = 100
@@ -28,12 +32,16 @@ type Replicator interface {
 	Database() string
 	// ShardID returns the shardID attribution.
 	ShardID() int32
-	// Pending returns the num of messages remaining to replicate.
+	// Pending returns the num of messages remaining to replicate, including the messages sent but not acked.
 	Pending() int64
 	// ReplicaIndex returns the index of message replica
 	ReplicaIndex() int64
 	// AckIndex returns the index of message replica ack
 	AckIndex() int64
+	// LastAckedSeq returns the last seq acked by target, -1 if no ack received
+	LastAckedSeq() int64
+	// IsReady returns if the stream to target is ready for replication
+	IsReady() bool
 	// Stop stops the replication task.
 	Stop()
 }
@@ -51,12 +59,17 @@ type replicator struct {
 	streamClient storage.WriteService_WriteClient
 	// current WriteServiceClient
 	serviceClient storage.WriteServiceClient
+	// compression of replica data negotiated with target, empty if not compressed
+	compression string
 	// lock to protect clients
 	lock4client sync.RWMutex
 	// 0 -> running, 1 -> stopped
 	stopped atomic.Int32
 	// 0 -> notReady, 1 -> ready
 	ready atomic.Int32
+	// the last seq acked by target, -1 if no ack received,
+	// the messages after it are re-sent after reconnecting if target has not received them.
+	lastAckedSeq atomic.Int64
 	//storage received cur sequence num
 	//storageCurSeq int64
 	logger *logger.Logger
@@ -73,6 +86,7 @@ func newReplicator(target models.Node, database string, shardID int32,
 		fct:      fct,
 		logger:   logger.GetLogger("replication", "Replicator"),
 	}
+	r.lastAckedSeq.Store(-1)
 
 	go r.recvLoop()
 	go r.sendLoop()
@@ -95,9 +109,36 @@ func (r *replicator) ShardID() int32 {
 	return r.shardID
 }
 
-// Pending returns the num of messages remaining to replicate.
+// Pending returns the num of messages remaining to replicate, including the messages sent but not acked.
 func (r *replicator) Pending() int64 {
-	return r.fo.Pending()
+	// messages not consumed + messages consumed but not acked
+	pending := r.fo.Pending() + r.fo.HeadSeq() - r.resumeSeq()
+	if pending < 0 {
+		return 0
+	}
+	return pending
+}
+
+// resumeSeq returns the first seq not acked by target, from which the messages are re-sent after reconnecting.
+func (r *replicator) resumeSeq() int64 {
+	// tail seq of fanOut may be acked before restart, re-sends it for at-least-once
+	resumeSeq := r.fo.TailSeq()
+	if lastAcked := r.lastAckedSeq.Load(); lastAcked >= resumeSeq {
+		resumeSeq = lastAcked + 1
+	}
+	return resumeSeq
+}
+
+// ack records the seq acked by target, then acks the fanOut.
+// The ack of message not consumed is ignored, which may be sent by target before reconnecting.
+func (r *replicator) ack(seq int64) {
+	if seq >= r.fo.HeadSeq() {
+		return
+	}
+	if seq > r.lastAckedSeq.Load() {
+		r.lastAckedSeq.Store(seq)
+	}
+	r.fo.Ack(seq)
 }
 
 // ReplicaIndex returns the index of message replica
@@ -110,6 +151,11 @@ func (r *replicator) AckIndex() int64 {
 	return r.fo.TailSeq()
 }
 
+// LastAckedSeq returns the last seq acked by target, -1 if no ack received
+func (r *replicator) LastAckedSeq() int64 {
+	return r.lastAckedSeq.Load()
+}
+
 // Stop stops the replication task.
 func (r *replicator) Stop() {
 	r.stopped.Store(1)
@@ -120,7 +166,8 @@ func (r *replicator) isStopped() bool {
 	return r.stopped.Load() == 1
 }
 
-func (r *replicator) isReady() bool {
+// IsReady returns if the stream to target is ready for replication
+func (r *replicator) IsReady() bool {
 	return r.ready.Load() == 1
 }
 
@@ -151,7 +198,7 @@ func (r *replicator) recvLoop() {
 	}()
 
 	for {
-		if !r.isReady() {
+		if !r.IsReady() {
 			r.initClient()
 		}
 
@@ -173,7 +220,7 @@ func (r *replicator) recvLoop() {
 		// ackSeq could be nil, means no ack signal
 		ack, ok := resp.Ack.(*storage.WriteResponse_AckSeq)
 		if ok {
-			r.fo.Ack(ack.AckSeq)
+			r.ack(ack.AckSeq)
 		}
 	}
 }
@@ -194,7 +241,7 @@ func (r *replicator) initClient() {
 		r.serviceClient = serviceClient
 
 		// get storage head seq, reset fanOut headSeq or reset storage headSeq.
-		nextSeq, err := r.remoteNextSeq()
+		nextSeq, compression, err := r.remoteNextSeq()
 		if err != nil {
 			r.logger.Error("recvLoop get remote next seq error", logger.Error(err))
 			// typically CreateWriteServiceClient won't return err if remote target is unavailable(async dial), the real rpc call will.
@@ -204,20 +251,27 @@ func (r *replicator) initClient() {
 		}
 
 		// try to reset fanOut headSeq, if success, consume from new headSeq,
-		// if fail, try to reset remote headSeq.
+		// if fail, re-sends the messages not acked, resets both fanOut headSeq and remote headSeq to resume seq,
+		// the consumed messages after last acked seq may be lost by target when the stream breaks,
+		// so resume from the consumed seq would skip them.
 		r.logger.Info("recvLoop try to set fanOut head seq", logger.Int64("headSeq", nextSeq))
 		if err := r.fo.SetHeadSeq(nextSeq); err != nil {
 			r.logger.Error("recvLoop reset fanOut head seq error", logger.Error(err))
 
-			foHeadSeq := r.fo.HeadSeq()
-			r.logger.Info("recvLoop try to set remote storage head seq", logger.Int64("headSeq", foHeadSeq))
-			if err := r.resetRemoteSeq(foHeadSeq); err != nil {
+			resumeSeq := r.resumeSeq()
+			r.logger.Info("recvLoop try to resume from last acked seq", logger.Int64("headSeq", resumeSeq))
+			if err := r.fo.SetHeadSeq(resumeSeq); err != nil {
+				r.logger.Error("recvLoop reset fanOut head seq to resume seq error", logger.Error(err))
+				time.Sleep(time.Second)
+				continue
+			}
+			if err := r.resetRemoteSeq(resumeSeq); err != nil {
 				r.logger.Error("recvLoop reset remote head seq error", logger.Error(err))
 				continue
 			}
 		}
 
-		streamClient, err := r.fct.CreateWriteClient(r.database, r.shardID, r.target)
+		streamClient, err := r.fct.CreateWriteClient(r.database, r.shardID, r.target, compression)
 		if err != nil {
 			r.logger.Error("recvLoop get clientStreaming error", logger.Error(err))
 			continue
@@ -226,13 +280,15 @@ func (r *replicator) initClient() {
 		r.logger.Info("recvLoop get clientStreaming success")
 		r.lock4client.Lock()
 		r.streamClient = streamClient
+		r.compression = compression
 		r.lock4client.Unlock()
 		break
 	}
 	r.setReady(true)
 }
 
-func (r *replicator) remoteNextSeq() (int64, error) {
+// remoteNextSeq returns the next seq of target and the compression of replica data supported by target.
+func (r *replicator) remoteNextSeq() (int64, string, error) {
 	nextReq := &storage.NextSeqRequest{
 		Database: r.database,
 		ShardID:  r.shardID,
@@ -240,12 +296,13 @@ func (r *replicator) remoteNextSeq() (int64, error) {
 
 	ctx, cancel := context.WithTimeout(context.TODO(), unaryRPCTimeout)
 	ctx = rpc.CreateOutgoingContextWithNode(ctx, r.fct.LogicNode())
-	nextResp, err := r.serviceClient.Next(ctx, nextReq)
+	var header metadata.MD
+	nextResp, err := r.serviceClient.Next(ctx, nextReq, grpc.Header(&header))
 	cancel()
 	if err != nil {
-		return -1, err
+		return -1, "", err
 	}
-	return nextResp.Seq, nil
+	return nextResp.Seq, rpc.GetCompressionFromHeader(header), nil
 }
 
 func (r *replicator) resetRemoteSeq(resetSeq int64) error {
@@ -287,7 +344,7 @@ func (r *replicator) sendLoop() {
 		}
 
 		// conn not ready
-		if !r.isReady() {
+		if !r.IsReady() {
 			time.Sleep(time.Second)
 			continue
 		}
@@ -298,10 +355,6 @@ func (r *replicator) sendLoop() {
 			time.Sleep(10 * time.Millisecond)
 			continue
 		}
-		wr := &storage.WriteRequest{
-			Replicas: replicas,
-		}
-
 		//todo debug level
 		r.logger.Info("send replicas",
 			logger.Int64("begin", replicas[0].Seq),
@@ -310,7 +363,15 @@ func (r *replicator) sendLoop() {
 		// recvLoop may change streamClient
 		r.lock4client.RLock()
 		cli := r.streamClient
+		compression := r.compression
 		r.lock4client.RUnlock()
+
+		for _, replica := range replicas {
+			replica.Data = rpc.EncodePayload(compression, replica.Data)
+		}
+		wr := &storage.WriteRequest{
+			Replicas: replicas,
+		}
 		if err := cli.Send(wr); err != nil {
 			r.logger.Error("sendLoop write request error", logger.Error(err))
 			r.setReady(false)
@@ -1,9 +1,13 @@
 package replication
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -11,9 +15,12 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
 	"github.com/lindb/lindb/models"
 	"github.com/lindb/lindb/pkg/queue"
+	"github.com/lindb/lindb/pkg/queue/segment"
 	"github.com/lindb/lindb/rpc"
 	"github.com/lindb/lindb/rpc/proto/storage"
 )
@@ -38,15 +45,16 @@ case get remote nextSeq success, set local fanOut seq fail, set remote head seq
 fct.CreateWriteServiceClient success
 r.serviceClient.Next(ctx, nextReq) success
 r.fo.SetHeadSeq(nextSeq) fail
-r.resetRemoteSeq(r.fo.HeadSeq()) fail
+r.fo.SetHeadSeq(resumeSeq) success
+r.resetRemoteSeq(resumeSeq) fail
 
 
 case get remote nextSeq success, set local fanOut seq fail, set remote head seq success:
 fct.CreateWriteServiceClient success
 r.serviceClient.Next(ctx, nextReq) success
 r.fo.SetHeadSeq(nextSeq) fail
-r.resetRemoteSeq(r.fo.HeadSeq()) fail
-r.serviceClient.Reset(ctx, nextReq) success
+r.fo.SetHeadSeq(resumeSeq) success
+r.resetRemoteSeq(resumeSeq) success
 
 ////
 with replicas
@@ -102,10 +110,11 @@ func buildWriteRequest(seqBegin, seqEnd int64) (*storage.WriteRequest, string) {
 	return wr, fmt.Sprintf("[%d,%d)", seqBegin, seqEnd)
 }
 
-// messageLen, message
+// writeSeq, messageLen, message
 func buildMessageBytes(seq int) []byte {
 	numInBytes := []byte(strconv.Itoa(seq))
 	buf := stream.NewBufferWriter(nil)
+	buf.PutVarint64(int64(seq))
 	buf.PutUvarint32(uint32(len(numInBytes)))
 	buf.PutBytes(numInBytes)
 	bytes, err := buf.Bytes()
@@ -143,7 +152,7 @@ func TestGetRemoteNextSeqFail(t *testing.T) {
 	defer ctl.Finish()
 
 	mockServiceClient := storage.NewMockWriteServiceClient(ctl)
-	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any()).Return(nil, errors.New("get remote next seq error"))
+	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("get remote next seq error"))
 
 	mockFct := rpc.NewMockClientStreamFactory(ctl)
 	mockFct.EXPECT().CreateWriteServiceClient(node).Return(nil, errors.New("get service client error"))
@@ -169,14 +178,15 @@ case get remote nextSeq success, set local fanOut seq fail:
 fct.CreateWriteServiceClient success
 r.serviceClient.Next(ctx, nextReq) success
 r.fo.SetHeadSeq(nextSeq) fail
-r.resetRemoteSeq(r.fo.HeadSeq()) fail
+r.fo.SetHeadSeq(resumeSeq) success
+r.resetRemoteSeq(resumeSeq) fail
 */
 func TestSetLocalHeadSeqFail(t *testing.T) {
 	ctl := gomock.NewController(t)
 	defer ctl.Finish()
 
 	mockServiceClient := storage.NewMockWriteServiceClient(ctl)
-	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any()).Return(&storage.NextSeqResponse{
+	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any(), gomock.Any()).Return(&storage.NextSeqResponse{
 		Seq: 0,
 	}, nil)
 	mockServiceClient.EXPECT().Reset(gomock.Any(), gomock.Any()).Return(nil, errors.New("reset remote next seq error"))
@@ -195,7 +205,9 @@ func TestSetLocalHeadSeqFail(t *testing.T) {
 
 	mockFanOut := queue.NewMockFanOut(ctl)
 	mockFanOut.EXPECT().SetHeadSeq(gomock.Any()).Return(errors.New("fanOut set head seq error"))
-	mockFanOut.EXPECT().HeadSeq().Return(int64(0))
+	// resume from last acked seq
+	mockFanOut.EXPECT().TailSeq().Return(int64(0))
+	mockFanOut.EXPECT().SetHeadSeq(int64(0)).Return(nil)
 
 	rep := newReplicator(node, database, shardID, mockFanOut, mockFct)
 
@@ -215,14 +227,14 @@ func TestSetLocalHeadSeqSuccess(t *testing.T) {
 
 	nextSeq := int64(5)
 	mockServiceClient := storage.NewMockWriteServiceClient(ctl)
-	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any()).Return(&storage.NextSeqResponse{
+	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any(), gomock.Any()).Return(&storage.NextSeqResponse{
 		Seq: nextSeq,
 	}, nil)
 
 	mockFct := rpc.NewMockClientStreamFactory(ctl)
 	mockFct.EXPECT().CreateWriteServiceClient(node).Return(mockServiceClient, nil)
 	mockFct.EXPECT().LogicNode().Return(node)
-	mockFct.EXPECT().CreateWriteClient(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("create stream client error"))
+	mockFct.EXPECT().CreateWriteClient(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("create stream client error"))
 
 	done := make(chan struct{})
 	mockFct.EXPECT().CreateWriteServiceClient(node).DoAndReturn(func(_ models.Node) (storage.WriteServiceClient, error) {
@@ -246,15 +258,15 @@ case get remote nextSeq success, set local fanOut seq fail, set remote head seq
 fct.CreateWriteServiceClient success
 r.serviceClient.Next(ctx, nextReq) success
 r.fo.SetHeadSeq(nextSeq) fail
-r.resetRemoteSeq(r.fo.HeadSeq()) fail
-r.serviceClient.Reset(ctx, nextReq) success
+r.fo.SetHeadSeq(resumeSeq) success
+r.resetRemoteSeq(resumeSeq) success
 */
 func TestResetRemoteSeqSuccess(t *testing.T) {
 	ctl := gomock.NewController(t)
 	defer ctl.Finish()
 
 	mockServiceClient := storage.NewMockWriteServiceClient(ctl)
-	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any()).Return(&storage.NextSeqResponse{
+	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any(), gomock.Any()).Return(&storage.NextSeqResponse{
 		Seq: 0,
 	}, nil)
 	mockServiceClient.EXPECT().Reset(gomock.Any(), gomock.Any()).Return(&storage.ResetSeqResponse{}, nil)
@@ -262,7 +274,7 @@ func TestResetRemoteSeqSuccess(t *testing.T) {
 	mockFct := rpc.NewMockClientStreamFactory(ctl)
 	mockFct.EXPECT().CreateWriteServiceClient(node).Return(mockServiceClient, nil)
 	mockFct.EXPECT().LogicNode().Return(node).Times(2)
-	mockFct.EXPECT().CreateWriteClient(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("creat write client error"))
+	mockFct.EXPECT().CreateWriteClient(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("creat write client error"))
 
 	done := make(chan struct{})
 	mockFct.EXPECT().CreateWriteServiceClient(node).DoAndReturn(func(_ models.Node) (storage.WriteServiceClient, error) {
@@ -273,7 +285,9 @@ func TestResetRemoteSeqSuccess(t *testing.T) {
 
 	mockFanOut := queue.NewMockFanOut(ctl)
 	mockFanOut.EXPECT().SetHeadSeq(gomock.Any()).Return(errors.New("fanOut set head seq error"))
-	mockFanOut.EXPECT().HeadSeq().Return(int64(0))
+	// resume from last acked seq
+	mockFanOut.EXPECT().TailSeq().Return(int64(0))
+	mockFanOut.EXPECT().SetHeadSeq(int64(0)).Return(nil)
 
 	rep := newReplicator(node, database, shardID, mockFanOut, mockFct)
 
@@ -298,7 +312,7 @@ func TestNormalReplication(t *testing.T) {
 
 	nextSeq := int64(5)
 	mockServiceClient := storage.NewMockWriteServiceClient(ctl)
-	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any()).Return(&storage.NextSeqResponse{
+	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any(), gomock.Any()).Return(&storage.NextSeqResponse{
 		Seq: nextSeq,
 	}, nil)
 
@@ -320,7 +334,7 @@ func TestNormalReplication(t *testing.T) {
 	mockFct := rpc.NewMockClientStreamFactory(ctl)
 	mockFct.EXPECT().CreateWriteServiceClient(node).Return(mockServiceClient, nil)
 	mockFct.EXPECT().LogicNode().Return(node)
-	mockFct.EXPECT().CreateWriteClient(database, shardID, node).Return(mockClientStream, nil)
+	mockFct.EXPECT().CreateWriteClient(database, shardID, node, "").Return(mockClientStream, nil)
 
 	mockFanOut := queue.NewMockFanOut(ctl)
 	mockFanOut.EXPECT().SetHeadSeq(nextSeq).Return(nil)
@@ -338,6 +352,60 @@ func TestNormalReplication(t *testing.T) {
 	close(done)
 }
 
+func TestReplication_compression(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	// storage advertises snappy compression
+	mockServiceClient := storage.NewMockWriteServiceClient(ctl)
+	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ *storage.NextSeqRequest, opts ...grpc.CallOption) (*storage.NextSeqResponse, error) {
+			for _, opt := range opts {
+				if header, ok := opt.(grpc.HeaderCallOption); ok {
+					*header.HeaderAddr = metadata.Pairs("metaKeyCompression", rpc.CompressionSnappy)
+				}
+			}
+			return &storage.NextSeqResponse{Seq: 5}, nil
+		})
+
+	done := make(chan struct{})
+	mockClientStream := storage.NewMockWriteService_WriteClient(ctl)
+	mockClientStream.EXPECT().Recv().DoAndReturn(func() (*storage.WriteResponse, error) {
+		<-done
+		return nil, errors.New("stream canceled")
+	})
+	var sent []*storage.Replica
+	mockClientStream.EXPECT().Send(gomock.Any()).DoAndReturn(func(wr *storage.WriteRequest) error {
+		sent = append(sent, wr.Replicas...)
+		return nil
+	})
+
+	mockFct := rpc.NewMockClientStreamFactory(ctl)
+	mockFct.EXPECT().CreateWriteServiceClient(node).Return(mockServiceClient, nil)
+	mockFct.EXPECT().LogicNode().Return(node)
+	mockFct.EXPECT().CreateWriteClient(database, shardID, node, rpc.CompressionSnappy).Return(mockClientStream, nil)
+
+	mockFanOut := queue.NewMockFanOut(ctl)
+	mockFanOut.EXPECT().SetHeadSeq(int64(5)).Return(nil)
+	for i := 5; i < 8; i++ {
+		mockFanOut.EXPECT().Consume().Return(int64(i))
+		mockFanOut.EXPECT().Get(int64(i)).Return(buildMessageBytes(i), nil)
+	}
+	mockFanOut.EXPECT().Consume().Return(queue.SeqNoNewMessageAvailable).AnyTimes()
+
+	rep := newReplicator(node, database, shardID, mockFanOut, mockFct)
+	time.Sleep(time.Second * 2)
+	rep.Stop()
+	close(done)
+
+	assert.Len(t, sent, 3)
+	for idx, replica := range sent {
+		data, err := rpc.DecodePayload(rpc.CompressionSnappy, replica.Data)
+		assert.NoError(t, err)
+		assert.Equal(t, buildMessageBytes(idx+5), data)
+	}
+}
+
 /**
 case replication seq not match, first set local fanOut seq to 5, second set to 7:
 fct.CreateWriteServiceClient success
@@ -361,10 +429,10 @@ func TestReplicationSeqNotMatch(t *testing.T) {
 	defer ctl.Finish()
 
 	mockServiceClient := storage.NewMockWriteServiceClient(ctl)
-	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any()).Return(&storage.NextSeqResponse{
+	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any(), gomock.Any()).Return(&storage.NextSeqResponse{
 		Seq: 5,
 	}, nil)
-	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any()).Return(&storage.NextSeqResponse{
+	mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any(), gomock.Any()).Return(&storage.NextSeqResponse{
 		Seq: 7,
 	}, nil)
 
@@ -398,11 +466,11 @@ func TestReplicationSeqNotMatch(t *testing.T) {
 	// first time
 	mockFct.EXPECT().CreateWriteServiceClient(node).Return(mockServiceClient, nil)
 	mockFct.EXPECT().LogicNode().Return(node)
-	mockFct.EXPECT().CreateWriteClient(database, shardID, node).Return(mockClientStream, nil)
+	mockFct.EXPECT().CreateWriteClient(database, shardID, node, "").Return(mockClientStream, nil)
 	// second time
 	mockFct.EXPECT().CreateWriteServiceClient(node).Return(mockServiceClient, nil)
 	mockFct.EXPECT().LogicNode().Return(node)
-	mockFct.EXPECT().CreateWriteClient(database, shardID, node).Return(mockClientStream, nil)
+	mockFct.EXPECT().CreateWriteClient(database, shardID, node, "").Return(mockClientStream, nil)
 
 	mockFanOut := queue.NewMockFanOut(ctl)
 	mockFanOut.EXPECT().SetHeadSeq(int64(5)).Return(nil)
@@ -426,3 +494,117 @@ func TestReplicationSeqNotMatch(t *testing.T) {
 	rep.Stop()
 	close(done2)
 }
+
+/**
+case stream breaks before the sent replicas are acked, target restarts and loses them:
+first stream: send 0 ~ 5, target acks 1, then stream breaks
+r.serviceClient.Next(ctx, nextReq) success next = 0, which is less than fanOut tail seq
+r.fo.SetHeadSeq(nextSeq) fail
+resume from last acked seq 1, reset fanOut and remote head seq to 2
+second stream: re-send 2 ~ 5, target acks 4
+*/
+func TestReplication_resumeFromLastAcked(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	dirPath := path.Join(os.TempDir(), "test_replicator_resume")
+	defer func() {
+		if err := os.RemoveAll(dirPath); err != nil {
+			t.Error(err)
+		}
+	}()
+	fq, err := queue.NewFanOutQueue(dirPath, 1024*1024, segment.CodecNone, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fq.Close()
+	fo, err := fq.GetOrCreateFanOut(node.Indicator())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		_, err := fq.Append(buildMessageBytes(i))
+		assert.NoError(t, err)
+	}
+
+	mockServiceClient := storage.NewMockWriteServiceClient(ctl)
+	gomock.InOrder(
+		mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any(), gomock.Any()).Return(&storage.NextSeqResponse{Seq: 0}, nil),
+		// target restarts, loses the replicas not acked
+		mockServiceClient.EXPECT().Next(gomock.Any(), gomock.Any(), gomock.Any()).Return(&storage.NextSeqResponse{Seq: 0}, nil),
+		mockServiceClient.EXPECT().Reset(gomock.Any(), &storage.ResetSeqRequest{
+			Database: database,
+			ShardID:  shardID,
+			Seq:      2,
+		}).Return(&storage.ResetSeqResponse{}, nil),
+	)
+
+	var rep Replicator
+	var lock sync.Mutex
+	var sent [][]int64
+	sendFunc := func(sentCh chan struct{}) func(wr *storage.WriteRequest) error {
+		return func(wr *storage.WriteRequest) error {
+			var seqs []int64
+			for _, replica := range wr.Replicas {
+				seqs = append(seqs, replica.Seq)
+			}
+			lock.Lock()
+			sent = append(sent, seqs)
+			lock.Unlock()
+			close(sentCh)
+			return nil
+		}
+	}
+
+	sent1 := make(chan struct{})
+	stream1 := storage.NewMockWriteService_WriteClient(ctl)
+	stream1.EXPECT().Send(gomock.Any()).DoAndReturn(sendFunc(sent1))
+	gomock.InOrder(
+		stream1.EXPECT().Recv().DoAndReturn(func() (*storage.WriteResponse, error) {
+			<-sent1
+			return &storage.WriteResponse{CurSeq: 4, Ack: &storage.WriteResponse_AckSeq{AckSeq: 1}}, nil
+		}),
+		stream1.EXPECT().Recv().Return(nil, errors.New("stream broken")),
+	)
+
+	sent2 := make(chan struct{})
+	done := make(chan struct{})
+	stream2 := storage.NewMockWriteService_WriteClient(ctl)
+	stream2.EXPECT().Send(gomock.Any()).DoAndReturn(sendFunc(sent2))
+	gomock.InOrder(
+		stream2.EXPECT().Recv().DoAndReturn(func() (*storage.WriteResponse, error) {
+			<-sent2
+			// replicas sent but not acked are still pending
+			assert.Equal(t, int64(3), rep.Pending())
+			return &storage.WriteResponse{CurSeq: 4, Ack: &storage.WriteResponse_AckSeq{AckSeq: 4}}, nil
+		}),
+		stream2.EXPECT().Recv().DoAndReturn(func() (*storage.WriteResponse, error) {
+			<-done
+			return nil, errors.New("stream canceled")
+		}),
+	)
+
+	mockFct := rpc.NewMockClientStreamFactory(ctl)
+	mockFct.EXPECT().CreateWriteServiceClient(node).Return(mockServiceClient, nil).Times(2)
+	mockFct.EXPECT().LogicNode().Return(node).AnyTimes()
+	gomock.InOrder(
+		mockFct.EXPECT().CreateWriteClient(database, shardID, node, "").Return(stream1, nil),
+		mockFct.EXPECT().CreateWriteClient(database, shardID, node, "").Return(stream2, nil),
+	)
+
+	rep = newReplicator(node, database, shardID, fo, mockFct)
+	assert.Equal(t, int64(5), rep.Pending())
+	// pending returns to zero only after all replicas acked
+	for i := 0; i < 50 && rep.Pending() > 0; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	rep.Stop()
+	close(done)
+
+	assert.Equal(t, int64(0), rep.Pending())
+	assert.Equal(t, int64(4), rep.AckIndex())
+	lock.Lock()
+	defer lock.Unlock()
+	// no replica is skipped, the replicas after last acked seq are re-sent
+	assert.Equal(t, [][]int64{{0, 1, 2, 3, 4}, {2, 3, 4}}, sent)
+}
@@ -0,0 +1,83 @@
+package replication
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lindb/lindb/pkg/stream"
+)
+
+// WriteEntry represents the metric list bytes written into the channel,
+// stamped with the monotonically increasing write sequence of the shard at ingest.
+type WriteEntry struct {
+	WriteSeq int64
+	Data     []byte
+}
+
+const (
+	// writeEntriesMarker is the first byte of the chunk with write sequence, the legacy chunk written before
+	// write sequence starts with the length of metric list bytes, which is never 0 because empty list is not written.
+	writeEntriesMarker byte = 0
+	// writeEntriesVersion is the format version of the chunk with write sequence
+	writeEntriesVersion byte = 1
+)
+
+// appendWriteEntry appends the write entry into the buffer, the chunk header is written before the first entry.
+// layout: [marker(1 byte), version(1 byte)], then [write seq(varint64), length of data(uvarint32), data]...
+func appendWriteEntry(binary *stream.BufferWriter, entry WriteEntry) {
+	if binary.Len() == 0 {
+		binary.PutByte(writeEntriesMarker)
+		binary.PutByte(writeEntriesVersion)
+	}
+	binary.PutVarint64(entry.WriteSeq)
+	binary.PutUvarint32(uint32(len(entry.Data)))
+	binary.PutBytes(entry.Data)
+}
+
+// DecodeWriteEntries decodes the write entries from the replica data, returns the entries decoded before error.
+// The legacy data without write sequence is decoded with write sequence 0, so that it is replayed in written order
+// and before the data written after upgrade.
+func DecodeWriteEntries(data []byte) ([]WriteEntry, error) {
+	if len(data) == 0 || data[0] != writeEntriesMarker {
+		return decodeLegacyWriteEntries(data)
+	}
+	if len(data) < 2 || data[1] != writeEntriesVersion {
+		return nil, fmt.Errorf("unknown write entries format version")
+	}
+	var entries []WriteEntry
+	reader := stream.NewReader(data[2:])
+	for !reader.Empty() {
+		writeSeq := reader.ReadVarint64()
+		length := reader.ReadUvarint32()
+		bytes := reader.ReadSlice(int(length))
+		if err := reader.Error(); err != nil {
+			return entries, err
+		}
+		entries = append(entries, WriteEntry{WriteSeq: writeSeq, Data: bytes})
+	}
+	return entries, nil
+}
+
+// decodeLegacyWriteEntries decodes the write entries written before write sequence.
+// layout: [length of data(uvarint32), data]...
+func decodeLegacyWriteEntries(data []byte) ([]WriteEntry, error) {
+	var entries []WriteEntry
+	reader := stream.NewReader(data)
+	for !reader.Empty() {
+		length := reader.ReadUvarint32()
+		bytes := reader.ReadSlice(int(length))
+		if err := reader.Error(); err != nil {
+			return entries, err
+		}
+		entries = append(entries, WriteEntry{Data: bytes})
+	}
+	return entries, nil
+}
+
+// SortWriteEntries sorts the write entries by write sequence, keeps the original order for same sequence,
+// so that the entries are replayed in ingest order, which last-value semantics depends on.
+func SortWriteEntries(entries []WriteEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].WriteSeq < entries[j].WriteSeq
+	})
+}
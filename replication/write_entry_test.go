@@ -0,0 +1,92 @@
+package replication
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/pkg/stream"
+)
+
+func TestWriteEntry_Decode(t *testing.T) {
+	buf := stream.NewBufferWriter(nil)
+	appendWriteEntry(buf, WriteEntry{WriteSeq: 3, Data: []byte("c")})
+	appendWriteEntry(buf, WriteEntry{WriteSeq: 1, Data: []byte("a")})
+	appendWriteEntry(buf, WriteEntry{WriteSeq: 2, Data: []byte("b")})
+	data, _ := buf.Bytes()
+
+	entries, err := DecodeWriteEntries(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []WriteEntry{
+		{WriteSeq: 3, Data: []byte("c")},
+		{WriteSeq: 1, Data: []byte("a")},
+		{WriteSeq: 2, Data: []byte("b")},
+	}, entries)
+
+	// corrupted data returns entries decoded before error
+	entries, err = DecodeWriteEntries(data[:len(data)-1])
+	assert.Error(t, err)
+	assert.Len(t, entries, 2)
+
+	// unknown format version
+	entries, err = DecodeWriteEntries([]byte{writeEntriesMarker, 2})
+	assert.Error(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestWriteEntry_DecodeLegacy(t *testing.T) {
+	// the data written before write sequence: [length of data(uvarint32), data]...
+	buf := stream.NewBufferWriter(nil)
+	for _, data := range []string{"a", "b"} {
+		buf.PutUvarint32(uint32(len(data)))
+		buf.PutBytes([]byte(data))
+	}
+	data, _ := buf.Bytes()
+
+	entries, err := DecodeWriteEntries(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []WriteEntry{{Data: []byte("a")}, {Data: []byte("b")}}, entries)
+
+	// the legacy entries are replayed before the entries with write sequence, in written order
+	entries = append(entries, WriteEntry{WriteSeq: 1, Data: []byte("c")})
+	SortWriteEntries(entries)
+	assert.Equal(t, []WriteEntry{{Data: []byte("a")}, {Data: []byte("b")}, {WriteSeq: 1, Data: []byte("c")}}, entries)
+
+	// corrupted legacy data
+	entries, err = DecodeWriteEntries(data[:len(data)-1])
+	assert.Error(t, err)
+	assert.Len(t, entries, 1)
+
+	entries, err = DecodeWriteEntries(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestWriteEntry_Sort(t *testing.T) {
+	entries := []WriteEntry{
+		{WriteSeq: 3, Data: []byte("last")},
+		{WriteSeq: 1, Data: []byte("first")},
+		{WriteSeq: 2, Data: []byte("second-1")},
+		{WriteSeq: 2, Data: []byte("second-2")},
+	}
+	SortWriteEntries(entries)
+	var values []string
+	for _, entry := range entries {
+		values = append(values, string(entry.Data))
+	}
+	assert.Equal(t, []string{"first", "second-1", "second-2", "last"}, values)
+}
+
+func TestChannel_WriteSeq(t *testing.T) {
+	ch := &channel{ctx: context.TODO(), ch: make(chan WriteEntry, 3)}
+	ch.writeSeq.Store(100)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, ch.Write([]byte{byte(i)}))
+	}
+	for i := 0; i < 3; i++ {
+		entry := <-ch.ch
+		assert.Equal(t, int64(101+i), entry.WriteSeq)
+		assert.Equal(t, []byte{byte(i)}, entry.Data)
+	}
+}
@@ -0,0 +1,60 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/snappy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const metaKeyCompression = "metaKeyCompression"
+
+// CompressionSnappy represents the write payload compressed by snappy.
+const CompressionSnappy = "snappy"
+
+// SetCompressionHeader advertises the write payload compression supported by storage in the response header,
+// the broker compresses the payload only if the compression is advertised, so old storage keeps working.
+func SetCompressionHeader(ctx context.Context) error {
+	return grpc.SetHeader(ctx, metadata.Pairs(metaKeyCompression, CompressionSnappy))
+}
+
+// GetCompressionFromHeader returns the compression advertised by the response header, empty if none.
+func GetCompressionFromHeader(header metadata.MD) string {
+	for _, compression := range header.Get(metaKeyCompression) {
+		if compression == CompressionSnappy {
+			return compression
+		}
+	}
+	return ""
+}
+
+// GetCompressionFromContext returns the compression of the write stream, empty if not compressed.
+func GetCompressionFromContext(ctx context.Context) string {
+	compression, err := getStringFromContext(ctx, metaKeyCompression)
+	if err != nil {
+		return ""
+	}
+	return compression
+}
+
+// EncodePayload compresses the write payload by the compression, returns the payload as is if no compression.
+func EncodePayload(compression string, data []byte) []byte {
+	if compression == CompressionSnappy {
+		return snappy.Encode(nil, data)
+	}
+	return data
+}
+
+// DecodePayload decompresses the write payload by the compression, returns the payload as is if no compression.
+func DecodePayload(compression string, data []byte) ([]byte, error) {
+	switch compression {
+	case "":
+		return data, nil
+	case CompressionSnappy:
+		return snappy.Decode(nil, data)
+	default:
+		return nil, fmt.Errorf("not support compression: %s", compression)
+	}
+}
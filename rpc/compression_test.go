@@ -0,0 +1,78 @@
+package rpc
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/lindb/lindb/pkg/stream"
+	"github.com/lindb/lindb/rpc/proto/field"
+)
+
+func TestPayload_snappy(t *testing.T) {
+	// tag-heavy batch
+	metricList := &field.MetricList{Database: "db"}
+	for i := 0; i < 100; i++ {
+		metricList.Metrics = append(metricList.Metrics, &field.Metric{
+			Name:      "system.cpu.utilization",
+			Timestamp: 1564300800000,
+			Tags: map[string]string{
+				"host":       "host-" + strconv.Itoa(i),
+				"datacenter": "datacenter-shanghai",
+				"cluster":    "cluster-lindb-storage",
+			},
+			Fields: []*field.Field{{Name: "sum", Field: &field.Field_Sum{Sum: &field.Sum{Value: float64(i)}}}},
+		})
+	}
+	data, err := metricList.Marshal()
+	assert.NoError(t, err)
+	buf := stream.NewBufferWriter(nil)
+	buf.PutVarint64(1)
+	buf.PutUvarint32(uint32(len(data)))
+	buf.PutBytes(data)
+	payload, _ := buf.Bytes()
+
+	compressed := EncodePayload(CompressionSnappy, payload)
+	assert.True(t, len(compressed) < len(payload))
+	decompressed, err := DecodePayload(CompressionSnappy, compressed)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, decompressed)
+
+	reader := stream.NewReader(decompressed)
+	assert.Equal(t, int64(1), reader.ReadVarint64())
+	length := reader.ReadUvarint32()
+	var decoded field.MetricList
+	assert.NoError(t, decoded.Unmarshal(reader.ReadSlice(int(length))))
+	assert.Equal(t, metricList.Metrics, decoded.Metrics)
+
+	// corrupted
+	_, err = DecodePayload(CompressionSnappy, []byte{0xff, 0xff, 0xff})
+	assert.Error(t, err)
+}
+
+func TestPayload_noCompression(t *testing.T) {
+	payload := []byte("payload")
+	assert.Equal(t, payload, EncodePayload("", payload))
+	data, err := DecodePayload("", payload)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, data)
+
+	_, err = DecodePayload("gzip", payload)
+	assert.Error(t, err)
+}
+
+func TestCompression_negotiation(t *testing.T) {
+	// not a grpc server stream
+	assert.Error(t, SetCompressionHeader(context.TODO()))
+
+	assert.Equal(t, "", GetCompressionFromHeader(nil))
+	assert.Equal(t, "", GetCompressionFromHeader(metadata.Pairs(metaKeyCompression, "gzip")))
+	assert.Equal(t, CompressionSnappy, GetCompressionFromHeader(metadata.Pairs(metaKeyCompression, CompressionSnappy)))
+
+	assert.Equal(t, "", GetCompressionFromContext(context.TODO()))
+	ctx := createIncomingContextWithPairs(context.TODO(), metaKeyCompression, CompressionSnappy)
+	assert.Equal(t, CompressionSnappy, GetCompressionFromContext(ctx))
+}
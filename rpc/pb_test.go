@@ -3,6 +3,7 @@ package rpc
 import (
 	"testing"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/lindb/lindb/pkg/timeutil"
@@ -15,6 +16,11 @@ func TestPBModel(t *testing.T) {
 		Timestamp: timeutil.Now(),
 		Fields: []*field.Field{
 			{Name: "f1", Field: &field.Field_Sum{Sum: &field.Sum{Value: 1.0}}},
+			{Name: "f2", Field: &field.Field_Distribution{Distribution: &field.Distribution{Values: []float64{1.0, 2.5}}}},
+			{Name: "f3", Field: &field.Field_Min{Min: &field.Min{Value: 3.0}}},
+			{Name: "f4", Field: &field.Field_Max{Max: &field.Max{Value: 4.0}}},
+			{Name: "f5", Field: &field.Field_Count{Count: &field.Count{Value: 5.0}}},
+			{Name: "f6", Field: &field.Field_Counter{Counter: &field.Counter{Value: 6.0}}},
 		},
 	}
 
@@ -22,4 +28,18 @@ func TestPBModel(t *testing.T) {
 	metric2 := &field.Metric{}
 	_ = metric2.Unmarshal(data)
 	assert.Equal(t, *metric, *metric2)
+	assert.Equal(t, []float64{1.0, 2.5}, metric2.Fields[1].GetDistribution().GetValues())
+
+	// reflection based marshal/unmarshal
+	data, err := proto.Marshal(metric)
+	assert.NoError(t, err)
+	metric3 := &field.Metric{}
+	assert.NoError(t, proto.Unmarshal(data, metric3))
+	assert.Equal(t, metric.Fields[1].GetDistribution().GetValues(), metric3.Fields[1].GetDistribution().GetValues())
+	assert.Contains(t, proto.CompactTextString(metric.Fields[1]), "distribution")
+	assert.Equal(t, 3.0, metric3.Fields[2].GetMin().GetValue())
+	assert.Equal(t, 4.0, metric3.Fields[3].GetMax().GetValue())
+	assert.Equal(t, 5.0, metric3.Fields[4].GetCount().GetValue())
+	assert.Equal(t, 6.0, metric3.Fields[5].GetCounter().GetValue())
+	assert.Contains(t, proto.CompactTextString(metric.Fields[5]), "counter")
 }
@@ -139,6 +139,7 @@ type TaskResponse struct {
 	Completed            bool     `protobuf:"varint,3,opt,name=completed,proto3" json:"completed,omitempty"`
 	ErrMsg               string   `protobuf:"bytes,4,opt,name=errMsg,proto3" json:"errMsg,omitempty"`
 	Payload              []byte   `protobuf:"bytes,5,opt,name=payload,proto3" json:"payload,omitempty"`
+	Partial              bool     `protobuf:"varint,6,opt,name=partial,proto3" json:"partial,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -212,6 +213,13 @@ func (m *TaskResponse) GetPayload() []byte {
 	return nil
 }
 
+func (m *TaskResponse) GetPartial() bool {
+	if m != nil {
+		return m.Partial
+	}
+	return false
+}
+
 type TimeSeriesList struct {
 	TimeSeriesList       []*TimeSeries `protobuf:"bytes,1,rep,name=timeSeriesList,proto3" json:"timeSeriesList,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
@@ -327,35 +335,36 @@ func init() {
 func init() { proto.RegisterFile("common.proto", fileDescriptor_555bd8c177793206) }
 
 var fileDescriptor_555bd8c177793206 = []byte{
-	// 446 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x53, 0xcb, 0x6e, 0xd3, 0x40,
-	0x14, 0xcd, 0xe4, 0xe1, 0xc6, 0x37, 0x56, 0x65, 0x5d, 0x2a, 0x64, 0x45, 0x95, 0x65, 0x79, 0x65,
-	0xb1, 0x88, 0xaa, 0x56, 0x02, 0xda, 0x25, 0x2a, 0xa8, 0x11, 0x21, 0xa0, 0x69, 0x10, 0xeb, 0x69,
-	0x7c, 0x1b, 0x4c, 0xfd, 0xc2, 0x33, 0xad, 0xe4, 0x7f, 0xe0, 0x03, 0xe0, 0x8f, 0x58, 0xf2, 0x09,
-	0x28, 0xec, 0xf8, 0x0a, 0xe4, 0xb1, 0xa9, 0x6b, 0x9e, 0xbb, 0x39, 0x67, 0xce, 0xb9, 0x3a, 0xbe,
-	0x3e, 0x03, 0xd6, 0x3a, 0x4b, 0x92, 0x2c, 0x9d, 0xe5, 0x45, 0xa6, 0x32, 0x34, 0x6a, 0xe4, 0x7f,
-	0x62, 0x30, 0x59, 0x09, 0x79, 0xc5, 0xe9, 0xfd, 0x35, 0x49, 0x85, 0x7b, 0x30, 0x7a, 0x97, 0x5d,
-	0xcc, 0x4f, 0x1d, 0xe6, 0xb1, 0x60, 0xc0, 0x6b, 0x80, 0x3e, 0x58, 0xb9, 0x28, 0x28, 0x55, 0x95,
-	0x74, 0x7e, 0xea, 0xf4, 0x3d, 0x16, 0x98, 0xbc, 0xc3, 0x21, 0xc2, 0x50, 0x95, 0x39, 0x39, 0x03,
-	0x8f, 0x05, 0x23, 0xae, 0xcf, 0xda, 0xf7, 0xb6, 0x94, 0xd1, 0x5a, 0xc4, 0xaf, 0x62, 0x91, 0x3a,
-	0x43, 0x8f, 0x05, 0x16, 0xef, 0x70, 0xe8, 0xc0, 0x4e, 0x2e, 0xca, 0x38, 0x13, 0xa1, 0x33, 0xd2,
-	0xd7, 0x3f, 0xa1, 0xff, 0x81, 0x81, 0x55, 0x67, 0x93, 0x79, 0x96, 0x4a, 0xfa, 0x4b, 0xb8, 0xfb,
-	0x60, 0x74, 0x62, 0x35, 0x08, 0xf7, 0xc1, 0x5c, 0x67, 0x49, 0x1e, 0x93, 0xa2, 0x50, 0xa7, 0x1a,
-	0xf3, 0x96, 0xa8, 0x5c, 0x54, 0x14, 0x2f, 0xe4, 0x46, 0x87, 0x32, 0x79, 0x83, 0xfe, 0x11, 0x67,
-	0x01, 0xbb, 0xab, 0x28, 0xa1, 0x73, 0x2a, 0x22, 0x92, 0x8b, 0x48, 0x2a, 0x3c, 0x81, 0x5d, 0xd5,
-	0x61, 0x1c, 0xe6, 0x0d, 0x82, 0xc9, 0x21, 0xce, 0x9a, 0x5d, 0xb7, 0x7a, 0xfe, 0x8b, 0xd2, 0xff,
-	0xce, 0x00, 0xda, 0x6b, 0x3c, 0x80, 0xa1, 0x12, 0x1b, 0xd9, 0x0c, 0xd8, 0xff, 0x7d, 0xc0, 0x6c,
-	0x25, 0x36, 0xf2, 0x69, 0xaa, 0x8a, 0x92, 0x6b, 0x25, 0x3e, 0x04, 0xe3, 0x32, 0xa2, 0x38, 0x94,
-	0x4e, 0x5f, 0x7b, 0xdc, 0x3f, 0x78, 0x9e, 0x69, 0x41, 0xed, 0x6a, 0xd4, 0xd3, 0x47, 0x60, 0xde,
-	0x8e, 0x42, 0x1b, 0x06, 0x57, 0x54, 0xea, 0x7d, 0x9a, 0xbc, 0x3a, 0x56, 0x3b, 0xbe, 0x11, 0xf1,
-	0x35, 0x35, 0xcb, 0xac, 0xc1, 0x49, 0xff, 0x31, 0x9b, 0x1e, 0xc3, 0xe4, 0xce, 0xbc, 0xff, 0x59,
-	0xad, 0x3b, 0xd6, 0x07, 0x47, 0x30, 0xae, 0x7e, 0xca, 0xaa, 0xea, 0xc4, 0x04, 0x76, 0x5e, 0x2f,
-	0x9f, 0x2f, 0x5f, 0xbe, 0x59, 0xda, 0x3d, 0xb4, 0xc1, 0x9a, 0xa7, 0x8a, 0x8a, 0x84, 0xc2, 0x48,
-	0x28, 0xb2, 0x19, 0x8e, 0x61, 0xb8, 0x20, 0x71, 0x69, 0xf7, 0x0f, 0xcf, 0xea, 0x66, 0x9e, 0x53,
-	0x71, 0x13, 0xad, 0x09, 0x8f, 0xc1, 0x38, 0x13, 0x69, 0x18, 0x13, 0xde, 0xbb, 0xfd, 0xd2, 0xb6,
-	0xb8, 0xd3, 0xbd, 0x2e, 0x59, 0x37, 0xc6, 0xef, 0x05, 0xec, 0x80, 0x3d, 0xb1, 0x3f, 0x6f, 0x5d,
-	0xf6, 0x65, 0xeb, 0xb2, 0xaf, 0x5b, 0x97, 0x7d, 0xfc, 0xe6, 0xf6, 0x2e, 0x0c, 0xfd, 0x0a, 0x8e,
-	0x7e, 0x04, 0x00, 0x00, 0xff, 0xff, 0x57, 0x5d, 0x48, 0xac, 0x15, 0x03, 0x00, 0x00,
+	// 454 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x53, 0xcd, 0x6e, 0xd3, 0x40,
+	0x18, 0xcc, 0xe6, 0xc7, 0x8d, 0xbf, 0x58, 0x95, 0xb5, 0x54, 0x68, 0x15, 0x55, 0x96, 0xe5, 0x93,
+	0xc5, 0x21, 0xaa, 0x5a, 0x09, 0x68, 0x8f, 0xa8, 0xa0, 0x46, 0x84, 0x80, 0xb6, 0x41, 0x9c, 0xb7,
+	0xf1, 0xd7, 0x60, 0xea, 0x3f, 0x76, 0xb7, 0x95, 0xfc, 0x26, 0xf0, 0x02, 0x3c, 0x0b, 0x47, 0x1e,
+	0x01, 0x85, 0x1b, 0x4f, 0x81, 0xbc, 0x76, 0x9b, 0x1a, 0x88, 0x7a, 0xdb, 0x99, 0x9d, 0x19, 0x8d,
+	0xbf, 0xfd, 0x0c, 0xce, 0x32, 0x4f, 0xd3, 0x3c, 0x9b, 0x14, 0x32, 0xd7, 0x39, 0xb5, 0x6a, 0x14,
+	0x7c, 0x25, 0x30, 0x5a, 0x08, 0x75, 0xc5, 0xf1, 0xf3, 0x35, 0x2a, 0x4d, 0xf7, 0x60, 0xf0, 0x29,
+	0xbf, 0x98, 0x9e, 0x32, 0xe2, 0x93, 0xb0, 0xc7, 0x6b, 0x40, 0x03, 0x70, 0x0a, 0x21, 0x31, 0xd3,
+	0x95, 0x74, 0x7a, 0xca, 0xba, 0x3e, 0x09, 0x6d, 0xde, 0xe2, 0x28, 0x85, 0xbe, 0x2e, 0x0b, 0x64,
+	0x3d, 0x9f, 0x84, 0x03, 0x6e, 0xce, 0xc6, 0xf7, 0xb1, 0x54, 0xf1, 0x52, 0x24, 0xef, 0x12, 0x91,
+	0xb1, 0xbe, 0x4f, 0x42, 0x87, 0xb7, 0x38, 0xca, 0x60, 0xa7, 0x10, 0x65, 0x92, 0x8b, 0x88, 0x0d,
+	0xcc, 0xf5, 0x2d, 0x0c, 0xbe, 0x11, 0x70, 0xea, 0x6e, 0xaa, 0xc8, 0x33, 0x85, 0x5b, 0xca, 0x3d,
+	0x06, 0xab, 0x55, 0xab, 0x41, 0x74, 0x1f, 0xec, 0x65, 0x9e, 0x16, 0x09, 0x6a, 0x8c, 0x4c, 0xab,
+	0x21, 0xdf, 0x10, 0x95, 0x0b, 0xa5, 0x7c, 0xa3, 0x56, 0xa6, 0x94, 0xcd, 0x1b, 0xb4, 0xbd, 0x4e,
+	0x7d, 0x23, 0x75, 0x2c, 0x12, 0x66, 0x99, 0xb4, 0x5b, 0x18, 0xcc, 0x60, 0x77, 0x11, 0xa7, 0x78,
+	0x8e, 0x32, 0x46, 0x35, 0x8b, 0x95, 0xa6, 0x27, 0xb0, 0xab, 0x5b, 0x0c, 0x23, 0x7e, 0x2f, 0x1c,
+	0x1d, 0xd2, 0x49, 0xf3, 0x0a, 0x1b, 0x3d, 0xff, 0x4b, 0x19, 0xfc, 0x26, 0x00, 0x9b, 0x6b, 0x7a,
+	0x00, 0x7d, 0x2d, 0x56, 0xaa, 0x09, 0xd8, 0xff, 0x37, 0x60, 0xb2, 0x10, 0x2b, 0xf5, 0x32, 0xd3,
+	0xb2, 0xe4, 0x46, 0x49, 0x9f, 0x82, 0x75, 0x19, 0x63, 0x12, 0x29, 0xd6, 0x35, 0x1e, 0xef, 0x3f,
+	0x9e, 0x57, 0x46, 0x50, 0xbb, 0x1a, 0xf5, 0xf8, 0x19, 0xd8, 0x77, 0x51, 0xd4, 0x85, 0xde, 0x15,
+	0x96, 0x66, 0xd2, 0x36, 0xaf, 0x8e, 0xd5, 0xf4, 0x6f, 0x44, 0x72, 0x8d, 0xcd, 0x98, 0x6b, 0x70,
+	0xd2, 0x7d, 0x4e, 0xc6, 0xc7, 0x30, 0xba, 0x97, 0xf7, 0x90, 0xd5, 0xb9, 0x67, 0x7d, 0x72, 0x04,
+	0xc3, 0xea, 0xb9, 0x16, 0xd5, 0xb6, 0x8c, 0x60, 0xe7, 0xfd, 0xfc, 0xf5, 0xfc, 0xed, 0x87, 0xb9,
+	0xdb, 0xa1, 0x2e, 0x38, 0xd3, 0x4c, 0xa3, 0x4c, 0x31, 0x8a, 0x85, 0x46, 0x97, 0xd0, 0x21, 0xf4,
+	0x67, 0x28, 0x2e, 0xdd, 0xee, 0xe1, 0x59, 0xbd, 0xb3, 0xe7, 0x28, 0x6f, 0xe2, 0x25, 0xd2, 0x63,
+	0xb0, 0xce, 0x44, 0x16, 0x25, 0x48, 0x1f, 0xdd, 0x7d, 0xe9, 0x66, 0xa5, 0xc7, 0x7b, 0x6d, 0xb2,
+	0xde, 0xa5, 0xa0, 0x13, 0x92, 0x03, 0xf2, 0xc2, 0xfd, 0xbe, 0xf6, 0xc8, 0x8f, 0xb5, 0x47, 0x7e,
+	0xae, 0x3d, 0xf2, 0xe5, 0x97, 0xd7, 0xb9, 0xb0, 0xcc, 0xff, 0x71, 0xf4, 0x67, 0x00, 0xdb, 0x0b,
+	0x73, 0xaf, 0x2f, 0x03, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -552,6 +561,16 @@ func (m *TaskResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if m.Partial {
+		i--
+		if m.Partial {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x30
+	}
 	if len(m.Payload) > 0 {
 		i -= len(m.Payload)
 		copy(dAtA[i:], m.Payload)
@@ -764,6 +783,9 @@ func (m *TaskResponse) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovCommon(uint64(l))
 	}
+	if m.Partial {
+		n += 2
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -1184,6 +1206,26 @@ func (m *TaskResponse) Unmarshal(dAtA []byte) error {
 				m.Payload = []byte{}
 			}
 			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Partial", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCommon
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Partial = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipCommon(dAtA[iNdEx:])
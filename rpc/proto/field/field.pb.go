@@ -486,6 +486,11 @@ type Field struct {
 	//	*Field_Gauge
 	//	*Field_Summary
 	//	*Field_Histogram
+	//	*Field_Distribution
+	//	*Field_Min
+	//	*Field_Max
+	//	*Field_Count
+	//	*Field_Counter
 	Field                isField_Field `protobuf_oneof:"field"`
 	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
 	XXX_unrecognized     []byte        `json:"-"`
@@ -543,11 +548,31 @@ type Field_Summary struct {
 type Field_Histogram struct {
 	Histogram *Histogram `protobuf:"bytes,5,opt,name=histogram,proto3,oneof"`
 }
+type Field_Distribution struct {
+	Distribution *Distribution `protobuf:"bytes,6,opt,name=distribution,proto3,oneof"`
+}
+type Field_Min struct {
+	Min *Min `protobuf:"bytes,7,opt,name=min,proto3,oneof"`
+}
+type Field_Max struct {
+	Max *Max `protobuf:"bytes,8,opt,name=max,proto3,oneof"`
+}
+type Field_Count struct {
+	Count *Count `protobuf:"bytes,9,opt,name=count,proto3,oneof"`
+}
+type Field_Counter struct {
+	Counter *Counter `protobuf:"bytes,10,opt,name=counter,proto3,oneof"`
+}
 
-func (*Field_Sum) isField_Field()       {}
-func (*Field_Gauge) isField_Field()     {}
-func (*Field_Summary) isField_Field()   {}
-func (*Field_Histogram) isField_Field() {}
+func (*Field_Sum) isField_Field()          {}
+func (*Field_Gauge) isField_Field()        {}
+func (*Field_Summary) isField_Field()      {}
+func (*Field_Histogram) isField_Field()    {}
+func (*Field_Distribution) isField_Field() {}
+func (*Field_Min) isField_Field()          {}
+func (*Field_Max) isField_Field()          {}
+func (*Field_Count) isField_Field()        {}
+func (*Field_Counter) isField_Field()      {}
 
 func (m *Field) GetField() isField_Field {
 	if m != nil {
@@ -591,6 +616,41 @@ func (m *Field) GetHistogram() *Histogram {
 	return nil
 }
 
+func (m *Field) GetDistribution() *Distribution {
+	if x, ok := m.GetField().(*Field_Distribution); ok {
+		return x.Distribution
+	}
+	return nil
+}
+
+func (m *Field) GetMin() *Min {
+	if x, ok := m.GetField().(*Field_Min); ok {
+		return x.Min
+	}
+	return nil
+}
+
+func (m *Field) GetMax() *Max {
+	if x, ok := m.GetField().(*Field_Max); ok {
+		return x.Max
+	}
+	return nil
+}
+
+func (m *Field) GetCount() *Count {
+	if x, ok := m.GetField().(*Field_Count); ok {
+		return x.Count
+	}
+	return nil
+}
+
+func (m *Field) GetCounter() *Counter {
+	if x, ok := m.GetField().(*Field_Counter); ok {
+		return x.Counter
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*Field) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _Field_OneofMarshaler, _Field_OneofUnmarshaler, _Field_OneofSizer, []interface{}{
@@ -598,6 +658,11 @@ func (*Field) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error,
 		(*Field_Gauge)(nil),
 		(*Field_Summary)(nil),
 		(*Field_Histogram)(nil),
+		(*Field_Distribution)(nil),
+		(*Field_Min)(nil),
+		(*Field_Max)(nil),
+		(*Field_Count)(nil),
+		(*Field_Counter)(nil),
 	}
 }
 
@@ -625,6 +690,31 @@ func _Field_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Histogram); err != nil {
 			return err
 		}
+	case *Field_Distribution:
+		_ = b.EncodeVarint(6<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Distribution); err != nil {
+			return err
+		}
+	case *Field_Min:
+		_ = b.EncodeVarint(7<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Min); err != nil {
+			return err
+		}
+	case *Field_Max:
+		_ = b.EncodeVarint(8<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Max); err != nil {
+			return err
+		}
+	case *Field_Count:
+		_ = b.EncodeVarint(9<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Count); err != nil {
+			return err
+		}
+	case *Field_Counter:
+		_ = b.EncodeVarint(10<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Counter); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("Field.Field has unexpected type %T", x)
@@ -667,6 +757,46 @@ func _Field_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer)
 		err := b.DecodeMessage(msg)
 		m.Field = &Field_Histogram{msg}
 		return true, err
+	case 6: // field.distribution
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Distribution)
+		err := b.DecodeMessage(msg)
+		m.Field = &Field_Distribution{msg}
+		return true, err
+	case 7: // field.min
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Min)
+		err := b.DecodeMessage(msg)
+		m.Field = &Field_Min{msg}
+		return true, err
+	case 8: // field.max
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Max)
+		err := b.DecodeMessage(msg)
+		m.Field = &Field_Max{msg}
+		return true, err
+	case 9: // field.count
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Count)
+		err := b.DecodeMessage(msg)
+		m.Field = &Field_Count{msg}
+		return true, err
+	case 10: // field.counter
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Counter)
+		err := b.DecodeMessage(msg)
+		m.Field = &Field_Counter{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -696,6 +826,31 @@ func _Field_OneofSizer(msg proto.Message) (n int) {
 		n += 1 // tag and wire
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *Field_Distribution:
+		s := proto.Size(x.Distribution)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Field_Min:
+		s := proto.Size(x.Min)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Field_Max:
+		s := proto.Size(x.Max)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Field_Count:
+		s := proto.Size(x.Count)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Field_Counter:
+		s := proto.Size(x.Counter)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -703,6 +858,243 @@ func _Field_OneofSizer(msg proto.Message) (n int) {
 	return n
 }
 
+// Distribution represents the observed values for quantile field, which are merged into sketch
+type Distribution struct {
+	Values               []float64 `protobuf:"fixed64,1,rep,packed,name=values,proto3" json:"values,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *Distribution) Reset()         { *m = Distribution{} }
+func (m *Distribution) String() string { return proto.CompactTextString(m) }
+func (*Distribution) ProtoMessage()    {}
+func (*Distribution) Descriptor() ([]byte, []int) {
+	return fileDescriptor_04234ff7fdd53e6e, []int{9}
+}
+func (m *Distribution) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Distribution) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Distribution.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Distribution) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Distribution.Merge(m, src)
+}
+func (m *Distribution) XXX_Size() int {
+	return m.Size()
+}
+func (m *Distribution) XXX_DiscardUnknown() {
+	xxx_messageInfo_Distribution.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Distribution proto.InternalMessageInfo
+
+func (m *Distribution) GetValues() []float64 {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+type Min struct {
+	Value                float64  `protobuf:"fixed64,1,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Min) Reset()         { *m = Min{} }
+func (m *Min) String() string { return proto.CompactTextString(m) }
+func (*Min) ProtoMessage()    {}
+func (*Min) Descriptor() ([]byte, []int) {
+	return fileDescriptor_04234ff7fdd53e6e, []int{10}
+}
+func (m *Min) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Min) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Min.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Min) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Min.Merge(m, src)
+}
+func (m *Min) XXX_Size() int {
+	return m.Size()
+}
+func (m *Min) XXX_DiscardUnknown() {
+	xxx_messageInfo_Min.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Min proto.InternalMessageInfo
+
+func (m *Min) GetValue() float64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+type Max struct {
+	Value                float64  `protobuf:"fixed64,1,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Max) Reset()         { *m = Max{} }
+func (m *Max) String() string { return proto.CompactTextString(m) }
+func (*Max) ProtoMessage()    {}
+func (*Max) Descriptor() ([]byte, []int) {
+	return fileDescriptor_04234ff7fdd53e6e, []int{11}
+}
+func (m *Max) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Max) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Max.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Max) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Max.Merge(m, src)
+}
+func (m *Max) XXX_Size() int {
+	return m.Size()
+}
+func (m *Max) XXX_DiscardUnknown() {
+	xxx_messageInfo_Max.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Max proto.InternalMessageInfo
+
+func (m *Max) GetValue() float64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+type Count struct {
+	Value                float64  `protobuf:"fixed64,1,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Count) Reset()         { *m = Count{} }
+func (m *Count) String() string { return proto.CompactTextString(m) }
+func (*Count) ProtoMessage()    {}
+func (*Count) Descriptor() ([]byte, []int) {
+	return fileDescriptor_04234ff7fdd53e6e, []int{12}
+}
+func (m *Count) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Count) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Count.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Count) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Count.Merge(m, src)
+}
+func (m *Count) XXX_Size() int {
+	return m.Size()
+}
+func (m *Count) XXX_DiscardUnknown() {
+	xxx_messageInfo_Count.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Count proto.InternalMessageInfo
+
+func (m *Count) GetValue() float64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+// Counter represents the raw cumulative value of monotonic counter
+type Counter struct {
+	Value                float64  `protobuf:"fixed64,1,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Counter) Reset()         { *m = Counter{} }
+func (m *Counter) String() string { return proto.CompactTextString(m) }
+func (*Counter) ProtoMessage()    {}
+func (*Counter) Descriptor() ([]byte, []int) {
+	return fileDescriptor_04234ff7fdd53e6e, []int{13}
+}
+func (m *Counter) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Counter) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Counter.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Counter) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Counter.Merge(m, src)
+}
+func (m *Counter) XXX_Size() int {
+	return m.Size()
+}
+func (m *Counter) XXX_DiscardUnknown() {
+	xxx_messageInfo_Counter.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Counter proto.InternalMessageInfo
+
+func (m *Counter) GetValue() float64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*MetricList)(nil), "field.MetricList")
 	proto.RegisterType((*Metric)(nil), "field.Metric")
@@ -714,42 +1106,54 @@ func init() {
 	proto.RegisterType((*Histogram)(nil), "field.Histogram")
 	proto.RegisterType((*Bucket)(nil), "field.Bucket")
 	proto.RegisterType((*Field)(nil), "field.Field")
+	proto.RegisterType((*Distribution)(nil), "field.Distribution")
+	proto.RegisterType((*Min)(nil), "field.Min")
+	proto.RegisterType((*Max)(nil), "field.Max")
+	proto.RegisterType((*Count)(nil), "field.Count")
+	proto.RegisterType((*Counter)(nil), "field.Counter")
 }
 
 func init() { proto.RegisterFile("field.proto", fileDescriptor_04234ff7fdd53e6e) }
 
 var fileDescriptor_04234ff7fdd53e6e = []byte{
-	// 472 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x93, 0xcf, 0x8e, 0xd3, 0x30,
-	0x10, 0xc6, 0xeb, 0xa6, 0x69, 0x37, 0x53, 0xfe, 0x54, 0x16, 0x12, 0xd1, 0x02, 0x51, 0x15, 0xad,
-	0x44, 0x05, 0xa2, 0x42, 0xcb, 0x01, 0x84, 0x10, 0x87, 0x4a, 0x40, 0x0e, 0x70, 0x58, 0x97, 0x23,
-	0x07, 0xdc, 0xd6, 0x84, 0x68, 0xeb, 0xa4, 0xc4, 0x36, 0x52, 0xdf, 0x84, 0x27, 0x42, 0x7b, 0xe4,
-	0x11, 0x50, 0x79, 0x11, 0xe4, 0xb1, 0x93, 0xb0, 0x12, 0x2b, 0xed, 0xa5, 0xf2, 0xcc, 0x7c, 0x33,
-	0xf3, 0x6b, 0x3e, 0x1b, 0xc6, 0x5f, 0x0a, 0xb1, 0xdd, 0xcc, 0x77, 0x75, 0xa5, 0x2b, 0x1a, 0x62,
-	0x90, 0x9e, 0x01, 0x7c, 0x10, 0xba, 0x2e, 0xd6, 0xef, 0x0b, 0xa5, 0xe9, 0x31, 0x1c, 0x6d, 0xb8,
-	0xe6, 0x2b, 0xae, 0x44, 0x4c, 0xa6, 0x64, 0x16, 0xb1, 0x36, 0xa6, 0x0f, 0x61, 0x24, 0x51, 0xa9,
-	0xe2, 0xfe, 0x34, 0x98, 0x8d, 0x4f, 0x6f, 0xce, 0xdd, 0x3c, 0xd7, 0xcf, 0x9a, 0x6a, 0xfa, 0x93,
-	0xc0, 0xd0, 0xe5, 0x28, 0x85, 0x41, 0xc9, 0x65, 0x33, 0x0b, 0xcf, 0xf4, 0x3e, 0x44, 0xba, 0x90,
-	0x42, 0x69, 0x2e, 0x77, 0x71, 0x7f, 0x4a, 0x66, 0x01, 0xeb, 0x12, 0xf4, 0x31, 0x0c, 0x34, 0xcf,
-	0x55, 0x1c, 0xe0, 0x8a, 0xbb, 0x97, 0x56, 0xcc, 0x3f, 0xf2, 0x5c, 0xbd, 0x29, 0x75, 0xbd, 0x67,
-	0x28, 0xa2, 0x27, 0x30, 0xc4, 0xba, 0x8a, 0x07, 0x28, 0xbf, 0xe1, 0xe5, 0x6f, 0xed, 0x2f, 0xf3,
-	0xb5, 0xe3, 0xe7, 0x10, 0xb5, 0x8d, 0x74, 0x02, 0xc1, 0xb9, 0xd8, 0x7b, 0x20, 0x7b, 0xa4, 0x77,
-	0x20, 0xfc, 0xce, 0xb7, 0x46, 0x20, 0x4b, 0xc4, 0x5c, 0xf0, 0xb2, 0xff, 0x82, 0xa4, 0xf7, 0x20,
-	0x58, 0x1a, 0xd9, 0x09, 0x6c, 0x13, 0xf1, 0x82, 0xf4, 0x01, 0x84, 0xef, 0xb8, 0xc9, 0xc5, 0x15,
-	0xe5, 0xcf, 0x30, 0x5a, 0x1a, 0x29, 0x79, 0xbd, 0xa7, 0x4f, 0x20, 0xfa, 0x66, 0x78, 0xa9, 0x8b,
-	0xad, 0x50, 0x31, 0x41, 0xd0, 0xdb, 0x1e, 0xf4, 0xcc, 0xe7, 0x59, 0xa7, 0xb0, 0x84, 0xca, 0x48,
-	0xa4, 0x21, 0xcc, 0x1e, 0xed, 0x86, 0x75, 0x65, 0x4a, 0x1d, 0x07, 0x6e, 0x03, 0x06, 0xe9, 0x2b,
-	0x38, 0x6a, 0xda, 0xad, 0x6f, 0xcd, 0x00, 0x8f, 0xd1, 0xc6, 0x97, 0xff, 0x5f, 0xcb, 0xf7, 0x09,
-	0xa2, 0xac, 0x50, 0xba, 0xca, 0x6b, 0x2e, 0xad, 0xb5, 0x2b, 0xb3, 0x3e, 0x17, 0xba, 0xe1, 0x6b,
-	0xac, 0x5d, 0x60, 0x96, 0x35, 0xd5, 0x6b, 0xb3, 0xbd, 0x86, 0xa1, 0x6b, 0xa5, 0x09, 0x80, 0xd9,
-	0xed, 0x44, 0xbd, 0xa8, 0x4c, 0xb9, 0xf1, 0x6c, 0xff, 0x64, 0xae, 0xa0, 0xbb, 0x20, 0x10, 0xa2,
-	0x89, 0xff, 0xbd, 0x41, 0x49, 0x47, 0x31, 0x3e, 0x05, 0x8f, 0xba, 0x34, 0x32, 0xeb, 0x39, 0xa6,
-	0x13, 0x08, 0x73, 0x6b, 0x0d, 0x32, 0x75, 0xb7, 0x02, 0xed, 0xca, 0x7a, 0xcc, 0x15, 0xe9, 0x23,
-	0x18, 0x29, 0xe7, 0x50, 0x3c, 0x40, 0xdd, 0xad, 0x6e, 0x92, 0xcd, 0x66, 0x3d, 0xd6, 0x08, 0xe8,
-	0x53, 0x88, 0xbe, 0x36, 0x5f, 0x2b, 0x0e, 0x51, 0x3d, 0xf1, 0xea, 0xf6, 0x2b, 0x66, 0x3d, 0xd6,
-	0x89, 0x16, 0x23, 0x70, 0x0f, 0x6c, 0x31, 0xb9, 0x38, 0x24, 0xe4, 0xd7, 0x21, 0x21, 0xbf, 0x0f,
-	0x09, 0xf9, 0xf1, 0x27, 0xe9, 0xad, 0x86, 0xf8, 0x00, 0x9f, 0xfd, 0x0d, 0x00, 0x00, 0xff, 0xff,
-	0xf1, 0x52, 0xbd, 0x82, 0x8f, 0x03, 0x00, 0x00,
+	// 588 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x94, 0xcf, 0x6e, 0xd4, 0x3e,
+	0x10, 0xc7, 0xe3, 0x66, 0x93, 0x34, 0xd3, 0xfe, 0x7e, 0x54, 0x06, 0x81, 0x55, 0x20, 0x54, 0xab,
+	0x0a, 0x2a, 0x10, 0x15, 0x2a, 0x07, 0xfe, 0x08, 0x71, 0xd8, 0xf2, 0x27, 0x07, 0x7a, 0xa8, 0xcb,
+	0x91, 0x03, 0xde, 0x6e, 0x58, 0xac, 0x36, 0xc9, 0x12, 0xdb, 0x68, 0xfb, 0x20, 0x48, 0x3c, 0x11,
+	0xe2, 0xc8, 0x23, 0xa0, 0xf2, 0x22, 0xc8, 0x63, 0x67, 0xd3, 0x48, 0x5d, 0x89, 0xcb, 0xca, 0x33,
+	0xdf, 0xef, 0xd8, 0x9f, 0xd9, 0xb1, 0x03, 0x6b, 0x9f, 0x64, 0x71, 0x3a, 0xd9, 0x9d, 0x35, 0xb5,
+	0xae, 0x69, 0x84, 0xc1, 0xf0, 0x10, 0xe0, 0xa0, 0xd0, 0x8d, 0x3c, 0x7e, 0x27, 0x95, 0xa6, 0x9b,
+	0xb0, 0x3a, 0x11, 0x5a, 0x8c, 0x85, 0x2a, 0x18, 0xd9, 0x22, 0x3b, 0x29, 0x5f, 0xc4, 0xf4, 0x1e,
+	0x24, 0x25, 0x3a, 0x15, 0x5b, 0xd9, 0x0a, 0x77, 0xd6, 0xf6, 0xfe, 0xdb, 0x75, 0xfb, 0xb9, 0x7a,
+	0xde, 0xaa, 0xc3, 0x1f, 0x04, 0x62, 0x97, 0xa3, 0x14, 0x06, 0x95, 0x28, 0xdb, 0xbd, 0x70, 0x4d,
+	0x6f, 0x41, 0xaa, 0x65, 0x59, 0x28, 0x2d, 0xca, 0x19, 0x5b, 0xd9, 0x22, 0x3b, 0x21, 0xef, 0x12,
+	0xf4, 0x01, 0x0c, 0xb4, 0x98, 0x2a, 0x16, 0xe2, 0x11, 0x37, 0x7a, 0x47, 0xec, 0xbe, 0x17, 0x53,
+	0xf5, 0xba, 0xd2, 0xcd, 0x19, 0x47, 0x13, 0xdd, 0x86, 0x18, 0x75, 0xc5, 0x06, 0x68, 0x5f, 0xf7,
+	0xf6, 0x37, 0xf6, 0x97, 0x7b, 0x6d, 0xf3, 0x09, 0xa4, 0x8b, 0x42, 0xba, 0x01, 0xe1, 0x49, 0x71,
+	0xe6, 0x81, 0xec, 0x92, 0x5e, 0x83, 0xe8, 0xab, 0x38, 0x35, 0x05, 0xb2, 0xa4, 0xdc, 0x05, 0xcf,
+	0x57, 0x9e, 0x92, 0xe1, 0x4d, 0x08, 0x8f, 0x4c, 0xd9, 0x19, 0x6c, 0x11, 0xf1, 0x86, 0xe1, 0x6d,
+	0x88, 0xde, 0x0a, 0x33, 0x2d, 0x96, 0xc8, 0x1f, 0x21, 0x39, 0x32, 0x65, 0x29, 0x9a, 0x33, 0xfa,
+	0x10, 0xd2, 0x2f, 0x46, 0x54, 0x5a, 0x9e, 0x16, 0x8a, 0x11, 0x04, 0xbd, 0xe2, 0x41, 0x0f, 0x7d,
+	0x9e, 0x77, 0x0e, 0x4b, 0xa8, 0x4c, 0x89, 0x34, 0x84, 0xdb, 0xa5, 0x3d, 0xe1, 0xb8, 0x36, 0x95,
+	0x66, 0xa1, 0x3b, 0x01, 0x83, 0xe1, 0x0b, 0x58, 0x6d, 0xcb, 0xed, 0xdc, 0xda, 0x0d, 0x3c, 0xc6,
+	0x22, 0xee, 0xf7, 0xb7, 0xe0, 0xfb, 0x00, 0x69, 0x2e, 0x95, 0xae, 0xa7, 0x8d, 0x28, 0xed, 0x68,
+	0xc7, 0xe6, 0xf8, 0xa4, 0xd0, 0x2d, 0x5f, 0x3b, 0xda, 0x11, 0x66, 0x79, 0xab, 0xfe, 0x33, 0xdb,
+	0x4b, 0x88, 0x5d, 0x29, 0xcd, 0x00, 0xcc, 0x6c, 0x56, 0x34, 0xa3, 0xda, 0x54, 0x13, 0xcf, 0x76,
+	0x21, 0xb3, 0x84, 0xee, 0x5b, 0x08, 0x11, 0x0e, 0xf1, 0xd2, 0x1b, 0x94, 0x75, 0x14, 0x6b, 0x7b,
+	0xe0, 0x51, 0x8f, 0x4c, 0x99, 0x07, 0x8e, 0x69, 0x1b, 0xa2, 0xa9, 0x1d, 0x0d, 0x32, 0x75, 0xb7,
+	0x02, 0xc7, 0x95, 0x07, 0xdc, 0x89, 0xf4, 0x3e, 0x24, 0xca, 0x4d, 0x88, 0x0d, 0xd0, 0xf7, 0x7f,
+	0xb7, 0x93, 0xcd, 0xe6, 0x01, 0x6f, 0x0d, 0xf4, 0x11, 0xa4, 0x9f, 0xdb, 0x7f, 0x8b, 0x45, 0xe8,
+	0xde, 0xf0, 0xee, 0xc5, 0xbf, 0x98, 0x07, 0xbc, 0x33, 0xd1, 0x67, 0xb0, 0x3e, 0x91, 0x4a, 0x37,
+	0x72, 0x6c, 0xb4, 0xac, 0x2b, 0x16, 0x63, 0xd1, 0x55, 0x5f, 0xf4, 0xea, 0x82, 0x94, 0x07, 0xbc,
+	0x67, 0xb5, 0xed, 0x95, 0xb2, 0x62, 0x49, 0xaf, 0xbd, 0x03, 0x69, 0x8d, 0x56, 0x40, 0x5d, 0xcc,
+	0xd9, 0x6a, 0x5f, 0x17, 0x73, 0xd4, 0xc5, 0xdc, 0xb6, 0xef, 0x46, 0x92, 0xf6, 0xda, 0xdf, 0xb7,
+	0x39, 0xdb, 0x3e, 0x8a, 0xb6, 0x7d, 0x5c, 0x14, 0x0d, 0x83, 0x5e, 0xfb, 0xfb, 0x2e, 0x6b, 0xdb,
+	0xf7, 0x86, 0x51, 0x02, 0xfe, 0x6b, 0x71, 0x17, 0xd6, 0x2f, 0xa2, 0xd3, 0xeb, 0x10, 0xe3, 0xc0,
+	0xdc, 0xbd, 0x21, 0xdc, 0x47, 0xf6, 0xe5, 0x1c, 0xc8, 0x6a, 0xc9, 0xd3, 0xb0, 0xa2, 0x98, 0x2f,
+	0x7f, 0x56, 0x08, 0xb0, 0x44, 0xbe, 0x03, 0x89, 0xe7, 0xbb, 0xdc, 0x30, 0xda, 0xf8, 0x79, 0x9e,
+	0x91, 0x5f, 0xe7, 0x19, 0xf9, 0x7d, 0x9e, 0x91, 0xef, 0x7f, 0xb2, 0x60, 0x1c, 0xe3, 0xf7, 0xee,
+	0xf1, 0xdf, 0x01, 0x00, 0xdc, 0x64, 0x53, 0xba, 0xfe, 0x04, 0x00, 0x00,
 }
 
 func (m *MetricList) Marshal() (dAtA []byte, err error) {
@@ -1245,25 +1649,293 @@ func (m *Field_Histogram) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	}
 	return len(dAtA) - i, nil
 }
-func encodeVarintField(dAtA []byte, offset int, v uint64) int {
-	offset -= sovField(v)
-	base := offset
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
-	}
-	dAtA[offset] = uint8(v)
-	return base
+func (m *Field_Distribution) MarshalTo(dAtA []byte) (int, error) {
+	return m.MarshalToSizedBuffer(dAtA[:m.Size()])
 }
-func (m *MetricList) Size() (n int) {
-	if m == nil {
-		return 0
+
+func (m *Field_Distribution) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	if m.Distribution != nil {
+		{
+			size, err := m.Distribution.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintField(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x32
 	}
-	var l int
-	_ = l
-	l = len(m.Database)
-	if l > 0 {
+	return len(dAtA) - i, nil
+}
+func (m *Distribution) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Distribution) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Distribution) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Values) > 0 {
+		for iNdEx := len(m.Values) - 1; iNdEx >= 0; iNdEx-- {
+			f1 := math.Float64bits(float64(m.Values[iNdEx]))
+			i -= 8
+			encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(f1))
+		}
+		i = encodeVarintField(dAtA, i, uint64(len(m.Values)*8))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Field_Min) MarshalTo(dAtA []byte) (int, error) {
+	return m.MarshalToSizedBuffer(dAtA[:m.Size()])
+}
+
+func (m *Field_Min) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	if m.Min != nil {
+		{
+			size, err := m.Min.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintField(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x3a
+	}
+	return len(dAtA) - i, nil
+}
+func (m *Min) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Min) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Min) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Value != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.Value))))
+		i--
+		dAtA[i] = 0x9
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Field_Max) MarshalTo(dAtA []byte) (int, error) {
+	return m.MarshalToSizedBuffer(dAtA[:m.Size()])
+}
+
+func (m *Field_Max) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	if m.Max != nil {
+		{
+			size, err := m.Max.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintField(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x42
+	}
+	return len(dAtA) - i, nil
+}
+func (m *Max) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Max) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Max) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Value != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.Value))))
+		i--
+		dAtA[i] = 0x9
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Field_Count) MarshalTo(dAtA []byte) (int, error) {
+	return m.MarshalToSizedBuffer(dAtA[:m.Size()])
+}
+
+func (m *Field_Count) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	if m.Count != nil {
+		{
+			size, err := m.Count.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintField(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x4a
+	}
+	return len(dAtA) - i, nil
+}
+func (m *Count) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Count) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Count) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Value != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.Value))))
+		i--
+		dAtA[i] = 0x9
+	}
+	return len(dAtA) - i, nil
+}
+func (m *Field_Counter) MarshalTo(dAtA []byte) (int, error) {
+	return m.MarshalToSizedBuffer(dAtA[:m.Size()])
+}
+
+func (m *Field_Counter) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	if m.Counter != nil {
+		{
+			size, err := m.Counter.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintField(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x52
+	}
+	return len(dAtA) - i, nil
+}
+func (m *Counter) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Counter) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Counter) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Value != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.Value))))
+		i--
+		dAtA[i] = 0x9
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintField(dAtA []byte, offset int, v uint64) int {
+	offset -= sovField(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *MetricList) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Database)
+	if l > 0 {
 		n += 1 + l + sovField(uint64(l))
 	}
 	if len(m.Metrics) > 0 {
@@ -1492,18 +2164,150 @@ func (m *Field_Histogram) Size() (n int) {
 	}
 	return n
 }
-
-func sovField(x uint64) (n int) {
-	return (math_bits.Len64(x|1) + 6) / 7
+func (m *Field_Distribution) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Distribution != nil {
+		l = m.Distribution.Size()
+		n += 1 + l + sovField(uint64(l))
+	}
+	return n
 }
-func sozField(x uint64) (n int) {
-	return sovField(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+func (m *Distribution) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Values) > 0 {
+		n += 1 + sovField(uint64(len(m.Values)*8)) + len(m.Values)*8
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
 }
-func (m *MetricList) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
+func (m *Field_Min) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Min != nil {
+		l = m.Min.Size()
+		n += 1 + l + sovField(uint64(l))
+	}
+	return n
+}
+func (m *Min) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Value != 0 {
+		n += 9
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Field_Max) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Max != nil {
+		l = m.Max.Size()
+		n += 1 + l + sovField(uint64(l))
+	}
+	return n
+}
+func (m *Max) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Value != 0 {
+		n += 9
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Field_Count) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Count != nil {
+		l = m.Count.Size()
+		n += 1 + l + sovField(uint64(l))
+	}
+	return n
+}
+func (m *Count) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Value != 0 {
+		n += 9
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+func (m *Field_Counter) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Counter != nil {
+		l = m.Counter.Size()
+		n += 1 + l + sovField(uint64(l))
+	}
+	return n
+}
+func (m *Counter) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Value != 0 {
+		n += 9
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func sovField(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozField(x uint64) (n int) {
+	return sovField(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *MetricList) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
 		var wire uint64
 		for shift := uint(0); ; shift += 7 {
 			if shift >= 64 {
@@ -2588,6 +3392,549 @@ func (m *Field) Unmarshal(dAtA []byte) error {
 			}
 			m.Field = &Field_Histogram{v}
 			iNdEx = postIndex
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Distribution", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowField
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthField
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthField
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &Distribution{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Field = &Field_Distribution{v}
+			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Min", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowField
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthField
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthField
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &Min{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Field = &Field_Min{v}
+			iNdEx = postIndex
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Max", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowField
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthField
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthField
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &Max{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Field = &Field_Max{v}
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowField
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthField
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthField
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &Count{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Field = &Field_Count{v}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Counter", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowField
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthField
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthField
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &Counter{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Field = &Field_Counter{v}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipField(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthField
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthField
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Distribution) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowField
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Distribution: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Distribution: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType == 1 {
+				var v uint64
+				if (iNdEx + 8) > l {
+					return io.ErrUnexpectedEOF
+				}
+				v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+				iNdEx += 8
+				v2 := float64(math.Float64frombits(v))
+				m.Values = append(m.Values, v2)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowField
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthField
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthField
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				elementCount = packedLen / 8
+				if elementCount != 0 && len(m.Values) == 0 {
+					m.Values = make([]float64, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v uint64
+					if (iNdEx + 8) > l {
+						return io.ErrUnexpectedEOF
+					}
+					v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+					iNdEx += 8
+					v2 := float64(math.Float64frombits(v))
+					m.Values = append(m.Values, v2)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Values", wireType)
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipField(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthField
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthField
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Min) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowField
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Min: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Min: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.Value = float64(math.Float64frombits(v))
+		default:
+			iNdEx = preIndex
+			skippy, err := skipField(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthField
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthField
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Max) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowField
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Max: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Max: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.Value = float64(math.Float64frombits(v))
+		default:
+			iNdEx = preIndex
+			skippy, err := skipField(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthField
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthField
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Count) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowField
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Count: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Count: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.Value = float64(math.Float64frombits(v))
+		default:
+			iNdEx = preIndex
+			skippy, err := skipField(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthField
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthField
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Counter) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowField
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Counter: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Counter: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.Value = float64(math.Float64frombits(v))
 		default:
 			iNdEx = preIndex
 			skippy, err := skipField(dAtA[iNdEx:])
@@ -79,8 +79,8 @@ func (fct *clientConnFactory) GetClientConn(target models.Node) (*grpc.ClientCon
 type ClientStreamFactory interface {
 	// LogicNode returns the a logic Node which will be transferred to the target server for identification.
 	LogicNode() models.Node
-	// CreateWriteClient creates a stream WriteClient.
-	CreateWriteClient(db string, shardID int32, target models.Node) (storage.WriteService_WriteClient, error)
+	// CreateWriteClient creates a stream WriteClient, the payload of stream is compressed by the compression if not empty.
+	CreateWriteClient(db string, shardID int32, target models.Node, compression string) (storage.WriteService_WriteClient, error)
 	// CreateQueryClient creates a stream task client
 	CreateTaskClient(target models.Node) (common.TaskService_HandleClient, error)
 	// CreateWriteServiceClient creates a WriteServiceClient
@@ -114,7 +114,7 @@ func (w *clientStreamFactory) CreateTaskClient(target models.Node) (common.TaskS
 
 // CreateWriteClient creates a WriteClient.
 func (w *clientStreamFactory) CreateWriteClient(db string, shardID int32,
-	target models.Node) (storage.WriteService_WriteClient, error) {
+	target models.Node, compression string) (storage.WriteService_WriteClient, error) {
 	conn, err := w.connFct.GetClientConn(target)
 	if err != nil {
 		return nil, err
@@ -122,6 +122,9 @@ func (w *clientStreamFactory) CreateWriteClient(db string, shardID int32,
 
 	// pass logicNode.ID as meta to rpc serve
 	ctx := createOutgoingContext(context.TODO(), db, shardID, w.LogicNode())
+	if compression != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, metaKeyCompression, compression)
+	}
 	cli, err := storage.NewWriteServiceClient(conn).Write(ctx)
 
 	return cli, err
@@ -93,6 +93,14 @@ func (b *BinaryIterator) Next() (startTime int64, fieldIt FieldIterator) {
 		return
 	}
 	data := b.reader.ReadBytes(int(length))
+	if b.fieldType == field.QuantileField {
+		// sketches of quantile field are merged before calculating the quantiles
+		sketchIt, err := UnmarshalSketchFieldIterator(data)
+		if err == nil {
+			fieldIt = sketchIt
+		}
+		return
+	}
 	if b.fieldIt == nil {
 		b.fieldIt = NewFieldIterator(data)
 	} else {
@@ -0,0 +1,215 @@
+package series
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/lindb/lindb/pkg/bit"
+	"github.com/lindb/lindb/pkg/encoding"
+	"github.com/lindb/lindb/pkg/stream"
+)
+
+// encoding flag of the primitive data in the delta encoded payload
+const (
+	rawPrimitive   byte = iota // keeps the tsd encoded data as is
+	deltaPrimitive             // slot deltas + zigzag value deltas
+)
+
+// maxDeltaValue is the max abs value which can be encoded as integer without loss of precision
+const maxDeltaValue = 1 << 53
+
+// EncodeDelta re-encodes the field data marshaled by MarshalIterator with delta/zigzag encoding
+// before rpc transmission. The start time of each segment is delta encoded, the primitive data
+// which values are all integers is encoded as slot deltas and zigzag value deltas,
+// otherwise the tsd encoded data is kept if delta encoding is not smaller.
+func EncodeDelta(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+	reader := stream.NewReader(data)
+	writer := stream.NewBufferWriter(nil)
+	writer.PutByte(reader.ReadByte()) // field type
+	prevStartTime := int64(0)
+	for !reader.Empty() {
+		startTime := reader.ReadVarint64()
+		writer.PutVarint64(startTime - prevStartTime)
+		prevStartTime = startTime
+		length := reader.ReadVarint32()
+		fieldData := reader.ReadBytes(int(length))
+		if reader.Error() != nil {
+			return nil, reader.Error()
+		}
+		encoded, err := encodeFieldDelta(fieldData)
+		if err != nil {
+			return nil, err
+		}
+		writer.PutVarint32(int32(len(encoded)))
+		writer.PutBytes(encoded)
+	}
+	return writer.Bytes()
+}
+
+// DecodeDelta decodes the delta encoded field data, returns the data as marshaled by MarshalIterator
+func DecodeDelta(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+	reader := stream.NewReader(data)
+	writer := stream.NewBufferWriter(nil)
+	writer.PutByte(reader.ReadByte()) // field type
+	startTime := int64(0)
+	for !reader.Empty() {
+		startTime += reader.ReadVarint64()
+		writer.PutVarint64(startTime)
+		length := reader.ReadVarint32()
+		fieldData := reader.ReadBytes(int(length))
+		if reader.Error() != nil {
+			return nil, reader.Error()
+		}
+		decoded, err := decodeFieldDelta(fieldData)
+		if err != nil {
+			return nil, err
+		}
+		writer.PutVarint32(int32(len(decoded)))
+		if len(decoded) > 0 {
+			writer.PutBytes(decoded)
+		}
+	}
+	return writer.Bytes()
+}
+
+// encodeFieldDelta encodes the field data(list of primitive data)
+func encodeFieldDelta(data []byte) ([]byte, error) {
+	reader := stream.NewReader(data)
+	writer := stream.NewBufferWriter(nil)
+	for !reader.Empty() {
+		fieldID := reader.ReadUint16()
+		aggType := reader.ReadByte()
+		length := reader.ReadVarint32()
+		tsd := reader.ReadBytes(int(length))
+		if reader.Error() != nil {
+			return nil, reader.Error()
+		}
+		writer.PutUInt16(fieldID)
+		writer.PutByte(aggType)
+		delta, ok := encodePrimitiveDelta(tsd)
+		if ok && len(delta) < len(tsd) {
+			writer.PutByte(deltaPrimitive)
+			writer.PutVarint32(int32(len(delta)))
+			writer.PutBytes(delta)
+		} else {
+			writer.PutByte(rawPrimitive)
+			writer.PutVarint32(int32(len(tsd)))
+			writer.PutBytes(tsd)
+		}
+	}
+	return writer.Bytes()
+}
+
+// decodeFieldDelta decodes the field data(list of primitive data)
+func decodeFieldDelta(data []byte) ([]byte, error) {
+	reader := stream.NewReader(data)
+	writer := stream.NewBufferWriter(nil)
+	for !reader.Empty() {
+		fieldID := reader.ReadUint16()
+		aggType := reader.ReadByte()
+		flag := reader.ReadByte()
+		length := reader.ReadVarint32()
+		primitiveData := reader.ReadBytes(int(length))
+		if reader.Error() != nil {
+			return nil, reader.Error()
+		}
+		tsd := primitiveData
+		switch flag {
+		case rawPrimitive:
+		case deltaPrimitive:
+			var err error
+			if tsd, err = decodePrimitiveDelta(primitiveData); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown primitive encoding flag: %d", flag)
+		}
+		writer.PutUInt16(fieldID)
+		writer.PutByte(aggType)
+		writer.PutVarint32(int32(len(tsd)))
+		writer.PutBytes(tsd)
+	}
+	return writer.Bytes()
+}
+
+// encodePrimitiveDelta encodes the tsd data as below, returns false if any value is not an integer.
+// layout: [start time(uvarint), count(uvarint), points(uvarint), [slot delta(uvarint), value delta(zigzag uvarint)]...]
+func encodePrimitiveDelta(tsd []byte) ([]byte, bool) {
+	if len(tsd) < 4 {
+		return nil, false
+	}
+	decoder := encoding.NewTSDDecoder(tsd)
+	var (
+		slots  []int
+		values []int64
+	)
+	idx := 0
+	for decoder.Next() {
+		if decoder.HasValue() {
+			value := math.Float64frombits(decoder.Value())
+			if value != math.Trunc(value) || math.Abs(value) > maxDeltaValue || (value == 0 && math.Signbit(value)) {
+				return nil, false
+			}
+			slots = append(slots, idx)
+			values = append(values, int64(value))
+		}
+		idx++
+	}
+	if decoder.Error() != nil {
+		return nil, false
+	}
+	writer := stream.NewBufferWriter(nil)
+	writer.PutUvarint64(uint64(decoder.StartTime()))
+	writer.PutUvarint64(uint64(idx))
+	writer.PutUvarint64(uint64(len(slots)))
+	prevSlot := 0
+	prevValue := int64(0)
+	for i, slot := range slots {
+		writer.PutUvarint64(uint64(slot - prevSlot))
+		writer.PutUvarint64(encoding.ZigZagEncode(values[i] - prevValue))
+		prevSlot = slot
+		prevValue = values[i]
+	}
+	data, err := writer.Bytes()
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// decodePrimitiveDelta decodes the delta encoded data, rebuilds the tsd data
+func decodePrimitiveDelta(data []byte) ([]byte, error) {
+	reader := stream.NewReader(data)
+	startTime := int(reader.ReadUvarint64())
+	count := int(reader.ReadUvarint64())
+	points := int(reader.ReadUvarint64())
+	encoder := encoding.NewTSDEncoder(startTime)
+	idx := 0
+	slot := 0
+	value := int64(0)
+	for i := 0; i < points; i++ {
+		slot += int(reader.ReadUvarint64())
+		value += encoding.ZigZagDecode(reader.ReadUvarint64())
+		for idx < slot {
+			encoder.AppendTime(bit.Zero)
+			idx++
+		}
+		encoder.AppendTime(bit.One)
+		encoder.AppendValue(math.Float64bits(float64(value)))
+		idx++
+	}
+	if reader.Error() != nil {
+		return nil, reader.Error()
+	}
+	for idx < count {
+		encoder.AppendTime(bit.Zero)
+		idx++
+	}
+	return encoder.Bytes()
+}
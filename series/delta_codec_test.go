@@ -0,0 +1,129 @@
+package series
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/pkg/bit"
+	"github.com/lindb/lindb/pkg/encoding"
+	"github.com/lindb/lindb/pkg/stream"
+	"github.com/lindb/lindb/series/field"
+)
+
+func buildTSD(t *testing.T, startSlot int, count int, values map[int]float64) []byte {
+	encoder := encoding.NewTSDEncoder(startSlot)
+	for i := 0; i < count; i++ {
+		value, ok := values[i]
+		if !ok {
+			encoder.AppendTime(bit.Zero)
+			continue
+		}
+		encoder.AppendTime(bit.One)
+		encoder.AppendValue(math.Float64bits(value))
+	}
+	data, err := encoder.Bytes()
+	assert.NoError(t, err)
+	return data
+}
+
+func buildFieldData(t *testing.T, startTimes []int64, primitives ...[]byte) []byte {
+	fieldWriter := stream.NewBufferWriter(nil)
+	for idx, tsd := range primitives {
+		fieldWriter.PutUInt16(uint16(idx + 1))
+		fieldWriter.PutByte(byte(field.Sum))
+		fieldWriter.PutVarint32(int32(len(tsd)))
+		fieldWriter.PutBytes(tsd)
+	}
+	fieldData, err := fieldWriter.Bytes()
+	assert.NoError(t, err)
+
+	writer := stream.NewBufferWriter(nil)
+	writer.PutByte(byte(field.SumField))
+	for _, startTime := range startTimes {
+		writer.PutVarint64(startTime)
+		writer.PutVarint32(int32(len(fieldData)))
+		writer.PutBytes(fieldData)
+	}
+	data, err := writer.Bytes()
+	assert.NoError(t, err)
+	return data
+}
+
+func TestDeltaCodec_RoundTrip(t *testing.T) {
+	counter := make(map[int]float64)
+	for i := 0; i < 360; i++ {
+		if i%7 == 3 {
+			continue
+		}
+		counter[i] = float64(1000000 + i*3)
+	}
+	gauge := map[int]float64{0: 1.5, 3: -2.25, 10: 100.125}
+	negative := map[int]float64{1: -10, 2: -20, 5: 30}
+	negativeZero := map[int]float64{1: math.Copysign(0, -1)}
+	data := buildFieldData(t, []int64{1571544000000, 1571547600000, 1571540400000},
+		buildTSD(t, 0, 360, counter),
+		buildTSD(t, 5, 12, gauge),
+		buildTSD(t, 10, 8, negative),
+		buildTSD(t, 0, 2, negativeZero),
+	)
+
+	encoded, err := EncodeDelta(data)
+	assert.NoError(t, err)
+	assert.True(t, len(encoded) < len(data))
+	decoded, err := DecodeDelta(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, data, decoded)
+
+	// decoded data can be read by binary iterator
+	it := NewIterator("f1", decoded)
+	assert.Equal(t, field.SumField, it.FieldType())
+	assert.True(t, it.HasNext())
+	startTime, fIt := it.Next()
+	assert.Equal(t, int64(1571544000000), startTime)
+	assert.True(t, fIt.HasNext())
+	pIt := fIt.Next()
+	points := 0
+	for pIt.HasNext() {
+		slot, value := pIt.Next()
+		assert.Equal(t, counter[slot], value)
+		points++
+	}
+	assert.Equal(t, len(counter), points)
+}
+
+func TestDeltaCodec_Empty(t *testing.T) {
+	data, err := EncodeDelta(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+	data, err = DecodeDelta(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+
+	data = buildFieldData(t, []int64{10})
+	encoded, err := EncodeDelta(data)
+	assert.NoError(t, err)
+	decoded, err := DecodeDelta(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, data, decoded)
+}
+
+func TestDeltaCodec_Corrupt(t *testing.T) {
+	// length exceeds data
+	_, err := EncodeDelta([]byte{byte(field.SumField), 2, 10, 1})
+	assert.Error(t, err)
+	_, err = DecodeDelta([]byte{byte(field.SumField), 2, 10, 1})
+	assert.Error(t, err)
+	// primitive length exceeds data
+	_, err = EncodeDelta([]byte{byte(field.SumField), 2, 8, 1, 0, 1, 10, 1, 2, 3, 4})
+	assert.Error(t, err)
+	_, err = DecodeDelta([]byte{byte(field.SumField), 2, 9, 1, 0, 1, 1, 10, 1, 2, 3, 4})
+	assert.Error(t, err)
+	// unknown flag
+	_, err = DecodeDelta([]byte{byte(field.SumField), 2, 5, 1, 0, 1, 9, 0})
+	assert.Error(t, err)
+	// delta primitive is corrupt
+	_, err = DecodeDelta([]byte{byte(field.SumField), 2, 7, 1, 0, 1, deltaPrimitive, 2, 1, 10})
+	assert.Error(t, err)
+}
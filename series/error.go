@@ -1,6 +1,9 @@
 package series
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // ErrNotFound is returned by index-database when the data does not exists on disk
 var ErrNotFound = errors.New("data not found")
@@ -24,3 +27,112 @@ var ErrWrongFieldType = errors.New("field type is wrong")
 // ErrResetVersionUnavailable is the error returned by tsdb when
 // the immutable tagIndex has not been flushed yet.
 var ErrResetVersionUnavailable = errors.New("reset version unavailable")
+
+// ErrTooManyTagsPerMetric is the error returned by tsdb when
+// the count of tags in one written metric exceeds the max limit.
+var ErrTooManyTagsPerMetric = errors.New("too many tags per metric")
+
+// ErrMetricNameTooLong is the error returned by tsdb when
+// the length of metric name exceeds the max limit.
+var ErrMetricNameTooLong = errors.New("metric name too long")
+
+// ErrSeriesQuotaExceeded is the error returned by tsdb when
+// the count of series in memory exceeds the quota of database.
+var ErrSeriesQuotaExceeded = errors.New("series quota exceeded")
+
+// ErrMemoryQuotaExceeded is the error returned by tsdb when
+// the memory size exceeds the quota of database.
+var ErrMemoryQuotaExceeded = errors.New("memory quota exceeded")
+
+// ErrIngestRateQuotaExceeded is the error returned by tsdb when
+// the ingest rate exceeds the quota of database.
+var ErrIngestRateQuotaExceeded = errors.New("ingest rate quota exceeded")
+
+// ErrMetricHashCollision is the error returned by tsdb when
+// the hash of metric name collides with another metric in memory.
+var ErrMetricHashCollision = errors.New("metric hash collision")
+
+// ErrMetricHashMismatch is the error returned by tsdb when
+// the precomputed metric hash doesn't match the hash of metric name.
+var ErrMetricHashMismatch = errors.New("metric hash mismatch")
+
+// ErrTimestampOutOfRange is the error returned by tsdb when
+// the timestamp of written metric is out of the ahead/behind window of now.
+var ErrTimestampOutOfRange = errors.New("timestamp out of range")
+
+// WriteErrorCode represents the classification of the error of writing metric
+type WriteErrorCode int
+
+const (
+	// WriteErrorUnknown is the code of the unclassified errors
+	WriteErrorUnknown WriteErrorCode = iota
+	// WriteErrorSchema is the code of the errors that the metric conflicts with the schema,
+	// such as wrong field type, the metric should be dropped permanently.
+	WriteErrorSchema
+	// WriteErrorCardinality is the code of the errors that the metric exceeds the limit of tags or fields,
+	// the metric can be written after the limit is raised or the expired series are evicted.
+	WriteErrorCardinality
+	// WriteErrorQuota is the code of the errors that the database exceeds the quota, the metric is retryable later.
+	WriteErrorQuota
+	// WriteErrorTimestamp is the code of the errors that the timestamp of metric is out of the acceptable window,
+	// the metric should be dropped.
+	WriteErrorTimestamp
+)
+
+// String returns the name of write error code
+func (code WriteErrorCode) String() string {
+	switch code {
+	case WriteErrorSchema:
+		return "schema"
+	case WriteErrorCardinality:
+		return "cardinality"
+	case WriteErrorQuota:
+		return "quota"
+	case WriteErrorTimestamp:
+		return "timestamp"
+	default:
+		return "unknown"
+	}
+}
+
+// WriteError is the error returned by tsdb when writing metric failed,
+// which wraps the underlying error with the classification for the callers.
+type WriteError struct {
+	MetricName string
+	Code       WriteErrorCode
+	Err        error // underlying error
+}
+
+// NewWriteError wraps the underlying error of writing metric with the classification, returns nil if err is nil.
+func NewWriteError(metricName string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*WriteError); ok {
+		return err
+	}
+	writeErr := &WriteError{MetricName: metricName, Err: err}
+	switch err {
+	case ErrWrongFieldType, ErrTooManyTagsPerMetric, ErrMetricNameTooLong, ErrMetricHashMismatch:
+		writeErr.Code = WriteErrorSchema
+	case ErrTooManyTags, ErrTooManyTagKeys, ErrTooManyFields:
+		writeErr.Code = WriteErrorCardinality
+	case ErrSeriesQuotaExceeded, ErrMemoryQuotaExceeded, ErrIngestRateQuotaExceeded:
+		writeErr.Code = WriteErrorQuota
+	case ErrTimestampOutOfRange:
+		writeErr.Code = WriteErrorTimestamp
+	default:
+		writeErr.Code = WriteErrorUnknown
+	}
+	return writeErr
+}
+
+// Error returns the message of underlying error with metric name and code
+func (e *WriteError) Error() string {
+	return fmt.Sprintf("write metric: %s error(%s): %s", e.MetricName, e.Code, e.Err)
+}
+
+// Retryable returns if the metric can be written later, the metric with schema error should be dropped.
+func (e *WriteError) Retryable() bool {
+	return e.Code == WriteErrorCardinality || e.Code == WriteErrorQuota
+}
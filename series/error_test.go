@@ -0,0 +1,49 @@
+package series
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWriteError(t *testing.T) {
+	assert.Nil(t, NewWriteError("cpu", nil))
+
+	cases := []struct {
+		err       error
+		code      WriteErrorCode
+		retryable bool
+	}{
+		{ErrWrongFieldType, WriteErrorSchema, false},
+		{ErrTooManyTagsPerMetric, WriteErrorSchema, false},
+		{ErrMetricNameTooLong, WriteErrorSchema, false},
+		{ErrMetricHashMismatch, WriteErrorSchema, false},
+		{ErrTooManyTags, WriteErrorCardinality, true},
+		{ErrTooManyTagKeys, WriteErrorCardinality, true},
+		{ErrTooManyFields, WriteErrorCardinality, true},
+		{ErrSeriesQuotaExceeded, WriteErrorQuota, true},
+		{ErrMemoryQuotaExceeded, WriteErrorQuota, true},
+		{ErrIngestRateQuotaExceeded, WriteErrorQuota, true},
+		{ErrTimestampOutOfRange, WriteErrorTimestamp, false},
+		{ErrMetricHashCollision, WriteErrorUnknown, false},
+		{errors.New("err"), WriteErrorUnknown, false},
+	}
+	for _, c := range cases {
+		err := NewWriteError("cpu", c.err)
+		writeErr, ok := err.(*WriteError)
+		assert.True(t, ok, c.err.Error())
+		assert.Equal(t, "cpu", writeErr.MetricName)
+		assert.Equal(t, c.code, writeErr.Code, c.err.Error())
+		assert.Equal(t, c.err, writeErr.Err)
+		assert.Equal(t, c.retryable, writeErr.Retryable(), c.err.Error())
+	}
+	// already wrapped
+	err := NewWriteError("cpu", ErrTooManyTags)
+	assert.Equal(t, err, NewWriteError("mem", err))
+	assert.Equal(t, "write metric: cpu error(cardinality): too many tags", err.Error())
+	assert.Equal(t, "schema", WriteErrorSchema.String())
+	assert.Equal(t, "quota", WriteErrorQuota.String())
+	assert.Equal(t, "timestamp", WriteErrorTimestamp.String())
+	assert.Equal(t, "unknown", WriteErrorUnknown.String())
+}
@@ -71,3 +71,12 @@ func (m maxAgg) AggregateInt(a, b int64) int64 {
 	return b
 }
 func (m maxAgg) AggregateFloat(a, b float64) float64 { return math.Max(a, b) }
+
+// lastAgg represents last value aggregator, which keeps the later value
+type lastAgg struct {
+	aggType AggType
+}
+
+func (l lastAgg) AggType() AggType                    { return l.aggType }
+func (l lastAgg) AggregateInt(a, b int64) int64       { return b }
+func (l lastAgg) AggregateFloat(a, b float64) float64 { return b }
@@ -37,3 +37,18 @@ func TestMaxAgg(t *testing.T) {
 	assert.Equal(t, 99.0, agg.AggregateFloat(1, 99.0))
 	assert.Equal(t, 99.0, agg.AggregateFloat(99.0, 1))
 }
+
+func TestRollupAggFunc(t *testing.T) {
+	agg := SumField.RollupAggFunc(Sum)
+	assert.Equal(t, Sum, agg.AggType())
+	assert.Equal(t, 100.0, agg.AggregateFloat(1, 99.0))
+	// gauge keeps the last value
+	agg = GaugeField.RollupAggFunc(Max)
+	assert.Equal(t, Max, agg.AggType())
+	assert.Equal(t, int64(1), agg.AggregateInt(99, 1))
+	assert.Equal(t, 1.0, agg.AggregateFloat(99.0, 1))
+	// counter keeps the last raw cumulative value
+	agg = CounterField.RollupAggFunc(Sum)
+	assert.Equal(t, Sum, agg.AggType())
+	assert.Equal(t, 1.0, agg.AggregateFloat(99.0, 1))
+}
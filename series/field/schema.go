@@ -1,9 +1,16 @@
 package field
 
 import (
+	"math"
+
 	"github.com/lindb/lindb/aggregation/function"
 )
 
+// SeriesCountFieldID represents the primitive field id of series count, which is not stored,
+// the series which has value in the time slot is counted when aggregating the field within a group,
+// so that avg of the primitive field is calculated as the summed values divided by the series count.
+const SeriesCountFieldID = uint16(math.MaxUint16)
+
 type schema interface {
 	getPrimitiveFields(funcType function.FuncType) map[uint16]AggType
 	getDefaultPrimitiveFields() map[uint16]AggType
@@ -23,6 +30,8 @@ func (s *sumSchema) getPrimitiveFields(funcType function.FuncType) map[uint16]Ag
 	switch funcType {
 	case function.Sum:
 		return map[uint16]AggType{s.primitiveFieldID: Sum}
+	case function.Avg:
+		return map[uint16]AggType{s.primitiveFieldID: Sum, SeriesCountFieldID: Sum}
 	default:
 		return nil
 	}
@@ -55,6 +64,80 @@ func (s *minSchema) getDefaultPrimitiveFields() map[uint16]AggType {
 	return map[uint16]AggType{s.primitiveFieldID: Min}
 }
 
+type maxSchema struct {
+	primitiveFieldID uint16
+}
+
+func newMaxSchema() schema {
+	return &maxSchema{
+		primitiveFieldID: uint16(1),
+	}
+}
+
+func (s *maxSchema) getPrimitiveFields(funcType function.FuncType) map[uint16]AggType {
+	switch funcType {
+	case function.Max:
+		return map[uint16]AggType{s.primitiveFieldID: Max}
+	default:
+		return nil
+	}
+}
+
+func (s *maxSchema) getDefaultPrimitiveFields() map[uint16]AggType {
+	return map[uint16]AggType{s.primitiveFieldID: Max}
+}
+
+type countSchema struct {
+	primitiveFieldID uint16
+}
+
+func newCountSchema() schema {
+	return &countSchema{
+		primitiveFieldID: uint16(1),
+	}
+}
+
+func (s *countSchema) getPrimitiveFields(funcType function.FuncType) map[uint16]AggType {
+	switch funcType {
+	case function.Sum, function.Count:
+		return map[uint16]AggType{s.primitiveFieldID: Count}
+	case function.Avg:
+		return map[uint16]AggType{s.primitiveFieldID: Count, SeriesCountFieldID: Sum}
+	default:
+		return nil
+	}
+}
+
+func (s *countSchema) getDefaultPrimitiveFields() map[uint16]AggType {
+	return map[uint16]AggType{s.primitiveFieldID: Count}
+}
+
+// counterSchema represents the schema of counter field, which stores the raw cumulative values,
+// the values in time slots are rolled up by last value(see Type.RollupAggFunc),
+// the values of series are summed, and the rate is calculated by the summed values at query time.
+type counterSchema struct {
+	primitiveFieldID uint16
+}
+
+func newCounterSchema() schema {
+	return &counterSchema{
+		primitiveFieldID: uint16(1),
+	}
+}
+
+func (s *counterSchema) getPrimitiveFields(funcType function.FuncType) map[uint16]AggType {
+	switch funcType {
+	case function.Sum, function.Rate:
+		return map[uint16]AggType{s.primitiveFieldID: Sum}
+	default:
+		return nil
+	}
+}
+
+func (s *counterSchema) getDefaultPrimitiveFields() map[uint16]AggType {
+	return map[uint16]AggType{s.primitiveFieldID: Sum}
+}
+
 type summarySchema struct {
 	sumFieldID, countFieldID, minFieldID, maxFieldID uint16
 }
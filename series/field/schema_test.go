@@ -11,6 +11,8 @@ import (
 func Test_Sum_getPrimitiveFields(t *testing.T) {
 	assert.True(t, newSumSchema().getPrimitiveFields(function.Sum)[uint16(1)] == Sum)
 	assert.Equal(t, 1, len(newSumSchema().getPrimitiveFields(function.Sum)))
+	assert.Equal(t, 2, len(newSumSchema().getPrimitiveFields(function.Avg)))
+	assert.True(t, newSumSchema().getPrimitiveFields(function.Avg)[SeriesCountFieldID] == Sum)
 
 	assert.True(t, newSumSchema().getDefaultPrimitiveFields()[uint16(1)] == Sum)
 	assert.Equal(t, 1, len(newSumSchema().getDefaultPrimitiveFields()))
@@ -27,6 +29,36 @@ func Test_Min_getPrimitiveFields(t *testing.T) {
 	assert.Nil(t, newMinSchema().getPrimitiveFields(function.FuncType(128)))
 }
 
+func Test_Max_getPrimitiveFields(t *testing.T) {
+	assert.True(t, newMaxSchema().getPrimitiveFields(function.Max)[uint16(1)] == Max)
+	assert.Equal(t, 1, len(newMaxSchema().getPrimitiveFields(function.Max)))
+
+	assert.True(t, newMaxSchema().getDefaultPrimitiveFields()[uint16(1)] == Max)
+	assert.Equal(t, 1, len(newMaxSchema().getDefaultPrimitiveFields()))
+
+	assert.Nil(t, newMaxSchema().getPrimitiveFields(function.FuncType(128)))
+}
+
+func Test_Count_getPrimitiveFields(t *testing.T) {
+	assert.True(t, newCountSchema().getPrimitiveFields(function.Sum)[uint16(1)] == Count)
+	assert.True(t, newCountSchema().getPrimitiveFields(function.Count)[uint16(1)] == Count)
+	assert.True(t, newCountSchema().getPrimitiveFields(function.Avg)[uint16(1)] == Count)
+	assert.True(t, newCountSchema().getPrimitiveFields(function.Avg)[SeriesCountFieldID] == Sum)
+
+	assert.True(t, newCountSchema().getDefaultPrimitiveFields()[uint16(1)] == Count)
+	assert.Equal(t, 1, len(newCountSchema().getDefaultPrimitiveFields()))
+
+	assert.Nil(t, newCountSchema().getPrimitiveFields(function.FuncType(128)))
+}
+func Test_Counter_getPrimitiveFields(t *testing.T) {
+	assert.True(t, newCounterSchema().getPrimitiveFields(function.Sum)[uint16(1)] == Sum)
+	assert.True(t, newCounterSchema().getPrimitiveFields(function.Rate)[uint16(1)] == Sum)
+
+	assert.True(t, newCounterSchema().getDefaultPrimitiveFields()[uint16(1)] == Sum)
+	assert.Equal(t, 1, len(newCounterSchema().getDefaultPrimitiveFields()))
+
+	assert.Nil(t, newCounterSchema().getPrimitiveFields(function.Max))
+}
 func Test_Summary_getPrimitiveFields(t *testing.T) {
 	assert.True(t, newSummarySchema().getDefaultPrimitiveFields()[uint16(2)] == Sum)
 	assert.Equal(t, 1, len(newSummarySchema().getDefaultPrimitiveFields()))
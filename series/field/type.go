@@ -29,6 +29,10 @@ var schemas = map[Type]schema{}
 func init() {
 	schemas[SumField] = newSumSchema()
 	schemas[MinField] = newMinSchema()
+	schemas[MaxField] = newMaxSchema()
+	schemas[GaugeField] = newMaxSchema()
+	schemas[CountField] = newCountSchema()
+	schemas[CounterField] = newCounterSchema()
 	schemas[SummaryField] = newSummarySchema()
 }
 
@@ -42,10 +46,18 @@ const (
 	MaxField
 	SummaryField
 	HistogramField
+	QuantileField
+	GaugeField
+	CountField
+	CounterField
 
 	Unknown
 )
 
+// Quantiles defines the quantiles returned by quantile field,
+// the primitive field id of quantile is the index+1, e.g. p50 => 1, p99 => 4
+var Quantiles = []float64{0.5, 0.9, 0.95, 0.99}
+
 // String returns the field type's string value
 func (t Type) String() string {
 	switch t {
@@ -59,6 +71,14 @@ func (t Type) String() string {
 		return "summary"
 	case HistogramField:
 		return "histogram"
+	case QuantileField:
+		return "quantile"
+	case GaugeField:
+		return "gauge"
+	case CountField:
+		return "count"
+	case CounterField:
+		return "counter"
 	default:
 		return "unknown"
 	}
@@ -70,8 +90,10 @@ func (t Type) DownSamplingFunc() function.FuncType {
 		return function.Sum
 	case MinField:
 		return function.Min
-	case MaxField:
+	case MaxField, GaugeField:
 		return function.Max
+	case CountField, CounterField:
+		return function.Sum
 	case HistogramField:
 		return function.Histogram
 	default:
@@ -79,11 +101,22 @@ func (t Type) DownSamplingFunc() function.FuncType {
 	}
 }
 
+// RollupAggFunc returns the aggregator function which rolls up the values of primitive field
+// from storage interval into the coarser query interval, gauge and counter keep the last value of the sub intervals,
+// others use the aggregator function of primitive field, e.g. sum field sums the sub intervals.
+// the agg type of aggregator function is not changed, so that the rolled up values can be merged as before.
+func (t Type) RollupAggFunc(aggType AggType) AggFunc {
+	if t == GaugeField || t == CounterField {
+		return lastAgg{aggType: aggType}
+	}
+	return aggType.AggFunc()
+}
+
 func (t Type) IsFuncSupported(funcType function.FuncType) bool {
 	switch t {
 	case SumField:
 		switch funcType {
-		case function.Sum, function.Min, function.Max:
+		case function.Sum, function.Min, function.Max, function.Avg:
 			return true
 		default:
 			return false
@@ -95,13 +128,34 @@ func (t Type) IsFuncSupported(funcType function.FuncType) bool {
 		default:
 			return false
 		}
-	case MaxField:
+	case MaxField, GaugeField:
 		switch funcType {
 		case function.Max:
 			return true
 		default:
 			return false
 		}
+	case CountField:
+		switch funcType {
+		case function.Sum, function.Count, function.Avg:
+			return true
+		default:
+			return false
+		}
+	case CounterField:
+		switch funcType {
+		case function.Sum, function.Rate:
+			return true
+		default:
+			return false
+		}
+	case SummaryField:
+		switch funcType {
+		case function.Sum, function.Count, function.Min, function.Max, function.Avg:
+			return true
+		default:
+			return false
+		}
 	case HistogramField:
 		return true
 	default:
@@ -12,6 +12,9 @@ func TestDownSamplingFunc(t *testing.T) {
 	assert.Equal(t, function.Sum, SumField.DownSamplingFunc())
 	assert.Equal(t, function.Min, MinField.DownSamplingFunc())
 	assert.Equal(t, function.Max, MaxField.DownSamplingFunc())
+	assert.Equal(t, function.Max, GaugeField.DownSamplingFunc())
+	assert.Equal(t, function.Sum, CountField.DownSamplingFunc())
+	assert.Equal(t, function.Sum, CounterField.DownSamplingFunc())
 	assert.Equal(t, function.Histogram, HistogramField.DownSamplingFunc())
 	assert.Equal(t, function.Unknown, Unknown.DownSamplingFunc())
 }
@@ -22,12 +25,19 @@ func TestType_String(t *testing.T) {
 	assert.Equal(t, "min", MinField.String())
 	assert.Equal(t, "summary", SummaryField.String())
 	assert.Equal(t, "histogram", HistogramField.String())
+	assert.Equal(t, "quantile", QuantileField.String())
+	assert.Equal(t, "gauge", GaugeField.String())
+	assert.Equal(t, "count", CountField.String())
+	assert.Equal(t, "counter", CounterField.String())
 	assert.Equal(t, "unknown", Unknown.String())
 }
 
 func Test_GetPrimitiveFields(t *testing.T) {
 	assert.NotNil(t, SumField.GetPrimitiveFields(function.Sum))
 	assert.NotNil(t, SumField.GetDefaultPrimitiveFields())
+	assert.Equal(t, map[uint16]AggType{1: Max}, GaugeField.GetDefaultPrimitiveFields())
+	assert.Equal(t, map[uint16]AggType{1: Count}, CountField.GetPrimitiveFields(function.Sum))
+	assert.Equal(t, map[uint16]AggType{1: Sum}, CounterField.GetPrimitiveFields(function.Rate))
 	assert.Nil(t, Unknown.GetPrimitiveFields(function.FuncType(128)))
 	assert.Nil(t, Unknown.GetDefaultPrimitiveFields())
 }
@@ -36,14 +46,31 @@ func TestIsSupportFunc(t *testing.T) {
 	assert.True(t, SumField.IsFuncSupported(function.Sum))
 	assert.True(t, SumField.IsFuncSupported(function.Min))
 	assert.True(t, SumField.IsFuncSupported(function.Max))
+	assert.True(t, SumField.IsFuncSupported(function.Avg))
 	assert.False(t, SumField.IsFuncSupported(function.Histogram))
 
 	assert.True(t, MaxField.IsFuncSupported(function.Max))
 	assert.False(t, MaxField.IsFuncSupported(function.Histogram))
 
+	assert.True(t, GaugeField.IsFuncSupported(function.Max))
+	assert.False(t, GaugeField.IsFuncSupported(function.Sum))
+
+	assert.True(t, CountField.IsFuncSupported(function.Sum))
+	assert.True(t, CountField.IsFuncSupported(function.Count))
+	assert.True(t, CountField.IsFuncSupported(function.Avg))
+	assert.False(t, CountField.IsFuncSupported(function.Max))
+
+	assert.True(t, CounterField.IsFuncSupported(function.Rate))
+	assert.True(t, CounterField.IsFuncSupported(function.Sum))
+	assert.False(t, CounterField.IsFuncSupported(function.Max))
+
 	assert.True(t, MinField.IsFuncSupported(function.Min))
 	assert.False(t, MinField.IsFuncSupported(function.Histogram))
 
+	assert.True(t, SummaryField.IsFuncSupported(function.Avg))
+	assert.True(t, SummaryField.IsFuncSupported(function.Count))
+	assert.False(t, SummaryField.IsFuncSupported(function.Histogram))
+
 	assert.True(t, HistogramField.IsFuncSupported(function.Min))
 	assert.True(t, HistogramField.IsFuncSupported(function.Sum))
 	assert.True(t, HistogramField.IsFuncSupported(function.Max))
@@ -16,6 +16,14 @@ type MetaGetter interface {
 		seriesID2TagValues map[uint32][]string, err error)
 }
 
+// MultiVerMetaGetter represents the query ability for metric level metadata of the series ids of multi versions
+type MultiVerMetaGetter interface {
+	// GetTagValuesByVersions returns tag values by tag keys for the series ids of multi versions,
+	// the tag values are returned by version, because the series ids are allocated by version
+	GetTagValuesByVersions(metricID uint32, tagKeys []string, seriesIDs *MultiVerSeriesIDSet) (
+		version2TagValues map[Version]map[uint32][]string, err error)
+}
+
 // MetricMetaSuggester represents the suggest ability for metricNames and tagKeys.
 // default max limit of suggestions is set in constants
 type MetricMetaSuggester interface {
@@ -32,6 +40,19 @@ type TagValueSuggester interface {
 	SuggestTagValues(metricName, tagKey, tagValuePrefix string, limit int) []string
 }
 
+// TagValueCount represents the tag value with the count of its series
+type TagValueCount struct {
+	TagValue    string
+	SeriesCount int
+}
+
+// TagValueRanker represents the ability of ranking tag values by the count of their series,
+// such as "top 10 hosts by number of series" for exploratory UIs.
+type TagValueRanker interface {
+	// TopTagValues returns at most k tag values of the metric's tag key ordered by count of series descending
+	TopTagValues(metricName, tagKey string, k int) []TagValueCount
+}
+
 // Filter represents the query ability for filtering seriesIDs by expr from an index of tags.
 // to support multi-version based on timestamp, time range for filtering spec version is necessary
 type Filter interface {
@@ -41,4 +62,16 @@ type Filter interface {
 	// GetSeriesIDsForTag get series ids for spec metric's tag key
 	GetSeriesIDsForTag(metricID uint32, tagKey string, timeRange timeutil.TimeRange) (
 		*MultiVerSeriesIDSet, error)
+	// GetSeriesIDsForMetric gets all the series ids for spec metric,
+	// which is the universe for negation filter, including the series without some tag key
+	GetSeriesIDsForMetric(metricID uint32, timeRange timeutil.TimeRange) (
+		*MultiVerSeriesIDSet, error)
+}
+
+// StaleFilter represents the query ability for filtering the series which have no recent data,
+// such as alerting on series with no data in the last 5 minutes.
+type StaleFilter interface {
+	// FindStaleSeriesIDs finds series ids for metric id whose latest data is older than the threshold timestamp,
+	// also returns all the series ids whose staleness is known, the staleness of other series is unknown.
+	FindStaleSeriesIDs(metricID uint32, threshold int64) (stale, known *MultiVerSeriesIDSet, err error)
 }
@@ -4,6 +4,7 @@ import (
 	enc "encoding"
 	"io"
 
+	"github.com/lindb/lindb/pkg/sketch"
 	"github.com/lindb/lindb/series/field"
 )
 
@@ -12,6 +13,10 @@ import (
 // TimeSeriesEvent represents time series event for query
 type TimeSeriesEvent struct {
 	SeriesList []GroupedIterator
+	// Partial represents the series list is partial result because the scan budget elapsed or some shards are missing
+	Partial bool
+	// SeriesOnly represents the series list has only the tags of series without any field values
+	SeriesOnly bool
 
 	Err error
 }
@@ -63,6 +68,15 @@ type FieldIterator interface {
 	enc.BinaryMarshaler
 }
 
+// SketchFieldIterator represents a field iterator of quantile field, which carries the sketches by time slot,
+// so that the sketches of series can be merged before calculating the quantiles,
+// the quantiles of sketches are returned as primitive fields, see field.Quantiles
+type SketchFieldIterator interface {
+	FieldIterator
+	// Sketches returns the start time slot and the sketches by index of time slot, nil if no data in the slot
+	Sketches() (startSlot int, sketches []*sketch.DDSketch)
+}
+
 // PrimitiveIterator represents an iterator over a primitive field, iterator points data of primitive field
 type PrimitiveIterator interface {
 	// FieldID returns the primitive field id
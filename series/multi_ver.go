@@ -91,6 +91,16 @@ func (mv *MultiVerSeriesIDSet) Versions() map[Version]*roaring.Bitmap {
 	return mv.versions
 }
 
+// Cardinality returns the count of series ids under all versions,
+// series ids in different versions are different series.
+func (mv *MultiVerSeriesIDSet) Cardinality() uint64 {
+	var count uint64
+	for _, ids := range mv.versions {
+		count += ids.GetCardinality()
+	}
+	return count
+}
+
 // Contains checks whether the version exists or not
 func (mv *MultiVerSeriesIDSet) Contains(v Version) bool {
 	_, ok := mv.versions[v]
@@ -88,3 +88,12 @@ func TestMultiVerSeriesIDSet_AndNot(t *testing.T) {
 	assert.Equal(t, *roaring.BitmapOf(1, 6, 7, 8), *(multiVer1.versions[Version(12)]))
 	assert.Equal(t, *roaring.BitmapOf(7, 8, 9), *(multiVer1.versions[Version(13)]))
 }
+
+func TestMultiVerSeriesIDSet_Cardinality(t *testing.T) {
+	multiVer := NewMultiVerSeriesIDSet()
+	assert.Equal(t, uint64(0), multiVer.Cardinality())
+
+	multiVer.Add(Version(12), roaring.BitmapOf(1, 2, 3))
+	multiVer.Add(Version(13), roaring.BitmapOf(1, 2))
+	assert.Equal(t, uint64(5), multiVer.Cardinality())
+}
@@ -1,6 +1,7 @@
 package series
 
 import (
+	"context"
 	"sync"
 
 	"github.com/lindb/lindb/pkg/timeutil"
@@ -19,11 +20,20 @@ type ScanContext struct {
 
 	Worker ScanWorker // scan worker which handles field event
 
+	// optional, the scan is aborted if the context is canceled, like the client has disconnected
+	Context context.Context
+
 	// optional, if SeriesIDSet is nil, just search metric level data
 	SeriesIDSet *MultiVerSeriesIDSet
 
+	// optional, query time range(truncated by query interval) and query interval,
+	// memory scan reads the slot directly if the time range resolves to a single slot of storage
+	TimeRange     timeutil.TimeRange
+	QueryInterval int64
+
 	// runtime, required for memory scan
-	IntervalCalc timeutil.Calculator
+	IntervalCalc    timeutil.Calculator
+	StorageInterval int64
 
 	Aggregators sync.Pool
 }
@@ -38,6 +48,19 @@ func (sCtx *ScanContext) ContainsFieldID(fieldID uint16) bool {
 	return false
 }
 
+// Canceled checks if the scan is canceled by the context, never canceled if without context
+func (sCtx *ScanContext) Canceled() bool {
+	if sCtx.Context == nil {
+		return false
+	}
+	select {
+	case <-sCtx.Context.Done():
+		return true
+	default:
+		return false
+	}
+}
+
 // GetAggregator gets aggregator from the pool of scanner context
 func (sCtx *ScanContext) GetAggregator() interface{} {
 	return sCtx.Aggregators.Get()
@@ -58,6 +81,8 @@ type Scanner interface {
 type ScanEvent interface {
 	// SeriesIDs returns the found series IDs
 	SeriesIDs() *roaring.Bitmap
+	// Version returns the version of the found series IDs
+	Version() Version
 	// Release releases the scan resource for reusing
 	Release()
 	// ResultSet returns the result set of scanner
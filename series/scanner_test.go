@@ -1,6 +1,7 @@
 package series
 
 import (
+	"context"
 	"sync"
 	"testing"
 
@@ -20,3 +21,14 @@ func TestScanContext_GetAggregator(t *testing.T) {
 	assert.Equal(t, "mock_agg", agg)
 	sCtx.Release(agg)
 }
+
+func TestScanContext_Canceled(t *testing.T) {
+	sCtx := &ScanContext{}
+	assert.False(t, sCtx.Canceled())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sCtx.Context = ctx
+	assert.False(t, sCtx.Canceled())
+	cancel()
+	assert.True(t, sCtx.Canceled())
+}
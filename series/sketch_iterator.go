@@ -0,0 +1,134 @@
+package series
+
+import (
+	"github.com/lindb/lindb/pkg/sketch"
+	"github.com/lindb/lindb/pkg/stream"
+	"github.com/lindb/lindb/series/field"
+)
+
+// sketchFieldIterator implements SketchFieldIterator,
+// the quantiles are calculated when the primitive fields are iterated.
+type sketchFieldIterator struct {
+	startSlot int
+	sketches  []*sketch.DDSketch
+
+	idx int
+}
+
+// NewSketchFieldIterator creates a field iterator of quantile field by the sketches of time slot
+func NewSketchFieldIterator(startSlot int, sketches []*sketch.DDSketch) SketchFieldIterator {
+	return &sketchFieldIterator{
+		startSlot: startSlot,
+		sketches:  sketches,
+	}
+}
+
+// UnmarshalSketchFieldIterator creates a field iterator of quantile field by the data written by MarshalBinary
+func UnmarshalSketchFieldIterator(data []byte) (SketchFieldIterator, error) {
+	reader := stream.NewReader(data)
+	startSlot := int(reader.ReadVarint32())
+	var sketches []*sketch.DDSketch
+	for !reader.Empty() && reader.Error() == nil {
+		idx := int(reader.ReadVarint32())
+		length := reader.ReadVarint32()
+		s := &sketch.DDSketch{}
+		if err := s.UnmarshalBinary(reader.ReadBytes(int(length))); err != nil {
+			return nil, err
+		}
+		if idx >= len(sketches) {
+			grown := make([]*sketch.DDSketch, idx+1)
+			copy(grown, sketches)
+			sketches = grown
+		}
+		sketches[idx] = s
+	}
+	if reader.Error() != nil {
+		return nil, reader.Error()
+	}
+	return NewSketchFieldIterator(startSlot, sketches), nil
+}
+
+// Sketches returns the start time slot and the sketches by index of time slot
+func (it *sketchFieldIterator) Sketches() (startSlot int, sketches []*sketch.DDSketch) {
+	return it.startSlot, it.sketches
+}
+
+// HasNext returns if the iteration has more quantiles
+func (it *sketchFieldIterator) HasNext() bool {
+	return it.idx < len(field.Quantiles)
+}
+
+// Next returns the primitive iterator of next quantile
+func (it *sketchFieldIterator) Next() PrimitiveIterator {
+	if it.idx >= len(field.Quantiles) {
+		return nil
+	}
+	it.idx++
+	return &quantileIterator{
+		fieldID:   uint16(it.idx),
+		quantile:  field.Quantiles[it.idx-1],
+		startSlot: it.startSlot,
+		sketches:  it.sketches,
+	}
+}
+
+// MarshalBinary marshals the start time slot, then the index and data of each sketch
+func (it *sketchFieldIterator) MarshalBinary() ([]byte, error) {
+	writer := stream.NewBufferWriter(nil)
+	writer.PutVarint32(int32(it.startSlot))
+	for idx, s := range it.sketches {
+		if s == nil {
+			continue
+		}
+		data, err := s.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		writer.PutVarint32(int32(idx))
+		writer.PutVarint32(int32(len(data)))
+		writer.PutBytes(data)
+	}
+	return writer.Bytes()
+}
+
+// quantileIterator implements PrimitiveIterator, which returns the quantile of sketches by time slot
+type quantileIterator struct {
+	fieldID   uint16
+	quantile  float64
+	startSlot int
+	sketches  []*sketch.DDSketch
+
+	idx   int
+	value float64
+}
+
+// FieldID returns the primitive field id of quantile
+func (it *quantileIterator) FieldID() uint16 {
+	return it.fieldID
+}
+
+// AggType returns max, the quantile is not aggregated after calculated
+func (it *quantileIterator) AggType() field.AggType {
+	return field.Max
+}
+
+// HasNext returns if the iteration has more sketches which have quantile
+func (it *quantileIterator) HasNext() bool {
+	for it.idx < len(it.sketches) {
+		s := it.sketches[it.idx]
+		it.idx++
+		if s == nil {
+			continue
+		}
+		if value, ok := s.Quantile(it.quantile); ok {
+			it.value = value
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the time slot and the quantile of sketch
+func (it *quantileIterator) Next() (timeSlot int, value float64) {
+	return it.startSlot + it.idx - 1, it.value
+}
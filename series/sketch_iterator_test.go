@@ -0,0 +1,67 @@
+package series
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/pkg/sketch"
+	"github.com/lindb/lindb/pkg/stream"
+	"github.com/lindb/lindb/series/field"
+)
+
+func TestSketchFieldIterator(t *testing.T) {
+	s1 := sketch.NewDDSketch(sketch.DefaultRelativeAccuracy)
+	for i := 1; i <= 100; i++ {
+		s1.Add(float64(i))
+	}
+	s2 := sketch.NewDDSketch(sketch.DefaultRelativeAccuracy)
+	s2.Add(10)
+	it := NewSketchFieldIterator(10, []*sketch.DDSketch{s1, nil, s2})
+	data, err := it.MarshalBinary()
+	assert.NoError(t, err)
+
+	writer := stream.NewBufferWriter(nil)
+	writer.PutByte(byte(field.QuantileField))
+	writer.PutVarint64(100)
+	writer.PutVarint32(int32(len(data)))
+	writer.PutBytes(data)
+	data, err = writer.Bytes()
+	assert.NoError(t, err)
+
+	bIt := NewIterator("latency", data)
+	assert.Equal(t, field.QuantileField, bIt.FieldType())
+	assert.True(t, bIt.HasNext())
+	startTime, fIt := bIt.Next()
+	assert.Equal(t, int64(100), startTime)
+	sketchIt, ok := fIt.(SketchFieldIterator)
+	assert.True(t, ok)
+	startSlot, sketches := sketchIt.Sketches()
+	assert.Equal(t, 10, startSlot)
+	assert.Len(t, sketches, 3)
+	assert.Equal(t, uint64(100), sketches[0].Count())
+	assert.Nil(t, sketches[1])
+	assert.Equal(t, uint64(1), sketches[2].Count())
+	assert.False(t, bIt.HasNext())
+
+	for idx, q := range field.Quantiles {
+		assert.True(t, fIt.HasNext())
+		pIt := fIt.Next()
+		assert.Equal(t, uint16(idx+1), pIt.FieldID())
+		assert.Equal(t, field.Max, pIt.AggType())
+		assert.True(t, pIt.HasNext())
+		slot, value := pIt.Next()
+		assert.Equal(t, 10, slot)
+		assert.InEpsilon(t, q*100, value, 0.05)
+		assert.True(t, pIt.HasNext())
+		slot, value = pIt.Next()
+		assert.Equal(t, 12, slot)
+		assert.InEpsilon(t, 10, value, 0.05)
+		assert.False(t, pIt.HasNext())
+	}
+	assert.False(t, fIt.HasNext())
+	assert.Nil(t, fIt.Next())
+
+	_, err = UnmarshalSketchFieldIterator([]byte{1, 2, 100})
+	assert.Error(t, err)
+}
@@ -88,7 +88,7 @@ intervalItem            :
                          | T_YEAR
                          ;
 exprFunc                : funcName T_OPEN_P exprFuncParams? T_CLOSE_P ;
-funcName                : T_SUM | T_MIN | T_MAX | T_AVG | T_STDDEV | T_HISTOGRAM;
+funcName                : T_SUM | T_MIN | T_MAX | T_AVG | T_STDDEV | T_HISTOGRAM | T_MOVING_AVERAGE | T_RATE;
 exprFuncParams          : funcParam (T_COMMA funcParam)* ;
 funcParam               :
                            fieldExpr
@@ -170,6 +170,8 @@ nonReservedWords      :
                         | T_AVG
                         | T_STDDEV
                         | T_HISTOGRAM
+                        | T_MOVING_AVERAGE
+                        | T_RATE
                         ;
 
 // Lexer rules
@@ -240,6 +242,8 @@ T_MAX                : M A X                            ;
 T_AVG                : A V G                            ;
 T_STDDEV             : S T D D E V                      ;
 T_HISTOGRAM          : H I S T O G R A M                ;
+T_MOVING_AVERAGE     : M O V I N G '_' A V E R A G E    ;
+T_RATE               : R A T E                          ;
 
 //time unit
 T_SECOND             : S                                ;
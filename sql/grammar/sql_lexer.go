@@ -13,7 +13,7 @@ var _ = unicode.IsLetter
 
 
 var serializedLexerAtn = []uint16{
-	3, 24715, 42794, 33075, 47597, 16764, 15335, 30598, 22884, 2, 101, 860, 
+	3, 24715, 42794, 33075, 47597, 16764, 15335, 30598, 22884, 2, 103, 884, 
 	8, 1, 4, 2, 9, 2, 4, 3, 9, 3, 4, 4, 9, 4, 4, 5, 9, 5, 4, 6, 9, 6, 4, 7, 
 	9, 7, 4, 8, 9, 8, 4, 9, 9, 9, 4, 10, 9, 10, 4, 11, 9, 11, 4, 12, 9, 12, 
 	4, 13, 9, 13, 4, 14, 9, 14, 4, 15, 9, 15, 4, 16, 9, 16, 4, 17, 9, 17, 4, 
@@ -26,92 +26,95 @@ var serializedLexerAtn = []uint16{
 	49, 4, 50, 9, 50, 4, 51, 9, 51, 4, 52, 9, 52, 4, 53, 9, 53, 4, 54, 9, 54, 
 	4, 55, 9, 55, 4, 56, 9, 56, 4, 57, 9, 57, 4, 58, 9, 58, 4, 59, 9, 59, 4, 
 	60, 9, 60, 4, 61, 9, 61, 4, 62, 9, 62, 4, 63, 9, 63, 4, 64, 9, 64, 4, 65, 
-	9, 65, 4, 66, 9, 66, 4, 67, 9, 67, 4, 68, 9, 68, 4, 69, 9, 69, 4, 70, 9, 
-	70, 4, 71, 9, 71, 4, 72, 9, 72, 4, 73, 9, 73, 4, 74, 9, 74, 4, 75, 9, 75, 
-	4, 76, 9, 76, 4, 77, 9, 77, 4, 78, 9, 78, 4, 79, 9, 79, 4, 80, 9, 80, 4, 
-	81, 9, 81, 4, 82, 9, 82, 4, 83, 9, 83, 4, 84, 9, 84, 4, 85, 9, 85, 4, 86, 
-	9, 86, 4, 87, 9, 87, 4, 88, 9, 88, 4, 89, 9, 89, 4, 90, 9, 90, 4, 91, 9, 
-	91, 4, 92, 9, 92, 4, 93, 9, 93, 4, 94, 9, 94, 4, 95, 9, 95, 4, 96, 9, 96, 
-	4, 97, 9, 97, 4, 98, 9, 98, 4, 99, 9, 99, 4, 100, 9, 100, 4, 101, 9, 101, 
-	4, 102, 9, 102, 4, 103, 9, 103, 4, 104, 9, 104, 4, 105, 9, 105, 4, 106, 
-	9, 106, 4, 107, 9, 107, 4, 108, 9, 108, 4, 109, 9, 109, 4, 110, 9, 110, 
-	4, 111, 9, 111, 4, 112, 9, 112, 4, 113, 9, 113, 4, 114, 9, 114, 4, 115, 
-	9, 115, 4, 116, 9, 116, 4, 117, 9, 117, 4, 118, 9, 118, 4, 119, 9, 119, 
-	4, 120, 9, 120, 4, 121, 9, 121, 4, 122, 9, 122, 4, 123, 9, 123, 4, 124, 
-	9, 124, 4, 125, 9, 125, 4, 126, 9, 126, 4, 127, 9, 127, 4, 128, 9, 128, 
-	4, 129, 9, 129, 3, 2, 3, 2, 3, 2, 3, 2, 3, 2, 3, 2, 3, 2, 3, 3, 3, 3, 3, 
-	3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 4, 3, 4, 3, 4, 3, 4, 3, 5, 3, 5, 3, 5, 3, 
-	5, 3, 5, 3, 6, 3, 6, 3, 6, 3, 6, 3, 6, 3, 6, 3, 6, 3, 6, 3, 6, 3, 7, 3, 
-	7, 3, 7, 3, 7, 3, 7, 3, 8, 3, 8, 3, 8, 3, 8, 3, 8, 3, 8, 3, 9, 3, 9, 3, 
-	9, 3, 9, 3, 9, 3, 9, 3, 9, 3, 9, 3, 9, 3, 9, 3, 9, 3, 9, 3, 10, 3, 10, 
-	3, 10, 3, 10, 3, 11, 3, 11, 3, 11, 3, 11, 3, 11, 3, 11, 3, 11, 3, 11, 3, 
-	12, 3, 12, 3, 12, 3, 12, 3, 12, 3, 12, 3, 12, 3, 12, 3, 13, 3, 13, 3, 13, 
-	3, 13, 3, 13, 3, 13, 3, 13, 3, 13, 3, 13, 3, 13, 3, 14, 3, 14, 3, 14, 3, 
-	14, 3, 14, 3, 15, 3, 15, 3, 15, 3, 16, 3, 16, 3, 16, 3, 16, 3, 16, 3, 17, 
-	3, 17, 3, 17, 3, 17, 3, 17, 3, 17, 3, 17, 3, 17, 3, 17, 3, 18, 3, 18, 3, 
-	18, 3, 18, 3, 18, 3, 18, 3, 18, 3, 18, 3, 18, 3, 18, 3, 19, 3, 19, 3, 19, 
-	3, 19, 3, 19, 3, 20, 3, 20, 3, 20, 3, 20, 3, 20, 3, 20, 3, 20, 3, 20, 3, 
-	20, 3, 20, 3, 20, 3, 20, 3, 20, 3, 21, 3, 21, 3, 21, 3, 21, 3, 21, 3, 21, 
-	3, 21, 3, 21, 3, 21, 3, 21, 3, 21, 3, 21, 3, 22, 3, 22, 3, 22, 3, 22, 3, 
-	22, 3, 22, 3, 23, 3, 23, 3, 23, 3, 23, 3, 24, 3, 24, 3, 24, 3, 24, 3, 24, 
-	3, 25, 3, 25, 3, 25, 3, 25, 3, 25, 3, 26, 3, 26, 3, 26, 3, 26, 3, 27, 3, 
-	27, 3, 27, 3, 27, 3, 27, 3, 28, 3, 28, 3, 28, 3, 28, 3, 28, 3, 28, 3, 28, 
-	3, 29, 3, 29, 3, 29, 3, 29, 3, 29, 3, 29, 3, 30, 3, 30, 3, 30, 3, 30, 3, 
-	30, 3, 31, 3, 31, 3, 31, 3, 31, 3, 31, 3, 31, 3, 32, 3, 32, 3, 32, 3, 32, 
-	3, 32, 3, 32, 3, 33, 3, 33, 3, 33, 3, 33, 3, 33, 3, 33, 3, 33, 3, 33, 3, 
-	34, 3, 34, 3, 34, 3, 34, 3, 34, 3, 34, 3, 35, 3, 35, 3, 35, 3, 35, 3, 35, 
-	3, 35, 3, 35, 3, 35, 3, 36, 3, 36, 3, 36, 3, 36, 3, 36, 3, 36, 3, 36, 3, 
-	36, 3, 36, 3, 36, 3, 37, 3, 37, 3, 37, 3, 37, 3, 37, 3, 37, 3, 37, 3, 38, 
-	3, 38, 3, 38, 3, 39, 3, 39, 3, 39, 3, 39, 3, 40, 3, 40, 3, 40, 3, 41, 3, 
-	41, 3, 41, 3, 41, 3, 41, 3, 42, 3, 42, 3, 42, 3, 42, 3, 42, 3, 43, 3, 43, 
-	3, 43, 3, 43, 3, 43, 3, 43, 3, 43, 3, 43, 3, 43, 3, 44, 3, 44, 3, 44, 3, 
-	44, 3, 44, 3, 44, 3, 45, 3, 45, 3, 45, 3, 45, 3, 46, 3, 46, 3, 46, 3, 46, 
-	3, 46, 3, 47, 3, 47, 3, 47, 3, 47, 3, 47, 3, 48, 3, 48, 3, 48, 3, 48, 3, 
-	49, 3, 49, 3, 49, 3, 49, 3, 49, 3, 49, 3, 49, 3, 49, 3, 50, 3, 50, 3, 50, 
-	3, 51, 3, 51, 3, 51, 3, 51, 3, 51, 3, 51, 3, 52, 3, 52, 3, 52, 3, 52, 3, 
-	52, 3, 52, 3, 52, 3, 53, 3, 53, 3, 53, 3, 54, 3, 54, 3, 54, 3, 54, 3, 55, 
-	3, 55, 3, 55, 3, 55, 3, 55, 3, 55, 3, 56, 3, 56, 3, 56, 3, 56, 3, 56, 3, 
-	57, 3, 57, 3, 57, 3, 57, 3, 58, 3, 58, 3, 58, 3, 59, 3, 59, 3, 59, 3, 59, 
-	3, 60, 3, 60, 3, 60, 3, 60, 3, 60, 3, 60, 3, 60, 3, 60, 3, 61, 3, 61, 3, 
-	61, 3, 61, 3, 62, 3, 62, 3, 62, 3, 62, 3, 63, 3, 63, 3, 63, 3, 63, 3, 64, 
-	3, 64, 3, 64, 3, 64, 3, 65, 3, 65, 3, 65, 3, 65, 3, 65, 3, 65, 3, 65, 3, 
-	66, 3, 66, 3, 66, 3, 66, 3, 66, 3, 66, 3, 66, 3, 66, 3, 66, 3, 66, 3, 67, 
-	3, 67, 3, 68, 3, 68, 3, 69, 3, 69, 3, 70, 3, 70, 3, 71, 3, 71, 3, 72, 3, 
-	72, 3, 73, 3, 73, 3, 74, 3, 74, 3, 75, 3, 75, 3, 76, 3, 76, 3, 77, 3, 77, 
-	3, 77, 3, 78, 3, 78, 3, 78, 3, 79, 3, 79, 3, 80, 3, 80, 3, 80, 3, 81, 3, 
-	81, 3, 82, 3, 82, 3, 82, 3, 83, 3, 83, 3, 83, 3, 84, 3, 84, 3, 84, 3, 85, 
-	3, 85, 3, 86, 3, 86, 3, 87, 3, 87, 3, 88, 3, 88, 3, 89, 3, 89, 3, 90, 3, 
-	90, 3, 91, 3, 91, 3, 92, 3, 92, 3, 93, 3, 93, 3, 94, 3, 94, 3, 95, 3, 95, 
-	3, 96, 3, 96, 3, 97, 3, 97, 3, 98, 6, 98, 721, 10, 98, 13, 98, 14, 98, 
-	722, 3, 99, 6, 99, 726, 10, 99, 13, 99, 14, 99, 727, 3, 99, 3, 99, 3, 99, 
-	7, 99, 733, 10, 99, 12, 99, 14, 99, 736, 11, 99, 3, 99, 3, 99, 6, 99, 740, 
-	10, 99, 13, 99, 14, 99, 741, 5, 99, 744, 10, 99, 3, 100, 6, 100, 747, 10, 
-	100, 13, 100, 14, 100, 748, 3, 100, 3, 100, 3, 101, 3, 101, 3, 102, 3, 
-	102, 3, 103, 3, 103, 3, 103, 3, 103, 7, 103, 761, 10, 103, 12, 103, 14, 
-	103, 764, 11, 103, 3, 103, 3, 103, 3, 103, 7, 103, 769, 10, 103, 12, 103, 
-	14, 103, 772, 11, 103, 3, 103, 3, 103, 3, 103, 3, 103, 3, 103, 6, 103, 
-	779, 10, 103, 13, 103, 14, 103, 780, 3, 103, 3, 103, 7, 103, 785, 10, 103, 
-	12, 103, 14, 103, 788, 11, 103, 3, 103, 3, 103, 3, 103, 7, 103, 793, 10, 
-	103, 12, 103, 14, 103, 796, 11, 103, 3, 103, 3, 103, 3, 103, 7, 103, 801, 
-	10, 103, 12, 103, 14, 103, 804, 11, 103, 3, 103, 5, 103, 807, 10, 103, 
-	3, 104, 3, 104, 3, 105, 3, 105, 3, 106, 3, 106, 3, 107, 3, 107, 3, 108, 
-	3, 108, 3, 109, 3, 109, 3, 110, 3, 110, 3, 111, 3, 111, 3, 112, 3, 112, 
-	3, 113, 3, 113, 3, 114, 3, 114, 3, 115, 3, 115, 3, 116, 3, 116, 3, 117, 
-	3, 117, 3, 118, 3, 118, 3, 119, 3, 119, 3, 120, 3, 120, 3, 121, 3, 121, 
-	3, 122, 3, 122, 3, 123, 3, 123, 3, 124, 3, 124, 3, 125, 3, 125, 3, 126, 
-	3, 126, 3, 127, 3, 127, 3, 128, 3, 128, 3, 129, 3, 129, 6, 770, 786, 794, 
-	802, 2, 130, 3, 3, 5, 4, 7, 5, 9, 6, 11, 7, 13, 8, 15, 9, 17, 10, 19, 11, 
-	21, 12, 23, 13, 25, 14, 27, 15, 29, 16, 31, 17, 33, 18, 35, 19, 37, 20, 
-	39, 21, 41, 22, 43, 23, 45, 24, 47, 25, 49, 26, 51, 27, 53, 28, 55, 29, 
-	57, 30, 59, 31, 61, 32, 63, 33, 65, 34, 67, 35, 69, 36, 71, 37, 73, 38, 
-	75, 39, 77, 40, 79, 41, 81, 42, 83, 43, 85, 44, 87, 45, 89, 46, 91, 47, 
-	93, 48, 95, 49, 97, 50, 99, 51, 101, 52, 103, 53, 105, 54, 107, 55, 109, 
-	56, 111, 57, 113, 58, 115, 59, 117, 60, 119, 61, 121, 62, 123, 63, 125, 
-	64, 127, 65, 129, 66, 131, 67, 133, 68, 135, 69, 137, 70, 139, 71, 141, 
-	72, 143, 73, 145, 74, 147, 75, 149, 76, 151, 77, 153, 78, 155, 79, 157, 
-	80, 159, 81, 161, 82, 163, 83, 165, 84, 167, 85, 169, 86, 171, 87, 173, 
-	88, 175, 89, 177, 90, 179, 91, 181, 92, 183, 93, 185, 94, 187, 95, 189, 
-	96, 191, 97, 193, 98, 195, 99, 197, 100, 199, 101, 201, 2, 203, 2, 205, 
+	9, 65, 4, 66, 9, 66, 4, 69, 9, 69, 4, 70, 9, 70, 4, 71, 9, 71, 4, 72, 9, 
+	72, 4, 73, 9, 73, 4, 74, 9, 74, 4, 75, 9, 75, 4, 76, 9, 76, 4, 77, 9, 77, 
+	4, 78, 9, 78, 4, 79, 9, 79, 4, 80, 9, 80, 4, 81, 9, 81, 4, 82, 9, 82, 4, 
+	83, 9, 83, 4, 84, 9, 84, 4, 85, 9, 85, 4, 86, 9, 86, 4, 87, 9, 87, 4, 88, 
+	9, 88, 4, 89, 9, 89, 4, 90, 9, 90, 4, 91, 9, 91, 4, 92, 9, 92, 4, 93, 9, 
+	93, 4, 94, 9, 94, 4, 95, 9, 95, 4, 96, 9, 96, 4, 97, 9, 97, 4, 98, 9, 98, 
+	4, 99, 9, 99, 4, 100, 9, 100, 4, 101, 9, 101, 4, 102, 9, 102, 4, 103, 9, 
+	103, 4, 104, 9, 104, 4, 105, 9, 105, 4, 106, 9, 106, 4, 107, 9, 107, 4, 
+	108, 9, 108, 4, 109, 9, 109, 4, 110, 9, 110, 4, 111, 9, 111, 4, 112, 9, 
+	112, 4, 113, 9, 113, 4, 114, 9, 114, 4, 115, 9, 115, 4, 116, 9, 116, 4, 
+	117, 9, 117, 4, 118, 9, 118, 4, 119, 9, 119, 4, 120, 9, 120, 4, 121, 9, 
+	121, 4, 122, 9, 122, 4, 123, 9, 123, 4, 124, 9, 124, 4, 125, 9, 125, 4, 
+	126, 9, 126, 4, 127, 9, 127, 4, 128, 9, 128, 4, 129, 9, 129, 4, 130, 9, 
+	130, 4, 131, 9, 131, 3, 2, 3, 2, 3, 2, 3, 2, 3, 2, 3, 2, 3, 2, 3, 3, 3, 
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 4, 3, 4, 3, 4, 3, 4, 3, 5, 3, 5, 3, 
+	5, 3, 5, 3, 5, 3, 6, 3, 6, 3, 6, 3, 6, 3, 6, 3, 6, 3, 6, 3, 6, 3, 6, 3, 
+	7, 3, 7, 3, 7, 3, 7, 3, 7, 3, 8, 3, 8, 3, 8, 3, 8, 3, 8, 3, 8, 3, 9, 3, 
+	9, 3, 9, 3, 9, 3, 9, 3, 9, 3, 9, 3, 9, 3, 9, 3, 9, 3, 9, 3, 9, 3, 10, 3, 
+	10, 3, 10, 3, 10, 3, 11, 3, 11, 3, 11, 3, 11, 3, 11, 3, 11, 3, 11, 3, 11, 
+	3, 12, 3, 12, 3, 12, 3, 12, 3, 12, 3, 12, 3, 12, 3, 12, 3, 13, 3, 13, 3, 
+	13, 3, 13, 3, 13, 3, 13, 3, 13, 3, 13, 3, 13, 3, 13, 3, 14, 3, 14, 3, 14, 
+	3, 14, 3, 14, 3, 15, 3, 15, 3, 15, 3, 16, 3, 16, 3, 16, 3, 16, 3, 16, 3, 
+	17, 3, 17, 3, 17, 3, 17, 3, 17, 3, 17, 3, 17, 3, 17, 3, 17, 3, 18, 3, 18, 
+	3, 18, 3, 18, 3, 18, 3, 18, 3, 18, 3, 18, 3, 18, 3, 18, 3, 19, 3, 19, 3, 
+	19, 3, 19, 3, 19, 3, 20, 3, 20, 3, 20, 3, 20, 3, 20, 3, 20, 3, 20, 3, 20, 
+	3, 20, 3, 20, 3, 20, 3, 20, 3, 20, 3, 21, 3, 21, 3, 21, 3, 21, 3, 21, 3, 
+	21, 3, 21, 3, 21, 3, 21, 3, 21, 3, 21, 3, 21, 3, 22, 3, 22, 3, 22, 3, 22, 
+	3, 22, 3, 22, 3, 23, 3, 23, 3, 23, 3, 23, 3, 24, 3, 24, 3, 24, 3, 24, 3, 
+	24, 3, 25, 3, 25, 3, 25, 3, 25, 3, 25, 3, 26, 3, 26, 3, 26, 3, 26, 3, 27, 
+	3, 27, 3, 27, 3, 27, 3, 27, 3, 28, 3, 28, 3, 28, 3, 28, 3, 28, 3, 28, 3, 
+	28, 3, 29, 3, 29, 3, 29, 3, 29, 3, 29, 3, 29, 3, 30, 3, 30, 3, 30, 3, 30, 
+	3, 30, 3, 31, 3, 31, 3, 31, 3, 31, 3, 31, 3, 31, 3, 32, 3, 32, 3, 32, 3, 
+	32, 3, 32, 3, 32, 3, 33, 3, 33, 3, 33, 3, 33, 3, 33, 3, 33, 3, 33, 3, 33, 
+	3, 34, 3, 34, 3, 34, 3, 34, 3, 34, 3, 34, 3, 35, 3, 35, 3, 35, 3, 35, 3, 
+	35, 3, 35, 3, 35, 3, 35, 3, 36, 3, 36, 3, 36, 3, 36, 3, 36, 3, 36, 3, 36, 
+	3, 36, 3, 36, 3, 36, 3, 37, 3, 37, 3, 37, 3, 37, 3, 37, 3, 37, 3, 37, 3, 
+	38, 3, 38, 3, 38, 3, 39, 3, 39, 3, 39, 3, 39, 3, 40, 3, 40, 3, 40, 3, 41, 
+	3, 41, 3, 41, 3, 41, 3, 41, 3, 42, 3, 42, 3, 42, 3, 42, 3, 42, 3, 43, 3, 
+	43, 3, 43, 3, 43, 3, 43, 3, 43, 3, 43, 3, 43, 3, 43, 3, 44, 3, 44, 3, 44, 
+	3, 44, 3, 44, 3, 44, 3, 45, 3, 45, 3, 45, 3, 45, 3, 46, 3, 46, 3, 46, 3, 
+	46, 3, 46, 3, 47, 3, 47, 3, 47, 3, 47, 3, 47, 3, 48, 3, 48, 3, 48, 3, 48, 
+	3, 49, 3, 49, 3, 49, 3, 49, 3, 49, 3, 49, 3, 49, 3, 49, 3, 50, 3, 50, 3, 
+	50, 3, 51, 3, 51, 3, 51, 3, 51, 3, 51, 3, 51, 3, 52, 3, 52, 3, 52, 3, 52, 
+	3, 52, 3, 52, 3, 52, 3, 53, 3, 53, 3, 53, 3, 54, 3, 54, 3, 54, 3, 54, 3, 
+	55, 3, 55, 3, 55, 3, 55, 3, 55, 3, 55, 3, 56, 3, 56, 3, 56, 3, 56, 3, 56, 
+	3, 57, 3, 57, 3, 57, 3, 57, 3, 58, 3, 58, 3, 58, 3, 59, 3, 59, 3, 59, 3, 
+	59, 3, 60, 3, 60, 3, 60, 3, 60, 3, 60, 3, 60, 3, 60, 3, 60, 3, 61, 3, 61, 
+	3, 61, 3, 61, 3, 62, 3, 62, 3, 62, 3, 62, 3, 63, 3, 63, 3, 63, 3, 63, 3, 
+	64, 3, 64, 3, 64, 3, 64, 3, 65, 3, 65, 3, 65, 3, 65, 3, 65, 3, 65, 3, 65, 
+	3, 66, 3, 66, 3, 66, 3, 66, 3, 66, 3, 66, 3, 66, 3, 66, 3, 66, 3, 66, 3, 
+	69, 3, 69, 3, 70, 3, 70, 3, 71, 3, 71, 3, 72, 3, 72, 3, 73, 3, 73, 3, 74, 
+	3, 74, 3, 75, 3, 75, 3, 76, 3, 76, 3, 77, 3, 77, 3, 78, 3, 78, 3, 79, 3, 
+	79, 3, 79, 3, 80, 3, 80, 3, 80, 3, 81, 3, 81, 3, 82, 3, 82, 3, 82, 3, 83, 
+	3, 83, 3, 84, 3, 84, 3, 84, 3, 85, 3, 85, 3, 85, 3, 86, 3, 86, 3, 86, 3, 
+	87, 3, 87, 3, 88, 3, 88, 3, 89, 3, 89, 3, 90, 3, 90, 3, 91, 3, 91, 3, 92, 
+	3, 92, 3, 93, 3, 93, 3, 94, 3, 94, 3, 95, 3, 95, 3, 96, 3, 96, 3, 97, 3, 
+	97, 3, 98, 3, 98, 3, 99, 3, 99, 3, 100, 6, 100, 721, 10, 100, 13, 100, 
+	14, 100, 722, 3, 101, 6, 101, 726, 10, 101, 13, 101, 14, 101, 727, 3, 101, 
+	3, 101, 3, 101, 7, 101, 733, 10, 101, 12, 101, 14, 101, 736, 11, 101, 3, 
+	101, 3, 101, 6, 101, 740, 10, 101, 13, 101, 14, 101, 741, 5, 101, 744, 
+	10, 101, 3, 102, 6, 102, 747, 10, 102, 13, 102, 14, 102, 748, 3, 102, 3, 
+	102, 3, 103, 3, 103, 3, 104, 3, 104, 3, 105, 3, 105, 3, 105, 3, 105, 7, 
+	105, 761, 10, 105, 12, 105, 14, 105, 764, 11, 105, 3, 105, 3, 105, 3, 105, 
+	7, 105, 769, 10, 105, 12, 105, 14, 105, 772, 11, 105, 3, 105, 3, 105, 3, 
+	105, 3, 105, 3, 105, 6, 105, 779, 10, 105, 13, 105, 14, 105, 780, 3, 105, 
+	3, 105, 7, 105, 785, 10, 105, 12, 105, 14, 105, 788, 11, 105, 3, 105, 3, 
+	105, 3, 105, 7, 105, 793, 10, 105, 12, 105, 14, 105, 796, 11, 105, 3, 105, 
+	3, 105, 3, 105, 7, 105, 801, 10, 105, 12, 105, 14, 105, 804, 11, 105, 3, 
+	105, 5, 105, 807, 10, 105, 3, 106, 3, 106, 3, 107, 3, 107, 3, 108, 3, 108, 
+	3, 109, 3, 109, 3, 110, 3, 110, 3, 111, 3, 111, 3, 112, 3, 112, 3, 113, 
+	3, 113, 3, 114, 3, 114, 3, 115, 3, 115, 3, 116, 3, 116, 3, 117, 3, 117, 
+	3, 118, 3, 118, 3, 119, 3, 119, 3, 120, 3, 120, 3, 121, 3, 121, 3, 122, 
+	3, 122, 3, 123, 3, 123, 3, 124, 3, 124, 3, 125, 3, 125, 3, 126, 3, 126, 
+	3, 127, 3, 127, 3, 128, 3, 128, 3, 129, 3, 129, 3, 130, 3, 130, 3, 131, 
+	3, 131, 4, 67, 9, 67, 4, 68, 9, 68, 3, 67, 3, 67, 3, 67, 3, 67, 3, 67, 
+	3, 67, 3, 67, 3, 67, 3, 67, 3, 67, 3, 67, 3, 67, 3, 67, 3, 67, 3, 67, 3, 
+	68, 3, 68, 3, 68, 3, 68, 3, 68, 6, 770, 786, 794, 802, 2, 132, 3, 3, 5, 
+	4, 7, 5, 9, 6, 11, 7, 13, 8, 15, 9, 17, 10, 19, 11, 21, 12, 23, 13, 25, 
+	14, 27, 15, 29, 16, 31, 17, 33, 18, 35, 19, 37, 20, 39, 21, 41, 22, 43, 
+	23, 45, 24, 47, 25, 49, 26, 51, 27, 53, 28, 55, 29, 57, 30, 59, 31, 61, 
+	32, 63, 33, 65, 34, 67, 35, 69, 36, 71, 37, 73, 38, 75, 39, 77, 40, 79, 
+	41, 81, 42, 83, 43, 85, 44, 87, 45, 89, 46, 91, 47, 93, 48, 95, 49, 97, 
+	50, 99, 51, 101, 52, 103, 53, 105, 54, 107, 55, 109, 56, 111, 57, 113, 
+	58, 115, 59, 117, 60, 119, 61, 121, 62, 123, 63, 125, 64, 127, 65, 129, 
+	66, 131, 67, 860, 68, 862, 69, 133, 70, 135, 71, 137, 72, 139, 73, 141, 
+	74, 143, 75, 145, 76, 147, 77, 149, 78, 151, 79, 153, 80, 155, 81, 157, 
+	82, 159, 83, 161, 84, 163, 85, 165, 86, 167, 87, 169, 88, 171, 89, 173, 
+	90, 175, 91, 177, 92, 179, 93, 181, 94, 183, 95, 185, 96, 187, 97, 189, 
+	98, 191, 99, 193, 100, 195, 101, 197, 102, 199, 103, 201, 2, 203, 2, 205, 
 	2, 207, 2, 209, 2, 211, 2, 213, 2, 215, 2, 217, 2, 219, 2, 221, 2, 223, 
 	2, 225, 2, 227, 2, 229, 2, 231, 2, 233, 2, 235, 2, 237, 2, 239, 2, 241, 
 	2, 243, 2, 245, 2, 247, 2, 249, 2, 251, 2, 253, 2, 255, 2, 257, 2, 3, 2, 
@@ -125,7 +128,7 @@ var serializedLexerAtn = []uint16{
 	2, 82, 82, 114, 114, 4, 2, 83, 83, 115, 115, 4, 2, 84, 84, 116, 116, 4, 
 	2, 85, 85, 117, 117, 4, 2, 86, 86, 118, 118, 4, 2, 87, 87, 119, 119, 4, 
 	2, 88, 88, 120, 120, 4, 2, 89, 89, 121, 121, 4, 2, 90, 90, 122, 122, 4, 
-	2, 91, 91, 123, 123, 4, 2, 92, 92, 124, 124, 2, 851, 2, 3, 3, 2, 2, 2, 
+	2, 91, 91, 123, 123, 4, 2, 92, 92, 124, 124, 2, 875, 2, 3, 3, 2, 2, 2, 
 	2, 5, 3, 2, 2, 2, 2, 7, 3, 2, 2, 2, 2, 9, 3, 2, 2, 2, 2, 11, 3, 2, 2, 2, 
 	2, 13, 3, 2, 2, 2, 2, 15, 3, 2, 2, 2, 2, 17, 3, 2, 2, 2, 2, 19, 3, 2, 2, 
 	2, 2, 21, 3, 2, 2, 2, 2, 23, 3, 2, 2, 2, 2, 25, 3, 2, 2, 2, 2, 27, 3, 2, 
@@ -143,262 +146,270 @@ var serializedLexerAtn = []uint16{
 	3, 2, 2, 2, 2, 113, 3, 2, 2, 2, 2, 115, 3, 2, 2, 2, 2, 117, 3, 2, 2, 2, 
 	2, 119, 3, 2, 2, 2, 2, 121, 3, 2, 2, 2, 2, 123, 3, 2, 2, 2, 2, 125, 3, 
 	2, 2, 2, 2, 127, 3, 2, 2, 2, 2, 129, 3, 2, 2, 2, 2, 131, 3, 2, 2, 2, 2, 
-	133, 3, 2, 2, 2, 2, 135, 3, 2, 2, 2, 2, 137, 3, 2, 2, 2, 2, 139, 3, 2, 
-	2, 2, 2, 141, 3, 2, 2, 2, 2, 143, 3, 2, 2, 2, 2, 145, 3, 2, 2, 2, 2, 147, 
-	3, 2, 2, 2, 2, 149, 3, 2, 2, 2, 2, 151, 3, 2, 2, 2, 2, 153, 3, 2, 2, 2, 
-	2, 155, 3, 2, 2, 2, 2, 157, 3, 2, 2, 2, 2, 159, 3, 2, 2, 2, 2, 161, 3, 
-	2, 2, 2, 2, 163, 3, 2, 2, 2, 2, 165, 3, 2, 2, 2, 2, 167, 3, 2, 2, 2, 2, 
-	169, 3, 2, 2, 2, 2, 171, 3, 2, 2, 2, 2, 173, 3, 2, 2, 2, 2, 175, 3, 2, 
-	2, 2, 2, 177, 3, 2, 2, 2, 2, 179, 3, 2, 2, 2, 2, 181, 3, 2, 2, 2, 2, 183, 
-	3, 2, 2, 2, 2, 185, 3, 2, 2, 2, 2, 187, 3, 2, 2, 2, 2, 189, 3, 2, 2, 2, 
-	2, 191, 3, 2, 2, 2, 2, 193, 3, 2, 2, 2, 2, 195, 3, 2, 2, 2, 2, 197, 3, 
-	2, 2, 2, 2, 199, 3, 2, 2, 2, 3, 259, 3, 2, 2, 2, 5, 266, 3, 2, 2, 2, 7, 
-	273, 3, 2, 2, 2, 9, 277, 3, 2, 2, 2, 11, 282, 3, 2, 2, 2, 13, 291, 3, 2, 
-	2, 2, 15, 296, 3, 2, 2, 2, 17, 302, 3, 2, 2, 2, 19, 314, 3, 2, 2, 2, 21, 
-	318, 3, 2, 2, 2, 23, 326, 3, 2, 2, 2, 25, 334, 3, 2, 2, 2, 27, 344, 3, 
-	2, 2, 2, 29, 349, 3, 2, 2, 2, 31, 352, 3, 2, 2, 2, 33, 357, 3, 2, 2, 2, 
-	35, 366, 3, 2, 2, 2, 37, 376, 3, 2, 2, 2, 39, 381, 3, 2, 2, 2, 41, 394, 
-	3, 2, 2, 2, 43, 406, 3, 2, 2, 2, 45, 412, 3, 2, 2, 2, 47, 416, 3, 2, 2, 
-	2, 49, 421, 3, 2, 2, 2, 51, 426, 3, 2, 2, 2, 53, 430, 3, 2, 2, 2, 55, 435, 
-	3, 2, 2, 2, 57, 442, 3, 2, 2, 2, 59, 448, 3, 2, 2, 2, 61, 453, 3, 2, 2, 
-	2, 63, 459, 3, 2, 2, 2, 65, 465, 3, 2, 2, 2, 67, 473, 3, 2, 2, 2, 69, 479, 
-	3, 2, 2, 2, 71, 487, 3, 2, 2, 2, 73, 497, 3, 2, 2, 2, 75, 504, 3, 2, 2, 
-	2, 77, 507, 3, 2, 2, 2, 79, 511, 3, 2, 2, 2, 81, 514, 3, 2, 2, 2, 83, 519, 
-	3, 2, 2, 2, 85, 524, 3, 2, 2, 2, 87, 533, 3, 2, 2, 2, 89, 539, 3, 2, 2, 
-	2, 91, 543, 3, 2, 2, 2, 93, 548, 3, 2, 2, 2, 95, 553, 3, 2, 2, 2, 97, 557, 
-	3, 2, 2, 2, 99, 565, 3, 2, 2, 2, 101, 568, 3, 2, 2, 2, 103, 574, 3, 2, 
-	2, 2, 105, 581, 3, 2, 2, 2, 107, 584, 3, 2, 2, 2, 109, 588, 3, 2, 2, 2, 
-	111, 594, 3, 2, 2, 2, 113, 599, 3, 2, 2, 2, 115, 603, 3, 2, 2, 2, 117, 
-	606, 3, 2, 2, 2, 119, 610, 3, 2, 2, 2, 121, 618, 3, 2, 2, 2, 123, 622, 
-	3, 2, 2, 2, 125, 626, 3, 2, 2, 2, 127, 630, 3, 2, 2, 2, 129, 634, 3, 2, 
-	2, 2, 131, 641, 3, 2, 2, 2, 133, 651, 3, 2, 2, 2, 135, 653, 3, 2, 2, 2, 
-	137, 655, 3, 2, 2, 2, 139, 657, 3, 2, 2, 2, 141, 659, 3, 2, 2, 2, 143, 
-	661, 3, 2, 2, 2, 145, 663, 3, 2, 2, 2, 147, 665, 3, 2, 2, 2, 149, 667, 
-	3, 2, 2, 2, 151, 669, 3, 2, 2, 2, 153, 671, 3, 2, 2, 2, 155, 674, 3, 2, 
-	2, 2, 157, 677, 3, 2, 2, 2, 159, 679, 3, 2, 2, 2, 161, 682, 3, 2, 2, 2, 
-	163, 684, 3, 2, 2, 2, 165, 687, 3, 2, 2, 2, 167, 690, 3, 2, 2, 2, 169, 
-	693, 3, 2, 2, 2, 171, 695, 3, 2, 2, 2, 173, 697, 3, 2, 2, 2, 175, 699, 
-	3, 2, 2, 2, 177, 701, 3, 2, 2, 2, 179, 703, 3, 2, 2, 2, 181, 705, 3, 2, 
-	2, 2, 183, 707, 3, 2, 2, 2, 185, 709, 3, 2, 2, 2, 187, 711, 3, 2, 2, 2, 
-	189, 713, 3, 2, 2, 2, 191, 715, 3, 2, 2, 2, 193, 717, 3, 2, 2, 2, 195, 
-	720, 3, 2, 2, 2, 197, 743, 3, 2, 2, 2, 199, 746, 3, 2, 2, 2, 201, 752, 
-	3, 2, 2, 2, 203, 754, 3, 2, 2, 2, 205, 806, 3, 2, 2, 2, 207, 808, 3, 2, 
-	2, 2, 209, 810, 3, 2, 2, 2, 211, 812, 3, 2, 2, 2, 213, 814, 3, 2, 2, 2, 
-	215, 816, 3, 2, 2, 2, 217, 818, 3, 2, 2, 2, 219, 820, 3, 2, 2, 2, 221, 
-	822, 3, 2, 2, 2, 223, 824, 3, 2, 2, 2, 225, 826, 3, 2, 2, 2, 227, 828, 
-	3, 2, 2, 2, 229, 830, 3, 2, 2, 2, 231, 832, 3, 2, 2, 2, 233, 834, 3, 2, 
-	2, 2, 235, 836, 3, 2, 2, 2, 237, 838, 3, 2, 2, 2, 239, 840, 3, 2, 2, 2, 
-	241, 842, 3, 2, 2, 2, 243, 844, 3, 2, 2, 2, 245, 846, 3, 2, 2, 2, 247, 
-	848, 3, 2, 2, 2, 249, 850, 3, 2, 2, 2, 251, 852, 3, 2, 2, 2, 253, 854, 
-	3, 2, 2, 2, 255, 856, 3, 2, 2, 2, 257, 858, 3, 2, 2, 2, 259, 260, 5, 211, 
-	106, 2, 260, 261, 5, 241, 121, 2, 261, 262, 5, 215, 108, 2, 262, 263, 5, 
-	207, 104, 2, 263, 264, 5, 245, 123, 2, 264, 265, 5, 215, 108, 2, 265, 4, 
-	3, 2, 2, 2, 266, 267, 5, 247, 124, 2, 267, 268, 5, 237, 119, 2, 268, 269, 
-	5, 213, 107, 2, 269, 270, 5, 207, 104, 2, 270, 271, 5, 245, 123, 2, 271, 
-	272, 5, 215, 108, 2, 272, 6, 3, 2, 2, 2, 273, 274, 5, 243, 122, 2, 274, 
-	275, 5, 215, 108, 2, 275, 276, 5, 245, 123, 2, 276, 8, 3, 2, 2, 2, 277, 
-	278, 5, 213, 107, 2, 278, 279, 5, 241, 121, 2, 279, 280, 5, 235, 118, 2, 
-	280, 281, 5, 237, 119, 2, 281, 10, 3, 2, 2, 2, 282, 283, 5, 223, 112, 2, 
-	283, 284, 5, 233, 117, 2, 284, 285, 5, 245, 123, 2, 285, 286, 5, 215, 108, 
-	2, 286, 287, 5, 241, 121, 2, 287, 288, 5, 249, 125, 2, 288, 289, 5, 207, 
-	104, 2, 289, 290, 5, 229, 115, 2, 290, 12, 3, 2, 2, 2, 291, 292, 5, 233, 
-	117, 2, 292, 293, 5, 207, 104, 2, 293, 294, 5, 231, 116, 2, 294, 295, 5, 
-	215, 108, 2, 295, 14, 3, 2, 2, 2, 296, 297, 5, 243, 122, 2, 297, 298, 5, 
-	221, 111, 2, 298, 299, 5, 207, 104, 2, 299, 300, 5, 241, 121, 2, 300, 301, 
-	5, 213, 107, 2, 301, 16, 3, 2, 2, 2, 302, 303, 5, 241, 121, 2, 303, 304, 
-	5, 215, 108, 2, 304, 305, 5, 237, 119, 2, 305, 306, 5, 229, 115, 2, 306, 
-	307, 5, 223, 112, 2, 307, 308, 5, 211, 106, 2, 308, 309, 5, 207, 104, 2, 
-	309, 310, 5, 245, 123, 2, 310, 311, 5, 223, 112, 2, 311, 312, 5, 235, 118, 
-	2, 312, 313, 5, 233, 117, 2, 313, 18, 3, 2, 2, 2, 314, 315, 5, 245, 123, 
-	2, 315, 316, 5, 245, 123, 2, 316, 317, 5, 229, 115, 2, 317, 20, 3, 2, 2, 
-	2, 318, 319, 5, 231, 116, 2, 319, 320, 5, 215, 108, 2, 320, 321, 5, 245, 
-	123, 2, 321, 322, 5, 207, 104, 2, 322, 323, 5, 245, 123, 2, 323, 324, 5, 
-	245, 123, 2, 324, 325, 5, 229, 115, 2, 325, 22, 3, 2, 2, 2, 326, 327, 5, 
-	237, 119, 2, 327, 328, 5, 207, 104, 2, 328, 329, 5, 243, 122, 2, 329, 330, 
-	5, 245, 123, 2, 330, 331, 5, 245, 123, 2, 331, 332, 5, 245, 123, 2, 332, 
-	333, 5, 229, 115, 2, 333, 24, 3, 2, 2, 2, 334, 335, 5, 217, 109, 2, 335, 
-	336, 5, 247, 124, 2, 336, 337, 5, 245, 123, 2, 337, 338, 5, 247, 124, 2, 
-	338, 339, 5, 241, 121, 2, 339, 340, 5, 215, 108, 2, 340, 341, 5, 245, 123, 
-	2, 341, 342, 5, 245, 123, 2, 342, 343, 5, 229, 115, 2, 343, 26, 3, 2, 2, 
-	2, 344, 345, 5, 227, 114, 2, 345, 346, 5, 223, 112, 2, 346, 347, 5, 229, 
-	115, 2, 347, 348, 5, 229, 115, 2, 348, 28, 3, 2, 2, 2, 349, 350, 5, 235, 
-	118, 2, 350, 351, 5, 233, 117, 2, 351, 30, 3, 2, 2, 2, 352, 353, 5, 243, 
-	122, 2, 353, 354, 5, 221, 111, 2, 354, 355, 5, 235, 118, 2, 355, 356, 5, 
-	251, 126, 2, 356, 32, 3, 2, 2, 2, 357, 358, 5, 213, 107, 2, 358, 359, 5, 
-	207, 104, 2, 359, 360, 5, 245, 123, 2, 360, 361, 5, 207, 104, 2, 361, 362, 
-	5, 209, 105, 2, 362, 363, 5, 207, 104, 2, 363, 364, 5, 243, 122, 2, 364, 
-	365, 5, 215, 108, 2, 365, 34, 3, 2, 2, 2, 366, 367, 5, 213, 107, 2, 367, 
-	368, 5, 207, 104, 2, 368, 369, 5, 245, 123, 2, 369, 370, 5, 207, 104, 2, 
-	370, 371, 5, 209, 105, 2, 371, 372, 5, 207, 104, 2, 372, 373, 5, 243, 122, 
-	2, 373, 374, 5, 215, 108, 2, 374, 375, 5, 243, 122, 2, 375, 36, 3, 2, 2, 
-	2, 376, 377, 5, 233, 117, 2, 377, 378, 5, 235, 118, 2, 378, 379, 5, 213, 
-	107, 2, 379, 380, 5, 215, 108, 2, 380, 38, 3, 2, 2, 2, 381, 382, 5, 231, 
-	116, 2, 382, 383, 5, 215, 108, 2, 383, 384, 5, 207, 104, 2, 384, 385, 5, 
-	243, 122, 2, 385, 386, 5, 247, 124, 2, 386, 387, 5, 241, 121, 2, 387, 388, 
-	5, 215, 108, 2, 388, 389, 5, 231, 116, 2, 389, 390, 5, 215, 108, 2, 390, 
-	391, 5, 233, 117, 2, 391, 392, 5, 245, 123, 2, 392, 393, 5, 243, 122, 2, 
-	393, 40, 3, 2, 2, 2, 394, 395, 5, 231, 116, 2, 395, 396, 5, 215, 108, 2, 
-	396, 397, 5, 207, 104, 2, 397, 398, 5, 243, 122, 2, 398, 399, 5, 247, 124, 
-	2, 399, 400, 5, 241, 121, 2, 400, 401, 5, 215, 108, 2, 401, 402, 5, 231, 
-	116, 2, 402, 403, 5, 215, 108, 2, 403, 404, 5, 233, 117, 2, 404, 405, 5, 
-	245, 123, 2, 405, 42, 3, 2, 2, 2, 406, 407, 5, 217, 109, 2, 407, 408, 5, 
-	223, 112, 2, 408, 409, 5, 215, 108, 2, 409, 410, 5, 229, 115, 2, 410, 411, 
-	5, 213, 107, 2, 411, 44, 3, 2, 2, 2, 412, 413, 5, 245, 123, 2, 413, 414, 
-	5, 207, 104, 2, 414, 415, 5, 219, 110, 2, 415, 46, 3, 2, 2, 2, 416, 417, 
-	5, 223, 112, 2, 417, 418, 5, 233, 117, 2, 418, 419, 5, 217, 109, 2, 419, 
-	420, 5, 235, 118, 2, 420, 48, 3, 2, 2, 2, 421, 422, 5, 227, 114, 2, 422, 
-	423, 5, 215, 108, 2, 423, 424, 5, 255, 128, 2, 424, 425, 5, 243, 122, 2, 
-	425, 50, 3, 2, 2, 2, 426, 427, 5, 227, 114, 2, 427, 428, 5, 215, 108, 2, 
-	428, 429, 5, 255, 128, 2, 429, 52, 3, 2, 2, 2, 430, 431, 5, 251, 126, 2, 
-	431, 432, 5, 223, 112, 2, 432, 433, 5, 245, 123, 2, 433, 434, 5, 221, 111, 
-	2, 434, 54, 3, 2, 2, 2, 435, 436, 5, 249, 125, 2, 436, 437, 5, 207, 104, 
-	2, 437, 438, 5, 229, 115, 2, 438, 439, 5, 247, 124, 2, 439, 440, 5, 215, 
-	108, 2, 440, 441, 5, 243, 122, 2, 441, 56, 3, 2, 2, 2, 442, 443, 5, 249, 
-	125, 2, 443, 444, 5, 207, 104, 2, 444, 445, 5, 229, 115, 2, 445, 446, 5, 
-	247, 124, 2, 446, 447, 5, 215, 108, 2, 447, 58, 3, 2, 2, 2, 448, 449, 5, 
-	217, 109, 2, 449, 450, 5, 241, 121, 2, 450, 451, 5, 235, 118, 2, 451, 452, 
-	5, 231, 116, 2, 452, 60, 3, 2, 2, 2, 453, 454, 5, 251, 126, 2, 454, 455, 
-	5, 221, 111, 2, 455, 456, 5, 215, 108, 2, 456, 457, 5, 241, 121, 2, 457, 
-	458, 5, 215, 108, 2, 458, 62, 3, 2, 2, 2, 459, 460, 5, 229, 115, 2, 460, 
-	461, 5, 223, 112, 2, 461, 462, 5, 231, 116, 2, 462, 463, 5, 223, 112, 2, 
-	463, 464, 5, 245, 123, 2, 464, 64, 3, 2, 2, 2, 465, 466, 5, 239, 120, 2, 
-	466, 467, 5, 247, 124, 2, 467, 468, 5, 215, 108, 2, 468, 469, 5, 241, 121, 
-	2, 469, 470, 5, 223, 112, 2, 470, 471, 5, 215, 108, 2, 471, 472, 5, 243, 
-	122, 2, 472, 66, 3, 2, 2, 2, 473, 474, 5, 239, 120, 2, 474, 475, 5, 247, 
-	124, 2, 475, 476, 5, 215, 108, 2, 476, 477, 5, 241, 121, 2, 477, 478, 5, 
-	255, 128, 2, 478, 68, 3, 2, 2, 2, 479, 480, 5, 215, 108, 2, 480, 481, 5, 
-	253, 127, 2, 481, 482, 5, 237, 119, 2, 482, 483, 5, 229, 115, 2, 483, 484, 
-	5, 207, 104, 2, 484, 485, 5, 223, 112, 2, 485, 486, 5, 233, 117, 2, 486, 
-	70, 3, 2, 2, 2, 487, 488, 5, 251, 126, 2, 488, 489, 5, 223, 112, 2, 489, 
-	490, 5, 245, 123, 2, 490, 491, 5, 221, 111, 2, 491, 492, 5, 249, 125, 2, 
-	492, 493, 5, 207, 104, 2, 493, 494, 5, 229, 115, 2, 494, 495, 5, 247, 124, 
-	2, 495, 496, 5, 215, 108, 2, 496, 72, 3, 2, 2, 2, 497, 498, 5, 243, 122, 
-	2, 498, 499, 5, 215, 108, 2, 499, 500, 5, 229, 115, 2, 500, 501, 5, 215, 
-	108, 2, 501, 502, 5, 211, 106, 2, 502, 503, 5, 245, 123, 2, 503, 74, 3, 
-	2, 2, 2, 504, 505, 5, 207, 104, 2, 505, 506, 5, 243, 122, 2, 506, 76, 3, 
-	2, 2, 2, 507, 508, 5, 207, 104, 2, 508, 509, 5, 233, 117, 2, 509, 510, 
-	5, 213, 107, 2, 510, 78, 3, 2, 2, 2, 511, 512, 5, 235, 118, 2, 512, 513, 
-	5, 241, 121, 2, 513, 80, 3, 2, 2, 2, 514, 515, 5, 217, 109, 2, 515, 516, 
-	5, 223, 112, 2, 516, 517, 5, 229, 115, 2, 517, 518, 5, 229, 115, 2, 518, 
-	82, 3, 2, 2, 2, 519, 520, 5, 233, 117, 2, 520, 521, 5, 247, 124, 2, 521, 
-	522, 5, 229, 115, 2, 522, 523, 5, 229, 115, 2, 523, 84, 3, 2, 2, 2, 524, 
-	525, 5, 237, 119, 2, 525, 526, 5, 241, 121, 2, 526, 527, 5, 215, 108, 2, 
-	527, 528, 5, 249, 125, 2, 528, 529, 5, 223, 112, 2, 529, 530, 5, 235, 118, 
-	2, 530, 531, 5, 247, 124, 2, 531, 532, 5, 243, 122, 2, 532, 86, 3, 2, 2, 
-	2, 533, 534, 5, 235, 118, 2, 534, 535, 5, 241, 121, 2, 535, 536, 5, 213, 
-	107, 2, 536, 537, 5, 215, 108, 2, 537, 538, 5, 241, 121, 2, 538, 88, 3, 
-	2, 2, 2, 539, 540, 5, 207, 104, 2, 540, 541, 5, 243, 122, 2, 541, 542, 
-	5, 211, 106, 2, 542, 90, 3, 2, 2, 2, 543, 544, 5, 213, 107, 2, 544, 545, 
-	5, 215, 108, 2, 545, 546, 5, 243, 122, 2, 546, 547, 5, 211, 106, 2, 547, 
-	92, 3, 2, 2, 2, 548, 549, 5, 229, 115, 2, 549, 550, 5, 223, 112, 2, 550, 
-	551, 5, 227, 114, 2, 551, 552, 5, 215, 108, 2, 552, 94, 3, 2, 2, 2, 553, 
-	554, 5, 233, 117, 2, 554, 555, 5, 235, 118, 2, 555, 556, 5, 245, 123, 2, 
-	556, 96, 3, 2, 2, 2, 557, 558, 5, 209, 105, 2, 558, 559, 5, 215, 108, 2, 
-	559, 560, 5, 245, 123, 2, 560, 561, 5, 251, 126, 2, 561, 562, 5, 215, 108, 
-	2, 562, 563, 5, 215, 108, 2, 563, 564, 5, 233, 117, 2, 564, 98, 3, 2, 2, 
-	2, 565, 566, 5, 223, 112, 2, 566, 567, 5, 243, 122, 2, 567, 100, 3, 2, 
-	2, 2, 568, 569, 5, 219, 110, 2, 569, 570, 5, 241, 121, 2, 570, 571, 5, 
-	235, 118, 2, 571, 572, 5, 247, 124, 2, 572, 573, 5, 237, 119, 2, 573, 102, 
-	3, 2, 2, 2, 574, 575, 5, 221, 111, 2, 575, 576, 5, 207, 104, 2, 576, 577, 
-	5, 249, 125, 2, 577, 578, 5, 223, 112, 2, 578, 579, 5, 233, 117, 2, 579, 
-	580, 5, 219, 110, 2, 580, 104, 3, 2, 2, 2, 581, 582, 5, 209, 105, 2, 582, 
-	583, 5, 255, 128, 2, 583, 106, 3, 2, 2, 2, 584, 585, 5, 217, 109, 2, 585, 
-	586, 5, 235, 118, 2, 586, 587, 5, 241, 121, 2, 587, 108, 3, 2, 2, 2, 588, 
-	589, 5, 243, 122, 2, 589, 590, 5, 245, 123, 2, 590, 591, 5, 207, 104, 2, 
-	591, 592, 5, 245, 123, 2, 592, 593, 5, 243, 122, 2, 593, 110, 3, 2, 2, 
-	2, 594, 595, 5, 245, 123, 2, 595, 596, 5, 223, 112, 2, 596, 597, 5, 231, 
-	116, 2, 597, 598, 5, 215, 108, 2, 598, 112, 3, 2, 2, 2, 599, 600, 5, 233, 
-	117, 2, 600, 601, 5, 235, 118, 2, 601, 602, 5, 251, 126, 2, 602, 114, 3, 
-	2, 2, 2, 603, 604, 5, 223, 112, 2, 604, 605, 5, 233, 117, 2, 605, 116, 
-	3, 2, 2, 2, 606, 607, 5, 229, 115, 2, 607, 608, 5, 235, 118, 2, 608, 609, 
-	5, 219, 110, 2, 609, 118, 3, 2, 2, 2, 610, 611, 5, 237, 119, 2, 611, 612, 
-	5, 241, 121, 2, 612, 613, 5, 235, 118, 2, 613, 614, 5, 217, 109, 2, 614, 
-	615, 5, 223, 112, 2, 615, 616, 5, 229, 115, 2, 616, 617, 5, 215, 108, 2, 
-	617, 120, 3, 2, 2, 2, 618, 619, 5, 243, 122, 2, 619, 620, 5, 247, 124, 
-	2, 620, 621, 5, 231, 116, 2, 621, 122, 3, 2, 2, 2, 622, 623, 5, 231, 116, 
-	2, 623, 624, 5, 223, 112, 2, 624, 625, 5, 233, 117, 2, 625, 124, 3, 2, 
-	2, 2, 626, 627, 5, 231, 116, 2, 627, 628, 5, 207, 104, 2, 628, 629, 5, 
-	253, 127, 2, 629, 126, 3, 2, 2, 2, 630, 631, 5, 207, 104, 2, 631, 632, 
-	5, 249, 125, 2, 632, 633, 5, 219, 110, 2, 633, 128, 3, 2, 2, 2, 634, 635, 
-	5, 243, 122, 2, 635, 636, 5, 245, 123, 2, 636, 637, 5, 213, 107, 2, 637, 
-	638, 5, 213, 107, 2, 638, 639, 5, 215, 108, 2, 639, 640, 5, 249, 125, 2, 
-	640, 130, 3, 2, 2, 2, 641, 642, 5, 221, 111, 2, 642, 643, 5, 223, 112, 
-	2, 643, 644, 5, 243, 122, 2, 644, 645, 5, 245, 123, 2, 645, 646, 5, 235, 
-	118, 2, 646, 647, 5, 219, 110, 2, 647, 648, 5, 241, 121, 2, 648, 649, 5, 
-	207, 104, 2, 649, 650, 5, 231, 116, 2, 650, 132, 3, 2, 2, 2, 651, 652, 
-	5, 243, 122, 2, 652, 134, 3, 2, 2, 2, 653, 654, 7, 111, 2, 2, 654, 136, 
-	3, 2, 2, 2, 655, 656, 5, 221, 111, 2, 656, 138, 3, 2, 2, 2, 657, 658, 5, 
-	213, 107, 2, 658, 140, 3, 2, 2, 2, 659, 660, 5, 251, 126, 2, 660, 142, 
-	3, 2, 2, 2, 661, 662, 7, 79, 2, 2, 662, 144, 3, 2, 2, 2, 663, 664, 5, 255, 
-	128, 2, 664, 146, 3, 2, 2, 2, 665, 666, 7, 48, 2, 2, 666, 148, 3, 2, 2, 
-	2, 667, 668, 7, 60, 2, 2, 668, 150, 3, 2, 2, 2, 669, 670, 7, 63, 2, 2, 
-	670, 152, 3, 2, 2, 2, 671, 672, 7, 62, 2, 2, 672, 673, 7, 64, 2, 2, 673, 
-	154, 3, 2, 2, 2, 674, 675, 7, 35, 2, 2, 675, 676, 7, 63, 2, 2, 676, 156, 
-	3, 2, 2, 2, 677, 678, 7, 64, 2, 2, 678, 158, 3, 2, 2, 2, 679, 680, 7, 64, 
-	2, 2, 680, 681, 7, 63, 2, 2, 681, 160, 3, 2, 2, 2, 682, 683, 7, 62, 2, 
-	2, 683, 162, 3, 2, 2, 2, 684, 685, 7, 62, 2, 2, 685, 686, 7, 63, 2, 2, 
-	686, 164, 3, 2, 2, 2, 687, 688, 7, 63, 2, 2, 688, 689, 7, 128, 2, 2, 689, 
-	166, 3, 2, 2, 2, 690, 691, 7, 35, 2, 2, 691, 692, 7, 128, 2, 2, 692, 168, 
-	3, 2, 2, 2, 693, 694, 7, 46, 2, 2, 694, 170, 3, 2, 2, 2, 695, 696, 7, 125, 
-	2, 2, 696, 172, 3, 2, 2, 2, 697, 698, 7, 127, 2, 2, 698, 174, 3, 2, 2, 
-	2, 699, 700, 7, 93, 2, 2, 700, 176, 3, 2, 2, 2, 701, 702, 7, 95, 2, 2, 
-	702, 178, 3, 2, 2, 2, 703, 704, 7, 42, 2, 2, 704, 180, 3, 2, 2, 2, 705, 
-	706, 7, 43, 2, 2, 706, 182, 3, 2, 2, 2, 707, 708, 7, 45, 2, 2, 708, 184, 
-	3, 2, 2, 2, 709, 710, 7, 47, 2, 2, 710, 186, 3, 2, 2, 2, 711, 712, 7, 49, 
-	2, 2, 712, 188, 3, 2, 2, 2, 713, 714, 7, 44, 2, 2, 714, 190, 3, 2, 2, 2, 
-	715, 716, 7, 39, 2, 2, 716, 192, 3, 2, 2, 2, 717, 718, 5, 205, 103, 2, 
-	718, 194, 3, 2, 2, 2, 719, 721, 5, 203, 102, 2, 720, 719, 3, 2, 2, 2, 721, 
-	722, 3, 2, 2, 2, 722, 720, 3, 2, 2, 2, 722, 723, 3, 2, 2, 2, 723, 196, 
-	3, 2, 2, 2, 724, 726, 5, 203, 102, 2, 725, 724, 3, 2, 2, 2, 726, 727, 3, 
-	2, 2, 2, 727, 725, 3, 2, 2, 2, 727, 728, 3, 2, 2, 2, 728, 729, 3, 2, 2, 
-	2, 729, 730, 7, 48, 2, 2, 730, 734, 10, 2, 2, 2, 731, 733, 5, 203, 102, 
-	2, 732, 731, 3, 2, 2, 2, 733, 736, 3, 2, 2, 2, 734, 732, 3, 2, 2, 2, 734, 
-	735, 3, 2, 2, 2, 735, 744, 3, 2, 2, 2, 736, 734, 3, 2, 2, 2, 737, 739, 
-	7, 48, 2, 2, 738, 740, 5, 203, 102, 2, 739, 738, 3, 2, 2, 2, 740, 741, 
-	3, 2, 2, 2, 741, 739, 3, 2, 2, 2, 741, 742, 3, 2, 2, 2, 742, 744, 3, 2, 
-	2, 2, 743, 725, 3, 2, 2, 2, 743, 737, 3, 2, 2, 2, 744, 198, 3, 2, 2, 2, 
-	745, 747, 5, 201, 101, 2, 746, 745, 3, 2, 2, 2, 747, 748, 3, 2, 2, 2, 748, 
-	746, 3, 2, 2, 2, 748, 749, 3, 2, 2, 2, 749, 750, 3, 2, 2, 2, 750, 751, 
-	8, 100, 2, 2, 751, 200, 3, 2, 2, 2, 752, 753, 9, 3, 2, 2, 753, 202, 3, 
-	2, 2, 2, 754, 755, 9, 4, 2, 2, 755, 204, 3, 2, 2, 2, 756, 762, 9, 5, 2, 
-	2, 757, 761, 9, 5, 2, 2, 758, 761, 5, 203, 102, 2, 759, 761, 9, 6, 2, 2, 
-	760, 757, 3, 2, 2, 2, 760, 758, 3, 2, 2, 2, 760, 759, 3, 2, 2, 2, 761, 
-	764, 3, 2, 2, 2, 762, 760, 3, 2, 2, 2, 762, 763, 3, 2, 2, 2, 763, 807, 
-	3, 2, 2, 2, 764, 762, 3, 2, 2, 2, 765, 766, 7, 38, 2, 2, 766, 770, 7, 125, 
-	2, 2, 767, 769, 11, 2, 2, 2, 768, 767, 3, 2, 2, 2, 769, 772, 3, 2, 2, 2, 
-	770, 771, 3, 2, 2, 2, 770, 768, 3, 2, 2, 2, 771, 773, 3, 2, 2, 2, 772, 
-	770, 3, 2, 2, 2, 773, 807, 7, 127, 2, 2, 774, 778, 9, 7, 2, 2, 775, 779, 
-	9, 5, 2, 2, 776, 779, 5, 203, 102, 2, 777, 779, 9, 7, 2, 2, 778, 775, 3, 
-	2, 2, 2, 778, 776, 3, 2, 2, 2, 778, 777, 3, 2, 2, 2, 779, 780, 3, 2, 2, 
-	2, 780, 778, 3, 2, 2, 2, 780, 781, 3, 2, 2, 2, 781, 807, 3, 2, 2, 2, 782, 
-	786, 7, 36, 2, 2, 783, 785, 11, 2, 2, 2, 784, 783, 3, 2, 2, 2, 785, 788, 
-	3, 2, 2, 2, 786, 787, 3, 2, 2, 2, 786, 784, 3, 2, 2, 2, 787, 789, 3, 2, 
-	2, 2, 788, 786, 3, 2, 2, 2, 789, 807, 7, 36, 2, 2, 790, 794, 7, 98, 2, 
-	2, 791, 793, 11, 2, 2, 2, 792, 791, 3, 2, 2, 2, 793, 796, 3, 2, 2, 2, 794, 
-	795, 3, 2, 2, 2, 794, 792, 3, 2, 2, 2, 795, 797, 3, 2, 2, 2, 796, 794, 
-	3, 2, 2, 2, 797, 807, 7, 98, 2, 2, 798, 802, 7, 41, 2, 2, 799, 801, 11, 
-	2, 2, 2, 800, 799, 3, 2, 2, 2, 801, 804, 3, 2, 2, 2, 802, 803, 3, 2, 2, 
-	2, 802, 800, 3, 2, 2, 2, 803, 805, 3, 2, 2, 2, 804, 802, 3, 2, 2, 2, 805, 
-	807, 7, 41, 2, 2, 806, 756, 3, 2, 2, 2, 806, 765, 3, 2, 2, 2, 806, 774, 
-	3, 2, 2, 2, 806, 782, 3, 2, 2, 2, 806, 790, 3, 2, 2, 2, 806, 798, 3, 2, 
-	2, 2, 807, 206, 3, 2, 2, 2, 808, 809, 9, 8, 2, 2, 809, 208, 3, 2, 2, 2, 
-	810, 811, 9, 9, 2, 2, 811, 210, 3, 2, 2, 2, 812, 813, 9, 10, 2, 2, 813, 
-	212, 3, 2, 2, 2, 814, 815, 9, 11, 2, 2, 815, 214, 3, 2, 2, 2, 816, 817, 
-	9, 12, 2, 2, 817, 216, 3, 2, 2, 2, 818, 819, 9, 13, 2, 2, 819, 218, 3, 
-	2, 2, 2, 820, 821, 9, 14, 2, 2, 821, 220, 3, 2, 2, 2, 822, 823, 9, 15, 
-	2, 2, 823, 222, 3, 2, 2, 2, 824, 825, 9, 16, 2, 2, 825, 224, 3, 2, 2, 2, 
-	826, 827, 9, 17, 2, 2, 827, 226, 3, 2, 2, 2, 828, 829, 9, 18, 2, 2, 829, 
-	228, 3, 2, 2, 2, 830, 831, 9, 19, 2, 2, 831, 230, 3, 2, 2, 2, 832, 833, 
-	9, 20, 2, 2, 833, 232, 3, 2, 2, 2, 834, 835, 9, 21, 2, 2, 835, 234, 3, 
-	2, 2, 2, 836, 837, 9, 22, 2, 2, 837, 236, 3, 2, 2, 2, 838, 839, 9, 23, 
-	2, 2, 839, 238, 3, 2, 2, 2, 840, 841, 9, 24, 2, 2, 841, 240, 3, 2, 2, 2, 
-	842, 843, 9, 25, 2, 2, 843, 242, 3, 2, 2, 2, 844, 845, 9, 26, 2, 2, 845, 
-	244, 3, 2, 2, 2, 846, 847, 9, 27, 2, 2, 847, 246, 3, 2, 2, 2, 848, 849, 
-	9, 28, 2, 2, 849, 248, 3, 2, 2, 2, 850, 851, 9, 29, 2, 2, 851, 250, 3, 
-	2, 2, 2, 852, 853, 9, 30, 2, 2, 853, 252, 3, 2, 2, 2, 854, 855, 9, 31, 
-	2, 2, 855, 254, 3, 2, 2, 2, 856, 857, 9, 32, 2, 2, 857, 256, 3, 2, 2, 2, 
-	858, 859, 9, 33, 2, 2, 859, 258, 3, 2, 2, 2, 18, 2, 722, 727, 734, 741, 
-	743, 748, 760, 762, 770, 778, 780, 786, 794, 802, 806, 3, 8, 2, 2,
+	860, 3, 2, 2, 2, 2, 862, 3, 2, 2, 2, 2, 133, 3, 2, 2, 2, 2, 135, 3, 2, 
+	2, 2, 2, 137, 3, 2, 2, 2, 2, 139, 3, 2, 2, 2, 2, 141, 3, 2, 2, 2, 2, 143, 
+	3, 2, 2, 2, 2, 145, 3, 2, 2, 2, 2, 147, 3, 2, 2, 2, 2, 149, 3, 2, 2, 2, 
+	2, 151, 3, 2, 2, 2, 2, 153, 3, 2, 2, 2, 2, 155, 3, 2, 2, 2, 2, 157, 3, 
+	2, 2, 2, 2, 159, 3, 2, 2, 2, 2, 161, 3, 2, 2, 2, 2, 163, 3, 2, 2, 2, 2, 
+	165, 3, 2, 2, 2, 2, 167, 3, 2, 2, 2, 2, 169, 3, 2, 2, 2, 2, 171, 3, 2, 
+	2, 2, 2, 173, 3, 2, 2, 2, 2, 175, 3, 2, 2, 2, 2, 177, 3, 2, 2, 2, 2, 179, 
+	3, 2, 2, 2, 2, 181, 3, 2, 2, 2, 2, 183, 3, 2, 2, 2, 2, 185, 3, 2, 2, 2, 
+	2, 187, 3, 2, 2, 2, 2, 189, 3, 2, 2, 2, 2, 191, 3, 2, 2, 2, 2, 193, 3, 
+	2, 2, 2, 2, 195, 3, 2, 2, 2, 2, 197, 3, 2, 2, 2, 2, 199, 3, 2, 2, 2, 3, 
+	259, 3, 2, 2, 2, 5, 266, 3, 2, 2, 2, 7, 273, 3, 2, 2, 2, 9, 277, 3, 2, 
+	2, 2, 11, 282, 3, 2, 2, 2, 13, 291, 3, 2, 2, 2, 15, 296, 3, 2, 2, 2, 17, 
+	302, 3, 2, 2, 2, 19, 314, 3, 2, 2, 2, 21, 318, 3, 2, 2, 2, 23, 326, 3, 
+	2, 2, 2, 25, 334, 3, 2, 2, 2, 27, 344, 3, 2, 2, 2, 29, 349, 3, 2, 2, 2, 
+	31, 352, 3, 2, 2, 2, 33, 357, 3, 2, 2, 2, 35, 366, 3, 2, 2, 2, 37, 376, 
+	3, 2, 2, 2, 39, 381, 3, 2, 2, 2, 41, 394, 3, 2, 2, 2, 43, 406, 3, 2, 2, 
+	2, 45, 412, 3, 2, 2, 2, 47, 416, 3, 2, 2, 2, 49, 421, 3, 2, 2, 2, 51, 426, 
+	3, 2, 2, 2, 53, 430, 3, 2, 2, 2, 55, 435, 3, 2, 2, 2, 57, 442, 3, 2, 2, 
+	2, 59, 448, 3, 2, 2, 2, 61, 453, 3, 2, 2, 2, 63, 459, 3, 2, 2, 2, 65, 465, 
+	3, 2, 2, 2, 67, 473, 3, 2, 2, 2, 69, 479, 3, 2, 2, 2, 71, 487, 3, 2, 2, 
+	2, 73, 497, 3, 2, 2, 2, 75, 504, 3, 2, 2, 2, 77, 507, 3, 2, 2, 2, 79, 511, 
+	3, 2, 2, 2, 81, 514, 3, 2, 2, 2, 83, 519, 3, 2, 2, 2, 85, 524, 3, 2, 2, 
+	2, 87, 533, 3, 2, 2, 2, 89, 539, 3, 2, 2, 2, 91, 543, 3, 2, 2, 2, 93, 548, 
+	3, 2, 2, 2, 95, 553, 3, 2, 2, 2, 97, 557, 3, 2, 2, 2, 99, 565, 3, 2, 2, 
+	2, 101, 568, 3, 2, 2, 2, 103, 574, 3, 2, 2, 2, 105, 581, 3, 2, 2, 2, 107, 
+	584, 3, 2, 2, 2, 109, 588, 3, 2, 2, 2, 111, 594, 3, 2, 2, 2, 113, 599, 
+	3, 2, 2, 2, 115, 603, 3, 2, 2, 2, 117, 606, 3, 2, 2, 2, 119, 610, 3, 2, 
+	2, 2, 121, 618, 3, 2, 2, 2, 123, 622, 3, 2, 2, 2, 125, 626, 3, 2, 2, 2, 
+	127, 630, 3, 2, 2, 2, 129, 634, 3, 2, 2, 2, 131, 641, 3, 2, 2, 2, 133, 
+	651, 3, 2, 2, 2, 135, 653, 3, 2, 2, 2, 137, 655, 3, 2, 2, 2, 139, 657, 
+	3, 2, 2, 2, 141, 659, 3, 2, 2, 2, 143, 661, 3, 2, 2, 2, 145, 663, 3, 2, 
+	2, 2, 147, 665, 3, 2, 2, 2, 149, 667, 3, 2, 2, 2, 151, 669, 3, 2, 2, 2, 
+	153, 671, 3, 2, 2, 2, 155, 674, 3, 2, 2, 2, 157, 677, 3, 2, 2, 2, 159, 
+	679, 3, 2, 2, 2, 161, 682, 3, 2, 2, 2, 163, 684, 3, 2, 2, 2, 165, 687, 
+	3, 2, 2, 2, 167, 690, 3, 2, 2, 2, 169, 693, 3, 2, 2, 2, 171, 695, 3, 2, 
+	2, 2, 173, 697, 3, 2, 2, 2, 175, 699, 3, 2, 2, 2, 177, 701, 3, 2, 2, 2, 
+	179, 703, 3, 2, 2, 2, 181, 705, 3, 2, 2, 2, 183, 707, 3, 2, 2, 2, 185, 
+	709, 3, 2, 2, 2, 187, 711, 3, 2, 2, 2, 189, 713, 3, 2, 2, 2, 191, 715, 
+	3, 2, 2, 2, 193, 717, 3, 2, 2, 2, 195, 720, 3, 2, 2, 2, 197, 743, 3, 2, 
+	2, 2, 199, 746, 3, 2, 2, 2, 201, 752, 3, 2, 2, 2, 203, 754, 3, 2, 2, 2, 
+	205, 806, 3, 2, 2, 2, 207, 808, 3, 2, 2, 2, 209, 810, 3, 2, 2, 2, 211, 
+	812, 3, 2, 2, 2, 213, 814, 3, 2, 2, 2, 215, 816, 3, 2, 2, 2, 217, 818, 
+	3, 2, 2, 2, 219, 820, 3, 2, 2, 2, 221, 822, 3, 2, 2, 2, 223, 824, 3, 2, 
+	2, 2, 225, 826, 3, 2, 2, 2, 227, 828, 3, 2, 2, 2, 229, 830, 3, 2, 2, 2, 
+	231, 832, 3, 2, 2, 2, 233, 834, 3, 2, 2, 2, 235, 836, 3, 2, 2, 2, 237, 
+	838, 3, 2, 2, 2, 239, 840, 3, 2, 2, 2, 241, 842, 3, 2, 2, 2, 243, 844, 
+	3, 2, 2, 2, 245, 846, 3, 2, 2, 2, 247, 848, 3, 2, 2, 2, 249, 850, 3, 2, 
+	2, 2, 251, 852, 3, 2, 2, 2, 253, 854, 3, 2, 2, 2, 255, 856, 3, 2, 2, 2, 
+	257, 858, 3, 2, 2, 2, 259, 260, 5, 211, 108, 2, 260, 261, 5, 241, 123, 
+	2, 261, 262, 5, 215, 110, 2, 262, 263, 5, 207, 106, 2, 263, 264, 5, 245, 
+	125, 2, 264, 265, 5, 215, 110, 2, 265, 4, 3, 2, 2, 2, 266, 267, 5, 247, 
+	126, 2, 267, 268, 5, 237, 121, 2, 268, 269, 5, 213, 109, 2, 269, 270, 5, 
+	207, 106, 2, 270, 271, 5, 245, 125, 2, 271, 272, 5, 215, 110, 2, 272, 6, 
+	3, 2, 2, 2, 273, 274, 5, 243, 124, 2, 274, 275, 5, 215, 110, 2, 275, 276, 
+	5, 245, 125, 2, 276, 8, 3, 2, 2, 2, 277, 278, 5, 213, 109, 2, 278, 279, 
+	5, 241, 123, 2, 279, 280, 5, 235, 120, 2, 280, 281, 5, 237, 121, 2, 281, 
+	10, 3, 2, 2, 2, 282, 283, 5, 223, 114, 2, 283, 284, 5, 233, 119, 2, 284, 
+	285, 5, 245, 125, 2, 285, 286, 5, 215, 110, 2, 286, 287, 5, 241, 123, 2, 
+	287, 288, 5, 249, 127, 2, 288, 289, 5, 207, 106, 2, 289, 290, 5, 229, 117, 
+	2, 290, 12, 3, 2, 2, 2, 291, 292, 5, 233, 119, 2, 292, 293, 5, 207, 106, 
+	2, 293, 294, 5, 231, 118, 2, 294, 295, 5, 215, 110, 2, 295, 14, 3, 2, 2, 
+	2, 296, 297, 5, 243, 124, 2, 297, 298, 5, 221, 113, 2, 298, 299, 5, 207, 
+	106, 2, 299, 300, 5, 241, 123, 2, 300, 301, 5, 213, 109, 2, 301, 16, 3, 
+	2, 2, 2, 302, 303, 5, 241, 123, 2, 303, 304, 5, 215, 110, 2, 304, 305, 
+	5, 237, 121, 2, 305, 306, 5, 229, 117, 2, 306, 307, 5, 223, 114, 2, 307, 
+	308, 5, 211, 108, 2, 308, 309, 5, 207, 106, 2, 309, 310, 5, 245, 125, 2, 
+	310, 311, 5, 223, 114, 2, 311, 312, 5, 235, 120, 2, 312, 313, 5, 233, 119, 
+	2, 313, 18, 3, 2, 2, 2, 314, 315, 5, 245, 125, 2, 315, 316, 5, 245, 125, 
+	2, 316, 317, 5, 229, 117, 2, 317, 20, 3, 2, 2, 2, 318, 319, 5, 231, 118, 
+	2, 319, 320, 5, 215, 110, 2, 320, 321, 5, 245, 125, 2, 321, 322, 5, 207, 
+	106, 2, 322, 323, 5, 245, 125, 2, 323, 324, 5, 245, 125, 2, 324, 325, 5, 
+	229, 117, 2, 325, 22, 3, 2, 2, 2, 326, 327, 5, 237, 121, 2, 327, 328, 5, 
+	207, 106, 2, 328, 329, 5, 243, 124, 2, 329, 330, 5, 245, 125, 2, 330, 331, 
+	5, 245, 125, 2, 331, 332, 5, 245, 125, 2, 332, 333, 5, 229, 117, 2, 333, 
+	24, 3, 2, 2, 2, 334, 335, 5, 217, 111, 2, 335, 336, 5, 247, 126, 2, 336, 
+	337, 5, 245, 125, 2, 337, 338, 5, 247, 126, 2, 338, 339, 5, 241, 123, 2, 
+	339, 340, 5, 215, 110, 2, 340, 341, 5, 245, 125, 2, 341, 342, 5, 245, 125, 
+	2, 342, 343, 5, 229, 117, 2, 343, 26, 3, 2, 2, 2, 344, 345, 5, 227, 116, 
+	2, 345, 346, 5, 223, 114, 2, 346, 347, 5, 229, 117, 2, 347, 348, 5, 229, 
+	117, 2, 348, 28, 3, 2, 2, 2, 349, 350, 5, 235, 120, 2, 350, 351, 5, 233, 
+	119, 2, 351, 30, 3, 2, 2, 2, 352, 353, 5, 243, 124, 2, 353, 354, 5, 221, 
+	113, 2, 354, 355, 5, 235, 120, 2, 355, 356, 5, 251, 128, 2, 356, 32, 3, 
+	2, 2, 2, 357, 358, 5, 213, 109, 2, 358, 359, 5, 207, 106, 2, 359, 360, 
+	5, 245, 125, 2, 360, 361, 5, 207, 106, 2, 361, 362, 5, 209, 107, 2, 362, 
+	363, 5, 207, 106, 2, 363, 364, 5, 243, 124, 2, 364, 365, 5, 215, 110, 2, 
+	365, 34, 3, 2, 2, 2, 366, 367, 5, 213, 109, 2, 367, 368, 5, 207, 106, 2, 
+	368, 369, 5, 245, 125, 2, 369, 370, 5, 207, 106, 2, 370, 371, 5, 209, 107, 
+	2, 371, 372, 5, 207, 106, 2, 372, 373, 5, 243, 124, 2, 373, 374, 5, 215, 
+	110, 2, 374, 375, 5, 243, 124, 2, 375, 36, 3, 2, 2, 2, 376, 377, 5, 233, 
+	119, 2, 377, 378, 5, 235, 120, 2, 378, 379, 5, 213, 109, 2, 379, 380, 5, 
+	215, 110, 2, 380, 38, 3, 2, 2, 2, 381, 382, 5, 231, 118, 2, 382, 383, 5, 
+	215, 110, 2, 383, 384, 5, 207, 106, 2, 384, 385, 5, 243, 124, 2, 385, 386, 
+	5, 247, 126, 2, 386, 387, 5, 241, 123, 2, 387, 388, 5, 215, 110, 2, 388, 
+	389, 5, 231, 118, 2, 389, 390, 5, 215, 110, 2, 390, 391, 5, 233, 119, 2, 
+	391, 392, 5, 245, 125, 2, 392, 393, 5, 243, 124, 2, 393, 40, 3, 2, 2, 2, 
+	394, 395, 5, 231, 118, 2, 395, 396, 5, 215, 110, 2, 396, 397, 5, 207, 106, 
+	2, 397, 398, 5, 243, 124, 2, 398, 399, 5, 247, 126, 2, 399, 400, 5, 241, 
+	123, 2, 400, 401, 5, 215, 110, 2, 401, 402, 5, 231, 118, 2, 402, 403, 5, 
+	215, 110, 2, 403, 404, 5, 233, 119, 2, 404, 405, 5, 245, 125, 2, 405, 42, 
+	3, 2, 2, 2, 406, 407, 5, 217, 111, 2, 407, 408, 5, 223, 114, 2, 408, 409, 
+	5, 215, 110, 2, 409, 410, 5, 229, 117, 2, 410, 411, 5, 213, 109, 2, 411, 
+	44, 3, 2, 2, 2, 412, 413, 5, 245, 125, 2, 413, 414, 5, 207, 106, 2, 414, 
+	415, 5, 219, 112, 2, 415, 46, 3, 2, 2, 2, 416, 417, 5, 223, 114, 2, 417, 
+	418, 5, 233, 119, 2, 418, 419, 5, 217, 111, 2, 419, 420, 5, 235, 120, 2, 
+	420, 48, 3, 2, 2, 2, 421, 422, 5, 227, 116, 2, 422, 423, 5, 215, 110, 2, 
+	423, 424, 5, 255, 130, 2, 424, 425, 5, 243, 124, 2, 425, 50, 3, 2, 2, 2, 
+	426, 427, 5, 227, 116, 2, 427, 428, 5, 215, 110, 2, 428, 429, 5, 255, 130, 
+	2, 429, 52, 3, 2, 2, 2, 430, 431, 5, 251, 128, 2, 431, 432, 5, 223, 114, 
+	2, 432, 433, 5, 245, 125, 2, 433, 434, 5, 221, 113, 2, 434, 54, 3, 2, 2, 
+	2, 435, 436, 5, 249, 127, 2, 436, 437, 5, 207, 106, 2, 437, 438, 5, 229, 
+	117, 2, 438, 439, 5, 247, 126, 2, 439, 440, 5, 215, 110, 2, 440, 441, 5, 
+	243, 124, 2, 441, 56, 3, 2, 2, 2, 442, 443, 5, 249, 127, 2, 443, 444, 5, 
+	207, 106, 2, 444, 445, 5, 229, 117, 2, 445, 446, 5, 247, 126, 2, 446, 447, 
+	5, 215, 110, 2, 447, 58, 3, 2, 2, 2, 448, 449, 5, 217, 111, 2, 449, 450, 
+	5, 241, 123, 2, 450, 451, 5, 235, 120, 2, 451, 452, 5, 231, 118, 2, 452, 
+	60, 3, 2, 2, 2, 453, 454, 5, 251, 128, 2, 454, 455, 5, 221, 113, 2, 455, 
+	456, 5, 215, 110, 2, 456, 457, 5, 241, 123, 2, 457, 458, 5, 215, 110, 2, 
+	458, 62, 3, 2, 2, 2, 459, 460, 5, 229, 117, 2, 460, 461, 5, 223, 114, 2, 
+	461, 462, 5, 231, 118, 2, 462, 463, 5, 223, 114, 2, 463, 464, 5, 245, 125, 
+	2, 464, 64, 3, 2, 2, 2, 465, 466, 5, 239, 122, 2, 466, 467, 5, 247, 126, 
+	2, 467, 468, 5, 215, 110, 2, 468, 469, 5, 241, 123, 2, 469, 470, 5, 223, 
+	114, 2, 470, 471, 5, 215, 110, 2, 471, 472, 5, 243, 124, 2, 472, 66, 3, 
+	2, 2, 2, 473, 474, 5, 239, 122, 2, 474, 475, 5, 247, 126, 2, 475, 476, 
+	5, 215, 110, 2, 476, 477, 5, 241, 123, 2, 477, 478, 5, 255, 130, 2, 478, 
+	68, 3, 2, 2, 2, 479, 480, 5, 215, 110, 2, 480, 481, 5, 253, 129, 2, 481, 
+	482, 5, 237, 121, 2, 482, 483, 5, 229, 117, 2, 483, 484, 5, 207, 106, 2, 
+	484, 485, 5, 223, 114, 2, 485, 486, 5, 233, 119, 2, 486, 70, 3, 2, 2, 2, 
+	487, 488, 5, 251, 128, 2, 488, 489, 5, 223, 114, 2, 489, 490, 5, 245, 125, 
+	2, 490, 491, 5, 221, 113, 2, 491, 492, 5, 249, 127, 2, 492, 493, 5, 207, 
+	106, 2, 493, 494, 5, 229, 117, 2, 494, 495, 5, 247, 126, 2, 495, 496, 5, 
+	215, 110, 2, 496, 72, 3, 2, 2, 2, 497, 498, 5, 243, 124, 2, 498, 499, 5, 
+	215, 110, 2, 499, 500, 5, 229, 117, 2, 500, 501, 5, 215, 110, 2, 501, 502, 
+	5, 211, 108, 2, 502, 503, 5, 245, 125, 2, 503, 74, 3, 2, 2, 2, 504, 505, 
+	5, 207, 106, 2, 505, 506, 5, 243, 124, 2, 506, 76, 3, 2, 2, 2, 507, 508, 
+	5, 207, 106, 2, 508, 509, 5, 233, 119, 2, 509, 510, 5, 213, 109, 2, 510, 
+	78, 3, 2, 2, 2, 511, 512, 5, 235, 120, 2, 512, 513, 5, 241, 123, 2, 513, 
+	80, 3, 2, 2, 2, 514, 515, 5, 217, 111, 2, 515, 516, 5, 223, 114, 2, 516, 
+	517, 5, 229, 117, 2, 517, 518, 5, 229, 117, 2, 518, 82, 3, 2, 2, 2, 519, 
+	520, 5, 233, 119, 2, 520, 521, 5, 247, 126, 2, 521, 522, 5, 229, 117, 2, 
+	522, 523, 5, 229, 117, 2, 523, 84, 3, 2, 2, 2, 524, 525, 5, 237, 121, 2, 
+	525, 526, 5, 241, 123, 2, 526, 527, 5, 215, 110, 2, 527, 528, 5, 249, 127, 
+	2, 528, 529, 5, 223, 114, 2, 529, 530, 5, 235, 120, 2, 530, 531, 5, 247, 
+	126, 2, 531, 532, 5, 243, 124, 2, 532, 86, 3, 2, 2, 2, 533, 534, 5, 235, 
+	120, 2, 534, 535, 5, 241, 123, 2, 535, 536, 5, 213, 109, 2, 536, 537, 5, 
+	215, 110, 2, 537, 538, 5, 241, 123, 2, 538, 88, 3, 2, 2, 2, 539, 540, 5, 
+	207, 106, 2, 540, 541, 5, 243, 124, 2, 541, 542, 5, 211, 108, 2, 542, 90, 
+	3, 2, 2, 2, 543, 544, 5, 213, 109, 2, 544, 545, 5, 215, 110, 2, 545, 546, 
+	5, 243, 124, 2, 546, 547, 5, 211, 108, 2, 547, 92, 3, 2, 2, 2, 548, 549, 
+	5, 229, 117, 2, 549, 550, 5, 223, 114, 2, 550, 551, 5, 227, 116, 2, 551, 
+	552, 5, 215, 110, 2, 552, 94, 3, 2, 2, 2, 553, 554, 5, 233, 119, 2, 554, 
+	555, 5, 235, 120, 2, 555, 556, 5, 245, 125, 2, 556, 96, 3, 2, 2, 2, 557, 
+	558, 5, 209, 107, 2, 558, 559, 5, 215, 110, 2, 559, 560, 5, 245, 125, 2, 
+	560, 561, 5, 251, 128, 2, 561, 562, 5, 215, 110, 2, 562, 563, 5, 215, 110, 
+	2, 563, 564, 5, 233, 119, 2, 564, 98, 3, 2, 2, 2, 565, 566, 5, 223, 114, 
+	2, 566, 567, 5, 243, 124, 2, 567, 100, 3, 2, 2, 2, 568, 569, 5, 219, 112, 
+	2, 569, 570, 5, 241, 123, 2, 570, 571, 5, 235, 120, 2, 571, 572, 5, 247, 
+	126, 2, 572, 573, 5, 237, 121, 2, 573, 102, 3, 2, 2, 2, 574, 575, 5, 221, 
+	113, 2, 575, 576, 5, 207, 106, 2, 576, 577, 5, 249, 127, 2, 577, 578, 5, 
+	223, 114, 2, 578, 579, 5, 233, 119, 2, 579, 580, 5, 219, 112, 2, 580, 104, 
+	3, 2, 2, 2, 581, 582, 5, 209, 107, 2, 582, 583, 5, 255, 130, 2, 583, 106, 
+	3, 2, 2, 2, 584, 585, 5, 217, 111, 2, 585, 586, 5, 235, 120, 2, 586, 587, 
+	5, 241, 123, 2, 587, 108, 3, 2, 2, 2, 588, 589, 5, 243, 124, 2, 589, 590, 
+	5, 245, 125, 2, 590, 591, 5, 207, 106, 2, 591, 592, 5, 245, 125, 2, 592, 
+	593, 5, 243, 124, 2, 593, 110, 3, 2, 2, 2, 594, 595, 5, 245, 125, 2, 595, 
+	596, 5, 223, 114, 2, 596, 597, 5, 231, 118, 2, 597, 598, 5, 215, 110, 2, 
+	598, 112, 3, 2, 2, 2, 599, 600, 5, 233, 119, 2, 600, 601, 5, 235, 120, 
+	2, 601, 602, 5, 251, 128, 2, 602, 114, 3, 2, 2, 2, 603, 604, 5, 223, 114, 
+	2, 604, 605, 5, 233, 119, 2, 605, 116, 3, 2, 2, 2, 606, 607, 5, 229, 117, 
+	2, 607, 608, 5, 235, 120, 2, 608, 609, 5, 219, 112, 2, 609, 118, 3, 2, 
+	2, 2, 610, 611, 5, 237, 121, 2, 611, 612, 5, 241, 123, 2, 612, 613, 5, 
+	235, 120, 2, 613, 614, 5, 217, 111, 2, 614, 615, 5, 223, 114, 2, 615, 616, 
+	5, 229, 117, 2, 616, 617, 5, 215, 110, 2, 617, 120, 3, 2, 2, 2, 618, 619, 
+	5, 243, 124, 2, 619, 620, 5, 247, 126, 2, 620, 621, 5, 231, 118, 2, 621, 
+	122, 3, 2, 2, 2, 622, 623, 5, 231, 118, 2, 623, 624, 5, 223, 114, 2, 624, 
+	625, 5, 233, 119, 2, 625, 124, 3, 2, 2, 2, 626, 627, 5, 231, 118, 2, 627, 
+	628, 5, 207, 106, 2, 628, 629, 5, 253, 129, 2, 629, 126, 3, 2, 2, 2, 630, 
+	631, 5, 207, 106, 2, 631, 632, 5, 249, 127, 2, 632, 633, 5, 219, 112, 2, 
+	633, 128, 3, 2, 2, 2, 634, 635, 5, 243, 124, 2, 635, 636, 5, 245, 125, 
+	2, 636, 637, 5, 213, 109, 2, 637, 638, 5, 213, 109, 2, 638, 639, 5, 215, 
+	110, 2, 639, 640, 5, 249, 127, 2, 640, 130, 3, 2, 2, 2, 641, 642, 5, 221, 
+	113, 2, 642, 643, 5, 223, 114, 2, 643, 644, 5, 243, 124, 2, 644, 645, 5, 
+	245, 125, 2, 645, 646, 5, 235, 120, 2, 646, 647, 5, 219, 112, 2, 647, 648, 
+	5, 241, 123, 2, 648, 649, 5, 207, 106, 2, 649, 650, 5, 231, 118, 2, 650, 
+	132, 3, 2, 2, 2, 651, 652, 5, 243, 124, 2, 652, 134, 3, 2, 2, 2, 653, 654, 
+	7, 111, 2, 2, 654, 136, 3, 2, 2, 2, 655, 656, 5, 221, 113, 2, 656, 138, 
+	3, 2, 2, 2, 657, 658, 5, 213, 109, 2, 658, 140, 3, 2, 2, 2, 659, 660, 5, 
+	251, 128, 2, 660, 142, 3, 2, 2, 2, 661, 662, 7, 79, 2, 2, 662, 144, 3, 
+	2, 2, 2, 663, 664, 5, 255, 130, 2, 664, 146, 3, 2, 2, 2, 665, 666, 7, 48, 
+	2, 2, 666, 148, 3, 2, 2, 2, 667, 668, 7, 60, 2, 2, 668, 150, 3, 2, 2, 2, 
+	669, 670, 7, 63, 2, 2, 670, 152, 3, 2, 2, 2, 671, 672, 7, 62, 2, 2, 672, 
+	673, 7, 64, 2, 2, 673, 154, 3, 2, 2, 2, 674, 675, 7, 35, 2, 2, 675, 676, 
+	7, 63, 2, 2, 676, 156, 3, 2, 2, 2, 677, 678, 7, 64, 2, 2, 678, 158, 3, 
+	2, 2, 2, 679, 680, 7, 64, 2, 2, 680, 681, 7, 63, 2, 2, 681, 160, 3, 2, 
+	2, 2, 682, 683, 7, 62, 2, 2, 683, 162, 3, 2, 2, 2, 684, 685, 7, 62, 2, 
+	2, 685, 686, 7, 63, 2, 2, 686, 164, 3, 2, 2, 2, 687, 688, 7, 63, 2, 2, 
+	688, 689, 7, 128, 2, 2, 689, 166, 3, 2, 2, 2, 690, 691, 7, 35, 2, 2, 691, 
+	692, 7, 128, 2, 2, 692, 168, 3, 2, 2, 2, 693, 694, 7, 46, 2, 2, 694, 170, 
+	3, 2, 2, 2, 695, 696, 7, 125, 2, 2, 696, 172, 3, 2, 2, 2, 697, 698, 7, 
+	127, 2, 2, 698, 174, 3, 2, 2, 2, 699, 700, 7, 93, 2, 2, 700, 176, 3, 2, 
+	2, 2, 701, 702, 7, 95, 2, 2, 702, 178, 3, 2, 2, 2, 703, 704, 7, 42, 2, 
+	2, 704, 180, 3, 2, 2, 2, 705, 706, 7, 43, 2, 2, 706, 182, 3, 2, 2, 2, 707, 
+	708, 7, 45, 2, 2, 708, 184, 3, 2, 2, 2, 709, 710, 7, 47, 2, 2, 710, 186, 
+	3, 2, 2, 2, 711, 712, 7, 49, 2, 2, 712, 188, 3, 2, 2, 2, 713, 714, 7, 44, 
+	2, 2, 714, 190, 3, 2, 2, 2, 715, 716, 7, 39, 2, 2, 716, 192, 3, 2, 2, 2, 
+	717, 718, 5, 205, 105, 2, 718, 194, 3, 2, 2, 2, 719, 721, 5, 203, 104, 
+	2, 720, 719, 3, 2, 2, 2, 721, 722, 3, 2, 2, 2, 722, 720, 3, 2, 2, 2, 722, 
+	723, 3, 2, 2, 2, 723, 196, 3, 2, 2, 2, 724, 726, 5, 203, 104, 2, 725, 724, 
+	3, 2, 2, 2, 726, 727, 3, 2, 2, 2, 727, 725, 3, 2, 2, 2, 727, 728, 3, 2, 
+	2, 2, 728, 729, 3, 2, 2, 2, 729, 730, 7, 48, 2, 2, 730, 734, 10, 2, 2, 
+	2, 731, 733, 5, 203, 104, 2, 732, 731, 3, 2, 2, 2, 733, 736, 3, 2, 2, 2, 
+	734, 732, 3, 2, 2, 2, 734, 735, 3, 2, 2, 2, 735, 744, 3, 2, 2, 2, 736, 
+	734, 3, 2, 2, 2, 737, 739, 7, 48, 2, 2, 738, 740, 5, 203, 104, 2, 739, 
+	738, 3, 2, 2, 2, 740, 741, 3, 2, 2, 2, 741, 739, 3, 2, 2, 2, 741, 742, 
+	3, 2, 2, 2, 742, 744, 3, 2, 2, 2, 743, 725, 3, 2, 2, 2, 743, 737, 3, 2, 
+	2, 2, 744, 198, 3, 2, 2, 2, 745, 747, 5, 201, 103, 2, 746, 745, 3, 2, 2, 
+	2, 747, 748, 3, 2, 2, 2, 748, 746, 3, 2, 2, 2, 748, 749, 3, 2, 2, 2, 749, 
+	750, 3, 2, 2, 2, 750, 751, 8, 102, 2, 2, 751, 200, 3, 2, 2, 2, 752, 753, 
+	9, 3, 2, 2, 753, 202, 3, 2, 2, 2, 754, 755, 9, 4, 2, 2, 755, 204, 3, 2, 
+	2, 2, 756, 762, 9, 5, 2, 2, 757, 761, 9, 5, 2, 2, 758, 761, 5, 203, 104, 
+	2, 759, 761, 9, 6, 2, 2, 760, 757, 3, 2, 2, 2, 760, 758, 3, 2, 2, 2, 760, 
+	759, 3, 2, 2, 2, 761, 764, 3, 2, 2, 2, 762, 760, 3, 2, 2, 2, 762, 763, 
+	3, 2, 2, 2, 763, 807, 3, 2, 2, 2, 764, 762, 3, 2, 2, 2, 765, 766, 7, 38, 
+	2, 2, 766, 770, 7, 125, 2, 2, 767, 769, 11, 2, 2, 2, 768, 767, 3, 2, 2, 
+	2, 769, 772, 3, 2, 2, 2, 770, 771, 3, 2, 2, 2, 770, 768, 3, 2, 2, 2, 771, 
+	773, 3, 2, 2, 2, 772, 770, 3, 2, 2, 2, 773, 807, 7, 127, 2, 2, 774, 778, 
+	9, 7, 2, 2, 775, 779, 9, 5, 2, 2, 776, 779, 5, 203, 104, 2, 777, 779, 9, 
+	7, 2, 2, 778, 775, 3, 2, 2, 2, 778, 776, 3, 2, 2, 2, 778, 777, 3, 2, 2, 
+	2, 779, 780, 3, 2, 2, 2, 780, 778, 3, 2, 2, 2, 780, 781, 3, 2, 2, 2, 781, 
+	807, 3, 2, 2, 2, 782, 786, 7, 36, 2, 2, 783, 785, 11, 2, 2, 2, 784, 783, 
+	3, 2, 2, 2, 785, 788, 3, 2, 2, 2, 786, 787, 3, 2, 2, 2, 786, 784, 3, 2, 
+	2, 2, 787, 789, 3, 2, 2, 2, 788, 786, 3, 2, 2, 2, 789, 807, 7, 36, 2, 2, 
+	790, 794, 7, 98, 2, 2, 791, 793, 11, 2, 2, 2, 792, 791, 3, 2, 2, 2, 793, 
+	796, 3, 2, 2, 2, 794, 795, 3, 2, 2, 2, 794, 792, 3, 2, 2, 2, 795, 797, 
+	3, 2, 2, 2, 796, 794, 3, 2, 2, 2, 797, 807, 7, 98, 2, 2, 798, 802, 7, 41, 
+	2, 2, 799, 801, 11, 2, 2, 2, 800, 799, 3, 2, 2, 2, 801, 804, 3, 2, 2, 2, 
+	802, 803, 3, 2, 2, 2, 802, 800, 3, 2, 2, 2, 803, 805, 3, 2, 2, 2, 804, 
+	802, 3, 2, 2, 2, 805, 807, 7, 41, 2, 2, 806, 756, 3, 2, 2, 2, 806, 765, 
+	3, 2, 2, 2, 806, 774, 3, 2, 2, 2, 806, 782, 3, 2, 2, 2, 806, 790, 3, 2, 
+	2, 2, 806, 798, 3, 2, 2, 2, 807, 206, 3, 2, 2, 2, 808, 809, 9, 8, 2, 2, 
+	809, 208, 3, 2, 2, 2, 810, 811, 9, 9, 2, 2, 811, 210, 3, 2, 2, 2, 812, 
+	813, 9, 10, 2, 2, 813, 212, 3, 2, 2, 2, 814, 815, 9, 11, 2, 2, 815, 214, 
+	3, 2, 2, 2, 816, 817, 9, 12, 2, 2, 817, 216, 3, 2, 2, 2, 818, 819, 9, 13, 
+	2, 2, 819, 218, 3, 2, 2, 2, 820, 821, 9, 14, 2, 2, 821, 220, 3, 2, 2, 2, 
+	822, 823, 9, 15, 2, 2, 823, 222, 3, 2, 2, 2, 824, 825, 9, 16, 2, 2, 825, 
+	224, 3, 2, 2, 2, 826, 827, 9, 17, 2, 2, 827, 226, 3, 2, 2, 2, 828, 829, 
+	9, 18, 2, 2, 829, 228, 3, 2, 2, 2, 830, 831, 9, 19, 2, 2, 831, 230, 3, 
+	2, 2, 2, 832, 833, 9, 20, 2, 2, 833, 232, 3, 2, 2, 2, 834, 835, 9, 21, 
+	2, 2, 835, 234, 3, 2, 2, 2, 836, 837, 9, 22, 2, 2, 837, 236, 3, 2, 2, 2, 
+	838, 839, 9, 23, 2, 2, 839, 238, 3, 2, 2, 2, 840, 841, 9, 24, 2, 2, 841, 
+	240, 3, 2, 2, 2, 842, 843, 9, 25, 2, 2, 843, 242, 3, 2, 2, 2, 844, 845, 
+	9, 26, 2, 2, 845, 244, 3, 2, 2, 2, 846, 847, 9, 27, 2, 2, 847, 246, 3, 
+	2, 2, 2, 848, 849, 9, 28, 2, 2, 849, 248, 3, 2, 2, 2, 850, 851, 9, 29, 
+	2, 2, 851, 250, 3, 2, 2, 2, 852, 853, 9, 30, 2, 2, 853, 252, 3, 2, 2, 2, 
+	854, 855, 9, 31, 2, 2, 855, 254, 3, 2, 2, 2, 856, 857, 9, 32, 2, 2, 857, 
+	256, 3, 2, 2, 2, 858, 859, 9, 33, 2, 2, 859, 258, 3, 2, 2, 2, 860, 864, 
+	3, 2, 2, 2, 864, 865, 5, 231, 118, 2, 865, 866, 5, 235, 120, 2, 866, 867, 
+	5, 249, 127, 2, 867, 868, 5, 223, 114, 2, 868, 869, 5, 233, 119, 2, 869, 
+	870, 5, 219, 112, 2, 870, 871, 7, 97, 2, 2, 871, 872, 5, 207, 106, 2, 872, 
+	873, 5, 249, 127, 2, 873, 874, 5, 215, 110, 2, 874, 875, 5, 241, 123, 2, 
+	875, 876, 5, 207, 106, 2, 876, 877, 5, 219, 112, 2, 877, 878, 5, 215, 110, 
+	2, 878, 861, 3, 2, 2, 2, 862, 879, 3, 2, 2, 2, 879, 880, 5, 241, 123, 2, 
+	880, 881, 5, 207, 106, 2, 881, 882, 5, 245, 125, 2, 882, 883, 5, 215, 110, 
+	2, 883, 863, 3, 2, 2, 2, 18, 2, 722, 727, 734, 741, 743, 748, 760, 762, 
+	770, 778, 780, 786, 794, 802, 806, 3, 8, 2, 2,
 }
 
 var lexerDeserializer = antlr.NewATNDeserializer(nil)
@@ -416,10 +427,10 @@ var lexerLiteralNames = []string{
 	"", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", 
 	"", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", 
 	"", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", 
-	"", "", "", "", "", "", "", "", "", "", "", "", "", "'m'", "", "", "", 
-	"'M'", "", "'.'", "':'", "'='", "'<>'", "'!='", "'>'", "'>='", "'<'", "'<='", 
-	"'=~'", "'!~'", "','", "'{'", "'}'", "'['", "']'", "'('", "')'", "'+'", 
-	"'-'", "'/'", "'*'", "'%'",
+	"", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "'m'", "", 
+	"", "", "'M'", "", "'.'", "':'", "'='", "'<>'", "'!='", "'>'", "'>='", 
+	"'<'", "'<='", "'=~'", "'!~'", "','", "'{'", "'}'", "'['", "']'", "'('", 
+	"')'", "'+'", "'-'", "'/'", "'*'", "'%'",
 }
 
 var lexerSymbolicNames = []string{
@@ -432,12 +443,12 @@ var lexerSymbolicNames = []string{
 	"T_NULL", "T_PREVIOUS", "T_ORDER", "T_ASC", "T_DESC", "T_LIKE", "T_NOT", 
 	"T_BETWEEN", "T_IS", "T_GROUP", "T_HAVING", "T_BY", "T_FOR", "T_STATS", 
 	"T_TIME", "T_NOW", "T_IN", "T_LOG", "T_PROFILE", "T_SUM", "T_MIN", "T_MAX", 
-	"T_AVG", "T_STDDEV", "T_HISTOGRAM", "T_SECOND", "T_MINUTE", "T_HOUR", "T_DAY", 
-	"T_WEEK", "T_MONTH", "T_YEAR", "T_DOT", "T_COLON", "T_EQUAL", "T_NOTEQUAL", 
-	"T_NOTEQUAL2", "T_GREATER", "T_GREATEREQUAL", "T_LESS", "T_LESSEQUAL", 
-	"T_REGEXP", "T_NEQREGEXP", "T_COMMA", "T_OPEN_B", "T_CLOSE_B", "T_OPEN_SB", 
-	"T_CLOSE_SB", "T_OPEN_P", "T_CLOSE_P", "T_ADD", "T_SUB", "T_DIV", "T_MUL", 
-	"T_MOD", "L_ID", "L_INT", "L_DEC", "WS",
+	"T_AVG", "T_STDDEV", "T_HISTOGRAM", "T_MOVING_AVERAGE", "T_RATE", "T_SECOND", 
+	"T_MINUTE", "T_HOUR", "T_DAY", "T_WEEK", "T_MONTH", "T_YEAR", "T_DOT", 
+	"T_COLON", "T_EQUAL", "T_NOTEQUAL", "T_NOTEQUAL2", "T_GREATER", "T_GREATEREQUAL", 
+	"T_LESS", "T_LESSEQUAL", "T_REGEXP", "T_NEQREGEXP", "T_COMMA", "T_OPEN_B", 
+	"T_CLOSE_B", "T_OPEN_SB", "T_CLOSE_SB", "T_OPEN_P", "T_CLOSE_P", "T_ADD", 
+	"T_SUB", "T_DIV", "T_MUL", "T_MOD", "L_ID", "L_INT", "L_DEC", "WS",
 }
 
 var lexerRuleNames = []string{
@@ -450,14 +461,15 @@ var lexerRuleNames = []string{
 	"T_NULL", "T_PREVIOUS", "T_ORDER", "T_ASC", "T_DESC", "T_LIKE", "T_NOT", 
 	"T_BETWEEN", "T_IS", "T_GROUP", "T_HAVING", "T_BY", "T_FOR", "T_STATS", 
 	"T_TIME", "T_NOW", "T_IN", "T_LOG", "T_PROFILE", "T_SUM", "T_MIN", "T_MAX", 
-	"T_AVG", "T_STDDEV", "T_HISTOGRAM", "T_SECOND", "T_MINUTE", "T_HOUR", "T_DAY", 
-	"T_WEEK", "T_MONTH", "T_YEAR", "T_DOT", "T_COLON", "T_EQUAL", "T_NOTEQUAL", 
-	"T_NOTEQUAL2", "T_GREATER", "T_GREATEREQUAL", "T_LESS", "T_LESSEQUAL", 
-	"T_REGEXP", "T_NEQREGEXP", "T_COMMA", "T_OPEN_B", "T_CLOSE_B", "T_OPEN_SB", 
-	"T_CLOSE_SB", "T_OPEN_P", "T_CLOSE_P", "T_ADD", "T_SUB", "T_DIV", "T_MUL", 
-	"T_MOD", "L_ID", "L_INT", "L_DEC", "WS", "BLANK", "L_DIGIT", "L_ID_PART", 
-	"A", "B", "C", "D", "E", "F", "G", "H", "I", "J", "K", "L", "M", "N", "O", 
-	"P", "Q", "R", "S", "T", "U", "V", "W", "X", "Y", "Z",
+	"T_AVG", "T_STDDEV", "T_HISTOGRAM", "T_MOVING_AVERAGE", "T_RATE", "T_SECOND", 
+	"T_MINUTE", "T_HOUR", "T_DAY", "T_WEEK", "T_MONTH", "T_YEAR", "T_DOT", 
+	"T_COLON", "T_EQUAL", "T_NOTEQUAL", "T_NOTEQUAL2", "T_GREATER", "T_GREATEREQUAL", 
+	"T_LESS", "T_LESSEQUAL", "T_REGEXP", "T_NEQREGEXP", "T_COMMA", "T_OPEN_B", 
+	"T_CLOSE_B", "T_OPEN_SB", "T_CLOSE_SB", "T_OPEN_P", "T_CLOSE_P", "T_ADD", 
+	"T_SUB", "T_DIV", "T_MUL", "T_MOD", "L_ID", "L_INT", "L_DEC", "WS", "BLANK", 
+	"L_DIGIT", "L_ID_PART", "A", "B", "C", "D", "E", "F", "G", "H", "I", "J", 
+	"K", "L", "M", "N", "O", "P", "Q", "R", "S", "T", "U", "V", "W", "X", "Y", 
+	"Z",
 }
 
 type SQLLexer struct {
@@ -560,39 +572,41 @@ const (
 	SQLLexerT_AVG = 63
 	SQLLexerT_STDDEV = 64
 	SQLLexerT_HISTOGRAM = 65
-	SQLLexerT_SECOND = 66
-	SQLLexerT_MINUTE = 67
-	SQLLexerT_HOUR = 68
-	SQLLexerT_DAY = 69
-	SQLLexerT_WEEK = 70
-	SQLLexerT_MONTH = 71
-	SQLLexerT_YEAR = 72
-	SQLLexerT_DOT = 73
-	SQLLexerT_COLON = 74
-	SQLLexerT_EQUAL = 75
-	SQLLexerT_NOTEQUAL = 76
-	SQLLexerT_NOTEQUAL2 = 77
-	SQLLexerT_GREATER = 78
-	SQLLexerT_GREATEREQUAL = 79
-	SQLLexerT_LESS = 80
-	SQLLexerT_LESSEQUAL = 81
-	SQLLexerT_REGEXP = 82
-	SQLLexerT_NEQREGEXP = 83
-	SQLLexerT_COMMA = 84
-	SQLLexerT_OPEN_B = 85
-	SQLLexerT_CLOSE_B = 86
-	SQLLexerT_OPEN_SB = 87
-	SQLLexerT_CLOSE_SB = 88
-	SQLLexerT_OPEN_P = 89
-	SQLLexerT_CLOSE_P = 90
-	SQLLexerT_ADD = 91
-	SQLLexerT_SUB = 92
-	SQLLexerT_DIV = 93
-	SQLLexerT_MUL = 94
-	SQLLexerT_MOD = 95
-	SQLLexerL_ID = 96
-	SQLLexerL_INT = 97
-	SQLLexerL_DEC = 98
-	SQLLexerWS = 99
+	SQLLexerT_MOVING_AVERAGE = 66
+	SQLLexerT_RATE = 67
+	SQLLexerT_SECOND = 68
+	SQLLexerT_MINUTE = 69
+	SQLLexerT_HOUR = 70
+	SQLLexerT_DAY = 71
+	SQLLexerT_WEEK = 72
+	SQLLexerT_MONTH = 73
+	SQLLexerT_YEAR = 74
+	SQLLexerT_DOT = 75
+	SQLLexerT_COLON = 76
+	SQLLexerT_EQUAL = 77
+	SQLLexerT_NOTEQUAL = 78
+	SQLLexerT_NOTEQUAL2 = 79
+	SQLLexerT_GREATER = 80
+	SQLLexerT_GREATEREQUAL = 81
+	SQLLexerT_LESS = 82
+	SQLLexerT_LESSEQUAL = 83
+	SQLLexerT_REGEXP = 84
+	SQLLexerT_NEQREGEXP = 85
+	SQLLexerT_COMMA = 86
+	SQLLexerT_OPEN_B = 87
+	SQLLexerT_CLOSE_B = 88
+	SQLLexerT_OPEN_SB = 89
+	SQLLexerT_CLOSE_SB = 90
+	SQLLexerT_OPEN_P = 91
+	SQLLexerT_CLOSE_P = 92
+	SQLLexerT_ADD = 93
+	SQLLexerT_SUB = 94
+	SQLLexerT_DIV = 95
+	SQLLexerT_MUL = 96
+	SQLLexerT_MOD = 97
+	SQLLexerL_ID = 98
+	SQLLexerL_INT = 99
+	SQLLexerL_DEC = 100
+	SQLLexerWS = 101
 )
 
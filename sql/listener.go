@@ -85,6 +85,20 @@ func (l *listener) EnterLimitClause(ctx *grammar.LimitClauseContext) {
 	}
 }
 
+// EnterSortField is called when production sortField is entered.
+func (l *listener) EnterSortField(ctx *grammar.SortFieldContext) {
+	if l.stmt != nil {
+		l.stmt.visitSortField(ctx)
+	}
+}
+
+// ExitSortField is called when production sortField is exited.
+func (l *listener) ExitSortField(ctx *grammar.SortFieldContext) {
+	if l.stmt != nil {
+		l.stmt.completeSortField()
+	}
+}
+
 // EnterTagFilterExpr is called when production tagFilterExpr is entered.
 func (l *listener) EnterTagFilterExpr(ctx *grammar.TagFilterExprContext) {
 	if l.stmt != nil {
@@ -120,6 +134,13 @@ func (l *listener) EnterGroupByKey(ctx *grammar.GroupByKeyContext) {
 	}
 }
 
+// EnterFillOption is called when production fillOption is entered.
+func (l *listener) EnterFillOption(ctx *grammar.FillOptionContext) {
+	if l.stmt != nil {
+		l.stmt.visitFillOption(ctx)
+	}
+}
+
 // statement returns query statement, if failure return error
 func (l *listener) statement() (*stmt.Query, error) {
 	if l.stmt != nil {
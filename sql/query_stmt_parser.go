@@ -22,12 +22,14 @@ type queryStmtParse struct {
 	endTime   int64
 
 	condition stmt.Expr
-	//orderByExpr stmt.Expr
-	//desc        bool
-	limit    int
-	groupBy  []string
-	interval int64
-	fieldID  int
+	orderBy   []*stmt.OrderByItem
+	sortField *stmt.OrderByItem // current sort field being parsed, nil if parsing select list
+	limit     int
+	hasLimit  bool
+	groupBy   []string
+	fill      stmt.Fill
+	interval  int64
+	fieldID   int
 
 	exprStack *collections.Stack
 
@@ -68,7 +70,10 @@ func (q *queryStmtParse) build() (*stmt.Query, error) {
 
 	query.Interval = q.interval
 	query.GroupBy = q.groupBy
+	query.Fill = q.fill
+	query.OrderBy = q.orderBy
 	query.Limit = q.limit
+	query.HasLimit = q.hasLimit
 	return query, nil
 }
 
@@ -102,6 +107,31 @@ func (q *queryStmtParse) visitLimit(ctx *grammar.LimitClauseContext) {
 		return
 	}
 	q.limit = int(limit)
+	q.hasLimit = true
+}
+
+// visitSortField visits when production sort field expression is entered,
+// the field expression of sort field is completed as order by expr instead of select item
+func (q *queryStmtParse) visitSortField(ctx *grammar.SortFieldContext) {
+	q.resetExprStack()
+	q.sortField = &stmt.OrderByItem{Desc: len(ctx.AllT_DESC()) > 0}
+}
+
+// completeSortField completes a sort field for order by
+func (q *queryStmtParse) completeSortField() {
+	if q.sortField != nil && q.sortField.Expr != nil {
+		q.orderBy = append(q.orderBy, q.sortField)
+	}
+	q.sortField = nil
+}
+
+// completeExpr completes a top level expression as select item, or as order by expr if parsing sort field
+func (q *queryStmtParse) completeExpr(expr stmt.Expr) {
+	if q.sortField != nil {
+		q.sortField.Expr = expr
+		return
+	}
+	q.selectItems = append(q.selectItems, &stmt.SelectItem{Expr: expr})
 }
 
 // visitGroupByKey visits when production groupBy key expression is entered
@@ -115,6 +145,23 @@ func (q *queryStmtParse) visitGroupByKey(ctx *grammar.GroupByKeyContext) {
 	}
 }
 
+// visitFillOption visits when production fill option expression is entered
+func (q *queryStmtParse) visitFillOption(ctx *grammar.FillOptionContext) {
+	switch {
+	case ctx.T_NULL() != nil:
+		q.fill = stmt.Fill{Type: stmt.FillNull}
+	case ctx.T_PREVIOUS() != nil:
+		q.fill = stmt.Fill{Type: stmt.FillPrevious}
+	case ctx.L_INT() != nil || ctx.L_DEC() != nil:
+		value, err := strconv.ParseFloat(ctx.GetText(), 64)
+		if err != nil {
+			q.err = err
+			return
+		}
+		q.fill = stmt.Fill{Type: stmt.FillValue, Value: value}
+	}
+}
+
 // visitMetricName visits when production metricName expression is entered
 func (q *queryStmtParse) visitMetricName(ctx *grammar.MetricNameContext) {
 	q.metricName = strutil.GetStringValue(ctx.Ident().GetText())
@@ -358,6 +405,10 @@ func (q *queryStmtParse) visitFuncName(ctx *grammar.FuncNameContext) {
 		callExpr.FuncType = function.Stddev
 	case ctx.T_HISTOGRAM() != nil:
 		callExpr.FuncType = function.Histogram
+	case ctx.T_MOVING_AVERAGE() != nil:
+		callExpr.FuncType = function.MovingAverage
+	case ctx.T_RATE() != nil:
+		callExpr.FuncType = function.Rate
 	}
 }
 
@@ -370,7 +421,7 @@ func (q *queryStmtParse) completeFuncExpr() {
 			q.setExprParam(expr)
 		}
 		if q.exprStack.Empty() {
-			q.selectItems = append(q.selectItems, &stmt.SelectItem{Expr: expr})
+			q.completeExpr(expr)
 		}
 	}
 }
@@ -381,7 +432,7 @@ func (q *queryStmtParse) visitExprAtom(ctx *grammar.ExprAtomContext) {
 	case ctx.Ident() != nil:
 		val := strutil.GetStringValue(ctx.Ident().GetText())
 		if q.exprStack.Empty() {
-			q.selectItems = append(q.selectItems, &stmt.SelectItem{Expr: &stmt.FieldExpr{Name: val}})
+			q.completeExpr(&stmt.FieldExpr{Name: val})
 		} else {
 			q.setExprParam(&stmt.FieldExpr{Name: val})
 		}
@@ -445,7 +496,7 @@ func (q *queryStmtParse) completeFieldExpr(ctx *grammar.FieldExprContext) {
 			q.setExprParam(expr)
 		}
 		if q.exprStack.Empty() {
-			q.selectItems = append(q.selectItems, &stmt.SelectItem{Expr: expr})
+			q.completeExpr(expr)
 		}
 	}
 }
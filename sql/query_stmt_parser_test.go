@@ -154,6 +154,59 @@ func TestComplexSelectItem(t *testing.T) {
 
 }
 
+func TestMovingAverage(t *testing.T) {
+	sql := "select moving_average(f,3),moving_average(sum(g),5) from memory"
+	query, err := Parse(sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t,
+		[]stmt.Expr{
+			&stmt.SelectItem{
+				Expr: &stmt.CallExpr{
+					FuncType: function.MovingAverage,
+					Params:   []stmt.Expr{&stmt.FieldExpr{Name: "f"}, &stmt.NumberLiteral{Val: 3}},
+				},
+			},
+			&stmt.SelectItem{
+				Expr: &stmt.CallExpr{
+					FuncType: function.MovingAverage,
+					Params: []stmt.Expr{
+						&stmt.CallExpr{
+							FuncType: function.Sum,
+							Params:   []stmt.Expr{&stmt.FieldExpr{Name: "g"}}},
+						&stmt.NumberLiteral{Val: 5},
+					},
+				},
+			},
+		},
+		query.SelectItems)
+
+	// moving_average is non-reserved word, can be used as metric name
+	query, err = Parse("select f from moving_average")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "moving_average", query.MetricName)
+}
+
+func TestRate(t *testing.T) {
+	query, err := Parse("select rate(f) from memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t,
+		[]stmt.Expr{
+			&stmt.SelectItem{
+				Expr: &stmt.CallExpr{
+					FuncType: function.Rate,
+					Params:   []stmt.Expr{&stmt.FieldExpr{Name: "f"}},
+				},
+			},
+		},
+		query.SelectItems)
+}
+
 func TestMathExpress(t *testing.T) {
 	// math expression
 	sql := "select max(sum(c)+c*d/e) from memory"
@@ -194,6 +247,8 @@ func TestLimit(t *testing.T) {
 	query, err := Parse(sql)
 	assert.Nil(t, err)
 	assert.Equal(t, 10, query.Limit)
+	assert.True(t, query.HasLimit)
+	assert.Equal(t, 10, query.SeriesLimit())
 
 	sql = "select f from cpu limit abc"
 	_, err = Parse(sql)
@@ -204,6 +259,36 @@ func TestLimit(t *testing.T) {
 	query, err = Parse(sql)
 	assert.Nil(t, err)
 	assert.Equal(t, 20, query.Limit)
+	// default limit is not applied to the time series
+	assert.False(t, query.HasLimit)
+	assert.Equal(t, 0, query.SeriesLimit())
+	query, err = Parse("select f from cpu group by host")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, query.SeriesLimit())
+}
+
+func TestOrderBy(t *testing.T) {
+	sql := "select f, max(g) from cpu group by host order by max(g) desc, f limit 10"
+	query, err := Parse(sql)
+	assert.Nil(t, err)
+	assert.Len(t, query.SelectItems, 2)
+	assert.Equal(t, []*stmt.OrderByItem{
+		{Expr: &stmt.CallExpr{FuncType: function.Max, Params: []stmt.Expr{&stmt.FieldExpr{Name: "g"}}}, Desc: true},
+		{Expr: &stmt.FieldExpr{Name: "f"}},
+	}, query.OrderBy)
+	assert.Equal(t, 10, query.Limit)
+
+	sql = "select f from cpu order by f asc"
+	query, err = Parse(sql)
+	assert.Nil(t, err)
+	assert.Equal(t, []stmt.Expr{&stmt.SelectItem{Expr: &stmt.FieldExpr{Name: "f"}}}, query.SelectItems)
+	assert.Equal(t, []*stmt.OrderByItem{{Expr: &stmt.FieldExpr{Name: "f"}}}, query.OrderBy)
+
+	sql = "select f from cpu order by sum(f)+1"
+	query, err = Parse(sql)
+	assert.Nil(t, err)
+	assert.Len(t, query.OrderBy, 1)
+	assert.IsType(t, &stmt.BinaryExpr{}, query.OrderBy[0].Expr)
 }
 
 func TestTimeRange(t *testing.T) {
@@ -252,6 +337,24 @@ func TestGroupBy(t *testing.T) {
 	assert.Equal(t, "/data", query.GroupBy[1])
 }
 
+func TestFill(t *testing.T) {
+	query, err := Parse("select a-b from cpu group by time(1m)")
+	assert.Nil(t, err)
+	assert.Equal(t, stmt.Fill{}, query.Fill)
+	query, err = Parse("select a-b from cpu group by time(1m) fill(null)")
+	assert.Nil(t, err)
+	assert.Equal(t, stmt.Fill{Type: stmt.FillNull}, query.Fill)
+	query, err = Parse("select a-b from cpu group by host fill(previous)")
+	assert.Nil(t, err)
+	assert.Equal(t, stmt.Fill{Type: stmt.FillPrevious}, query.Fill)
+	query, err = Parse("select a-b from cpu group by time(1m) fill(10)")
+	assert.Nil(t, err)
+	assert.Equal(t, stmt.Fill{Type: stmt.FillValue, Value: 10}, query.Fill)
+	query, err = Parse("select a-b from cpu group by time(1m) fill(1.5)")
+	assert.Nil(t, err)
+	assert.Equal(t, stmt.Fill{Type: stmt.FillValue, Value: 1.5}, query.Fill)
+}
+
 func TestEmptyCondition(t *testing.T) {
 	sql := "select f from cpu"
 	query, err := Parse(sql)
@@ -123,6 +123,14 @@ func (e *FieldExpr) Rewrite() string {
 	return e.Name
 }
 
+// NamePattern returns the regex pattern if the field name is quoted by slash, like /disk_.*/
+func (e *FieldExpr) NamePattern() (string, bool) {
+	if len(e.Name) > 2 && strings.HasPrefix(e.Name, "/") && strings.HasSuffix(e.Name, "/") {
+		return e.Name[1 : len(e.Name)-1], true
+	}
+	return "", false
+}
+
 // Rewrite rewrites the call expr after parse
 func (e *CallExpr) Rewrite() string {
 	var params []string
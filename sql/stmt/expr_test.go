@@ -47,6 +47,16 @@ func TestExpr_Rewrite(t *testing.T) {
 	assert.Equal(t, "tagKey=~Regexp", (&RegexExpr{Key: "tagKey", Regexp: "Regexp"}).Rewrite())
 }
 
+func TestFieldExpr_NamePattern(t *testing.T) {
+	pattern, ok := (&FieldExpr{Name: "/disk_.*/"}).NamePattern()
+	assert.True(t, ok)
+	assert.Equal(t, "disk_.*", pattern)
+	_, ok = (&FieldExpr{Name: "//"}).NamePattern()
+	assert.False(t, ok)
+	_, ok = (&FieldExpr{Name: "/disk"}).NamePattern()
+	assert.False(t, ok)
+}
+
 func TestTagFilter(t *testing.T) {
 	assert.Equal(t, "tagKey", (&EqualsExpr{Key: "tagKey", Value: "tagValue"}).TagKey())
 	assert.Equal(t, "tagKey", (&LikeExpr{Key: "tagKey", Value: "tagValue"}).TagKey())
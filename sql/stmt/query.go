@@ -2,6 +2,8 @@ package stmt
 
 import (
 	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/lindb/lindb/pkg/encoding"
 	"github.com/lindb/lindb/pkg/timeutil"
@@ -13,11 +15,102 @@ type Query struct {
 	SelectItems []Expr // select list, such as field, function call, math expression etc.
 	Condition   Expr   // tag filter condition expression
 
-	TimeRange timeutil.TimeRange // query time range
-	Interval  int64              // down sampling interval
+	TimeRange     timeutil.TimeRange // query time range
+	Interval      int64              // down sampling interval
+	ForceInterval int64              // forced aggregation interval regardless of time range, multiple of storage interval
+	Budget        int64              // soft time budget(ms) of storage scan, returns partial results when elapsed
 
-	GroupBy []string // group by tag keys
-	Limit   int      // num. of time series list for result
+	GroupBy []string       // group by tag keys
+	Fill    Fill           // fill policy for missing slots
+	OrderBy []*OrderByItem // order the time series by the aggregated value of fields
+	Limit   int            // num. of time series list for result, 0 means unlimited
+	// the limit is given in sql explicitly, the default limit is not applied to the time series
+	HasLimit bool
+
+	ValidateTagKeys bool // returns error if the query references an unknown tag key
+	// returns the internal series id of each time series as a tag for debugging, the series are not aggregated
+	WithSeriesID bool
+	// IANA timezone name(like Asia/Shanghai), aligns the down sampling buckets to local midnight/week-start,
+	// default UTC
+	Timezone string
+	// returns only the series whose latest data is older than the duration(ms) for alerting on missing data,
+	// 0 means all the series are returned
+	StaleFor int64
+	// returns only the tags of the series matching the condition from the index without any field values,
+	// bounded by the limit, for service discovery like "what series exist"
+	SeriesOnly bool
+	// sends the series updated by each task response of storage once merged rather than the whole result set
+	// after all tasks completed, only used by the root task of broker, not sent to storage
+	Streaming bool
+}
+
+// QueryOptions represents the query options given besides sql(like http params of query api),
+// which are applied to the parsed query
+type QueryOptions struct {
+	ForceInterval int64  // forced aggregation interval, 0 means not forced
+	Budget        int64  // soft time budget(ms) of storage scan, 0 means unlimited
+	FillMaxGap    int64  // max duration(ms) of carrying forward the previous value for previous fill, 0 means unbounded
+	WithSeriesID  bool   // returns the internal series id of each time series as a tag for debugging
+	Timezone      string // IANA timezone name which the down sampling buckets are aligned to, default UTC
+	// returns error if the query references an unknown tag key
+	ValidateTagKeys bool
+	// returns only the series whose latest data is older than the duration(ms), 0 means all the series
+	StaleFor int64
+	// returns only the tags of the series matching the condition without any field values
+	SeriesOnly bool
+	// sends the series incrementally once each task response of storage is merged
+	Streaming bool
+}
+
+// Apply applies the options to the query
+func (o QueryOptions) Apply(q *Query) {
+	q.ForceInterval = o.ForceInterval
+	q.Budget = o.Budget
+	q.Fill.MaxGap = o.FillMaxGap
+	q.WithSeriesID = o.WithSeriesID
+	q.Timezone = o.Timezone
+	q.ValidateTagKeys = o.ValidateTagKeys
+	q.StaleFor = o.StaleFor
+	q.SeriesOnly = o.SeriesOnly
+	q.Streaming = o.Streaming
+}
+
+// FillType represents the fill policy type for the missing slots
+type FillType int
+
+// Defines all fill policy types
+const (
+	// FillNone keeps the missing slot empty, evaluates the slot with 0 if the other operand has value
+	FillNone FillType = iota
+	// FillNull skips the slot if any operand is missing
+	FillNull
+	// FillPrevious fills the missing slot with the previous value, bounded by the max gap if set
+	FillPrevious
+	// FillValue fills the missing slot with the given value
+	FillValue
+)
+
+// Fill represents the fill policy for the missing slots when evaluating the select items
+type Fill struct {
+	Type  FillType `json:"type,omitempty"`
+	Value float64  `json:"value,omitempty"`
+	// MaxGap is the max duration(millisecond) of carrying forward the previous value for previous fill,
+	// the slots beyond the gap since the previous value keep missing, 0 means unbounded
+	MaxGap int64 `json:"maxGap,omitempty"`
+}
+
+// OrderByItem represents an order by item, the time series are ordered by the aggregated value of expr
+type OrderByItem struct {
+	Expr Expr // field or function call on field
+	Desc bool // descending order
+}
+
+// SeriesLimit returns the limit of time series given in sql explicitly, 0 means unlimited
+func (q *Query) SeriesLimit() int {
+	if !q.HasLimit {
+		return 0
+	}
+	return q.Limit
 }
 
 // HasGroupBy returns whether query has group by tag keys
@@ -25,17 +118,49 @@ func (q *Query) HasGroupBy() bool {
 	return len(q.GroupBy) > 0
 }
 
+// Location returns the location of query timezone, returns UTC if timezone not set
+func (q *Query) Location() (*time.Location, error) {
+	if q.Timezone == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(q.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone[%s]: %s", q.Timezone, err)
+	}
+	return location, nil
+}
+
 // innerQuery represents a wrapper of query for json encoding
 type innerQuery struct {
 	MetricName  string            `json:"metricName,omitempty"`
 	SelectItems []json.RawMessage `json:"selectItems,omitempty"`
 	Condition   json.RawMessage   `json:"condition,omitempty"`
 
-	TimeRange timeutil.TimeRange `json:"timeRange,omitempty"`
-	Interval  int64              `json:"interval,omitempty"`
+	TimeRange     timeutil.TimeRange `json:"timeRange,omitempty"`
+	Interval      int64              `json:"interval,omitempty"`
+	ForceInterval int64              `json:"forceInterval,omitempty"`
+	Budget        int64              `json:"budget,omitempty"`
+
+	GroupBy []string           `json:"groupBy,omitempty"`
+	Fill    Fill               `json:"fill,omitempty"`
+	OrderBy []innerOrderByItem `json:"orderBy,omitempty"`
+	Limit   int                `json:"limit,omitempty"`
+
+	HasLimit bool `json:"hasLimit,omitempty"`
+
+	ValidateTagKeys bool `json:"validateTagKeys,omitempty"`
+	WithSeriesID    bool `json:"withSeriesID,omitempty"`
 
-	GroupBy []string `json:"groupBy,omitempty"`
-	Limit   int      `json:"limit,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+	StaleFor int64  `json:"staleFor,omitempty"`
+
+	SeriesOnly bool `json:"seriesOnly,omitempty"`
+}
+
+// innerOrderByItem represents a wrapper of order by item for json encoding
+type innerOrderByItem struct {
+	Expr json.RawMessage `json:"expr,omitempty"`
+	Desc bool            `json:"desc,omitempty"`
 }
 
 // MarshalJSON returns json data of query
@@ -46,11 +171,27 @@ func (q *Query) MarshalJSON() ([]byte, error) {
 		TimeRange:  q.TimeRange,
 		Interval:   q.Interval,
 		GroupBy:    q.GroupBy,
+		Fill:       q.Fill,
 		Limit:      q.Limit,
+		HasLimit:   q.HasLimit,
+
+		ForceInterval: q.ForceInterval,
+		Budget:        q.Budget,
+
+		ValidateTagKeys: q.ValidateTagKeys,
+		WithSeriesID:    q.WithSeriesID,
+
+		Timezone: q.Timezone,
+		StaleFor: q.StaleFor,
+
+		SeriesOnly: q.SeriesOnly,
 	}
 	for _, item := range q.SelectItems {
 		inner.SelectItems = append(inner.SelectItems, Marshal(item))
 	}
+	for _, item := range q.OrderBy {
+		inner.OrderBy = append(inner.OrderBy, innerOrderByItem{Expr: Marshal(item.Expr), Desc: item.Desc})
+	}
 	return encoding.JSONMarshal(&inner), nil
 }
 
@@ -75,11 +216,29 @@ func (q *Query) UnmarshalJSON(value []byte) error {
 		}
 		selectItems = append(selectItems, selectItem)
 	}
+	var orderBy []*OrderByItem
+	for _, item := range inner.OrderBy {
+		expr, err := Unmarshal(item.Expr)
+		if err != nil {
+			return err
+		}
+		orderBy = append(orderBy, &OrderByItem{Expr: expr, Desc: item.Desc})
+	}
 	q.MetricName = inner.MetricName
 	q.SelectItems = selectItems
 	q.TimeRange = inner.TimeRange
 	q.Interval = inner.Interval
+	q.ForceInterval = inner.ForceInterval
+	q.Budget = inner.Budget
 	q.GroupBy = inner.GroupBy
+	q.Fill = inner.Fill
+	q.OrderBy = orderBy
 	q.Limit = inner.Limit
+	q.HasLimit = inner.HasLimit
+	q.ValidateTagKeys = inner.ValidateTagKeys
+	q.WithSeriesID = inner.WithSeriesID
+	q.Timezone = inner.Timezone
+	q.StaleFor = inner.StaleFor
+	q.SeriesOnly = inner.SeriesOnly
 	return nil
 }
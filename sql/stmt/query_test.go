@@ -2,6 +2,7 @@ package stmt
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -44,10 +45,23 @@ func TestQuery_Marshal(t *testing.T) {
 				Right:    &EqualsExpr{Key: "path", Value: "/home"},
 			}},
 		},
-		TimeRange: timeutil.TimeRange{Start: 10, End: 30},
-		Interval:  1000,
-		GroupBy:   []string{"a", "b", "c"},
-		Limit:     100,
+		TimeRange:     timeutil.TimeRange{Start: 10, End: 30},
+		Interval:      1000,
+		ForceInterval: 60000,
+		Budget:        500,
+		GroupBy:       []string{"a", "b", "c"},
+		Fill:          Fill{Type: FillValue, Value: 1.5},
+		OrderBy: []*OrderByItem{
+			{Expr: &CallExpr{FuncType: function.Max, Params: []Expr{&FieldExpr{Name: "f"}}}, Desc: true},
+			{Expr: &FieldExpr{Name: "c"}},
+		},
+		Limit:    100,
+		HasLimit: true,
+
+		WithSeriesID: true,
+		Timezone:     "Asia/Shanghai",
+		StaleFor:     300000,
+		SeriesOnly:   true,
 	}
 
 	data := encoding.JSONMarshal(&query)
@@ -68,4 +82,42 @@ func TestQuery_Marshal_Fail(t *testing.T) {
 	assert.NotNil(t, err)
 	err = query.UnmarshalJSON([]byte("{\"selectItems\":[\"123\"]}"))
 	assert.NotNil(t, err)
+	err = query.UnmarshalJSON([]byte("{\"orderBy\":[{\"expr\":\"123\"}]}"))
+	assert.NotNil(t, err)
+}
+
+func TestQuery_Location(t *testing.T) {
+	query := &Query{}
+	location, err := query.Location()
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, location)
+
+	query.Timezone = "Asia/Shanghai"
+	location, err = query.Location()
+	assert.NoError(t, err)
+	assert.Equal(t, "Asia/Shanghai", location.String())
+
+	query.Timezone = "Unknown/Zone"
+	location, err = query.Location()
+	assert.Error(t, err)
+	assert.Nil(t, location)
+}
+
+func TestQueryOptions_Apply(t *testing.T) {
+	query := &Query{MetricName: "cpu"}
+	QueryOptions{}.Apply(query)
+	assert.Equal(t, &Query{MetricName: "cpu"}, query)
+
+	QueryOptions{ForceInterval: 60000, Budget: 500, FillMaxGap: 300000, WithSeriesID: true,
+		Timezone: "Asia/Shanghai", ValidateTagKeys: true, StaleFor: 300000,
+		SeriesOnly: true, Streaming: true}.Apply(query)
+	assert.Equal(t, int64(60000), query.ForceInterval)
+	assert.Equal(t, int64(500), query.Budget)
+	assert.Equal(t, int64(300000), query.Fill.MaxGap)
+	assert.True(t, query.WithSeriesID)
+	assert.Equal(t, "Asia/Shanghai", query.Timezone)
+	assert.True(t, query.ValidateTagKeys)
+	assert.Equal(t, int64(300000), query.StaleFor)
+	assert.True(t, query.SeriesOnly)
+	assert.True(t, query.Streaming)
 }
@@ -68,14 +68,41 @@ func buildMetricList() *field.MetricList {
 		}}}
 }
 
-// messageLen, message
 func buildMessageBytes() []byte {
-	ml := buildMetricList()
+	return buildMessageBytesWithSeq(buildWriteEntry(1, buildMetricList()))
+}
+
+func buildWriteEntry(writeSeq int64, ml *field.MetricList) replication.WriteEntry {
 	mlBytes, err := ml.Marshal()
 	if err != nil {
 		panic(err)
 	}
+	return replication.WriteEntry{WriteSeq: writeSeq, Data: mlBytes}
+}
 
+// marker, version, then [writeSeq, messageLen, message]...
+func buildMessageBytesWithSeq(entries ...replication.WriteEntry) []byte {
+	buf := stream.NewBufferWriter(nil)
+	buf.PutByte(0)
+	buf.PutByte(1)
+	for _, entry := range entries {
+		buf.PutVarint64(entry.WriteSeq)
+		buf.PutUvarint32(uint32(len(entry.Data)))
+		buf.PutBytes(entry.Data)
+	}
+	bytes, err := buf.Bytes()
+	if err != nil {
+		panic(err)
+	}
+	return bytes
+}
+
+// messageLen, message, written before write sequence
+func buildLegacyMessageBytes(ml *field.MetricList) []byte {
+	mlBytes, err := ml.Marshal()
+	if err != nil {
+		panic(err)
+	}
 	buf := stream.NewBufferWriter(nil)
 	buf.PutUvarint32(uint32(len(mlBytes)))
 	buf.PutBytes(mlBytes)
@@ -256,6 +283,144 @@ func TestWriter_Write_Success(t *testing.T) {
 	}
 }
 
+func TestWriter_Write_ReplayOrdered(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	sm := replication.NewMockSequenceManager(ctl)
+	s := replication.NewMockSequence(ctl)
+
+	buildLastValue := func(value float64) *field.MetricList {
+		return &field.MetricList{Metrics: []*field.Metric{{
+			Name:      "name",
+			Timestamp: 1000,
+			Fields: []*field.Field{{
+				Name:  "gauge",
+				Field: &field.Field_Gauge{Gauge: &field.Gauge{Value: value}},
+			}},
+		}}}
+	}
+	// out of order entries: write seq 3, 1, 2 in replica 5, write seq 5, 4 in replica 6
+	wr := &storage.WriteRequest{Replicas: []*storage.Replica{
+		{Seq: 5, Data: buildMessageBytesWithSeq(
+			buildWriteEntry(3, buildLastValue(3)),
+			buildWriteEntry(1, buildLastValue(1)),
+			buildWriteEntry(2, buildLastValue(2)))},
+		{Seq: 6, Data: buildMessageBytesWithSeq(
+			buildWriteEntry(5, buildLastValue(5)),
+			buildWriteEntry(4, buildLastValue(4)))},
+	}}
+
+	s.EXPECT().GetHeadSeq().Return(int64(5))
+	s.EXPECT().SetHeadSeq(int64(6)).Return()
+	s.EXPECT().GetHeadSeq().Return(int64(6))
+	s.EXPECT().SetHeadSeq(int64(7)).Return()
+	s.EXPECT().GetHeadSeq().Return(int64(7))
+	s.EXPECT().Synced().Return(false)
+	sm.EXPECT().GetSequence(database, shardID, node).Return(s, true)
+
+	// last value wins after ordering
+	var lastValue float64
+	var written []float64
+	shard := tsdb.NewMockShard(ctl)
+	shard.EXPECT().Write(gomock.Any()).DoAndReturn(func(metric *field.Metric) error {
+		lastValue = metric.Fields[0].GetGauge().Value
+		written = append(written, lastValue)
+		return nil
+	}).Times(5)
+
+	writer := NewWriter(mockStorage(ctl, database, shardID, shard), sm)
+	stream := storage.NewMockWriteService_WriteServer(ctl)
+	stream.EXPECT().Context().Return(mockContext(database, shardID, node))
+	stream.EXPECT().Recv().Return(wr, nil)
+	stream.EXPECT().Send(&storage.WriteResponse{CurSeq: 6}).Return(errors.New("send error"))
+
+	err := writer.Write(stream)
+	assert.NotNil(t, err)
+	assert.Equal(t, []float64{1, 2, 3, 4, 5}, written)
+	assert.Equal(t, 5.0, lastValue)
+}
+
+func TestWriter_Write_Legacy(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	sm := replication.NewMockSequenceManager(ctl)
+	s := replication.NewMockSequence(ctl)
+
+	s.EXPECT().GetHeadSeq().Return(int64(5))
+	s.EXPECT().SetHeadSeq(int64(6)).Return()
+	s.EXPECT().GetHeadSeq().Return(int64(6))
+	s.EXPECT().SetHeadSeq(int64(7)).Return()
+	s.EXPECT().GetHeadSeq().Return(int64(7))
+	s.EXPECT().Synced().Return(false)
+	sm.EXPECT().GetSequence(database, shardID, node).Return(s, true)
+
+	metricList := buildMetricList()
+	var written []*field.Metric
+	shard := tsdb.NewMockShard(ctl)
+	shard.EXPECT().Write(gomock.Any()).DoAndReturn(func(metric *field.Metric) error {
+		written = append(written, metric)
+		return nil
+	}).Times(2)
+
+	// the replica queued before upgrade is replayed before the replica with write sequence
+	writer := NewWriter(mockStorage(ctl, database, shardID, shard), sm)
+	stream := storage.NewMockWriteService_WriteServer(ctl)
+	stream.EXPECT().Context().Return(mockContext(database, shardID, node))
+	stream.EXPECT().Recv().Return(&storage.WriteRequest{Replicas: []*storage.Replica{
+		{Seq: 5, Data: buildLegacyMessageBytes(metricList)},
+		{Seq: 6, Data: buildMessageBytesWithSeq(buildWriteEntry(1, metricList))},
+	}}, nil)
+	stream.EXPECT().Send(&storage.WriteResponse{CurSeq: 6}).Return(errors.New("send error"))
+
+	err := writer.Write(stream)
+	assert.NotNil(t, err)
+	assert.Equal(t, append(metricList.Metrics, metricList.Metrics...), written)
+}
+
+func TestWriter_Write_Compressed(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	sm := replication.NewMockSequenceManager(ctl)
+	s := replication.NewMockSequence(ctl)
+
+	s.EXPECT().GetHeadSeq().Return(int64(5))
+	s.EXPECT().SetHeadSeq(int64(6)).Return()
+	s.EXPECT().GetHeadSeq().Return(int64(6))
+	s.EXPECT().Synced().Return(false)
+	sm.EXPECT().GetSequence(database, shardID, node).Return(s, true)
+
+	metricList := buildMetricList()
+	var written []*field.Metric
+	shard := tsdb.NewMockShard(ctl)
+	shard.EXPECT().Write(gomock.Any()).DoAndReturn(func(metric *field.Metric) error {
+		written = append(written, metric)
+		return nil
+	})
+
+	writer := NewWriter(mockStorage(ctl, database, shardID, shard), sm)
+	ctx := metadata.NewIncomingContext(mockContext(database, shardID, node),
+		metadata.Join(metadata.Pairs("metaKeyCompression", rpc.CompressionSnappy), mockMetadata()))
+	stream := storage.NewMockWriteService_WriteServer(ctl)
+	stream.EXPECT().Context().Return(ctx).AnyTimes()
+	stream.EXPECT().Recv().Return(&storage.WriteRequest{Replicas: []*storage.Replica{{
+		Seq:  5,
+		Data: rpc.EncodePayload(rpc.CompressionSnappy, buildMessageBytesWithSeq(buildWriteEntry(1, metricList))),
+	}}}, nil)
+	stream.EXPECT().Send(&storage.WriteResponse{CurSeq: 5}).Return(errors.New("send error"))
+
+	err := writer.Write(stream)
+	assert.NotNil(t, err)
+	assert.Equal(t, metricList.Metrics, written)
+
+	// not support compression
+	ctx = metadata.NewIncomingContext(context.TODO(),
+		metadata.Join(metadata.Pairs("metaKeyCompression", "gzip"), mockMetadata()))
+	stream = storage.NewMockWriteService_WriteServer(ctl)
+	stream.EXPECT().Context().Return(ctx).AnyTimes()
+	err = writer.Write(stream)
+	assert.NotNil(t, err)
+}
+
 func TestWriter_WriteSeqNotMatch(t *testing.T) {
 	ctl := gomock.NewController(t)
 	defer ctl.Finish()
@@ -321,6 +486,11 @@ func mockShard(ctl *gomock.Controller) tsdb.Shard {
 	return mockShard
 }
 
+func mockMetadata() metadata.MD {
+	md, _ := metadata.FromIncomingContext(mockContext(database, shardID, node))
+	return md
+}
+
 func mockContext(db string, shardID int32, node models.Node) context.Context {
 	return rpc.CreateIncomingContext(context.TODO(), db, shardID, node)
 }
@@ -10,7 +10,6 @@ import (
 
 	"github.com/lindb/lindb/models"
 	"github.com/lindb/lindb/pkg/logger"
-	streamIO "github.com/lindb/lindb/pkg/stream"
 	"github.com/lindb/lindb/replication"
 	"github.com/lindb/lindb/rpc"
 	"github.com/lindb/lindb/rpc/proto/field"
@@ -61,16 +60,23 @@ func (w *Writer) Next(ctx context.Context, req *storage.NextSeqRequest) (*storag
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	// advertises the supported compression of replica data, fails only if not called by grpc server
+	_ = rpc.SetCompressionHeader(ctx)
 
 	return &storage.NextSeqResponse{Seq: sequence.GetHeadSeq()}, nil
 }
 
 // Write handles the stream write request.
 func (w *Writer) Write(stream storage.WriteService_WriteServer) error {
-	database, shardID, logicNode, err := parseCtx(stream.Context())
+	ctx := stream.Context()
+	database, shardID, logicNode, err := parseCtx(ctx)
 	if err != nil {
 		return status.Error(codes.InvalidArgument, err.Error())
 	}
+	compression := rpc.GetCompressionFromContext(ctx)
+	if compression != "" && compression != rpc.CompressionSnappy {
+		return status.Errorf(codes.InvalidArgument, "not support compression: %s", compression)
+	}
 
 	sequence, err := w.getSequence(database, shardID, *logicNode)
 	if err != nil {
@@ -96,21 +102,26 @@ func (w *Writer) Write(stream storage.WriteService_WriteServer) error {
 			continue
 		}
 
+		// collect write entries of all replicas, then replay them ordered by write sequence
+		var entries []replication.WriteEntry
 		// nextSeq means the sequence replica wanted
 		for _, replica := range req.Replicas {
 			seq := replica.Seq
 
 			hs := sequence.GetHeadSeq()
 			if hs != seq {
+				// replay the accepted replicas
+				w.replay(shard, entries)
 				// reset to headSeq
 				return status.Errorf(codes.OutOfRange, "seq num not match replica:%d, storage:%d", seq, hs)
 			}
 
-			w.handleReplica(shard, replica)
+			entries = append(entries, w.handleReplica(compression, replica)...)
 
 			sequence.SetHeadSeq(hs + 1)
 
 		}
+		w.replay(shard, entries)
 
 		resp := &storage.WriteResponse{
 			CurSeq: sequence.GetHeadSeq() - 1,
@@ -127,20 +138,27 @@ func (w *Writer) Write(stream storage.WriteService_WriteServer) error {
 	}
 }
 
-func (w *Writer) handleReplica(shard tsdb.Shard, replica *storage.Replica) {
-	reader := streamIO.NewReader(replica.Data)
-	for !reader.Empty() {
-		bytesLen := reader.ReadUvarint32()
-
-		bytes := reader.ReadSlice(int(bytesLen))
-
-		if err := reader.Error(); err != nil {
-			w.logger.Error("read metricList bytes from replica", logger.Error(err))
-			break
-		}
+// handleReplica decompresses the replica data by the compression of stream, then decodes the write entries
+func (w *Writer) handleReplica(compression string, replica *storage.Replica) []replication.WriteEntry {
+	data, err := rpc.DecodePayload(compression, replica.Data)
+	if err != nil {
+		w.logger.Error("decompress replica data", logger.Error(err))
+		return nil
+	}
+	entries, err := replication.DecodeWriteEntries(data)
+	if err != nil {
+		w.logger.Error("read metricList bytes from replica", logger.Error(err))
+	}
+	return entries
+}
 
+// replay writes the metrics of write entries into shard ordered by write sequence,
+// so that last-value fields keep the value of the latest write.
+func (w *Writer) replay(shard tsdb.Shard, entries []replication.WriteEntry) {
+	replication.SortWriteEntries(entries)
+	for _, entry := range entries {
 		var metricList field.MetricList
-		err := metricList.Unmarshal(bytes)
+		err := metricList.Unmarshal(entry.Data)
 		if err != nil {
 			w.logger.Error("unmarshal metricList", logger.Error(err))
 			continue
@@ -29,6 +29,7 @@ import (
 
 // srv represents all dependency services
 type srv struct {
+	engine          tsdb.Engine
 	storageService  service.StorageService
 	sequenceManager replication.SequenceManager
 }
@@ -183,6 +184,14 @@ func (r *runtime) Stop() error {
 		r.log.Info("stopping grpc server")
 		r.server.Stop()
 	}
+
+	// flush all the memory databases after the writes stopped
+	if r.srv.engine != nil {
+		r.log.Info("shutting down tsdb engine")
+		if err := r.srv.engine.Shutdown(r.config.StorageBase.TSDB.ShutdownTimeout.Duration()); err != nil {
+			r.log.Error("shutdown tsdb engine error, unflushed data is lost", logger.Error(err))
+		}
+	}
 	r.log.Info("storage server stop complete")
 	r.state = server.Terminated
 	return nil
@@ -199,6 +208,7 @@ func (r *runtime) buildServiceDependency() error {
 		return err
 	}
 	srv := srv{
+		engine:          engine,
 		storageService:  service.NewStorageService(engine),
 		sequenceManager: sm,
 	}
@@ -224,7 +234,7 @@ func (r *runtime) startTCPServer() {
 func (r *runtime) bindRPCHandlers() {
 	//FIXME: (stone1100) need close
 	dispatcher := taskHandler.NewLeafTaskDispatcher(r.node, r.srv.storageService,
-		query.NewExecutorFactory(), r.factory.taskServer)
+		query.NewExecutorFactory(r.config.StorageBase.Query), r.factory.taskServer)
 
 	r.handler = &rpcHandler{
 		writer: handler.NewWriter(r.srv.storageService, r.srv.sequenceManager),
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
-	"runtime"
 	"strconv"
 	"sync"
 	"time"
@@ -12,10 +11,10 @@ import (
 	"go.uber.org/atomic"
 
 	"github.com/lindb/lindb/kv"
-	"github.com/lindb/lindb/pkg/concurrent"
 	"github.com/lindb/lindb/pkg/logger"
 	"github.com/lindb/lindb/pkg/ltoml"
 	"github.com/lindb/lindb/pkg/option"
+	"github.com/lindb/lindb/pkg/timeutil"
 	"github.com/lindb/lindb/tsdb/metadb"
 )
 
@@ -73,34 +72,33 @@ type database struct {
 	idSequencer  metadb.IDSequencer // database-level reused object
 	metaStore    kv.Store           // underlying meta kv store
 	isFlushing   atomic.Bool        // restrict flusher concurrency
+	metricTTL    atomic.Value       // metric name -> ttl of forward index(map[string]time.Duration)
+	// merger name of the forward index family of shards
+	forwardIndexMerger string
 }
 
 func newDatabase(
 	databaseName string,
 	databasePath string,
 	cfg *databaseConfig,
+	executorPool *ExecutorPool,
 ) (
 	db *database,
 	err error,
 ) {
 	db = &database{
-		name:        databaseName,
-		path:        databasePath,
-		config:      cfg,
-		numOfShards: *atomic.NewInt32(0),
-		executorPool: &ExecutorPool{
-			Scanners: concurrent.NewPool(
-				runtime.NumCPU(), /*nRoutines*/
-				time.Second*5),
-			Mergers: concurrent.NewPool(
-				runtime.NumCPU(),
-				time.Second*5),
-		},
-		isFlushing: *atomic.NewBool(false),
+		name:         databaseName,
+		path:         databasePath,
+		config:       cfg,
+		numOfShards:  *atomic.NewInt32(0),
+		executorPool: executorPool,
+		isFlushing:   *atomic.NewBool(false),
 	}
 	if err = db.initIDSequencer(); err != nil {
 		return nil, err
 	}
+	db.metricTTL.Store(metricTTL(cfg.Option.MetricTTL))
+	db.forwardIndexMerger = registerForwardIndexMerger(databaseName, db.getMetricTTL)
 	// load shards if engine is exist
 	if len(db.config.ShardIDs) > 0 {
 		for _, shardID := range db.config.ShardIDs {
@@ -108,6 +106,7 @@ func newDatabase(
 				shardID,
 				filepath.Join(databasePath, shardDir, strconv.Itoa(int(shardID))),
 				db.idSequencer,
+				db.forwardIndexMerger,
 				db.config.Option)
 			if err != nil {
 				return nil, fmt.Errorf("cannot create shard[%d] of database[%s] with error: %s",
@@ -156,6 +155,7 @@ func (db *database) CreateShards(
 			shardID,
 			filepath.Join(db.path, shardDir, strconv.Itoa(int(shardID))),
 			db.idSequencer,
+			db.forwardIndexMerger,
 			option)
 		if err != nil {
 			db.mutex.Unlock()
@@ -169,6 +169,7 @@ func (db *database) CreateShards(
 			db.mutex.Unlock()
 			return err
 		}
+		db.metricTTL.Store(metricTTL(option.MetricTTL))
 		db.shards.Store(shardID, createdShard)
 		db.numOfShards.Inc()
 		db.mutex.Unlock()
@@ -185,6 +186,28 @@ func (db *database) GetShard(shardID int32) (Shard, bool) {
 	return item.(Shard), true
 }
 
+// getMetricTTL returns the ttl of metric in database option by metric id, false if the metric uses the default ttl
+func (db *database) getMetricTTL(metricID uint32) (time.Duration, bool) {
+	ttls, _ := db.metricTTL.Load().(map[string]time.Duration)
+	for metricName, ttl := range ttls {
+		if id, err := db.idSequencer.GetMetricID(metricName); err == nil && id == metricID {
+			return ttl, true
+		}
+	}
+	return 0, false
+}
+
+// metricTTL converts the ttl of metrics to duration, the option is validated before
+func metricTTL(ttls []option.MetricTTLOption) map[string]time.Duration {
+	result := make(map[string]time.Duration, len(ttls))
+	for _, ttlOpt := range ttls {
+		var ttl timeutil.Interval
+		_ = ttl.ValueOf(ttlOpt.TTL)
+		result[ttlOpt.Metric] = time.Duration(ttl.Int64()) * time.Millisecond
+	}
+	return result
+}
+
 // ExecutorPool returns the query task execute pool
 func (db *database) ExecutorPool() *ExecutorPool {
 	return db.executorPool
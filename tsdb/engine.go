@@ -23,6 +23,12 @@ var (
 	flushMetaInterval              = *atomic.NewDuration(time.Hour)
 )
 
+// interval of checking if the in-progress flushing is done when shutdown
+const shutdownFlushCheckInterval = 10 * time.Millisecond
+
+// num. of metrics holding the most memory logged when the biggest shard is evicted or flushed
+const topMemoryMetrics = 10
+
 //go:generate mockgen -source=./engine.go -destination=./engine_mock.go -package=tsdb
 
 var engineLogger = logger.GetLogger("tsdb", "Engine")
@@ -36,6 +42,9 @@ type Engine interface {
 	GetDatabase(databaseName string) (Database, bool)
 	// Close closes the cached time series databases
 	Close()
+	// Shutdown flushes all families of all shards' memory database, waits for the in-progress flushing,
+	// then closes the databases, returns error without closing if not finished within the timeout(0 means no limit)
+	Shutdown(timeout time.Duration) error
 
 	// There are 4 flush policies of the Engine as below:
 	// 1. FullFlush
@@ -146,7 +155,7 @@ func (e *engine) CreateDatabase(databaseName string) (Database, error) {
 				databaseName, cfgPath, err)
 		}
 	}
-	db, err := newDatabase(databaseName, dbPath, cfg)
+	db, err := newDatabase(databaseName, dbPath, cfg, NewExecutorPool(e.cfg.MergeWorkers))
 	if err != nil {
 		return nil, err
 	}
@@ -172,6 +181,79 @@ func (e *engine) Close() {
 	})
 }
 
+func (e *engine) Shutdown(timeout time.Duration) error {
+	// stops the background flushers
+	e.isFullFlushing.Store(true)
+	e.cancel()
+
+	var shards []Shard
+	e.databases.Range(func(key, value interface{}) bool {
+		value.(Database).Range(func(key, value interface{}) bool {
+			shards = append(shards, value.(Shard))
+			return true
+		})
+		return true
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- flushShardsOnShutdown(shards)
+	}()
+	var timeoutCh <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutCh = timer.C
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+	case <-timeoutCh:
+		return fmt.Errorf("flush shards timeout after %s when shutdown", timeout)
+	}
+	e.Close()
+	return nil
+}
+
+// flushShardsOnShutdown flushes the shards concurrently, returns the first error.
+func flushShardsOnShutdown(shards []Shard) error {
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for _, theShard := range shards {
+		wg.Add(1)
+		go func(theShard Shard) {
+			defer wg.Done()
+			if err := flushShardOnShutdown(theShard); err != nil {
+				errOnce.Do(func() { firstErr = err })
+			}
+		}(theShard)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// flushShardOnShutdown waits for the in-progress flushing which may not include the latest written data,
+// then flushes the shard, and waits again in case of another flushing started meanwhile.
+func flushShardOnShutdown(theShard Shard) error {
+	waitShardFlushed(theShard)
+	if err := theShard.Flush(); err != nil {
+		return err
+	}
+	waitShardFlushed(theShard)
+	return nil
+}
+
+// waitShardFlushed waits until the shard is not in flushing
+func waitShardFlushed(theShard Shard) {
+	for theShard.IsFlushing() {
+		time.Sleep(shutdownFlushCheckInterval)
+	}
+}
+
 // load loads the time series engines if exist
 func (e *engine) load() error {
 	databaseNames, err := fileutil.ListDir(e.cfg.Dir)
@@ -291,11 +373,13 @@ func (e *engine) shardMemoryUsageChecker(ctx context.Context) {
 
 func (e *engine) flushBiggestMemoryUsageShard(ctx context.Context) {
 	var (
-		biggestShard   Shard
-		biggestMemSize int
+		biggestShard    Shard
+		biggestMemSize  int
+		biggestDatabase string
+		biggestShardID  int32
 	)
 	// iterate databases;
-	e.databases.Range(func(key, value interface{}) bool {
+	e.databases.Range(func(dbKey, value interface{}) bool {
 		db := value.(Database)
 		// iterate shards
 		db.Range(func(key, value interface{}) bool {
@@ -308,6 +392,8 @@ func (e *engine) flushBiggestMemoryUsageShard(ctx context.Context) {
 			if theShardSize > biggestMemSize {
 				biggestMemSize = theShardSize
 				biggestShard = theShard
+				biggestDatabase, _ = dbKey.(string)
+				biggestShardID, _ = key.(int32)
 			}
 			return true
 		})
@@ -320,6 +406,17 @@ func (e *engine) flushBiggestMemoryUsageShard(ctx context.Context) {
 	if e.isFullFlushing.Load() {
 		return
 	}
+	// logs the metrics holding the most memory of the biggest shard for targeting the cardinality offenders
+	engineLogger.Info("memory usage of shard is high",
+		logger.String("database", biggestDatabase),
+		logger.Int32("shard", biggestShardID),
+		logger.Int64("memSize", int64(biggestMemSize)),
+		logger.Any("topMetrics", biggestShard.MemoryDatabase().MemSizeByMetric(topMemoryMetrics)))
+	// evicts the flushed series of the biggest shard first, which releases memory without flushing
+	targetSize := biggestMemSize * constants.MemoryLowWaterMark / constants.MemoryHighWaterMark
+	if biggestShard.MemoryDatabase().EvictToSize(targetSize) > 0 {
+		return
+	}
 	select {
 	case <-ctx.Done():
 		return
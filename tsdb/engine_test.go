@@ -11,6 +11,8 @@ import (
 	"github.com/lindb/lindb/models"
 	"github.com/lindb/lindb/pkg/fileutil"
 	"github.com/lindb/lindb/pkg/option"
+	"github.com/lindb/lindb/pkg/timeutil"
+	pb "github.com/lindb/lindb/rpc/proto/field"
 	"github.com/lindb/lindb/tsdb/memdb"
 
 	"github.com/golang/mock/gomock"
@@ -103,6 +105,102 @@ func Test_Engine_Close(t *testing.T) {
 	e.Close()
 }
 
+func Test_Engine_Shutdown(t *testing.T) {
+	defer func() {
+		_ = fileutil.RemoveDir(testPath)
+	}()
+
+	e, _ := NewEngine(engineCfg)
+	db, _ := e.CreateDatabase("test_db")
+	assert.NoError(t, db.CreateShards(validOption, 1, 2))
+	for _, shardID := range []int32{1, 2} {
+		theShard, _ := db.GetShard(shardID)
+		for _, timestamp := range []int64{timeutil.Now(), timeutil.Now() - timeutil.OneHour} {
+			assert.NoError(t, theShard.Write(&pb.Metric{
+				Name:      "cpu",
+				Timestamp: timestamp,
+				Fields:    []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}}},
+			}))
+		}
+		assert.True(t, theShard.MemoryDatabase().CountFamilies() > 0)
+	}
+
+	assert.NoError(t, e.Shutdown(time.Second*10))
+	// all families are flushed
+	for _, shardID := range []int32{1, 2} {
+		theShard, _ := db.GetShard(shardID)
+		assert.Equal(t, 0, theShard.MemoryDatabase().CountFamilies())
+	}
+}
+
+func Test_Engine_Shutdown_waitFlushing(t *testing.T) {
+	defer func() {
+		_ = fileutil.RemoveDir(testPath)
+	}()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	e, _ := NewEngine(engineCfg)
+	engineImpl := e.(*engine)
+
+	// waits for the in-progress flushing before flushing
+	flushingShard := NewMockShard(ctrl)
+	gomock.InOrder(
+		flushingShard.EXPECT().IsFlushing().Return(true).Times(2),
+		flushingShard.EXPECT().IsFlushing().Return(false),
+		flushingShard.EXPECT().Flush().Return(nil),
+		flushingShard.EXPECT().IsFlushing().Return(false),
+	)
+	idleShard := NewMockShard(ctrl)
+	idleShard.EXPECT().IsFlushing().Return(false).Times(2)
+	idleShard.EXPECT().Flush().Return(nil)
+	mockDatabase := NewMockDatabase(ctrl)
+	mockDatabase.EXPECT().Range(gomock.Any()).DoAndReturn(func(f func(key, value interface{}) bool) {
+		f(int32(1), flushingShard)
+		f(int32(2), idleShard)
+	})
+	mockDatabase.EXPECT().Close().Return(nil)
+	engineImpl.databases.Store("db", mockDatabase)
+
+	assert.NoError(t, e.Shutdown(0))
+}
+
+func Test_Engine_Shutdown_fail(t *testing.T) {
+	defer func() {
+		_ = fileutil.RemoveDir(testPath)
+	}()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// flush failure, databases are not closed
+	e, _ := NewEngine(engineCfg)
+	mockShard := NewMockShard(ctrl)
+	mockShard.EXPECT().IsFlushing().Return(false)
+	mockShard.EXPECT().Flush().Return(fmt.Errorf("err"))
+	mockDatabase := NewMockDatabase(ctrl)
+	mockDatabase.EXPECT().Range(gomock.Any()).DoAndReturn(func(f func(key, value interface{}) bool) {
+		f(int32(1), mockShard)
+	})
+	e.(*engine).databases.Store("db", mockDatabase)
+	assert.Error(t, e.Shutdown(time.Second))
+
+	// timeout
+	e, _ = NewEngine(engineCfg)
+	flushing := atomic.NewBool(true)
+	mockShard = NewMockShard(ctrl)
+	mockShard.EXPECT().IsFlushing().DoAndReturn(flushing.Load).AnyTimes()
+	mockShard.EXPECT().Flush().Return(nil).AnyTimes()
+	mockDatabase = NewMockDatabase(ctrl)
+	mockDatabase.EXPECT().Range(gomock.Any()).DoAndReturn(func(f func(key, value interface{}) bool) {
+		f(int32(1), mockShard)
+	})
+	e.(*engine).databases.Store("db", mockDatabase)
+	assert.Error(t, e.Shutdown(time.Millisecond*50))
+	// finishes the flushing in background
+	flushing.Store(false)
+	time.Sleep(time.Millisecond * 50)
+}
+
 func Test_Engine_Flush(t *testing.T) {
 	defer func() {
 		_ = fileutil.RemoveDir(testPath)
@@ -233,9 +331,18 @@ func Test_Engine_flushBiggestMemoryUsageShard(t *testing.T) {
 	engineImpl.isFullFlushing.Store(true)
 	e.flushBiggestMemoryUsageShard(engineImpl.ctx)
 
-	// mock biggest-shard available
+	// mock biggest-shard available, evicts flushed series without flushing
 	mockShard.EXPECT().IsFlushing().Return(false).AnyTimes()
 	engineImpl.isFullFlushing.Store(false)
+	// the metrics holding the most memory are logged
+	mockMemoryDatabase.EXPECT().MemSizeByMetric(topMemoryMetrics).
+		Return([]memdb.MetricMemStat{{MetricName: "cpu", MemSize: 1024, TagCount: 10, FieldCount: 2}}).Times(2)
+	mockMemoryDatabase.EXPECT().EvictToSize(1024 * 1024 * 1024 * constants.MemoryLowWaterMark /
+		constants.MemoryHighWaterMark).Return(100)
+	e.flushBiggestMemoryUsageShard(engineImpl.ctx)
+
+	// mock biggest-shard available, nothing evicted
+	mockMemoryDatabase.EXPECT().EvictToSize(gomock.Any()).Return(0)
 	e.flushBiggestMemoryUsageShard(engineImpl.ctx)
 
 	time.Sleep(time.Second)
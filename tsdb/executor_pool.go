@@ -1,8 +1,30 @@
 package tsdb
 
-import "github.com/lindb/lindb/pkg/concurrent"
+import (
+	"runtime"
+	"time"
 
+	"github.com/lindb/lindb/pkg/concurrent"
+)
+
+// idle worker of executor pool is recycled in this duration
+const executorIdleTimeout = 5 * time.Second
+
+// ExecutorPool represents the worker pools for querying task,
+// the scanners read the data, the mergers aggregate the scanned data.
 type ExecutorPool struct {
 	Scanners concurrent.Pool
 	Mergers  concurrent.Pool
 }
+
+// NewExecutorPool creates the executor pool with the max number of merge workers,
+// the number of CPUs is used if mergeWorkers <= 0.
+func NewExecutorPool(mergeWorkers int) *ExecutorPool {
+	if mergeWorkers <= 0 {
+		mergeWorkers = runtime.NumCPU()
+	}
+	return &ExecutorPool{
+		Scanners: concurrent.NewPool(runtime.NumCPU(), executorIdleTimeout),
+		Mergers:  concurrent.NewPool(mergeWorkers, executorIdleTimeout),
+	}
+}
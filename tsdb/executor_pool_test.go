@@ -0,0 +1,49 @@
+package tsdb
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+)
+
+func TestNewExecutorPool(t *testing.T) {
+	pool := NewExecutorPool(2)
+	defer func() {
+		pool.Scanners.Stop()
+		pool.Mergers.Stop()
+	}()
+
+	var (
+		running    atomic.Int32
+		maxRunning atomic.Int32
+		wg         sync.WaitGroup
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		pool.Mergers.Submit(func() {
+			defer wg.Done()
+			n := running.Inc()
+			for {
+				max := maxRunning.Load()
+				if n <= max || maxRunning.CAS(max, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			running.Dec()
+		})
+	}
+	wg.Wait()
+	// concurrent merges are bounded by the configured worker count
+	assert.True(t, maxRunning.Load() <= 2)
+	assert.True(t, maxRunning.Load() > 0)
+
+	// default worker count
+	pool2 := NewExecutorPool(0)
+	assert.NotNil(t, pool2.Mergers)
+	pool2.Scanners.Stop()
+	pool2.Mergers.Stop()
+}
@@ -127,3 +127,53 @@ func (db *indexDatabase) GetSeriesIDsForTag(
 	}
 	return invertedindex.NewReader(readers).GetSeriesIDsForTagKeyID(tagKeyID, timeRange)
 }
+
+// GetSeriesIDsForMetric gets all the series ids for spec metric by unioning the series ids of all its tag keys,
+// including the empty tag key of series without tags, the tag keys not flushed yet are skipped.
+func (db *indexDatabase) GetSeriesIDsForMetric(
+	metricID uint32,
+	timeRange timeutil.TimeRange,
+) (
+	*series.MultiVerSeriesIDSet,
+	error,
+) {
+	tagKeys, err := db.idGetter.GetTagKeys(metricID)
+	if err != nil {
+		return nil, err
+	}
+	snapShot := db.invertedIndexFamily.GetSnapshot()
+	defer snapShot.Close()
+
+	readers, err := snapShot.FindReaders(metricID)
+	if err != nil {
+		return nil, err
+	}
+	reader := invertedindex.NewReader(readers)
+	unionIDSet := series.NewMultiVerSeriesIDSet()
+	// series without tags are indexed by the empty tag key,
+	// which is not found if the metric has no such series
+	if tagKeyID, err := db.idGetter.GetTagKeyID(metricID, ""); err == nil {
+		idSet, err := reader.GetSeriesIDsForTagKeyID(tagKeyID, timeRange)
+		switch {
+		case err == nil:
+			unionIDSet.Or(idSet)
+		case err != series.ErrNotFound:
+			return nil, err
+		}
+	}
+	for _, tagKey := range tagKeys {
+		tagKeyID, err := db.idGetter.GetTagKeyID(metricID, tagKey)
+		if err != nil {
+			return nil, err
+		}
+		idSet, err := reader.GetSeriesIDsForTagKeyID(tagKeyID, timeRange)
+		if err == series.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		unionIDSet.Or(idSet)
+	}
+	return unionIDSet, nil
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/lindb/lindb/kv/table"
 	"github.com/lindb/lindb/kv/version"
 	"github.com/lindb/lindb/pkg/timeutil"
+	"github.com/lindb/lindb/series"
 	"github.com/lindb/lindb/tsdb/metadb"
+	"github.com/lindb/lindb/tsdb/tblstore/invertedindex"
 
 	"github.com/RoaringBitmap/roaring"
 	"github.com/golang/mock/gomock"
@@ -150,3 +152,53 @@ func Test_IndexDatabase_GetSeriesIDsForTag(t *testing.T) {
 	_, err = mockedDB.indexDatabase.GetSeriesIDsForTag(0, "", timeutil.TimeRange{})
 	assert.NotNil(t, err)
 }
+
+func Test_IndexDatabase_GetSeriesIDsForMetric(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedDB := mockIndexDatabase(ctrl)
+
+	// case1: GetTagKeys failed
+	mockedDB.idGetter.EXPECT().GetTagKeys(uint32(1)).Return(nil, fmt.Errorf("error"))
+	set, err := mockedDB.indexDatabase.GetSeriesIDsForMetric(1, timeutil.TimeRange{})
+	assert.Nil(t, set)
+	assert.NotNil(t, err)
+	// case2: snapshot FindReaders error
+	mockedDB.idGetter.EXPECT().GetTagKeys(uint32(1)).Return([]string{"host", "zone"}, nil).AnyTimes()
+	mockedDB.WithFindReadersError()
+	_, err = mockedDB.indexDatabase.GetSeriesIDsForMetric(1, timeutil.TimeRange{})
+	assert.NotNil(t, err)
+	// case3: GetTagKeyID failed, metric has no series without tags
+	mockedDB.WithFindReadersOK()
+	mockedDB.idGetter.EXPECT().GetTagKeyID(uint32(1), "").Return(uint32(0), series.ErrNotFound)
+	mockedDB.idGetter.EXPECT().GetTagKeyID(uint32(1), "host").Return(uint32(0), fmt.Errorf("error"))
+	_, err = mockedDB.indexDatabase.GetSeriesIDsForMetric(1, timeutil.TimeRange{})
+	assert.NotNil(t, err)
+	// case4: tag keys not flushed are skipped
+	mockedDB.WithFindReadersOK()
+	mockedDB.idGetter.EXPECT().GetTagKeyID(uint32(1), "").Return(uint32(3), nil)
+	mockedDB.idGetter.EXPECT().GetTagKeyID(uint32(1), "host").Return(uint32(1), nil)
+	mockedDB.idGetter.EXPECT().GetTagKeyID(uint32(1), "zone").Return(uint32(2), nil)
+	mockedDB.reader.EXPECT().Get(gomock.Any()).Return(nil).Times(3)
+	set, err = mockedDB.indexDatabase.GetSeriesIDsForMetric(1, timeutil.TimeRange{})
+	assert.Nil(t, err)
+	assert.True(t, set.IsEmpty())
+	// case5: series without tags are included
+	nopKVFlusher := kv.NewNopFlusher()
+	indexFlusher := invertedindex.NewFlusher(nopKVFlusher)
+	version := series.Version(1500000000000)
+	indexFlusher.FlushVersion(version, timeutil.TimeRange{Start: version.Int64() + 1000, End: version.Int64() + 2000},
+		roaring.BitmapOf(5))
+	indexFlusher.FlushTagValue("")
+	assert.NoError(t, indexFlusher.FlushTagKeyID(3))
+	mockedDB.WithFindReadersOK()
+	mockedDB.idGetter.EXPECT().GetTagKeyID(uint32(1), "").Return(uint32(3), nil)
+	mockedDB.idGetter.EXPECT().GetTagKeyID(uint32(1), "host").Return(uint32(1), nil)
+	mockedDB.idGetter.EXPECT().GetTagKeyID(uint32(1), "zone").Return(uint32(2), nil)
+	mockedDB.reader.EXPECT().Get(uint32(3)).Return(nopKVFlusher.Bytes())
+	mockedDB.reader.EXPECT().Get(gomock.Any()).Return(nil).Times(2)
+	set, err = mockedDB.indexDatabase.GetSeriesIDsForMetric(1,
+		timeutil.TimeRange{Start: version.Int64(), End: version.Int64() + 3000})
+	assert.Nil(t, err)
+	assert.Equal(t, roaring.BitmapOf(5), set.Versions()[version])
+}
@@ -23,9 +23,10 @@ type IntervalSegment interface {
 
 // intervalSegment implements IntervalSegment interface
 type intervalSegment struct {
-	path     string
-	interval timeutil.Interval
-	segments sync.Map
+	path            string
+	interval        timeutil.Interval
+	flushBufferSize int // write buffer size of flushed data file
+	segments        sync.Map
 
 	mutex sync.Mutex
 }
@@ -34,6 +35,7 @@ type intervalSegment struct {
 func newIntervalSegment(
 	interval timeutil.Interval,
 	path string,
+	flushBufferSize int,
 ) (
 	segment IntervalSegment,
 	err error,
@@ -42,8 +44,9 @@ func newIntervalSegment(
 		return segment, err
 	}
 	intervalSegment := &intervalSegment{
-		path:     path,
-		interval: interval,
+		path:            path,
+		interval:        interval,
+		flushBufferSize: flushBufferSize,
 	}
 
 	defer func() {
@@ -60,7 +63,8 @@ func newIntervalSegment(
 		return segment, err
 	}
 	for _, segmentName := range segmentNames {
-		seg, err := newSegment(segmentName, intervalSegment.interval, filepath.Join(path, segmentName))
+		seg, err := newSegment(segmentName, intervalSegment.interval, filepath.Join(path, segmentName),
+			intervalSegment.flushBufferSize)
 		if err != nil {
 			err = fmt.Errorf("create segmenet error: %s", err)
 			return segment, err
@@ -82,7 +86,7 @@ func (s *intervalSegment) GetOrCreateSegment(segmentName string) (Segment, error
 		defer s.mutex.Unlock()
 		segment, ok = s.getSegment(segmentName)
 		if !ok {
-			seg, err := newSegment(segmentName, s.interval, filepath.Join(s.path, segmentName))
+			seg, err := newSegment(segmentName, s.interval, filepath.Join(s.path, segmentName), s.flushBufferSize)
 			if err != nil {
 				return nil, fmt.Errorf("create segmenet error: %s", err)
 			}
@@ -14,7 +14,7 @@ func TestNewIntervalSegment(t *testing.T) {
 	defer func() {
 		_ = fileutil.RemoveDir(testPath)
 	}()
-	s, err := newIntervalSegment(timeutil.Interval(timeutil.OneSecond*10), segPath)
+	s, err := newIntervalSegment(timeutil.Interval(timeutil.OneSecond*10), segPath, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -26,12 +26,13 @@ func TestNewIntervalSegment(t *testing.T) {
 	_, err = newSegment(
 		"20190903",
 		timeutil.Interval(timeutil.OneSecond*10),
-		filepath.Join(segPath, "20190903"))
+		filepath.Join(segPath, "20190903"),
+		0)
 	if err != nil {
 		t.Fatal(err)
 	}
 	// cannot re-open kv-store
-	s, err = newIntervalSegment(timeutil.Interval(timeutil.OneSecond*10), segPath)
+	s, err = newIntervalSegment(timeutil.Interval(timeutil.OneSecond*10), segPath, 0)
 	assert.Nil(t, s)
 	assert.NotNil(t, err)
 
@@ -41,7 +42,7 @@ func TestIntervalSegment_GetOrCreateSegment(t *testing.T) {
 	defer func() {
 		_ = fileutil.RemoveDir(testPath)
 	}()
-	s, _ := newIntervalSegment(timeutil.Interval(timeutil.OneSecond*10), segPath)
+	s, _ := newIntervalSegment(timeutil.Interval(timeutil.OneSecond*10), segPath, 0)
 	seg, err := s.GetOrCreateSegment("20190702")
 	assert.Nil(t, err)
 	assert.NotNil(t, seg)
@@ -60,7 +61,7 @@ func TestIntervalSegment_GetOrCreateSegment(t *testing.T) {
 
 	s.Close()
 
-	s, _ = newIntervalSegment(timeutil.Interval(timeutil.OneSecond*10), segPath)
+	s, _ = newIntervalSegment(timeutil.Interval(timeutil.OneSecond*10), segPath, 0)
 
 	s1, ok := s.(*intervalSegment)
 	if ok {
@@ -77,7 +78,7 @@ func TestIntervalSegment_getDataFamilies(t *testing.T) {
 	defer func() {
 		_ = fileutil.RemoveDir(testPath)
 	}()
-	s, _ := newIntervalSegment(timeutil.Interval(timeutil.OneSecond*10), segPath)
+	s, _ := newIntervalSegment(timeutil.Interval(timeutil.OneSecond*10), segPath, 0)
 	segment1, _ := s.GetOrCreateSegment("20190902")
 	now, _ := timeutil.ParseTimestamp("20190902 19:10:48", "20060102 15:04:05")
 	_, _ = segment1.GetDataFamily(now)
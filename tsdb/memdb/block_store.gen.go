@@ -15,15 +15,19 @@ import (
 	"github.com/lindb/lindb/series/field"
 )
 
-// intBlock represents a int block for storing metric point in memory
+// intBlock represents a int block for storing metric point in memory,
+// the slot values are stored in a dense array or a sparse map based on the slot density.
 type intBlock struct {
 	container
-	values []int64
+	size         int
+	values       []int64
+	sparseValues map[int]int64
 }
 
 // newIntBlock returns int block with fixed time window
 func newIntBlock(size int) *intBlock {
 	return &intBlock{
+		size:   size,
 		values: make([]int64, size),
 	}
 }
@@ -31,17 +35,51 @@ func newIntBlock(size int) *intBlock {
 // setIntValue updates int64 value with pos
 func (b *intBlock) setIntValue(pos int, value int64) {
 	b.setValue(pos)
+	if b.sparseValues != nil {
+		b.sparseValues[pos] = value
+		return
+	}
 	b.values[pos] = value
 }
 
 // getIntValue return int64 value for pos
 func (b *intBlock) getIntValue(pos int) int64 {
+	if b.sparseValues != nil {
+		return b.sparseValues[pos]
+	}
 	return b.values[pos]
 }
 
+// isSparse returns if the slot values are stored in sparse map
+func (b *intBlock) isSparse() bool {
+	return b.sparseValues != nil
+}
+
+// setSparse switches the slot values between dense array and sparse map,
+// NOTICE: must be called when current buffer is empty, values in buffer will be dropped
+func (b *intBlock) setSparse(sparse bool) {
+	switch {
+	case sparse && b.sparseValues == nil:
+		b.sparseValues = make(map[int]int64)
+		b.values = nil
+	case !sparse && b.sparseValues != nil:
+		b.sparseValues = nil
+		b.values = make([]int64, b.size)
+	case sparse:
+		// drop the values of last time window
+		for pos := range b.sparseValues {
+			delete(b.sparseValues, pos)
+		}
+	}
+}
+
 // memsize returns the memory size in bytes count
 func (b *intBlock) memsize() int {
-	return b.container.memsize() + 24 + cap(b.values)*8
+	size := b.container.memsize() + 8 + 24 + 8 // size + values + sparse values
+	if b.sparseValues != nil {
+		return size + emptySparseValuesSize + len(b.sparseValues)*sparseValueEntrySize
+	}
+	return size + cap(b.values)*8
 }
 
 // compact compress block data
@@ -60,7 +98,7 @@ func (b *intBlock) compact(aggFunc field.AggFunc) (start, end int, err error) {
 			idx := i - start
 			if b.hasValue(idx) {
 				encode.AppendTime(bit.One)
-				encode.AppendValue(encoding.ZigZagEncode(b.values[idx]))
+				encode.AppendValue(encoding.ZigZagEncode(b.getIntValue(idx)))
 			} else {
 				encode.AppendTime(bit.Zero)
 			}
@@ -80,13 +118,13 @@ func (b *intBlock) compact(aggFunc field.AggFunc) (start, end int, err error) {
 				encode.AppendTime(bit.Zero)
 			case appendNew:
 				encode.AppendTime(bit.One)
-				encode.AppendValue(encoding.ZigZagEncode(b.values[idx]))
+				encode.AppendValue(encoding.ZigZagEncode(b.getIntValue(idx)))
 			case appendOld:
 				encode.AppendTime(bit.One)
 				encode.AppendValue(oldValue)
 			case mergeType:
 				encode.AppendTime(bit.One)
-				encode.AppendValue(encoding.ZigZagEncode(aggFunc.AggregateInt(b.values[idx], encoding.ZigZagDecode(oldValue))))
+				encode.AppendValue(encoding.ZigZagEncode(aggFunc.AggregateInt(b.getIntValue(idx), encoding.ZigZagDecode(oldValue))))
 			}
 		}
 		scanner.scan()
@@ -150,25 +188,67 @@ func (b *intBlock) scan(
 	}
 }
 
+// scanSlot reads the value of the single time slot directly, then aggregates it,
+// skips iterating the whole time window of block data.
+func (b *intBlock) scanSlot(
+	slot int,
+	aggFunc field.AggFunc,
+	agg []aggregation.PrimitiveAggregator,
+	memScanCtx *memScanContext,
+) {
+	newSlot := slot - b.startTime
+	hasNew := b.container.container != 0 && newSlot >= 0 && slot <= b.getEndTime() && b.hasValue(newSlot)
+	hasOld := false
+	var oldValue uint64
+	if len(b.compress) > 0 {
+		tsd := memScanCtx.tsd
+		tsd.Reset(b.compress)
+		if isInRange(slot, tsd.StartTime(), tsd.EndTime()) {
+			// values are decoded sequentially, so reads the values before the slot too
+			for tsd.Error() == nil && tsd.Next() {
+				hasValue := tsd.HasValue()
+				if tsd.Slot() == slot {
+					if hasValue {
+						hasOld = true
+						oldValue = tsd.Value()
+					}
+					break
+				}
+				if hasValue {
+					_ = tsd.Value()
+				}
+			}
+		}
+	}
+	var value float64
+	switch {
+	case hasNew && hasOld:
+		value, _ = b.slotValue(merge, newSlot, oldValue, aggFunc)
+	case hasNew:
+		value, _ = b.slotValue(appendNew, newSlot, 0, aggFunc)
+	case hasOld:
+		value, _ = b.slotValue(appendOld, slot, oldValue, aggFunc)
+	default:
+		return
+	}
+	for _, a := range agg {
+		a.Aggregate(slot, value)
+	}
+}
+
 // aggregate aggregates the value with index
 func (b *intBlock) aggregate(mergeType mergeType, idx int, oldValue uint64,
 	aggFunc field.AggFunc,
 	agg []aggregation.PrimitiveAggregator,
 ) (completed bool) {
-	value := 0.0
 	// 1. get value and time slot
-	switch mergeType {
-	case appendOld:
-		value = float64(encoding.ZigZagDecode(oldValue))
-	case appendNew:
-		value = float64(b.values[idx])
-		idx += b.startTime
-	case merge:
-		value = float64(aggFunc.AggregateInt(b.values[idx], encoding.ZigZagDecode(oldValue)))
-		idx += b.startTime
-	default:
+	value, ok := b.slotValue(mergeType, idx, oldValue, aggFunc)
+	if !ok {
 		return
 	}
+	if mergeType != appendOld {
+		idx += b.startTime
+	}
 	// 2. aggregate the value based on time slot
 	for _, a := range agg {
 		completed = a.Aggregate(idx, value)
@@ -176,6 +256,23 @@ func (b *intBlock) aggregate(mergeType mergeType, idx int, oldValue uint64,
 	return
 }
 
+// slotValue returns the value with index based on merge type
+func (b *intBlock) slotValue(mergeType mergeType, idx int, oldValue uint64,
+	aggFunc field.AggFunc,
+) (value float64, ok bool) {
+	switch mergeType {
+	case appendOld:
+		value = float64(encoding.ZigZagDecode(oldValue))
+	case appendNew:
+		value = float64(b.getIntValue(idx))
+	case merge:
+		value = float64(aggFunc.AggregateInt(b.getIntValue(idx), encoding.ZigZagDecode(oldValue)))
+	default:
+		return 0, false
+	}
+	return value, true
+}
+
 // intBlockMergeScanner represents the scanner which scans the block store current buffer data and compress data
 type intBlockMergeScanner struct {
 	block            *intBlock            // current block
@@ -267,15 +364,19 @@ func (s *intBlockMergeScanner) merge(hasValue bool, hasOldValue bool, newSlot in
 	}
 }
 
-// floatBlock represents a float block for storing metric point in memory
+// floatBlock represents a float block for storing metric point in memory,
+// the slot values are stored in a dense array or a sparse map based on the slot density.
 type floatBlock struct {
 	container
-	values []float64
+	size         int
+	values       []float64
+	sparseValues map[int]float64
 }
 
 // newFloatBlock returns float block with fixed time window
 func newFloatBlock(size int) *floatBlock {
 	return &floatBlock{
+		size:   size,
 		values: make([]float64, size),
 	}
 }
@@ -283,17 +384,51 @@ func newFloatBlock(size int) *floatBlock {
 // setFloatValue updates float64 value with pos
 func (b *floatBlock) setFloatValue(pos int, value float64) {
 	b.setValue(pos)
+	if b.sparseValues != nil {
+		b.sparseValues[pos] = value
+		return
+	}
 	b.values[pos] = value
 }
 
 // getFloatValue return float64 value for pos
 func (b *floatBlock) getFloatValue(pos int) float64 {
+	if b.sparseValues != nil {
+		return b.sparseValues[pos]
+	}
 	return b.values[pos]
 }
 
+// isSparse returns if the slot values are stored in sparse map
+func (b *floatBlock) isSparse() bool {
+	return b.sparseValues != nil
+}
+
+// setSparse switches the slot values between dense array and sparse map,
+// NOTICE: must be called when current buffer is empty, values in buffer will be dropped
+func (b *floatBlock) setSparse(sparse bool) {
+	switch {
+	case sparse && b.sparseValues == nil:
+		b.sparseValues = make(map[int]float64)
+		b.values = nil
+	case !sparse && b.sparseValues != nil:
+		b.sparseValues = nil
+		b.values = make([]float64, b.size)
+	case sparse:
+		// drop the values of last time window
+		for pos := range b.sparseValues {
+			delete(b.sparseValues, pos)
+		}
+	}
+}
+
 // memsize returns the memory size in bytes count
 func (b *floatBlock) memsize() int {
-	return b.container.memsize() + 24 + cap(b.values)*8
+	size := b.container.memsize() + 8 + 24 + 8 // size + values + sparse values
+	if b.sparseValues != nil {
+		return size + emptySparseValuesSize + len(b.sparseValues)*sparseValueEntrySize
+	}
+	return size + cap(b.values)*8
 }
 
 // compact compress block data
@@ -312,7 +447,7 @@ func (b *floatBlock) compact(aggFunc field.AggFunc) (start, end int, err error)
 			idx := i - start
 			if b.hasValue(idx) {
 				encode.AppendTime(bit.One)
-				encode.AppendValue(math.Float64bits(b.values[idx]))
+				encode.AppendValue(math.Float64bits(b.getFloatValue(idx)))
 			} else {
 				encode.AppendTime(bit.Zero)
 			}
@@ -332,13 +467,13 @@ func (b *floatBlock) compact(aggFunc field.AggFunc) (start, end int, err error)
 				encode.AppendTime(bit.Zero)
 			case appendNew:
 				encode.AppendTime(bit.One)
-				encode.AppendValue(math.Float64bits(b.values[idx]))
+				encode.AppendValue(math.Float64bits(b.getFloatValue(idx)))
 			case appendOld:
 				encode.AppendTime(bit.One)
 				encode.AppendValue(oldValue)
 			case mergeType:
 				encode.AppendTime(bit.One)
-				encode.AppendValue(math.Float64bits(aggFunc.AggregateFloat(b.values[idx], math.Float64frombits(oldValue))))
+				encode.AppendValue(math.Float64bits(aggFunc.AggregateFloat(b.getFloatValue(idx), math.Float64frombits(oldValue))))
 			}
 		}
 		scanner.scan()
@@ -402,25 +537,67 @@ func (b *floatBlock) scan(
 	}
 }
 
+// scanSlot reads the value of the single time slot directly, then aggregates it,
+// skips iterating the whole time window of block data.
+func (b *floatBlock) scanSlot(
+	slot int,
+	aggFunc field.AggFunc,
+	agg []aggregation.PrimitiveAggregator,
+	memScanCtx *memScanContext,
+) {
+	newSlot := slot - b.startTime
+	hasNew := b.container.container != 0 && newSlot >= 0 && slot <= b.getEndTime() && b.hasValue(newSlot)
+	hasOld := false
+	var oldValue uint64
+	if len(b.compress) > 0 {
+		tsd := memScanCtx.tsd
+		tsd.Reset(b.compress)
+		if isInRange(slot, tsd.StartTime(), tsd.EndTime()) {
+			// values are decoded sequentially, so reads the values before the slot too
+			for tsd.Error() == nil && tsd.Next() {
+				hasValue := tsd.HasValue()
+				if tsd.Slot() == slot {
+					if hasValue {
+						hasOld = true
+						oldValue = tsd.Value()
+					}
+					break
+				}
+				if hasValue {
+					_ = tsd.Value()
+				}
+			}
+		}
+	}
+	var value float64
+	switch {
+	case hasNew && hasOld:
+		value, _ = b.slotValue(merge, newSlot, oldValue, aggFunc)
+	case hasNew:
+		value, _ = b.slotValue(appendNew, newSlot, 0, aggFunc)
+	case hasOld:
+		value, _ = b.slotValue(appendOld, slot, oldValue, aggFunc)
+	default:
+		return
+	}
+	for _, a := range agg {
+		a.Aggregate(slot, value)
+	}
+}
+
 // aggregate aggregates the value with index
 func (b *floatBlock) aggregate(mergeType mergeType, idx int, oldValue uint64,
 	aggFunc field.AggFunc,
 	agg []aggregation.PrimitiveAggregator,
 ) (completed bool) {
-	value := 0.0
 	// 1. get value and time slot
-	switch mergeType {
-	case appendOld:
-		value = math.Float64frombits(oldValue)
-	case appendNew:
-		value = b.values[idx]
-		idx += b.startTime
-	case merge:
-		value = aggFunc.AggregateFloat(b.values[idx], math.Float64frombits(oldValue))
-		idx += b.startTime
-	default:
+	value, ok := b.slotValue(mergeType, idx, oldValue, aggFunc)
+	if !ok {
 		return
 	}
+	if mergeType != appendOld {
+		idx += b.startTime
+	}
 	// 2. aggregate the value based on time slot
 	for _, a := range agg {
 		completed = a.Aggregate(idx, value)
@@ -428,6 +605,23 @@ func (b *floatBlock) aggregate(mergeType mergeType, idx int, oldValue uint64,
 	return
 }
 
+// slotValue returns the value with index based on merge type
+func (b *floatBlock) slotValue(mergeType mergeType, idx int, oldValue uint64,
+	aggFunc field.AggFunc,
+) (value float64, ok bool) {
+	switch mergeType {
+	case appendOld:
+		value = math.Float64frombits(oldValue)
+	case appendNew:
+		value = b.getFloatValue(idx)
+	case merge:
+		value = aggFunc.AggregateFloat(b.getFloatValue(idx), math.Float64frombits(oldValue))
+	default:
+		return 0, false
+	}
+	return value, true
+}
+
 // floatBlockMergeScanner represents the scanner which scans the block store current buffer data and compress data
 type floatBlockMergeScanner struct {
 	block            *floatBlock          // current block
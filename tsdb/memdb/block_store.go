@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/lindb/lindb/aggregation"
+	"github.com/lindb/lindb/pkg/encoding"
 	"github.com/lindb/lindb/series/field"
 )
 
@@ -23,14 +24,37 @@ const (
 // the longest length of basic-variable on x64 platform
 const maxTimeWindow = 64
 
+// SlotStrategy represents the strategy of storing the slot values of block
+type SlotStrategy uint8
+
+// Defines all slot strategies of block
+const (
+	// SlotAuto chooses dense array or sparse map based on the observed slot density of last time window
+	SlotAuto SlotStrategy = iota
+	// SlotDense always stores slot values in dense array
+	SlotDense
+	// SlotSparse always stores slot values in sparse map
+	SlotSparse
+)
+
+// defaultSparseThreshold is the slot density below which the block switches to sparse map
+const defaultSparseThreshold = 0.25
+
+const (
+	emptySparseValuesSize = 48        // map header
+	sparseValueEntrySize  = 8 + 8 + 1 // key + value + top hash
+)
+
 // define mergeFunc func for merging block store value and compress value
 type mergeFunc func(mergeType mergeType, idx int, oldValue uint64)
 
 // blockStore represents a pool of block for reuse
 type blockStore struct {
-	timeWindow     int
-	intBlockPool   sync.Pool
-	floatBlockPool sync.Pool
+	timeWindow      int
+	slotStrategy    SlotStrategy
+	sparseThreshold float64
+	intBlockPool    sync.Pool
+	floatBlockPool  sync.Pool
 }
 
 // newBlockStore returns a pool of block with fixed time window
@@ -40,7 +64,9 @@ func newBlockStore(timeWindow int) *blockStore {
 		tw = maxTimeWindow
 	}
 	return &blockStore{
-		timeWindow: tw,
+		timeWindow:      tw,
+		slotStrategy:    SlotAuto,
+		sparseThreshold: defaultSparseThreshold,
 		intBlockPool: sync.Pool{
 			New: func() interface{} {
 				return newIntBlock(tw)
@@ -65,15 +91,33 @@ func (bs *blockStore) freeBlock(block block) {
 	}
 }
 
+// allocBlock alloc block from pool based on value type, the slot values are stored in sparse map
+// only if the slot strategy is sparse, because no density is observed for new block.
 func (bs *blockStore) allocBlock(valueType field.ValueType) block {
+	var b block
 	switch valueType {
 	case field.Integer:
-		return bs.allocIntBlock()
+		b = bs.allocIntBlock()
 	case field.Float:
-		return bs.allocFloatBlock()
+		b = bs.allocFloatBlock()
 	default:
 		return nil
 	}
+	b.setSparse(bs.slotStrategy == SlotSparse)
+	return b
+}
+
+// useSparse returns if the block should store slot values in sparse map,
+// based on the slot count of last time window.
+func (bs *blockStore) useSparse(slotCount int) bool {
+	switch bs.slotStrategy {
+	case SlotDense:
+		return false
+	case SlotSparse:
+		return true
+	default:
+		return float64(slotCount) < bs.sparseThreshold*float64(bs.timeWindow)
+	}
 }
 
 // allocIntBlock alloc int block from pool
@@ -103,6 +147,14 @@ type block interface {
 	setFloatValue(pos int, value float64)
 	// getFloatValue returns float64 value for pos
 	getFloatValue(pos int) float64
+	// slotCount returns the count of slots which has value in current buffer
+	slotCount() int
+	// forEachSlot calls fn with the time slot which has value in current buffer or compress data
+	forEachSlot(tsd *encoding.TSDDecoder, fn func(slot int))
+	// isSparse returns if the slot values are stored in sparse map
+	isSparse() bool
+	// setSparse switches the slot values between dense array and sparse map
+	setSparse(sparse bool)
 	// setStartTime sets start time slot
 	setStartTime(startTime int)
 	// getStartTime returns start time slot
@@ -119,6 +171,8 @@ type block interface {
 	memsize() int
 	// scan scans block data, then aggregates the data
 	scan(aggFunc field.AggFunc, agg []aggregation.PrimitiveAggregator, memScanCtx *memScanContext)
+	// scanSlot reads the value of the single time slot, then aggregates it
+	scanSlot(slot int, aggFunc field.AggFunc, agg []aggregation.PrimitiveAggregator, memScanCtx *memScanContext)
 }
 
 const (
@@ -146,6 +200,35 @@ func (c *container) setValue(pos int) {
 	c.container |= 1 << uint64(maxTimeWindow-pos-1)
 }
 
+// slotCount returns the count of slots which has value
+func (c *container) slotCount() int {
+	return bits.OnesCount64(c.container)
+}
+
+// forEachSlot calls fn with the time slot which has value in current buffer or compress data,
+// the slot may be called twice if it exists in both of them.
+func (c *container) forEachSlot(tsd *encoding.TSDDecoder, fn func(slot int)) {
+	if len(c.compress) > 0 {
+		tsd.Reset(c.compress)
+		for tsd.Error() == nil && tsd.Next() {
+			if tsd.HasValue() {
+				// reads the value for decoding the next slot
+				_ = tsd.Value()
+				fn(tsd.Slot())
+			}
+		}
+	}
+	if c.container == 0 {
+		return
+	}
+	end := c.getEndTime() - c.startTime
+	for i := 0; i <= end; i++ {
+		if c.hasValue(i) {
+			fn(c.startTime + i)
+		}
+	}
+}
+
 // setStartTime sets start time slot
 func (c *container) setStartTime(startTime int) {
 	c.startTime = startTime
@@ -355,6 +355,36 @@ func TestContainer_Get_Set(t *testing.T) {
 	assert.Equal(t, int64(0), c.getIntValue(10))
 }
 
+func TestContainer_forEachSlot(t *testing.T) {
+	bs := newBlockStore(30)
+	b1 := bs.allocIntBlock()
+	tsd := encoding.GetTSDDecoder()
+	defer encoding.ReleaseTSDDecoder(tsd)
+	var slots []int
+	collect := func(slot int) {
+		slots = append(slots, slot)
+	}
+	// no data
+	b1.forEachSlot(tsd, collect)
+	assert.Empty(t, slots)
+
+	// only current buf has data
+	b1.setStartTime(10)
+	b1.setIntValue(0, int64(100))
+	b1.setIntValue(2, int64(120))
+	b1.forEachSlot(tsd, collect)
+	assert.Equal(t, []int{10, 12}, slots)
+
+	// compress data and current buf
+	_, _, err := b1.compact(field.Sum.AggFunc())
+	assert.Nil(t, err)
+	b1.setStartTime(20)
+	b1.setIntValue(5, int64(250))
+	slots = slots[:0]
+	b1.forEachSlot(tsd, collect)
+	assert.Equal(t, []int{10, 12, 25}, slots)
+}
+
 func TestIntBlock_scan(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
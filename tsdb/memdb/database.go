@@ -3,10 +3,16 @@ package memdb
 import (
 	"context"
 	"fmt"
+	"io"
+	"regexp"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/lindb/lindb/constants"
 	"github.com/lindb/lindb/pkg/logger"
+	"github.com/lindb/lindb/pkg/option"
 	"github.com/lindb/lindb/pkg/timeutil"
 	pb "github.com/lindb/lindb/rpc/proto/field"
 	"github.com/lindb/lindb/series"
@@ -23,6 +29,15 @@ import (
 
 var memDBLogger = logger.GetLogger("tsdb", "MemDB")
 
+const (
+	// highCardinalityRatio is the ratio of used tags to max tags limit which triggers the high cardinality callback
+	highCardinalityRatio = 0.8
+	// highCardinalityChSize is the buffer size of pending high cardinality notifications
+	highCardinalityChSize = 64
+	// defaultEvictInterval is the default interval of evicting periodically
+	defaultEvictInterval = time.Minute
+)
+
 //go:generate mockgen -source ./database.go -destination=./database_mock.go -package memdb
 
 // MemoryDatabase is a database-like concept of Shard as memTable in cassandra.
@@ -32,17 +47,46 @@ type MemoryDatabase interface {
 	// key: metric-name, value: max-limit
 	WithMaxTagsLimit(<-chan map[string]uint32)
 	// Write writes metrics to the memory-database,
-	// return error on exceeding max count of tagsIdentifier or writing failure
+	// return error on exceeding max count of tagsIdentifier or writing failure,
+	// the error is *series.WriteError classifying the underlying error
 	Write(metric *pb.Metric) error
+	// WriteWithHash writes metrics with the precomputed hash of metric name for skipping hashing, such as replaying,
+	// the hash is validated against the metric name if ValidateMetricHash is enabled
+	WriteWithHash(metric *pb.Metric, hash uint64) error
+	// WriteBatch writes the metrics grouped by bucket, the lock of each bucket is acquired only once,
+	// returns the count of written metrics and the first error, the metrics after failure are still written
+	WriteBatch(metrics []*pb.Metric) (written int, err error)
+	// Validate checks whether the metric would be accepted by Write, such as timestamp window, cardinality limit
+	// and field types of existing metric, without changing the memory-database or generating any ids,
+	// the error is *series.WriteError classifying the underlying error
+	Validate(metric *pb.Metric) error
+	// DeleteTagValues deletes the tag values of tag key matching the regular expression pattern across the metric,
+	// removes their series in memory for cleaning up cardinality explosion, returns the count of deleted series
+	DeleteTagValues(metricName, tagKey, pattern string) (deletedSeries int, err error)
+	// SetMaxTagsLimit sets the max tags limit of the metric at runtime, returns the new effective limit,
+	// the limit may be overwritten by the limitations of WithMaxTagsLimit later
+	SetMaxTagsLimit(metricName string, limit uint32) (effectiveLimit uint32, err error)
+	// SetMaxTagKeysLimit sets the max count of tag keys of the metric at runtime, returns the new effective limit,
+	// the write introducing a new tag key beyond the limit is rejected, the existing tag keys are still writable
+	SetMaxTagKeysLimit(metricName string, limit uint32) (effectiveLimit uint32, err error)
 	// ResetMetricStore reassigns a new version to metricStore
 	// This method provides the ability to reset the tsStore in memory for skipping the tsID-limitation
 	ResetMetricStore(metricName string) error
+	// DeleteMetric drops the metric store of the metric from memory for reclaiming memory immediately,
+	// the data not flushed yet is discarded, returns error if the metric doesn't exist
+	DeleteMetric(metricName string) error
 	// CountMetrics returns the metrics-count of the memory-database
 	CountMetrics() int
 	// CountTags returns the tags-count of the metricName, return -1 if not exist
 	CountTags(metricName string) int
+	// CountFields returns the fields-count of the metricName, return -1 if not exist
+	CountFields(metricName string) int
+	// MetricInterval returns the interval of the metric, the per-metric interval overrides the database interval
+	MetricInterval(metricName string) int64
 	// Families returns the families in memory which has not been flushed yet
 	Families() []int64
+	// CountFamilies returns the count of families in memory which has not been flushed yet
+	CountFamilies() int
 	// FlushInvertedIndexTo flushes the inverted-index of series to the kv builder
 	FlushInvertedIndexTo(flusher invertedindex.Flusher) error
 	// FlushFamilyTo flushes the corresponded family data to builder.
@@ -52,28 +96,70 @@ type MemoryDatabase interface {
 	FlushForwardIndexTo(flusher forwardindex.Flusher) error
 	// MemSize returns the memory-size of this metric-store
 	MemSize() int
+	// MemSizeByMetric returns the top n metrics by memory-size in descending order,
+	// for finding the metrics which hold the most memory
+	MemSizeByMetric(top int) []MetricMemStat
+	// EvictToSize evicts the series whose data has been flushed in least-recently-written order,
+	// until the memory-size is below the target size, returns the evicted size
+	EvictToSize(targetBytes int) (evictedSize int)
+	// QuotaStats returns the current usage and quota of the memory-database
+	QuotaStats() QuotaStats
+	// OutOfRangeWrites returns the count of metrics rejected for the timestamp out of the ahead/behind window
+	OutOfRangeWrites() int64
+	// CountSlots returns the count of time slots which has value in the time range per series of each version,
+	// for sparsity analysis, such as identifying flapping collectors
+	CountSlots(metricID uint32, timeRange timeutil.TimeRange) (map[series.Version]map[uint32]int, error)
+	// Snapshot serializes the index state of all the metrics in memory as gzip-compressed json stream for debugging,
+	// the metric name, field metas, tag keys with count of tag values and count of series per version are dumped,
+	// the stream is decoded by ReadSnapshot
+	Snapshot(w io.Writer) error
 	// series.Filter contains the methods for filtering seriesIDs from memDB
 	series.Filter
+	// series.StaleFilter contains the methods for filtering stale seriesIDs from memDB
+	series.StaleFilter
 	// series.MetaGetter returns tag values by tag keys and spec version for metric level
 	series.MetaGetter
+	// series.MultiVerMetaGetter returns tag values by tag keys for the series ids of multi versions
+	series.MultiVerMetaGetter
 	// series.Suggester returns the suggestions from prefix string
 	series.MetricMetaSuggester
 	series.TagValueSuggester
+	// series.TagValueRanker returns the tag values ranked by count of series
+	series.TagValueRanker
 	// series.Scanner scans metric-data
 	series.Scanner
 	// series.Storage returns the high level function of storage
 	series.Storage
 }
 
+// MetricHash is the default hash function of metric-name for locating the metric-store, xxhash64.
+func MetricHash(metricName string) uint64 {
+	return xxhash.Sum64String(metricName)
+}
+
+// BucketIndex returns the index of bucket which the metric-store with the metric-hash is located in,
+// for reproducing the bucket placement outside.
+func BucketIndex(metricHash uint64) int {
+	return int(shardingCountMask & metricHash)
+}
+
 // mStoresBucket is a simple rwMutex locked map of metricStore.
 type mStoresBucket struct {
 	rwLock      sync.RWMutex          // read-write lock of hash2MStore
-	hash2MStore map[uint64]mStoreINTF // key: FNV64a(metric-name)
+	hash2MStore map[uint64]mStoreINTF // key: hash of metric-name
+	hash2Name   map[uint64]string     // metric-name of mStore, for detecting hash collision
 }
 
 func newMStoreBucket() *mStoresBucket {
 	return &mStoresBucket{
-		hash2MStore: make(map[uint64]mStoreINTF)}
+		hash2MStore: make(map[uint64]mStoreINTF),
+		hash2Name:   make(map[uint64]string)}
+}
+
+// isCollided checks if the metric-hash is owned by another metric-name, not thread-safe.
+func (bkt *mStoresBucket) isCollided(hash uint64, metricName string) bool {
+	name, ok := bkt.hash2Name[hash]
+	return ok && name != metricName
 }
 
 // allMetricStores returns a clone of metric-hashes and pointer of mStores in bucket.
@@ -98,6 +184,73 @@ type MemoryDatabaseCfg struct {
 	TimeWindow int
 	Interval   timeutil.Interval
 	Generator  metadb.IDGenerator
+	// SlotStrategy is the strategy of storing slot values, dense array or sparse map
+	SlotStrategy SlotStrategy
+	// SparseThreshold is the slot density below which switches to sparse map with auto strategy
+	SparseThreshold float64
+	// Quota is the resource quota of database, rejects the writes when exceeded
+	Quota option.QuotaOption
+	// FieldFlushOrder is the field order of the flushed metric block
+	FieldFlushOrder option.FieldFlushOrderOption
+	// MaxTagsPerMetric is the max count of tags in one written metric, 0 means unlimited
+	MaxTagsPerMetric int
+	// FlushedIndex is the filter of flushed inverted index, consulted for the series evicted from memory
+	FlushedIndex series.Filter
+	// Clock is the source of current time for ingest rate and series eviction, nil means system clock
+	Clock timeutil.Clock
+	// FieldRetention is the retention of fields which are evicted sooner, key: field name, value: retention(millisecond)
+	FieldRetention map[string]int64
+	// MetricIntervals is the per-metric interval overriding Interval for coarser metrics, key: metric name,
+	// the interval must be multiple of Interval with same interval type, so that the families are shared
+	MetricIntervals map[string]timeutil.Interval
+	// ValidateMetricHash validates the precomputed metric hash against the metric name, for debugging
+	ValidateMetricHash bool
+	// FlushWritePolicy is the write behavior during a flush in-progress, option.FlushWriteAccept by default
+	FlushWritePolicy string
+	// FlushWriteMaxWait is the max wait of the writes blocked by flush with block-brief policy, default 1s
+	FlushWriteMaxWait time.Duration
+	// OnHighCardinality is called when the used tags of metric crosses 80% of the max tags limit,
+	// it is called once per crossing in a separate goroutine, and the notifications are dropped if it falls behind.
+	OnHighCardinality func(metricName string, used uint32, limit uint32)
+	// Ahead is the allowed timestamp write ahead of now(millisecond), 0 means unlimited
+	Ahead int64
+	// Behind is the allowed timestamp write behind now(millisecond), 0 means unlimited
+	Behind int64
+	// MetricHash is the hash function of metric-name for locating the metric-store, MetricHash by default,
+	// all the writes and reads of memory database use this function
+	MetricHash func(metricName string) uint64
+	// EvictInterval is the interval of evicting the expired series periodically besides evicting after flush,
+	// default 1 minute
+	EvictInterval time.Duration
+	// OnFlushProgress is called periodically with the progress during flush, in the flushing goroutine without lock
+	OnFlushProgress func(progress FlushProgress)
+	// FlushProgressEvery is the count of flushed metrics between the flush progress callbacks, default 1000
+	FlushProgressEvery int
+}
+
+// highCardinalityEvent represents the used tags of metric crossing the threshold of max tags limit
+type highCardinalityEvent struct {
+	metricName string
+	used       uint32
+	limit      uint32
+}
+
+// QuotaStats represents the current usage and quota of memory database, quota 0 means unlimited
+type QuotaStats struct {
+	Series        int   // count of series in memory
+	MaxSeries     int   // max count of series
+	MemSize       int64 // memory size, unit(byte)
+	MaxMemSize    int64 // max memory size, unit(byte)
+	IngestRate    int64 // count of written points in current second
+	MaxIngestRate int64 // max written points per second
+}
+
+// MetricMemStat represents the memory usage of a metric in memory database
+type MetricMemStat struct {
+	MetricName string // name of metric
+	MemSize    int    // memory size, unit(byte)
+	TagCount   int    // count of used tags(series)
+	FieldCount int    // count of fields
 }
 
 // memoryDatabase implements MemoryDatabase.
@@ -106,7 +259,8 @@ type memoryDatabase struct {
 	interval            timeutil.Interval                      // time interval of rollup
 	blockStore          *blockStore                            // reusable pool
 	ctx                 context.Context                        // used for exiting goroutines
-	evictNotifier       chan struct{}                          // notifying evictor to evict
+	evictNotifier       chan struct{}                          // notifying evictor to evict, at most one pending
+	evictInterval       time.Duration                          // interval of evicting periodically
 	once4Syncer         sync.Once                              // once for tags-limitation syncer
 	metricID2Hash       sync.Map                               // key: metric-id(uint32), value: hash(uint64)
 	mStoresList         [shardingCountOfMStores]*mStoresBucket // metric-name -> *metricStore
@@ -114,6 +268,33 @@ type memoryDatabase struct {
 	size                atomic.Int32                           // memdb's size
 	lastWroteFamilyTime atomic.Int64                           // prevents familyTime inserting repeatedly
 	familyTimes         sync.Map                               // familyTime(int64) -> struct{}
+	familyCount         atomic.Int32                           // count of familyTimes
+	familyMux           sync.RWMutex                           // lock of switching the flushing family
+	quota               option.QuotaOption                     // resource quota of database
+	ingestSecond        atomic.Int64                           // current second of ingest rate window
+	ingestCount         atomic.Int64                           // count of written points in current second
+	fieldFlushOrder     option.FieldFlushOrderOption           // field order of the flushed metric block
+	maxTagsPerMetric    int                                    // max count of tags in one written metric
+	flushedIndex        series.Filter                          // filter of flushed inverted index, nil if not set
+	clock               timeutil.Clock                         // source of current time
+	fieldRetention      map[string]int64                       // field name -> retention(millisecond)
+	metricIntervals     map[string]timeutil.Interval           // metric name -> interval, read only
+	validateMetricHash  bool                                   // validates the precomputed metric hash
+	flushWritePolicy    string                                 // write behavior during a flush in-progress
+	flushWriteMaxWait   time.Duration                          // max wait of the writes blocked by flush
+	flushMux            sync.Mutex                             // lock of flush status
+	flushCount          int                                    // count of flushes in-progress
+	flushDone           chan struct{}                          // closed when all the flushes in-progress complete
+	onHighCardinality   func(string, uint32, uint32)           // callback of high cardinality, nil if not set
+	highCardinalityCh   chan highCardinalityEvent              // pending notifications of high cardinality
+	highCardinality     sync.Map                               // metric name(string) -> struct{}, notified metrics
+	onFlushProgress     func(FlushProgress)                    // callback of flush progress, nil if not set
+	flushProgressEvery  int                                    // count of flushed metrics between progress callbacks
+	metricHash          func(string) uint64                    // hash function of metric-name
+	ahead               int64                                  // allowed timestamp write ahead of now(millisecond)
+	behind              int64                                  // allowed timestamp write behind now(millisecond)
+	outOfRangeWrites    atomic.Int64                           // count of writes rejected for timestamp out of range
+	seriesCount         atomic.Int32                           // count of in-use series of all metrics
 }
 
 // NewMemoryDatabase returns a new MemoryDatabase.
@@ -124,25 +305,73 @@ func NewMemoryDatabase(ctx context.Context, cfg MemoryDatabaseCfg) MemoryDatabas
 		generator:           cfg.Generator,
 		blockStore:          newBlockStore(cfg.TimeWindow),
 		ctx:                 ctx,
-		evictNotifier:       make(chan struct{}),
+		evictNotifier:       make(chan struct{}, 1),
+		evictInterval:       cfg.EvictInterval,
 		size:                *atomic.NewInt32(0),
 		lastWroteFamilyTime: *atomic.NewInt64(0),
+		quota:               cfg.Quota,
+		fieldFlushOrder:     cfg.FieldFlushOrder,
+		maxTagsPerMetric:    cfg.MaxTagsPerMetric,
+		flushedIndex:        cfg.FlushedIndex,
+		clock:               cfg.Clock,
+		fieldRetention:      cfg.FieldRetention,
+		metricIntervals:     cfg.MetricIntervals,
+		validateMetricHash:  cfg.ValidateMetricHash,
+		flushWritePolicy:    cfg.FlushWritePolicy,
+		flushWriteMaxWait:   cfg.FlushWriteMaxWait,
+		onHighCardinality:   cfg.OnHighCardinality,
+		onFlushProgress:     cfg.OnFlushProgress,
+		flushProgressEvery:  cfg.FlushProgressEvery,
+		metricHash:          cfg.MetricHash,
+		ahead:               cfg.Ahead,
+		behind:              cfg.Behind,
+	}
+	if md.clock == nil {
+		md.clock = timeutil.SystemClock
+	}
+	if md.flushWriteMaxWait <= 0 {
+		md.flushWriteMaxWait = defaultFlushWriteMaxWait
+	}
+	if md.evictInterval <= 0 {
+		md.evictInterval = defaultEvictInterval
+	}
+	if md.metricHash == nil {
+		md.metricHash = MetricHash
+	}
+	if md.flushProgressEvery <= 0 {
+		md.flushProgressEvery = defaultFlushProgressEvery
+	}
+	md.blockStore.slotStrategy = cfg.SlotStrategy
+	if cfg.SparseThreshold > 0 {
+		md.blockStore.sparseThreshold = cfg.SparseThreshold
 	}
 	for i := range md.mStoresList {
 		md.mStoresList[i] = newMStoreBucket()
 	}
 	go md.evictor(ctx)
+	if md.onHighCardinality != nil {
+		md.highCardinalityCh = make(chan highCardinalityEvent, highCardinalityChSize)
+		go md.highCardinalityNotifier(ctx)
+	}
 	return &md
 }
 
 // getBucket returns the mStoresBucket by metric-hash.
 func (md *memoryDatabase) getBucket(metricHash uint64) *mStoresBucket {
-	return md.mStoresList[shardingCountMask&metricHash]
+	return md.mStoresList[BucketIndex(metricHash)]
 }
 
-// getMStore returns the mStore by metric-name.
+// getMStore returns the mStore by metric-name, returns false if the metric-hash is owned by another metric.
 func (md *memoryDatabase) getMStore(metricName string) (mStore mStoreINTF, ok bool) {
-	return md.getMStoreByMetricHash(xxhash.Sum64String(metricName))
+	hash := md.metricHash(metricName)
+	bkt := md.getBucket(hash)
+	bkt.rwLock.RLock()
+	defer bkt.rwLock.RUnlock()
+	if bkt.isCollided(hash, metricName) {
+		return nil, false
+	}
+	mStore, ok = bkt.hash2MStore[hash]
+	return
 }
 
 // getMStoreByMetricHash returns the mStore by metric-hash.
@@ -163,25 +392,37 @@ func (md *memoryDatabase) getMStoreByMetricID(metricID uint32) (mStore mStoreINT
 	return md.getMStoreByMetricHash(item.(uint64))
 }
 
-// getOrCreateMStore returns the mStore by metricHash.
-func (md *memoryDatabase) getOrCreateMStore(metricName string, hash uint64) mStoreINTF {
-	var mStore mStoreINTF
-	mStore, ok := md.getMStoreByMetricHash(hash)
-	if !ok {
-		metricID := md.generator.GenMetricID(metricName)
+// getOrCreateMStore returns the mStore by metricHash,
+// returns ErrMetricHashCollision if the metric-hash is owned by another metric.
+func (md *memoryDatabase) getOrCreateMStore(metricName string, hash uint64) (mStoreINTF, error) {
+	bucket := md.getBucket(hash)
+	bucket.rwLock.RLock()
+	mStore, ok := bucket.hash2MStore[hash]
+	collided := bucket.isCollided(hash, metricName)
+	bucket.rwLock.RUnlock()
+	if collided {
+		return nil, series.ErrMetricHashCollision
+	}
+	if ok {
+		return mStore, nil
+	}
+	metricID := md.generator.GenMetricID(metricName)
 
-		bucket := md.getBucket(hash)
-		bucket.rwLock.Lock()
-		mStore, ok = bucket.hash2MStore[hash]
-		if !ok {
-			mStore = newMetricStore(metricID)
-			md.size.Add(int32(mStore.MemSize()))
-			bucket.hash2MStore[hash] = mStore
-			md.metricID2Hash.Store(metricID, hash)
-		}
-		bucket.rwLock.Unlock()
+	bucket.rwLock.Lock()
+	defer bucket.rwLock.Unlock()
+	// double check
+	if bucket.isCollided(hash, metricName) {
+		return nil, series.ErrMetricHashCollision
 	}
-	return mStore
+	mStore, ok = bucket.hash2MStore[hash]
+	if !ok {
+		mStore = newCountedMetricStore(metricID, &md.seriesCount)
+		md.size.Add(int32(mStore.MemSize()))
+		bucket.hash2MStore[hash] = mStore
+		bucket.hash2Name[hash] = metricName
+		md.metricID2Hash.Store(metricID, hash)
+	}
+	return mStore, nil
 }
 
 // WithMaxTagsLimit syncs the limitation for different metrics.
@@ -226,6 +467,14 @@ type writeContext struct {
 	slotIndex    int
 	timeInterval int64
 	mStoreFieldIDGetter
+	// newSeriesAllowed checks if allows creating new series, nil means unlimited
+	newSeriesAllowed func() bool
+	// maxTagsPerMetric is the max count of tags in one written metric, 0 means unlimited
+	maxTagsPerMetric int
+	// maxTagKeys is the max count of tag keys in the index of metric, 0 means the default limit
+	maxTagKeys int
+	// writeTime is the current time of writing in millisecond
+	writeTime int64
 }
 
 // PointTime returns the point time
@@ -233,25 +482,81 @@ func (writeCtx writeContext) PointTime() int64 {
 	return writeCtx.familyTime + writeCtx.timeInterval*int64(writeCtx.slotIndex)
 }
 
+// addFamilyTime adds the family time after the data of family written,
+// so that the family written after removed by flushing is added again, see removeFamilyTime.
 func (md *memoryDatabase) addFamilyTime(familyTime int64) {
+	md.familyMux.RLock()
+	defer md.familyMux.RUnlock()
+
 	if md.lastWroteFamilyTime.Swap(familyTime) == familyTime {
 		return
 	}
-	md.familyTimes.Store(familyTime, struct{}{})
+	if _, loaded := md.familyTimes.LoadOrStore(familyTime, struct{}{}); !loaded {
+		md.familyCount.Inc()
+	}
 }
 
-// Write writes metric-point to database.
+// Write writes metric-point to database, returns *series.WriteError if failure.
 func (md *memoryDatabase) Write(metric *pb.Metric) error {
+	return series.NewWriteError(metric.Name, md.write(metric, md.metricHash(metric.Name)))
+}
+
+// WriteWithHash writes metrics with the precomputed hash of metric name for skipping hashing,
+// returns *series.WriteError if failure.
+func (md *memoryDatabase) WriteWithHash(metric *pb.Metric, hash uint64) error {
+	if md.validateMetricHash && hash != md.metricHash(metric.Name) {
+		return series.NewWriteError(metric.Name, series.ErrMetricHashMismatch)
+	}
+	return series.NewWriteError(metric.Name, md.write(metric, hash))
+}
+
+// write writes metrics with the hash of metric name
+func (md *memoryDatabase) write(metric *pb.Metric, hash uint64) error {
+	md.waitFlush()
+	if err := md.checkTimestamp(metric.Timestamp); err != nil {
+		return err
+	}
+	if err := md.checkQuota(); err != nil {
+		return err
+	}
+	for {
+		mStore, err := md.getOrCreateMStore(metric.Name, hash)
+		if err != nil {
+			return err
+		}
+		// the metric store is deleted concurrently, writes into the re-created metric store
+		if err := md.writeMStore(mStore, metric); err != errMetricStoreDeleted {
+			return err
+		}
+	}
+}
+
+// Validate checks whether the metric would be accepted by Write without changing any state,
+// the metric not exist is checked by timestamp window only, returns *series.WriteError if rejected.
+func (md *memoryDatabase) Validate(metric *pb.Metric) error {
+	if !md.inTimeWindow(metric.Timestamp) {
+		return series.NewWriteError(metric.Name, series.ErrTimestampOutOfRange)
+	}
+	mStore, ok := md.getMStore(metric.Name)
+	if !ok {
+		return nil
+	}
+	return series.NewWriteError(metric.Name, mStore.Validate(metric, writeContext{
+		maxTagsPerMetric: md.maxTagsPerMetric,
+		newSeriesAllowed: md.newSeriesAllowed(),
+	}))
+}
+
+// writeMStore writes the metric into the metric store, then accounts the family time and memory size.
+func (md *memoryDatabase) writeMStore(mStore mStoreINTF, metric *pb.Metric) error {
 	timestamp := metric.Timestamp
+	interval := md.metricInterval(metric.Name)
 	// calculate family start time and slot index
-	intervalCalc := md.interval.Calculator()
-	segmentTime := intervalCalc.CalcSegmentTime(timestamp)                         // day
-	family := intervalCalc.CalcFamily(timestamp, segmentTime)                      // hours
-	familyTime := intervalCalc.CalcFamilyStartTime(segmentTime, family)            // family timestamp
-	slotIndex := intervalCalc.CalcSlot(timestamp, familyTime, md.interval.Int64()) // slot offset of family
-
-	hash := xxhash.Sum64String(metric.Name)
-	mStore := md.getOrCreateMStore(metric.Name, hash)
+	intervalCalc := interval.Calculator()
+	segmentTime := intervalCalc.CalcSegmentTime(timestamp)                      // day
+	family := intervalCalc.CalcFamily(timestamp, segmentTime)                   // hours
+	familyTime := intervalCalc.CalcFamilyStartTime(segmentTime, family)         // family timestamp
+	slotIndex := intervalCalc.CalcSlot(timestamp, familyTime, interval.Int64()) // slot offset of family
 
 	writtenSize, err := mStore.Write(metric, writeContext{
 		metricID:            mStore.GetMetricID(),
@@ -259,24 +564,288 @@ func (md *memoryDatabase) Write(metric *pb.Metric) error {
 		generator:           md.generator,
 		familyTime:          familyTime,
 		slotIndex:           slotIndex,
-		timeInterval:        md.interval.Int64(),
-		mStoreFieldIDGetter: mStore})
+		timeInterval:        interval.Int64(),
+		mStoreFieldIDGetter: mStore,
+		newSeriesAllowed:    md.newSeriesAllowed(),
+		maxTagsPerMetric:    md.maxTagsPerMetric,
+		writeTime:           md.clock.Now()})
 	if err == nil {
 		md.addFamilyTime(familyTime)
+		md.checkHighCardinality(metric.Name, mStore)
 	}
 	md.size.Add(int32(writtenSize))
 	return err
 }
 
-// evictor do evict periodically.
+// checkHighCardinality notifies the high cardinality callback once when the used tags of metric crosses
+// the threshold of max tags limit, and re-arms the notification after the used tags drops below the threshold.
+func (md *memoryDatabase) checkHighCardinality(metricName string, mStore mStoreINTF) {
+	if md.onHighCardinality == nil {
+		return
+	}
+	used := uint32(mStore.GetTagsUsed())
+	limit := mStore.GetMaxTagsLimit()
+	if float64(used) < float64(limit)*highCardinalityRatio {
+		if _, notified := md.highCardinality.Load(metricName); notified {
+			md.highCardinality.Delete(metricName)
+		}
+		return
+	}
+	if _, notified := md.highCardinality.LoadOrStore(metricName, struct{}{}); notified {
+		return
+	}
+	// never blocks the write, un-marks the metric if dropped so that the next write retries
+	select {
+	case md.highCardinalityCh <- highCardinalityEvent{metricName: metricName, used: used, limit: limit}:
+	default:
+		md.highCardinality.Delete(metricName)
+	}
+}
+
+// highCardinalityNotifier calls the high cardinality callback with the pending notifications.
+func (md *memoryDatabase) highCardinalityNotifier(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-md.highCardinalityCh:
+			md.onHighCardinality(event.metricName, event.used, event.limit)
+		}
+	}
+}
+
+// WriteBatch writes the metrics grouped by bucket index, all metrics targeting one bucket are written
+// under a single acquisition of the bucket lock instead of locking per metric.
+func (md *memoryDatabase) WriteBatch(metrics []*pb.Metric) (written int, err error) {
+	if len(metrics) == 0 {
+		return 0, nil
+	}
+	md.waitFlush()
+	var (
+		hashes = make([]uint64, len(metrics))
+		ends   [shardingCountOfMStores + 1]int
+	)
+	// counting sort of metric indexes by bucket index
+	for idx, metric := range metrics {
+		hashes[idx] = md.metricHash(metric.Name)
+		ends[(shardingCountMask&hashes[idx])+1]++
+	}
+	for bucketIndex := 1; bucketIndex <= shardingCountOfMStores; bucketIndex++ {
+		ends[bucketIndex] += ends[bucketIndex-1]
+	}
+	starts := ends
+	idxes := make([]int, len(metrics))
+	for idx := range metrics {
+		bucketIndex := shardingCountMask & hashes[idx]
+		idxes[starts[bucketIndex]] = idx
+		starts[bucketIndex]++
+	}
+	for bucketIndex := 0; bucketIndex < shardingCountOfMStores; bucketIndex++ {
+		start, end := ends[bucketIndex], ends[bucketIndex+1]
+		if start == end {
+			continue
+		}
+		n, bucketErr := md.writeBucket(md.mStoresList[bucketIndex], metrics, hashes, idxes[start:end])
+		written += n
+		if err == nil {
+			err = bucketErr
+		}
+	}
+	return written, err
+}
+
+// writeBucket writes the metrics of the indexes targeting the bucket under the bucket lock,
+// returns the count of written metrics and the first error.
+// the metric ids of the metrics are resolved before locking, so that the id generator
+// never runs under the bucket lock, and no other bucket is locked while holding the lock.
+func (md *memoryDatabase) writeBucket(
+	bucket *mStoresBucket,
+	metrics []*pb.Metric,
+	hashes []uint64,
+	idxes []int,
+) (written int, err error) {
+	metricIDs := md.genMetricIDs(bucket, metrics, hashes, idxes)
+
+	bucket.rwLock.Lock()
+	defer bucket.rwLock.Unlock()
+
+	for _, idx := range idxes {
+		metric, hash := metrics[idx], hashes[idx]
+		writeErr := md.checkTimestamp(metric.Timestamp)
+		if writeErr == nil {
+			writeErr = md.checkQuota()
+		}
+		if writeErr == nil {
+			writeErr = md.writeLocked(bucket, metric, hash, metricIDs)
+		}
+		if writeErr != nil {
+			if err == nil {
+				err = series.NewWriteError(metric.Name, writeErr)
+			}
+			continue
+		}
+		written++
+	}
+	return written, err
+}
+
+// genMetricIDs returns the metric ids of the metrics targeting the bucket, key: hash of metric-name,
+// the ids of the existing metric stores are reused, the others are generated without holding the bucket lock.
+func (md *memoryDatabase) genMetricIDs(
+	bucket *mStoresBucket,
+	metrics []*pb.Metric,
+	hashes []uint64,
+	idxes []int,
+) (metricIDs map[uint64]uint32) {
+	var missing []int
+	metricIDs = make(map[uint64]uint32)
+	bucket.rwLock.RLock()
+	for _, idx := range idxes {
+		hash := hashes[idx]
+		if _, ok := metricIDs[hash]; ok {
+			continue
+		}
+		if mStore, ok := bucket.hash2MStore[hash]; ok {
+			metricIDs[hash] = mStore.GetMetricID()
+			continue
+		}
+		if !bucket.isCollided(hash, metrics[idx].Name) {
+			missing = append(missing, idx)
+		}
+	}
+	bucket.rwLock.RUnlock()
+	for _, idx := range missing {
+		if _, ok := metricIDs[hashes[idx]]; !ok {
+			metricIDs[hashes[idx]] = md.generator.GenMetricID(metrics[idx].Name)
+		}
+	}
+	return metricIDs
+}
+
+// writeLocked gets or creates the metric store in the bucket with the generated metric ids, then writes the metric,
+// not thread-safe.
+func (md *memoryDatabase) writeLocked(
+	bucket *mStoresBucket,
+	metric *pb.Metric,
+	hash uint64,
+	metricIDs map[uint64]uint32,
+) error {
+	if bucket.isCollided(hash, metric.Name) {
+		return series.ErrMetricHashCollision
+	}
+	mStore, ok := bucket.hash2MStore[hash]
+	if !ok {
+		// the metric store may be removed concurrently after resolving the ids, recreated with the same id
+		metricID, ok := metricIDs[hash]
+		if !ok {
+			return series.ErrMetricHashCollision
+		}
+		mStore = newCountedMetricStore(metricID, &md.seriesCount)
+		md.size.Add(int32(mStore.MemSize()))
+		bucket.hash2MStore[hash] = mStore
+		bucket.hash2Name[hash] = metric.Name
+		md.metricID2Hash.Store(metricID, hash)
+	}
+	return md.writeMStore(mStore, metric)
+}
+
+// checkTimestamp checks the timestamp of metric is in the ahead/behind window of now,
+// the metric out of the window is rejected and counted, so that the family times in memory are not polluted.
+func (md *memoryDatabase) checkTimestamp(timestamp int64) error {
+	if !md.inTimeWindow(timestamp) {
+		md.outOfRangeWrites.Inc()
+		return series.ErrTimestampOutOfRange
+	}
+	return nil
+}
+
+// inTimeWindow returns if the timestamp is in the ahead/behind window of now, always true if no window.
+func (md *memoryDatabase) inTimeWindow(timestamp int64) bool {
+	if md.ahead <= 0 && md.behind <= 0 {
+		return true
+	}
+	now := md.clock.Now()
+	return (md.behind <= 0 || timestamp >= now-md.behind) && (md.ahead <= 0 || timestamp <= now+md.ahead)
+}
+
+// OutOfRangeWrites returns the count of metrics rejected for the timestamp out of the ahead/behind window.
+func (md *memoryDatabase) OutOfRangeWrites() int64 {
+	return md.outOfRangeWrites.Load()
+}
+
+// checkQuota checks the memory and ingest rate quota of database before writing.
+func (md *memoryDatabase) checkQuota() error {
+	if md.quota.MaxMemory > 0 && int64(md.MemSize()) >= md.quota.MaxMemory*1024*1024 {
+		return series.ErrMemoryQuotaExceeded
+	}
+	if md.quota.MaxIngestRate > 0 {
+		second := md.clock.Now() / timeutil.OneSecond
+		if old := md.ingestSecond.Load(); old != second && md.ingestSecond.CAS(old, second) {
+			md.ingestCount.Store(0)
+		}
+		if md.ingestCount.Inc() > int64(md.quota.MaxIngestRate) {
+			md.ingestCount.Dec()
+			return series.ErrIngestRateQuotaExceeded
+		}
+	}
+	return nil
+}
+
+// newSeriesAllowed returns the checker of series quota, nil if unlimited.
+func (md *memoryDatabase) newSeriesAllowed() func() bool {
+	if md.quota.MaxSeries <= 0 {
+		return nil
+	}
+	return func() bool {
+		return md.countSeries() < md.quota.MaxSeries
+	}
+}
+
+// countSeries returns count of in-use series of all metrics,
+// which is counted by the metric stores on creating and removing series without locking any bucket.
+func (md *memoryDatabase) countSeries() int {
+	return int(md.seriesCount.Load())
+}
+
+// QuotaStats returns the current usage and quota of memory database.
+func (md *memoryDatabase) QuotaStats() QuotaStats {
+	stats := QuotaStats{
+		Series:        md.countSeries(),
+		MaxSeries:     md.quota.MaxSeries,
+		MemSize:       int64(md.MemSize()),
+		MaxMemSize:    md.quota.MaxMemory * 1024 * 1024,
+		MaxIngestRate: int64(md.quota.MaxIngestRate),
+	}
+	if md.ingestSecond.Load() == md.clock.Now()/timeutil.OneSecond {
+		stats.IngestRate = md.ingestCount.Load()
+	}
+	return stats
+}
+
+// evictor do evict periodically and after flush, the evictions never overlap because they run in this goroutine.
+// the notifications during eviction are coalesced into one pending notification,
+// and the tick is skipped if the last eviction completed within the interval, both are measured by the clock.
 func (md *memoryDatabase) evictor(ctx context.Context) {
+	ticker := md.clock.NewTicker(md.evictInterval)
+	defer ticker.Stop()
+
+	// timestamp of last eviction in millisecond
+	var lastEvicted int64
+	evictAll := func() {
+		for i := 0; i < shardingCountOfMStores; i++ {
+			md.evict(md.mStoresList[i&shardingCountMask])
+		}
+		lastEvicted = md.clock.Now()
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-md.evictNotifier:
-			for i := 0; i < shardingCountOfMStores; i++ {
-				md.evict(md.mStoresList[i&shardingCountMask])
+			evictAll()
+		case <-ticker.C():
+			if md.clock.Now()-lastEvicted >= md.evictInterval.Nanoseconds()/int64(time.Millisecond) {
+				evictAll()
 			}
 		}
 	}
@@ -287,24 +856,159 @@ func (md *memoryDatabase) evictor(ctx context.Context) {
 func (md *memoryDatabase) evict(bucket *mStoresBucket) {
 	// get all allMStores
 	metricHashes, allMStores := bucket.allMetricStores()
+	now := md.clock.Now()
+	fieldFamilyTimes := md.fieldRetentionFamilyTimes(now)
 
 	for idx, mStore := range allMStores {
+		// delete field data which exceeds the retention of field
+		if len(fieldFamilyTimes) > 0 {
+			md.size.Sub(int32(mStore.EvictFieldData(fieldFamilyTimes)))
+		}
 		// delete tag of tStore which has not been used for a while
-		evictedSize := mStore.Evict()
+		evictedSize := mStore.Evict(now)
 		// reduce evicted size
 		md.size.Sub(int32(evictedSize))
-		// delete mStore whose tags is empty now.
-		if mStore.IsEmpty() {
-			bucket.rwLock.Lock()
-			if mStore.IsEmpty() {
-				delete(bucket.hash2MStore, metricHashes[idx])
-				md.metricID2Hash.Delete(mStore.GetMetricID())
+		md.removeEmptyMStore(bucket, metricHashes[idx], mStore)
+	}
+}
+
+// removeEmptyMStore deletes mStore whose tags is empty now.
+func (md *memoryDatabase) removeEmptyMStore(bucket *mStoresBucket, metricHash uint64, mStore mStoreINTF) {
+	if !mStore.IsEmpty() {
+		return
+	}
+	bucket.rwLock.Lock()
+	if mStore.IsEmpty() {
+		delete(bucket.hash2MStore, metricHash)
+		delete(bucket.hash2Name, metricHash)
+		md.metricID2Hash.Delete(mStore.GetMetricID())
+	}
+	// reduce empty mstore size
+	md.size.Sub(int32(mStore.MemSize()))
+	bucket.rwLock.Unlock()
+}
+
+// MemSizeByMetric returns the top n metrics by memory-size in descending order, ties are ordered by metric name,
+// the metric stores are collected under the read lock of each bucket, then the stats are computed without the lock.
+func (md *memoryDatabase) MemSizeByMetric(top int) []MetricMemStat {
+	if top <= 0 {
+		return nil
+	}
+	var (
+		names  []string
+		stores []mStoreINTF
+	)
+	for _, bucket := range md.mStoresList {
+		bucket.rwLock.RLock()
+		for hash, mStore := range bucket.hash2MStore {
+			names = append(names, bucket.hash2Name[hash])
+			stores = append(stores, mStore)
+		}
+		bucket.rwLock.RUnlock()
+	}
+	stats := make([]MetricMemStat, len(stores))
+	for idx, mStore := range stores {
+		stats[idx] = MetricMemStat{
+			MetricName: names[idx],
+			MemSize:    mStore.MemSize(),
+			TagCount:   mStore.GetTagsUsed(),
+			FieldCount: mStore.GetFieldsCount(),
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].MemSize != stats[j].MemSize {
+			return stats[i].MemSize > stats[j].MemSize
+		}
+		return stats[i].MetricName < stats[j].MetricName
+	})
+	if len(stats) > top {
+		stats = stats[:top]
+	}
+	return stats
+}
+
+// EvictToSize evicts the series whose data has been flushed in least-recently-written order,
+// until the memory-size is below the target size, returns the evicted size.
+// each mStore evicts the exceeded size in proportion to its memory-size.
+func (md *memoryDatabase) EvictToSize(targetBytes int) (evictedSize int) {
+	totalSize := md.MemSize()
+	exceededSize := totalSize - targetBytes
+	if exceededSize < 0 || totalSize <= 0 {
+		return 0
+	}
+	for i := 0; i < shardingCountOfMStores; i++ {
+		bucket := md.mStoresList[i&shardingCountMask]
+		metricHashes, allMStores := bucket.allMetricStores()
+		for idx, mStore := range allMStores {
+			if evictedSize > exceededSize {
+				return evictedSize
 			}
-			// reduce empty mstore size
-			md.size.Sub(int32(mStore.MemSize()))
-			bucket.rwLock.Unlock()
+			mStoreSize := mStore.MemSize()
+			mStoreExceededSize := int(int64(exceededSize) * int64(mStoreSize) / int64(totalSize))
+			size := mStore.EvictToSize(mStoreSize - mStoreExceededSize)
+			md.size.Sub(int32(size))
+			evictedSize += size
+			md.removeEmptyMStore(bucket, metricHashes[idx], mStore)
 		}
 	}
+	return evictedSize
+}
+
+// fieldRetentionFamilyTimes returns the family time of fields with retention,
+// the families before it are out of retention entirely, key: field name, value: family time.
+func (md *memoryDatabase) fieldRetentionFamilyTimes(now int64) map[string]int64 {
+	if len(md.fieldRetention) == 0 {
+		return nil
+	}
+	calc := md.interval.Calculator()
+	familyTimes := make(map[string]int64, len(md.fieldRetention))
+	for fieldName, retention := range md.fieldRetention {
+		timestamp := now - retention
+		segmentTime := calc.CalcSegmentTime(timestamp)
+		familyTimes[fieldName] = calc.CalcFamilyStartTime(segmentTime, calc.CalcFamily(timestamp, segmentTime))
+	}
+	return familyTimes
+}
+
+// DeleteTagValues deletes the tag values of tag key matching the pattern and their series of the metric.
+func (md *memoryDatabase) DeleteTagValues(metricName, tagKey, pattern string) (deletedSeries int, err error) {
+	mStore, ok := md.getMStore(metricName)
+	if !ok {
+		return 0, series.ErrNotFound
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return 0, err
+	}
+	deletedSeries, removedSize := mStore.DeleteTagValues(tagKey, regex)
+	md.size.Sub(int32(removedSize))
+	return deletedSeries, nil
+}
+
+// SetMaxTagsLimit sets the max tags limit of the metric at runtime, returns the new effective limit.
+func (md *memoryDatabase) SetMaxTagsLimit(metricName string, limit uint32) (effectiveLimit uint32, err error) {
+	if limit == 0 {
+		return 0, fmt.Errorf("max tags limit cannot be zero")
+	}
+	mStore, ok := md.getMStore(metricName)
+	if !ok {
+		return 0, series.ErrNotFound
+	}
+	mStore.SetMaxTagsLimit(limit)
+	return mStore.GetMaxTagsLimit(), nil
+}
+
+// SetMaxTagKeysLimit sets the max count of tag keys of the metric at runtime, returns the new effective limit.
+func (md *memoryDatabase) SetMaxTagKeysLimit(metricName string, limit uint32) (effectiveLimit uint32, err error) {
+	if limit == 0 {
+		return 0, fmt.Errorf("max tag keys limit cannot be zero")
+	}
+	mStore, ok := md.getMStore(metricName)
+	if !ok {
+		return 0, series.ErrNotFound
+	}
+	mStore.SetMaxTagKeysLimit(limit)
+	return mStore.GetMaxTagKeysLimit(), nil
 }
 
 // ResetMetricStore assigns a new version to the specified metric.
@@ -313,11 +1017,38 @@ func (md *memoryDatabase) ResetMetricStore(metricName string) error {
 	if !ok {
 		return fmt.Errorf("metric: %s doesn't exist", metricName)
 	}
+	// waits the flush removing the immutable indexes, so that reset is not rejected with block-brief policy
+	md.waitFlush()
 	createdSize, err := mStore.ResetVersion()
 	md.size.Add(int32(createdSize))
 	return err
 }
 
+// DeleteMetric removes the metric store of the specified metric from its bucket.
+func (md *memoryDatabase) DeleteMetric(metricName string) error {
+	mStore, ok := md.getMStore(metricName)
+	if !ok {
+		return fmt.Errorf("metric: %s doesn't exist", metricName)
+	}
+	hash := md.metricHash(metricName)
+	bucket := md.getBucket(hash)
+	bucket.rwLock.Lock()
+	defer bucket.rwLock.Unlock()
+	// double check, the metric store may be removed or recreated concurrently
+	if bucket.hash2MStore[hash] != mStore {
+		return fmt.Errorf("metric: %s doesn't exist", metricName)
+	}
+	// the writers holding the metric store are rejected, then write into the re-created metric store
+	mStore.MarkDeleted()
+	delete(bucket.hash2MStore, hash)
+	delete(bucket.hash2Name, hash)
+	md.metricID2Hash.Delete(mStore.GetMetricID())
+	md.highCardinality.Delete(metricName)
+	md.size.Sub(int32(mStore.MemSize()))
+	md.seriesCount.Sub(int32(mStore.GetTagsInUse()))
+	return nil
+}
+
 // CountMetrics returns count of metrics in all buckets.
 func (md *memoryDatabase) CountMetrics() int {
 	var counter = 0
@@ -338,6 +1069,16 @@ func (md *memoryDatabase) CountTags(metricName string) int {
 	return mStore.GetTagsUsed()
 }
 
+// CountFields returns count of fields of a specified metricName, return -1 when metric not exist.
+// the fields are read from the atomic value of metric store without lock.
+func (md *memoryDatabase) CountFields(metricName string) int {
+	mStore, ok := md.getMStore(metricName)
+	if !ok {
+		return -1
+	}
+	return mStore.GetFieldsCount()
+}
+
 // Families returns the families in memory which has not been flushed yet.
 func (md *memoryDatabase) Families() []int64 {
 	var families []int64
@@ -352,49 +1093,227 @@ func (md *memoryDatabase) Families() []int64 {
 	return families
 }
 
+// CountFamilies returns the count of families in memory which has not been flushed yet.
+func (md *memoryDatabase) CountFamilies() int {
+	return int(md.familyCount.Load())
+}
+
 // flushContext holds the context for flushing
 type flushContext struct {
 	metricID     uint32
 	familyTime   int64
 	timeInterval int64
+	fieldOrder   option.FieldFlushOrderOption
 }
 
 // FlushFamilyTo flushes all data related to the family from metric-stores to builder,
+// the metric stores are flushed concurrently by the workers in chunks, then the flushed chunks are written
+// into builder in order of metric id as soon as the previous chunks are written,
+// because the keys of kv store must be added in order.
+// the flush is aborted if any metric store fails, the metric stores not flushed yet keep their data
+// and the family is flushed again next time.
 func (md *memoryDatabase) FlushFamilyTo(flusher metricsdata.Flusher, familyTime int64) error {
+	md.beginFlush()
+	defer md.endFlush()
 	defer func() {
-		// non-block notifying evictor
+		// non-block notifying evictor, the notification is coalesced if an eviction is pending
 		select {
 		case md.evictNotifier <- struct{}{}:
 		default:
-			memDBLogger.Warn("flusher is working, concurrently flushing is not allowed")
 		}
 	}()
 
-	md.familyTimes.Delete(familyTime)
+	md.removeFamilyTime(familyTime)
+
+	progress := md.newFlushProgressTracker(FlushTargetFamily)
+	if err := md.flushChunks(flusher, familyTime, progress); err != nil {
+		if _, loaded := md.familyTimes.LoadOrStore(familyTime, struct{}{}); !loaded {
+			md.familyCount.Inc()
+		}
+		return err
+	}
+	progress.done()
+	return nil
+}
+
+// removeFamilyTime removes the family time before flushing the family, the last wrote family time is reset
+// under the same lock, so the family written concurrently after removed is always added again by the writer,
+// because the data written before removed is flushed, the data written after is flushed next time.
+func (md *memoryDatabase) removeFamilyTime(familyTime int64) {
+	md.familyMux.Lock()
+	defer md.familyMux.Unlock()
+
+	if _, ok := md.familyTimes.Load(familyTime); ok {
+		md.familyTimes.Delete(familyTime)
+		md.familyCount.Dec()
+	}
 	md.lastWroteFamilyTime.Store(0)
+}
 
-	for bucketIndex := 0; bucketIndex < shardingCountOfMStores; bucketIndex++ {
-		bkt := md.mStoresList[bucketIndex]
+// flushingMetricStore represents a metric store to flush with its storage interval
+type flushingMetricStore struct {
+	mStore       mStoreINTF
+	timeInterval int64
+}
 
-		_, allMetricStores := bkt.allMetricStores()
-		for _, mStore := range allMetricStores {
-			flushedSize, err := mStore.FlushMetricsDataTo(flusher, flushContext{
-				metricID:     mStore.GetMetricID(),
-				familyTime:   familyTime,
-				timeInterval: md.interval.Int64(),
+// flushedChunk represents the recorded metric-blocks of a chunk of metric stores
+type flushedChunk struct {
+	recorder *metricsDataRecorder
+	// flushed size of each recorded metric-block
+	sizes []int
+	err   error
+}
+
+// chunkMetricStores returns the metric stores of all buckets in order of metric id,
+// split into chunks(at most shardingCountOfMStores).
+func (md *memoryDatabase) chunkMetricStores() (chunks [][]flushingMetricStore) {
+	var stores []flushingMetricStore
+	for bucketIndex := 0; bucketIndex < shardingCountOfMStores; bucketIndex++ {
+		metricHashes, allMetricStores := md.mStoresList[bucketIndex].allMetricStores()
+		for idx, mStore := range allMetricStores {
+			stores = append(stores, flushingMetricStore{
+				mStore:       mStore,
+				timeInterval: md.metricIntervalByHash(metricHashes[idx]).Int64(),
 			})
-			md.size.Sub(int32(flushedSize))
-			if err != nil {
-				return err
+		}
+	}
+	sort.Slice(stores, func(i, j int) bool {
+		return stores[i].mStore.GetMetricID() < stores[j].mStore.GetMetricID()
+	})
+	chunkSize := (len(stores) + shardingCountOfMStores - 1) / shardingCountOfMStores
+	for begin := 0; begin < len(stores); begin += chunkSize {
+		end := begin + chunkSize
+		if end > len(stores) {
+			end = len(stores)
+		}
+		chunks = append(chunks, stores[begin:end])
+	}
+	return chunks
+}
+
+// flushChunks flushes the chunks of metric stores by the workers(FlushConcurrency) concurrently,
+// and writes the flushed chunks into flusher in order in current goroutine. At most 2*workers chunks
+// are flushed ahead of writing, which bounds the buffered data. The size of memory database is reduced
+// after the metric-block is written, or the flushed chunk is discarded by abort.
+func (md *memoryDatabase) flushChunks(flusher metricsdata.Flusher, familyTime int64,
+	progress *flushProgressTracker,
+) error {
+	chunks := md.chunkMetricStores()
+	if len(chunks) == 0 {
+		return nil
+	}
+	workers := constants.FlushConcurrency
+	if workers > len(chunks) {
+		workers = len(chunks)
+	}
+	window := make(chan struct{}, 2*workers)
+	tasks := make(chan int, len(chunks))
+	flushed := make([]chan *flushedChunk, len(chunks))
+	for idx := range flushed {
+		flushed[idx] = make(chan *flushedChunk, 1)
+	}
+	abort := make(chan struct{})
+	aborted := atomic.NewBool(false)
+
+	// dispatches the chunks to the workers when the window is available
+	go func() {
+		defer close(tasks)
+		for idx := range chunks {
+			select {
+			case window <- struct{}{}:
+				tasks <- idx
+			case <-abort:
+				return
 			}
 		}
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range tasks {
+				chunk := &flushedChunk{recorder: newMetricsDataRecorder()}
+				for _, store := range chunks[idx] {
+					// the metric stores keep their data if aborted
+					if aborted.Load() {
+						break
+					}
+					flushedSize, err := store.mStore.FlushMetricsDataTo(chunk.recorder, flushContext{
+						metricID:     store.mStore.GetMetricID(),
+						familyTime:   familyTime,
+						timeInterval: store.timeInterval,
+						fieldOrder:   md.fieldFlushOrder,
+					})
+					chunk.sizes = append(chunk.sizes, flushedSize)
+					if err != nil {
+						chunk.err = err
+						break
+					}
+				}
+				flushed[idx] <- chunk
+			}
+		}()
+	}
+
+	var err error
+	written := 0
+	for ; written < len(chunks); written++ {
+		if err = md.writeChunk(flusher, <-flushed[written], written, progress); err != nil {
+			aborted.Store(true)
+			close(abort)
+			break
+		}
+		<-window
+	}
+	wg.Wait()
+	// the data of the chunks flushed ahead is lost if aborted
+	for idx := written + 1; idx < len(chunks); idx++ {
+		select {
+		case chunk := <-flushed[idx]:
+			md.size.Sub(int32(sumOfSizes(chunk.sizes)))
+		default:
+		}
+	}
+	return err
+}
+
+// writeChunk writes the recorded metric-blocks of the chunk into flusher,
+// the size of memory database is reduced after each metric-block is written or discarded.
+func (md *memoryDatabase) writeChunk(flusher metricsdata.Flusher, chunk *flushedChunk, chunkIndex int,
+	progress *flushProgressTracker,
+) error {
+	if chunk.err != nil {
+		// the data of the flushed metric stores is lost
+		md.size.Sub(int32(sumOfSizes(chunk.sizes)))
+		return chunk.err
+	}
+	for idx := range chunk.recorder.blocks {
+		if err := chunk.recorder.blocks[idx].replay(flusher); err != nil {
+			// the data of the metric-blocks not written is lost
+			md.size.Sub(int32(sumOfSizes(chunk.sizes[idx:])))
+			return err
+		}
+		md.size.Sub(int32(chunk.sizes[idx]))
+		progress.flushed(chunkIndex, chunk.sizes[idx])
 	}
 	return nil
 }
 
+// sumOfSizes returns the sum of the sizes
+func sumOfSizes(sizes []int) (sum int) {
+	for _, size := range sizes {
+		sum += size
+	}
+	return sum
+}
+
 // FlushInvertedIndexTo flushes the series data to a inverted-index file.
 func (md *memoryDatabase) FlushInvertedIndexTo(flusher invertedindex.Flusher) error {
+	md.beginFlush()
+	defer md.endFlush()
 	var err error
+	progress := md.newFlushProgressTracker(FlushTargetInvertedIndex)
 	for bucketIndex := 0; bucketIndex < shardingCountOfMStores; bucketIndex++ {
 		bkt := md.mStoresList[bucketIndex]
 		_, allMetricStores := bkt.allMetricStores()
@@ -402,14 +1321,19 @@ func (md *memoryDatabase) FlushInvertedIndexTo(flusher invertedindex.Flusher) er
 			if err = mStore.FlushInvertedIndexTo(flusher, md.generator); err != nil {
 				return err
 			}
+			progress.flushed(bucketIndex, 0)
 		}
 	}
+	progress.done()
 	return nil
 }
 
 // FlushForwardIndexTo flushes the forward-index of series to a forward-index file
 func (md *memoryDatabase) FlushForwardIndexTo(flusher forwardindex.Flusher) error {
+	md.beginFlush()
+	defer md.endFlush()
 	var err error
+	progress := md.newFlushProgressTracker(FlushTargetForwardIndex)
 	for bucketIndex := 0; bucketIndex < shardingCountOfMStores; bucketIndex++ {
 		bkt := md.mStoresList[bucketIndex]
 		_, allMetricStores := bkt.allMetricStores()
@@ -417,12 +1341,16 @@ func (md *memoryDatabase) FlushForwardIndexTo(flusher forwardindex.Flusher) erro
 			if err = mStore.FlushForwardIndexTo(flusher); err != nil {
 				return err
 			}
+			progress.flushed(bucketIndex, 0)
 		}
 	}
+	progress.done()
 	return nil
 }
 
-// FindSeriesIDsByExpr finds series ids by tag filter expr for metric id from mStore.
+// FindSeriesIDsByExpr finds series ids by tag filter expr for metric id from mStore,
+// then unions the series ids of flushed inverted index for versions overlapping the time range,
+// so that the series evicted from memory are still found.
 func (md *memoryDatabase) FindSeriesIDsByExpr(
 	metricID uint32,
 	expr stmt.TagFilter,
@@ -431,15 +1359,86 @@ func (md *memoryDatabase) FindSeriesIDsByExpr(
 	*series.MultiVerSeriesIDSet,
 	error,
 ) {
+	var memResult *series.MultiVerSeriesIDSet
+	mStore, ok := md.getMStoreByMetricID(metricID)
+	if ok {
+		var err error
+		if memResult, err = mStore.FindSeriesIDsByExpr(expr); err != nil {
+			return nil, err
+		}
+	}
+	return md.unionFlushedIndex(ok, memResult, func() (*series.MultiVerSeriesIDSet, error) {
+		return md.flushedIndex.FindSeriesIDsByExpr(metricID, expr, timeRange)
+	})
+}
+
+// unionFlushedIndex unions the series ids found in memory with the series ids found by the flushed inverted index,
+// the series ids are unioned per version.
+func (md *memoryDatabase) unionFlushedIndex(
+	inMemory bool,
+	memResult *series.MultiVerSeriesIDSet,
+	findFlushed func() (*series.MultiVerSeriesIDSet, error),
+) (
+	*series.MultiVerSeriesIDSet,
+	error,
+) {
+	if md.flushedIndex == nil {
+		if !inMemory {
+			return nil, series.ErrNotFound
+		}
+		return memResult, nil
+	}
+	flushedResult, err := findFlushed()
+	switch {
+	case err != nil && memResult != nil:
+		// ignore the error of flushed index, returns the series ids in memory
+		return memResult, nil
+	case err != nil:
+		return nil, err
+	case flushedResult == nil:
+		return memResult, nil
+	case memResult == nil:
+		return flushedResult, nil
+	}
+	memResult.Or(flushedResult)
+	return memResult, nil
+}
 
+// FindStaleSeriesIDs finds series ids from mStore whose latest data is older than the threshold timestamp,
+// the staleness is known only for the series in memory, which are also returned.
+func (md *memoryDatabase) FindStaleSeriesIDs(
+	metricID uint32,
+	threshold int64,
+) (
+	stale, known *series.MultiVerSeriesIDSet,
+	err error,
+) {
+	mStore, ok := md.getMStoreByMetricID(metricID)
+	if !ok {
+		return nil, nil, series.ErrNotFound
+	}
+	stale, known = mStore.FindStaleSeriesIDs(md.metricIntervalByID(metricID).Int64(), threshold)
+	return stale, known, nil
+}
+
+// CountSlots returns the count of time slots which has value in the time range per series of each version.
+func (md *memoryDatabase) CountSlots(
+	metricID uint32,
+	timeRange timeutil.TimeRange,
+) (
+	map[series.Version]map[uint32]int,
+	error,
+) {
 	mStore, ok := md.getMStoreByMetricID(metricID)
 	if !ok {
 		return nil, series.ErrNotFound
 	}
-	return mStore.FindSeriesIDsByExpr(expr)
+	return mStore.CountSlots(md.metricIntervalByID(metricID).Int64(), timeRange), nil
 }
 
-// GetSeriesIDsForTag get series ids for spec metric's tag key from mStore.
+// GetSeriesIDsForTag get series ids for spec metric's tag key from mStore,
+// then unions the series ids of flushed inverted index like FindSeriesIDsByExpr,
+// so that negation is computed against all the series of the tag key.
 func (md *memoryDatabase) GetSeriesIDsForTag(
 	metricID uint32,
 	tagKey string,
@@ -448,12 +1447,39 @@ func (md *memoryDatabase) GetSeriesIDsForTag(
 	*series.MultiVerSeriesIDSet,
 	error,
 ) {
+	var memResult *series.MultiVerSeriesIDSet
+	mStore, ok := md.getMStoreByMetricID(metricID)
+	if ok {
+		var err error
+		if memResult, err = mStore.GetSeriesIDsForTag(tagKey); err != nil {
+			return nil, err
+		}
+	}
+	return md.unionFlushedIndex(ok, memResult, func() (*series.MultiVerSeriesIDSet, error) {
+		return md.flushedIndex.GetSeriesIDsForTag(metricID, tagKey, timeRange)
+	})
+}
 
+// GetSeriesIDsForMetric get all the series ids for spec metric from mStore,
+// then unions the series ids of flushed inverted index like FindSeriesIDsByExpr.
+func (md *memoryDatabase) GetSeriesIDsForMetric(
+	metricID uint32,
+	timeRange timeutil.TimeRange,
+) (
+	*series.MultiVerSeriesIDSet,
+	error,
+) {
+	var memResult *series.MultiVerSeriesIDSet
 	mStore, ok := md.getMStoreByMetricID(metricID)
-	if !ok {
-		return nil, series.ErrNotFound
+	if ok {
+		var err error
+		if memResult, err = mStore.GetSeriesIDsForMetric(); err != nil {
+			return nil, err
+		}
 	}
-	return mStore.GetSeriesIDsForTag(tagKey)
+	return md.unionFlushedIndex(ok, memResult, func() (*series.MultiVerSeriesIDSet, error) {
+		return md.flushedIndex.GetSeriesIDsForMetric(metricID, timeRange)
+	})
 }
 
 // GetTagValues returns tag values by tag keys and spec version for metric level from memory-database
@@ -474,9 +1500,43 @@ func (md *memoryDatabase) GetTagValues(
 	return mStore.GetTagValues(tagKeys, version, seriesIDs)
 }
 
-// SuggestMetrics returns nil, as the index-db contains all metricNames
+// GetTagValuesByVersions returns tag values by tag keys for the series ids of multi versions from memory-database,
+// such as the series ids of query spanning a version rotation, the tag values are returned by version.
+func (md *memoryDatabase) GetTagValuesByVersions(
+	metricID uint32,
+	tagKeys []string,
+	seriesIDs *series.MultiVerSeriesIDSet,
+) (
+	version2TagValues map[series.Version]map[uint32][]string,
+	err error,
+) {
+	mStore, ok := md.getMStoreByMetricID(metricID)
+	if !ok {
+		return nil, series.ErrNotFound
+	}
+	return mStore.GetTagValuesByVersions(tagKeys, seriesIDs)
+}
+
+// SuggestMetrics returns the metric names in memory with the prefix in ascending order,
+// which covers the metrics not flushed into index-db yet.
 func (md *memoryDatabase) SuggestMetrics(prefix string, limit int) (suggestions []string) {
-	return nil
+	if limit <= 0 {
+		return nil
+	}
+	for _, bucket := range md.mStoresList {
+		bucket.rwLock.RLock()
+		for _, metricName := range bucket.hash2Name {
+			if strings.HasPrefix(metricName, prefix) {
+				suggestions = append(suggestions, metricName)
+			}
+		}
+		bucket.rwLock.RUnlock()
+	}
+	sort.Strings(suggestions)
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
 }
 
 // SuggestTagKeys returns suggestions from given metricName and prefix of tagKey
@@ -497,11 +1557,22 @@ func (md *memoryDatabase) SuggestTagValues(metricName, tagKey, tagValuePrefix st
 	return mStore.SuggestTagValues(tagKey, tagValuePrefix, limit)
 }
 
+// TopTagValues returns at most k tag values of the metric's tag key ordered by count of series descending.
+func (md *memoryDatabase) TopTagValues(metricName, tagKey string, k int) []series.TagValueCount {
+	mStore, ok := md.getMStore(metricName)
+	if !ok {
+		return nil
+	}
+	return mStore.TopTagValues(tagKey, k)
+}
+
 // Scan scans data from memory by scan-context
 func (md *memoryDatabase) Scan(sCtx *series.ScanContext) {
 	mStore, ok := md.getMStoreByMetricID(sCtx.MetricID)
 	if ok {
-		sCtx.IntervalCalc = md.interval.Calculator()
+		interval := md.metricIntervalByID(sCtx.MetricID)
+		sCtx.IntervalCalc = interval.Calculator()
+		sCtx.StorageInterval = interval.Int64()
 		mStore.Scan(sCtx)
 	}
 }
@@ -511,6 +1582,43 @@ func (md *memoryDatabase) Interval() int64 {
 	return md.interval.Int64()
 }
 
+// MetricInterval returns the interval of the metric, the per-metric interval overrides the database interval
+func (md *memoryDatabase) MetricInterval(metricName string) int64 {
+	return md.metricInterval(metricName).Int64()
+}
+
+// metricInterval returns the interval of the metric by metric name
+func (md *memoryDatabase) metricInterval(metricName string) timeutil.Interval {
+	if interval, ok := md.metricIntervals[metricName]; ok {
+		return interval
+	}
+	return md.interval
+}
+
+// metricIntervalByHash returns the interval of the metric by metric hash
+func (md *memoryDatabase) metricIntervalByHash(hash uint64) timeutil.Interval {
+	if len(md.metricIntervals) == 0 {
+		return md.interval
+	}
+	bkt := md.getBucket(hash)
+	bkt.rwLock.RLock()
+	metricName := bkt.hash2Name[hash]
+	bkt.rwLock.RUnlock()
+	return md.metricInterval(metricName)
+}
+
+// metricIntervalByID returns the interval of the metric by metric id
+func (md *memoryDatabase) metricIntervalByID(metricID uint32) timeutil.Interval {
+	if len(md.metricIntervals) == 0 {
+		return md.interval
+	}
+	item, ok := md.metricID2Hash.Load(metricID)
+	if !ok {
+		return md.interval
+	}
+	return md.metricIntervalByHash(item.(uint64))
+}
+
 func (md *memoryDatabase) MemSize() int {
 	return int(md.size.Load())
 }
@@ -4,17 +4,26 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/lindb/lindb/constants"
+	"github.com/lindb/lindb/kv"
+	"github.com/lindb/lindb/pkg/option"
 	"github.com/lindb/lindb/pkg/timeutil"
 	pb "github.com/lindb/lindb/rpc/proto/field"
 	"github.com/lindb/lindb/series"
+	"github.com/lindb/lindb/series/field"
+	"github.com/lindb/lindb/sql/stmt"
 	"github.com/lindb/lindb/tsdb/metadb"
+	"github.com/lindb/lindb/tsdb/tblstore/metricsdata"
 
+	"github.com/RoaringBitmap/roaring"
 	"github.com/cespare/xxhash"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
 )
 
 var cfg = MemoryDatabaseCfg{
@@ -41,6 +50,19 @@ func Test_MemoryDatabase_addFamilyTime(t *testing.T) {
 	md.addFamilyTime(1)
 	md.addFamilyTime(1)
 	md.addFamilyTime(2)
+	assert.Equal(t, 2, md.CountFamilies())
+	// lastWroteFamilyTime changed, but family exists
+	md.addFamilyTime(1)
+	assert.Equal(t, 2, md.CountFamilies())
+	assert.Equal(t, []int64{1, 2}, md.Families())
+
+	// family written after removed by flushing is added again
+	md.removeFamilyTime(1)
+	assert.Equal(t, []int64{2}, md.Families())
+	md.addFamilyTime(1)
+	assert.Equal(t, []int64{1, 2}, md.Families())
+	md.removeFamilyTime(3)
+	assert.Equal(t, 2, md.CountFamilies())
 }
 
 func Test_MemoryDatabase_Write(t *testing.T) {
@@ -85,6 +107,192 @@ func Test_MemoryDatabase_Write(t *testing.T) {
 	assert.Len(t, md.Families(), 3)
 }
 
+func Test_MemoryDatabase_Write_quota(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	quotaCfg := cfg
+	quotaCfg.Quota = option.QuotaOption{MaxSeries: 10, MaxMemory: 1, MaxIngestRate: 1000000}
+	mdINTF := NewMemoryDatabase(ctx, quotaCfg)
+	md := mdINTF.(*memoryDatabase)
+
+	mockMStore := NewMockmStoreINTF(ctrl)
+	mockMStore.EXPECT().GetMetricID().Return(uint32(1)).AnyTimes()
+	mockMStore.EXPECT().GetTagsInUse().Return(10).AnyTimes()
+	mockMStore.EXPECT().Write(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(metric *pb.Metric, writeCtx writeContext) (int, error) {
+			// series quota exceeded
+			assert.False(t, writeCtx.newSeriesAllowed())
+			return 1024, nil
+		}).AnyTimes()
+	hash := xxhash.Sum64String("test1")
+	md.getBucket(hash).hash2MStore[hash] = mockMStore
+	md.seriesCount.Store(10)
+
+	assert.Nil(t, md.Write(&pb.Metric{Name: "test1", Timestamp: timeutil.Now()}))
+	stats := md.QuotaStats()
+	assert.Equal(t, QuotaStats{
+		Series:        10,
+		MaxSeries:     10,
+		MemSize:       1024,
+		MaxMemSize:    1024 * 1024,
+		IngestRate:    stats.IngestRate,
+		MaxIngestRate: 1000000,
+	}, stats)
+	// memory quota exceeded
+	md.size.Store(1024 * 1024)
+	assert.Equal(t, &series.WriteError{MetricName: "test1", Code: series.WriteErrorQuota, Err: series.ErrMemoryQuotaExceeded},
+		md.Write(&pb.Metric{Name: "test1", Timestamp: timeutil.Now()}))
+}
+
+func Test_MemoryDatabase_Write_timestampOutOfRange(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := int64(1564300800000)
+	rangeCfg := cfg
+	rangeCfg.Generator = makeMockIDGenerator(ctrl)
+	rangeCfg.Clock = timeutil.NewFakeClock(now)
+	rangeCfg.Ahead = 10 * timeutil.OneMinute
+	rangeCfg.Behind = timeutil.OneHour
+	md := NewMemoryDatabase(ctx, rangeCfg)
+	newMetric := func(timestamp int64) *pb.Metric {
+		return &pb.Metric{
+			Name:      "cpu",
+			Timestamp: timestamp,
+			Tags:      map[string]string{"host": "1.1.1.1"},
+			Fields:    []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}}},
+		}
+	}
+	outOfRange := &series.WriteError{MetricName: "cpu", Code: series.WriteErrorTimestamp, Err: series.ErrTimestampOutOfRange}
+	// just inside
+	assert.NoError(t, md.Write(newMetric(now-timeutil.OneHour)))
+	assert.NoError(t, md.Write(newMetric(now+10*timeutil.OneMinute)))
+	assert.Equal(t, int64(0), md.OutOfRangeWrites())
+	families := md.Families()
+	// just outside
+	assert.Equal(t, outOfRange, md.Write(newMetric(now-timeutil.OneHour-1)))
+	assert.Equal(t, outOfRange, md.Write(newMetric(now+10*timeutil.OneMinute+1)))
+	assert.Equal(t, int64(2), md.OutOfRangeWrites())
+	// batch
+	written, err := md.WriteBatch([]*pb.Metric{newMetric(now), newMetric(now + timeutil.OneDay)})
+	assert.Equal(t, 1, written)
+	assert.Equal(t, outOfRange, err)
+	assert.Equal(t, int64(3), md.OutOfRangeWrites())
+	// family times are not polluted by the rejected metrics
+	assert.Equal(t, families, md.Families())
+
+	// unlimited
+	unlimitedCfg := rangeCfg
+	unlimitedCfg.Ahead = 0
+	unlimitedCfg.Behind = 0
+	md = NewMemoryDatabase(ctx, unlimitedCfg)
+	assert.NoError(t, md.Write(newMetric(now+timeutil.OneDay)))
+	assert.NoError(t, md.Write(newMetric(now-timeutil.OneDay)))
+	assert.Equal(t, int64(0), md.OutOfRangeWrites())
+}
+
+func Test_MemoryDatabase_Validate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := int64(1564300800000)
+	// ids are generated only once by the write
+	mockGen := metadb.NewMockIDGenerator(ctrl)
+	mockGen.EXPECT().GenMetricID("cpu").Return(uint32(1))
+	mockGen.EXPECT().GenFieldID(uint32(1), "f1", field.SumField).Return(uint16(1), nil)
+	mockGen.EXPECT().GenTagKeyID(uint32(1), "host").Return(uint32(1))
+	validateCfg := cfg
+	validateCfg.Generator = mockGen
+	validateCfg.Clock = timeutil.NewFakeClock(now)
+	validateCfg.Behind = timeutil.OneHour
+	validateCfg.MaxTagsPerMetric = 2
+	validateCfg.Quota = option.QuotaOption{MaxSeries: 1}
+	md := NewMemoryDatabase(ctx, validateCfg)
+	newMetric := func(host string, timestamp int64, f *pb.Field) *pb.Metric {
+		return &pb.Metric{
+			Name:      "cpu",
+			Timestamp: timestamp,
+			Tags:      map[string]string{"host": host},
+			Fields:    []*pb.Field{f},
+		}
+	}
+	sumField := &pb.Field{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}}
+	gaugeField := &pb.Field{Name: "f1", Field: &pb.Field_Gauge{Gauge: &pb.Gauge{Value: 1.0}}}
+
+	// metric not exist
+	assert.NoError(t, md.Validate(newMetric("1.1.1.1", now, sumField)))
+	assert.Zero(t, md.CountMetrics())
+	assert.NoError(t, md.Write(newMetric("1.1.1.1", now, sumField)))
+	memSize := md.MemSize()
+	families := md.Families()
+
+	// accepted
+	assert.NoError(t, md.Validate(newMetric("1.1.1.1", now, sumField)))
+	// series quota exceeded
+	assert.Equal(t,
+		&series.WriteError{MetricName: "cpu", Code: series.WriteErrorQuota, Err: series.ErrSeriesQuotaExceeded},
+		md.Validate(newMetric("1.1.1.2", now, sumField)))
+	// timestamp out of window
+	assert.Equal(t,
+		&series.WriteError{MetricName: "cpu", Code: series.WriteErrorTimestamp, Err: series.ErrTimestampOutOfRange},
+		md.Validate(newMetric("1.1.1.1", now-timeutil.OneDay, sumField)))
+	assert.Zero(t, md.OutOfRangeWrites())
+	// type mismatch
+	assert.Equal(t,
+		&series.WriteError{MetricName: "cpu", Code: series.WriteErrorSchema, Err: series.ErrWrongFieldType},
+		md.Validate(newMetric("1.1.1.1", now, gaugeField)))
+	// too many tags per metric
+	tooManyTags := newMetric("1.1.1.1", now, sumField)
+	tooManyTags.Tags = map[string]string{"host": "1.1.1.1", "ip": "1.1.1.1", "zone": "sh"}
+	assert.Equal(t,
+		&series.WriteError{MetricName: "cpu", Code: series.WriteErrorSchema, Err: series.ErrTooManyTagsPerMetric},
+		md.Validate(tooManyTags))
+	// full store
+	_, err := md.SetMaxTagsLimit("cpu", 1)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		&series.WriteError{MetricName: "cpu", Code: series.WriteErrorCardinality, Err: series.ErrTooManyTags},
+		md.Validate(newMetric("1.1.1.2", now, sumField)))
+
+	// no side effects
+	assert.Equal(t, memSize, md.MemSize())
+	assert.Equal(t, families, md.Families())
+	assert.Equal(t, 1, md.CountMetrics())
+	assert.Equal(t, 1, md.CountTags("cpu"))
+	assert.Equal(t, 1, md.CountFields("cpu"))
+}
+
+func Test_MemoryDatabase_Write_ingestRateQuota(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	quotaCfg := cfg
+	quotaCfg.Quota = option.QuotaOption{MaxIngestRate: 5}
+	mdINTF := NewMemoryDatabase(ctx, quotaCfg)
+	md := mdINTF.(*memoryDatabase)
+	// no series quota
+	assert.Nil(t, md.newSeriesAllowed())
+
+	// rate window of current second is full
+	md.ingestSecond.Store(timeutil.Now() / timeutil.OneSecond)
+	md.ingestCount.Store(5)
+	assert.Equal(t, series.ErrIngestRateQuotaExceeded, md.checkQuota())
+	assert.Equal(t, int64(5), md.ingestCount.Load())
+	// new rate window
+	md.ingestSecond.Store(0)
+	assert.Nil(t, md.checkQuota())
+	assert.Equal(t, int64(1), md.ingestCount.Load())
+}
+
 func Test_MemoryDatabase_setLimitations_countTags_countMetrics_resetMStore(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -184,6 +392,50 @@ func Test_MemoryDatabase_evictor(t *testing.T) {
 	time.Sleep(time.Millisecond * 10)
 }
 
+func Test_MemoryDatabase_evictor_interval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	familyTime := int64(1564300800000)
+	clock := timeutil.NewFakeClock(familyTime)
+	evictCfg := cfg
+	evictCfg.Generator = makeMockIDGenerator(ctrl)
+	evictCfg.Clock = clock
+	// the interval is long enough that only the ticks of fake clock trigger the eviction
+	evictCfg.EvictInterval = time.Hour
+	md := NewMemoryDatabase(ctx, evictCfg).(*memoryDatabase)
+	assert.Equal(t, time.Hour, md.evictInterval)
+	assert.Equal(t, defaultEvictInterval, NewMemoryDatabase(ctx, cfg).(*memoryDatabase).evictInterval)
+
+	assert.Nil(t, md.Write(&pb.Metric{
+		Name:      "cpu",
+		Timestamp: familyTime,
+		Tags:      map[string]string{"host": "1.1.1.1"},
+		Fields:    []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}}},
+	}))
+	flusher := makeMockDataFlusher(ctrl)
+	flusher.EXPECT().FlushVersion(gomock.Any()).AnyTimes()
+	assert.Nil(t, md.FlushFamilyTo(flusher, familyTime))
+	// wait for the eviction after flush, the series is not expired yet
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, md.countSeries())
+	// series is expired without any flush later, evicted by ticker of idle database
+	clock.Advance(seriesTTL.Load() + time.Hour)
+	evicted := false
+	for i := 0; i < 100 && !evicted; i++ {
+		time.Sleep(10 * time.Millisecond)
+		evicted = md.countSeries() == 0
+	}
+	assert.True(t, evicted)
+	_, ok := md.getMStore("cpu")
+	assert.False(t, ok)
+
+	// evictor exits after context done
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+}
+
 func Test_FindSeriesIDsByExpr_GetSeriesIDsForTag(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -210,14 +462,330 @@ func Test_FindSeriesIDsByExpr_GetSeriesIDsForTag(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func Test_MemoryDatabase_FindSeriesIDsByExpr_flushedIndex(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	flushedIndex := series.NewMockFilter(ctrl)
+	c := cfg
+	c.FlushedIndex = flushedIndex
+	md := NewMemoryDatabase(ctx, c).(*memoryDatabase)
+	expr := &stmt.EqualsExpr{Key: "host", Value: "1.1.1.1"}
+	timeRange := timeutil.TimeRange{Start: 10, End: 20}
+	flushedVersion := series.Version(1)
+	flushedSet := func() *series.MultiVerSeriesIDSet {
+		set := series.NewMultiVerSeriesIDSet()
+		set.Add(flushedVersion, roaring.BitmapOf(1, 2))
+		return set
+	}
+
+	// data only on disk
+	flushedIndex.EXPECT().FindSeriesIDsByExpr(uint32(1), expr, timeRange).Return(flushedSet(), nil)
+	set, err := md.FindSeriesIDsByExpr(1, expr, timeRange)
+	assert.Nil(t, err)
+	assert.Equal(t, roaring.BitmapOf(1, 2), set.Versions()[flushedVersion])
+	// not found in memory and disk
+	flushedIndex.EXPECT().FindSeriesIDsByExpr(uint32(1), expr, timeRange).Return(nil, series.ErrNotFound)
+	_, err = md.FindSeriesIDsByExpr(1, expr, timeRange)
+	assert.Equal(t, series.ErrNotFound, err)
+
+	// data both in memory and on disk
+	memVersion := series.Version(2)
+	memSet := series.NewMultiVerSeriesIDSet()
+	memSet.Add(memVersion, roaring.BitmapOf(3))
+	mockMStore := NewMockmStoreINTF(ctrl)
+	mockMStore.EXPECT().FindSeriesIDsByExpr(expr).Return(memSet, nil).AnyTimes()
+	md.getBucket(3333).hash2MStore[3333] = mockMStore
+	md.metricID2Hash.Store(uint32(1), uint64(3333))
+	flushedIndex.EXPECT().FindSeriesIDsByExpr(uint32(1), expr, timeRange).Return(flushedSet(), nil)
+	set, err = md.FindSeriesIDsByExpr(1, expr, timeRange)
+	assert.Nil(t, err)
+	assert.Len(t, set.Versions(), 2)
+	assert.Equal(t, roaring.BitmapOf(1, 2), set.Versions()[flushedVersion])
+	assert.Equal(t, roaring.BitmapOf(3), set.Versions()[memVersion])
+	// error of flushed index, returns the series in memory
+	flushedIndex.EXPECT().FindSeriesIDsByExpr(uint32(1), expr, timeRange).Return(nil, fmt.Errorf("err"))
+	set, err = md.FindSeriesIDsByExpr(1, expr, timeRange)
+	assert.Nil(t, err)
+	assert.Equal(t, roaring.BitmapOf(3), set.Versions()[memVersion])
+	// all series of tag key both in memory and on disk
+	memTagSet := series.NewMultiVerSeriesIDSet()
+	memTagSet.Add(memVersion, roaring.BitmapOf(3, 4))
+	mockMStore.EXPECT().GetSeriesIDsForTag("host").Return(memTagSet, nil)
+	flushedIndex.EXPECT().GetSeriesIDsForTag(uint32(1), "host", timeRange).Return(flushedSet(), nil)
+	set, err = md.GetSeriesIDsForTag(1, "host", timeRange)
+	assert.Nil(t, err)
+	assert.Equal(t, roaring.BitmapOf(1, 2), set.Versions()[flushedVersion])
+	assert.Equal(t, roaring.BitmapOf(3, 4), set.Versions()[memVersion])
+	// all series of metric both in memory and on disk
+	memMetricSet := series.NewMultiVerSeriesIDSet()
+	memMetricSet.Add(memVersion, roaring.BitmapOf(3, 4, 5))
+	mockMStore.EXPECT().GetSeriesIDsForMetric().Return(memMetricSet, nil)
+	flushedIndex.EXPECT().GetSeriesIDsForMetric(uint32(1), timeRange).Return(flushedSet(), nil)
+	set, err = md.GetSeriesIDsForMetric(1, timeRange)
+	assert.Nil(t, err)
+	assert.Equal(t, roaring.BitmapOf(1, 2), set.Versions()[flushedVersion])
+	assert.Equal(t, roaring.BitmapOf(3, 4, 5), set.Versions()[memVersion])
+	// mStore error
+	mockMStore.EXPECT().GetSeriesIDsForMetric().Return(nil, fmt.Errorf("err"))
+	_, err = md.GetSeriesIDsForMetric(1, timeRange)
+	assert.Error(t, err)
+	// not found in memory and disk
+	flushedIndex.EXPECT().GetSeriesIDsForMetric(uint32(2), timeRange).Return(nil, series.ErrNotFound)
+	_, err = md.GetSeriesIDsForMetric(2, timeRange)
+	assert.Equal(t, series.ErrNotFound, err)
+}
+
+func Test_MemoryDatabase_FindStaleSeriesIDs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mdINTF := NewMemoryDatabase(ctx, cfg)
+	md := mdINTF.(*memoryDatabase)
+	threshold := timeutil.Now() - timeutil.OneMinute*5
+	// not exist
+	_, _, err := md.FindStaleSeriesIDs(1, threshold)
+	assert.Equal(t, series.ErrNotFound, err)
+	// exist
+	mockMStore := NewMockmStoreINTF(ctrl)
+	mockMStore.EXPECT().FindStaleSeriesIDs(int64(10*1000), threshold).
+		Return(series.NewMultiVerSeriesIDSet(), series.NewMultiVerSeriesIDSet())
+	md.getBucket(3333).hash2MStore[3333] = mockMStore
+	md.metricID2Hash.Store(uint32(1), uint64(3333))
+	stale, known, err := md.FindStaleSeriesIDs(1, threshold)
+	assert.Nil(t, err)
+	assert.NotNil(t, stale)
+	assert.NotNil(t, known)
+}
+
+func Test_MemoryDatabase_CountSlots(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockGen := metadb.NewMockIDGenerator(ctrl)
+	mockGen.EXPECT().GenMetricID("cpu").Return(uint32(1)).AnyTimes()
+	mockGen.EXPECT().GenFieldID(uint32(1), "f1", field.SumField).Return(uint16(1), nil).AnyTimes()
+	mockGen.EXPECT().GenFieldID(uint32(1), "f2", field.SumField).Return(uint16(2), nil).AnyTimes()
+	mockGen.EXPECT().GenTagKeyID(gomock.Any(), gomock.Any()).Return(uint32(1)).AnyTimes()
+	slotsCfg := cfg
+	slotsCfg.Generator = mockGen
+	md := NewMemoryDatabase(ctx, slotsCfg)
+
+	// metric not found
+	_, err := md.CountSlots(1, timeutil.TimeRange{})
+	assert.Equal(t, series.ErrNotFound, err)
+
+	familyTime := int64(1564300800000)
+	interval := 10 * timeutil.OneSecond
+	write := func(host, fieldName string, slots ...int) {
+		for _, slot := range slots {
+			assert.Nil(t, md.Write(&pb.Metric{
+				Name:      "cpu",
+				Timestamp: familyTime + int64(slot)*interval,
+				Tags:      map[string]string{"host": host},
+				Fields: []*pb.Field{{Name: fieldName, Field: &pb.Field_Sum{Sum: &pb.Sum{
+					Value: 1.0,
+				}}}},
+			}))
+		}
+	}
+	// slot 2 has value of both fields
+	write("a", "f1", 0, 1, 2)
+	write("a", "f2", 2, 3)
+	// slot 40 exceeds the time window, the slots before are compressed
+	write("b", "f1", 5, 40)
+
+	counts, err := md.CountSlots(1, timeutil.TimeRange{Start: familyTime, End: familyTime + timeutil.OneHour})
+	assert.Nil(t, err)
+	assert.Len(t, counts, 1)
+	for _, seriesCounts := range counts {
+		assert.Equal(t, map[uint32]int{1: 4, 2: 2}, seriesCounts)
+	}
+	// slots out of time range are not counted
+	counts, err = md.CountSlots(1, timeutil.TimeRange{Start: familyTime + interval, End: familyTime + 2*interval})
+	assert.Nil(t, err)
+	for _, seriesCounts := range counts {
+		assert.Equal(t, map[uint32]int{1: 2}, seriesCounts)
+	}
+}
+
+func Test_MemoryDatabase_evict_fakeClock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	familyTime := int64(1564300800000)
+	clock := timeutil.NewFakeClock(familyTime)
+	evictCfg := cfg
+	evictCfg.Generator = makeMockIDGenerator(ctrl)
+	evictCfg.Clock = clock
+	md := NewMemoryDatabase(ctx, evictCfg).(*memoryDatabase)
+
+	assert.Nil(t, md.Write(&pb.Metric{
+		Name:      "cpu",
+		Timestamp: familyTime,
+		Tags:      map[string]string{"host": "1.1.1.1"},
+		Fields: []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{
+			Value: 1.0,
+		}}}},
+	}))
+	evictAll := func() {
+		for i := range md.mStoresList {
+			md.evict(md.mStoresList[i])
+		}
+	}
+	// series has data in memory
+	clock.Advance(seriesTTL.Load() + time.Minute)
+	evictAll()
+	assert.Equal(t, 1, md.countSeries())
+
+	// series is flushed, but written recently
+	clock.Set(familyTime)
+	flusher := makeMockDataFlusher(ctrl)
+	flusher.EXPECT().FlushVersion(gomock.Any()).AnyTimes()
+	assert.Nil(t, md.FlushFamilyTo(flusher, familyTime))
+	evictAll()
+	assert.Equal(t, 1, md.countSeries())
+
+	// series is expired after ttl
+	clock.Advance(seriesTTL.Load() + time.Second)
+	evictAll()
+	assert.Equal(t, 0, md.countSeries())
+	_, ok := md.getMStore("cpu")
+	assert.False(t, ok)
+}
+
+func Test_MemoryDatabase_EvictToSize(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	familyTime := int64(1564300800000)
+	clock := timeutil.NewFakeClock(familyTime)
+	evictCfg := cfg
+	evictCfg.Generator = makeMockIDGenerator(ctrl)
+	evictCfg.Clock = clock
+	md := NewMemoryDatabase(ctx, evictCfg).(*memoryDatabase)
+	// nothing to evict
+	assert.Zero(t, md.EvictToSize(0))
+
+	// writes series at different time
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, md.Write(&pb.Metric{
+			Name:      "cpu",
+			Timestamp: familyTime,
+			Tags:      map[string]string{"host": strconv.Itoa(i)},
+			Fields: []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{
+				Value: 1.0,
+			}}}},
+		}))
+		clock.Advance(time.Second)
+	}
+	// series has data in memory
+	assert.Zero(t, md.EvictToSize(0))
+	assert.Equal(t, 3, md.countSeries())
+
+	flusher := makeMockDataFlusher(ctrl)
+	flusher.EXPECT().FlushVersion(gomock.Any()).AnyTimes()
+	assert.Nil(t, md.FlushFamilyTo(flusher, familyTime))
+	size := md.MemSize()
+	// stops once under the target
+	evictedSize := md.EvictToSize(size - 1)
+	assert.True(t, evictedSize > 0)
+	assert.True(t, md.MemSize() < size)
+	assert.Equal(t, 2, md.countSeries())
+	// the least-recently-written series is evicted first
+	mStore, ok := md.getMStore("cpu")
+	assert.True(t, ok)
+	tagIdx := mStore.(*metricStore).mutable
+	_, ok = tagIdx.GetTStoreBySeriesID(1)
+	assert.False(t, ok)
+	_, ok = tagIdx.GetTStoreBySeriesID(2)
+	assert.True(t, ok)
+	// evicts all, then removes the empty mStore
+	assert.True(t, md.EvictToSize(0) > 0)
+	assert.Equal(t, 0, md.countSeries())
+	_, ok = md.getMStore("cpu")
+	assert.False(t, ok)
+}
+
+func Test_MemoryDatabase_evict_fieldRetention(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockGen := metadb.NewMockIDGenerator(ctrl)
+	mockGen.EXPECT().GenMetricID("cpu").Return(uint32(1)).AnyTimes()
+	mockGen.EXPECT().GenFieldID(uint32(1), "debug", field.SumField).Return(uint16(1), nil).AnyTimes()
+	mockGen.EXPECT().GenFieldID(uint32(1), "f1", field.SumField).Return(uint16(2), nil).AnyTimes()
+	mockGen.EXPECT().GenTagKeyID(gomock.Any(), gomock.Any()).Return(uint32(1)).AnyTimes()
+
+	familyTime := int64(1564300800000)
+	lastFamilyTime := familyTime + 2*timeutil.OneHour
+	clock := timeutil.NewFakeClock(lastFamilyTime + 30*timeutil.OneMinute)
+	retentionCfg := cfg
+	retentionCfg.Generator = mockGen
+	retentionCfg.Clock = clock
+	retentionCfg.FieldRetention = map[string]int64{"debug": timeutil.OneHour}
+	md := NewMemoryDatabase(ctx, retentionCfg).(*memoryDatabase)
+
+	interval := 10 * timeutil.OneSecond
+	write := func(fieldName string, timestamp int64) {
+		assert.Nil(t, md.Write(&pb.Metric{
+			Name:      "cpu",
+			Timestamp: timestamp,
+			Tags:      map[string]string{"host": "1.1.1.1"},
+			Fields: []*pb.Field{{Name: fieldName, Field: &pb.Field_Sum{Sum: &pb.Sum{
+				Value: 1.0,
+			}}}},
+		}))
+	}
+	write("debug", familyTime+interval)
+	write("f1", familyTime+2*interval)
+	write("debug", lastFamilyTime+3*interval)
+	write("f1", lastFamilyTime+3*interval)
+	countSlots := func() int {
+		counts, err := md.CountSlots(1, timeutil.TimeRange{Start: familyTime, End: lastFamilyTime + timeutil.OneHour})
+		assert.Nil(t, err)
+		count := 0
+		for _, seriesCounts := range counts {
+			for _, c := range seriesCounts {
+				count += c
+			}
+		}
+		return count
+	}
+	assert.Equal(t, 3, countSlots())
+	size := md.MemSize()
+
+	// old data of short retention field is evicted, the long retention field's is kept
+	for i := range md.mStoresList {
+		md.evict(md.mStoresList[i])
+	}
+	assert.Equal(t, 2, countSlots())
+	assert.True(t, md.MemSize() < size)
+}
+
 func Test_MemoryDatabase_FlushFamilyTo(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	mdINTF := NewMemoryDatabase(ctx, cfg)
+	mdINTF.(*memoryDatabase).addFamilyTime(10)
+	assert.Equal(t, 1, mdINTF.CountFamilies())
 	_ = mdINTF.FlushFamilyTo(nil, 10)
 	_ = mdINTF.FlushFamilyTo(nil, 10)
 	_ = mdINTF.FlushFamilyTo(nil, 10)
+	assert.Equal(t, 0, mdINTF.CountFamilies())
 	time.Sleep(time.Millisecond * 10)
 }
 
@@ -232,7 +800,7 @@ func Test_MemoryDatabase_flushFamilyTo_ok(t *testing.T) {
 
 	mockMStore := NewMockmStoreINTF(ctrl)
 	mockMStore.EXPECT().GetMetricID().Return(uint32(1)).AnyTimes()
-	mockMStore.EXPECT().Evict().Return(100).AnyTimes()
+	mockMStore.EXPECT().Evict(gomock.Any()).Return(100).AnyTimes()
 	mockMStore.EXPECT().IsEmpty().Return(false).AnyTimes()
 
 	returnNil := mockMStore.EXPECT().FlushMetricsDataTo(gomock.Any(), gomock.Any()).Return(100, nil)
@@ -244,6 +812,110 @@ func Test_MemoryDatabase_flushFamilyTo_ok(t *testing.T) {
 	assert.NotNil(t, md.FlushFamilyTo(nil, 10))
 }
 
+func Test_MemoryDatabase_flushFamilyTo_concurrently(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	familyTime := int64(1564300800000)
+	metricID := atomic.NewUint32(0)
+	gen := metadb.NewMockIDGenerator(ctrl)
+	gen.EXPECT().GenMetricID(gomock.Any()).DoAndReturn(func(metricName string) uint32 {
+		return metricID.Inc()
+	}).AnyTimes()
+	gen.EXPECT().GenFieldID(gomock.Any(), gomock.Any(), gomock.Any()).Return(uint16(1), nil).AnyTimes()
+	gen.EXPECT().GenTagKeyID(gomock.Any(), gomock.Any()).Return(uint32(1)).AnyTimes()
+	flushCfg := cfg
+	flushCfg.Generator = gen
+	md := NewMemoryDatabase(ctx, flushCfg).(*memoryDatabase)
+	metricCount := 200
+	for i := 0; i < metricCount; i++ {
+		for _, host := range []string{"1.1.1.1", "1.1.1.2"} {
+			assert.NoError(t, md.Write(&pb.Metric{
+				Name:      fmt.Sprintf("cpu.%d", i),
+				Timestamp: familyTime,
+				Tags:      map[string]string{"host": host},
+				Fields:    []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}}},
+			}))
+		}
+	}
+	storesSize := func() (size int) {
+		for _, bkt := range md.mStoresList {
+			_, allMetricStores := bkt.allMetricStores()
+			for _, mStore := range allMetricStores {
+				size += mStore.MemSize()
+			}
+		}
+		return size
+	}
+	sizeBefore, storesSizeBefore := md.MemSize(), storesSize()
+
+	var keys []uint32
+	kvFlusher := kv.NewMockFlusher(ctrl)
+	kvFlusher.EXPECT().Add(gomock.Any(), gomock.Any()).DoAndReturn(func(key uint32, value []byte) error {
+		keys = append(keys, key)
+		return nil
+	}).AnyTimes()
+	assert.NoError(t, md.FlushFamilyTo(metricsdata.NewFlusher(kvFlusher), familyTime))
+	// all the metric-blocks are written in order of metric id
+	assert.Len(t, keys, metricCount)
+	for idx := 1; idx < len(keys); idx++ {
+		assert.True(t, keys[idx-1] < keys[idx])
+	}
+	// the flushed size is accounted
+	assert.True(t, md.MemSize() < sizeBefore)
+	assert.Equal(t, sizeBefore-md.MemSize(), storesSizeBefore-storesSize())
+	// nothing to flush
+	assert.NoError(t, md.FlushFamilyTo(metricsdata.NewFlusher(kvFlusher), familyTime))
+	assert.Len(t, keys, metricCount)
+}
+
+func Test_MemoryDatabase_flushFamilyTo_abort(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	md := NewMemoryDatabase(ctx, cfg).(*memoryDatabase)
+	failedMetricID := uint32(shardingCountOfMStores / 2)
+	for bucketIndex := 0; bucketIndex < shardingCountOfMStores; bucketIndex++ {
+		mockMStore := NewMockmStoreINTF(ctrl)
+		mockMStore.EXPECT().GetMetricID().Return(uint32(bucketIndex)).AnyTimes()
+		mockMStore.EXPECT().Evict(gomock.Any()).Return(0).AnyTimes()
+		mockMStore.EXPECT().IsEmpty().Return(false).AnyTimes()
+		if uint32(bucketIndex) == failedMetricID {
+			mockMStore.EXPECT().FlushMetricsDataTo(gomock.Any(), gomock.Any()).Return(10, fmt.Errorf("error"))
+		} else {
+			mockMStore.EXPECT().FlushMetricsDataTo(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(flusher metricsdata.Flusher, flushCtx flushContext) (int, error) {
+					flusher.FlushFieldMetas(nil)
+					return 10, flusher.FlushMetric(flushCtx.metricID)
+				}).MaxTimes(1)
+		}
+		md.mStoresList[bucketIndex].hash2MStore[uint64(bucketIndex)] = mockMStore
+	}
+	md.size.Store(int32(10 * shardingCountOfMStores))
+	// the metric-blocks before the failed one are written in order
+	var metricIDs []uint32
+	flusher := metricsdata.NewMockFlusher(ctrl)
+	flusher.EXPECT().FlushFieldMetas(gomock.Any()).AnyTimes()
+	flusher.EXPECT().FlushMetric(gomock.Any()).DoAndReturn(func(metricID uint32) error {
+		metricIDs = append(metricIDs, metricID)
+		return nil
+	}).AnyTimes()
+	assert.Error(t, md.FlushFamilyTo(flusher, 10))
+	assert.Len(t, metricIDs, int(failedMetricID))
+	for idx, metricID := range metricIDs {
+		assert.Equal(t, uint32(idx), metricID)
+	}
+	// the metric stores out of the flushing window keep their data, the family is flushed again next time
+	assert.True(t, md.MemSize() >= 10*(shardingCountOfMStores-int(failedMetricID)-2*constants.FlushConcurrency))
+	assert.True(t, md.MemSize() < 10*(shardingCountOfMStores-int(failedMetricID)))
+	assert.Zero(t, md.MemSize()%10)
+	assert.Equal(t, []int64{10}, md.Families())
+}
+
 func Test_MemoryDatabase_flushIndexTo(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -294,6 +966,17 @@ func Test_MemoryDatabase_GetTagValues(t *testing.T) {
 	_, err = mdINTF.GetTagValues(3334, nil, 1, nil)
 	assert.NotNil(t, err)
 
+	// multi versions
+	seriesIDs := series.NewMultiVerSeriesIDSet()
+	seriesIDs.Add(1, roaring.BitmapOf(1))
+	seriesIDs.Add(2, roaring.BitmapOf(2))
+	mockMStore.EXPECT().GetTagValuesByVersions([]string{"host"}, seriesIDs).
+		Return(map[series.Version]map[uint32][]string{1: {1: {"a"}}, 2: {2: {"b"}}}, nil)
+	tagValues, err := mdINTF.GetTagValuesByVersions(3333, []string{"host"}, seriesIDs)
+	assert.Nil(t, err)
+	assert.Equal(t, map[series.Version]map[uint32][]string{1: {1: {"a"}}, 2: {2: {"b"}}}, tagValues)
+	_, err = mdINTF.GetTagValuesByVersions(3334, []string{"host"}, seriesIDs)
+	assert.Equal(t, series.ErrNotFound, err)
 }
 
 func Test_MemoryDatabase_Suggset(t *testing.T) {
@@ -316,6 +999,120 @@ func Test_MemoryDatabase_Suggset(t *testing.T) {
 
 	assert.Nil(t, md.SuggestTagKeys("test", "", 100))
 	assert.Nil(t, md.SuggestTagValues("test", "", "", 100))
+
+	// top tag values
+	assert.Nil(t, md.TopTagValues("", "host", 10))
+	mockMStore.EXPECT().TopTagValues("host", 10).Return([]series.TagValueCount{{TagValue: "a", SeriesCount: 2}})
+	assert.Equal(t, []series.TagValueCount{{TagValue: "a", SeriesCount: 2}}, md.TopTagValues("test", "host", 10))
+}
+
+func Test_MemoryDatabase_SuggestMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	suggestCfg := cfg
+	suggestCfg.Generator = makeMockIDGenerator(ctrl)
+	md := NewMemoryDatabase(ctx, suggestCfg)
+	for _, metricName := range []string{"cpu.usage", "mem.used", "cpu.idle", "cpu.load", "disk.free"} {
+		assert.NoError(t, md.Write(&pb.Metric{
+			Name:      metricName,
+			Timestamp: timeutil.Now(),
+			Tags:      map[string]string{"host": "1.1.1.1"},
+			Fields:    []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}}},
+		}))
+	}
+	assert.Equal(t, []string{"cpu.idle", "cpu.load", "cpu.usage"}, md.SuggestMetrics("cpu.", 10))
+	// truncated by limit
+	assert.Equal(t, []string{"cpu.idle", "cpu.load"}, md.SuggestMetrics("cpu.", 2))
+	assert.Equal(t, []string{"cpu.idle", "cpu.load", "cpu.usage", "disk.free", "mem.used"}, md.SuggestMetrics("", 10))
+	assert.Nil(t, md.SuggestMetrics("net", 10))
+	assert.Nil(t, md.SuggestMetrics("cpu", 0))
+	// deleted metric
+	assert.NoError(t, md.DeleteMetric("cpu.load"))
+	assert.Equal(t, []string{"cpu.idle", "cpu.usage"}, md.SuggestMetrics("cpu", 10))
+}
+
+func Test_MemoryDatabase_CountFields(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	countCfg := cfg
+	countCfg.Generator = makeMockIDGenerator(ctrl)
+	md := NewMemoryDatabase(ctx, countCfg)
+	assert.Equal(t, -1, md.CountFields("cpu"))
+	write := func(fieldNames ...string) {
+		var fields []*pb.Field
+		for _, fieldName := range fieldNames {
+			fields = append(fields, &pb.Field{Name: fieldName, Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}})
+		}
+		assert.NoError(t, md.Write(&pb.Metric{
+			Name:      "cpu",
+			Timestamp: timeutil.Now(),
+			Tags:      map[string]string{"host": "1.1.1.1"},
+			Fields:    fields,
+		}))
+	}
+	write("f1", "f2", "f3")
+	assert.Equal(t, 3, md.CountFields("cpu"))
+	// existed fields are not counted again
+	write("f2", "f3", "f4")
+	assert.Equal(t, 4, md.CountFields("cpu"))
+	assert.Equal(t, -1, md.CountFields("mem"))
+}
+
+func Test_MemoryDatabase_MemSizeByMetric(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	statCfg := cfg
+	statCfg.Generator = makeMockIDGenerator(ctrl)
+	md := NewMemoryDatabase(ctx, statCfg)
+	assert.Empty(t, md.MemSizeByMetric(10))
+	write := func(metricName string, seriesCount int, fieldNames ...string) {
+		var fields []*pb.Field
+		for _, fieldName := range fieldNames {
+			fields = append(fields, &pb.Field{Name: fieldName, Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}})
+		}
+		for i := 0; i < seriesCount; i++ {
+			assert.NoError(t, md.Write(&pb.Metric{
+				Name:      metricName,
+				Timestamp: timeutil.Now(),
+				Tags:      map[string]string{"host": strconv.Itoa(i)},
+				Fields:    fields,
+			}))
+		}
+	}
+	write("cpu.usage", 10, "f1")
+	write("mem.used", 1, "f1")
+	write("disk.free", 30, "f1", "f2")
+	write("net.in", 1, "f1")
+
+	stats := md.MemSizeByMetric(10)
+	assert.Len(t, stats, 4)
+	var names []string
+	for idx, stat := range stats {
+		names = append(names, stat.MetricName)
+		assert.True(t, stat.MemSize > 0)
+		if idx > 0 {
+			assert.True(t, stats[idx-1].MemSize >= stat.MemSize)
+		}
+	}
+	// ties are ordered by metric name
+	assert.Equal(t, []string{"disk.free", "cpu.usage", "mem.used", "net.in"}, names)
+	assert.Equal(t, stats[2].MemSize, stats[3].MemSize)
+	assert.Equal(t, 30, stats[0].TagCount)
+	assert.Equal(t, 2, stats[0].FieldCount)
+	assert.Equal(t, 10, stats[1].TagCount)
+	assert.Equal(t, 1, stats[1].FieldCount)
+	// truncated by top
+	assert.Equal(t, stats[:2], md.MemSizeByMetric(2))
+	assert.Nil(t, md.MemSizeByMetric(0))
 }
 
 func Test_MemoryDatabase_Scan(t *testing.T) {
@@ -348,3 +1145,638 @@ func Test_MemoryDatabase_MemSize(t *testing.T) {
 
 	assert.Zero(t, md.MemSize())
 }
+
+func Test_MemoryDatabase_DeleteTagValues(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mdINTF := NewMemoryDatabase(ctx, cfg)
+	md := mdINTF.(*memoryDatabase)
+	// metric not exist
+	_, err := mdINTF.DeleteTagValues("test1", "host", "^abc")
+	assert.Equal(t, series.ErrNotFound, err)
+
+	mockMStore := NewMockmStoreINTF(ctrl)
+	mockMStore.EXPECT().DeleteTagValues("host", gomock.Any()).Return(2, 200)
+	hash := xxhash.Sum64String("test1")
+	md.getBucket(hash).hash2MStore[hash] = mockMStore
+	md.size.Store(1000)
+	// bad pattern
+	_, err = mdINTF.DeleteTagValues("test1", "host", "[a")
+	assert.Error(t, err)
+
+	deletedSeries, err := mdINTF.DeleteTagValues("test1", "host", "^abc")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, deletedSeries)
+	assert.Equal(t, 800, mdINTF.MemSize())
+}
+
+func Test_MemoryDatabase_DeleteMetric(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mdINTF := NewMemoryDatabase(ctx, cfg)
+	md := mdINTF.(*memoryDatabase)
+	md.generator = makeMockIDGenerator(ctrl)
+	// metric not exist
+	assert.Error(t, mdINTF.DeleteMetric("cpu"))
+
+	write := func(host string) {
+		assert.NoError(t, mdINTF.Write(&pb.Metric{
+			Name:      "cpu",
+			Timestamp: timeutil.Now(),
+			Tags:      map[string]string{"host": host},
+			Fields:    []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1}}}},
+		}))
+	}
+	write("1.1.1.1")
+	write("1.1.1.2")
+	assert.NoError(t, mdINTF.Write(&pb.Metric{Name: "mem", Timestamp: timeutil.Now()}))
+	assert.Equal(t, 2, mdINTF.CountMetrics())
+	assert.Equal(t, 2, mdINTF.CountTags("cpu"))
+	memSize := mdINTF.MemSize()
+	cpuStore, _ := md.getMStore("cpu")
+	cpuSize := cpuStore.MemSize()
+
+	md.highCardinality.Store("cpu", struct{}{})
+	assert.NoError(t, mdINTF.DeleteMetric("cpu"))
+	assert.Equal(t, 1, mdINTF.CountMetrics())
+	assert.Equal(t, -1, mdINTF.CountTags("cpu"))
+	// high cardinality is notified again for the re-created metric store
+	_, notified := md.highCardinality.Load("cpu")
+	assert.False(t, notified)
+	// the writer holding the deleted metric store is rejected
+	assert.Equal(t, errMetricStoreDeleted, md.writeMStore(cpuStore, &pb.Metric{
+		Name:      "cpu",
+		Timestamp: timeutil.Now(),
+		Tags:      map[string]string{"host": "1.1.1.1"},
+		Fields:    []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1}}}},
+	}))
+	assert.Equal(t, memSize-cpuSize, mdINTF.MemSize())
+	_, ok := md.getMStoreByMetricID(cpuStore.GetMetricID())
+	assert.False(t, ok)
+	assert.Error(t, mdINTF.DeleteMetric("cpu"))
+
+	// write re-creates a fresh metric store
+	write("1.1.1.3")
+	assert.Equal(t, 2, mdINTF.CountMetrics())
+	assert.Equal(t, 1, mdINTF.CountTags("cpu"))
+
+	// delete metric under concurrent writes
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			write(strconv.Itoa(i))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_ = mdINTF.DeleteMetric("cpu")
+	}
+	wg.Wait()
+}
+
+func Test_MemoryDatabase_SetMaxTagsLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	limitCfg := cfg
+	limitCfg.Generator = makeMockIDGenerator(ctrl)
+	mdINTF := NewMemoryDatabase(ctx, limitCfg)
+	// metric not exist
+	_, err := mdINTF.SetMaxTagsLimit("cpu", 2)
+	assert.Equal(t, series.ErrNotFound, err)
+
+	write := func(host string) error {
+		return mdINTF.Write(&pb.Metric{
+			Name:      "cpu",
+			Timestamp: timeutil.Now(),
+			Tags:      map[string]string{"host": host},
+			Fields: []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{
+				Value: 1.0,
+			}}}},
+		})
+	}
+	assert.Nil(t, write("1.1.1.1"))
+	// zero limit
+	_, err = mdINTF.SetMaxTagsLimit("cpu", 0)
+	assert.Error(t, err)
+
+	// lowers the limit
+	limit, err := mdINTF.SetMaxTagsLimit("cpu", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), limit)
+	assert.Nil(t, write("1.1.1.2"))
+	assert.Equal(t, &series.WriteError{MetricName: "cpu", Code: series.WriteErrorCardinality, Err: series.ErrTooManyTags},
+		write("1.1.1.3"))
+	// raises the limit, takes effect on subsequent writes
+	limit, err = mdINTF.SetMaxTagsLimit("cpu", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(3), limit)
+	assert.Nil(t, write("1.1.1.3"))
+	assert.Equal(t, 3, mdINTF.CountTags("cpu"))
+}
+
+func Test_MemoryDatabase_SetMaxTagKeysLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	limitCfg := cfg
+	limitCfg.Generator = makeMockIDGenerator(ctrl)
+	mdINTF := NewMemoryDatabase(ctx, limitCfg)
+	// metric not exist
+	_, err := mdINTF.SetMaxTagKeysLimit("cpu", 2)
+	assert.Equal(t, series.ErrNotFound, err)
+
+	write := func(tagKey string) error {
+		return mdINTF.Write(&pb.Metric{
+			Name:      "cpu",
+			Timestamp: timeutil.Now(),
+			Tags:      map[string]string{tagKey: "1.1.1.1"},
+			Fields: []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{
+				Value: 1.0,
+			}}}},
+		})
+	}
+	tooManyTagKeys := &series.WriteError{MetricName: "cpu", Code: series.WriteErrorCardinality, Err: series.ErrTooManyTagKeys}
+	// default limit
+	for i := 0; i < constants.MStoreMaxTagKeysCount; i++ {
+		assert.Nil(t, write("key"+strconv.Itoa(i)))
+	}
+	assert.Equal(t, tooManyTagKeys, write("key"+strconv.Itoa(constants.MStoreMaxTagKeysCount)))
+	// existing tag keys are still writable
+	assert.Nil(t, write("key0"))
+	assert.Nil(t, write("key511"))
+	// zero limit
+	_, err = mdINTF.SetMaxTagKeysLimit("cpu", 0)
+	assert.Error(t, err)
+	// raises the limit, takes effect on subsequent writes
+	limit, err := mdINTF.SetMaxTagKeysLimit("cpu", constants.MStoreMaxTagKeysCount+1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(constants.MStoreMaxTagKeysCount+1), limit)
+	assert.Nil(t, write("key"+strconv.Itoa(constants.MStoreMaxTagKeysCount)))
+	assert.Equal(t, tooManyTagKeys, write("key"+strconv.Itoa(constants.MStoreMaxTagKeysCount+1)))
+	// lowers the limit, rejects only new tag keys
+	_, err = mdINTF.SetMaxTagKeysLimit("cpu", 1)
+	assert.Nil(t, err)
+	assert.Nil(t, write("key1"))
+	assert.Equal(t, tooManyTagKeys, write("key"+strconv.Itoa(constants.MStoreMaxTagKeysCount+1)))
+}
+
+func Test_MemoryDatabase_OnHighCardinality(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	notified := make(chan highCardinalityEvent, 10)
+	highCfg := cfg
+	highCfg.Generator = makeMockIDGenerator(ctrl)
+	highCfg.OnHighCardinality = func(metricName string, used uint32, limit uint32) {
+		notified <- highCardinalityEvent{metricName: metricName, used: used, limit: limit}
+	}
+	mdINTF := NewMemoryDatabase(ctx, highCfg)
+	write := func(host int) {
+		assert.NoError(t, mdINTF.Write(&pb.Metric{
+			Name:      "cpu",
+			Timestamp: timeutil.Now(),
+			Tags:      map[string]string{"host": strconv.Itoa(host)},
+			Fields:    []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}}},
+		}))
+	}
+	write(0)
+	_, err := mdINTF.SetMaxTagsLimit("cpu", 10)
+	assert.NoError(t, err)
+	for host := 1; host < 7; host++ {
+		write(host)
+	}
+	// below 80% of limit
+	assert.Len(t, notified, 0)
+	// crosses 80% of limit, notified once
+	for host := 7; host < 10; host++ {
+		write(host)
+	}
+	select {
+	case event := <-notified:
+		assert.Equal(t, highCardinalityEvent{metricName: "cpu", used: 8, limit: 10}, event)
+	case <-time.After(time.Second):
+		t.Fatal("high cardinality callback is not called")
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, notified, 0)
+
+	// re-armed after the used tags drops below the threshold
+	assert.NoError(t, mdINTF.ResetMetricStore("cpu"))
+	for host := 0; host < 8; host++ {
+		write(host)
+	}
+	select {
+	case event := <-notified:
+		assert.Equal(t, uint32(8), event.used)
+	case <-time.After(time.Second):
+		t.Fatal("high cardinality callback is not called")
+	}
+}
+
+func Test_MemoryDatabase_checkHighCardinality_dropped(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	md := &memoryDatabase{
+		onHighCardinality: func(metricName string, used uint32, limit uint32) {},
+		highCardinalityCh: make(chan highCardinalityEvent),
+	}
+	mStore := NewMockmStoreINTF(ctrl)
+	mStore.EXPECT().GetTagsUsed().Return(9).AnyTimes()
+	mStore.EXPECT().GetMaxTagsLimit().Return(uint32(10)).AnyTimes()
+	// notification is dropped without blocking when the notifier falls behind
+	md.checkHighCardinality("cpu", mStore)
+	_, marked := md.highCardinality.Load("cpu")
+	assert.False(t, marked)
+}
+
+func Test_MemoryDatabase_metricHashCollision(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockGenerator := metadb.NewMockIDGenerator(ctrl)
+	mockGenerator.EXPECT().GenMetricID("cpu").Return(uint32(1))
+	mockGenerator.EXPECT().GenMetricID("mem").Return(uint32(2))
+	collisionCfg := cfg
+	collisionCfg.Generator = mockGenerator
+	// force collision of metric hash
+	collisionCfg.MetricHash = func(metricName string) uint64 { return 10 }
+	mdINTF := NewMemoryDatabase(ctx, collisionCfg)
+	md := mdINTF.(*memoryDatabase)
+
+	cpuStore, err := md.getOrCreateMStore("cpu", md.metricHash("cpu"))
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), cpuStore.GetMetricID())
+	// metric with collided hash is rejected, doesn't share the mStore
+	_, err = md.getOrCreateMStore("mem", md.metricHash("mem"))
+	assert.Equal(t, series.ErrMetricHashCollision, err)
+	assert.Equal(t, &series.WriteError{MetricName: "mem", Code: series.WriteErrorUnknown, Err: series.ErrMetricHashCollision},
+		mdINTF.Write(&pb.Metric{Name: "mem", Timestamp: timeutil.Now()}))
+	mStore, ok := md.getMStore("cpu")
+	assert.True(t, ok)
+	assert.Equal(t, cpuStore, mStore)
+	_, ok = md.getMStore("mem")
+	assert.False(t, ok)
+	assert.Equal(t, -1, mdINTF.CountTags("mem"))
+	assert.Equal(t, 1, mdINTF.CountMetrics())
+
+	// hash is released after the mStore evicted
+	md.evict(md.getBucket(md.metricHash("cpu")))
+	_, ok = md.getMStore("cpu")
+	assert.False(t, ok)
+	memStore, err := md.getOrCreateMStore("mem", md.metricHash("mem"))
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), memStore.GetMetricID())
+	_, ok = md.getMStore("cpu")
+	assert.False(t, ok)
+}
+
+func Test_MemoryDatabase_MetricHash(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	assert.Equal(t, xxhash.Sum64String("cpu"), MetricHash("cpu"))
+	assert.Equal(t, 5, BucketIndex(shardingCountOfMStores*3+5))
+	// default hash
+	defaultCfg := cfg
+	defaultCfg.Generator = makeMockIDGenerator(ctrl)
+	md := NewMemoryDatabase(ctx, defaultCfg).(*memoryDatabase)
+	assert.NoError(t, md.Write(&pb.Metric{
+		Name:      "cpu",
+		Timestamp: timeutil.Now(),
+		Fields:    []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}}},
+	}))
+	_, ok := md.mStoresList[BucketIndex(MetricHash("cpu"))].hash2MStore[MetricHash("cpu")]
+	assert.True(t, ok)
+
+	// custom hash, the expected bucket is computed outside
+	hashes := map[string]uint64{"cpu": 3, "mem": shardingCountOfMStores + 7, "disk": 7}
+	hashCfg := cfg
+	hashCfg.Generator = makeMockIDGenerator(ctrl)
+	hashCfg.MetricHash = func(metricName string) uint64 { return hashes[metricName] }
+	md = NewMemoryDatabase(ctx, hashCfg).(*memoryDatabase)
+	assert.NoError(t, md.Write(&pb.Metric{
+		Name:      "cpu",
+		Timestamp: timeutil.Now(),
+		Fields:    []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}}},
+	}))
+	written, err := md.WriteBatch([]*pb.Metric{
+		{Name: "mem", Timestamp: timeutil.Now(), Fields: []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}}}},
+		{Name: "disk", Timestamp: timeutil.Now(), Fields: []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, written)
+	for metricName, hash := range hashes {
+		bucket := md.mStoresList[BucketIndex(hash)]
+		writtenStore, ok := bucket.hash2MStore[hash]
+		assert.True(t, ok, metricName)
+		assert.Equal(t, metricName, bucket.hash2Name[hash])
+		// reads locate the same metric store
+		mStore, ok := md.getMStore(metricName)
+		assert.True(t, ok, metricName)
+		assert.Equal(t, writtenStore, mStore)
+		created, err := md.getOrCreateMStore(metricName, md.metricHash(metricName))
+		assert.NoError(t, err)
+		assert.Equal(t, writtenStore, created)
+	}
+	assert.Len(t, md.mStoresList[7].hash2MStore, 2)
+	assert.NoError(t, md.DeleteMetric("mem"))
+	_, ok = md.getMStore("mem")
+	assert.False(t, ok)
+}
+
+func Test_MemoryDatabase_WriteWithHash(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mdINTF := NewMemoryDatabase(ctx, cfg)
+	md := mdINTF.(*memoryDatabase)
+	mockMStore := NewMockmStoreINTF(ctrl)
+	mockMStore.EXPECT().GetMetricID().Return(uint32(1)).AnyTimes()
+	mockMStore.EXPECT().Write(gomock.Any(), gomock.Any()).Return(20, nil).Times(2)
+	hash := xxhash.Sum64String("cpu")
+	md.getBucket(hash).hash2Name[hash] = "cpu"
+	md.getBucket(hash).hash2MStore[hash] = mockMStore
+
+	metric := &pb.Metric{Name: "cpu", Timestamp: 1564300800000}
+	assert.Nil(t, mdINTF.WriteWithHash(metric, hash))
+	// validates hash in debug mode
+	md.validateMetricHash = true
+	assert.Equal(t, &series.WriteError{MetricName: "cpu", Code: series.WriteErrorSchema, Err: series.ErrMetricHashMismatch},
+		mdINTF.WriteWithHash(metric, hash+1))
+	assert.Nil(t, mdINTF.WriteWithHash(metric, hash))
+}
+
+// nopMStore discards the written metrics, for benchmarking the overhead of memory database
+type nopMStore struct {
+	mStoreINTF
+}
+
+func (ms *nopMStore) Write(metric *pb.Metric, writeCtx writeContext) (int, error) { return 0, nil }
+
+func (ms *nopMStore) GetMetricID() uint32 { return 1 }
+
+func benchmarkMemoryDatabaseWrite(b *testing.B, precomputed bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mdINTF := NewMemoryDatabase(ctx, cfg)
+	md := mdINTF.(*memoryDatabase)
+	metric := &pb.Metric{Name: "system.cpu.utilization.user.percentage", Timestamp: timeutil.Now()}
+	hash := xxhash.Sum64String(metric.Name)
+	md.getBucket(hash).hash2Name[hash] = metric.Name
+	md.getBucket(hash).hash2MStore[hash] = &nopMStore{}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if precomputed {
+			_ = md.WriteWithHash(metric, hash)
+		} else {
+			_ = md.Write(metric)
+		}
+	}
+}
+
+func Benchmark_MemoryDatabase_Write_hashed(b *testing.B) {
+	benchmarkMemoryDatabaseWrite(b, false)
+}
+
+func Benchmark_MemoryDatabase_Write_precomputedHash(b *testing.B) {
+	benchmarkMemoryDatabaseWrite(b, true)
+}
+
+func Test_MemoryDatabase_WriteBatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	generator := metadb.NewMockIDGenerator(ctrl)
+	batchCfg := cfg
+	batchCfg.Generator = generator
+	batchCfg.Quota = option.QuotaOption{MaxSeries: 100}
+	mdINTF := NewMemoryDatabase(ctx, batchCfg)
+	md := mdINTF.(*memoryDatabase)
+
+	// empty batch
+	written, err := md.WriteBatch(nil)
+	assert.Equal(t, 0, written)
+	assert.Nil(t, err)
+
+	mockMStore := NewMockmStoreINTF(ctrl)
+	mockMStore.EXPECT().GetMetricID().Return(uint32(1)).AnyTimes()
+	mockMStore.EXPECT().GetTagsInUse().Return(1).AnyTimes()
+	mockMStore.EXPECT().Write(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(metric *pb.Metric, writeCtx writeContext) (int, error) {
+			// counts series without locking other buckets
+			assert.True(t, writeCtx.newSeriesAllowed())
+			if metric.Timestamp < 0 {
+				return 0, fmt.Errorf("err")
+			}
+			return 10, nil
+		}).Times(4)
+	for _, metricName := range []string{"cpu", "disk"} {
+		hash := xxhash.Sum64String(metricName)
+		md.getBucket(hash).hash2Name[hash] = metricName
+		md.getBucket(hash).hash2MStore[hash] = mockMStore
+	}
+	// hash of collided is owned by another metric
+	collidedHash := xxhash.Sum64String("collided")
+	md.getBucket(collidedHash).hash2Name[collidedHash] = "other"
+	// store of new metric is created
+	generator.EXPECT().GenMetricID("mem").Return(uint32(3))
+	generator.EXPECT().GenTagKeyID(gomock.Any(), gomock.Any()).Return(uint32(1)).AnyTimes()
+
+	familyTime := int64(1564300800000)
+	written, err = md.WriteBatch([]*pb.Metric{
+		{Name: "cpu", Timestamp: familyTime},
+		{Name: "collided", Timestamp: familyTime},
+		{Name: "disk", Timestamp: familyTime + timeutil.OneHour},
+		{Name: "cpu", Timestamp: -1},
+		{Name: "disk", Timestamp: familyTime + timeutil.OneHour},
+		{Name: "mem", Timestamp: familyTime},
+	})
+	// metrics after failure are written
+	assert.Equal(t, 4, written)
+	assert.Error(t, err)
+	assert.Equal(t, []int64{familyTime, familyTime + timeutil.OneHour}, md.Families())
+	memHash := xxhash.Sum64String("mem")
+	assert.Equal(t, "mem", md.getBucket(memHash).hash2Name[memHash])
+	metricHash, ok := md.metricID2Hash.Load(uint32(3))
+	assert.True(t, ok)
+	assert.Equal(t, memHash, metricHash)
+	assert.True(t, md.MemSize() >= 30)
+
+	// memory quota exceeded
+	md.quota.MaxMemory = 1
+	md.size.Store(1024 * 1024)
+	written, err = md.WriteBatch([]*pb.Metric{{Name: "cpu", Timestamp: familyTime}})
+	assert.Equal(t, 0, written)
+	assert.Equal(t, &series.WriteError{MetricName: "cpu", Code: series.WriteErrorQuota, Err: series.ErrMemoryQuotaExceeded}, err)
+}
+
+func Test_MemoryDatabase_WriteBatch_concurrent_quota(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	batchCfg := cfg
+	batchCfg.Generator = makeMockIDGenerator(ctrl)
+	batchCfg.Quota = option.QuotaOption{MaxSeries: 1000}
+	md := NewMemoryDatabase(ctx, batchCfg).(*memoryDatabase)
+
+	now := timeutil.Now()
+	newBatch := func(reverse bool) []*pb.Metric {
+		var metrics []*pb.Metric
+		for i := 0; i < 50; i++ {
+			metricName := "metric" + strconv.Itoa(i)
+			if reverse {
+				metricName = "metric" + strconv.Itoa(49-i)
+			}
+			metrics = append(metrics, &pb.Metric{
+				Name:      metricName,
+				Timestamp: now,
+				Tags:      map[string]string{"host": "1.1.1.1"},
+				Fields:    []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}}},
+			})
+		}
+		return metrics
+	}
+	// counting series never locks other buckets while writing one bucket, so no deadlock
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(reverse bool) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_, err := md.WriteBatch(newBatch(reverse))
+				assert.Nil(t, err)
+			}
+		}(i%2 == 0)
+	}
+	wg.Wait()
+	assert.Equal(t, 50, md.countSeries())
+	assert.Equal(t, 50, md.QuotaStats().Series)
+
+	// counter is decreased after deleting
+	assert.Nil(t, md.DeleteMetric("metric0"))
+	assert.Equal(t, 49, md.countSeries())
+	assert.Nil(t, md.ResetMetricStore("metric1"))
+	assert.Equal(t, 48, md.countSeries())
+}
+
+func benchmarkMemoryDatabaseWriteBatch(b *testing.B, batch bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mdINTF := NewMemoryDatabase(ctx, cfg)
+	md := mdINTF.(*memoryDatabase)
+	now := timeutil.Now()
+	metrics := make([]*pb.Metric, 50000)
+	for i := range metrics {
+		metrics[i] = &pb.Metric{Name: "metric" + strconv.Itoa(i%100), Timestamp: now}
+	}
+	for i := 0; i < 100; i++ {
+		metricName := "metric" + strconv.Itoa(i)
+		hash := xxhash.Sum64String(metricName)
+		md.getBucket(hash).hash2Name[hash] = metricName
+		md.getBucket(hash).hash2MStore[hash] = &nopMStore{}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if batch {
+			_, _ = md.WriteBatch(metrics)
+		} else {
+			for _, metric := range metrics {
+				_ = md.Write(metric)
+			}
+		}
+	}
+}
+
+func Benchmark_MemoryDatabase_Write_50k(b *testing.B) {
+	benchmarkMemoryDatabaseWriteBatch(b, false)
+}
+
+func Benchmark_MemoryDatabase_WriteBatch_50k(b *testing.B) {
+	benchmarkMemoryDatabaseWriteBatch(b, true)
+}
+
+func Test_MemoryDatabase_metricInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	generator := metadb.NewMockIDGenerator(ctrl)
+	generator.EXPECT().GenMetricID("cpu").Return(uint32(1)).AnyTimes()
+	generator.EXPECT().GenMetricID("disk").Return(uint32(2)).AnyTimes()
+	generator.EXPECT().GenFieldID(gomock.Any(), gomock.Any(), gomock.Any()).Return(uint16(1), nil).AnyTimes()
+	generator.EXPECT().GenTagKeyID(gomock.Any(), gomock.Any()).Return(uint32(1)).AnyTimes()
+	intervalCfg := cfg
+	intervalCfg.Generator = generator
+	intervalCfg.MetricIntervals = map[string]timeutil.Interval{"disk": timeutil.Interval(timeutil.OneMinute)}
+	md := NewMemoryDatabase(ctx, intervalCfg).(*memoryDatabase)
+
+	assert.Equal(t, 10*timeutil.OneSecond, md.MetricInterval("cpu"))
+	assert.Equal(t, timeutil.OneMinute, md.MetricInterval("disk"))
+
+	familyTime := int64(1564300800000)
+	for _, metricName := range []string{"cpu", "disk"} {
+		for _, offset := range []int64{0, 30, 40, 90} {
+			assert.Nil(t, md.Write(&pb.Metric{
+				Name:      metricName,
+				Timestamp: familyTime + offset*timeutil.OneSecond,
+				Tags:      map[string]string{"host": "1.1.1.1"},
+				Fields:    []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1}}}},
+			}))
+		}
+	}
+	// fine and coarse metrics share the family
+	assert.Equal(t, []int64{familyTime}, md.Families())
+	timeRange := timeutil.TimeRange{Start: familyTime, End: familyTime + timeutil.OneHour}
+	// cpu at native resolution of 10s: slot 0, 3, 4, 9
+	counts, err := md.CountSlots(1, timeRange)
+	assert.Nil(t, err)
+	for _, seriesCounts := range counts {
+		assert.Equal(t, map[uint32]int{1: 4}, seriesCounts)
+	}
+	// disk at native resolution of 1m: slot 0, 1
+	counts, err = md.CountSlots(2, timeRange)
+	assert.Nil(t, err)
+	for _, seriesCounts := range counts {
+		assert.Equal(t, map[uint32]int{1: 2}, seriesCounts)
+	}
+
+	// scan at native resolution
+	mockMStore := NewMockmStoreINTF(ctrl)
+	mockMStore.EXPECT().Scan(gomock.Any()).Do(func(sCtx *series.ScanContext) {
+		assert.Equal(t, timeutil.OneMinute, sCtx.StorageInterval)
+	})
+	hash := xxhash.Sum64String("disk")
+	md.getBucket(hash).hash2MStore[hash] = mockMStore
+	md.Scan(&series.ScanContext{MetricID: 2})
+	// metric id not exist
+	assert.Equal(t, timeutil.Interval(10*timeutil.OneSecond), md.metricIntervalByID(100))
+}
@@ -4,6 +4,7 @@ import (
 	"sort"
 
 	"github.com/lindb/lindb/aggregation"
+	"github.com/lindb/lindb/pkg/encoding"
 	"github.com/lindb/lindb/pkg/logger"
 	"github.com/lindb/lindb/pkg/timeutil"
 	pb "github.com/lindb/lindb/rpc/proto/field"
@@ -53,6 +54,13 @@ type fStoreINTF interface {
 
 	// scan scans the field store's data
 	scan(agg aggregation.SeriesAggregator, memScanCtx *memScanContext)
+
+	// forEachSlot calls fn with the family time and time slot which has value
+	forEachSlot(tsd *encoding.TSDDecoder, fn func(familyTime int64, slot int))
+
+	// EvictSegments removes the segments whose family time is before the given family time,
+	// returns the evicted size
+	EvictSegments(beforeFamilyTime int64) (evictedSize int)
 }
 
 // sStoreNodes implements the sort.Interface
@@ -126,20 +134,59 @@ func (fs *fieldStore) Write(
 
 	switch fields := f.Field.(type) {
 	case *pb.Field_Sum:
+		writtenSize += fs.writeSimpleField(sStore, ok, field.Sum.AggFunc(), fields.Sum.Value, writeCtx)
+	case *pb.Field_Min:
+		writtenSize += fs.writeSimpleField(sStore, ok, field.Min.AggFunc(), fields.Min.Value, writeCtx)
+	case *pb.Field_Max:
+		writtenSize += fs.writeSimpleField(sStore, ok, field.Max.AggFunc(), fields.Max.Value, writeCtx)
+	case *pb.Field_Gauge:
+		// gauge keeps the max value in the slot, so the peak is not lost after down sampling
+		writtenSize += fs.writeSimpleField(sStore, ok, field.Max.AggFunc(), fields.Gauge.Value, writeCtx)
+	case *pb.Field_Count:
+		writtenSize += fs.writeSimpleField(sStore, ok, field.Count.AggFunc(), fields.Count.Value, writeCtx)
+	case *pb.Field_Counter:
+		// counter keeps the last raw cumulative value in the slot by the rollup func of counter,
+		// the agg type is still sum, so that the values of series are summed when grouping,
+		// the rate is calculated at query time
+		aggFunc := field.CounterField.RollupAggFunc(field.Sum)
+		writtenSize += fs.writeSimpleField(sStore, ok, aggFunc, fields.Counter.Value, writeCtx)
+	case *pb.Field_Distribution:
 		if !ok {
-			//TODO ???
 			oldCap := cap(fs.sStoreNodes)
-			sStore = newSimpleFieldStore(writeCtx.familyTime, field.Sum.AggFunc())
+			sStore = newQuantileFieldStore(writeCtx.familyTime)
 			fs.insertSStore(sStore)
 			writtenSize += (cap(fs.sStoreNodes)-oldCap)*8 + sStore.MemSize()
 		}
-		writtenSize += sStore.WriteFloat(fields.Sum.Value, writeCtx)
+		for _, value := range fields.Distribution.Values {
+			writtenSize += sStore.WriteFloat(value, writeCtx)
+		}
 	default:
 		memDBLogger.Warn("convert field error, unknown field type")
 	}
 	return writtenSize
 }
 
+// writeSimpleField writes the float value into the simple field store of the family,
+// creates the store with the given aggregator function if not exist.
+func (fs *fieldStore) writeSimpleField(
+	sStore sStoreINTF,
+	exist bool,
+	aggFunc field.AggFunc,
+	value float64,
+	writeCtx writeContext,
+) (
+	writtenSize int,
+) {
+	if !exist {
+		oldCap := cap(fs.sStoreNodes)
+		sStore = newSimpleFieldStore(writeCtx.familyTime, aggFunc)
+		fs.insertSStore(sStore)
+		writtenSize += (cap(fs.sStoreNodes)-oldCap)*8 + sStore.MemSize()
+	}
+	writtenSize += sStore.WriteFloat(value, writeCtx)
+	return writtenSize
+}
+
 // FlushFieldTo flushes segments' data to writer and reset the segments-map.
 func (fs *fieldStore) FlushFieldTo(
 	tableFlusher metricsdata.Flusher,
@@ -183,6 +230,36 @@ func (fs *fieldStore) TimeRange(interval int64) (timeRange timeutil.TimeRange, o
 	return
 }
 
+// forEachSlot calls fn with the family time and time slot which has value
+func (fs *fieldStore) forEachSlot(tsd *encoding.TSDDecoder, fn func(familyTime int64, slot int)) {
+	for _, sStore := range fs.sStoreNodes {
+		familyTime := sStore.GetFamilyTime()
+		sStore.forEachSlot(tsd, func(slot int) {
+			fn(familyTime, slot)
+		})
+	}
+}
+
+// EvictSegments removes the segments whose family time is before the given family time,
+// returns the evicted size
+func (fs *fieldStore) EvictSegments(beforeFamilyTime int64) (evictedSize int) {
+	idx := sort.Search(len(fs.sStoreNodes), func(i int) bool {
+		return fs.sStoreNodes[i].GetFamilyTime() >= beforeFamilyTime
+	})
+	if idx == 0 {
+		return 0
+	}
+	for _, sStore := range fs.sStoreNodes[:idx] {
+		evictedSize += sStore.MemSize()
+	}
+	copy(fs.sStoreNodes, fs.sStoreNodes[idx:])
+	for i := len(fs.sStoreNodes) - idx; i < len(fs.sStoreNodes); i++ {
+		fs.sStoreNodes[i] = nil
+	}
+	fs.sStoreNodes = fs.sStoreNodes[:len(fs.sStoreNodes)-idx]
+	return evictedSize
+}
+
 func (fs *fieldStore) MemSize() int {
 	size := emptyFieldStoreSize + 8*cap(fs.sStoreNodes)
 	for _, sStore := range fs.sStoreNodes {
@@ -2,9 +2,21 @@ package memdb
 
 import "github.com/lindb/lindb/aggregation"
 
-// scan scans the field store's data
+// scan scans the field store's data,
+// sStores are sorted by family time and block data is scanned by slot order,
+// so the scanned points of series are in time order even if written out of order.
 func (fs *fieldStore) scan(agg aggregation.SeriesAggregator, memScanCtx *memScanContext) {
+	// single slot, only scans the segment of the family
+	if memScanCtx.singleSlot {
+		if sStore, ok := fs.GetSStore(memScanCtx.familyTime); ok {
+			sStore.scan(agg, memScanCtx)
+		}
+		return
+	}
 	for _, fsStore := range fs.sStoreNodes {
+		if memScanCtx.canceled() {
+			return
+		}
 		fsStore.scan(agg, memScanCtx)
 	}
 }
@@ -5,10 +5,15 @@ import (
 
 	"github.com/golang/mock/gomock"
 
+	"github.com/stretchr/testify/assert"
+
 	"github.com/lindb/lindb/aggregation"
+	"github.com/lindb/lindb/aggregation/fields"
+	"github.com/lindb/lindb/aggregation/function"
 	"github.com/lindb/lindb/pkg/timeutil"
 	pb "github.com/lindb/lindb/rpc/proto/field"
 	"github.com/lindb/lindb/series"
+	"github.com/lindb/lindb/series/field"
 )
 
 type mockScanWorker struct {
@@ -57,3 +62,41 @@ func TestFieldStore_Scan(t *testing.T) {
 	)
 	fStore.scan(agg, sCtx)
 }
+
+func TestFieldStore_Scan_counterRate(t *testing.T) {
+	familyTime, _ := timeutil.ParseTimestamp("20190702 19:00:00", "20060102 15:04:05")
+	bs := newBlockStore(10)
+	fStore := newFieldStore(10)
+	write := func(slot int, value float64) {
+		fStore.Write(
+			&pb.Field{Name: "f1", Field: &pb.Field_Counter{Counter: &pb.Counter{Value: value}}},
+			writeContext{blockStore: bs, familyTime: familyTime, slotIndex: slot, metricID: uint32(10)})
+	}
+	// the last raw value of the slot is kept
+	write(0, 8)
+	write(0, 10)
+	write(1, 15)
+	write(2, 25)
+	// counter reset
+	write(3, 4)
+	write(4, 10)
+
+	interval := 10 * timeutil.OneSecond
+	timeRange := timeutil.TimeRange{Start: familyTime, End: familyTime + timeutil.OneMinute}
+	aggSpec := aggregation.NewAggregatorSpec("f1", field.CounterField)
+	aggSpec.AddFunctionType(function.Rate)
+	agg := aggregation.NewSeriesAggregator(timeutil.Interval(interval), 1, timeRange, true, aggSpec)
+	fStore.scan(agg, &memScanContext{})
+
+	f := fields.NewDynamicField(field.CounterField, familyTime, interval,
+		timeutil.CalPointCount(timeRange.Start, timeRange.End, interval))
+	f.SetValue(agg.ResultSet())
+	result := function.FuncCall(function.Rate, f.GetValues(function.Rate)...)
+	assert.False(t, result.HasValue(0))
+	assert.Equal(t, 5.0, result.GetValue(1))
+	assert.Equal(t, 10.0, result.GetValue(2))
+	// delta from zero after reset
+	assert.Equal(t, 4.0, result.GetValue(3))
+	assert.Equal(t, 6.0, result.GetValue(4))
+	assert.Equal(t, 4, result.Size())
+}
@@ -2,10 +2,14 @@ package memdb
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"testing"
 
+	"github.com/lindb/lindb/pkg/encoding"
 	pb "github.com/lindb/lindb/rpc/proto/field"
+	"github.com/lindb/lindb/series/field"
+	"github.com/lindb/lindb/tsdb/tblstore/metricsdata"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -43,6 +47,68 @@ func Test_fStore_write(t *testing.T) {
 	}}, writeCtx)
 }
 
+func Test_fStore_write_flush_fieldTypes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cases := []struct {
+		fieldType field.Type
+		newField  func(value float64) *pb.Field
+		expect    float64
+	}{
+		{field.SumField, func(value float64) *pb.Field {
+			return &pb.Field{Name: "f", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: value}}}
+		}, 6},
+		{field.MinField, func(value float64) *pb.Field {
+			return &pb.Field{Name: "f", Field: &pb.Field_Min{Min: &pb.Min{Value: value}}}
+		}, 1},
+		{field.MaxField, func(value float64) *pb.Field {
+			return &pb.Field{Name: "f", Field: &pb.Field_Max{Max: &pb.Max{Value: value}}}
+		}, 3},
+		{field.GaugeField, func(value float64) *pb.Field {
+			return &pb.Field{Name: "f", Field: &pb.Field_Gauge{Gauge: &pb.Gauge{Value: value}}}
+		}, 3},
+		{field.CountField, func(value float64) *pb.Field {
+			return &pb.Field{Name: "f", Field: &pb.Field_Count{Count: &pb.Count{Value: value}}}
+		}, 6},
+		// counter keeps the last raw value
+		{field.CounterField, func(value float64) *pb.Field {
+			return &pb.Field{Name: "f", Field: &pb.Field_Counter{Counter: &pb.Counter{Value: value}}}
+		}, 2},
+	}
+	for _, c := range cases {
+		fStore := newFieldStore(10)
+		assert.Equal(t, c.fieldType, getFieldType(c.newField(0)))
+		writeCtx := writeContext{familyTime: 15, slotIndex: 5, blockStore: newBlockStore(30)}
+		for _, value := range []float64{1, 3, 2} {
+			assert.True(t, fStore.Write(c.newField(value), writeCtx) >= 0)
+		}
+		writeCtx.slotIndex = 6
+		assert.True(t, fStore.Write(c.newField(10), writeCtx) >= 0)
+
+		sStore, ok := fStore.GetSStore(15)
+		assert.True(t, ok)
+		assert.Equal(t, c.fieldType.GetDefaultPrimitiveFields()[1], sStore.AggType())
+
+		var data []byte
+		mockTF := metricsdata.NewMockFlusher(ctrl)
+		mockTF.EXPECT().FlushField(uint16(10), gomock.Any()).Do(func(fieldID uint16, value []byte) {
+			data = value
+		})
+		assert.NotZero(t, fStore.FlushFieldTo(mockTF, 15))
+		assert.Zero(t, fStore.SegmentsCount())
+
+		tsd := encoding.NewTSDDecoder(data)
+		values := make(map[int]float64)
+		for tsd.Next() {
+			if tsd.HasValue() {
+				values[tsd.Slot()] = math.Float64frombits(tsd.Value())
+			}
+		}
+		assert.Equal(t, map[int]float64{5: c.expect, 6: 10}, values, c.fieldType.String())
+	}
+}
+
 func Test_fStore_timeRange(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -143,3 +209,24 @@ func Test_fStore_removeSStore(t *testing.T) {
 	fs.removeSStore(2)
 	fs.removeSStore(7)
 }
+
+func Test_fStore_EvictSegments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fsINTF := newFieldStore(1)
+	fs := fsINTF.(*fieldStore)
+	assert.Zero(t, fsINTF.EvictSegments(10))
+
+	fs.insertSStore(getMockSStore(ctrl, 1))
+	fs.insertSStore(getMockSStore(ctrl, 5))
+	fs.insertSStore(getMockSStore(ctrl, 9))
+	// no segment before family time
+	assert.Zero(t, fsINTF.EvictSegments(1))
+	assert.Equal(t, 3, fsINTF.SegmentsCount())
+	// evicts segments before family time
+	assert.Equal(t, 2*emptySimpleFieldStoreSize, fsINTF.EvictSegments(6))
+	assert.Equal(t, 1, fsINTF.SegmentsCount())
+	_, ok := fsINTF.GetSStore(9)
+	assert.True(t, ok)
+}
@@ -10,6 +10,18 @@ func getFieldType(f *pb.Field) field.Type {
 	switch f.Field.(type) {
 	case *pb.Field_Sum:
 		return field.SumField
+	case *pb.Field_Min:
+		return field.MinField
+	case *pb.Field_Max:
+		return field.MaxField
+	case *pb.Field_Gauge:
+		return field.GaugeField
+	case *pb.Field_Count:
+		return field.CountField
+	case *pb.Field_Counter:
+		return field.CounterField
+	case *pb.Field_Distribution:
+		return field.QuantileField
 	default:
 		return field.Unknown
 	}
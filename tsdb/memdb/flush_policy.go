@@ -0,0 +1,54 @@
+package memdb
+
+import (
+	"time"
+
+	"github.com/lindb/lindb/pkg/option"
+)
+
+// defaultFlushWriteMaxWait is the default max wait of the writes blocked by flush with block-brief policy
+const defaultFlushWriteMaxWait = time.Second
+
+// beginFlush marks a flush in-progress, the writes arrived after are blocked with block-brief policy
+func (md *memoryDatabase) beginFlush() {
+	md.flushMux.Lock()
+	defer md.flushMux.Unlock()
+
+	if md.flushCount == 0 {
+		md.flushDone = make(chan struct{})
+	}
+	md.flushCount++
+}
+
+// endFlush marks a flush completed, wakes up the blocked writes after all the flushes in-progress completed
+func (md *memoryDatabase) endFlush() {
+	md.flushMux.Lock()
+	defer md.flushMux.Unlock()
+
+	md.flushCount--
+	if md.flushCount == 0 {
+		close(md.flushDone)
+		md.flushDone = nil
+	}
+}
+
+// waitFlush waits the flushes in-progress complete or the max wait elapses with block-brief policy,
+// returns immediately with accept policy, the write is always accepted after waiting, never dropped.
+func (md *memoryDatabase) waitFlush() {
+	if md.flushWritePolicy != option.FlushWriteBlockBrief {
+		return
+	}
+	md.flushMux.Lock()
+	flushDone := md.flushDone
+	md.flushMux.Unlock()
+	if flushDone == nil {
+		return
+	}
+	timer := time.NewTimer(md.flushWriteMaxWait)
+	defer timer.Stop()
+	select {
+	case <-flushDone:
+	case <-timer.C:
+	case <-md.ctx.Done():
+	}
+}
@@ -0,0 +1,133 @@
+package memdb
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/pkg/encoding"
+	"github.com/lindb/lindb/pkg/option"
+	pb "github.com/lindb/lindb/rpc/proto/field"
+	"github.com/lindb/lindb/tsdb/tblstore/metricsdata"
+)
+
+// makeSumCollectingFlusher returns a flusher which sums up the values of all the flushed field data
+func makeSumCollectingFlusher(ctrl *gomock.Controller, total *float64) *metricsdata.MockFlusher {
+	var mux sync.Mutex
+	flusher := metricsdata.NewMockFlusher(ctrl)
+	flusher.EXPECT().FlushFieldMetas(gomock.Any()).AnyTimes()
+	flusher.EXPECT().FlushSeries(gomock.Any()).AnyTimes()
+	flusher.EXPECT().FlushVersion(gomock.Any()).AnyTimes()
+	flusher.EXPECT().FlushMetric(gomock.Any()).Return(nil).AnyTimes()
+	flusher.EXPECT().FlushField(gomock.Any(), gomock.Any()).Do(func(fieldID uint16, data []byte) {
+		mux.Lock()
+		defer mux.Unlock()
+		tsd := encoding.NewTSDDecoder(data)
+		for tsd.Next() {
+			if tsd.HasValue() {
+				*total += math.Float64frombits(tsd.Value())
+			}
+		}
+	}).AnyTimes()
+	return flusher
+}
+
+func Test_MemoryDatabase_writeDuringFlush(t *testing.T) {
+	for _, policy := range []string{option.FlushWriteAccept, option.FlushWriteBlockBrief} {
+		policy := policy
+		t.Run(policy, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			policyCfg := cfg
+			policyCfg.Generator = makeMockIDGenerator(ctrl)
+			policyCfg.FlushWritePolicy = policy
+			md := NewMemoryDatabase(ctx, policyCfg)
+			var total float64
+			flusher := makeSumCollectingFlusher(ctrl, &total)
+
+			familyTime := int64(1564300800000)
+			writers, points := 4, 200
+			var wg sync.WaitGroup
+			for i := 0; i < writers; i++ {
+				wg.Add(1)
+				go func(host int) {
+					defer wg.Done()
+					for j := 0; j < points; j++ {
+						assert.NoError(t, md.Write(&pb.Metric{
+							Name:      "cpu",
+							Timestamp: familyTime + int64(j%60)*10*1000,
+							Tags:      map[string]string{"host": fmt.Sprintf("host-%d", host)},
+							Fields:    []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1}}}},
+						}))
+					}
+				}(i)
+			}
+			done := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(done)
+			}()
+			// flushes concurrently with the writes
+			flushing := true
+			for flushing {
+				select {
+				case <-done:
+					flushing = false
+				default:
+				}
+				assert.NoError(t, md.FlushFamilyTo(flusher, familyTime))
+			}
+			// flushes the data written after last flush
+			assert.NoError(t, md.FlushFamilyTo(flusher, familyTime))
+			assert.Equal(t, float64(writers*points), total)
+		})
+	}
+}
+
+func Test_memoryDatabase_waitFlush(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	md := NewMemoryDatabase(ctx, cfg).(*memoryDatabase)
+	// accept policy never blocks
+	md.beginFlush()
+	md.waitFlush()
+	md.endFlush()
+
+	md.flushWritePolicy = option.FlushWriteBlockBrief
+	// no flush in-progress
+	md.waitFlush()
+	// blocks until all the flushes complete
+	md.beginFlush()
+	md.beginFlush()
+	waited := make(chan struct{})
+	go func() {
+		md.waitFlush()
+		close(waited)
+	}()
+	md.endFlush()
+	select {
+	case <-waited:
+		t.Fatal("write is not blocked by flush in-progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	md.endFlush()
+	<-waited
+
+	// blocks at most max wait
+	md.flushWriteMaxWait = 10 * time.Millisecond
+	md.beginFlush()
+	start := time.Now()
+	md.waitFlush()
+	assert.True(t, time.Since(start) >= 10*time.Millisecond)
+	md.endFlush()
+}
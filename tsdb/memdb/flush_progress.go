@@ -0,0 +1,69 @@
+package memdb
+
+// defaultFlushProgressEvery is the default count of flushed metrics between the flush progress callbacks
+const defaultFlushProgressEvery = 1000
+
+// FlushTarget represents the target of flush
+type FlushTarget string
+
+// Defines all the targets of flush
+const (
+	FlushTargetFamily        FlushTarget = "family"
+	FlushTargetInvertedIndex FlushTarget = "invertedIndex"
+	FlushTargetForwardIndex  FlushTarget = "forwardIndex"
+)
+
+// FlushProgress represents the progress of flushing memory database
+type FlushProgress struct {
+	Target         FlushTarget // target of flush
+	MetricsFlushed int         // count of flushed metrics
+	BytesFlushed   int         // flushed memory size of family data, unit(byte), 0 for index
+	BucketIndex    int         // index of current bucket, count of flushed buckets for family flushed concurrently
+	BucketCount    int         // count of buckets, for estimating the remaining time with bucket index
+	Done           bool        // flush completed
+}
+
+// flushProgressTracker reports the progress of a flush by callback every count of flushed metrics,
+// the methods of nil tracker do nothing if no callback.
+type flushProgressTracker struct {
+	callback     func(progress FlushProgress)
+	every        int
+	progress     FlushProgress
+	lastReported int
+}
+
+// newFlushProgressTracker creates a tracker for the flush of target, nil if no callback.
+func (md *memoryDatabase) newFlushProgressTracker(target FlushTarget) *flushProgressTracker {
+	if md.onFlushProgress == nil {
+		return nil
+	}
+	return &flushProgressTracker{
+		callback: md.onFlushProgress,
+		every:    md.flushProgressEvery,
+		progress: FlushProgress{Target: target, BucketCount: shardingCountOfMStores},
+	}
+}
+
+// flushed accounts a flushed metric of bucket, calls back if the count of flushed metrics since
+// last callback reaches the threshold. it must be called without holding any lock.
+func (t *flushProgressTracker) flushed(bucketIndex int, flushedSize int) {
+	if t == nil {
+		return
+	}
+	t.progress.MetricsFlushed++
+	t.progress.BytesFlushed += flushedSize
+	t.progress.BucketIndex = bucketIndex
+	if t.progress.MetricsFlushed-t.lastReported >= t.every {
+		t.lastReported = t.progress.MetricsFlushed
+		t.callback(t.progress)
+	}
+}
+
+// done calls back the final progress after the flush completed.
+func (t *flushProgressTracker) done() {
+	if t == nil {
+		return
+	}
+	t.progress.Done = true
+	t.callback(t.progress)
+}
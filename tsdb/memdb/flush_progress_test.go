@@ -0,0 +1,74 @@
+package memdb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/lindb/lindb/rpc/proto/field"
+)
+
+func Test_MemoryDatabase_flushProgress(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var ticks []FlushProgress
+	familyTime := int64(1564300800000)
+	progressCfg := cfg
+	progressCfg.Generator = makeMockIDGenerator(ctrl)
+	progressCfg.FlushProgressEvery = 2
+	progressCfg.OnFlushProgress = func(progress FlushProgress) {
+		ticks = append(ticks, progress)
+	}
+	md := NewMemoryDatabase(ctx, progressCfg).(*memoryDatabase)
+	assert.Equal(t, defaultFlushProgressEvery, NewMemoryDatabase(ctx, cfg).(*memoryDatabase).flushProgressEvery)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, md.Write(&pb.Metric{
+			Name:      fmt.Sprintf("cpu.%d", i),
+			Timestamp: familyTime,
+			Tags:      map[string]string{"host": "1.1.1.1"},
+			Fields:    []*pb.Field{{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}}},
+		}))
+	}
+	flusher := makeMockDataFlusher(ctrl)
+	flusher.EXPECT().FlushVersion(gomock.Any()).AnyTimes()
+	assert.NoError(t, md.FlushFamilyTo(flusher, familyTime))
+	// called back every 2 metrics, and after flush completed
+	assert.Len(t, ticks, 3)
+	assert.Equal(t, []int{2, 4, 5}, []int{ticks[0].MetricsFlushed, ticks[1].MetricsFlushed, ticks[2].MetricsFlushed})
+	for idx, tick := range ticks {
+		assert.Equal(t, FlushTargetFamily, tick.Target)
+		assert.Equal(t, shardingCountOfMStores, tick.BucketCount)
+		assert.True(t, tick.BytesFlushed > 0)
+		if idx > 0 {
+			assert.True(t, tick.BucketIndex >= ticks[idx-1].BucketIndex)
+			assert.True(t, tick.BytesFlushed >= ticks[idx-1].BytesFlushed)
+		}
+		assert.Equal(t, idx == 2, tick.Done)
+	}
+
+	// index flushes
+	ticks = nil
+	mockMStore := NewMockmStoreINTF(ctrl)
+	mockMStore.EXPECT().FlushForwardIndexTo(gomock.Any()).Return(nil)
+	mockMStore.EXPECT().FlushInvertedIndexTo(gomock.Any(), gomock.Any()).Return(fmt.Errorf("error"))
+	md = NewMemoryDatabase(ctx, progressCfg).(*memoryDatabase)
+	md.getBucket(4).hash2MStore[1] = mockMStore
+	assert.NoError(t, md.FlushForwardIndexTo(nil))
+	assert.Equal(t, []FlushProgress{{
+		Target:         FlushTargetForwardIndex,
+		MetricsFlushed: 1,
+		BucketIndex:    4,
+		BucketCount:    shardingCountOfMStores,
+		Done:           true,
+	}}, ticks)
+	// not done if failure
+	ticks = nil
+	assert.Error(t, md.FlushInvertedIndexTo(nil))
+	assert.Empty(t, ticks)
+}
@@ -0,0 +1,75 @@
+package memdb
+
+import (
+	"github.com/lindb/lindb/series"
+	"github.com/lindb/lindb/series/field"
+	"github.com/lindb/lindb/tsdb/tblstore/metricsdata"
+)
+
+// recordedMetricBlock represents the recorded calls of flushing a metric-block
+type recordedMetricBlock struct {
+	metricID uint32
+	calls    []func(flusher metricsdata.Flusher)
+}
+
+// replay replays the recorded calls on the flusher, then writes the metric-block
+func (b *recordedMetricBlock) replay(flusher metricsdata.Flusher) error {
+	for _, call := range b.calls {
+		call(flusher)
+	}
+	return flusher.FlushMetric(b.metricID)
+}
+
+// metricsDataRecorder implements metricsdata.Flusher, records the calls of each metric-block instead of writing,
+// so that the metric stores can be flushed concurrently by the recorders of workers,
+// then the metric-blocks are replayed on the real flusher serially.
+// the field data is compressed when flushing metric store, the recorder just holds it.
+type metricsDataRecorder struct {
+	blocks []recordedMetricBlock
+	calls  []func(flusher metricsdata.Flusher)
+}
+
+// newMetricsDataRecorder returns a recorder of metrics data flush
+func newMetricsDataRecorder() *metricsDataRecorder {
+	return &metricsDataRecorder{}
+}
+
+// FlushFieldMetas records the meta info of the fields
+func (r *metricsDataRecorder) FlushFieldMetas(fieldMetas []field.Meta) {
+	r.calls = append(r.calls, func(flusher metricsdata.Flusher) {
+		flusher.FlushFieldMetas(fieldMetas)
+	})
+}
+
+// FlushField records a compressed field data
+func (r *metricsDataRecorder) FlushField(fieldID uint16, data []byte) {
+	r.calls = append(r.calls, func(flusher metricsdata.Flusher) {
+		flusher.FlushField(fieldID, data)
+	})
+}
+
+// FlushSeries records the end of a series
+func (r *metricsDataRecorder) FlushSeries(seriesID uint32) {
+	r.calls = append(r.calls, func(flusher metricsdata.Flusher) {
+		flusher.FlushSeries(seriesID)
+	})
+}
+
+// FlushVersion records the end of a version
+func (r *metricsDataRecorder) FlushVersion(version series.Version) {
+	r.calls = append(r.calls, func(flusher metricsdata.Flusher) {
+		flusher.FlushVersion(version)
+	})
+}
+
+// FlushMetric completes the recorded metric-block, the metric-block is written when replaying
+func (r *metricsDataRecorder) FlushMetric(metricID uint32) error {
+	r.blocks = append(r.blocks, recordedMetricBlock{metricID: metricID, calls: r.calls})
+	r.calls = nil
+	return nil
+}
+
+// Commit does nothing, the real flusher is committed by the owner
+func (r *metricsDataRecorder) Commit() error {
+	return nil
+}
@@ -0,0 +1,42 @@
+package memdb
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/series"
+	"github.com/lindb/lindb/series/field"
+	"github.com/lindb/lindb/tsdb/tblstore/metricsdata"
+)
+
+func Test_metricsDataRecorder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	recorder := newMetricsDataRecorder()
+	fieldMetas := []field.Meta{{ID: 1, Type: field.SumField, Name: "f1"}}
+	for _, metricID := range []uint32{2, 1} {
+		recorder.FlushFieldMetas(fieldMetas)
+		recorder.FlushField(1, []byte{byte(metricID)})
+		recorder.FlushSeries(10)
+		recorder.FlushVersion(series.Version(100))
+		assert.NoError(t, recorder.FlushMetric(metricID))
+	}
+	assert.NoError(t, recorder.Commit())
+	assert.Len(t, recorder.blocks, 2)
+	assert.Empty(t, recorder.calls)
+
+	// replays the calls of metric-block in order
+	flusher := metricsdata.NewMockFlusher(ctrl)
+	gomock.InOrder(
+		flusher.EXPECT().FlushFieldMetas(fieldMetas),
+		flusher.EXPECT().FlushField(uint16(1), []byte{1}),
+		flusher.EXPECT().FlushSeries(uint32(10)),
+		flusher.EXPECT().FlushVersion(series.Version(100)),
+		flusher.EXPECT().FlushMetric(uint32(1)).Return(nil),
+	)
+	assert.Equal(t, uint32(1), recorder.blocks[1].metricID)
+	assert.NoError(t, recorder.blocks[1].replay(flusher))
+}
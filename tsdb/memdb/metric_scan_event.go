@@ -36,6 +36,9 @@ type metricScanEvent struct {
 	sCtx        *series.ScanContext
 	length      int
 	aggregators aggregation.FieldAggregates
+	// aggregators of each time series for group by
+	seriesAggregators aggregation.SeriesFieldAggregates
+	seriesIDSet       *roaring.Bitmap // series ids of scanned series, seriesIDs buffer is released after scan
 }
 
 // newScanEvent creates a new metric scan event
@@ -55,13 +58,25 @@ func newScanEvent(
 	}
 }
 
-// ResultSet returns the result set of scanner
+// ResultSet returns the result set of scanner,
+// returns the field aggregates of each time series if has group by
 func (e *metricScanEvent) ResultSet() interface{} {
+	if e.sCtx.HasGroupBy {
+		return e.seriesAggregators
+	}
 	return e.aggregators
 }
 
+// Version returns the version of the found series IDs
+func (e *metricScanEvent) Version() series.Version {
+	return e.version
+}
+
 // SeriesIDs returns the found series IDs
 func (e *metricScanEvent) SeriesIDs() *roaring.Bitmap {
+	if e.seriesIDSet != nil {
+		return e.seriesIDSet
+	}
 	return roaring.BitmapOf(e.seriesIDs[:e.length]...)
 }
 
@@ -71,6 +86,22 @@ func (e *metricScanEvent) Release() {
 		e.aggregators.Reset()
 		e.sCtx.Release(e.aggregators)
 	}
+	for _, aggregators := range e.seriesAggregators {
+		aggregators.Reset()
+		e.sCtx.Release(aggregators)
+	}
+}
+
+// canceled checks if the scan context is canceled,
+// puts back the aggregators to pool if canceled, because the event is dropped without Release.
+func (e *metricScanEvent) canceled() bool {
+	if !e.sCtx.Canceled() {
+		return false
+	}
+	e.Release()
+	e.aggregators = nil
+	e.seriesAggregators = nil
+	return true
 }
 
 // release releases the memory metric store scan's resource
@@ -87,34 +118,80 @@ func (e *metricScanEvent) release() {
 // Scan scans the memory database, then aggregates the data
 func (e *metricScanEvent) Scan() bool {
 	defer e.release()
+	if e.sCtx.HasGroupBy {
+		return e.scanSeries()
+	}
 	//FIXME add lock?????
 	aggregators, ok := e.sCtx.GetAggregator().(aggregation.FieldAggregates)
 	if !ok {
 		return false
 	}
 	e.aggregators = aggregators
-	memScanCtx := &memScanContext{
-		fieldIDs:    e.sCtx.FieldIDs,
-		aggregators: aggregators,
-		tsd:         encoding.GetTSDDecoder(),
-		fieldCount:  len(e.sCtx.FieldIDs),
-	}
+	memScanCtx := newMemScanContext(e.sCtx)
+	memScanCtx.aggregators = aggregators
+
+	defer encoding.ReleaseTSDDecoder(memScanCtx.tsd)
 
 	for i := 0; i < e.length; i++ {
-		//FIXME do group by and lock/using metric lock
-		//seriesID := e.seriesIDs[i]
+		if e.canceled() {
+			return false
+		}
 		store := e.stores[i]
 		store.scan(memScanCtx)
 	}
-	encoding.ReleaseTSDDecoder(memScanCtx.tsd)
+	return true
+}
+
+// scanSeries scans the memory database, aggregates the data of each time series for group by
+func (e *metricScanEvent) scanSeries() bool {
+	memScanCtx := newMemScanContext(e.sCtx)
+	defer encoding.ReleaseTSDDecoder(memScanCtx.tsd)
+
+	e.seriesAggregators = make(aggregation.SeriesFieldAggregates, e.length)
+	e.seriesIDSet = roaring.BitmapOf(e.seriesIDs[:e.length]...)
+	for i := 0; i < e.length; i++ {
+		if e.canceled() {
+			return false
+		}
+		aggregators, ok := e.sCtx.GetAggregator().(aggregation.FieldAggregates)
+		if !ok {
+			return false
+		}
+		e.seriesAggregators[e.seriesIDs[i]] = aggregators
+		memScanCtx.aggregators = aggregators
+		e.stores[i].scan(memScanCtx)
+	}
 	return true
 }
 
 // memScanContext represents the memory metric store scan context
 type memScanContext struct {
+	sCtx        *series.ScanContext
 	fieldIDs    []uint16
 	aggregators aggregation.FieldAggregates
 	tsd         *encoding.TSDDecoder
 
 	fieldCount int
+
+	// single slot fast path, reads the value of the slot in the family directly
+	singleSlot bool
+	familyTime int64
+	slot       int
+}
+
+// newMemScanContext creates the memory metric store scan context
+func newMemScanContext(sCtx *series.ScanContext) *memScanContext {
+	memScanCtx := &memScanContext{
+		sCtx:       sCtx,
+		fieldIDs:   sCtx.FieldIDs,
+		tsd:        encoding.GetTSDDecoder(),
+		fieldCount: len(sCtx.FieldIDs),
+	}
+	memScanCtx.familyTime, memScanCtx.slot, memScanCtx.singleSlot = singleSlot(sCtx)
+	return memScanCtx
+}
+
+// canceled checks if the scan context is canceled
+func (m *memScanContext) canceled() bool {
+	return m.sCtx != nil && m.sCtx.Canceled()
 }
@@ -5,6 +5,7 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/RoaringBitmap/roaring"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 
@@ -62,3 +63,51 @@ func TestMetricScanEvent_Scan(t *testing.T) {
 	sAgg.EXPECT().Reset()
 	event.Release()
 }
+
+func TestMetricScanEvent_Scan_GroupBy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tStore1 := NewMocktStoreINTF(ctrl)
+	tStore2 := NewMocktStoreINTF(ctrl)
+	sCtx := &series.ScanContext{
+		FieldIDs:   []uint16{3, 4, 5},
+		HasGroupBy: true,
+	}
+	stores := getStores()
+	stores[0] = tStore1
+	stores[1] = tStore2
+	seriesIDs := *series.Uint32Pool.Get()
+	seriesIDs[0] = uint32(1)
+	seriesIDs[1] = uint32(5)
+	// test not match aggregator
+	event := newScanEvent(2, stores, seriesIDs, series.Version(1), sCtx)
+	assert.False(t, event.Scan())
+
+	sAgg := aggregation.NewMockSeriesAggregator(ctrl)
+	sCtx.Aggregators = sync.Pool{
+		New: func() interface{} {
+			return aggregation.FieldAggregates{sAgg}
+		},
+	}
+	tStore1.EXPECT().scan(gomock.Any())
+	tStore2.EXPECT().scan(gomock.Any())
+	stores = getStores()
+	stores[0] = tStore1
+	stores[1] = tStore2
+	seriesIDs = *series.Uint32Pool.Get()
+	seriesIDs[0] = uint32(1)
+	seriesIDs[1] = uint32(5)
+	event = newScanEvent(2, stores, seriesIDs, series.Version(1), sCtx)
+	assert.True(t, event.Scan())
+	assert.Equal(t, series.Version(1), event.Version())
+	assert.Equal(t, roaring.BitmapOf(1, 5), event.SeriesIDs())
+
+	resultSet, ok := event.ResultSet().(aggregation.SeriesFieldAggregates)
+	assert.True(t, ok)
+	assert.Len(t, resultSet, 2)
+	assert.Equal(t, aggregation.FieldAggregates{sAgg}, resultSet[1])
+	assert.Equal(t, aggregation.FieldAggregates{sAgg}, resultSet[5])
+	sAgg.EXPECT().Reset().Times(2)
+	event.Release()
+}
@@ -1,11 +1,17 @@
 package memdb
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/lindb/lindb/constants"
+	"github.com/lindb/lindb/pkg/encoding"
+	"github.com/lindb/lindb/pkg/option"
+	"github.com/lindb/lindb/pkg/timeutil"
 	pb "github.com/lindb/lindb/rpc/proto/field"
 	"github.com/lindb/lindb/series"
 	"github.com/lindb/lindb/series/field"
@@ -21,9 +27,22 @@ import (
 
 //go:generate mockgen -source ./metric_store.go -destination=./metric_store_mock_test.go -package memdb
 
-const emptyMStoreSize = 8 + // immutable
+// maxImmutableIndexes is the max number of immutable indexes waiting for flushing,
+// reset version is unavailable when exceeded.
+const maxImmutableIndexes = 4
+
+// tagValuesSeparator separates the tag values of a series as the identity of tag combination
+const tagValuesSeparator = "\x00"
+
+// errMetricStoreDeleted is returned when writing the metric store removed by DeleteMetric,
+// the metric is written into the re-created metric store.
+var errMetricStoreDeleted = errors.New("metric store deleted")
+
+const emptyMStoreSize = 8 + // immutables
 	8 + // mutable
 	24 + // rwmutex
+	24 + // write rwmutex
+	1 + // deleted
 	8 + // atomic.Value
 	4 + // uint32
 	4 + // uint32
@@ -40,6 +59,9 @@ type mStoreINTF interface {
 	// SuggestTagValues returns tagValues by prefix-search
 	SuggestTagValues(tagKey, tagValuePrefix string, limit int) []string
 
+	// TopTagValues returns at most k tag values of the tag key ordered by count of series descending
+	TopTagValues(tagKey string, k int) []series.TagValueCount
+
 	// GetTagValues get tagValues from the specified version and tagKeys
 	GetTagValues(
 		tagKeys []string,
@@ -49,9 +71,26 @@ type mStoreINTF interface {
 		seriesID2TagValues map[uint32][]string,
 		err error)
 
+	// GetTagValuesByVersions get tagValues of the series ids of multi versions and tagKeys by version
+	GetTagValuesByVersions(
+		tagKeys []string,
+		seriesIDs *series.MultiVerSeriesIDSet,
+	) (
+		version2TagValues map[series.Version]map[uint32][]string,
+		err error)
+
 	// SetMaxTagsLimit sets the max tags-limit
 	SetMaxTagsLimit(limit uint32)
 
+	// GetMaxTagsLimit returns the max tags-limit
+	GetMaxTagsLimit() uint32
+
+	// SetMaxTagKeysLimit sets the max count of tag keys
+	SetMaxTagKeysLimit(limit uint32)
+
+	// GetMaxTagKeysLimit returns the max count of tag keys
+	GetMaxTagKeysLimit() uint32
+
 	// IsEmpty detects whether if tags number is empty or not.
 	IsEmpty() bool
 
@@ -61,6 +100,9 @@ type mStoreINTF interface {
 	// GetTagsUsed return count of all used tStores.
 	GetTagsUsed() int
 
+	// GetFieldsCount returns the count of fields.
+	GetFieldsCount() int
+
 	// FlushForwardIndexTo flushes metric-block of mStore to the Writer.
 	FlushForwardIndexTo(tableFlusher forwardindex.Flusher) error
 
@@ -76,6 +118,18 @@ type mStoreINTF interface {
 	// GetSeriesIDsForTag get series ids by tagKey
 	GetSeriesIDsForTag(tagKey string) (*series.MultiVerSeriesIDSet, error)
 
+	// GetSeriesIDsForMetric get all the series ids of metric
+	GetSeriesIDsForMetric() (*series.MultiVerSeriesIDSet, error)
+
+	// FindStaleSeriesIDs finds series ids whose latest data is older than the threshold timestamp,
+	// also returns all the series ids in memory
+	FindStaleSeriesIDs(interval, threshold int64) (stale, inMemory *series.MultiVerSeriesIDSet)
+	// CountSlots returns the count of time slots which has value in the time range per series of each version
+	CountSlots(interval int64, timeRange timeutil.TimeRange) map[series.Version]map[uint32]int
+	// Snapshot returns the field metas, tag keys with count of distinct tag values and count of series per version,
+	// the metric name is not set
+	Snapshot() MetricSnapshot
+
 	mStoreFieldIDGetter
 
 	series.Scanner
@@ -83,6 +137,10 @@ type mStoreINTF interface {
 	// MemSize returns the memory-size of this metric-store
 	MemSize() int
 
+	// Validate checks whether the metric would be accepted by Write without changing any state,
+	// the types of existing fields must match, new fields must not exceed the limit
+	Validate(metric *pb.Metric, writeCtx writeContext) error
+
 	///////////////////////////////////
 	// Methods below will change the memory size
 	///////////////////////////////////
@@ -95,7 +153,19 @@ type mStoreINTF interface {
 		err error)
 
 	// Evict scans all tsStore and removes which are not in use for a while.
-	Evict() (evictedSize int)
+	Evict(now int64) (evictedSize int)
+
+	// EvictToSize removes the tsStores whose data has been flushed in least-recently-written order,
+	// until the memory-size is below the target size, returns the evicted size.
+	EvictToSize(targetBytes int) (evictedSize int)
+
+	// EvictFieldData removes the field data whose family time is before the family time of field,
+	// key: field name, value: family time, returns the evicted size
+	EvictFieldData(fieldFamilyTimes map[string]int64) (evictedSize int)
+
+	// DeleteTagValues deletes the tag values of tag key matching the pattern and the related series,
+	// returns the count of deleted series and the removed memory size.
+	DeleteTagValues(tagKey string, pattern *regexp.Regexp) (deletedSeries int, removedSize int)
 
 	// FlushMetricsDataTo flushes metric-block of mStore to the Writer.
 	FlushMetricsDataTo(
@@ -106,8 +176,12 @@ type mStoreINTF interface {
 		err error)
 
 	// ResetVersion moves the current running mutable index to immutable list,
-	// then creates a new mutable map.
+	// then creates a new mutable map, returns error if the immutable list is full.
 	ResetVersion() (createdSize int, err error)
+
+	// MarkDeleted marks the metric store as deleted after the in-flight writes complete,
+	// then the writes are rejected with errMetricStoreDeleted.
+	MarkDeleted()
 }
 
 type mStoreFieldIDGetter interface {
@@ -123,26 +197,38 @@ type mStoreFieldIDGetter interface {
 
 // metricStore is composed of the immutable part and mutable part of indexes.
 // evictor scans the index to check which of them should be purged from the mutable part.
-// flusher flushes both the immutable and mutable index to disk,
+// flusher flushes both the immutable and mutable indexes to disk,
 // after flushing, the immutable part will be removed.
 type metricStore struct {
-	immutable    atomic.Value  // lock free immutable index that has not been flushed to disk
-	mutable      tagIndexINTF  // active mutable index in use
-	mux          sync.RWMutex  // read-Write lock for mutable index and fieldMetas
-	fieldsMetas  atomic.Value  // read only, storing (field.Metas), hold mux before storing new value
-	maxTagsLimit atomic.Uint32 // maximum number of combinations of tags
-	metricID     uint32        // persistent on the disk
-	size         atomic.Int32  // memory-size
+	immutables      atomic.Value  // lock free immutable indexes(read only list) that have not been flushed to disk
+	mutable         tagIndexINTF  // active mutable index in use
+	mux             sync.RWMutex  // read-Write lock for mutable index and fieldMetas
+	writeMux        sync.RWMutex  // read lock held by writing, write lock held by marking deleted
+	deleted         bool          // removed from memory database, hold writeMux
+	fieldsMetas     atomic.Value  // read only, storing (field.Metas), hold mux before storing new value
+	maxTagsLimit    atomic.Uint32 // maximum number of combinations of tags
+	maxTagKeysLimit atomic.Uint32 // maximum number of tag keys
+	metricID        uint32        // persistent on the disk
+	size            atomic.Int32  // memory-size
+	seriesCounter   *atomic.Int32 // counter of in-use series shared by the memory database, nil if not counted
 }
 
 // newMetricStore returns a new mStoreINTF.
 func newMetricStore(metricID uint32) mStoreINTF {
+	return newCountedMetricStore(metricID, nil)
+}
+
+// newCountedMetricStore returns a new mStoreINTF which counts the created and removed in-use series
+// into the shared series counter.
+func newCountedMetricStore(metricID uint32, seriesCounter *atomic.Int32) mStoreINTF {
 	mutable := newTagIndex()
 	ms := metricStore{
-		metricID:     metricID,
-		mutable:      mutable,
-		maxTagsLimit: *atomic.NewUint32(constants.DefaultMStoreMaxTagsCount),
-		size:         *atomic.NewInt32(int32(mutable.MemSize()))}
+		metricID:        metricID,
+		mutable:         mutable,
+		seriesCounter:   seriesCounter,
+		maxTagsLimit:    *atomic.NewUint32(constants.DefaultMStoreMaxTagsCount),
+		maxTagKeysLimit: *atomic.NewUint32(constants.MStoreMaxTagKeysCount),
+		size:            *atomic.NewInt32(int32(mutable.MemSize()))}
 	var fm field.Metas
 	ms.fieldsMetas.Store(fm)
 	return &ms
@@ -178,7 +264,10 @@ func (ms *metricStore) GetFieldIDOrGenerate(
 	fm, ok = fmList.GetFromName(fieldName)
 	// double check
 	if ok {
-		return fm.ID, nil
+		if fm.Type == fieldType {
+			return fm.ID, nil
+		}
+		return 0, series.ErrWrongFieldType
 	}
 	// generate and check fieldType
 	newFieldID, err := generator.GenFieldID(ms.metricID, fieldName, fieldType)
@@ -201,7 +290,7 @@ func (ms *metricStore) GetMetricID() uint32 {
 	return ms.metricID
 }
 
-// SuggestTagKeys returns tagKeys by prefix-search
+// SuggestTagKeys returns tagKeys by prefix-search, the lexicographically-smallest tagKeys are returned in order
 func (ms *metricStore) SuggestTagKeys(
 	tagKeyPrefix string,
 	limit int,
@@ -214,29 +303,24 @@ func (ms *metricStore) SuggestTagKeys(
 	var tagKeysMap = make(map[string]struct{})
 	prefixSearchTagKey := func(tagIndex tagIndexINTF) {
 		for _, entrySet := range tagIndex.GetTagKVEntrySets() {
-			if len(tagKeysMap) >= limit {
-				return
-			}
 			if strings.HasPrefix(entrySet.key, tagKeyPrefix) {
 				tagKeysMap[entrySet.key] = struct{}{}
 			}
 		}
 	}
 	ms.mux.RLock()
-	immutable := ms.atomicGetImmutable()
+	immutables := ms.atomicGetImmutables()
 	prefixSearchTagKey(ms.mutable)
 	ms.mux.RUnlock()
-	if immutable != nil {
+	for _, immutable := range immutables {
 		prefixSearchTagKey(immutable)
 	}
 
-	for tagKey := range tagKeysMap {
-		tagKeysList = append(tagKeysList, tagKey)
-	}
-	return tagKeysList
+	return sortedSuggestions(tagKeysMap, limit)
 }
 
-// SuggestTagValues returns tagValues by prefix-search
+// SuggestTagValues returns tagValues of the tagKey by prefix-search,
+// the lexicographically-smallest tagValues are returned in order
 func (ms *metricStore) SuggestTagValues(
 	tagKey,
 	tagValuePrefix string,
@@ -252,29 +336,108 @@ func (ms *metricStore) SuggestTagValues(
 	}
 	var tagValuesMap = make(map[string]struct{})
 	prefixSearchTagValue := func(tagIndex tagIndexINTF) {
-		for _, entrySet := range tagIndex.GetTagKVEntrySets() {
-			if len(tagValuesMap) >= limit {
-				return
-			}
-			for tagValue := range entrySet.values {
-				if strings.HasPrefix(tagValue, tagValuePrefix) {
-					tagValuesMap[tagValue] = struct{}{}
-				}
+		entrySet, ok := tagIndex.GetTagKVEntrySet(tagKey)
+		if !ok {
+			return
+		}
+		for tagValue := range entrySet.values {
+			if strings.HasPrefix(tagValue, tagValuePrefix) {
+				tagValuesMap[tagValue] = struct{}{}
 			}
 		}
 	}
 	ms.mux.RLock()
-	immutable := ms.atomicGetImmutable()
+	immutables := ms.atomicGetImmutables()
 	prefixSearchTagValue(ms.mutable)
 	ms.mux.RUnlock()
-	if immutable != nil {
+	for _, immutable := range immutables {
 		prefixSearchTagValue(immutable)
 	}
 
-	for tagValue := range tagValuesMap {
-		tagValuesList = append(tagValuesList, tagValue)
+	return sortedSuggestions(tagValuesMap, limit)
+}
+
+// sortedSuggestions returns the smallest suggestions up to limit in ascending order, nil if empty.
+// all the matched candidates are collected before truncating, so that paging through the suggestions is stable.
+func sortedSuggestions(candidates map[string]struct{}, limit int) (suggestions []string) {
+	if len(candidates) == 0 {
+		return nil
 	}
-	return tagValuesList
+	suggestions = make([]string, 0, len(candidates))
+	for candidate := range candidates {
+		suggestions = append(suggestions, candidate)
+	}
+	sort.Strings(suggestions)
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// TopTagValues returns at most k tag values of the tag key ordered by count of series descending,
+// the series of all the versions in memory are counted, ties are ordered by tag value.
+// the series ids are allocated by version, so the series existing in multiple versions are identified
+// by the tag values of all the tag keys, and counted once.
+func (ms *metricStore) TopTagValues(tagKey string, k int) []series.TagValueCount {
+	if k <= 0 {
+		return nil
+	}
+	ms.mux.RLock()
+	defer ms.mux.RUnlock()
+
+	tagIndexes := append([]tagIndexINTF{ms.mutable}, ms.atomicGetImmutables()...)
+	tagKeySet := make(map[string]struct{})
+	for _, tagIndex := range tagIndexes {
+		for _, entrySet := range tagIndex.GetTagKVEntrySets() {
+			tagKeySet[entrySet.key] = struct{}{}
+		}
+	}
+	tagKeys := make([]string, 0, len(tagKeySet))
+	for key := range tagKeySet {
+		tagKeys = append(tagKeys, key)
+	}
+	sort.Strings(tagKeys)
+	tagKeyIdx := sort.SearchStrings(tagKeys, tagKey)
+
+	tagValue2Series := make(map[string]map[string]struct{})
+	for _, tagIndex := range tagIndexes {
+		seriesIDs := tagIndex.GetSeriesIDsForTag(tagKey)
+		if seriesIDs == nil {
+			continue
+		}
+		seriesID2TagValues := make(map[uint32][]string)
+		collectTagValues(tagIndex, tagKeys, seriesIDs, seriesID2TagValues)
+		for _, tagValues := range seriesID2TagValues {
+			tagValue := tagValues[tagKeyIdx]
+			seriesSet, ok := tagValue2Series[tagValue]
+			if !ok {
+				seriesSet = make(map[string]struct{})
+				tagValue2Series[tagValue] = seriesSet
+			}
+			seriesSet[strings.Join(tagValues, tagValuesSeparator)] = struct{}{}
+		}
+	}
+	tagValue2Count := make(map[string]int, len(tagValue2Series))
+	for tagValue, seriesSet := range tagValue2Series {
+		tagValue2Count[tagValue] = len(seriesSet)
+	}
+
+	result := make([]series.TagValueCount, 0, len(tagValue2Count))
+	for tagValue, count := range tagValue2Count {
+		if count > 0 {
+			result = append(result, series.TagValueCount{TagValue: tagValue, SeriesCount: count})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].SeriesCount != result[j].SeriesCount {
+			return result[i].SeriesCount > result[j].SeriesCount
+		}
+		return result[i].TagValue < result[j].TagValue
+	})
+	if len(result) > k {
+		result = result[:k]
+	}
+	return result
 }
 
 // GetTagValues get tagValues from the specified version and tagKeys
@@ -290,52 +453,139 @@ func (ms *metricStore) GetTagValues(
 	var found tagIndexINTF
 
 	ms.mux.RLock()
+	immutables := ms.atomicGetImmutables()
+	if err := ms.validateTagKeys(immutables, tagKeys); err != nil {
+		ms.mux.RUnlock()
+		return nil, err
+	}
 	// release the lock when immutable matches to the version
-	immutable := ms.atomicGetImmutable()
-	if immutable != nil && immutable.Version() == version {
-		found = immutable
+	for _, immutable := range immutables {
+		if immutable.Version() == version {
+			found = immutable
+			break
+		}
+	}
+	if found != nil {
 		ms.mux.RUnlock()
 	} else {
 		defer ms.mux.RUnlock()
-	}
-	if ms.mutable.Version() == version {
-		found = ms.mutable
+		if ms.mutable.Version() == version {
+			found = ms.mutable
+		}
 	}
 	if found == nil {
 		return nil, series.ErrNotFound
 	}
-	// validate tagKeys
+	collectTagValues(found, tagKeys, seriesID, seriesID2TagValues)
+	return seriesID2TagValues, nil
+}
+
+// GetTagValuesByVersions get tagValues of the series ids of multi versions and tagKeys, the tag values of each
+// version are resolved from the mutable or immutable matching the version, and returned by version,
+// because the same series id of different versions may be different series.
+// the versions not found are skipped, returns ErrNotFound if none of the versions is found.
+func (ms *metricStore) GetTagValuesByVersions(
+	tagKeys []string,
+	seriesIDs *series.MultiVerSeriesIDSet,
+) (
+	version2TagValues map[series.Version]map[uint32][]string,
+	err error,
+) {
+	ms.mux.RLock()
+	defer ms.mux.RUnlock()
+
+	immutables := ms.atomicGetImmutables()
+	if err := ms.validateTagKeys(immutables, tagKeys); err != nil {
+		return nil, err
+	}
+	version2TagValues = make(map[series.Version]map[uint32][]string)
+	for version, ids := range seriesIDs.Versions() {
+		var tagIdx tagIndexINTF
+		for _, immutable := range immutables {
+			if immutable.Version() == version {
+				tagIdx = immutable
+				break
+			}
+		}
+		if tagIdx == nil && ms.mutable.Version() == version {
+			tagIdx = ms.mutable
+		}
+		if tagIdx == nil {
+			continue
+		}
+		seriesID2TagValues := make(map[uint32][]string)
+		collectTagValues(tagIdx, tagKeys, ids, seriesID2TagValues)
+		version2TagValues[version] = seriesID2TagValues
+	}
+	if len(version2TagValues) == 0 {
+		return nil, series.ErrNotFound
+	}
+	return version2TagValues, nil
+}
+
+// validateTagKeys validates that each tagKey exists in the mutable or immutable parts of metric store,
+// the tagKey may not exist in some versions, then the tag value of series in these versions is empty string.
+func (ms *metricStore) validateTagKeys(immutables []tagIndexINTF, tagKeys []string) error {
 	for _, tagKey := range tagKeys {
-		_, ok := found.GetTagKVEntrySet(tagKey)
+		_, ok := ms.mutable.GetTagKVEntrySet(tagKey)
+		for idx := 0; !ok && idx < len(immutables); idx++ {
+			_, ok = immutables[idx].GetTagKVEntrySet(tagKey)
+		}
 		if !ok {
-			return nil, fmt.Errorf("tagKey: %s not exist", tagKey)
+			return fmt.Errorf("tagKey: %s not exist", tagKey)
 		}
 	}
-	itr := seriesID.Iterator()
+	return nil
+}
+
+// collectTagValues collects the tag values of tagKeys of the series ids from the tag index into the map,
+// tag value of the tagKey which not exist is empty string.
+func collectTagValues(
+	tagIdx tagIndexINTF,
+	tagKeys []string,
+	seriesIDs *roaring.Bitmap,
+	seriesID2TagValues map[uint32][]string,
+) {
+	// builds the inverted lookup(seriesID->tagValue) of each tagKey by iterating each tag value's bitmap once,
+	// nil if the tagKey not exist
+	tagValueLookups := make([]map[uint32]string, len(tagKeys))
+	for idx, tagKey := range tagKeys {
+		tagValueLookups[idx] = buildTagValueLookup(tagIdx, tagKey, seriesIDs)
+	}
+	itr := seriesIDs.Iterator()
 	for itr.HasNext() {
 		seriesID := itr.Next()
-		var tagValues []string
-		for _, tagKey := range tagKeys {
-			entrySet, ok := found.GetTagKVEntrySet(tagKey)
-			if !ok {
-				tagValues = append(tagValues, "")
+		tagValues := make([]string, len(tagKeys))
+		for idx, lookup := range tagValueLookups {
+			// lookup of nil map returns empty string
+			tagValues[idx] = lookup[seriesID]
+		}
+		seriesID2TagValues[seriesID] = tagValues
+	}
+}
+
+// buildTagValueLookup returns the mapping of seriesID->tagValue for the series ids of the tagKey,
+// returns nil if the tagKey not exist
+func buildTagValueLookup(tagIdx tagIndexINTF, tagKey string, seriesIDs *roaring.Bitmap) map[uint32]string {
+	entrySet, ok := tagIdx.GetTagKVEntrySet(tagKey)
+	if !ok {
+		return nil
+	}
+	lookup := make(map[uint32]string)
+	for tagValue, bitmap := range entrySet.values {
+		itr := bitmap.Iterator()
+		for itr.HasNext() {
+			seriesID := itr.Next()
+			if !seriesIDs.Contains(seriesID) {
 				continue
 			}
-			var found bool
-			for tagValue, bitmap := range entrySet.values {
-				if bitmap.Contains(seriesID) {
-					found = true
-					tagValues = append(tagValues, tagValue)
-					break
-				}
-			}
-			if !found {
-				tagValues = append(tagValues, "")
+			// keeps the first tag value found
+			if _, exist := lookup[seriesID]; !exist {
+				lookup[seriesID] = tagValue
 			}
 		}
-		seriesID2TagValues[seriesID] = tagValues
 	}
-	return seriesID2TagValues, nil
+	return lookup
 }
 
 // Write Writes the metric to the tStore
@@ -346,6 +596,14 @@ func (ms *metricStore) Write(
 	writtenSize int,
 	err error,
 ) {
+	ms.writeMux.RLock()
+	defer ms.writeMux.RUnlock()
+	if ms.deleted {
+		return 0, errMetricStoreDeleted
+	}
+	if writeCtx.maxTagsPerMetric > 0 && len(metric.Tags) > writeCtx.maxTagsPerMetric {
+		return 0, series.ErrTooManyTagsPerMetric
+	}
 	if ms.isFull() {
 		return 0, series.ErrTooManyTags
 	}
@@ -354,6 +612,10 @@ func (ms *metricStore) Write(
 	tStore, ok := ms.mutable.GetTStore(metric.Tags)
 	ms.mux.RUnlock()
 	if !ok {
+		if writeCtx.newSeriesAllowed != nil && !writeCtx.newSeriesAllowed() {
+			return 0, series.ErrSeriesQuotaExceeded
+		}
+		writeCtx.maxTagKeys = int(ms.GetMaxTagKeysLimit())
 		ms.mux.Lock()
 		tStore, createdSize, err = ms.mutable.GetOrCreateTStore(metric.Tags, writeCtx)
 		if err != nil {
@@ -362,6 +624,9 @@ func (ms *metricStore) Write(
 		}
 		ms.mux.Unlock()
 		ms.size.Add(int32(createdSize))
+		if createdSize > 0 {
+			ms.countSeries(1)
+		}
 	}
 
 	writtenSize, err = tStore.Write(metric, writeCtx)
@@ -379,11 +644,21 @@ func (ms *metricStore) SetMaxTagsLimit(limit uint32) {
 	ms.maxTagsLimit.Store(limit)
 }
 
-// getMaxTagsLimit return the max tags limit without race condition.
-func (ms *metricStore) getMaxTagsLimit() uint32 {
+// GetMaxTagsLimit returns the max tags limit without race condition.
+func (ms *metricStore) GetMaxTagsLimit() uint32 {
 	return ms.maxTagsLimit.Load()
 }
 
+// SetMaxTagKeysLimit sets the max count of tag keys of the metricStore
+func (ms *metricStore) SetMaxTagKeysLimit(limit uint32) {
+	ms.maxTagKeysLimit.Store(limit)
+}
+
+// GetMaxTagKeysLimit returns the max count of tag keys without race condition.
+func (ms *metricStore) GetMaxTagKeysLimit() uint32 {
+	return ms.maxTagKeysLimit.Load()
+}
+
 // GetTagsInUse return the tStores count.
 func (ms *metricStore) GetTagsInUse() int {
 	ms.mux.RLock()
@@ -392,6 +667,13 @@ func (ms *metricStore) GetTagsInUse() int {
 	return count
 }
 
+// countSeries adds the delta of in-use series to the shared series counter if set.
+func (ms *metricStore) countSeries(delta int) {
+	if ms.seriesCounter != nil && delta != 0 {
+		ms.seriesCounter.Add(int32(delta))
+	}
+}
+
 // GetTagsUsed return count of all used tStores.
 func (ms *metricStore) GetTagsUsed() int {
 	ms.mux.RLock()
@@ -400,27 +682,96 @@ func (ms *metricStore) GetTagsUsed() int {
 	return count
 }
 
+// GetFieldsCount returns the count of fields.
+func (ms *metricStore) GetFieldsCount() int {
+	return len(ms.fieldsMetas.Load().(field.Metas))
+}
+
+// Validate checks whether the metric would be accepted by Write without changing any state.
+func (ms *metricStore) Validate(metric *pb.Metric, writeCtx writeContext) error {
+	if writeCtx.maxTagsPerMetric > 0 && len(metric.Tags) > writeCtx.maxTagsPerMetric {
+		return series.ErrTooManyTagsPerMetric
+	}
+	if ms.isFull() {
+		return series.ErrTooManyTags
+	}
+	ms.mux.RLock()
+	_, ok := ms.mutable.GetTStore(metric.Tags)
+	if !ok {
+		if err := ms.validateNewSeries(metric.Tags, writeCtx); err != nil {
+			ms.mux.RUnlock()
+			return err
+		}
+	}
+	ms.mux.RUnlock()
+	fmList := ms.fieldsMetas.Load().(field.Metas)
+	newFields := make(map[string]struct{})
+	for _, f := range metric.Fields {
+		fieldType := getFieldType(f)
+		if fieldType == field.Unknown {
+			continue
+		}
+		fm, ok := fmList.GetFromName(f.Name)
+		if ok {
+			if fm.Type != fieldType {
+				return series.ErrWrongFieldType
+			}
+			continue
+		}
+		newFields[f.Name] = struct{}{}
+	}
+	if len(newFields) > 0 && fmList.Len()+len(newFields) > constants.TStoreMaxFieldsCount {
+		return series.ErrTooManyFields
+	}
+	return nil
+}
+
+// validateNewSeries checks the series quota and the tag keys limit for creating the series of tags,
+// same as Write, must be called with the read lock of mutable index.
+func (ms *metricStore) validateNewSeries(tags map[string]string, writeCtx writeContext) error {
+	if writeCtx.newSeriesAllowed != nil && !writeCtx.newSeriesAllowed() {
+		return series.ErrSeriesQuotaExceeded
+	}
+	maxTagKeys := int(ms.GetMaxTagKeysLimit())
+	if maxTagKeys <= 0 {
+		maxTagKeys = constants.MStoreMaxTagKeysCount
+	}
+	newTagKeys := 0
+	if len(tags) == 0 {
+		// series without tags is indexed by empty tag key
+		if _, ok := ms.mutable.GetTagKVEntrySet(""); !ok {
+			newTagKeys++
+		}
+	}
+	for tagKey := range tags {
+		if _, ok := ms.mutable.GetTagKVEntrySet(tagKey); !ok {
+			newTagKeys++
+		}
+	}
+	if newTagKeys > 0 && len(ms.mutable.GetTagKVEntrySets())+newTagKeys > maxTagKeys {
+		return series.ErrTooManyTagKeys
+	}
+	return nil
+}
+
 // isFull detects if timeSeriesMap exceeds the tagsID limitation.
 func (ms *metricStore) isFull() bool {
-	return uint32(ms.GetTagsUsed()) >= ms.getMaxTagsLimit()
+	return uint32(ms.GetTagsUsed()) >= ms.GetMaxTagsLimit()
 }
 
 // IsEmpty detects if tStores were all Evicted or not.
 func (ms *metricStore) IsEmpty() bool {
-	return ms.GetTagsInUse() == 0 && ms.atomicGetImmutable() == nil
+	return ms.GetTagsInUse() == 0 && len(ms.atomicGetImmutables()) == 0
 }
 
-func (ms *metricStore) atomicGetImmutable() tagIndexINTF {
-	immutable, ok := ms.immutable.Load().(tagIndexINTF)
-	// version zero is the placeholder tagIndexINTF stored in atomic.Value
-	if ok && immutable.Version() != 0 {
-		return immutable
-	}
-	return nil
+// atomicGetImmutables returns the immutable indexes, the list is read only
+func (ms *metricStore) atomicGetImmutables() []tagIndexINTF {
+	immutables, _ := ms.immutables.Load().([]tagIndexINTF)
+	return immutables
 }
 
 // Evict scans all tsStore and removes which are not in use for a while.
-func (ms *metricStore) Evict() (evictedSize int) {
+func (ms *metricStore) Evict(now int64) (evictedSize int) {
 	var (
 		evictList            []uint32
 		doubleCheckEvictList []uint32
@@ -431,7 +782,7 @@ func (ms *metricStore) Evict() (evictedSize int) {
 	it := metricMap.iterator()
 	for it.hasNext() {
 		seriesID, tStore := it.next()
-		if tStore.IsExpired() && tStore.IsNoData() {
+		if tStore.IsExpired(now) && tStore.IsNoData() {
 			evictList = append(evictList, seriesID)
 		}
 	}
@@ -443,7 +794,7 @@ func (ms *metricStore) Evict() (evictedSize int) {
 		if !ok {
 			continue
 		}
-		if tStore.IsExpired() && tStore.IsNoData() {
+		if tStore.IsExpired(now) && tStore.IsNoData() {
 			doubleCheckEvictList = append(doubleCheckEvictList, seriesID)
 		}
 	}
@@ -454,32 +805,158 @@ func (ms *metricStore) Evict() (evictedSize int) {
 		evictedSize += tStore.MemSize()
 	}
 	ms.size.Sub(int32(evictedSize))
+	ms.countSeries(-len(removedTStores))
+	return evictedSize
+}
+
+// EvictToSize removes the tsStores whose data has been flushed in least-recently-written order,
+// until the memory-size is below the target size, returns the evicted size.
+// tsStores with data not flushed are never evicted, so the target may not be reached.
+func (ms *metricStore) EvictToSize(targetBytes int) (evictedSize int) {
+	exceededSize := ms.MemSize() - targetBytes
+	if exceededSize < 0 {
+		return 0
+	}
+	type evictCandidate struct {
+		seriesID      uint32
+		lastWroteTime uint32
+	}
+	var candidates []evictCandidate
+	// collect the candidates
+	ms.mux.RLock()
+	it := ms.mutable.AllTStores().iterator()
+	for it.hasNext() {
+		seriesID, tStore := it.next()
+		if tStore.IsNoData() {
+			candidates = append(candidates, evictCandidate{seriesID: seriesID, lastWroteTime: tStore.LastWroteTime()})
+		}
+	}
+	ms.mux.RUnlock()
+	if len(candidates) == 0 {
+		return 0
+	}
+	// least-recently-written first
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].lastWroteTime == candidates[j].lastWroteTime {
+			return candidates[i].seriesID < candidates[j].seriesID
+		}
+		return candidates[i].lastWroteTime < candidates[j].lastWroteTime
+	})
+	// double check, then picks the candidates until the exceeded size is covered
+	var evictList []uint32
+	pickedSize := 0
+	ms.mux.Lock()
+	for _, candidate := range candidates {
+		if pickedSize > exceededSize {
+			break
+		}
+		tStore, ok := ms.mutable.GetTStoreBySeriesID(candidate.seriesID)
+		if !ok || !tStore.IsNoData() {
+			continue
+		}
+		evictList = append(evictList, candidate.seriesID)
+		pickedSize += tStore.MemSize()
+	}
+	removedTStores := ms.mutable.RemoveTStores(evictList...)
+	ms.mux.Unlock()
+
+	for _, tStore := range removedTStores {
+		evictedSize += tStore.MemSize()
+	}
+	ms.size.Sub(int32(evictedSize))
+	ms.countSeries(-len(removedTStores))
 	return evictedSize
 }
 
+// EvictFieldData removes the field data whose family time is before the family time of field,
+// key: field name, value: family time, returns the evicted size
+func (ms *metricStore) EvictFieldData(fieldFamilyTimes map[string]int64) (evictedSize int) {
+	fmList := ms.fieldsMetas.Load().(field.Metas)
+	fieldIDFamilyTimes := make(map[uint16]int64)
+	for fieldName, familyTime := range fieldFamilyTimes {
+		if fm, ok := fmList.GetFromName(fieldName); ok {
+			fieldIDFamilyTimes[fm.ID] = familyTime
+		}
+	}
+	if len(fieldIDFamilyTimes) == 0 {
+		return 0
+	}
+	evictFieldSegments := func(tagIdx tagIndexINTF) {
+		it := tagIdx.AllTStores().iterator()
+		for it.hasNext() {
+			_, tStore := it.next()
+			evictedSize += tStore.EvictFieldSegments(fieldIDFamilyTimes)
+		}
+	}
+	ms.mux.RLock()
+	evictFieldSegments(ms.mutable)
+	immutables := ms.atomicGetImmutables()
+	ms.mux.RUnlock()
+	for _, immutable := range immutables {
+		evictFieldSegments(immutable)
+	}
+	ms.size.Sub(int32(evictedSize))
+	return evictedSize
+}
+
+// DeleteTagValues deletes the tag values of tag key matching the pattern and the related series of mutable index,
+// the immutable indexes are kept because they are waiting for flushing.
+func (ms *metricStore) DeleteTagValues(
+	tagKey string,
+	pattern *regexp.Regexp,
+) (
+	deletedSeries int,
+	removedSize int,
+) {
+	ms.mux.Lock()
+	removedSeriesIDs, removedTStores := ms.mutable.DeleteTagValues(tagKey, pattern)
+	ms.mux.Unlock()
+
+	for _, tStore := range removedTStores {
+		removedSize += tStore.MemSize()
+	}
+	ms.size.Sub(int32(removedSize))
+	ms.countSeries(-len(removedTStores))
+	return int(removedSeriesIDs.GetCardinality()), removedSize
+}
+
+// MarkDeleted marks the metric store as deleted after the in-flight writes complete,
+// then the writes are rejected with errMetricStoreDeleted.
+func (ms *metricStore) MarkDeleted() {
+	ms.writeMux.Lock()
+	ms.deleted = true
+	ms.writeMux.Unlock()
+}
+
 // ResetVersion marks the mutable index's status to immutable, then creates a new active index.
+// The immutable indexes are kept in a bounded list until flushing,
+// returns ErrResetVersionUnavailable if the list is full.
 func (ms *metricStore) ResetVersion() (createdSize int, err error) {
-	immutable := ms.atomicGetImmutable()
-	if immutable != nil {
+	if len(ms.atomicGetImmutables()) >= maxImmutableIndexes {
 		return 0, series.ErrResetVersionUnavailable
 	}
 
 	ms.mux.Lock()
 	defer ms.mux.Unlock()
 	// double check
-	immutable = ms.atomicGetImmutable()
-	if immutable != nil {
+	immutables := ms.atomicGetImmutables()
+	if len(immutables) >= maxImmutableIndexes {
 		return 0, series.ErrResetVersionUnavailable
 	}
-	ms.immutable.Store(ms.mutable)
-	ms.mutable = newTagIndex()
+	// copy on write, the old list may be read without lock
+	newImmutables := make([]tagIndexINTF, len(immutables), len(immutables)+1)
+	copy(newImmutables, immutables)
+	ms.immutables.Store(append(newImmutables, ms.mutable))
+	// the series of immutable index are not in use any more
+	ms.countSeries(-ms.mutable.TagsInUse())
+	ms.mutable = newNextTagIndex(ms.mutable.Version())
 	createdSize = ms.mutable.MemSize()
 	ms.size.Store(int32(createdSize))
 	return createdSize, nil
 }
 
 // FlushMetricsTo Writes metric-data to the table.
-// immutable tagIndex will be removed after call,
+// immutable tagIndexes will be removed after call,
 // index shall be flushed before flushing data.
 func (ms *metricStore) FlushMetricsDataTo(
 	flusher metricsdata.Flusher,
@@ -488,25 +965,64 @@ func (ms *metricStore) FlushMetricsDataTo(
 	flushedSize int,
 	err error,
 ) {
-	// flush field meta info
+	// flush field meta info, the fields data of series are flushed in the same order
 	fmList := ms.fieldsMetas.Load().(field.Metas)
-	flusher.FlushFieldMetas(fmList)
+	flusher.FlushFieldMetas(orderFieldMetas(fmList, flushCtx.fieldOrder))
 
 	// reset the mutable part
 	ms.mux.RLock()
 	flushedSize = ms.mutable.FlushVersionDataTo(flusher, flushCtx)
-	immutable := ms.atomicGetImmutable()
-	// remove the immutable, put the nopTagIndex into it
-	ms.immutable.Store(staticNopTagIndex)
+	immutables := ms.atomicGetImmutables()
+	// remove all the immutables
+	ms.immutables.Store([]tagIndexINTF(nil))
 	ms.mux.RUnlock()
 
-	if immutable != nil {
+	for _, immutable := range immutables {
 		flushedSize += immutable.FlushVersionDataTo(flusher, flushCtx)
 	}
 	ms.size.Sub(int32(flushedSize))
 	return flushedSize, flusher.FlushMetric(flushCtx.metricID)
 }
 
+// orderFieldMetas returns the field metas in the configured flush order,
+// returns a reordered copy for the field metas are read without lock.
+func orderFieldMetas(fmList field.Metas, order option.FieldFlushOrderOption) field.Metas {
+	switch order.Order {
+	case option.FieldOrderByID:
+		ordered := fmList.Clone()
+		sort.Slice(ordered, func(i, j int) bool { return ordered[i].ID < ordered[j].ID })
+		return ordered
+	case option.FieldOrderByAffinity:
+		name2Meta := make(map[string]field.Meta, len(fmList))
+		for _, fm := range fmList {
+			name2Meta[fm.Name] = fm
+		}
+		ordered := make(field.Metas, 0, len(fmList))
+		picked := make(map[uint16]struct{})
+		for _, fieldName := range order.Affinity {
+			fm, ok := name2Meta[fieldName]
+			if !ok {
+				continue
+			}
+			if _, exist := picked[fm.ID]; !exist {
+				picked[fm.ID] = struct{}{}
+				ordered = append(ordered, fm)
+			}
+		}
+		// the others are flushed by field id after the affinity fields
+		others := make(field.Metas, 0, len(fmList)-len(ordered))
+		for _, fm := range fmList {
+			if _, ok := picked[fm.ID]; !ok {
+				others = append(others, fm)
+			}
+		}
+		sort.Slice(others, func(i, j int) bool { return others[i].ID < others[j].ID })
+		return append(ordered, others...)
+	default:
+		return fmList
+	}
+}
+
 // FlushForwardIndexTo flushes metric-block of mStore to the Writer.
 func (ms *metricStore) FlushForwardIndexTo(
 	flusher forwardindex.Flusher,
@@ -522,11 +1038,11 @@ func (ms *metricStore) FlushForwardIndexTo(
 	}
 
 	ms.mux.RLock()
-	immutable := ms.atomicGetImmutable()
+	immutables := ms.atomicGetImmutables()
 	flushForwardIndex(ms.mutable)
 	ms.mux.RUnlock()
 
-	if immutable != nil {
+	for _, immutable := range immutables {
 		flushForwardIndex(immutable)
 	}
 	return flusher.FlushMetricID(ms.metricID)
@@ -542,26 +1058,23 @@ func (ms *metricStore) FlushInvertedIndexTo(
 
 	ms.mux.RLock()
 	defer ms.mux.RUnlock()
-	immutable := ms.atomicGetImmutable()
-	if immutable != nil {
-		for _, entrySet := range immutable.GetTagKVEntrySets() {
-			tagValues := make(map[string]struct{})
+	immutables := ms.atomicGetImmutables()
+	collectTagKeyValues := func(tagIndex tagIndexINTF) {
+		for _, entrySet := range tagIndex.GetTagKVEntrySets() {
+			tagValues, ok := tagKeyValues[entrySet.key]
+			if !ok {
+				tagValues = make(map[string]struct{})
+			}
 			for tagValue := range entrySet.values {
 				tagValues[tagValue] = struct{}{}
 			}
 			tagKeyValues[entrySet.key] = tagValues
 		}
 	}
-	for _, entrySet := range ms.mutable.GetTagKVEntrySets() {
-		tagValues, ok := tagKeyValues[entrySet.key]
-		if !ok {
-			tagValues = make(map[string]struct{})
-		}
-		for tagValue := range entrySet.values {
-			tagValues[tagValue] = struct{}{}
-		}
-		tagKeyValues[entrySet.key] = tagValues
+	for _, immutable := range immutables {
+		collectTagKeyValues(immutable)
 	}
+	collectTagKeyValues(ms.mutable)
 
 	// flush data process
 	flushInvertedIndex := func(tagIndex tagIndexINTF, tagKey, tagValue string) {
@@ -575,7 +1088,7 @@ func (ms *metricStore) FlushInvertedIndexTo(
 	}
 	for tagKey, tagValues := range tagKeyValues {
 		for tagValue := range tagValues {
-			if immutable != nil {
+			for _, immutable := range immutables {
 				flushInvertedIndex(immutable, tagKey, tagValue)
 			}
 			flushInvertedIndex(ms.mutable, tagKey, tagValue)
@@ -588,6 +1101,70 @@ func (ms *metricStore) FlushInvertedIndexTo(
 	return nil
 }
 
+// FindStaleSeriesIDs finds series ids whose latest data is older than the threshold timestamp,
+// also returns all the series ids in memory
+func (ms *metricStore) FindStaleSeriesIDs(interval, threshold int64) (stale, inMemory *series.MultiVerSeriesIDSet) {
+	stale = series.NewMultiVerSeriesIDSet()
+	inMemory = series.NewMultiVerSeriesIDSet()
+
+	findStaleSeriesIDs := func(tagIdx tagIndexINTF) {
+		staleIDs := roaring.New()
+		allIDs := roaring.New()
+		it := tagIdx.AllTStores().iterator()
+		for it.hasNext() {
+			seriesID, tStore := it.next()
+			allIDs.Add(seriesID)
+			if tStore.IsStale(interval, threshold) {
+				staleIDs.Add(seriesID)
+			}
+		}
+		if !staleIDs.IsEmpty() {
+			stale.Add(tagIdx.Version(), staleIDs)
+		}
+		if !allIDs.IsEmpty() {
+			inMemory.Add(tagIdx.Version(), allIDs)
+		}
+	}
+	ms.mux.RLock()
+	findStaleSeriesIDs(ms.mutable)
+	immutables := ms.atomicGetImmutables()
+	ms.mux.RUnlock()
+	for _, immutable := range immutables {
+		findStaleSeriesIDs(immutable)
+	}
+	return stale, inMemory
+}
+
+// CountSlots returns the count of time slots which has value in the time range per series of each version,
+// the series without value in the time range are not included.
+func (ms *metricStore) CountSlots(interval int64, timeRange timeutil.TimeRange) map[series.Version]map[uint32]int {
+	result := make(map[series.Version]map[uint32]int)
+	tsd := encoding.GetTSDDecoder()
+	defer encoding.ReleaseTSDDecoder(tsd)
+
+	countSlots := func(tagIdx tagIndexINTF) {
+		counts := make(map[uint32]int)
+		it := tagIdx.AllTStores().iterator()
+		for it.hasNext() {
+			seriesID, tStore := it.next()
+			if count := tStore.CountSlots(interval, timeRange, tsd); count > 0 {
+				counts[seriesID] = count
+			}
+		}
+		if len(counts) > 0 {
+			result[tagIdx.Version()] = counts
+		}
+	}
+	ms.mux.RLock()
+	countSlots(ms.mutable)
+	immutables := ms.atomicGetImmutables()
+	ms.mux.RUnlock()
+	for _, immutable := range immutables {
+		countSlots(immutable)
+	}
+	return result
+}
+
 // FindSeriesIDsByExpr finds series ids by tag filter expr
 func (ms *metricStore) FindSeriesIDsByExpr(
 	expr stmt.TagFilter,
@@ -595,18 +1172,32 @@ func (ms *metricStore) FindSeriesIDsByExpr(
 	*series.MultiVerSeriesIDSet,
 	error,
 ) {
+	// the regex pattern is compiled once for the mutable and immutable indexes
+	var pattern *regexp.Regexp
+	if regexExpr, ok := expr.(*stmt.RegexExpr); ok {
+		var err error
+		if pattern, err = regexp.Compile(regexExpr.Regexp); err != nil {
+			return nil, fmt.Errorf("compile regex: %s of tag: %s error:%s", regexExpr.Regexp, regexExpr.Key, err)
+		}
+	}
 	multiVerSeriesIDSet := series.NewMultiVerSeriesIDSet()
 
 	findSeriesIDsByExpr := func(tagIdx tagIndexINTF) {
-		if bitMap := tagIdx.FindSeriesIDsByExpr(expr); bitMap != nil {
+		var bitMap *roaring.Bitmap
+		if pattern != nil {
+			bitMap = tagIdx.FindSeriesIDsByRegex(expr.TagKey(), pattern)
+		} else {
+			bitMap = tagIdx.FindSeriesIDsByExpr(expr)
+		}
+		if bitMap != nil {
 			multiVerSeriesIDSet.Add(tagIdx.Version(), bitMap)
 		}
 	}
 	ms.mux.RLock()
 	findSeriesIDsByExpr(ms.mutable)
-	immutable := ms.atomicGetImmutable()
+	immutables := ms.atomicGetImmutables()
 	ms.mux.RUnlock()
-	if immutable != nil {
+	for _, immutable := range immutables {
 		findSeriesIDsByExpr(immutable)
 	}
 	return multiVerSeriesIDSet, nil
@@ -622,25 +1213,47 @@ func (ms *metricStore) GetSeriesIDsForTag(
 	multiVerSeriesIDSet := series.NewMultiVerSeriesIDSet()
 	getSeriesIDsForTag := func(tagIdx tagIndexINTF) {
 		if bitMap := tagIdx.GetSeriesIDsForTag(tagKey); bitMap != nil {
-			multiVerSeriesIDSet.Add(ms.mutable.Version(), bitMap)
+			multiVerSeriesIDSet.Add(tagIdx.Version(), bitMap)
 		}
 	}
 
 	ms.mux.RLock()
 	getSeriesIDsForTag(ms.mutable)
-	immutable := ms.atomicGetImmutable()
+	immutables := ms.atomicGetImmutables()
 	ms.mux.RUnlock()
 
-	if immutable != nil {
+	for _, immutable := range immutables {
 		getSeriesIDsForTag(immutable)
 	}
 	return multiVerSeriesIDSet, nil
 }
 
+// GetSeriesIDsForMetric get all the series ids of metric
+func (ms *metricStore) GetSeriesIDsForMetric() (
+	*series.MultiVerSeriesIDSet,
+	error,
+) {
+	multiVerSeriesIDSet := series.NewMultiVerSeriesIDSet()
+	getSeriesIDsForMetric := func(tagIdx tagIndexINTF) {
+		if bitMap := tagIdx.GetSeriesIDsForMetric(); !bitMap.IsEmpty() {
+			multiVerSeriesIDSet.Add(tagIdx.Version(), bitMap)
+		}
+	}
+
+	ms.mux.RLock()
+	getSeriesIDsForMetric(ms.mutable)
+	immutables := ms.atomicGetImmutables()
+	ms.mux.RUnlock()
+
+	for _, immutable := range immutables {
+		getSeriesIDsForMetric(immutable)
+	}
+	return multiVerSeriesIDSet, nil
+}
+
 func (ms *metricStore) MemSize() int {
 	size := emptyMStoreSize + int(ms.size.Load())
-	immutable := ms.atomicGetImmutable()
-	if immutable != nil {
+	for _, immutable := range ms.atomicGetImmutables() {
 		size += immutable.MemSize()
 	}
 	return size
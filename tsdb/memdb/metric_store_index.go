@@ -59,6 +59,15 @@ type tagIndexINTF interface {
 	// RemoveTStores removes tStores from a list of seriesID
 	RemoveTStores(seriesIDs ...uint32) (removedTStores []tStoreINTF)
 
+	// DeleteTagValues deletes the tag values of tag key matching the pattern,
+	// removes the related series from index, returns the removed series ids and tStores
+	DeleteTagValues(
+		tagKey string,
+		pattern *regexp.Regexp,
+	) (
+		removedSeriesIDs *roaring.Bitmap,
+		removedTStores []tStoreINTF)
+
 	// TagsUsed returns the count of all used tags, it is used for restricting write.
 	TagsUsed() int
 
@@ -80,9 +89,15 @@ type tagIndexINTF interface {
 	// FindSeriesIDsByExpr finds series ids by tag filter expr
 	FindSeriesIDsByExpr(expr stmt.TagFilter) *roaring.Bitmap
 
+	// FindSeriesIDsByRegex finds series ids by the compiled pattern matching the tag values of tag key
+	FindSeriesIDsByRegex(tagKey string, pattern *regexp.Regexp) *roaring.Bitmap
+
 	// GetSeriesIDsForTag get series ids by tagKey
 	GetSeriesIDsForTag(tagKey string) *roaring.Bitmap
 
+	// GetSeriesIDsForMetric get all the series ids of the index
+	GetSeriesIDsForMetric() *roaring.Bitmap
+
 	// MemSize returns the memory size in bytes
 	MemSize() int
 
@@ -185,7 +200,7 @@ func (index *tagIndex) insertNewTStore(
 		tags[""] = ""
 	}
 	for tagKey, tagValue := range tags {
-		entrySet, created, err := index.getOrInsertTagKeyEntry(tagKey)
+		entrySet, created, err := index.getOrInsertTagKeyEntry(tagKey, writeCtx.maxTagKeys)
 		if err != nil {
 			return err
 		}
@@ -216,9 +231,11 @@ func (index *tagIndex) GetTagKVEntrySet(tagKey string) (*tagKVEntrySet, bool) {
 	return index.tagKVEntrySet[offset], true
 }
 
-// getOrInsertTagKeyEntry get or insert a new entrySet, return error when tag keys exceeds the limit.
+// getOrInsertTagKeyEntry get or insert a new entrySet, return error when tag keys exceeds the limit,
+// the default limit is used if maxTagKeys <= 0.
 func (index *tagIndex) getOrInsertTagKeyEntry(
 	tagKey string,
+	maxTagKeys int,
 ) (
 	entrySet *tagKVEntrySet,
 	created bool,
@@ -230,7 +247,10 @@ func (index *tagIndex) getOrInsertTagKeyEntry(
 	if offset < len(index.tagKVEntrySet) && index.tagKVEntrySet[offset].key == tagKey {
 		return index.tagKVEntrySet[offset], false, nil
 	}
-	if length >= constants.MStoreMaxTagKeysCount {
+	if maxTagKeys <= 0 {
+		maxTagKeys = constants.MStoreMaxTagKeysCount
+	}
+	if length >= maxTagKeys {
 		return nil, false, series.ErrTooManyTagKeys
 	}
 	// not present
@@ -277,7 +297,7 @@ func (index *tagIndex) GetOrCreateTStore(
 		tStore, ok := index.seriesID2TStore.get(seriesID)
 		// has been evicted before, reuse the old seriesID
 		if !ok {
-			tStore = newTimeSeriesStore()
+			tStore = newTimeSeriesStore(writeCtx.writeTime)
 			index.seriesID2TStore.put(seriesID, tStore)
 			createdSize += tStore.MemSize()
 		}
@@ -285,7 +305,7 @@ func (index *tagIndex) GetOrCreateTStore(
 	}
 	// seriesID is not allocated before, assign a new one.
 	incrSeriesID := index.idCounter.Inc()
-	newTStore := newTimeSeriesStore()
+	newTStore := newTimeSeriesStore(writeCtx.writeTime)
 	// bind relation of tag kv pairs to the tStore
 	err = index.insertNewTStore(tags, incrSeriesID, newTStore, writeCtx)
 	if err != nil {
@@ -311,6 +331,53 @@ func (index *tagIndex) RemoveTStores(
 	return index.seriesID2TStore.deleteMany(seriesIDs...)
 }
 
+// DeleteTagValues deletes the tag values of tag key matching the pattern,
+// the series of matched tag values are removed from all tag kv entry sets, forward index and tStores,
+// so that the tags limit is released too.
+func (index *tagIndex) DeleteTagValues(
+	tagKey string,
+	pattern *regexp.Regexp,
+) (
+	removedSeriesIDs *roaring.Bitmap,
+	removedTStores []tStoreINTF,
+) {
+	removedSeriesIDs = roaring.New()
+	entrySet, ok := index.GetTagKVEntrySet(tagKey)
+	if !ok {
+		return removedSeriesIDs, nil
+	}
+	for tagValue, bitmap := range entrySet.values {
+		if pattern.MatchString(tagValue) {
+			removedSeriesIDs.Or(bitmap)
+		}
+	}
+	if removedSeriesIDs.IsEmpty() {
+		return removedSeriesIDs, nil
+	}
+	// remove series ids from inverted index, removes the empty tag value and tag key
+	entrySets := index.tagKVEntrySet[:0]
+	for _, entrySet := range index.tagKVEntrySet {
+		for tagValue, bitmap := range entrySet.values {
+			bitmap.AndNot(removedSeriesIDs)
+			if bitmap.IsEmpty() {
+				delete(entrySet.values, tagValue)
+			}
+		}
+		if len(entrySet.values) > 0 {
+			entrySets = append(entrySets, entrySet)
+		}
+	}
+	index.tagKVEntrySet = entrySets
+	// remove series ids from forward index
+	for hash, seriesID := range index.hash2SeriesID {
+		if removedSeriesIDs.Contains(seriesID) {
+			delete(index.hash2SeriesID, hash)
+		}
+	}
+	removedTStores = index.seriesID2TStore.deleteMany(removedSeriesIDs.ToArray()...)
+	return removedSeriesIDs, removedTStores
+}
+
 // TagsUsed returns the count of all used tStores
 func (index *tagIndex) TagsUsed() int {
 	return len(index.hash2SeriesID)
@@ -411,6 +478,19 @@ func (index *tagIndex) findSeriesIDsByRegex(entrySet *tagKVEntrySet, expr *stmt.
 	if err != nil {
 		return nil
 	}
+	return index.findSeriesIDsByPattern(entrySet, pattern)
+}
+
+// FindSeriesIDsByRegex finds series ids by the compiled pattern matching the tag values of tag key
+func (index *tagIndex) FindSeriesIDsByRegex(tagKey string, pattern *regexp.Regexp) *roaring.Bitmap {
+	entrySet, ok := index.GetTagKVEntrySet(tagKey)
+	if !ok {
+		return nil
+	}
+	return index.findSeriesIDsByPattern(entrySet, pattern)
+}
+
+func (index *tagIndex) findSeriesIDsByPattern(entrySet *tagKVEntrySet, pattern *regexp.Regexp) *roaring.Bitmap {
 	// the regex pattern is regarded as a prefix string + pattern
 	literalPrefix, _ := pattern.LiteralPrefix()
 	union := roaring.New()
@@ -447,17 +527,22 @@ func (index *tagIndex) GetSeriesIDsForTag(tagKey string) *roaring.Bitmap {
 	return union
 }
 
+// GetSeriesIDsForMetric get all the series ids of the index
+func (index *tagIndex) GetSeriesIDsForMetric() *roaring.Bitmap {
+	return index.seriesID2TStore.seriesIDs.Clone()
+}
+
 // scan scans metric store data based on scanner context
 func (index *tagIndex) scan(sCtx *series.ScanContext) {
 	index.seriesID2TStore.scan(index.version, sCtx)
 }
 
-// staticNopTagIndex is the static nop-tagIndex,
-// it is used as a placeholder of immutable atomic.Value
-var staticNopTagIndex = newNopTagIndex()
-
-func newNopTagIndex() tagIndexINTF {
+// newNextTagIndex returns a new tagIndexINTF which version is greater than the previous version,
+// keeps versions distinct when resetting version repeatedly in the same millisecond.
+func newNextTagIndex(prevVersion series.Version) tagIndexINTF {
 	ti := newTagIndex().(*tagIndex)
-	ti.version = 0
+	if ti.version <= prevVersion {
+		ti.version = prevVersion + 1
+	}
 	return ti
 }
@@ -1,6 +1,7 @@
 package memdb
 
 import (
+	"regexp"
 	"strconv"
 	"testing"
 
@@ -236,6 +237,10 @@ func Test_tagIndex_findSeriesIDsByRegex(t *testing.T) {
 	// literal prefix:22 not exist
 	bitmap = tagIdxInterface.FindSeriesIDsByExpr(&stmt.RegexExpr{Key: "host", Regexp: `22+`})
 	assert.Equal(t, uint64(0), bitmap.GetCardinality())
+	// compiled pattern
+	bitmap = tagIdxInterface.FindSeriesIDsByRegex("host", regexp.MustCompile(`b2[0-9]+`))
+	assert.Equal(t, uint64(2), bitmap.GetCardinality())
+	assert.Nil(t, tagIdxInterface.FindSeriesIDsByRegex("not-exist-key", regexp.MustCompile(`b2[0-9]+`)))
 
 }
 
@@ -252,6 +257,25 @@ func Test_tagIndex_getSeriesIDsForTag(t *testing.T) {
 	assert.Equal(t, uint64(8), bitmap.GetCardinality())
 }
 
+func Test_tagIndex_getSeriesIDsForMetric(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockGenerator := metadb.NewMockIDGenerator(ctrl)
+	mockGenerator.EXPECT().GenTagKeyID(gomock.Any(), gomock.Any()).Return(uint32(1)).AnyTimes()
+	tagIdxInterface := newTagIndex()
+	assert.True(t, tagIdxInterface.GetSeriesIDsForMetric().IsEmpty())
+
+	_, _, _ = tagIdxInterface.GetOrCreateTStore(map[string]string{"host": "a", "zone": "nj"},
+		writeContext{generator: mockGenerator})
+	_, _, _ = tagIdxInterface.GetOrCreateTStore(map[string]string{"host": "b"},
+		writeContext{generator: mockGenerator})
+	bitmap := tagIdxInterface.GetSeriesIDsForMetric()
+	assert.Equal(t, []uint32{1, 2}, bitmap.ToArray())
+	// returns a copy
+	bitmap.Clear()
+	assert.False(t, tagIdxInterface.GetSeriesIDsForMetric().IsEmpty())
+}
+
 type mockTagKey struct {
 }
 
@@ -347,3 +371,72 @@ func Benchmark_xxhash(b *testing.B) {
 		xxhash.Sum64String(_testHashString)
 	}
 }
+
+func Test_tagIndex_DeleteTagValues(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockGenerator := metadb.NewMockIDGenerator(ctrl)
+	mockGenerator.EXPECT().GenTagKeyID(gomock.Any(), gomock.Any()).Return(uint32(1)).AnyTimes()
+
+	tagIdxInterface := newTagIndex()
+	tagIdx := tagIdxInterface.(*tagIndex)
+	for _, tags := range []map[string]string{
+		{"host": "abc-1", "zone": "sh"},   // 1
+		{"host": "abc-2", "zone": "sh"},   // 2
+		{"host": "abc-3", "zone": "bj"},   // 3
+		{"host": "bcd-1", "zone": "bj"},   // 4
+		{"host": "bcd-2", "path": "/tmp"}, // 5
+		{"path": "/data"},                 // 6
+	} {
+		_, _, err := tagIdxInterface.GetOrCreateTStore(tags, writeContext{generator: mockGenerator})
+		assert.Nil(t, err)
+	}
+	// tag key not exist
+	removedSeriesIDs, removedTStores := tagIdxInterface.DeleteTagValues("ip", regexp.MustCompile(".*"))
+	assert.True(t, removedSeriesIDs.IsEmpty())
+	assert.Empty(t, removedTStores)
+	// no tag value matched
+	removedSeriesIDs, removedTStores = tagIdxInterface.DeleteTagValues("host", regexp.MustCompile("^xyz"))
+	assert.True(t, removedSeriesIDs.IsEmpty())
+	assert.Empty(t, removedTStores)
+	assert.Equal(t, 6, tagIdxInterface.TagsUsed())
+
+	removedSeriesIDs, removedTStores = tagIdxInterface.DeleteTagValues("host", regexp.MustCompile("^abc-|-2$"))
+	assert.Equal(t, []uint32{1, 2, 3, 5}, removedSeriesIDs.ToArray())
+	assert.Len(t, removedTStores, 4)
+	assert.Equal(t, 2, tagIdxInterface.TagsUsed())
+	assert.Equal(t, 2, tagIdxInterface.TagsInUse())
+
+	// matching tag values and series are removed
+	entrySet, ok := tagIdxInterface.GetTagKVEntrySet("host")
+	assert.True(t, ok)
+	assert.Len(t, entrySet.values, 1)
+	assert.Equal(t, []uint32{4}, entrySet.values["bcd-1"].ToArray())
+	// non-matching retained
+	entrySet, ok = tagIdxInterface.GetTagKVEntrySet("zone")
+	assert.True(t, ok)
+	assert.Len(t, entrySet.values, 1)
+	assert.Equal(t, []uint32{4}, entrySet.values["bj"].ToArray())
+	entrySet, ok = tagIdxInterface.GetTagKVEntrySet("path")
+	assert.True(t, ok)
+	assert.Len(t, entrySet.values, 1)
+	assert.Equal(t, []uint32{6}, entrySet.values["/data"].ToArray())
+	_, ok = tagIdxInterface.GetTStore(map[string]string{"host": "abc-1", "zone": "sh"})
+	assert.False(t, ok)
+	_, ok = tagIdxInterface.GetTStore(map[string]string{"host": "bcd-1", "zone": "bj"})
+	assert.True(t, ok)
+	_, ok = tagIdxInterface.GetTStoreBySeriesID(5)
+	assert.False(t, ok)
+
+	// delete all values of tag key, removes the empty tag key
+	_, _ = tagIdxInterface.DeleteTagValues("zone", regexp.MustCompile(".*"))
+	_, ok = tagIdxInterface.GetTagKVEntrySet("zone")
+	assert.False(t, ok)
+	assert.Len(t, tagIdx.tagKVEntrySet, 1)
+
+	// deleted series is recreated with a new series id
+	_, _, err := tagIdxInterface.GetOrCreateTStore(map[string]string{"host": "abc-1"}, writeContext{generator: mockGenerator})
+	assert.Nil(t, err)
+	entrySet, _ = tagIdxInterface.GetTagKVEntrySet("host")
+	assert.Equal(t, []uint32{7}, entrySet.values["abc-1"].ToArray())
+}
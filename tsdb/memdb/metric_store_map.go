@@ -184,7 +184,12 @@ func (m *metricMap) scan(version series.Version, sCtx *series.ScanContext) {
 	for {
 		if i1 >= n1 || len(querySeriesIDs) == 0 {
 			if idx > 0 {
-				worker.Emit(newScanEvent(idx, stores, seriesIDBuf, version, sCtx))
+				event := newScanEvent(idx, stores, seriesIDBuf, version, sCtx)
+				if sCtx.Canceled() {
+					event.release()
+					return
+				}
+				worker.Emit(event)
 				idx = 0
 			}
 			n1, querySeriesIDs = queryIt.Next()
@@ -238,7 +243,12 @@ func (m *metricMap) scanAll(version series.Version, sCtx *series.ScanContext) {
 			if hasGroupBy {
 				seriesIt.NextMany(seriesIDs)
 			}
-			worker.Emit(newScanEvent(idx, stores, seriesIDs, version, sCtx))
+			event := newScanEvent(idx, stores, seriesIDs, version, sCtx)
+			if sCtx.Canceled() {
+				event.release()
+				return
+			}
+			worker.Emit(event)
 			stores = getStores()
 			if hasGroupBy {
 				seriesIDs = *series.Uint32Pool.Get()
@@ -250,7 +260,12 @@ func (m *metricMap) scanAll(version series.Version, sCtx *series.ScanContext) {
 		if hasGroupBy {
 			seriesIt.NextMany(seriesIDs)
 		}
-		worker.Emit(newScanEvent(idx, stores, seriesIDs, version, sCtx))
+		event := newScanEvent(idx, stores, seriesIDs, version, sCtx)
+		if sCtx.Canceled() {
+			event.release()
+			return
+		}
+		worker.Emit(event)
 	}
 }
 
@@ -20,9 +20,7 @@ func _assertSortedOrder(t *testing.T, m *metricMap) {
 }
 
 func _newTestTStore(seriesID uint32) tStoreINTF {
-	tStore := newTimeSeriesStore()
-	tStore.(*timeSeriesStore).lastWroteTime.Store(seriesID)
-	return tStore
+	return newTimeSeriesStore(int64(seriesID) * 1000)
 }
 
 func Test_metricMap_put(t *testing.T) {
@@ -5,7 +5,9 @@ import (
 	"github.com/lindb/lindb/series/field"
 )
 
-// Scan scans metric store based on scan context
+// Scan scans metric store based on scan context,
+// the scan events read the single slot directly if the query time range resolves to a single slot(fast path).
+// the scan is aborted between the series if the scan context is canceled.
 func (ms *metricStore) Scan(sCtx *series.ScanContext) {
 	// first need check query's fields is match store's fields, if not return.
 	fmList := ms.fieldsMetas.Load().(field.Metas)
@@ -15,15 +17,32 @@ func (ms *metricStore) Scan(sCtx *series.ScanContext) {
 	}
 	// scan tagIndex when version matches the idSet
 	scanOnVersionMatch := func(idx tagIndexINTF) {
+		if sCtx.Canceled() {
+			return
+		}
 		if _, ok := sCtx.SeriesIDSet.Versions()[idx.Version()]; ok {
 			idx.scan(sCtx)
 		}
 	}
 	ms.mux.RLock()
 	scanOnVersionMatch(ms.mutable)
-	immutable := ms.atomicGetImmutable()
+	immutables := ms.atomicGetImmutables()
 	ms.mux.RUnlock()
-	if immutable != nil {
+	for _, immutable := range immutables {
 		scanOnVersionMatch(immutable)
 	}
 }
+
+// singleSlot returns the family time and slot if the query time range resolves to a single slot of storage
+func singleSlot(sCtx *series.ScanContext) (familyTime int64, slot int, ok bool) {
+	timeRange := sCtx.TimeRange
+	if sCtx.IntervalCalc == nil || sCtx.StorageInterval <= 0 || sCtx.QueryInterval != sCtx.StorageInterval ||
+		timeRange.Start <= 0 || timeRange.Start != timeRange.End {
+		return 0, 0, false
+	}
+	calc := sCtx.IntervalCalc
+	segmentTime := calc.CalcSegmentTime(timeRange.Start)
+	familyTime = calc.CalcFamilyStartTime(segmentTime, calc.CalcFamily(timeRange.Start, segmentTime))
+	slot = calc.CalcSlot(timeRange.Start, familyTime, sCtx.StorageInterval)
+	return familyTime, slot, true
+}
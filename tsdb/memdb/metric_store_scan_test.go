@@ -1,12 +1,16 @@
 package memdb
 
 import (
+	"context"
+	"sync"
 	"testing"
 
 	"github.com/RoaringBitmap/roaring"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/lindb/lindb/aggregation"
+	"github.com/lindb/lindb/aggregation/function"
 	"github.com/lindb/lindb/pkg/timeutil"
 	pb "github.com/lindb/lindb/rpc/proto/field"
 	"github.com/lindb/lindb/series"
@@ -39,10 +43,10 @@ func Test_MetricStore_scan(t *testing.T) {
 	ti2.version = 2
 	ti2.earliestTimeDelta.Store(200)
 	ti2.latestTimeDelta.Store(300)
-	ts5 := newTimeSeriesStore()
-	ts6 := newTimeSeriesStore()
-	ts7 := newTimeSeriesStore()
-	ts8 := newTimeSeriesStore()
+	ts5 := newTimeSeriesStore(timeutil.Now())
+	ts6 := newTimeSeriesStore(timeutil.Now())
+	ts7 := newTimeSeriesStore(timeutil.Now())
+	ts8 := newTimeSeriesStore(timeutil.Now())
 	ts5.(*timeSeriesStore).insertFStore(newFieldStore(1))
 	ts5.(*timeSeriesStore).insertFStore(newFieldStore(2))
 	ts5.(*timeSeriesStore).insertFStore(newFieldStore(3))
@@ -61,7 +65,7 @@ func Test_MetricStore_scan(t *testing.T) {
 	idset.Add(2, bitmap)
 
 	// build mStore
-	mStore.immutable.Store(ti1)
+	mStore.immutables.Store([]tagIndexINTF{ti1})
 	mStore.mutable = ti2
 	metric := &pb.Metric{
 		Name:      "cpu",
@@ -117,3 +121,361 @@ func Test_MetricStore_scan(t *testing.T) {
 		FieldIDs:    []uint16{1, 2, 3, 4},
 	})
 }
+
+// buildScanStores builds time series stores which have sum field(id:1) with value=slot for slots [0,100)
+func buildScanStores(count int, familyTime int64) []tStoreINTF {
+	bs := newBlockStore(32)
+	var stores []tStoreINTF
+	for i := 0; i < count; i++ {
+		tStore := newTimeSeriesStore(timeutil.Now()).(*timeSeriesStore)
+		fStore := newFieldStore(1)
+		for slot := 0; slot < 100; slot++ {
+			fStore.Write(&pb.Field{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: float64(slot)}}},
+				writeContext{
+					blockStore:   bs,
+					familyTime:   familyTime,
+					slotIndex:    slot,
+					timeInterval: 10 * timeutil.OneSecond,
+				})
+		}
+		tStore.insertFStore(fStore)
+		stores = append(stores, tStore)
+	}
+	return stores
+}
+
+// newSingleSlotScanContext returns the scan context for querying the single slot,
+// the fast path is disabled if not single slot.
+func newSingleSlotScanContext(slotTime int64, singleSlot bool) *series.ScanContext {
+	interval := timeutil.Interval(10 * timeutil.OneSecond)
+	timeRange := timeutil.TimeRange{Start: slotTime, End: slotTime}
+	aggSpec := aggregation.NewAggregatorSpec("f1", field.SumField)
+	aggSpec.AddFunctionType(function.Sum)
+	sCtx := &series.ScanContext{
+		FieldIDs:        []uint16{1},
+		TimeRange:       timeRange,
+		IntervalCalc:    interval.Calculator(),
+		StorageInterval: interval.Int64(),
+		Aggregators: sync.Pool{
+			New: func() interface{} {
+				return aggregation.NewFieldAggregates(interval, 1, timeRange, true,
+					aggregation.AggregatorSpecs{aggSpec})
+			},
+		},
+	}
+	if singleSlot {
+		sCtx.QueryInterval = interval.Int64()
+	}
+	return sCtx
+}
+
+// scanPoints scans the stores with the scan context, returns the points of result set
+func scanPoints(t testing.TB, stores []tStoreINTF, sCtx *series.ScanContext) map[int64]float64 {
+	buf := getStores()
+	copy(buf, stores)
+	event := newScanEvent(len(stores), buf, nil, series.Version(1), sCtx)
+	assert.True(t, event.Scan())
+	points := make(map[int64]float64)
+	it := event.ResultSet().(aggregation.FieldAggregates)[0].ResultSet()
+	for it.HasNext() {
+		startTime, fieldIt := it.Next()
+		if fieldIt == nil {
+			continue
+		}
+		for fieldIt.HasNext() {
+			primitiveIt := fieldIt.Next()
+			for primitiveIt.HasNext() {
+				slot, value := primitiveIt.Next()
+				points[startTime+int64(slot)*10*timeutil.OneSecond] += value
+			}
+		}
+	}
+	event.Release()
+	return points
+}
+
+func Test_MetricStore_scan_outOfOrderWrites(t *testing.T) {
+	familyTime, _ := timeutil.ParseTimestamp("20190702 19:00:00", "20060102 15:04:05")
+	nextFamilyTime := familyTime + timeutil.OneHour
+	interval := timeutil.Interval(10 * timeutil.OneSecond)
+
+	// writes slots out of order, which expands or compresses the block of family
+	bs := newBlockStore(32)
+	tStore := newTimeSeriesStore(timeutil.Now()).(*timeSeriesStore)
+	fStore := newFieldStore(1)
+	writes := []struct {
+		familyTime int64
+		slot       int
+	}{
+		{nextFamilyTime, 5}, {familyTime, 200}, {familyTime, 3}, {familyTime, 100},
+		{nextFamilyTime, 1}, {familyTime, 2}, {familyTime, 150}, {familyTime, 3},
+	}
+	for _, w := range writes {
+		fStore.Write(&pb.Field{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1}}},
+			writeContext{
+				blockStore:   bs,
+				familyTime:   w.familyTime,
+				slotIndex:    w.slot,
+				timeInterval: interval.Int64(),
+			})
+	}
+	tStore.insertFStore(fStore)
+
+	timeRange := timeutil.TimeRange{Start: familyTime, End: nextFamilyTime + timeutil.OneHour - interval.Int64()}
+	sCtx := newSingleSlotScanContext(familyTime, false)
+	sCtx.TimeRange = timeRange
+	sCtx.QueryInterval = interval.Int64()
+	sCtx.Aggregators = sync.Pool{
+		New: func() interface{} {
+			aggSpec := aggregation.NewAggregatorSpec("f1", field.SumField)
+			aggSpec.AddFunctionType(function.Sum)
+			return aggregation.NewFieldAggregates(interval, 1, timeRange, true, aggregation.AggregatorSpecs{aggSpec})
+		},
+	}
+	buf := getStores()
+	buf[0] = tStore
+	event := newScanEvent(1, buf, nil, series.Version(1), sCtx)
+	assert.True(t, event.Scan())
+	defer event.Release()
+
+	// result timestamps of series are monotonic
+	var (
+		timestamps []int64
+		values     []float64
+	)
+	it := event.ResultSet().(aggregation.FieldAggregates)[0].ResultSet()
+	for it.HasNext() {
+		startTime, fieldIt := it.Next()
+		if fieldIt == nil {
+			continue
+		}
+		for fieldIt.HasNext() {
+			primitiveIt := fieldIt.Next()
+			for primitiveIt.HasNext() {
+				slot, value := primitiveIt.Next()
+				timestamps = append(timestamps, startTime+int64(slot)*interval.Int64())
+				values = append(values, value)
+			}
+		}
+	}
+	slotTime := func(familyTime int64, slot int) int64 {
+		return familyTime + int64(slot)*interval.Int64()
+	}
+	assert.Equal(t, []int64{
+		slotTime(familyTime, 2), slotTime(familyTime, 3), slotTime(familyTime, 100),
+		slotTime(familyTime, 150), slotTime(familyTime, 200),
+		slotTime(nextFamilyTime, 1), slotTime(nextFamilyTime, 5),
+	}, timestamps)
+	assert.Equal(t, []float64{1, 2, 1, 1, 1, 1, 1}, values)
+}
+
+func Test_MetricStore_scan_rollup(t *testing.T) {
+	familyTime, _ := timeutil.ParseTimestamp("20190702 19:00:00", "20060102 15:04:05")
+	storageInterval := 10 * timeutil.OneSecond
+	queryInterval := timeutil.Interval(timeutil.OneMinute)
+	// query time range 19:01:00 ~ 19:05:00, slots of storage interval: 6 ~ 30
+	timeRange := timeutil.TimeRange{Start: familyTime + timeutil.OneMinute, End: familyTime + 5*timeutil.OneMinute}
+	slots := []int{5, 6, 7, 11, 12, 30}
+
+	scanRollup := func(fieldType field.Type, values []float64, newField func(value float64) *pb.Field) map[int64]float64 {
+		bs := newBlockStore(32)
+		tStore := newTimeSeriesStore(timeutil.Now()).(*timeSeriesStore)
+		fStore := newFieldStore(1)
+		for idx, slot := range slots {
+			fStore.Write(newField(values[idx]), writeContext{
+				blockStore:   bs,
+				familyTime:   familyTime,
+				slotIndex:    slot,
+				timeInterval: storageInterval,
+			})
+		}
+		tStore.insertFStore(fStore)
+		sCtx := &series.ScanContext{
+			FieldIDs:        []uint16{1},
+			TimeRange:       timeRange,
+			QueryInterval:   queryInterval.Int64(),
+			IntervalCalc:    queryInterval.Calculator(),
+			StorageInterval: storageInterval,
+			Aggregators: sync.Pool{
+				New: func() interface{} {
+					aggSpec := aggregation.NewAggregatorSpec("f1", fieldType)
+					aggSpec.AddFunctionType(fieldType.DownSamplingFunc())
+					return aggregation.NewFieldAggregates(queryInterval, 6, timeRange, true, aggregation.AggregatorSpecs{aggSpec})
+				},
+			},
+		}
+		buf := getStores()
+		buf[0] = tStore
+		event := newScanEvent(1, buf, nil, series.Version(1), sCtx)
+		assert.True(t, event.Scan())
+		defer event.Release()
+
+		points := make(map[int64]float64)
+		it := event.ResultSet().(aggregation.FieldAggregates)[0].ResultSet()
+		for it.HasNext() {
+			startTime, fieldIt := it.Next()
+			if fieldIt == nil {
+				continue
+			}
+			for fieldIt.HasNext() {
+				primitiveIt := fieldIt.Next()
+				for primitiveIt.HasNext() {
+					slot, value := primitiveIt.Next()
+					points[startTime+int64(slot)*queryInterval.Int64()] = value
+				}
+			}
+		}
+		return points
+	}
+
+	// sum field sums the slots of query interval
+	points := scanRollup(field.SumField, []float64{1, 2, 3, 4, 5, 6}, func(value float64) *pb.Field {
+		return &pb.Field{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: value}}}
+	})
+	assert.Equal(t, map[int64]float64{
+		familyTime + timeutil.OneMinute:   2 + 3 + 4,
+		familyTime + 2*timeutil.OneMinute: 5,
+		// the slot at the end of time range is included
+		familyTime + 5*timeutil.OneMinute: 6,
+	}, points)
+	// gauge field keeps the last value of query interval
+	points = scanRollup(field.GaugeField, []float64{6, 5, 4, 3, 2, 1}, func(value float64) *pb.Field {
+		return &pb.Field{Name: "f1", Field: &pb.Field_Gauge{Gauge: &pb.Gauge{Value: value}}}
+	})
+	assert.Equal(t, map[int64]float64{
+		familyTime + timeutil.OneMinute:   3,
+		familyTime + 2*timeutil.OneMinute: 2,
+		familyTime + 5*timeutil.OneMinute: 1,
+	}, points)
+}
+
+func Test_MetricStore_scan_singleSlot(t *testing.T) {
+	familyTime, _ := timeutil.ParseTimestamp("20190702 19:00:00", "20060102 15:04:05")
+	stores := buildScanStores(3, familyTime)
+
+	// slot in compress data, current buffer and without data
+	for _, slot := range []int{10, 50, 99, 120} {
+		slotTime := familyTime + int64(slot)*10*timeutil.OneSecond
+		sCtx := newSingleSlotScanContext(slotTime, true)
+		familyTimeOfSlot, slotOfFamily, ok := singleSlot(sCtx)
+		assert.True(t, ok)
+		assert.Equal(t, familyTime, familyTimeOfSlot)
+		assert.Equal(t, slot, slotOfFamily)
+
+		points := scanPoints(t, stores, sCtx)
+		if slot < 100 {
+			assert.Equal(t, map[int64]float64{slotTime: float64(slot * 3)}, points)
+		} else {
+			assert.Empty(t, points)
+		}
+	}
+	// not single slot
+	sCtx := newSingleSlotScanContext(familyTime, true)
+	sCtx.TimeRange.End += timeutil.OneMinute
+	_, _, ok := singleSlot(sCtx)
+	assert.False(t, ok)
+	sCtx = newSingleSlotScanContext(familyTime, true)
+	sCtx.QueryInterval = timeutil.OneMinute
+	_, _, ok = singleSlot(sCtx)
+	assert.False(t, ok)
+}
+
+func Benchmark_MetricStore_scan_singleSlot(b *testing.B) {
+	familyTime, _ := timeutil.ParseTimestamp("20190702 19:00:00", "20060102 15:04:05")
+	stores := buildScanStores(series.ScanBufSize, familyTime)
+
+	for name, slot := range map[string]int64{"current buffer": 99, "compress data": 50} {
+		slotTime := familyTime + slot*10*timeutil.OneSecond
+		b.Run(name+"/fast path", func(b *testing.B) {
+			sCtx := newSingleSlotScanContext(slotTime, true)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = scanPoints(b, stores, sCtx)
+			}
+		})
+		b.Run(name+"/general path", func(b *testing.B) {
+			sCtx := newSingleSlotScanContext(slotTime, false)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = scanPoints(b, stores, sCtx)
+			}
+		})
+	}
+}
+
+// cancelScanWorker scans the emitted events, cancels the scan after receiving the first event
+type cancelScanWorker struct {
+	cancel  context.CancelFunc
+	events  int
+	scanned []bool
+}
+
+func (w *cancelScanWorker) Emit(event series.ScanEvent) {
+	w.events++
+	w.cancel()
+	w.scanned = append(w.scanned, event.Scan())
+}
+func (w *cancelScanWorker) Close() {}
+
+func Test_MetricStore_scan_cancel(t *testing.T) {
+	familyTime, _ := timeutil.ParseTimestamp("20190702 19:00:00", "20060102 15:04:05")
+	mStore := newMetricStore(100).(*metricStore)
+	mStore.fieldsMetas.Store(field.Metas{{ID: 1, Type: field.SumField, Name: "f1"}})
+	ti := newTagIndex().(*tagIndex)
+	ti.version = 1
+	// 3 scan events of all series
+	stores := buildScanStores(3*series.ScanBufSize, familyTime)
+	seriesIDs := roaring.New()
+	for idx, store := range stores {
+		ti.seriesID2TStore.put(uint32(idx+1), store)
+		seriesIDs.Add(uint32(idx + 1))
+	}
+	mStore.mutable = ti
+	newScanContext := func(ids *roaring.Bitmap, hasGroupBy bool) (*series.ScanContext, *cancelScanWorker) {
+		sCtx := newSingleSlotScanContext(familyTime, false)
+		idSet := series.NewMultiVerSeriesIDSet()
+		idSet.Add(1, ids)
+		ctx, cancel := context.WithCancel(context.Background())
+		worker := &cancelScanWorker{cancel: cancel}
+		sCtx.SeriesIDSet = idSet
+		sCtx.HasGroupBy = hasGroupBy
+		sCtx.Worker = worker
+		sCtx.Context = ctx
+		return sCtx, worker
+	}
+
+	// canceled mid-scan, scan all series of store
+	for _, hasGroupBy := range []bool{false, true} {
+		sCtx, worker := newScanContext(seriesIDs, hasGroupBy)
+		mStore.Scan(sCtx)
+		assert.Equal(t, 1, worker.events)
+		// event is dropped after canceled
+		assert.Equal(t, []bool{false}, worker.scanned)
+	}
+	// canceled mid-scan, scan part of series
+	partIDs := seriesIDs.Clone()
+	partIDs.Remove(1)
+	sCtx, worker := newScanContext(partIDs, false)
+	mStore.Scan(sCtx)
+	assert.Equal(t, 1, worker.events)
+
+	// canceled before scan
+	sCtx, worker = newScanContext(seriesIDs, false)
+	worker.cancel()
+	mStore.Scan(sCtx)
+	assert.Zero(t, worker.events)
+
+	// canceled before scanning the last batch
+	m := newMetricMap()
+	for idx, store := range stores[:series.ScanBufSize+10] {
+		m.put(uint32(idx+1), store)
+	}
+	sCtx, worker = newScanContext(m.seriesIDs.Clone(), false)
+	m.scanAll(series.Version(1), sCtx)
+	assert.Equal(t, 1, worker.events)
+	// field store aborts scanning the segments, no aggregator is called
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	fStore := stores[0].(*timeSeriesStore).fStoreNodes[0]
+	fStore.scan(aggregation.NewMockSeriesAggregator(ctrl), newMemScanContext(sCtx))
+}
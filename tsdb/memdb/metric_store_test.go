@@ -2,12 +2,17 @@ package memdb
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"testing"
 
+	"github.com/lindb/lindb/constants"
+	"github.com/lindb/lindb/pkg/option"
 	"github.com/lindb/lindb/pkg/timeutil"
 	pb "github.com/lindb/lindb/rpc/proto/field"
 	"github.com/lindb/lindb/series"
 	"github.com/lindb/lindb/series/field"
+	"github.com/lindb/lindb/sql/stmt"
 	"github.com/lindb/lindb/tsdb/metadb"
 	"github.com/lindb/lindb/tsdb/tblstore/forwardindex"
 	"github.com/lindb/lindb/tsdb/tblstore/invertedindex"
@@ -34,9 +39,17 @@ func Test_mStore_setMaxTagsLimit(t *testing.T) {
 	mStoreInterface := newMetricStore(100)
 	mStore := mStoreInterface.(*metricStore)
 
-	assert.NotZero(t, mStore.getMaxTagsLimit())
+	assert.NotZero(t, mStore.GetMaxTagsLimit())
 	mStoreInterface.SetMaxTagsLimit(1000)
-	assert.Equal(t, uint32(1000), mStore.getMaxTagsLimit())
+	assert.Equal(t, uint32(1000), mStore.GetMaxTagsLimit())
+}
+
+func Test_mStore_setMaxTagKeysLimit(t *testing.T) {
+	mStoreInterface := newMetricStore(100)
+
+	assert.Equal(t, uint32(constants.MStoreMaxTagKeysCount), mStoreInterface.GetMaxTagKeysLimit())
+	mStoreInterface.SetMaxTagKeysLimit(10)
+	assert.Equal(t, uint32(10), mStoreInterface.GetMaxTagKeysLimit())
 }
 
 func Test_mStore_write_getOrCreateTStore_error(t *testing.T) {
@@ -75,6 +88,108 @@ func Test_mStore_isFull(t *testing.T) {
 	assert.Zero(t, writtenSize)
 }
 
+func Test_mStore_write_seriesQuotaExceeded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mStoreInterface := newMetricStore(100)
+	mStore := mStoreInterface.(*metricStore)
+
+	mockTStore := NewMocktStoreINTF(ctrl)
+	mockTStore.EXPECT().Write(gomock.Any(), gomock.Any()).Return(10, nil).AnyTimes()
+	mockTagIdx := NewMocktagIndexINTF(ctrl)
+	mockTagIdx.EXPECT().TagsUsed().Return(1).AnyTimes()
+	mockTagIdx.EXPECT().UpdateIndexTimeRange(gomock.Any()).Return().AnyTimes()
+	mockTagIdx.EXPECT().GetTStore(map[string]string{"type": "new"}).Return(nil, false).AnyTimes()
+	mockTagIdx.EXPECT().GetTStore(map[string]string{"type": "old"}).Return(mockTStore, true).AnyTimes()
+	mStore.mutable = mockTagIdx
+
+	writeCtx := writeContext{newSeriesAllowed: func() bool { return false }}
+	// reject new series
+	writtenSize, err := mStoreInterface.Write(
+		&pb.Metric{Name: "metric", Tags: map[string]string{"type": "new"}}, writeCtx)
+	assert.Equal(t, series.ErrSeriesQuotaExceeded, err)
+	assert.Zero(t, writtenSize)
+	// existed series is writable
+	writtenSize, err = mStoreInterface.Write(
+		&pb.Metric{Name: "metric", Tags: map[string]string{"type": "old"}}, writeCtx)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, writtenSize)
+}
+
+func Test_mStore_write_tooManyTagsPerMetric(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mStoreInterface := newMetricStore(100)
+	mStore := mStoreInterface.(*metricStore)
+
+	mockTStore := NewMocktStoreINTF(ctrl)
+	mockTStore.EXPECT().Write(gomock.Any(), gomock.Any()).Return(10, nil).AnyTimes()
+	mockTagIdx := NewMocktagIndexINTF(ctrl)
+	mockTagIdx.EXPECT().TagsUsed().Return(1).AnyTimes()
+	mockTagIdx.EXPECT().UpdateIndexTimeRange(gomock.Any()).Return().AnyTimes()
+	mockTagIdx.EXPECT().GetTStore(gomock.Any()).Return(mockTStore, true).AnyTimes()
+	mStore.mutable = mockTagIdx
+
+	tags := map[string]string{"host": "1.1.1.1", "ip": "1.1.1.1", "zone": "sh"}
+	// reject the metric before indexing
+	writtenSize, err := mStoreInterface.Write(&pb.Metric{Name: "metric", Tags: tags},
+		writeContext{maxTagsPerMetric: 2})
+	assert.Equal(t, series.ErrTooManyTagsPerMetric, err)
+	assert.Zero(t, writtenSize)
+	// within the limit
+	writtenSize, err = mStoreInterface.Write(&pb.Metric{Name: "metric", Tags: tags},
+		writeContext{maxTagsPerMetric: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, 10, writtenSize)
+	// unlimited
+	writtenSize, err = mStoreInterface.Write(&pb.Metric{Name: "metric", Tags: tags}, writeContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, 10, writtenSize)
+}
+
+func Test_mStore_Validate(t *testing.T) {
+	mStoreInterface := newMetricStore(100)
+	mStore := mStoreInterface.(*metricStore)
+	var fms field.Metas
+	for id := 1; id < constants.TStoreMaxFieldsCount; id++ {
+		fms = fms.Insert(field.Meta{Name: "f" + strconv.Itoa(id), ID: uint16(id), Type: field.SumField})
+	}
+	mStore.fieldsMetas.Store(fms)
+
+	newField := func(name string) *pb.Field {
+		return &pb.Field{Name: name, Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}}
+	}
+	metric := &pb.Metric{Name: "metric", Tags: map[string]string{"host": "1.1.1.1"},
+		Fields: []*pb.Field{newField("f1"), newField("new1"), {Name: "unknown"}}}
+	// one more field is allowed
+	assert.NoError(t, mStoreInterface.Validate(metric, writeContext{}))
+	// too many fields
+	metric.Fields = append(metric.Fields, newField("new2"))
+	assert.Equal(t, series.ErrTooManyFields, mStoreInterface.Validate(metric, writeContext{}))
+	// wrong field type
+	metric.Fields = []*pb.Field{{Name: "f1", Field: &pb.Field_Max{Max: &pb.Max{Value: 1.0}}}}
+	assert.Equal(t, series.ErrWrongFieldType, mStoreInterface.Validate(metric, writeContext{}))
+	// too many tags per metric
+	assert.Equal(t, series.ErrTooManyTagsPerMetric,
+		mStoreInterface.Validate(&pb.Metric{Tags: map[string]string{"host": "1.1.1.1", "ip": "1.1.1.1"}},
+			writeContext{maxTagsPerMetric: 1}))
+	// series quota exceeded
+	metric.Fields = []*pb.Field{newField("f1")}
+	assert.Equal(t, series.ErrSeriesQuotaExceeded,
+		mStoreInterface.Validate(metric, writeContext{newSeriesAllowed: func() bool { return false }}))
+	// too many tag keys
+	mStoreInterface.SetMaxTagKeysLimit(1)
+	assert.Equal(t, series.ErrTooManyTagKeys,
+		mStoreInterface.Validate(&pb.Metric{Tags: map[string]string{"host": "1.1.1.1", "ip": "1.1.1.1"}},
+			writeContext{}))
+	assert.NoError(t, mStoreInterface.Validate(metric, writeContext{}))
+	// no state changed
+	assert.Equal(t, constants.TStoreMaxFieldsCount-1, mStoreInterface.GetFieldsCount())
+	assert.True(t, mStoreInterface.IsEmpty())
+}
+
 func Test_mStore_write_ok(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -96,31 +211,82 @@ func Test_mStore_write_ok(t *testing.T) {
 		&pb.Metric{Name: "metric", Tags: map[string]string{"type": "test"}}, writeContext{})
 	assert.Nil(t, err)
 	assert.NotZero(t, writtenSize)
+
+	// the deleted metric store rejects writes
+	mStoreInterface.MarkDeleted()
+	writtenSize, err = mStoreInterface.Write(
+		&pb.Metric{Name: "metric", Tags: map[string]string{"type": "test"}}, writeContext{})
+	assert.Equal(t, errMetricStoreDeleted, err)
+	assert.Zero(t, writtenSize)
 }
 
 func Test_mStore_resetVersion(t *testing.T) {
 	mStoreInterface := newMetricStore(100)
 	size1 := mStoreInterface.MemSize()
+	// reset version repeatedly before flushing
+	for i := 0; i < maxImmutableIndexes; i++ {
+		createdSize, err := mStoreInterface.ResetVersion()
+		assert.Nil(t, err)
+		assert.NotZero(t, createdSize)
+	}
+	// immutable list is full
 	createdSize, err := mStoreInterface.ResetVersion()
-	assert.Nil(t, err)
-	assert.NotZero(t, createdSize)
-
-	createdSize, err = mStoreInterface.ResetVersion()
-	assert.NotNil(t, err)
-	assert.Zero(t, createdSize)
-
-	createdSize, err = mStoreInterface.ResetVersion()
-	assert.NotNil(t, err)
+	assert.Equal(t, series.ErrResetVersionUnavailable, err)
 	assert.Zero(t, createdSize)
 	size2 := mStoreInterface.MemSize()
 	assert.NotEqual(t, size1, size2)
 }
 
+func Test_mStore_resetVersion_flushAll(t *testing.T) {
+	mStoreInterface := newMetricStore(100)
+	mStore := mStoreInterface.(*metricStore)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	versions := make(map[series.Version]struct{})
+	for i := 0; i < maxImmutableIndexes; i++ {
+		versions[mStore.mutable.Version()] = struct{}{}
+		_, err := mStoreInterface.ResetVersion()
+		assert.Nil(t, err)
+	}
+	// versions are distinct even if reset in the same millisecond
+	assert.Len(t, versions, maxImmutableIndexes)
+	immutables := mStore.atomicGetImmutables()
+	assert.Len(t, immutables, maxImmutableIndexes)
+	for _, immutable := range immutables {
+		_, ok := versions[immutable.Version()]
+		assert.True(t, ok)
+	}
+	assert.False(t, mStore.IsEmpty())
+
+	// all immutable versions are flushed
+	mockTagIdxes := make([]tagIndexINTF, maxImmutableIndexes)
+	for i := range mockTagIdxes {
+		mockTagIdx := NewMocktagIndexINTF(ctrl)
+		mockTagIdx.EXPECT().FlushVersionDataTo(gomock.Any(), gomock.Any()).Return(10)
+		mockTagIdxes[i] = mockTagIdx
+	}
+	mStore.immutables.Store(mockTagIdxes)
+	flusher := metricsdata.NewMockFlusher(ctrl)
+	flusher.EXPECT().FlushFieldMetas(gomock.Any()).AnyTimes()
+	flusher.EXPECT().FlushMetric(gomock.Any()).Return(nil).AnyTimes()
+	flushedSize, err := mStoreInterface.FlushMetricsDataTo(flusher, flushContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, 10*maxImmutableIndexes, flushedSize)
+	assert.Empty(t, mStore.atomicGetImmutables())
+	assert.True(t, mStore.IsEmpty())
+
+	// reset version is available again after flushing
+	_, err = mStoreInterface.ResetVersion()
+	assert.Nil(t, err)
+}
+
 func Test_mStore_evict(t *testing.T) {
 	mStoreInterface := newMetricStore(100)
 	mStore := mStoreInterface.(*metricStore)
 	// evict on empty
-	mStore.Evict()
+	mStore.Evict(timeutil.Now())
 	assert.True(t, mStore.IsEmpty())
 
 	ctrl := gomock.NewController(t)
@@ -129,16 +295,16 @@ func Test_mStore_evict(t *testing.T) {
 	// mock tStores
 	mockTStore1 := NewMocktStoreINTF(ctrl)
 	mockTStore1.EXPECT().IsNoData().Return(true).AnyTimes()
-	mockTStore1.EXPECT().IsExpired().Return(false).AnyTimes()
+	mockTStore1.EXPECT().IsExpired(gomock.Any()).Return(false).AnyTimes()
 	mockTStore2 := NewMocktStoreINTF(ctrl)
 	mockTStore2.EXPECT().IsNoData().Return(false).AnyTimes()
-	mockTStore2.EXPECT().IsExpired().Return(false).AnyTimes()
+	mockTStore2.EXPECT().IsExpired(gomock.Any()).Return(false).AnyTimes()
 	mockTStore3 := NewMocktStoreINTF(ctrl)
 	mockTStore3.EXPECT().IsNoData().Return(true).AnyTimes()
-	mockTStore3.EXPECT().IsExpired().Return(true).AnyTimes()
+	mockTStore3.EXPECT().IsExpired(gomock.Any()).Return(true).AnyTimes()
 	mockTStore4 := NewMocktStoreINTF(ctrl)
 	mockTStore4.EXPECT().IsNoData().Return(true).AnyTimes()
-	mockTStore4.EXPECT().IsExpired().Return(true).AnyTimes()
+	mockTStore4.EXPECT().IsExpired(gomock.Any()).Return(true).AnyTimes()
 	// mock tagIndex
 	mockTagIdx := NewMocktagIndexINTF(ctrl)
 	metricMap := newMetricMap()
@@ -152,7 +318,50 @@ func Test_mStore_evict(t *testing.T) {
 	mockTagIdx.EXPECT().RemoveTStores(uint32(33)).Return(nil).AnyTimes()
 
 	mStore.mutable = mockTagIdx
-	mStoreInterface.Evict()
+	mStoreInterface.Evict(timeutil.Now())
+}
+
+func Test_mStore_EvictToSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mStoreInterface := newMetricStore(100)
+	mStore := mStoreInterface.(*metricStore)
+	// evict on empty
+	assert.Zero(t, mStoreInterface.EvictToSize(0))
+
+	newMockTStore := func(noData bool, lastWroteTime uint32) tStoreINTF {
+		mockTStore := NewMocktStoreINTF(ctrl)
+		mockTStore.EXPECT().IsNoData().Return(noData).AnyTimes()
+		mockTStore.EXPECT().LastWroteTime().Return(lastWroteTime).AnyTimes()
+		mockTStore.EXPECT().MemSize().Return(100).AnyTimes()
+		return mockTStore
+	}
+	tagIdx := newTagIndex().(*tagIndex)
+	tagIdx.seriesID2TStore.put(11, newMockTStore(true, 30))
+	tagIdx.seriesID2TStore.put(22, newMockTStore(false, 10))
+	tagIdx.seriesID2TStore.put(33, newMockTStore(true, 20))
+	tagIdx.seriesID2TStore.put(44, newMockTStore(true, 10))
+	mStore.mutable = tagIdx
+	mStore.size.Add(400)
+	size := mStoreInterface.MemSize()
+
+	// below the target
+	assert.Zero(t, mStoreInterface.EvictToSize(size+1))
+	// stops once under the target, evicts in least-recently-written order
+	assert.Equal(t, 200, mStoreInterface.EvictToSize(size-150))
+	assert.Equal(t, size-200, mStoreInterface.MemSize())
+	_, ok := tagIdx.GetTStoreBySeriesID(44)
+	assert.False(t, ok)
+	_, ok = tagIdx.GetTStoreBySeriesID(33)
+	assert.False(t, ok)
+	_, ok = tagIdx.GetTStoreBySeriesID(11)
+	assert.True(t, ok)
+	// tStore with data is never evicted
+	assert.Equal(t, 100, mStoreInterface.EvictToSize(0))
+	_, ok = tagIdx.GetTStoreBySeriesID(22)
+	assert.True(t, ok)
+	assert.Zero(t, mStoreInterface.EvictToSize(0))
 }
 
 func Test_mStore_FlushMetricsDataTo_withImmutable(t *testing.T) {
@@ -185,7 +394,7 @@ func Test_mStore_FlushMetricsDataTo_OK(t *testing.T) {
 	mockTagIdx.EXPECT().FlushVersionDataTo(gomock.Any(), gomock.Any()).Return(10).AnyTimes()
 	mStore.mutable = mockTagIdx
 
-	assert.Nil(t, mStore.atomicGetImmutable())
+	assert.Empty(t, mStore.atomicGetImmutables())
 	// mock flush field meta
 	mockTF := metricsdata.NewMockFlusher(ctrl)
 	mockTF.EXPECT().FlushFieldMetas(gomock.Any()).AnyTimes()
@@ -195,7 +404,76 @@ func Test_mStore_FlushMetricsDataTo_OK(t *testing.T) {
 	flushedSize, err := mStoreInterface.FlushMetricsDataTo(mockTF, flushContext{})
 	assert.NotZero(t, flushedSize)
 	assert.Nil(t, err)
-	assert.Nil(t, mStore.atomicGetImmutable())
+	assert.Empty(t, mStore.atomicGetImmutables())
+}
+
+func Test_mStore_DeleteTagValues(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mStoreInterface := newMetricStore(100)
+	mStore := mStoreInterface.(*metricStore)
+	mStore.size.Store(1000)
+	mockTStore := NewMocktStoreINTF(ctrl)
+	mockTStore.EXPECT().MemSize().Return(100).Times(2)
+	mockTagIdx := NewMocktagIndexINTF(ctrl)
+	mockTagIdx.EXPECT().DeleteTagValues("host", gomock.Any()).
+		Return(roaring.BitmapOf(1, 2), []tStoreINTF{mockTStore, mockTStore})
+	mStore.mutable = mockTagIdx
+
+	deletedSeries, removedSize := mStoreInterface.DeleteTagValues("host", regexp.MustCompile("^abc"))
+	assert.Equal(t, 2, deletedSeries)
+	assert.Equal(t, 200, removedSize)
+	assert.Equal(t, int32(800), mStore.size.Load())
+}
+
+func Test_mStore_FlushMetricsDataTo_fieldOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fmList := field.Metas{
+		{ID: 3, Type: field.SumField, Name: "sum"},
+		{ID: 1, Type: field.SumField, Name: "count"},
+		{ID: 4, Type: field.MinField, Name: "min"},
+		{ID: 2, Type: field.MaxField, Name: "max"},
+	}
+	cases := []struct {
+		name  string
+		order option.FieldFlushOrderOption
+		ids   []uint16
+	}{
+		{name: "default", order: option.FieldFlushOrderOption{}, ids: []uint16{3, 1, 4, 2}},
+		{name: "by id", order: option.FieldFlushOrderOption{Order: option.FieldOrderByID}, ids: []uint16{1, 2, 3, 4}},
+		{name: "by affinity", order: option.FieldFlushOrderOption{
+			Order:    option.FieldOrderByAffinity,
+			Affinity: []string{"max", "unknown", "sum", "max"},
+		}, ids: []uint16{2, 3, 1, 4}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			mStoreInterface := newMetricStore(100)
+			mStore := mStoreInterface.(*metricStore)
+			mockTagIdx := NewMocktagIndexINTF(ctrl)
+			mockTagIdx.EXPECT().FlushVersionDataTo(gomock.Any(), gomock.Any()).Return(10)
+			mStore.mutable = mockTagIdx
+			mStore.fieldsMetas.Store(fmList)
+
+			var flushedIDs []uint16
+			mockTF := metricsdata.NewMockFlusher(ctrl)
+			mockTF.EXPECT().FlushFieldMetas(gomock.Any()).Do(func(fieldMetas []field.Meta) {
+				for _, fm := range fieldMetas {
+					flushedIDs = append(flushedIDs, fm.ID)
+				}
+			})
+			mockTF.EXPECT().FlushMetric(uint32(100)).Return(nil)
+
+			_, err := mStoreInterface.FlushMetricsDataTo(mockTF, flushContext{metricID: 100, fieldOrder: tt.order})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.ids, flushedIDs)
+			// field metas of metric store keep unchanged
+			assert.Equal(t, fmList, mStore.fieldsMetas.Load().(field.Metas))
+		})
+	}
 }
 
 func Test_mStore_findSeriesIDsByExpr_getSeriesIDsForTag(t *testing.T) {
@@ -217,7 +495,7 @@ func Test_mStore_findSeriesIDsByExpr_getSeriesIDsForTag(t *testing.T) {
 	returnNil := mockTagIdx.EXPECT().FindSeriesIDsByExpr(gomock.Any()).Return(nil).Times(2)
 	gomock.InOrder(returnNotNil, returnNil)
 	// build mStore
-	mStore.immutable.Store(mockTagIdx)
+	mStore.immutables.Store([]tagIndexINTF{mockTagIdx})
 	mStore.mutable = mockTagIdx
 	// result assert
 	set, err := mStoreInterface.FindSeriesIDsByExpr(nil)
@@ -233,6 +511,34 @@ func Test_mStore_findSeriesIDsByExpr_getSeriesIDsForTag(t *testing.T) {
 	_, _ = mStoreInterface.GetSeriesIDsForTag("")
 }
 
+func Test_mStore_GetSeriesIDsForTag_version(t *testing.T) {
+	mStoreInterface := newMetricStore(100)
+	mStore := mStoreInterface.(*metricStore)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mutable := NewMocktagIndexINTF(ctrl)
+	mutable.EXPECT().Version().Return(series.Version(3)).AnyTimes()
+	mutable.EXPECT().GetSeriesIDsForTag("host").Return(roaring.BitmapOf(5, 6))
+	immutable1 := NewMocktagIndexINTF(ctrl)
+	immutable1.EXPECT().Version().Return(series.Version(1)).AnyTimes()
+	immutable1.EXPECT().GetSeriesIDsForTag("host").Return(roaring.BitmapOf(1, 2))
+	immutable2 := NewMocktagIndexINTF(ctrl)
+	immutable2.EXPECT().Version().Return(series.Version(2)).AnyTimes()
+	immutable2.EXPECT().GetSeriesIDsForTag("host").Return(nil)
+	mStore.mutable = mutable
+	mStore.immutables.Store([]tagIndexINTF{immutable1, immutable2})
+
+	// each bitmap is labeled with the version of tag index which it comes from
+	set, err := mStoreInterface.GetSeriesIDsForTag("host")
+	assert.NoError(t, err)
+	versions := set.Versions()
+	assert.Len(t, versions, 2)
+	assert.Equal(t, []uint32{5, 6}, versions[series.Version(3)].ToArray())
+	assert.Equal(t, []uint32{1, 2}, versions[series.Version(1)].ToArray())
+}
+
 func Test_getFieldIDOrGenerate(t *testing.T) {
 	mStoreInterface := newMetricStore(100)
 	mStore := mStoreInterface.(*metricStore)
@@ -364,7 +670,7 @@ func Test_mStore_flushInvertedIndexTo(t *testing.T) {
 	//////////////////////////////////////////////
 	// neither mutable nor immutable part is empty
 	//////////////////////////////////////////////
-	mStore.immutable.Store(mockTagIdx1)
+	mStore.immutables.Store([]tagIndexINTF{mockTagIdx1})
 	mStore.mutable = mockTagIdx3
 	// flush error
 	mockTableFlusher.EXPECT().FlushTagKeyID(gomock.Any()).Return(fmt.Errorf("error")).Times(1)
@@ -396,7 +702,7 @@ func Test_mStore_flushForwardIndexTo(t *testing.T) {
 	//////////////////////////////////////////////
 	// neither mutable nor immutable part is empty
 	//////////////////////////////////////////////
-	mStore.immutable.Store(mockTagIdx2)
+	mStore.immutables.Store([]tagIndexINTF{mockTagIdx2})
 	mStore.mutable = mockTagIdx3
 	assert.Nil(t, mStoreInterface.FlushForwardIndexTo(mockTableFlusher))
 }
@@ -412,7 +718,7 @@ func Test_mStore_getTagValues(t *testing.T) {
 	// immutable part empty
 	//////////////////////////////////////////////
 	mStore.mutable = mockTagIdx3
-	// host not exist
+	// host not exist in metric store
 	mappings, err := mStoreInterface.GetTagValues(
 		[]string{"host", "zone", "usage"}, 3, roaring.BitmapOf(3, 4, 5, 6, 11))
 	assert.NotNil(t, err)
@@ -431,7 +737,7 @@ func Test_mStore_getTagValues(t *testing.T) {
 	//////////////////////////////////////////////
 	// immutable part not empty
 	//////////////////////////////////////////////
-	mStore.immutable.Store(mockTagIdx2)
+	mStore.immutables.Store([]tagIndexINTF{mockTagIdx2})
 	mStore.mutable = mockTagIdx3
 	// version not match
 	_, err = mStoreInterface.GetTagValues([]string{"ip"}, 4, roaring.BitmapOf(1, 2, 3))
@@ -439,6 +745,53 @@ func Test_mStore_getTagValues(t *testing.T) {
 	// version match, ip not exist
 	_, err = mStoreInterface.GetTagValues([]string{"ip"}, 1, roaring.BitmapOf(1, 2, 3))
 	assert.NotNil(t, err)
+	// ip exists in immutable part, tag value of the version without ip is empty
+	mappings, err = mStoreInterface.GetTagValues([]string{"ip", "usage"}, 3, roaring.BitmapOf(3))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"", "idle"}, mappings[3])
+}
+
+func Test_mStore_getTagValuesByVersions(t *testing.T) {
+	mStoreInterface := newMetricStore(100)
+	mStore := mStoreInterface.(*metricStore)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	_, mockTagIdx2, mockTagIdx3 := prepareMockTagIndexes(ctrl)
+	mStore.immutables.Store([]tagIndexINTF{mockTagIdx2})
+	mStore.mutable = mockTagIdx3
+
+	// spanning immutable(version 2) and mutable(version 3), version 4 not found is skipped
+	seriesIDs := series.NewMultiVerSeriesIDSet()
+	seriesIDs.Add(2, roaring.BitmapOf(1, 6))
+	seriesIDs.Add(3, roaring.BitmapOf(6, 8))
+	seriesIDs.Add(4, roaring.BitmapOf(9))
+	mappings, err := mStoreInterface.GetTagValuesByVersions([]string{"zone", "ip", "usage"}, seriesIDs)
+	assert.Nil(t, err)
+	assert.Equal(t, map[series.Version]map[uint32][]string{
+		// same series id of different versions keeps the tag values of each version
+		2: {1: {"sh", "1.1.1.1", ""}, 6: {"bj", "2.2.2.2", ""}},
+		3: {6: {"nt", "", "system"}, 8: {"nt", "", "idle"}},
+	}, mappings)
+
+	// only immutable
+	seriesIDs = series.NewMultiVerSeriesIDSet()
+	seriesIDs.Add(2, roaring.BitmapOf(7))
+	mappings, err = mStoreInterface.GetTagValuesByVersions([]string{"ip"}, seriesIDs)
+	assert.Nil(t, err)
+	assert.Equal(t, map[series.Version]map[uint32][]string{2: {7: {"2.2.2.2"}}}, mappings)
+
+	// none of versions found
+	seriesIDs = series.NewMultiVerSeriesIDSet()
+	seriesIDs.Add(4, roaring.BitmapOf(1))
+	_, err = mStoreInterface.GetTagValuesByVersions([]string{"ip"}, seriesIDs)
+	assert.Equal(t, series.ErrNotFound, err)
+	_, err = mStoreInterface.GetTagValuesByVersions([]string{"ip"}, series.NewMultiVerSeriesIDSet())
+	assert.Equal(t, series.ErrNotFound, err)
+	// host not exist in metric store
+	seriesIDs = series.NewMultiVerSeriesIDSet()
+	seriesIDs.Add(2, roaring.BitmapOf(1))
+	_, err = mStoreInterface.GetTagValuesByVersions([]string{"host"}, seriesIDs)
+	assert.NotNil(t, err)
 }
 
 func Test_mStore_suggest(t *testing.T) {
@@ -452,7 +805,7 @@ func Test_mStore_suggest(t *testing.T) {
 	assert.Nil(t, mStoreInterface.SuggestTagValues("", "", 0))
 	assert.Nil(t, mStoreInterface.SuggestTagKeys("", 0))
 
-	mStore.immutable.Store(mockTagIdx1)
+	mStore.immutables.Store([]tagIndexINTF{mockTagIdx1})
 	mStore.mutable = mockTagIdx3
 
 	assert.Len(t, mStoreInterface.SuggestTagKeys("host", 1), 1)
@@ -460,3 +813,294 @@ func Test_mStore_suggest(t *testing.T) {
 	assert.Len(t, mStoreInterface.SuggestTagValues("host", "a", 1), 1)
 	assert.Len(t, mStoreInterface.SuggestTagValues("host", "a", 100000), 1)
 }
+
+func Test_mStore_suggest_sorted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mStoreInterface := newMetricStore(100)
+	mStore := mStoreInterface.(*metricStore)
+
+	newEntrySet := func(key string, values ...string) *tagKVEntrySet {
+		entrySet := &tagKVEntrySet{key: key, values: make(map[string]*roaring.Bitmap)}
+		for idx, value := range values {
+			entrySet.values[value] = roaring.BitmapOf(uint32(idx))
+		}
+		return entrySet
+	}
+	newTagIndex := func(entrySets ...*tagKVEntrySet) tagIndexINTF {
+		tagIndex := NewMocktagIndexINTF(ctrl)
+		tagIndex.EXPECT().GetTagKVEntrySets().Return(entrySets).AnyTimes()
+		tagIndex.EXPECT().GetTagKVEntrySet(gomock.Any()).DoAndReturn(func(tagKey string) (*tagKVEntrySet, bool) {
+			for _, entrySet := range entrySets {
+				if entrySet.key == tagKey {
+					return entrySet, true
+				}
+			}
+			return nil, false
+		}).AnyTimes()
+		return tagIndex
+	}
+	immutable := newTagIndex(
+		newEntrySet("host", "host-9", "host-3", "host-7", "host-1"),
+		newEntrySet("zone", "sh"),
+	)
+	mutable := newTagIndex(
+		newEntrySet("ip", "1.1.1.1"),
+		newEntrySet("host", "host-8", "host-3", "host-2", "host-5"),
+		newEntrySet("zone", "bj"),
+		newEntrySet("alias", "host-0", "host-4"),
+	)
+	mStore.immutables.Store([]tagIndexINTF{immutable})
+	mStore.mutable = mutable
+
+	// sorted and deduped across versions
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, []string{"host-1", "host-2", "host-3", "host-5", "host-7", "host-8", "host-9"},
+			mStoreInterface.SuggestTagValues("host", "host", 100))
+		assert.Equal(t, []string{"alias", "host", "ip", "zone"}, mStoreInterface.SuggestTagKeys("", 100))
+	}
+	// only the tag values of the tag key
+	assert.Equal(t, []string{"host-0", "host-4"}, mStoreInterface.SuggestTagValues("alias", "host", 100))
+	assert.Equal(t, []string{"bj", "sh"}, mStoreInterface.SuggestTagValues("zone", "", 100))
+	assert.Nil(t, mStoreInterface.SuggestTagValues("usage", "", 100))
+	// smallest values are returned when truncated by limit
+	assert.Equal(t, []string{"host-1", "host-2", "host-3"}, mStoreInterface.SuggestTagValues("host", "host", 3))
+	assert.Equal(t, []string{"alias", "host"}, mStoreInterface.SuggestTagKeys("", 2))
+	assert.Equal(t, []string{"zone"}, mStoreInterface.SuggestTagKeys("z", 2))
+	// no match
+	assert.Nil(t, mStoreInterface.SuggestTagValues("host", "web", 3))
+	assert.Nil(t, mStoreInterface.SuggestTagKeys("web", 3))
+}
+
+func Test_mStore_GetSeriesIDsForMetric(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockGenerator := metadb.NewMockIDGenerator(ctrl)
+	mockGenerator.EXPECT().GenTagKeyID(gomock.Any(), gomock.Any()).Return(uint32(1)).AnyTimes()
+
+	mStoreInterface := newMetricStore(100)
+	mStore := mStoreInterface.(*metricStore)
+	createSeries := func(tags map[string]string) {
+		_, _, err := mStore.mutable.GetOrCreateTStore(tags, writeContext{generator: mockGenerator})
+		assert.Nil(t, err)
+	}
+	// empty
+	set, err := mStoreInterface.GetSeriesIDsForMetric()
+	assert.NoError(t, err)
+	assert.True(t, set.IsEmpty())
+
+	createSeries(map[string]string{"host": "a", "env": "prod"})
+	createSeries(map[string]string{"host": "b", "env": "test"})
+	createSeries(map[string]string{"host": "c"})
+	oldVersion := mStore.mutable.Version()
+	_, err = mStoreInterface.ResetVersion()
+	assert.Nil(t, err)
+	createSeries(map[string]string{"host": "d", "env": "prod"})
+	createSeries(map[string]string{"host": "e"})
+	newVersion := mStore.mutable.Version()
+
+	set, err = mStoreInterface.GetSeriesIDsForMetric()
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{1, 2, 3}, set.Versions()[oldVersion].ToArray())
+	assert.Equal(t, []uint32{1, 2}, set.Versions()[newVersion].ToArray())
+	// env != 'prod' includes the series without tag key env
+	prodSet, _ := mStoreInterface.FindSeriesIDsByExpr(&stmt.EqualsExpr{Key: "env", Value: "prod"})
+	set.AndNot(prodSet)
+	assert.Equal(t, []uint32{2, 3}, set.Versions()[oldVersion].ToArray())
+	assert.Equal(t, []uint32{2}, set.Versions()[newVersion].ToArray())
+}
+
+func Test_mStore_TopTagValues(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockGenerator := metadb.NewMockIDGenerator(ctrl)
+	mockGenerator.EXPECT().GenTagKeyID(gomock.Any(), gomock.Any()).Return(uint32(1)).AnyTimes()
+
+	mStoreInterface := newMetricStore(100)
+	mStore := mStoreInterface.(*metricStore)
+	createSeries := func(host, disk string) {
+		_, _, err := mStore.mutable.GetOrCreateTStore(
+			map[string]string{"host": host, "disk": disk}, writeContext{generator: mockGenerator})
+		assert.Nil(t, err)
+	}
+	createSeries("a", "sda")
+	createSeries("b", "sda")
+	createSeries("b", "sdb")
+	createSeries("c", "sda")
+	// series of immutable index are counted
+	_, err := mStoreInterface.ResetVersion()
+	assert.Nil(t, err)
+	createSeries("c", "sdb")
+	createSeries("c", "sdc")
+	createSeries("d", "sda")
+	// series existing in both versions are counted once
+	createSeries("a", "sda")
+	createSeries("b", "sdb")
+
+	// invalid k
+	assert.Nil(t, mStoreInterface.TopTagValues("host", 0))
+	// tag key not exist
+	assert.Empty(t, mStoreInterface.TopTagValues("zone", 10))
+	// ordered by count of series, then tag value
+	assert.Equal(t, []series.TagValueCount{
+		{TagValue: "c", SeriesCount: 3},
+		{TagValue: "b", SeriesCount: 2},
+		{TagValue: "a", SeriesCount: 1},
+		{TagValue: "d", SeriesCount: 1},
+	}, mStoreInterface.TopTagValues("host", 10))
+	// top k
+	assert.Equal(t, []series.TagValueCount{
+		{TagValue: "sda", SeriesCount: 4},
+		{TagValue: "sdb", SeriesCount: 2},
+	}, mStoreInterface.TopTagValues("disk", 2))
+}
+
+func Test_mStore_findSeriesIDsByExpr_combined(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockGenerator := metadb.NewMockIDGenerator(ctrl)
+	mockGenerator.EXPECT().GenTagKeyID(gomock.Any(), gomock.Any()).Return(uint32(1)).AnyTimes()
+
+	mStoreInterface := newMetricStore(100)
+	mStore := mStoreInterface.(*metricStore)
+	createSeries := func(host, region string) {
+		_, _, err := mStore.mutable.GetOrCreateTStore(
+			map[string]string{"host": host, "region": region}, writeContext{generator: mockGenerator})
+		assert.Nil(t, err)
+	}
+	createSeries("a", "us-east")
+	createSeries("a", "eu-west")
+	createSeries("b", "us-west")
+	createSeries("b", "us-east")
+	createSeries("a", "us-west")
+	version := mStore.mutable.Version()
+
+	hostSet, _ := mStoreInterface.FindSeriesIDsByExpr(&stmt.EqualsExpr{Key: "host", Value: "a"})
+	regionSet, _ := mStoreInterface.FindSeriesIDsByExpr(&stmt.RegexExpr{Key: "region", Regexp: "us-.*"})
+	allRegionSet, _ := mStoreInterface.GetSeriesIDsForTag("region")
+	assert.Equal(t, []uint32{1, 2, 3, 4, 5}, allRegionSet.Versions()[version].ToArray())
+	// host='a' and not region=~'us-.*'
+	allRegionSet.AndNot(regionSet)
+	hostSet.And(allRegionSet)
+	assert.Equal(t, []uint32{2}, hostSet.Versions()[version].ToArray())
+}
+
+func Test_mStore_findSeriesIDsByRegex(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockGenerator := metadb.NewMockIDGenerator(ctrl)
+	mockGenerator.EXPECT().GenTagKeyID(gomock.Any(), gomock.Any()).Return(uint32(1)).AnyTimes()
+
+	mStoreInterface := newMetricStore(100)
+	mStore := mStoreInterface.(*metricStore)
+	createSeries := func(host string) {
+		_, _, err := mStore.mutable.GetOrCreateTStore(
+			map[string]string{"host": host}, writeContext{generator: mockGenerator})
+		assert.Nil(t, err)
+	}
+	createSeries("web-1")
+	createSeries("db-1")
+	immutableVersion := mStore.mutable.Version()
+	_, err := mStoreInterface.ResetVersion()
+	assert.Nil(t, err)
+	createSeries("web-2")
+	createSeries("app-web")
+	mutableVersion := mStore.mutable.Version()
+
+	// anchored
+	set, err := mStoreInterface.FindSeriesIDsByExpr(&stmt.RegexExpr{Key: "host", Regexp: "^web-.*"})
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1}, set.Versions()[immutableVersion].ToArray())
+	assert.Equal(t, []uint32{1}, set.Versions()[mutableVersion].ToArray())
+	// unanchored, the tag value must start with the literal prefix of pattern
+	set, err = mStoreInterface.FindSeriesIDsByExpr(&stmt.RegexExpr{Key: "host", Regexp: "web-[0-9]"})
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1}, set.Versions()[immutableVersion].ToArray())
+	assert.Equal(t, []uint32{1}, set.Versions()[mutableVersion].ToArray())
+	set, err = mStoreInterface.FindSeriesIDsByExpr(&stmt.RegexExpr{Key: "host", Regexp: ".*web"})
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1}, set.Versions()[immutableVersion].ToArray())
+	assert.Equal(t, []uint32{1, 2}, set.Versions()[mutableVersion].ToArray())
+	// no match
+	set, err = mStoreInterface.FindSeriesIDsByExpr(&stmt.RegexExpr{Key: "host", Regexp: "^cache"})
+	assert.Nil(t, err)
+	assert.True(t, set.IsEmpty())
+	set, err = mStoreInterface.FindSeriesIDsByExpr(&stmt.RegexExpr{Key: "zone", Regexp: "web"})
+	assert.Nil(t, err)
+	assert.True(t, set.IsEmpty())
+	// invalid regex
+	set, err = mStoreInterface.FindSeriesIDsByExpr(&stmt.RegexExpr{Key: "host", Regexp: "web-(1"})
+	assert.Error(t, err)
+	assert.Nil(t, set)
+}
+
+func Test_mStore_FindStaleSeriesIDs(t *testing.T) {
+	mStoreInterface := newMetricStore(100)
+	mStore := mStoreInterface.(*metricStore)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// empty
+	stale, inMemory := mStoreInterface.FindStaleSeriesIDs(10*1000, timeutil.Now())
+	assert.Empty(t, stale.Versions())
+	assert.Empty(t, inMemory.Versions())
+
+	threshold := timeutil.Now() - timeutil.OneMinute*5
+	mockTStore := func(stale bool) tStoreINTF {
+		tStore := NewMocktStoreINTF(ctrl)
+		tStore.EXPECT().IsStale(int64(10*1000), threshold).Return(stale).AnyTimes()
+		return tStore
+	}
+	mutableMap := newMetricMap()
+	mutableMap.put(1, mockTStore(true))
+	mutableMap.put(2, mockTStore(false))
+	mutableMap.put(3, mockTStore(true))
+	mockMutable := NewMocktagIndexINTF(ctrl)
+	mockMutable.EXPECT().Version().Return(series.Version(2)).AnyTimes()
+	mockMutable.EXPECT().AllTStores().Return(mutableMap).AnyTimes()
+	mStore.mutable = mockMutable
+
+	immutableMap := newMetricMap()
+	immutableMap.put(1, mockTStore(false))
+	mockImmutable1 := NewMocktagIndexINTF(ctrl)
+	mockImmutable1.EXPECT().Version().Return(series.Version(3)).AnyTimes()
+	mockImmutable1.EXPECT().AllTStores().Return(immutableMap).AnyTimes()
+	immutableMap2 := newMetricMap()
+	immutableMap2.put(5, mockTStore(true))
+	mockImmutable2 := NewMocktagIndexINTF(ctrl)
+	mockImmutable2.EXPECT().Version().Return(series.Version(1)).AnyTimes()
+	mockImmutable2.EXPECT().AllTStores().Return(immutableMap2).AnyTimes()
+	mStore.immutables.Store([]tagIndexINTF{mockImmutable1, mockImmutable2})
+
+	stale, inMemory = mStoreInterface.FindStaleSeriesIDs(10*1000, threshold)
+	versions := stale.Versions()
+	assert.Len(t, versions, 2)
+	assert.Equal(t, []uint32{1, 3}, versions[series.Version(2)].ToArray())
+	assert.Equal(t, []uint32{5}, versions[series.Version(1)].ToArray())
+	// all the series in memory, the staleness of which is known
+	versions = inMemory.Versions()
+	assert.Len(t, versions, 3)
+	assert.Equal(t, []uint32{1, 2, 3}, versions[series.Version(2)].ToArray())
+	assert.Equal(t, []uint32{1}, versions[series.Version(3)].ToArray())
+	assert.Equal(t, []uint32{5}, versions[series.Version(1)].ToArray())
+}
+
+func Benchmark_mStore_GetTagValues_5kTagValues_5kSeries(b *testing.B) {
+	const count = 5000
+	entrySet := newTagKVEntrySet("host")
+	seriesIDs := roaring.New()
+	for i := 1; i <= count; i++ {
+		entrySet.values[fmt.Sprintf("host-%d", i)] = roaring.BitmapOf(uint32(i))
+		seriesIDs.Add(uint32(i))
+	}
+	tagIdx := newTagIndex().(*tagIndex)
+	tagIdx.tagKVEntrySet = []*tagKVEntrySet{entrySet}
+	mStore := newMetricStore(100).(*metricStore)
+	mStore.mutable = tagIdx
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = mStore.GetTagValues([]string{"host"}, tagIdx.Version(), seriesIDs)
+	}
+}
@@ -0,0 +1,124 @@
+package memdb
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lindb/lindb/pkg/encoding"
+	"github.com/lindb/lindb/pkg/sketch"
+	"github.com/lindb/lindb/pkg/stream"
+	"github.com/lindb/lindb/series/field"
+)
+
+const (
+	emptyQuantileFieldStoreSize = 8 + // familyTime
+		8 // sketches map pointer
+	// sketchSize is the estimated memory of an empty sketch in map of quantile field store
+	sketchSize = 8 + // slot
+		8 + // sketch pointer
+		96 // sketch
+	// sketchBucketSize is the estimated memory of a bucket in sketch
+	sketchBucketSize = 16
+)
+
+// quantileFieldStore stores the sketches of quantile field by time slot,
+// the written values of same time slot are added into the sketch of the slot.
+type quantileFieldStore struct {
+	familyTime int64
+	sketches   map[int]*sketch.DDSketch // key: time slot
+}
+
+// newQuantileFieldStore returns a new segment store for quantile field
+func newQuantileFieldStore(familyTime int64) sStoreINTF {
+	return &quantileFieldStore{
+		familyTime: familyTime,
+		sketches:   make(map[int]*sketch.DDSketch),
+	}
+}
+
+func (fs *quantileFieldStore) GetFamilyTime() int64 {
+	return fs.familyTime
+}
+
+// AggType returns sum, because sketches are merged by adding the counts of buckets
+func (fs *quantileFieldStore) AggType() field.AggType {
+	return field.Sum
+}
+
+// WriteFloat adds the value into the sketch of time slot
+func (fs *quantileFieldStore) WriteFloat(value float64, writeCtx writeContext) int {
+	oldSize := fs.MemSize()
+	s, ok := fs.sketches[writeCtx.slotIndex]
+	if !ok {
+		s = sketch.NewDDSketch(sketch.DefaultRelativeAccuracy)
+		fs.sketches[writeCtx.slotIndex] = s
+	}
+	s.Add(value)
+	return fs.MemSize() - oldSize
+}
+
+// WriteInt adds the value as float into the sketch of time slot
+func (fs *quantileFieldStore) WriteInt(value int64, writeCtx writeContext) int {
+	return fs.WriteFloat(float64(value), writeCtx)
+}
+
+// Bytes serializes the sketches of time slots,
+// format: start slot, end slot, sketch count, [slot, sketch length, sketch data] in order of slot
+func (fs *quantileFieldStore) Bytes(needSlotRange bool) (data []byte, startSlot, endSlot int, err error) {
+	slots := fs.sortedSlots()
+	if len(slots) == 0 {
+		err = fmt.Errorf("sketches are empty")
+		return
+	}
+	startSlot, endSlot = slots[0], slots[len(slots)-1]
+	writer := stream.NewBufferWriter(nil)
+	writer.PutUvarint32(uint32(startSlot))
+	writer.PutUvarint32(uint32(endSlot))
+	writer.PutUvarint32(uint32(len(slots)))
+	for _, slot := range slots {
+		sketchData, marshalErr := fs.sketches[slot].MarshalBinary()
+		if marshalErr != nil {
+			err = fmt.Errorf("marshal sketch in quantile field store error:%s", marshalErr)
+			return
+		}
+		writer.PutUvarint32(uint32(slot))
+		writer.PutUvarint32(uint32(len(sketchData)))
+		writer.PutBytes(sketchData)
+	}
+	data, err = writer.Bytes()
+	return
+}
+
+func (fs *quantileFieldStore) SlotRange() (startSlot, endSlot int, err error) {
+	slots := fs.sortedSlots()
+	if len(slots) == 0 {
+		err = fmt.Errorf("sketches are empty")
+		return
+	}
+	return slots[0], slots[len(slots)-1], nil
+}
+
+// forEachSlot calls fn with the time slot which has value
+func (fs *quantileFieldStore) forEachSlot(tsd *encoding.TSDDecoder, fn func(slot int)) {
+	for _, slot := range fs.sortedSlots() {
+		fn(slot)
+	}
+}
+
+func (fs *quantileFieldStore) MemSize() int {
+	size := emptyQuantileFieldStoreSize
+	for _, s := range fs.sketches {
+		size += sketchSize + s.BucketCount()*sketchBucketSize
+	}
+	return size
+}
+
+// sortedSlots returns the time slots which have sketch in ascending order
+func (fs *quantileFieldStore) sortedSlots() []int {
+	slots := make([]int, 0, len(fs.sketches))
+	for slot := range fs.sketches {
+		slots = append(slots, slot)
+	}
+	sort.Ints(slots)
+	return slots
+}
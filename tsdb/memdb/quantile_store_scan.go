@@ -0,0 +1,27 @@
+package memdb
+
+import "github.com/lindb/lindb/aggregation"
+
+// scan merges the sketches of quantile field into the sketch aggregator based on query time range
+func (fs *quantileFieldStore) scan(agg aggregation.SeriesAggregator, memScanCtx *memScanContext) {
+	// check family time is in query time range
+	segmentAgg, ok := agg.GetAggregator(fs.familyTime)
+	if !ok {
+		return
+	}
+	sketchAgg, ok := segmentAgg.(aggregation.SketchAggregator)
+	if !ok {
+		return
+	}
+	if memScanCtx.singleSlot {
+		if s, ok := fs.sketches[memScanCtx.slot]; ok {
+			sketchAgg.AggregateSketch(memScanCtx.slot, s)
+		}
+		return
+	}
+	for _, slot := range fs.sortedSlots() {
+		if completed := sketchAgg.AggregateSketch(slot, fs.sketches[slot]); completed {
+			return
+		}
+	}
+}
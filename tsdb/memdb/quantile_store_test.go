@@ -0,0 +1,149 @@
+package memdb
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/aggregation"
+	"github.com/lindb/lindb/pkg/sketch"
+	"github.com/lindb/lindb/pkg/stream"
+	"github.com/lindb/lindb/pkg/timeutil"
+	pb "github.com/lindb/lindb/rpc/proto/field"
+	"github.com/lindb/lindb/series/field"
+)
+
+// assertRelativeError asserts the actual value within the relative accuracy of sketch
+func assertRelativeError(t *testing.T, expect, actual float64) {
+	assert.True(t, math.Abs(actual-expect) <= sketch.DefaultRelativeAccuracy*math.Abs(expect),
+		"expect:%f, actual:%f", expect, actual)
+}
+
+func TestQuantileFieldStore(t *testing.T) {
+	store := newQuantileFieldStore(10)
+	assert.Equal(t, int64(10), store.GetFamilyTime())
+	assert.Equal(t, field.Sum, store.AggType())
+	assert.Equal(t, emptyQuantileFieldStoreSize, store.MemSize())
+
+	_, _, err := store.SlotRange()
+	assert.Error(t, err)
+	_, _, _, err = store.Bytes(true)
+	assert.Error(t, err)
+
+	writeCtx := writeContext{slotIndex: 20}
+	assert.True(t, store.WriteFloat(1.5, writeCtx) > 0)
+	writeCtx.slotIndex = 5
+	assert.True(t, store.WriteInt(10, writeCtx) > 0)
+	// same bucket of sketch, no memory grows
+	assert.Zero(t, store.WriteInt(10, writeCtx))
+
+	startSlot, endSlot, err := store.SlotRange()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, startSlot)
+	assert.Equal(t, 20, endSlot)
+
+	var slots []int
+	store.forEachSlot(nil, func(slot int) {
+		slots = append(slots, slot)
+	})
+	assert.Equal(t, []int{5, 20}, slots)
+
+	data, startSlot, endSlot, err := store.Bytes(true)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, startSlot)
+	assert.Equal(t, 20, endSlot)
+	// decode the flushed sketches
+	reader := stream.NewReader(data)
+	assert.Equal(t, uint32(5), reader.ReadUvarint32())
+	assert.Equal(t, uint32(20), reader.ReadUvarint32())
+	assert.Equal(t, uint32(2), reader.ReadUvarint32())
+	counts := map[uint32]uint64{}
+	for i := 0; i < 2; i++ {
+		slot := reader.ReadUvarint32()
+		s := &sketch.DDSketch{}
+		assert.NoError(t, s.UnmarshalBinary(reader.ReadBytes(int(reader.ReadUvarint32()))))
+		counts[slot] = s.Count()
+	}
+	assert.True(t, reader.Empty())
+	assert.Equal(t, map[uint32]uint64{5: 2, 20: 1}, counts)
+}
+
+func TestQuantileFieldStore_Scan(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	familyTime, _ := timeutil.ParseTimestamp("20190702 19:00:00", "20060102 15:04:05")
+	fStore := newFieldStore(10)
+	writeCtx := writeContext{familyTime: familyTime}
+
+	// write a known distribution into two series, values 1~1000 are split by odd/even
+	fStore2 := newFieldStore(10)
+	r := rand.New(rand.NewSource(1))
+	for _, value := range r.Perm(1000) {
+		writeCtx.slotIndex = 20 + value%2
+		distribution := &pb.Field{Name: "latency", Field: &pb.Field_Distribution{
+			Distribution: &pb.Distribution{Values: []float64{float64(value + 1)}}}}
+		if value%3 == 0 {
+			assert.True(t, fStore.Write(distribution, writeCtx) >= 0)
+		} else {
+			assert.True(t, fStore2.Write(distribution, writeCtx) >= 0)
+		}
+	}
+	assert.Equal(t, field.QuantileField, getFieldType(&pb.Field{Field: &pb.Field_Distribution{}}))
+
+	newAgg := func(ratio int) aggregation.SeriesAggregator {
+		aggSpec := aggregation.NewAggregatorSpec("latency", field.QuantileField)
+		return aggregation.NewSeriesAggregator(
+			timeutil.Interval(10*timeutil.OneSecond*int64(ratio)),
+			ratio,
+			timeutil.TimeRange{Start: familyTime, End: familyTime + 10*timeutil.OneMinute},
+			false,
+			aggSpec,
+		)
+	}
+	// down sampling two slots into one, merge the sketches across series
+	agg1, agg2 := newAgg(2), newAgg(2)
+	fStore.scan(agg1, &memScanContext{})
+	fStore2.scan(agg2, &memScanContext{})
+	fAgg1, ok := agg1.GetAggregator(familyTime)
+	assert.True(t, ok)
+	fAgg2, _ := agg2.GetAggregator(familyTime)
+	sketchAgg := fAgg1.(aggregation.SketchAggregator)
+	sketchAgg.Merge(fAgg2.(aggregation.SketchAggregator))
+
+	s := sketchAgg.Sketches()[10]
+	assert.Equal(t, uint64(1000), s.Count())
+	p50, _ := s.Quantile(0.5)
+	assertRelativeError(t, 500, p50)
+	p95, _ := s.Quantile(0.95)
+	assertRelativeError(t, 950, p95)
+
+	// result set of quantiles
+	_, it := sketchAgg.ResultSet()
+	assert.True(t, it.HasNext())
+	primitiveIt := it.Next()
+	assert.Equal(t, uint16(1), primitiveIt.FieldID())
+	assert.True(t, primitiveIt.HasNext())
+	slot, value := primitiveIt.Next()
+	assert.Equal(t, 10, slot)
+	assertRelativeError(t, 500, value)
+
+	// single slot, only scans the sketch of the slot
+	agg := newAgg(1)
+	fStore2.scan(agg, &memScanContext{singleSlot: true, familyTime: familyTime, slot: 21})
+	fAgg, _ := agg.GetAggregator(familyTime)
+	sketches := fAgg.(aggregation.SketchAggregator).Sketches()
+	assert.Nil(t, sketches[20])
+	assert.NotNil(t, sketches[21])
+
+	// not sketch aggregator
+	mockAgg := aggregation.NewMockSeriesAggregator(ctrl)
+	mockAgg.EXPECT().GetAggregator(familyTime).Return(aggregation.NewMockFieldAggregator(ctrl), true)
+	fStore.scan(mockAgg, &memScanContext{})
+	// family time not in query range
+	mockAgg.EXPECT().GetAggregator(familyTime).Return(nil, false)
+	fStore.scan(mockAgg, &memScanContext{})
+}
@@ -52,6 +52,9 @@ type sStoreINTF interface {
 
 	// scan scans segment store data based on query time range
 	scan(agg aggregation.SeriesAggregator, memScanCtx *memScanContext)
+
+	// forEachSlot calls fn with the time slot which has value
+	forEachSlot(tsd *encoding.TSDDecoder, fn func(slot int))
 }
 
 // singleFieldStore stores single field
@@ -108,6 +111,7 @@ func (fs *simpleFieldStore) WriteInt(value int64, writeCtx writeContext) int {
 // 1) block is nil, create new block, return 0, false
 // 2) slot time out of current time window, need compress time window then create new one, return 0, false
 // 3) in current time window, if has old value return pos, true, else return pos, false
+// The slot values of new time window are stored in dense array or sparse map based on the observed density.
 func (fs *simpleFieldStore) calcTimeWindow(blockStore *blockStore, slotTime int,
 	valueType field.ValueType) (int, bool) {
 	currentBlock := fs.block
@@ -124,10 +128,14 @@ func (fs *simpleFieldStore) calcTimeWindow(blockStore *blockStore, slotTime int,
 
 	// if current slot time out of current time window, need compress block data, start new time window
 	if slotTime < startTime || slotTime >= startTime+blockStore.timeWindow {
+		// observe the slot density of current time window before compress
+		slotCount := currentBlock.slotCount()
 		_, _, err := currentBlock.compact(fs.aggFunc)
 		if err != nil {
 			memDBLogger.Error("compress block data error, data will lost", logger.Error(err))
 		} else {
+			// choose dense array or sparse map for the new time window
+			currentBlock.setSparse(blockStore.useSparse(slotCount))
 			// reset start time using slot time
 			currentBlock.setStartTime(slotTime)
 		}
@@ -166,6 +174,14 @@ func (fs *simpleFieldStore) SlotRange() (startSlot, endSlot int, err error) {
 	return
 }
 
+// forEachSlot calls fn with the time slot which has value
+func (fs *simpleFieldStore) forEachSlot(tsd *encoding.TSDDecoder, fn func(slot int)) {
+	if fs.block == nil {
+		return
+	}
+	fs.block.forEachSlot(tsd, fn)
+}
+
 func (fs *simpleFieldStore) MemSize() int {
 	if fs.block == nil {
 		return emptySimpleFieldStoreSize
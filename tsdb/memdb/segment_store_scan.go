@@ -10,5 +10,9 @@ func (fs *simpleFieldStore) scan(agg aggregation.SeriesAggregator, memScanCtx *m
 		return
 	}
 	aggregators := segmentAgg.GetAllAggregators()
+	if memScanCtx.singleSlot {
+		fs.block.scanSlot(memScanCtx.slot, fs.aggFunc, aggregators, memScanCtx)
+		return
+	}
 	fs.block.scan(fs.aggFunc, aggregators, memScanCtx)
 }
@@ -2,6 +2,7 @@ package memdb
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/lindb/lindb/pkg/encoding"
@@ -115,6 +116,7 @@ func Test_sStore_error(t *testing.T) {
 	mockBlock.EXPECT().getStartTime().Return(12).AnyTimes()
 	mockBlock.EXPECT().getEndTime().Return(40).AnyTimes()
 	mockBlock.EXPECT().memsize().Return(300).AnyTimes()
+	mockBlock.EXPECT().slotCount().Return(1).AnyTimes()
 	ss.block = mockBlock
 	_, _, _, err := ss.Bytes(false)
 	assert.NotNil(t, err)
@@ -165,3 +167,99 @@ func BenchmarkSimpleSegmentStore(b *testing.B) {
 
 	_, _, _, _ = store.Bytes(true)
 }
+
+func Test_sStore_SlotStrategy(t *testing.T) {
+	store := newSimpleFieldStore(0, field.Sum.AggFunc())
+	ss, _ := store.(*simpleFieldStore)
+	writeCtx := writeContext{blockStore: newBlockStore(64)}
+
+	// sparse time window[1,64]: 2 points
+	writeCtx.slotIndex = 1
+	ss.WriteFloat(1, writeCtx)
+	writeCtx.slotIndex = 50
+	ss.WriteFloat(50, writeCtx)
+	assert.False(t, ss.block.isSparse())
+	// switch to sparse map for next time window[65,128]
+	writeCtx.slotIndex = 65
+	ss.WriteFloat(65, writeCtx)
+	assert.True(t, ss.block.isSparse())
+	for i := 66; i <= 128; i++ {
+		writeCtx.slotIndex = i
+		ss.WriteFloat(float64(i), writeCtx)
+	}
+	// rollup in sparse map
+	writeCtx.slotIndex = 65
+	ss.WriteFloat(65, writeCtx)
+	assert.True(t, ss.block.isSparse())
+	// switch to dense array for next time window, because of dense time window[65,128]
+	writeCtx.slotIndex = 129
+	ss.WriteFloat(129, writeCtx)
+	assert.False(t, ss.block.isSparse())
+
+	data, startSlot, endSlot, err := ss.Bytes(true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, startSlot)
+	assert.Equal(t, 129, endSlot)
+	tsd := encoding.NewTSDDecoder(data)
+	values := make(map[int]float64)
+	for tsd.Next() {
+		if tsd.HasValue() {
+			values[tsd.Slot()] = math.Float64frombits(tsd.Value())
+		}
+	}
+	assert.Len(t, values, 2+64+1)
+	assert.Equal(t, 1.0, values[1])
+	assert.Equal(t, 50.0, values[50])
+	assert.Equal(t, 130.0, values[65])
+	assert.Equal(t, 100.0, values[100])
+	assert.Equal(t, 129.0, values[129])
+}
+
+func Test_blockStore_useSparse(t *testing.T) {
+	bs := newBlockStore(64)
+	assert.True(t, bs.useSparse(15))
+	assert.False(t, bs.useSparse(16))
+	bs.slotStrategy = SlotDense
+	assert.False(t, bs.useSparse(0))
+	assert.False(t, bs.allocBlock(field.Integer).isSparse())
+	bs.slotStrategy = SlotSparse
+	assert.True(t, bs.useSparse(64))
+	assert.True(t, bs.allocBlock(field.Integer).isSparse())
+	assert.True(t, bs.allocBlock(field.Float).isSparse())
+}
+
+// BenchmarkSimpleSegmentStore_SlotStrategy shows the memory/time tradeoffs of
+// dense array and sparse map for sparse/dense series.
+func BenchmarkSimpleSegmentStore_SlotStrategy(b *testing.B) {
+	series := map[string]int{
+		"sparse-series": 16, // 1 point per 16 slots
+		"dense-series":  1,  // 1 point per slot
+	}
+	strategies := map[string]SlotStrategy{
+		"dense":  SlotDense,
+		"sparse": SlotSparse,
+		"auto":   SlotAuto,
+	}
+	for seriesName, step := range series {
+		for strategyName, strategy := range strategies {
+			b.Run(seriesName+"/"+strategyName, func(b *testing.B) {
+				bs := newBlockStore(64)
+				bs.slotStrategy = strategy
+				writeCtx := writeContext{blockStore: bs}
+				memSize := 0
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					store := newSimpleFieldStore(0, field.Sum.AggFunc())
+					// write 3 time windows, keep the last one in memory
+					for slot := 0; slot < 64*3; slot += step {
+						writeCtx.slotIndex = slot
+						store.WriteFloat(float64(slot), writeCtx)
+					}
+					memSize = store.MemSize()
+				}
+				b.ReportMetric(float64(memSize), "bytes/store")
+			})
+		}
+	}
+}
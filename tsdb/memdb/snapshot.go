@@ -0,0 +1,135 @@
+package memdb
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/lindb/lindb/series"
+	"github.com/lindb/lindb/series/field"
+)
+
+// MetricSnapshot represents the index state of a metric in memory database,
+// it is dumped without waiting for a flush for diagnosing cardinality explosion.
+type MetricSnapshot struct {
+	MetricName string            `json:"metricName"`
+	MetricID   uint32            `json:"metricID"`
+	Fields     []FieldSnapshot   `json:"fields"`
+	TagKeys    []TagKeySnapshot  `json:"tagKeys"`
+	Versions   []VersionSnapshot `json:"versions"`
+}
+
+// FieldSnapshot represents the meta of a field in memory
+type FieldSnapshot struct {
+	Name string `json:"name"`
+	ID   uint16 `json:"id"`
+	Type string `json:"type"`
+}
+
+// TagKeySnapshot represents a tag key with the count of distinct tag values across all the versions in memory
+type TagKeySnapshot struct {
+	TagKey     string `json:"tagKey"`
+	ValueCount int    `json:"valueCount"`
+}
+
+// VersionSnapshot represents the count of series of an index version in memory
+type VersionSnapshot struct {
+	Version     series.Version `json:"version"`
+	SeriesCount int            `json:"seriesCount"`
+}
+
+// Snapshot serializes the index state of all the metrics in memory as gzip-compressed json stream,
+// one json object per metric. The read lock of each bucket is held only when cloning the metric stores of it,
+// so the writes are not blocked during the serialization.
+func (md *memoryDatabase) Snapshot(w io.Writer) error {
+	gzipWriter := gzip.NewWriter(w)
+	encoder := json.NewEncoder(gzipWriter)
+	for _, bucket := range md.mStoresList {
+		bucket.rwLock.RLock()
+		metricNames := make([]string, 0, len(bucket.hash2MStore))
+		stores := make([]mStoreINTF, 0, len(bucket.hash2MStore))
+		for metricHash, mStore := range bucket.hash2MStore {
+			metricNames = append(metricNames, bucket.hash2Name[metricHash])
+			stores = append(stores, mStore)
+		}
+		bucket.rwLock.RUnlock()
+
+		for idx, mStore := range stores {
+			snapshot := mStore.Snapshot()
+			snapshot.MetricName = metricNames[idx]
+			if err := encoder.Encode(&snapshot); err != nil {
+				return fmt.Errorf("encode snapshot of metric: %s error:%s", metricNames[idx], err)
+			}
+		}
+	}
+	return gzipWriter.Close()
+}
+
+// ReadSnapshot decodes the metric snapshots written by MemoryDatabase.Snapshot, for tooling
+func ReadSnapshot(r io.Reader) ([]MetricSnapshot, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = gzipReader.Close()
+	}()
+	decoder := json.NewDecoder(gzipReader)
+	var snapshots []MetricSnapshot
+	for {
+		var snapshot MetricSnapshot
+		err := decoder.Decode(&snapshot)
+		if err == io.EOF {
+			return snapshots, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+}
+
+// Snapshot returns the field metas, tag keys with count of distinct tag values and count of series per version
+func (ms *metricStore) Snapshot() MetricSnapshot {
+	snapshot := MetricSnapshot{MetricID: ms.metricID}
+	tagKey2Values := make(map[string]map[string]struct{})
+	snapshotTagIndex := func(tagIndex tagIndexINTF) {
+		for _, entrySet := range tagIndex.GetTagKVEntrySets() {
+			values, ok := tagKey2Values[entrySet.key]
+			if !ok {
+				values = make(map[string]struct{})
+				tagKey2Values[entrySet.key] = values
+			}
+			for tagValue := range entrySet.values {
+				values[tagValue] = struct{}{}
+			}
+		}
+		snapshot.Versions = append(snapshot.Versions, VersionSnapshot{
+			Version:     tagIndex.Version(),
+			SeriesCount: tagIndex.AllTStores().size(),
+		})
+	}
+	ms.mux.RLock()
+	immutables := ms.atomicGetImmutables()
+	snapshotTagIndex(ms.mutable)
+	ms.mux.RUnlock()
+	for _, immutable := range immutables {
+		snapshotTagIndex(immutable)
+	}
+
+	for _, fm := range ms.fieldsMetas.Load().(field.Metas) {
+		snapshot.Fields = append(snapshot.Fields, FieldSnapshot{Name: fm.Name, ID: fm.ID, Type: fm.Type.String()})
+	}
+	for tagKey, values := range tagKey2Values {
+		snapshot.TagKeys = append(snapshot.TagKeys, TagKeySnapshot{TagKey: tagKey, ValueCount: len(values)})
+	}
+	sort.Slice(snapshot.TagKeys, func(i, j int) bool {
+		return snapshot.TagKeys[i].TagKey < snapshot.TagKeys[j].TagKey
+	})
+	sort.Slice(snapshot.Versions, func(i, j int) bool {
+		return snapshot.Versions[i].Version < snapshot.Versions[j].Version
+	})
+	return snapshot
+}
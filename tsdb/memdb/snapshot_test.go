@@ -0,0 +1,123 @@
+package memdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/pkg/timeutil"
+	pb "github.com/lindb/lindb/rpc/proto/field"
+	"github.com/lindb/lindb/series/field"
+	"github.com/lindb/lindb/tsdb/metadb"
+)
+
+type errWriter struct{}
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	return 0, fmt.Errorf("write error")
+}
+
+func Test_MemoryDatabase_Snapshot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockGen := metadb.NewMockIDGenerator(ctrl)
+	mockGen.EXPECT().GenMetricID("cpu").Return(uint32(1))
+	mockGen.EXPECT().GenMetricID("mem").Return(uint32(2))
+	mockGen.EXPECT().GenFieldID(gomock.Any(), "usage", field.SumField).Return(uint16(1), nil).AnyTimes()
+	mockGen.EXPECT().GenFieldID(gomock.Any(), "latency", field.QuantileField).Return(uint16(2), nil).AnyTimes()
+	mockGen.EXPECT().GenTagKeyID(gomock.Any(), gomock.Any()).Return(uint32(1)).AnyTimes()
+	snapshotCfg := cfg
+	snapshotCfg.Generator = mockGen
+	md := NewMemoryDatabase(ctx, snapshotCfg)
+
+	write := func(metricName string, tags map[string]string, f *pb.Field) {
+		assert.NoError(t, md.Write(&pb.Metric{
+			Name:      metricName,
+			Timestamp: timeutil.Now(),
+			Tags:      tags,
+			Fields:    []*pb.Field{f},
+		}))
+	}
+	usage := &pb.Field{Name: "usage", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1}}}
+	latency := &pb.Field{Name: "latency", Field: &pb.Field_Distribution{
+		Distribution: &pb.Distribution{Values: []float64{1, 2}}}}
+	write("cpu", map[string]string{"host": "a", "zone": "sh"}, usage)
+	write("cpu", map[string]string{"host": "b", "zone": "sh"}, usage)
+	write("cpu", map[string]string{"host": "c", "zone": "bj"}, usage)
+	assert.NoError(t, md.ResetMetricStore("cpu"))
+	write("cpu", map[string]string{"host": "a", "zone": "sh"}, usage)
+	write("cpu", map[string]string{"host": "d", "zone": "nj"}, usage)
+	write("mem", map[string]string{"host": "a"}, latency)
+
+	var buf bytes.Buffer
+	assert.NoError(t, md.Snapshot(&buf))
+	snapshots, err := ReadSnapshot(&buf)
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].MetricName < snapshots[j].MetricName
+	})
+
+	cpu := snapshots[0]
+	assert.Equal(t, "cpu", cpu.MetricName)
+	assert.Equal(t, uint32(1), cpu.MetricID)
+	assert.Equal(t, []FieldSnapshot{{Name: "usage", ID: 1, Type: field.SumField.String()}}, cpu.Fields)
+	// distinct tag values across versions
+	assert.Equal(t, []TagKeySnapshot{{TagKey: "host", ValueCount: 4}, {TagKey: "zone", ValueCount: 3}}, cpu.TagKeys)
+	assert.Len(t, cpu.Versions, 2)
+	assert.True(t, cpu.Versions[0].Version < cpu.Versions[1].Version)
+	assert.Equal(t, 3, cpu.Versions[0].SeriesCount)
+	assert.Equal(t, 2, cpu.Versions[1].SeriesCount)
+
+	mem := snapshots[1]
+	assert.Equal(t, "mem", mem.MetricName)
+	assert.Equal(t, []FieldSnapshot{{Name: "latency", ID: 2, Type: field.QuantileField.String()}}, mem.Fields)
+	assert.Equal(t, []TagKeySnapshot{{TagKey: "host", ValueCount: 1}}, mem.TagKeys)
+	assert.Len(t, mem.Versions, 1)
+	assert.Equal(t, 1, mem.Versions[0].SeriesCount)
+
+	// snapshot under concurrent writes
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			write("cpu", map[string]string{"host": fmt.Sprintf("host-%d", i)}, usage)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		buf.Reset()
+		assert.NoError(t, md.Snapshot(&buf))
+		snapshots, err = ReadSnapshot(&buf)
+		assert.NoError(t, err)
+		assert.Len(t, snapshots, 2)
+	}
+	wg.Wait()
+
+	// write error
+	assert.Error(t, md.Snapshot(&errWriter{}))
+}
+
+func Test_ReadSnapshot(t *testing.T) {
+	// empty memory database
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf bytes.Buffer
+	assert.NoError(t, NewMemoryDatabase(ctx, cfg).Snapshot(&buf))
+	snapshots, err := ReadSnapshot(&buf)
+	assert.NoError(t, err)
+	assert.Empty(t, snapshots)
+
+	// not gzip
+	_, err = ReadSnapshot(bytes.NewReader([]byte("snapshot")))
+	assert.Error(t, err)
+}
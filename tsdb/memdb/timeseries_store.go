@@ -6,6 +6,7 @@ import (
 
 	"go.uber.org/atomic"
 
+	"github.com/lindb/lindb/pkg/encoding"
 	"github.com/lindb/lindb/pkg/lockers"
 	"github.com/lindb/lindb/pkg/timeutil"
 	pb "github.com/lindb/lindb/rpc/proto/field"
@@ -39,12 +40,25 @@ type tStoreINTF interface {
 		seriesID uint32,
 	) (flushedSize int)
 
-	// IsExpired detects if this tStore has not been used for a TTL
-	IsExpired() bool
+	// IsExpired detects if this tStore has not been used for a TTL since now(millisecond)
+	IsExpired(now int64) bool
 
 	// IsNoData symbols if all data of this tStore has been flushed
 	IsNoData() bool
 
+	// LastWroteTime returns the last write-time in seconds
+	LastWroteTime() uint32
+
+	// IsStale detects if the latest data of this tStore is older than the threshold timestamp
+	IsStale(interval, threshold int64) bool
+
+	// CountSlots returns the count of time slots which has value of any field in the time range
+	CountSlots(interval int64, timeRange timeutil.TimeRange, tsd *encoding.TSDDecoder) int
+
+	// EvictFieldSegments removes the segments of fields whose family time is before the family time of field,
+	// key: field id, value: family time, returns the evicted size
+	EvictFieldSegments(fieldFamilyTimes map[uint16]int64) (evictedSize int)
+
 	MemSize() int
 
 	// scan scans the time series data based on field ids
@@ -65,10 +79,10 @@ type timeSeriesStore struct {
 	fStoreNodes   fStoreNodes      // key: sorted fStore list by field-name, insert-only
 }
 
-// newTimeSeriesStore returns a new tStoreINTF.
-func newTimeSeriesStore() tStoreINTF {
+// newTimeSeriesStore returns a new tStoreINTF created at now(millisecond).
+func newTimeSeriesStore(now int64) tStoreINTF {
 	return &timeSeriesStore{
-		lastWroteTime: *atomic.NewUint32(uint32(timeutil.Now() / 1000))}
+		lastWroteTime: *atomic.NewUint32(uint32(now / 1000))}
 }
 
 // GetFStore returns the fStore in this list from field-id.
@@ -101,6 +115,65 @@ func (ts *timeSeriesStore) IsNoData() bool {
 	return true
 }
 
+// IsStale detects if the latest data of this tStore is older than the threshold timestamp,
+// the end of fStore's time range is the latest data time,
+// the last write time is used if all data has been flushed.
+func (ts *timeSeriesStore) IsStale(interval, threshold int64) bool {
+	ts.sl.Lock()
+	defer ts.sl.Unlock()
+
+	var (
+		latest  int64
+		hasData bool
+	)
+	for _, fStore := range ts.fStoreNodes {
+		timeRange, ok := fStore.TimeRange(interval)
+		if !ok {
+			continue
+		}
+		if !hasData || latest < timeRange.End {
+			latest = timeRange.End
+		}
+		hasData = true
+	}
+	if !hasData {
+		latest = int64(ts.lastWroteTime.Load()) * 1000
+	}
+	return latest < threshold
+}
+
+// CountSlots returns the count of time slots which has value of any field in the time range,
+// the null slots of all fields are not counted.
+func (ts *timeSeriesStore) CountSlots(interval int64, timeRange timeutil.TimeRange, tsd *encoding.TSDDecoder) int {
+	ts.sl.Lock()
+	defer ts.sl.Unlock()
+
+	timestamps := make(map[int64]struct{})
+	for _, fStore := range ts.fStoreNodes {
+		fStore.forEachSlot(tsd, func(familyTime int64, slot int) {
+			timestamp := familyTime + int64(slot)*interval
+			if timeRange.Contains(timestamp) {
+				timestamps[timestamp] = struct{}{}
+			}
+		})
+	}
+	return len(timestamps)
+}
+
+// EvictFieldSegments removes the segments of fields whose family time is before the family time of field,
+// key: field id, value: family time, returns the evicted size
+func (ts *timeSeriesStore) EvictFieldSegments(fieldFamilyTimes map[uint16]int64) (evictedSize int) {
+	ts.sl.Lock()
+	defer ts.sl.Unlock()
+
+	for _, fStore := range ts.fStoreNodes {
+		if beforeFamilyTime, ok := fieldFamilyTimes[fStore.GetFieldID()]; ok {
+			evictedSize += fStore.EvictSegments(beforeFamilyTime)
+		}
+	}
+	return evictedSize
+}
+
 // afterFlush checks if the tStore contains any data after flushing
 func (ts *timeSeriesStore) afterFlush(flushCtx flushContext) {
 	// update hasData flag
@@ -119,9 +192,14 @@ func (ts *timeSeriesStore) afterFlush(flushCtx flushContext) {
 	}
 }
 
-// IsExpired detects if this tStore has not been used for a TTL
-func (ts *timeSeriesStore) IsExpired() bool {
-	return time.Unix(int64(ts.lastWroteTime.Load()), 0).Add(seriesTTL.Load()).Before(time.Now())
+// LastWroteTime returns the last write-time in seconds
+func (ts *timeSeriesStore) LastWroteTime() uint32 {
+	return ts.lastWroteTime.Load()
+}
+
+// IsExpired detects if this tStore has not been used for a TTL since now(millisecond)
+func (ts *timeSeriesStore) IsExpired(now int64) bool {
+	return time.Unix(int64(ts.lastWroteTime.Load()), 0).Add(seriesTTL.Load()).Before(time.Unix(0, now*int64(time.Millisecond)))
 }
 
 // Write Write the data of metric to the fStore.
@@ -157,7 +235,7 @@ func (ts *timeSeriesStore) Write(
 			writtenSize += (cap(ts.fStoreNodes)-oldCap)*8 + fStore.MemSize()
 		}
 		writtenSize += fStore.Write(f, writeCtx)
-		ts.lastWroteTime.Store(uint32(timeutil.Now() / 1000))
+		ts.lastWroteTime.Store(uint32(writeCtx.writeTime / 1000))
 	}
 	return writtenSize, err
 }
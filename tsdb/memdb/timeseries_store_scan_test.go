@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/lindb/lindb/aggregation"
+	"github.com/lindb/lindb/pkg/timeutil"
 	pb "github.com/lindb/lindb/rpc/proto/field"
 )
 
@@ -15,7 +16,7 @@ func TestTimeSeriesStore_scan(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	tStoreInterface := newTimeSeriesStore()
+	tStoreInterface := newTimeSeriesStore(timeutil.Now())
 	tStore := tStoreInterface.(*timeSeriesStore)
 	mCtx := &memScanContext{
 		fieldIDs:   []uint16{1, 2, 3},
@@ -13,27 +13,29 @@ import (
 )
 
 func Test_newTimeSeriesStore(t *testing.T) {
-	tStore := newTimeSeriesStore()
+	now := timeutil.Now()
+	tStore := newTimeSeriesStore(now)
 	assert.NotNil(t, tStore)
 	assert.True(t, tStore.IsNoData())
-	assert.False(t, tStore.IsExpired())
+	assert.False(t, tStore.IsExpired(now))
 }
 
 func Test_tStore_expired(t *testing.T) {
-	tStore := newTimeSeriesStore()
-	time.Sleep(time.Millisecond * 1)
-	assert.False(t, tStore.IsExpired())
-
-	seriesTTL.Store(time.Nanosecond)
-	time.Sleep(time.Millisecond * 1)
-	assert.True(t, tStore.IsExpired())
+	clock := timeutil.NewFakeClock(1564300800000)
+	tStore := newTimeSeriesStore(clock.Now())
+	assert.False(t, tStore.IsExpired(clock.Now()))
+
+	clock.Advance(seriesTTL.Load())
+	assert.False(t, tStore.IsExpired(clock.Now()))
+	clock.Advance(time.Second)
+	assert.True(t, tStore.IsExpired(clock.Now()))
 }
 
 func Test_tStore_write(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	tStoreInterface := newTimeSeriesStore()
+	tStoreInterface := newTimeSeriesStore(timeutil.Now())
 	tStore := tStoreInterface.(*timeSeriesStore)
 	// mock fieldID getter
 	mockFieldIDGetter := NewMockmStoreFieldIDGetter(ctrl)
@@ -70,7 +72,7 @@ func Test_tStore_GenFieldID_error(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	tStoreInterface := newTimeSeriesStore()
+	tStoreInterface := newTimeSeriesStore(timeutil.Now())
 	tStore := tStoreInterface.(*timeSeriesStore)
 	// mock id generator
 	mockGetter := NewMockmStoreFieldIDGetter(ctrl)
@@ -89,7 +91,7 @@ func Test_tStore_flushSeriesTo(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	tStoreInterface := newTimeSeriesStore()
+	tStoreInterface := newTimeSeriesStore(timeutil.Now())
 	tStore := tStoreInterface.(*timeSeriesStore)
 	assert.Equal(t, emptyTimeSeriesStoreSize, tStore.MemSize())
 
@@ -142,3 +144,34 @@ func Test_tStore_flushSeriesTo(t *testing.T) {
 	tStore.insertFStore(mockFStore4)
 	assert.NotZero(t, tStore.FlushSeriesTo(mockTF, flushContext{timeInterval: 10 * 1000}, 100))
 }
+
+func Test_tStore_IsStale(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tStoreInterface := newTimeSeriesStore(timeutil.Now())
+	tStore := tStoreInterface.(*timeSeriesStore)
+	now := timeutil.Now()
+	// no data in memory, uses last write time
+	assert.False(t, tStore.IsStale(10*1000, now-timeutil.OneMinute*5))
+	assert.True(t, tStore.IsStale(10*1000, now+timeutil.OneMinute))
+
+	mockFStore1 := NewMockfStoreINTF(ctrl)
+	mockFStore1.EXPECT().GetFieldID().Return(uint16(1)).AnyTimes()
+	mockFStore1.EXPECT().TimeRange(gomock.Any()).Return(timeutil.TimeRange{
+		Start: now - timeutil.OneMinute*20, End: now - timeutil.OneMinute*10}, true).AnyTimes()
+	mockFStore2 := NewMockfStoreINTF(ctrl)
+	mockFStore2.EXPECT().GetFieldID().Return(uint16(2)).AnyTimes()
+	mockFStore2.EXPECT().TimeRange(gomock.Any()).Return(timeutil.TimeRange{
+		Start: now - timeutil.OneMinute*20, End: now - timeutil.OneMinute*8}, true).AnyTimes()
+	mockFStore3 := NewMockfStoreINTF(ctrl)
+	mockFStore3.EXPECT().GetFieldID().Return(uint16(3)).AnyTimes()
+	mockFStore3.EXPECT().TimeRange(gomock.Any()).Return(timeutil.TimeRange{}, false).AnyTimes()
+	tStore.insertFStore(mockFStore1)
+	tStore.insertFStore(mockFStore2)
+	tStore.insertFStore(mockFStore3)
+	// latest data is 8 minutes ago
+	assert.True(t, tStore.IsStale(10*1000, now-timeutil.OneMinute*5))
+	assert.False(t, tStore.IsStale(10*1000, now-timeutil.OneMinute*8))
+	assert.False(t, tStore.IsStale(10*1000, now-timeutil.OneMinute*10))
+}
@@ -1,6 +1,7 @@
 package tsdb
 
 import (
+	"sync"
 	"time"
 
 	"github.com/lindb/lindb/kv"
@@ -39,6 +40,29 @@ func init() {
 	kv.RegisterMerger(nopMerger, &_nopMerger{})
 }
 
+// metricTTLGetters holds the metric ttl getter of the forward index merger of each database,
+// key: merger name, value: forwardindex.MetricTTLGetter
+var metricTTLGetters sync.Map
+
+// registerForwardIndexMerger registers the forward index merger of database, the versions older than
+// the ttl of metric from metricTTL are dropped, returns the merger name for the forward index family of database.
+// The merger name is kept in the option of family, so the family created before keeps the default merger.
+// The merger is registered only once, the reopened database replaces the metric ttl getter of merger.
+func registerForwardIndexMerger(databaseName string, metricTTL forwardindex.MetricTTLGetter) string {
+	name := forwardIndexMerger + "_" + databaseName
+	if _, loaded := metricTTLGetters.LoadOrStore(name, metricTTL); loaded {
+		metricTTLGetters.Store(name, metricTTL)
+		return name
+	}
+	kv.RegisterMerger(
+		name,
+		forwardindex.NewMergerWithMetricTTL(defaultTTLDuration, func(metricID uint32) (time.Duration, bool) {
+			getter, _ := metricTTLGetters.Load(name)
+			return getter.(forwardindex.MetricTTLGetter)(metricID)
+		}))
+	return name
+}
+
 // nopMerger does nothing
 type _nopMerger struct{}
 
@@ -0,0 +1,83 @@
+package tsdb
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lindb/lindb/kv"
+	"github.com/lindb/lindb/pkg/fileutil"
+	"github.com/lindb/lindb/pkg/option"
+	"github.com/lindb/lindb/pkg/timeutil"
+	"github.com/lindb/lindb/series"
+	"github.com/lindb/lindb/tsdb/tblstore"
+	"github.com/lindb/lindb/tsdb/tblstore/forwardindex"
+)
+
+func TestForwardIndexMerger_metricTTL(t *testing.T) {
+	defer func() {
+		_ = fileutil.RemoveDir(testPath)
+	}()
+	dbPath := filepath.Join(testPath, "ttl_db")
+	// cpu keeps 40 days, disk keeps 90 days, mem keeps 30 days by default
+	db, err := newDatabase("ttl_db", dbPath, &databaseConfig{Option: option.DatabaseOption{
+		Interval:  "10s",
+		MetricTTL: []option.MetricTTLOption{{Metric: "cpu", TTL: "40d"}, {Metric: "disk", TTL: "90d"}},
+	}}, nil)
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+	metricIDs := []uint32{db.idSequencer.GenMetricID("cpu"), db.idSequencer.GenMetricID("disk"),
+		db.idSequencer.GenMetricID("mem")}
+
+	storeOption := kv.DefaultStoreOption(filepath.Join(dbPath, "index"))
+	storeOption.CompactCheckInterval = 1
+	store, err := kv.NewStore(storeOption.Path, storeOption)
+	assert.NoError(t, err)
+	defer func() {
+		_ = store.Close()
+	}()
+	family, err := store.CreateFamily("forward", kv.FamilyOption{CompactThreshold: 2, Merger: db.forwardIndexMerger})
+	assert.NoError(t, err)
+
+	// flushes the versions of 60, 20 days ago to a file, then the versions of 35, 20 days ago to another file
+	day := int64(24 * time.Hour / time.Millisecond)
+	now := timeutil.Now()
+	for _, daysAgo := range [][]int64{{60, 20}, {35, 20}} {
+		flusher := forwardindex.NewFlusher(family.NewFlusher())
+		for _, metricID := range metricIDs {
+			for _, daysAgo := range daysAgo {
+				flusher.FlushTagValue("192.168.1.1", roaring.BitmapOf(1))
+				flusher.FlushTagKey("ip")
+				flusher.FlushVersion(series.Version(now-daysAgo*day), timeutil.TimeRange{Start: 1, End: 2})
+			}
+			assert.NoError(t, flusher.FlushMetricID(metricID))
+		}
+		assert.NoError(t, flusher.Commit())
+	}
+	time.Sleep(2 * time.Second)
+
+	countVersions := func(metricID uint32) int {
+		snapshot := family.GetSnapshot()
+		defer snapshot.Close()
+		readers, err := snapshot.FindReaders(metricID)
+		assert.NoError(t, err)
+		// the files are compacted into one
+		assert.Len(t, readers, 1)
+		itr, err := tblstore.NewVersionBlockIterator(readers[0].Get(metricID))
+		assert.NoError(t, err)
+		count := 0
+		for itr.HasNext() {
+			_, _ = itr.Next()
+			count++
+		}
+		return count
+	}
+	assert.Equal(t, 2, countVersions(metricIDs[0]))
+	assert.Equal(t, 3, countVersions(metricIDs[1]))
+	assert.Equal(t, 1, countVersions(metricIDs[2]))
+}
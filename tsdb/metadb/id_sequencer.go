@@ -2,6 +2,7 @@ package metadb
 
 import (
 	"math"
+	"sort"
 	"sync"
 
 	"github.com/lindb/lindb/constants"
@@ -204,6 +205,36 @@ func (seq *idSequencer) GetTagKeyID(metricID uint32, tagKey string) (tagID uint3
 	return seq.readTagKeyID(metricsmeta.NewReader(readers), metricID, tagKey)
 }
 
+// GetTagKeys returns all the sorted tag keys of the metric, both in memory and on disk,
+// the empty tag key of series without tags is not included.
+func (seq *idSequencer) GetTagKeys(metricID uint32) (tagKeys []string, err error) {
+	tagKeysMap := make(map[string]struct{})
+	// case1: tagKeys in memory
+	seq.rwMux.RLock()
+	for _, tagMeta := range seq.newTagMetas[metricID] {
+		if tagMeta.Key != "" {
+			tagKeysMap[tagMeta.Key] = struct{}{}
+		}
+	}
+	seq.rwMux.RUnlock()
+	// case2: tagKeys on disk
+	snapShot := seq.metaFamily.GetSnapshot()
+	defer snapShot.Close()
+
+	readers, err := snapShot.FindReaders(metricID)
+	if err != nil {
+		return nil, err
+	}
+	for _, tagKey := range metricsmeta.NewReader(readers).SuggestTagKeys(metricID, "", math.MaxInt32) {
+		tagKeysMap[tagKey] = struct{}{}
+	}
+	for tagKey := range tagKeysMap {
+		tagKeys = append(tagKeys, tagKey)
+	}
+	sort.Strings(tagKeys)
+	return tagKeys, nil
+}
+
 // readTagKeyID reads the tagKeyID from reader.
 func (seq *idSequencer) readTagKeyID(
 	reader metricsmeta.Reader,
@@ -372,6 +403,33 @@ func (seq *idSequencer) GetFieldID(
 	return seq.readFieldID(metricsmeta.NewReader(readers), metricID, fieldName)
 }
 
+// GetFields returns all the field metas sorted by name of the metric, both in memory and on disk
+func (seq *idSequencer) GetFields(metricID uint32) (fields field.Metas, err error) {
+	fieldsMap := make(map[string]field.Meta)
+	// case1: fields in memory
+	seq.rwMux.RLock()
+	for _, fieldMeta := range seq.newFieldMetas[metricID] {
+		fieldsMap[fieldMeta.Name] = fieldMeta
+	}
+	seq.rwMux.RUnlock()
+	// case2: fields on disk
+	snapShot := seq.metaFamily.GetSnapshot()
+	defer snapShot.Close()
+
+	readers, err := snapShot.FindReaders(metricID)
+	if err != nil {
+		return nil, err
+	}
+	for _, fieldMeta := range metricsmeta.NewReader(readers).ReadFieldMetas(metricID) {
+		fieldsMap[fieldMeta.Name] = fieldMeta
+	}
+	for _, fieldMeta := range fieldsMap {
+		fields = append(fields, fieldMeta)
+	}
+	sort.Sort(fields)
+	return fields, nil
+}
+
 // readFieldID read fieldID from the reader
 func (seq *idSequencer) readFieldID(
 	reader metricsmeta.Reader,
@@ -199,6 +199,46 @@ func Test_IDSequencer_GetTagKeyID(t *testing.T) {
 	assert.Zero(t, tagKeyID)
 }
 
+func Test_IDSequencer_GetTagKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mocked := mockIDSequencer(ctrl)
+	mocked.Clear()
+	mocked.idSequencer.newTagMetas[uint32(1)] = []tag.Meta{{Key: "zone", ID: 2}, {Key: "host", ID: 1}, {Key: "", ID: 3}}
+	// case1: snapShot FindReaders error
+	mocked.WithFindReadersError()
+	_, err := mocked.idSequencer.GetTagKeys(1)
+	assert.NotNil(t, err)
+	// case2: snapShot FindReaders ok, tag keys are sorted, empty tag key of series without tags is skipped
+	mocked.WithFindReadersOK()
+	mocked.reader.EXPECT().Get(gomock.Any()).Return(nil)
+	tagKeys, err := mocked.idSequencer.GetTagKeys(1)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"host", "zone"}, tagKeys)
+}
+
+func Test_IDSequencer_GetFields(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mocked := mockIDSequencer(ctrl)
+	mocked.Clear()
+	mocked.idSequencer.newFieldMetas[uint32(1)] = []field.Meta{
+		{Name: "disk_/var", ID: 2, Type: field.SumField}, {Name: "disk_/tmp", ID: 1, Type: field.SumField}}
+	// case1: snapShot FindReaders error
+	mocked.WithFindReadersError()
+	_, err := mocked.idSequencer.GetFields(1)
+	assert.NotNil(t, err)
+	// case2: snapShot FindReaders ok, fields are sorted by name
+	mocked.WithFindReadersOK()
+	mocked.reader.EXPECT().Get(gomock.Any()).Return(nil)
+	fields, err := mocked.idSequencer.GetFields(1)
+	assert.Nil(t, err)
+	assert.Equal(t, field.Metas{
+		{Name: "disk_/tmp", ID: 1, Type: field.SumField}, {Name: "disk_/var", ID: 2, Type: field.SumField}}, fields)
+}
+
 func Test_IDSequencer_GenTagKeyID(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -23,9 +23,13 @@ type IDGetter interface {
 	GetMetricID(metricName string) (uint32, error)
 	// GetTagKeyID returns tag ID(uint32), return ErrNotFound if not exist
 	GetTagKeyID(metricID uint32, tagKey string) (tagKeyID uint32, err error)
+	// GetTagKeys returns all the sorted tag keys of the metric, both in memory and on disk
+	GetTagKeys(metricID uint32) (tagKeys []string, err error)
 	// GetFieldID returns field id and type by given metricID and field name,
 	// if not exist return ErrNotFound error
 	GetFieldID(metricID uint32, fieldName string) (fieldID uint16, fieldType field.Type, err error)
+	// GetFields returns all the field metas sorted by name of the metric, both in memory and on disk
+	GetFields(metricID uint32) (fields field.Metas, err error)
 }
 
 // IDSequencer contains the abilities for querying and generating ID numbers.
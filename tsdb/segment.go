@@ -28,10 +28,11 @@ type Segment interface {
 
 // segment implements Segment interface
 type segment struct {
-	baseTime int64
-	kvStore  kv.Store
-	interval timeutil.Interval
-	families sync.Map
+	baseTime        int64
+	kvStore         kv.Store
+	interval        timeutil.Interval
+	flushBufferSize int // write buffer size of flushed data file
+	families        sync.Map
 
 	mutex sync.Mutex
 
@@ -43,6 +44,7 @@ func newSegment(
 	segmentName string,
 	interval timeutil.Interval,
 	path string,
+	flushBufferSize int,
 ) (
 	Segment,
 	error,
@@ -59,10 +61,11 @@ func newSegment(
 	}
 
 	return &segment{
-		baseTime: baseTime,
-		kvStore:  kvStore,
-		interval: interval,
-		logger:   logger.GetLogger("tsdb", "Segment"),
+		baseTime:        baseTime,
+		kvStore:         kvStore,
+		interval:        interval,
+		flushBufferSize: flushBufferSize,
+		logger:          logger.GetLogger("tsdb", "Segment"),
 	}, nil
 }
 
@@ -71,20 +74,16 @@ func (s *segment) BaseTime() int64 {
 	return s.baseTime
 }
 
-// GetDataFamilies returns data family list by time range, return nil if not match
+// GetDataFamilies returns data family list whose time range overlaps the query time range, return nil if not match.
+// the time range of family is calculated by the interval calculator when creating, so the query time range
+// is compared without truncating, which also works when the query time range crosses the segment.
 func (s *segment) getDataFamilies(timeRange timeutil.TimeRange) []DataFamily {
 	var result []DataFamily
-	calc := s.interval.Calculator()
-
-	familyQueryTimeRange := timeutil.TimeRange{
-		Start: calc.CalcFamilyStartTime(s.baseTime, calc.CalcFamily(timeRange.Start, s.baseTime)),
-		End:   calc.CalcFamilyStartTime(s.baseTime, calc.CalcFamily(timeRange.End, s.baseTime)),
-	}
 	s.families.Range(func(k, v interface{}) bool {
 		family, ok := v.(DataFamily)
 		if ok {
-			timeRange := family.TimeRange()
-			if familyQueryTimeRange.Overlap(&timeRange) {
+			familyTimeRange := family.TimeRange()
+			if timeRange.Overlap(&familyTimeRange) {
 				result = append(result, family)
 			}
 		}
@@ -114,6 +113,7 @@ func (s *segment) GetDataFamily(timestamp int64) (DataFamily, error) {
 			familyOption := kv.FamilyOption{
 				CompactThreshold: 0,
 				Merger:           nopMerger,
+				FlushBufferSize:  s.flushBufferSize,
 			}
 			// create kv family
 			f, err := s.kvStore.CreateFamily(fmt.Sprintf("%d", familyTime), familyOption)
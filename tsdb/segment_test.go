@@ -20,7 +20,7 @@ func TestSegment_Close(t *testing.T) {
 	defer func() {
 		_ = fileutil.RemoveDir(testPath)
 	}()
-	s, _ := newIntervalSegment(timeutil.Interval(timeutil.OneSecond*10), segPath)
+	s, _ := newIntervalSegment(timeutil.Interval(timeutil.OneSecond*10), segPath, 0)
 	seg, _ := s.GetOrCreateSegment("20190702")
 	seg1 := seg.(*segment)
 
@@ -37,7 +37,7 @@ func TestSegment_GetDataFamily(t *testing.T) {
 	defer func() {
 		_ = fileutil.RemoveDir(testPath)
 	}()
-	s, _ := newIntervalSegment(timeutil.Interval(timeutil.OneSecond*10), segPath)
+	s, _ := newIntervalSegment(timeutil.Interval(timeutil.OneSecond*10), segPath, 0)
 	seg, _ := s.GetOrCreateSegment("20190904")
 	now, _ := timeutil.ParseTimestamp("20190904 19:10:48", "20060102 15:04:05")
 	familyBaseTime, _ := timeutil.ParseTimestamp("20190904 19:00:00", "20060102 15:04:05")
@@ -83,16 +83,54 @@ func TestSegment_GetDataFamily(t *testing.T) {
 	assert.Nil(t, dataFamily)
 }
 
+func TestSegment_getDataFamilies(t *testing.T) {
+	defer func() {
+		_ = fileutil.RemoveDir(testPath)
+	}()
+	s, _ := newIntervalSegment(timeutil.Interval(timeutil.OneSecond*10), segPath, 0)
+	seg, _ := s.GetOrCreateSegment("20190904")
+	for _, hour := range []string{"10", "11", "13"} {
+		now, _ := timeutil.ParseTimestamp("20190904 "+hour+":10:48", "20060102 15:04:05")
+		_, _ = seg.GetDataFamily(now)
+	}
+	getDataFamilies := func(start, end string) []DataFamily {
+		startTime, _ := timeutil.ParseTimestamp(start, "20060102 15:04:05")
+		endTime, _ := timeutil.ParseTimestamp(end, "20060102 15:04:05")
+		return seg.(*segment).getDataFamilies(timeutil.TimeRange{Start: startTime, End: endTime})
+	}
+	// one hour query range only gets the overlapping families
+	families := getDataFamilies("20190904 10:30:00", "20190904 11:29:59")
+	assert.Len(t, families, 2)
+	families = getDataFamilies("20190904 11:30:00", "20190904 12:29:59")
+	assert.Len(t, families, 1)
+	familyStartTime, _ := timeutil.ParseTimestamp("20190904 11:00:00", "20060102 15:04:05")
+	assert.Equal(t, familyStartTime, families[0].TimeRange().Start)
+	assert.Empty(t, getDataFamilies("20190904 12:00:00", "20190904 12:59:59"))
+	// crosses the segment
+	assert.Len(t, getDataFamilies("20190903 23:00:00", "20190904 10:00:00"), 1)
+	assert.Len(t, getDataFamilies("20190904 13:00:00", "20190905 01:00:00"), 1)
+
+	// month interval, the family of which is calculated by the day of month
+	s, _ = newIntervalSegment(timeutil.Interval(timeutil.OneMinute*5),
+		filepath.Join(testPath, shardDir, "2", segmentDir, timeutil.Month.String()), 0)
+	seg, _ = s.GetOrCreateSegment("201909")
+	now, _ := timeutil.ParseTimestamp("20190902 10:10:48", "20060102 15:04:05")
+	_, _ = seg.GetDataFamily(now)
+	assert.Len(t, getDataFamilies("20190831 12:00:00", "20190902 01:00:00"), 1)
+	assert.Empty(t, getDataFamilies("20190831 12:00:00", "20190901 23:59:59"))
+	assert.Empty(t, getDataFamilies("20190903 00:00:00", "20190905 01:00:00"))
+}
+
 func TestSegment_New(t *testing.T) {
 	defer func() {
 		_ = fileutil.RemoveDir(testPath)
 	}()
-	s, err := newSegment("20190904", timeutil.Interval(timeutil.OneSecond*10), testPath)
+	s, err := newSegment("20190904", timeutil.Interval(timeutil.OneSecond*10), testPath, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
 	assert.NotNil(t, s)
-	s, err = newSegment("20190904", timeutil.Interval(timeutil.OneSecond*10), testPath)
+	s, err = newSegment("20190904", timeutil.Interval(timeutil.OneSecond*10), testPath, 0)
 	assert.NotNil(t, err)
 	assert.Nil(t, s)
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"time"
 
 	"go.uber.org/atomic"
 
@@ -33,7 +34,8 @@ const (
 
 // Shard is a horizontal partition of metrics for LinDB.
 type Shard interface {
-	// GetDataFamilies returns data family list by interval type and time range, return nil if not match
+	// GetDataFamilies returns data family list by interval type, whose time range overlaps the query time range,
+	// return nil if not match
 	GetDataFamilies(intervalType timeutil.IntervalType, timeRange timeutil.TimeRange) []DataFamily
 	// MemoryDatabase returns memory database
 	MemoryDatabase() memdb.MemoryDatabase
@@ -48,6 +50,9 @@ type Shard interface {
 	// IsFlushing checks if this shard is in flushing
 	IsFlushing() bool
 
+	// FieldRetention returns the retention(millisecond) of field, false if the field has no retention
+	FieldRetention(fieldName string) (retention int64, ok bool)
+
 	MemoryFilter() series.Filter
 	IndexFilter() series.Filter
 	MemoryMetaGetter() series.MetaGetter
@@ -84,6 +89,11 @@ type shard struct {
 	indexStore     kv.Store           // kv stores
 	invertedFamily kv.Family
 	forwardFamily  kv.Family
+	objectStore    kv.ObjectStore   // object store for flush output, nil if not enabled
+	clock          timeutil.Clock   // source of current time for write time range and flush
+	fieldRetention map[string]int64 // field name -> retention(millisecond)
+	// merger name of forward index family, which drops the expired series versions by the ttl of metric
+	forwardIndexMerger string
 }
 
 // newShard creates shard instance, if shard path exist then load shard data for init.
@@ -92,6 +102,7 @@ func newShard(
 	shardID int32,
 	shardPath string,
 	idSequencer metadb.IDSequencer,
+	forwardIndexMerger string,
 	option option.DatabaseOption,
 ) (
 	s Shard,
@@ -107,40 +118,96 @@ func newShard(
 		return nil, err
 	}
 	createdShard := &shard{
-		id:          shardID,
-		path:        shardPath,
-		option:      option,
-		interval:    interval,
-		idSequencer: idSequencer,
-		segments:    make(map[timeutil.IntervalType]IntervalSegment),
-		isFlushing:  *atomic.NewBool(false),
+		id:                 shardID,
+		path:               shardPath,
+		option:             option,
+		interval:           interval,
+		idSequencer:        idSequencer,
+		forwardIndexMerger: forwardIndexMerger,
+		segments:           make(map[timeutil.IntervalType]IntervalSegment),
+		isFlushing:         *atomic.NewBool(false),
+		clock:              timeutil.SystemClock,
 	}
 	// new segment for writing
 	createdShard.segment, err = newIntervalSegment(
 		interval,
-		filepath.Join(shardPath, segmentDir, interval.Type().String()))
+		filepath.Join(shardPath, segmentDir, interval.Type().String()),
+		option.Data.BufferSize*1024)
 
 	if err != nil {
 		return nil, err
 	}
 	_ = createdShard.ahead.ValueOf(option.Ahead)
 	_ = createdShard.behind.ValueOf(option.Behind)
+	if option.ObjectStore.Enabled() {
+		createdShard.objectStore = kv.NewHTTPObjectStore(option.ObjectStore.Endpoint, option.ObjectStore.Bucket,
+			option.ObjectStore.GetTimeout())
+	}
 	// add writing segment into segment list
 	createdShard.segments[interval.Type()] = createdShard.segment
 
 	if err = createdShard.initIndexDatabase(); err != nil {
 		return nil, fmt.Errorf("create index database for shard[%d] error: %s", shardID, err)
 	}
+	createdShard.fieldRetention = fieldRetention(option.FieldRetention)
 	var ctx context.Context
 	ctx, createdShard.cancel = context.WithCancel(context.Background())
 	createdShard.memDB = memdb.NewMemoryDatabase(ctx, memdb.MemoryDatabaseCfg{
 		TimeWindow: option.TimeWindow,
 		Interval:   interval,
 		Generator:  idSequencer,
+		Quota:      option.Quota,
+
+		FieldFlushOrder:  option.FieldFlushOrder,
+		MaxTagsPerMetric: option.MaxTagsPerMetric,
+		FlushedIndex:     createdShard.indexDB,
+		Clock:            createdShard.clock,
+		FieldRetention:   createdShard.fieldRetention,
+
+		FlushWritePolicy:  option.FlushWrite.Policy,
+		FlushWriteMaxWait: flushWriteMaxWait(option.FlushWrite),
+		MetricIntervals:   metricIntervals(option.MetricIntervals),
+		Ahead:             createdShard.ahead.Int64(),
+		Behind:            createdShard.behind.Int64(),
 	})
 	return createdShard, nil
 }
 
+// flushWriteMaxWait converts the max wait of flush write option to duration, 0 means default
+func flushWriteMaxWait(flushWrite option.FlushWriteOption) time.Duration {
+	var maxWait timeutil.Interval
+	_ = maxWait.ValueOf(flushWrite.MaxWait)
+	return time.Duration(maxWait.Int64()) * time.Millisecond
+}
+
+// metricIntervals converts the per-metric intervals to map, the option is validated before
+func metricIntervals(intervals []option.MetricIntervalOption) map[string]timeutil.Interval {
+	if len(intervals) == 0 {
+		return nil
+	}
+	result := make(map[string]timeutil.Interval, len(intervals))
+	for _, intervalOpt := range intervals {
+		var interval timeutil.Interval
+		_ = interval.ValueOf(intervalOpt.Interval)
+		result[intervalOpt.Metric] = interval
+	}
+	return result
+}
+
+// fieldRetention converts the retention of fields to millisecond, the option is validated before
+func fieldRetention(retentions []option.FieldRetentionOption) map[string]int64 {
+	if len(retentions) == 0 {
+		return nil
+	}
+	result := make(map[string]int64, len(retentions))
+	for _, retentionOpt := range retentions {
+		var retention timeutil.Interval
+		_ = retention.ValueOf(retentionOpt.Retention)
+		result[retentionOpt.Field] = retention.Int64()
+	}
+	return result
+}
+
 func (s *shard) IndexDatabase() indexdb.IndexDatabase {
 	return s.indexDB
 }
@@ -157,6 +224,13 @@ func (s *shard) MemoryDatabase() memdb.MemoryDatabase {
 	return s.memDB
 }
 
+// FieldRetention returns the retention(millisecond) of field, false if the field has no retention,
+// the data of field older than retention is evicted from memory and not read from data families.
+func (s *shard) FieldRetention(fieldName string) (retention int64, ok bool) {
+	retention, ok = s.fieldRetention[fieldName]
+	return
+}
+
 func (s *shard) Write(metric *pb.Metric) error {
 	if metric == nil {
 		return fmt.Errorf("metric is nil")
@@ -164,16 +238,34 @@ func (s *shard) Write(metric *pb.Metric) error {
 	if metric.Fields == nil {
 		return fmt.Errorf("fields is nil")
 	}
+	if s.option.MaxMetricNameLength > 0 && len(metric.Name) > s.option.MaxMetricNameLength {
+		return series.ErrMetricNameTooLong
+	}
+	// convert timestamp to millisecond, routes the metric to the right family/slot
+	metric.Timestamp = s.option.TimestampPrecision.ToMillisecond(metric.Timestamp)
 	timestamp := metric.Timestamp
-	now := timeutil.Now()
+	now := s.clock.Now()
 
 	// check metric timestamp if in acceptable time range
 	if (s.behind.Int64() > 0 && timestamp < now-s.behind.Int64()) ||
 		(s.ahead.Int64() > 0 && timestamp > now+s.ahead.Int64()) {
 		return nil
 	}
+	// normalize tag values before indexing
+	if s.option.TagNormalization.Enabled() {
+		for tagKey, tagValue := range metric.Tags {
+			metric.Tags[tagKey] = s.option.TagNormalization.Normalize(tagValue)
+		}
+	}
 	// write metric point into memory db
-	return s.memDB.Write(metric)
+	if err := s.memDB.Write(metric); err != nil {
+		return err
+	}
+	// force flush the oldest families if too many families in memory, such as a wide backfill
+	if s.option.MaxFamilies > 0 && s.memDB.CountFamilies() > s.option.MaxFamilies {
+		return s.flushOldestFamilies()
+	}
+	return nil
 }
 
 func (s *shard) Close() error {
@@ -195,7 +287,8 @@ func (s *shard) initIndexDatabase() error {
 		forwardIndexDir,
 		kv.FamilyOption{
 			CompactThreshold: 0,
-			Merger:           invertedIndexMerger})
+			Merger:           invertedIndexMerger,
+			FlushBufferSize:  s.option.Index.BufferSize * 1024})
 	if err != nil {
 		return err
 	}
@@ -203,7 +296,8 @@ func (s *shard) initIndexDatabase() error {
 		invertedIndexDir,
 		kv.FamilyOption{
 			CompactThreshold: 0,
-			Merger:           forwardIndexMerger})
+			Merger:           s.forwardIndexMerger,
+			FlushBufferSize:  s.option.Index.BufferSize * 1024})
 	if err != nil {
 		return err
 	}
@@ -224,16 +318,49 @@ func (s *shard) Flush() (err error) {
 	}
 	defer s.isFlushing.Store(false)
 
-	if err = s.memDB.FlushForwardIndexTo(
-		forwardindex.NewFlusher(s.forwardFamily.NewFlusher())); err != nil {
+	flushTime := s.clock.Now()
+	if err = s.flushIndex(flushTime); err != nil {
 		return err
 	}
-	if err = s.memDB.FlushInvertedIndexTo(
-		invertedindex.NewFlusher(s.invertedFamily.NewFlusher())); err != nil {
+	return s.flushFamilies(s.memDB.Families(), flushTime)
+}
+
+// flushOldestFamilies flushes the oldest families which exceed the max families limit
+func (s *shard) flushOldestFamilies() error {
+	// another flush process is running
+	if !s.isFlushing.CAS(false, true) {
+		return nil
+	}
+	defer s.isFlushing.Store(false)
+
+	families := s.memDB.Families()
+	exceeded := len(families) - s.option.MaxFamilies
+	if exceeded <= 0 {
+		return nil
+	}
+	// index shall be flushed before flushing data
+	flushTime := s.clock.Now()
+	if err := s.flushIndex(flushTime); err != nil {
 		return err
 	}
+	return s.flushFamilies(families[:exceeded], flushTime)
+}
+
+// flushIndex flushes the forward and inverted index of memory database
+func (s *shard) flushIndex(flushTime int64) error {
+	if err := s.memDB.FlushForwardIndexTo(
+		forwardindex.NewFlusher(s.newKVFlusher(s.forwardFamily,
+			s.objectKey(filepath.Join(indexParDir, forwardIndexDir), flushTime)))); err != nil {
+		return err
+	}
+	return s.memDB.FlushInvertedIndexTo(
+		invertedindex.NewFlusher(s.newKVFlusher(s.invertedFamily,
+			s.objectKey(filepath.Join(indexParDir, invertedIndexDir), flushTime))))
+}
 
-	for _, familyTime := range s.memDB.Families() {
+// flushFamilies flushes the data of the given families
+func (s *shard) flushFamilies(families []int64, flushTime int64) error {
+	for _, familyTime := range families {
 		segmentName := s.interval.Calculator().GetSegment(familyTime)
 		segment, err := s.segment.GetOrCreateSegment(segmentName)
 		if err != nil {
@@ -243,10 +370,30 @@ func (s *shard) Flush() (err error) {
 		if err != nil {
 			continue
 		}
+		dataDir := filepath.Join(segmentDir, s.interval.Type().String(), segmentName, thisDataFamily.Family().Name())
 		if err := s.memDB.FlushFamilyTo(
-			metricsdata.NewFlusher(thisDataFamily.Family().NewFlusher()), familyTime); err != nil {
+			metricsdata.NewFlusher(s.newKVFlusher(thisDataFamily.Family(),
+				s.objectKey(dataDir, flushTime))), familyTime); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// newKVFlusher returns the kv flusher based on the object store flush mode,
+// writes the flush output to local disk, object store or both.
+func (s *shard) newKVFlusher(family kv.Family, objectKey string) kv.Flusher {
+	if s.objectStore == nil {
+		return family.NewFlusher()
+	}
+	remote := kv.NewObjectStoreFlusher(s.objectStore, objectKey)
+	if s.option.ObjectStore.Mode == option.FlushToRemote {
+		return remote
+	}
+	return kv.NewMultiFlusher(family.NewFlusher(), remote)
+}
+
+// objectKey returns the object key for the flush output, like shard/1/index/forward/1571544000000
+func (s *shard) objectKey(dir string, flushTime int64) string {
+	return fmt.Sprintf("shard/%d/%s/%d", s.id, filepath.ToSlash(dir), flushTime)
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/RoaringBitmap/roaring"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 
@@ -17,6 +20,7 @@ import (
 	"github.com/lindb/lindb/series"
 	"github.com/lindb/lindb/tsdb/memdb"
 	"github.com/lindb/lindb/tsdb/metadb"
+	"github.com/lindb/lindb/tsdb/tblstore/forwardindex"
 )
 
 var _testShard1Path = filepath.Join(testPath, shardDir, "1")
@@ -29,15 +33,17 @@ func TestNewShard(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockIDSequencer := metadb.NewMockIDSequencer(ctrl)
-	thisShard, err := newShard(1, _testShard1Path, mockIDSequencer, option.DatabaseOption{})
+	thisShard, err := newShard(1, _testShard1Path, mockIDSequencer, forwardIndexMerger, option.DatabaseOption{})
 	assert.NotNil(t, err)
 	assert.Nil(t, thisShard)
 
-	thisShard, err = newShard(1, _testShard1Path, mockIDSequencer, option.DatabaseOption{Interval: "as"})
+	thisShard, err = newShard(1, _testShard1Path, mockIDSequencer, forwardIndexMerger,
+		option.DatabaseOption{Interval: "as"})
 	assert.NotNil(t, err)
 	assert.Nil(t, thisShard)
 
-	thisShard, err = newShard(1, _testShard1Path, mockIDSequencer, option.DatabaseOption{Interval: "10s"})
+	thisShard, err = newShard(1, _testShard1Path, mockIDSequencer, forwardIndexMerger,
+		option.DatabaseOption{Interval: "10s"})
 	assert.Nil(t, err)
 	assert.NotNil(t, thisShard)
 	assert.NotNil(t, thisShard.IndexDatabase())
@@ -53,12 +59,63 @@ func TestGetSegments(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockIDSequencer := metadb.NewMockIDSequencer(ctrl)
-	s, _ := newShard(1, _testShard1Path, mockIDSequencer, option.DatabaseOption{Interval: "10s"})
+	s, _ := newShard(1, _testShard1Path, mockIDSequencer, forwardIndexMerger, option.DatabaseOption{Interval: "10s"})
 	assert.Nil(t, s.GetDataFamilies(timeutil.Month, timeutil.TimeRange{}))
 	assert.Nil(t, s.GetDataFamilies(timeutil.Day, timeutil.TimeRange{}))
 	assert.Equal(t, 0, len(s.GetDataFamilies(timeutil.Day, timeutil.TimeRange{})))
 }
 
+func TestShard_FieldRetention(t *testing.T) {
+	defer func() {
+		_ = fileutil.RemoveDir(testPath)
+	}()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockIDSequencer := metadb.NewMockIDSequencer(ctrl)
+	s, err := newShard(1, _testShard1Path, mockIDSequencer, forwardIndexMerger, option.DatabaseOption{Interval: "10s",
+		FieldRetention: []option.FieldRetentionOption{{Field: "debug", Retention: "1h"}}})
+	assert.NoError(t, err)
+	retention, ok := s.FieldRetention("debug")
+	assert.True(t, ok)
+	assert.Equal(t, timeutil.OneHour, retention)
+	_, ok = s.FieldRetention("f")
+	assert.False(t, ok)
+}
+
+func TestShard_GetDataFamilies_timeRange(t *testing.T) {
+	defer func() {
+		_ = fileutil.RemoveDir(testPath)
+	}()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockIDSequencer := metadb.NewMockIDSequencer(ctrl)
+	s, _ := newShard(1, _testShard1Path, mockIDSequencer, forwardIndexMerger, option.DatabaseOption{Interval: "10s"})
+	seg, _ := s.(*shard).segment.GetOrCreateSegment("20190902")
+	for _, hour := range []string{"00", "19", "20", "22", "23"} {
+		now, _ := timeutil.ParseTimestamp("20190902 "+hour+":10:48", "20060102 15:04:05")
+		_, _ = seg.GetDataFamily(now)
+	}
+	getDataFamilies := func(start, end string) []DataFamily {
+		startTime, _ := timeutil.ParseTimestamp(start, "20060102 15:04:05")
+		endTime, _ := timeutil.ParseTimestamp(end, "20060102 15:04:05")
+		return s.GetDataFamilies(timeutil.Day, timeutil.TimeRange{Start: startTime, End: endTime})
+	}
+	// 1 hour query range over the day segment
+	familyStartTimes := func(families []DataFamily) (result []string) {
+		for _, family := range families {
+			result = append(result, timeutil.FormatTimestamp(family.TimeRange().Start, "20060102 15:04:05"))
+		}
+		return
+	}
+	assert.ElementsMatch(t, []string{"20190902 19:00:00", "20190902 20:00:00"},
+		familyStartTimes(getDataFamilies("20190902 19:30:00", "20190902 20:29:59")))
+	assert.Equal(t, []string{"20190902 22:00:00"},
+		familyStartTimes(getDataFamilies("20190902 22:00:00", "20190902 22:59:59")))
+	assert.Empty(t, getDataFamilies("20190902 21:00:00", "20190902 21:59:59"))
+}
+
 func TestWrite(t *testing.T) {
 	defer func() {
 		_ = fileutil.RemoveDir(testPath)
@@ -74,7 +131,8 @@ func TestWrite(t *testing.T) {
 		mockMemDB.EXPECT().Write(gomock.Any()).Return(series.ErrTooManyTags),
 	)
 
-	shardINTF, _ := newShard(1, _testShard1Path, mockIDSequencer, option.DatabaseOption{Interval: "10s"})
+	shardINTF, _ := newShard(1, _testShard1Path, mockIDSequencer, forwardIndexMerger,
+		option.DatabaseOption{Interval: "10s"})
 	shardIns := shardINTF.(*shard)
 	shardIns.memDB = mockMemDB
 
@@ -117,6 +175,7 @@ func TestShard_Write_Accept(t *testing.T) {
 		1,
 		_testShard1Path,
 		mockIDSequencer,
+		forwardIndexMerger,
 		option.DatabaseOption{Interval: "10s", Ahead: "1h", Behind: "1h"})
 	assert.NotNil(t, shardINTF.IndexFilter())
 	assert.NotNil(t, shardINTF.IndexMetaGetter())
@@ -140,6 +199,38 @@ func TestShard_Write_Accept(t *testing.T) {
 	shardINTF.(*shard).cancel()
 }
 
+func TestShard_Write_Accept_fakeClock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockMemdb := memdb.NewMockMemoryDatabase(ctrl)
+	clock := timeutil.NewFakeClock(1564300800000)
+	s := &shard{
+		memDB:  mockMemdb,
+		behind: timeutil.Interval(timeutil.OneHour),
+		ahead:  timeutil.Interval(timeutil.OneHour),
+		clock:  clock,
+	}
+	metric := &pb.Metric{
+		Name:      "test",
+		Timestamp: clock.Now(),
+		Fields: []*pb.Field{
+			{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}},
+		},
+	}
+	// in acceptable time range
+	mockMemdb.EXPECT().Write(metric).Return(nil)
+	assert.Nil(t, s.Write(metric))
+
+	// too old after clock advancing, dropped without writing memory database
+	clock.Advance(time.Hour + time.Second)
+	assert.Nil(t, s.Write(metric))
+
+	// too new after clock going back
+	clock.Set(metric.Timestamp - timeutil.OneHour - timeutil.OneSecond)
+	assert.Nil(t, s.Write(metric))
+}
+
 func Test_Shard_Close_Flush_error(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -151,6 +242,7 @@ func Test_Shard_Close_Flush_error(t *testing.T) {
 	s := &shard{
 		segment:  mockIntervalSegment,
 		interval: timeutil.Interval(timeutil.OneSecond * 10),
+		clock:    timeutil.SystemClock,
 	}
 	_, cancel := context.WithCancel(context.Background())
 	s.cancel = cancel
@@ -162,6 +254,7 @@ func Test_Shard_Close_Flush_error(t *testing.T) {
 
 	mockFamily := kv.NewMockFamily(ctrl)
 	mockFamily.EXPECT().NewFlusher().Return(mockFlusher).AnyTimes()
+	mockFamily.EXPECT().Name().Return("1").AnyTimes()
 	s.forwardFamily = mockFamily
 	s.invertedFamily = mockFamily
 
@@ -208,3 +301,247 @@ func Test_Shard_Close_Flush_error(t *testing.T) {
 	s.isFlushing.Store(true)
 	assert.Nil(t, s.Flush())
 }
+
+func Test_Shard_Flush_ObjectStore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockObjectStore := kv.NewMockObjectStore(ctrl)
+	mockFlusher := kv.NewMockFlusher(ctrl)
+	mockFamily := kv.NewMockFamily(ctrl)
+	mockMemdb := memdb.NewMockMemoryDatabase(ctrl)
+	s := &shard{
+		id:             1,
+		interval:       timeutil.Interval(timeutil.OneSecond * 10),
+		memDB:          mockMemdb,
+		forwardFamily:  mockFamily,
+		invertedFamily: mockFamily,
+		objectStore:    mockObjectStore,
+		clock:          timeutil.SystemClock,
+	}
+	flushForwardIndex := func(flusher forwardindex.Flusher) error {
+		flusher.FlushTagValue("1.1.1.1", roaring.BitmapOf(1))
+		flusher.FlushTagKey("host")
+		flusher.FlushVersion(series.NewVersion(), timeutil.TimeRange{})
+		if err := flusher.FlushMetricID(1); err != nil {
+			return err
+		}
+		return flusher.Commit()
+	}
+	mockMemdb.EXPECT().Families().Return(nil).AnyTimes()
+	mockMemdb.EXPECT().FlushInvertedIndexTo(gomock.Any()).Return(nil).AnyTimes()
+	mockMemdb.EXPECT().FlushForwardIndexTo(gomock.Any()).DoAndReturn(flushForwardIndex).AnyTimes()
+
+	// flush to remote only, local flusher not created
+	s.option.ObjectStore.Mode = option.FlushToRemote
+	mockObjectStore.EXPECT().PutObject(gomock.Any(), gomock.Any()).DoAndReturn(func(key string, data []byte) error {
+		assert.Contains(t, key, "shard/1/index/forward/")
+		assert.True(t, len(data) > 0)
+		return nil
+	})
+	assert.Nil(t, s.Flush())
+	// upload failure
+	mockObjectStore.EXPECT().PutObject(gomock.Any(), gomock.Any()).Return(fmt.Errorf("err"))
+	assert.NotNil(t, s.Flush())
+
+	// flush to both local and remote
+	s.option.ObjectStore.Mode = option.FlushToBoth
+	mockFamily.EXPECT().NewFlusher().Return(mockFlusher).Times(2)
+	mockFlusher.EXPECT().Add(uint32(1), gomock.Any()).Return(nil)
+	mockFlusher.EXPECT().Commit().Return(nil)
+	mockObjectStore.EXPECT().PutObject(gomock.Any(), gomock.Any()).Return(nil)
+	assert.Nil(t, s.Flush())
+}
+
+func TestShard_Write_MaxFamilies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockMemdb := memdb.NewMockMemoryDatabase(ctrl)
+	mockFlusher := kv.NewMockFlusher(ctrl)
+	mockFlusher.EXPECT().Commit().Return(nil).AnyTimes()
+	mockFamily := kv.NewMockFamily(ctrl)
+	mockFamily.EXPECT().NewFlusher().Return(mockFlusher).AnyTimes()
+	mockFamily.EXPECT().Name().Return("1").AnyTimes()
+	mockIntervalSegment := NewMockIntervalSegment(ctrl)
+	mockSegment := NewMockSegment(ctrl)
+	mockDataFamily := NewMockDataFamily(ctrl)
+	mockDataFamily.EXPECT().Family().Return(mockFamily).AnyTimes()
+	mockIntervalSegment.EXPECT().GetOrCreateSegment(gomock.Any()).Return(mockSegment, nil).AnyTimes()
+	mockSegment.EXPECT().GetDataFamily(gomock.Any()).Return(mockDataFamily, nil).AnyTimes()
+	s := &shard{
+		option:         option.DatabaseOption{MaxFamilies: 3},
+		segment:        mockIntervalSegment,
+		interval:       timeutil.Interval(timeutil.OneSecond * 10),
+		memDB:          mockMemdb,
+		forwardFamily:  mockFamily,
+		invertedFamily: mockFamily,
+		clock:          timeutil.SystemClock,
+	}
+	metric := &pb.Metric{
+		Name:      "test",
+		Timestamp: timeutil.Now(),
+		Fields: []*pb.Field{
+			{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}},
+		},
+	}
+	mockMemdb.EXPECT().Write(gomock.Any()).Return(nil).AnyTimes()
+	// not exceed
+	mockMemdb.EXPECT().CountFamilies().Return(3)
+	assert.Nil(t, s.Write(metric))
+	// exceed, flush the oldest families
+	mockMemdb.EXPECT().CountFamilies().Return(5)
+	mockMemdb.EXPECT().Families().Return([]int64{1, 2, 3, 4, 5})
+	mockMemdb.EXPECT().FlushForwardIndexTo(gomock.Any()).Return(nil)
+	mockMemdb.EXPECT().FlushInvertedIndexTo(gomock.Any()).Return(nil)
+	mockMemdb.EXPECT().FlushFamilyTo(gomock.Any(), int64(1)).Return(nil)
+	mockMemdb.EXPECT().FlushFamilyTo(gomock.Any(), int64(2)).Return(nil)
+	assert.Nil(t, s.Write(metric))
+	// flush error
+	mockMemdb.EXPECT().CountFamilies().Return(5)
+	mockMemdb.EXPECT().Families().Return([]int64{1, 2, 3, 4, 5})
+	mockMemdb.EXPECT().FlushForwardIndexTo(gomock.Any()).Return(fmt.Errorf("err"))
+	assert.NotNil(t, s.Write(metric))
+	// families flushed by others
+	mockMemdb.EXPECT().CountFamilies().Return(5)
+	mockMemdb.EXPECT().Families().Return([]int64{1, 2})
+	assert.Nil(t, s.Write(metric))
+	// another flush is running
+	s.isFlushing.Store(true)
+	mockMemdb.EXPECT().CountFamilies().Return(5)
+	assert.Nil(t, s.Write(metric))
+}
+
+func TestShard_Write_MaxMetricNameLength(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockMemdb := memdb.NewMockMemoryDatabase(ctrl)
+	s := &shard{
+		option: option.DatabaseOption{MaxMetricNameLength: 8},
+		memDB:  mockMemdb,
+		clock:  timeutil.SystemClock,
+	}
+	newMetric := func(name string) *pb.Metric {
+		return &pb.Metric{
+			Name:      name,
+			Timestamp: timeutil.Now(),
+			Fields: []*pb.Field{
+				{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}},
+			},
+		}
+	}
+	// in-bounds
+	mockMemdb.EXPECT().Write(gomock.Any()).Return(nil).Times(2)
+	assert.Nil(t, s.Write(newMetric("cpu")))
+	assert.Nil(t, s.Write(newMetric("cpu.load")))
+	// over-length
+	assert.Equal(t, series.ErrMetricNameTooLong, s.Write(newMetric("cpu.load1")))
+	// unlimited
+	s.option.MaxMetricNameLength = 0
+	mockMemdb.EXPECT().Write(gomock.Any()).Return(nil)
+	assert.Nil(t, s.Write(newMetric("cpu.load1")))
+}
+
+func TestShard_Write_Backfill_FamiliesBounded(t *testing.T) {
+	defer func() {
+		_ = fileutil.RemoveDir(testPath)
+	}()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockIDSequencer := metadb.NewMockIDSequencer(ctrl)
+	mockIDSequencer.EXPECT().GenMetricID(gomock.Any()).Return(uint32(1)).AnyTimes()
+	mockIDSequencer.EXPECT().GenFieldID(gomock.Any(), gomock.Any(), gomock.Any()).Return(uint16(1), nil).AnyTimes()
+	mockIDSequencer.EXPECT().GenTagKeyID(gomock.Any(), gomock.Any()).Return(uint32(1)).AnyTimes()
+
+	shardINTF, err := newShard(1, _testShard1Path, mockIDSequencer, forwardIndexMerger,
+		option.DatabaseOption{Interval: "10s", MaxFamilies: 2})
+	assert.Nil(t, err)
+	defer shardINTF.(*shard).cancel()
+	now := timeutil.Now()
+	// backfill data of the last 24 hours
+	for i := 24; i > 0; i-- {
+		assert.Nil(t, shardINTF.Write(&pb.Metric{
+			Name:      "test",
+			Timestamp: now - int64(i)*timeutil.OneHour,
+			Fields: []*pb.Field{
+				{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}},
+			},
+		}))
+		assert.True(t, shardINTF.MemoryDatabase().CountFamilies() <= 2)
+	}
+	assert.Len(t, shardINTF.MemoryDatabase().Families(), 2)
+}
+
+func TestShard_Write_TagNormalization(t *testing.T) {
+	defer func() {
+		_ = fileutil.RemoveDir(testPath)
+	}()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockIDSequencer := metadb.NewMockIDSequencer(ctrl)
+	mockIDSequencer.EXPECT().GenMetricID(gomock.Any()).Return(uint32(1)).AnyTimes()
+	mockIDSequencer.EXPECT().GenFieldID(gomock.Any(), gomock.Any(), gomock.Any()).Return(uint16(1), nil).AnyTimes()
+	mockIDSequencer.EXPECT().GenTagKeyID(gomock.Any(), gomock.Any()).Return(uint32(1)).AnyTimes()
+
+	write := func(shardID int32, normalization option.TagNormalizationOption) int {
+		shardINTF, err := newShard(shardID, filepath.Join(testPath, shardDir, strconv.Itoa(int(shardID))),
+			mockIDSequencer, forwardIndexMerger, option.DatabaseOption{Interval: "10s", TagNormalization: normalization})
+		assert.Nil(t, err)
+		defer shardINTF.(*shard).cancel()
+		for _, host := range []string{"Host ", "host", " HOST"} {
+			assert.Nil(t, shardINTF.Write(&pb.Metric{
+				Name:      "test",
+				Timestamp: timeutil.Now(),
+				Tags:      map[string]string{"host": host},
+				Fields: []*pb.Field{
+					{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}},
+				},
+			}))
+		}
+		return shardINTF.MemoryDatabase().CountTags("test")
+	}
+	// normalization off
+	assert.Equal(t, 3, write(1, option.TagNormalizationOption{}))
+	// normalization on, maps to the same series
+	assert.Equal(t, 1, write(2, option.TagNormalizationOption{Trim: true, Lowercase: true}))
+}
+
+func TestShard_Write_NanosecondTimestamp(t *testing.T) {
+	defer func() {
+		_ = fileutil.RemoveDir(testPath)
+	}()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockIDSequencer := metadb.NewMockIDSequencer(ctrl)
+	mockIDSequencer.EXPECT().GenMetricID(gomock.Any()).Return(uint32(1)).AnyTimes()
+	mockIDSequencer.EXPECT().GenFieldID(gomock.Any(), gomock.Any(), gomock.Any()).Return(uint16(1), nil).AnyTimes()
+	mockIDSequencer.EXPECT().GenTagKeyID(gomock.Any(), gomock.Any()).Return(uint32(1)).AnyTimes()
+
+	shardINTF, err := newShard(1, _testShard1Path, mockIDSequencer, forwardIndexMerger,
+		option.DatabaseOption{Interval: "10s", Behind: "1h", Ahead: "1h", TimestampPrecision: option.PrecisionNanosecond})
+	assert.Nil(t, err)
+	defer shardINTF.(*shard).cancel()
+
+	now := timeutil.Now()
+	metric := &pb.Metric{
+		Name:      "test",
+		Timestamp: now*1000000 + 123456,
+		Fields: []*pb.Field{
+			{Name: "f1", Field: &pb.Field_Sum{Sum: &pb.Sum{Value: 1.0}}},
+		},
+	}
+	// ns timestamp is accepted by the behind/ahead check after converting
+	assert.Nil(t, shardINTF.Write(metric))
+	assert.Equal(t, now, metric.Timestamp)
+
+	var interval timeutil.Interval
+	_ = interval.ValueOf("10s")
+	calc := interval.Calculator()
+	segmentTime := calc.CalcSegmentTime(now)
+	familyTime := calc.CalcFamilyStartTime(segmentTime, calc.CalcFamily(now, segmentTime))
+	assert.Equal(t, []int64{familyTime}, shardINTF.MemoryDatabase().Families())
+}
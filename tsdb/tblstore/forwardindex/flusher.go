@@ -247,7 +247,7 @@ func (flusher *flusher) finishVersion(startPos, dictBlockOffsetPos int) {
 
 	// write keys
 	flusher.keys.RunOptimize()
-	keys, err := flusher.keys.MarshalBinary()
+	keys, err := encoding.BitmapMarshal(flusher.keys)
 	if err != nil {
 		forwardIndexFlusherLogger.Error("marshal keys error", logger.Error(err))
 	}
@@ -11,24 +11,46 @@ import (
 	"github.com/lindb/lindb/tsdb/tblstore"
 )
 
+// MetricTTLGetter returns the ttl of metric by metric id, false if the metric uses the default ttl
+type MetricTTLGetter func(metricID uint32) (ttl time.Duration, ok bool)
+
 type merger struct {
 	flusher      *flusher
 	reader       *reader
 	nopKVFlusher *kv.NopFlusher
 	ttl          time.Duration
+	metricTTL    MetricTTLGetter
 	sr           *stream.Reader
 }
 
+// NewMerger creates the forward index merger, the versions older than ttl are dropped for all metrics
 func NewMerger(ttl time.Duration) kv.Merger {
+	return NewMergerWithMetricTTL(ttl, nil)
+}
+
+// NewMergerWithMetricTTL creates the forward index merger, the versions older than the ttl of metric are dropped,
+// the metrics without ttl from metricTTL use the default ttl.
+func NewMergerWithMetricTTL(defaultTTL time.Duration, metricTTL MetricTTLGetter) kv.Merger {
 	nopKVFlusher := kv.NewNopFlusher()
 	return &merger{
 		reader:       NewReader(nil).(*reader),
 		nopKVFlusher: nopKVFlusher,
 		flusher:      NewFlusher(nopKVFlusher).(*flusher),
-		ttl:          ttl,
+		ttl:          defaultTTL,
+		metricTTL:    metricTTL,
 		sr:           stream.NewReader(nil)}
 }
 
+// getTTL returns the ttl of metric, the default ttl if the metric has no ttl
+func (m *merger) getTTL(metricID uint32) time.Duration {
+	if m.metricTTL != nil {
+		if ttl, ok := m.metricTTL(metricID); ok {
+			return ttl
+		}
+	}
+	return m.ttl
+}
+
 func (m *merger) Reset() {
 	m.flusher.Reset()
 }
@@ -50,8 +72,9 @@ func (m *merger) latestVersionBlock(versionBlocks [][]byte) []byte {
 	return versionBlocks[latestIndex]
 }
 
-// AliveVersions deletes the expired versions
+// AliveVersions deletes the versions expired by ttl, the last version is kept even if all versions are expired
 func (m *merger) AliveVersions(
+	ttl time.Duration,
 	versionBlocksMap map[series.Version][][]byte,
 ) (alive []series.Version,
 ) {
@@ -65,7 +88,7 @@ func (m *merger) AliveVersions(
 	var lastVersion series.Version
 	for _, version := range versions {
 		lastVersion = version
-		if !version.IsExpired(m.ttl) {
+		if !version.IsExpired(ttl) {
 			alive = append(alive, version)
 		}
 	}
@@ -105,7 +128,7 @@ func (m *merger) Merge(
 	if len(versionBlocksMap) == 0 {
 		return nil, fmt.Errorf("no available blocks for compacting")
 	}
-	for _, version := range m.AliveVersions(versionBlocksMap) {
+	for _, version := range m.AliveVersions(m.getTTL(key), versionBlocksMap) {
 		latestVersionBlock := m.latestVersionBlock(versionBlocksMap[version])
 		startPos := m.flusher.metricBlockWriter.Len()
 		m.flusher.metricBlockWriter.PutBytes(latestVersionBlock)
@@ -14,7 +14,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func buildBlockToCompact() (data [][]byte) {
+func buildBlockToCompact(metricID uint32) (data [][]byte) {
 	nopKVFlusher := kv.NewNopFlusher()
 	flusher := NewFlusher(nopKVFlusher)
 	now := timeutil.Now()
@@ -30,14 +30,14 @@ func buildBlockToCompact() (data [][]byte) {
 	flusher.FlushVersion(series.Version(now-3600*1000*24*60), timeutil.TimeRange{Start: 1, End: 2})
 	flushVersion(10)
 	flusher.FlushVersion(series.Version(now-3600*1000*24*20), timeutil.TimeRange{Start: 1, End: 2})
-	_ = flusher.FlushMetricID(1)
+	_ = flusher.FlushMetricID(metricID)
 	data = append(data, append([]byte{}, nopKVFlusher.Bytes()...))
 
 	flushVersion(12)
 	flusher.FlushVersion(series.Version(now-3600*1000*24*35), timeutil.TimeRange{Start: 1, End: 2})
 	flushVersion(12)
 	flusher.FlushVersion(series.Version(now-3600*1000*24*20), timeutil.TimeRange{Start: 1, End: 2})
-	_ = flusher.FlushMetricID(1)
+	_ = flusher.FlushMetricID(metricID)
 	data = append(data, append([]byte{}, nopKVFlusher.Bytes()...))
 
 	return data
@@ -56,7 +56,7 @@ func Test_Merger(t *testing.T) {
 	assert.Nil(t, data)
 	assert.NotNil(t, err)
 	// merge normal
-	block := buildBlockToCompact()
+	block := buildBlockToCompact(1)
 	data, err = m.Merge(1, block)
 	assert.Nil(t, err)
 	assert.NotNil(t, data)
@@ -74,3 +74,34 @@ func Test_Merger(t *testing.T) {
 	assert.NotNil(t, data)
 	assert.Nil(t, err)
 }
+
+func Test_Merger_metricTTL(t *testing.T) {
+	// metric 1 keeps 7 days, metric 2 keeps 90 days, others keep 30 days
+	metricTTL := map[uint32]time.Duration{1: time.Hour * 24 * 7, 2: time.Hour * 24 * 90}
+	m := NewMergerWithMetricTTL(time.Hour*24*30, func(metricID uint32) (time.Duration, bool) {
+		ttl, ok := metricTTL[metricID]
+		return ttl, ok
+	})
+	countVersions := func(metricID uint32) int {
+		data, err := m.Merge(metricID, buildBlockToCompact(metricID))
+		assert.NoError(t, err)
+		itr, err := tblstore.NewVersionBlockIterator(data)
+		assert.NoError(t, err)
+		count := 0
+		for itr.HasNext() {
+			_, versionBlock := itr.Next()
+			assert.NotNil(t, versionBlock)
+			count++
+		}
+		return count
+	}
+	// versions of 60, 35, 20 days ago
+	// all versions expired, keep the last one
+	assert.Equal(t, 1, countVersions(1))
+	// all versions alive
+	assert.Equal(t, 3, countVersions(2))
+	// default ttl
+	assert.Equal(t, 1, countVersions(3))
+	metricTTL[3] = time.Hour * 24 * 40
+	assert.Equal(t, 2, countVersions(3))
+}
@@ -91,8 +91,8 @@ func newForwardIndexVersionEntry(
 	}
 	// Unmarshal seriesIDBitmap
 	entry.seriesIDBitmap = roaring.New()
-	if err := entry.seriesIDBitmap.UnmarshalBinary(
-		entry.versionBlock[entry.posOfSeriesIDBitmap : len(versionBlock)-footerSizeOfVersionEntry]); err != nil {
+	if err := encoding.BitmapUnmarshal(entry.seriesIDBitmap,
+		entry.versionBlock[entry.posOfSeriesIDBitmap:len(versionBlock)-footerSizeOfVersionEntry]); err != nil {
 		return nil, err
 	}
 	if len(entry.offsets) != int(entry.seriesIDBitmap.GetCardinality()) {
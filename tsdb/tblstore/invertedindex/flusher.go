@@ -64,7 +64,7 @@ func (w *flusher) FlushVersion(
 	timeRange timeutil.TimeRange,
 	bitmap *roaring.Bitmap,
 ) {
-	out, err := bitmap.MarshalBinary()
+	out, err := encoding.BitmapMarshal(bitmap)
 	if err != nil {
 		invertedIndexFlusherLogger.Error("marshal bitmap failure", logger.Error(err))
 	}
@@ -425,7 +425,7 @@ func (data *versionedTagValueData) TimeRange() timeutil.TimeRange {
 // Bitmap unmarshals the binary to bitmap
 func (data *versionedTagValueData) Bitmap() (*roaring.Bitmap, error) {
 	bitmap := roaring.New()
-	if err := bitmap.UnmarshalBinary(data.bitMapData); err != nil {
+	if err := encoding.BitmapUnmarshal(bitmap, data.bitMapData); err != nil {
 		return nil, err
 	}
 	return bitmap, nil
@@ -80,11 +80,9 @@ func (tt *trieTree) newNode() *trieTreeNode {
 	return trieTreeNodePool.Get().(*trieTreeNode)
 }
 
-// Add adds a new key to the tree.
+// Add adds a new key to the tree,
+// empty key(e.g. tag value of series without tags) is set on the root node.
 func (tt *trieTree) Add(tagValue string, item interface{}) {
-	if tagValue == "" {
-		return
-	}
 	n := tt.root
 	for _, k := range []byte(tagValue) {
 		k := k
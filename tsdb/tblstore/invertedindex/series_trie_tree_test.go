@@ -42,8 +42,9 @@ func Test_trie_tree(t *testing.T) {
 	assert.Equal(t, 10, tree.KeyNum())
 	assert.Equal(t, 23, tree.NodeNum())
 
+	// empty key is set on root node
 	tree.Add("", 323333)
-	assert.Equal(t, 10, tree.KeyNum())
+	assert.Equal(t, 11, tree.KeyNum())
 	assert.Equal(t, 23, tree.NodeNum())
 
 	tree.Reset()
@@ -144,6 +145,17 @@ func Test_trieTree_FindOffsetsByEqual(t *testing.T) {
 	assert.Len(t, data.FindOffsetsByEqual("etrac"), 0)
 }
 
+func Test_trieTree_emptyKey(t *testing.T) {
+	tree := newTrieTree()
+	tree.Add("a", 1)
+	tree.Add("", 2)
+	data := tree.MarshalBinary()
+	// value of root node is the first
+	assert.Equal(t, []interface{}{2, 1}, data.values)
+	assert.Equal(t, []int{0}, data.FindOffsetsByEqual(""))
+	assert.Equal(t, []int{1}, data.FindOffsetsByEqual("a"))
+}
+
 func Test_trieTree_walkTreeByValue(t *testing.T) {
 	data := buildTestTrieTreeData()
 
@@ -139,7 +139,7 @@ func (w *flusher) FlushVersion(version series.Version) {
 	// write series bitmap
 	w.seriesIDs.RunOptimize()
 	seriesBitmapPos := w.writer.Len() - w.versionStartPos
-	data, _ := w.seriesIDs.MarshalBinary()
+	data, _ := encoding.BitmapMarshal(w.seriesIDs)
 	w.writer.PutBytes(data)
 
 	// write fields-meta
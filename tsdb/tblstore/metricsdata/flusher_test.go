@@ -1,6 +1,7 @@
 package metricsdata
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -47,3 +48,27 @@ func Test_MetricsDataFlusher_Commit(t *testing.T) {
 
 	assert.Nil(t, flusher.FlushMetric(1))
 }
+
+func Test_MetricsDataFlusher_fieldOrder(t *testing.T) {
+	nopKVFlusher := kv.NewNopFlusher()
+	flusher := NewFlusher(nopKVFlusher)
+
+	// fields data of series are flushed in the order of field metas
+	flusher.FlushFieldMetas([]field.Meta{
+		{ID: 3, Type: field.SumField, Name: "sum3"},
+		{ID: 1, Type: field.SumField, Name: "sum1"},
+		{ID: 2, Type: field.SumField, Name: "sum2"},
+	})
+	flusher.FlushField(1, []byte{1, 1, 1, 1})
+	flusher.FlushField(2, []byte{2, 2, 2, 2})
+	flusher.FlushField(3, []byte{3, 3, 3, 3})
+	flusher.FlushSeries(1)
+	flusher.FlushVersion(series.Version(1))
+	assert.Nil(t, flusher.FlushMetric(1))
+
+	data := nopKVFlusher.Bytes()
+	pos3 := bytes.Index(data, []byte{3, 3, 3, 3})
+	pos1 := bytes.Index(data, []byte{1, 1, 1, 1})
+	pos2 := bytes.Index(data, []byte{2, 2, 2, 2})
+	assert.True(t, pos3 >= 0 && pos3 < pos1 && pos1 < pos2)
+}
@@ -179,7 +179,7 @@ func (vb *mdtVersionBlock) readOffsetsAndBitmap() error {
 	vb.seriesOffsets = encoding.NewDeltaBitPackingDecoder(vb.block[vb.seriesOffsetPos:vb.seriesBitmapPos])
 	// read bitmap
 	vb.seriesBitmap = roaring.New()
-	if err := vb.seriesBitmap.UnmarshalBinary(vb.block[vb.seriesBitmapPos:vb.fieldMetaPos]); err != nil {
+	if err := encoding.BitmapUnmarshal(vb.seriesBitmap, vb.block[vb.seriesBitmapPos:vb.fieldMetaPos]); err != nil {
 		return err
 	}
 	return nil
@@ -189,6 +189,10 @@ func (vb *mdtVersionBlock) SeriesIDs() *roaring.Bitmap {
 	return vb.seriesBitmap
 }
 
+func (vb *mdtVersionBlock) Version() series.Version {
+	return vb.version
+}
+
 func (vb *mdtVersionBlock) Release() {
 	// todo
 	if vb.aggregators == nil {
@@ -46,11 +46,9 @@ func NewFlusher(kvFlusher kv.Flusher) Flusher {
 		writer:    stream.NewBufferWriter(nil)}
 }
 
-// FlushTagMeta flushes the relation of tagKey and tagID to buffer
+// FlushTagMeta flushes the relation of tagKey and tagID to buffer,
+// the empty tagKey of series without tags is flushed too, so that its inverted index is found after flush.
 func (f *flusher) FlushTagMeta(tagMeta tag.Meta) {
-	if tagMeta.Key == "" {
-		return
-	}
 	if len(tagMeta.Key) > math.MaxUint8 {
 		metaFlusherLogger.Error("tagKey too long", zap.Int("length", len(tagMeta.Key)))
 	}
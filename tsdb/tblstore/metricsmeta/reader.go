@@ -19,6 +19,8 @@ type Reader interface {
 	ReadMaxFieldID(metricID uint32) (maxFieldID uint16)
 	// ReadFieldID read fieldID and fieldType from metricID and fieldName
 	ReadFieldID(metricID uint32, fieldName string) (fieldID uint16, fieldType field.Type, ok bool)
+	// ReadFieldMetas reads all the field metas of this metric
+	ReadFieldMetas(metricID uint32) field.Metas
 	// SuggestTagKeys returns suggestion of tagKeys by prefix
 	SuggestTagKeys(metricID uint32, tagKeyPrefix string, limit int) []string
 }
@@ -130,6 +132,25 @@ func (r *reader) ReadFieldID(
 	return 0, field.Type(0), false
 }
 
+// ReadFieldMetas reads all the field metas of this metric
+func (r *reader) ReadFieldMetas(
+	metricID uint32,
+) (
+	fieldMetas field.Metas,
+) {
+	for _, reader := range r.readers {
+		_, fieldMetaBlock := r.readMetasBlock(reader.Get(metricID))
+		if fieldMetaBlock == nil {
+			continue
+		}
+		itr := newFieldMetaIterator(fieldMetaBlock)
+		for itr.HasNext() {
+			fieldMetas = append(fieldMetas, itr.Next())
+		}
+	}
+	return fieldMetas
+}
+
 // SuggestTagKeys returns suggestion of tagKeys by prefix
 func (r *reader) SuggestTagKeys(
 	metricID uint32,
@@ -149,7 +170,8 @@ func (r *reader) SuggestTagKeys(
 				return collectedTagKeys
 			}
 			tagMeta := itr.Next()
-			if strings.HasPrefix(tagMeta.Key, tagKeyPrefix) {
+			// skip the empty tagKey of series without tags
+			if tagMeta.Key != "" && strings.HasPrefix(tagMeta.Key, tagKeyPrefix) {
 				collectedTagKeys = append(collectedTagKeys, tagMeta.Key)
 			}
 		}
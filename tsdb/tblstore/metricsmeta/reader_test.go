@@ -27,6 +27,7 @@ func prepareData() ([]byte, []byte) {
 
 	metaFlusherINTF2.FlushTagMeta(tag.Meta{Key: "a2", ID: 7})
 	metaFlusherINTF2.FlushTagMeta(tag.Meta{Key: "b2", ID: 8})
+	metaFlusherINTF2.FlushTagMeta(tag.Meta{Key: "", ID: 9})
 	metaFlusherINTF2.FlushFieldMeta(field.Meta{ID: 5, Type: field.SumField, Name: "sum2"})
 	metaFlusherINTF2.FlushFieldMeta(field.Meta{ID: 6, Type: field.MinField, Name: "min2"})
 	_ = metaFlusherINTF2.FlushMetricMeta(2)
@@ -45,10 +46,11 @@ func Test_MetricsMetaReader_ok(t *testing.T) {
 	assert.NotNil(t, metaReader)
 
 	// mock nil
-	mockReader1.EXPECT().Get(uint32(1)).Return(nil).Times(2)
-	mockReader2.EXPECT().Get(uint32(1)).Return(nil).Times(2)
+	mockReader1.EXPECT().Get(uint32(1)).Return(nil).Times(3)
+	mockReader2.EXPECT().Get(uint32(1)).Return(nil).Times(3)
 	metaReader.ReadTagKeyID(1, "test-tag")
 	metaReader.ReadFieldID(1, "test-field")
+	assert.Nil(t, metaReader.ReadFieldMetas(1))
 
 	// mockOK
 	data1, data2 := prepareData()
@@ -60,6 +62,11 @@ func Test_MetricsMetaReader_ok(t *testing.T) {
 	assert.True(t, ok)
 	assert.Len(t, metaReader.SuggestTagKeys(2, "a", 100), 2)
 	assert.Len(t, metaReader.SuggestTagKeys(2, "a", 1), 1)
+	// empty tag key of series without tags
+	tagID, ok = metaReader.ReadTagKeyID(2, "")
+	assert.Equal(t, uint32(9), tagID)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a1", "b1", "a2", "b2"}, metaReader.SuggestTagKeys(2, "", 100))
 	// tag not found
 	tagID, ok = metaReader.ReadTagKeyID(2, "a3")
 	assert.Zero(t, tagID)
@@ -75,6 +82,13 @@ func Test_MetricsMetaReader_ok(t *testing.T) {
 	assert.Equal(t, uint16(0), fieldID)
 	assert.False(t, ok)
 	assert.Equal(t, field.Type(0), fieldType)
+	// field metas of all readers
+	assert.Equal(t, field.Metas{
+		{ID: 1, Type: field.SumField, Name: "sum1"},
+		{ID: 2, Type: field.MinField, Name: "min1"},
+		{ID: 5, Type: field.SumField, Name: "sum2"},
+		{ID: 6, Type: field.MinField, Name: "min2"},
+	}, metaReader.ReadFieldMetas(2))
 }
 
 func Test_MetricsMetaReader_ReadMaxFieldID(t *testing.T) {